@@ -0,0 +1,206 @@
+// Package client is a typed Go client of the CourseAI API. The types and
+// methods in client_gen.go are generated from the server's OpenAPI document
+// by cmd/apigen; only this file is written by hand.
+//
+//	c := client.New("http://localhost:8080")
+//	if err := c.SignIn(ctx, "admin123", "admin123"); err != nil {
+//		log.Fatal(err)
+//	}
+//	programs, err := c.ListPrograms(ctx, nil)
+package client
+
+//go:generate go run ../cmd/apigen -out client_gen.go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client calls the API at BaseURL. Token is sent as a bearer token once set,
+// by SignIn or by hand.
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	Token      string
+	// Organization selects the tenant for super-admins and anonymous callers
+	// (the X-Organization header)
+	Organization string
+}
+
+// New returns a client for the server at baseURL, e.g. http://localhost:8080
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// RequestOption changes a single request
+type RequestOption func(*http.Request)
+
+// IfMatch sends the version a change is based on. Versioned writes require it:
+// the server answers 428 without it, and 412 with the current record if it
+// moved on.
+func IfMatch(version int) RequestOption {
+	return Header("If-Match", `"`+strconv.Itoa(version)+`"`)
+}
+
+// Header sets a request header
+func Header(key, value string) RequestOption {
+	return func(r *http.Request) { r.Header.Set(key, value) }
+}
+
+// File is a file part of a multipart upload
+type File struct {
+	Name    string
+	Content io.Reader
+}
+
+// APIError is a non-2xx response. Errors lists the field problems of a
+// rejected request body.
+type APIError struct {
+	StatusCode int
+	ErrorResponse
+	Body []byte
+}
+
+func (e *APIError) Error() string {
+	if e.ErrorResponse.Error != "" {
+		return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.ErrorResponse.Error)
+	}
+	return fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// StatusCode returns the HTTP status of an *APIError, or 0 for other errors
+func StatusCode(err error) int {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return 0
+}
+
+// SignIn logs in with a password and keeps the session token. Accounts that
+// need a second factor have to use Login and the two-factor calls instead.
+func (c *Client) SignIn(ctx context.Context, username, password string) error {
+	resp, err := c.Login(ctx, &LoginRequest{Username: username, Password: password})
+	if err != nil {
+		return err
+	}
+	if resp.Token == "" {
+		return errors.New("client: login needs a second factor")
+	}
+	c.Token = resp.Token
+	return nil
+}
+
+// do sends a request and decodes a JSON response into out, if not nil
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, contentType string, body, out interface{}, opts []RequestOption) error {
+	var reader io.Reader
+	if body != nil {
+		var buf bytes.Buffer
+		if contentType == "multipart/form-data" {
+			w := multipart.NewWriter(&buf)
+			if err := writeMultipart(w, body); err != nil {
+				return err
+			}
+			if err := w.Close(); err != nil {
+				return err
+			}
+			contentType = w.FormDataContentType()
+		} else if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			return err
+		}
+		reader = &buf
+	}
+
+	target := c.BaseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	if c.Organization != "" {
+		req.Header.Set("X-Organization", c.Organization)
+	}
+	for _, opt := range opts {
+		opt(req)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		apiErr := &APIError{StatusCode: resp.StatusCode, Body: data}
+		_ = json.Unmarshal(data, &apiErr.ErrorResponse)
+		return apiErr
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("client: decode %s %s response: %w", method, path, err)
+	}
+	return nil
+}
+
+// writeMultipart writes the fields of a form struct by their json names;
+// File fields become file parts
+func writeMultipart(w *multipart.Writer, form interface{}) error {
+	v := reflect.Indirect(reflect.ValueOf(form))
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, opts, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		field := v.Field(i)
+		if name == "" || name == "-" || (strings.Contains(opts, "omitempty") && field.IsZero()) {
+			continue
+		}
+		switch value := field.Interface().(type) {
+		case []File:
+			for _, file := range value {
+				part, err := w.CreateFormFile(name, file.Name)
+				if err != nil {
+					return err
+				}
+				if _, err := io.Copy(part, file.Content); err != nil {
+					return err
+				}
+			}
+		default:
+			if err := w.WriteField(name, fmt.Sprint(value)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
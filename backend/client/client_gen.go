@@ -0,0 +1,2195 @@
+// Code generated by cmd/apigen from the OpenAPI document; DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AssignmentsResponse struct {
+	Assignments []TeacherAssignment `json:"assignments,omitempty"`
+}
+
+type AuthorizationURL struct {
+	URL string `json:"url,omitempty"`
+}
+
+type BuildType string
+
+const (
+	BuildTypePDF    BuildType = "pdf"
+	BuildTypeImages BuildType = "images"
+)
+
+type CheckResult struct {
+	Name       string  `json:"name,omitempty"`
+	Status     string  `json:"status,omitempty"`
+	Error      string  `json:"error,omitempty"`
+	DurationMS float64 `json:"duration_ms,omitempty"`
+}
+
+type CloneInput struct {
+	SubcourseID *uuid.UUID `json:"subcourse_id,omitempty"`
+	ProgramID   *uuid.UUID `json:"program_id,omitempty"`
+	Title       string     `json:"title,omitempty"`
+	Name        string     `json:"name,omitempty"`
+	Slug        string     `json:"slug,omitempty"`
+}
+
+type ContentStatus string
+
+const (
+	ContentStatusDraft     ContentStatus = "draft"
+	ContentStatusPublished ContentStatus = "published"
+	ContentStatusArchived  ContentStatus = "archived"
+)
+
+type CreateTeacherInput struct {
+	Username       string     `json:"username,omitempty"`
+	Email          string     `json:"email,omitempty"`
+	Password       string     `json:"password,omitempty"`
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty"`
+}
+
+type DeleteImpact struct {
+	Type       string           `json:"type,omitempty"`
+	ID         uuid.UUID        `json:"id,omitempty"`
+	Subcourses int64            `json:"subcourses,omitempty"`
+	Lessons    int64            `json:"lessons,omitempty"`
+	Components map[string]int64 `json:"components,omitempty"`
+	Templates  int64            `json:"templates,omitempty"`
+	Media      int64            `json:"media,omitempty"`
+	MediaFiles int64            `json:"media_files,omitempty"`
+	MediaBytes int64            `json:"media_bytes,omitempty"`
+	Summary    string           `json:"summary,omitempty"`
+}
+
+type EffectivePermissions struct {
+	UserID      uuid.UUID    `json:"user_id,omitempty"`
+	Username    string       `json:"username,omitempty"`
+	Role        UserRole     `json:"role,omitempty"`
+	Permissions []Permission `json:"permissions,omitempty"`
+}
+
+type ErrorResponse struct {
+	Error      string       `json:"error"`
+	Errors     []FieldError `json:"errors,omitempty"`
+	RetryAfter int          `json:"retry_after,omitempty"`
+	RequestID  string       `json:"request_id,omitempty"`
+}
+
+type FieldError struct {
+	Field   string `json:"field,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type HealthStatus struct {
+	Status  string `json:"status,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type Lesson struct {
+	ID              uuid.UUID            `json:"id,omitempty"`
+	SubcourseID     uuid.UUID            `json:"subcourse_id,omitempty"`
+	OrganizationID  *uuid.UUID           `json:"organization_id,omitempty"`
+	Title           string               `json:"title,omitempty"`
+	Subtitle        string               `json:"subtitle,omitempty"`
+	Overview        string               `json:"overview,omitempty"`
+	BlockTypes      json.RawMessage      `json:"block_types,omitempty"`
+	Status          ContentStatus        `json:"status,omitempty"`
+	SortOrder       int                  `json:"sort_order,omitempty"`
+	Version         int                  `json:"version,omitempty"`
+	DurationMinutes int                  `json:"duration_minutes,omitempty"`
+	Difficulty      string               `json:"difficulty,omitempty"`
+	EstimatedTime   string               `json:"estimated_time,omitempty"`
+	CoverMediaID    *uuid.UUID           `json:"cover_media_id,omitempty"`
+	AuthorID        *uuid.UUID           `json:"author_id,omitempty"`
+	IsFeatured      bool                 `json:"is_featured,omitempty"`
+	PublishedAt     *time.Time           `json:"published_at,omitempty"`
+	MediaCount      int                  `json:"media_count,omitempty"`
+	Slug            string               `json:"slug,omitempty"`
+	CreatedAt       time.Time            `json:"created_at,omitempty"`
+	UpdatedAt       time.Time            `json:"updated_at,omitempty"`
+	DeletedAt       *time.Time           `json:"deleted_at,omitempty"`
+	DeletedBy       *uuid.UUID           `json:"deleted_by,omitempty"`
+	Subcourse       *Subcourse           `json:"subcourse,omitempty"`
+	Objectives      *LessonObjective     `json:"objectives,omitempty"`
+	Models          []LessonModel        `json:"models,omitempty"`
+	Preparation     *LessonPreparation   `json:"preparation,omitempty"`
+	Builds          []LessonBuild        `json:"builds,omitempty"`
+	ContentBlocks   []LessonContentBlock `json:"content_blocks,omitempty"`
+	Attachments     []LessonAttachment   `json:"attachments,omitempty"`
+	Challenges      []LessonChallenge    `json:"challenges,omitempty"`
+	Quizzes         []LessonQuiz         `json:"quizzes,omitempty"`
+	Media           []Media              `json:"media,omitempty"`
+}
+
+type LessonAttachment struct {
+	ID          uuid.UUID `json:"id,omitempty"`
+	LessonID    uuid.UUID `json:"lesson_id,omitempty"`
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	FileType    string    `json:"file_type,omitempty"`
+	SortOrder   int       `json:"sort_order,omitempty"`
+	CreatedAt   time.Time `json:"created_at,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at,omitempty"`
+	Media       []Media   `json:"media,omitempty"`
+}
+
+type LessonBuild struct {
+	ID          uuid.UUID `json:"id,omitempty"`
+	LessonID    uuid.UUID `json:"lesson_id,omitempty"`
+	BuildType   BuildType `json:"build_type,omitempty"`
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	SortOrder   int       `json:"sort_order,omitempty"`
+	CreatedAt   time.Time `json:"created_at,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at,omitempty"`
+	Media       []Media   `json:"media,omitempty"`
+}
+
+type LessonChallenge struct {
+	ID           uuid.UUID `json:"id,omitempty"`
+	LessonID     uuid.UUID `json:"lesson_id,omitempty"`
+	Title        string    `json:"title,omitempty"`
+	Subtitle     string    `json:"subtitle,omitempty"`
+	Description  string    `json:"description,omitempty"`
+	Instructions string    `json:"instructions,omitempty"`
+	SortOrder    int       `json:"sort_order,omitempty"`
+	CreatedAt    time.Time `json:"created_at,omitempty"`
+	UpdatedAt    time.Time `json:"updated_at,omitempty"`
+	Media        []Media   `json:"media,omitempty"`
+}
+
+type LessonContentBlock struct {
+	ID          uuid.UUID `json:"id,omitempty"`
+	LessonID    uuid.UUID `json:"lesson_id,omitempty"`
+	Title       string    `json:"title,omitempty"`
+	Subtitle    string    `json:"subtitle,omitempty"`
+	Description string    `json:"description,omitempty"`
+	UsageText   string    `json:"usage_text,omitempty"`
+	ExampleText string    `json:"example_text,omitempty"`
+	SortOrder   int       `json:"sort_order,omitempty"`
+	CreatedAt   time.Time `json:"created_at,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at,omitempty"`
+	Media       []Media   `json:"media,omitempty"`
+}
+
+type LessonCreateInput struct {
+	ID              uuid.UUID            `json:"id,omitempty"`
+	SubcourseID     uuid.UUID            `json:"subcourse_id,omitempty"`
+	OrganizationID  *uuid.UUID           `json:"organization_id,omitempty"`
+	Title           string               `json:"title,omitempty"`
+	Subtitle        string               `json:"subtitle,omitempty"`
+	Overview        string               `json:"overview,omitempty"`
+	BlockTypes      json.RawMessage      `json:"block_types,omitempty"`
+	Status          ContentStatus        `json:"status,omitempty"`
+	SortOrder       int                  `json:"sort_order,omitempty"`
+	Version         int                  `json:"version,omitempty"`
+	DurationMinutes int                  `json:"duration_minutes,omitempty"`
+	Difficulty      string               `json:"difficulty,omitempty"`
+	EstimatedTime   string               `json:"estimated_time,omitempty"`
+	CoverMediaID    *uuid.UUID           `json:"cover_media_id,omitempty"`
+	AuthorID        *uuid.UUID           `json:"author_id,omitempty"`
+	IsFeatured      bool                 `json:"is_featured,omitempty"`
+	PublishedAt     *time.Time           `json:"published_at,omitempty"`
+	MediaCount      int                  `json:"media_count,omitempty"`
+	Slug            string               `json:"slug,omitempty"`
+	CreatedAt       time.Time            `json:"created_at,omitempty"`
+	UpdatedAt       time.Time            `json:"updated_at,omitempty"`
+	DeletedAt       *time.Time           `json:"deleted_at,omitempty"`
+	DeletedBy       *uuid.UUID           `json:"deleted_by,omitempty"`
+	Subcourse       *Subcourse           `json:"subcourse,omitempty"`
+	Objectives      *LessonObjective     `json:"objectives,omitempty"`
+	Models          []LessonModel        `json:"models,omitempty"`
+	Preparation     *LessonPreparation   `json:"preparation,omitempty"`
+	Builds          []LessonBuild        `json:"builds,omitempty"`
+	ContentBlocks   []LessonContentBlock `json:"content_blocks,omitempty"`
+	Attachments     []LessonAttachment   `json:"attachments,omitempty"`
+	Challenges      []LessonChallenge    `json:"challenges,omitempty"`
+	Quizzes         []LessonQuiz         `json:"quizzes,omitempty"`
+	Media           []Media              `json:"media,omitempty"`
+	TemplateID      *uuid.UUID           `json:"template_id,omitempty"`
+}
+
+type LessonHistoryItem struct {
+	ID          uuid.UUID `json:"id,omitempty"`
+	Title       string    `json:"title,omitempty"`
+	Slug        string    `json:"slug,omitempty"`
+	SubcourseID uuid.UUID `json:"subcourse_id,omitempty"`
+	Status      string    `json:"status,omitempty"`
+	CreatedAt   time.Time `json:"created_at,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at,omitempty"`
+}
+
+type LessonModel struct {
+	ID          uuid.UUID `json:"id,omitempty"`
+	LessonID    uuid.UUID `json:"lesson_id,omitempty"`
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	SortOrder   int       `json:"sort_order,omitempty"`
+	CreatedAt   time.Time `json:"created_at,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at,omitempty"`
+	Media       []Media   `json:"media,omitempty"`
+}
+
+type LessonObjective struct {
+	ID        uuid.UUID `json:"id,omitempty"`
+	LessonID  uuid.UUID `json:"lesson_id,omitempty"`
+	Knowledge string    `json:"knowledge,omitempty"`
+	Thinking  string    `json:"thinking,omitempty"`
+	Skills    string    `json:"skills,omitempty"`
+	Attitude  string    `json:"attitude,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+type LessonPreparation struct {
+	ID        uuid.UUID `json:"id,omitempty"`
+	LessonID  uuid.UUID `json:"lesson_id,omitempty"`
+	Notes     string    `json:"notes,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+	Media     []Media   `json:"media,omitempty"`
+}
+
+type LessonQuiz struct {
+	ID          uuid.UUID          `json:"id,omitempty"`
+	LessonID    uuid.UUID          `json:"lesson_id,omitempty"`
+	Title       string             `json:"title,omitempty"`
+	Description string             `json:"description,omitempty"`
+	QuizType    QuizType           `json:"quiz_type,omitempty"`
+	SortOrder   int                `json:"sort_order,omitempty"`
+	CreatedAt   time.Time          `json:"created_at,omitempty"`
+	UpdatedAt   time.Time          `json:"updated_at,omitempty"`
+	Options     []LessonQuizOption `json:"options,omitempty"`
+}
+
+type LessonQuizOption struct {
+	ID          uuid.UUID `json:"id,omitempty"`
+	QuizID      uuid.UUID `json:"quiz_id,omitempty"`
+	Content     string    `json:"content,omitempty"`
+	IsCorrect   bool      `json:"is_correct,omitempty"`
+	Explanation string    `json:"explanation,omitempty"`
+	SortOrder   int       `json:"sort_order,omitempty"`
+	CreatedAt   time.Time `json:"created_at,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at,omitempty"`
+}
+
+type LessonStatusInput struct {
+	Status ContentStatus `json:"status"`
+}
+
+type LessonSummary struct {
+	ID              uuid.UUID     `json:"id,omitempty"`
+	SubcourseID     uuid.UUID     `json:"subcourse_id,omitempty"`
+	SubcourseName   string        `json:"subcourse_name,omitempty"`
+	ProgramID       uuid.UUID     `json:"program_id,omitempty"`
+	ProgramName     string        `json:"program_name,omitempty"`
+	Title           string        `json:"title,omitempty"`
+	Subtitle        string        `json:"subtitle,omitempty"`
+	Slug            string        `json:"slug,omitempty"`
+	Status          ContentStatus `json:"status,omitempty"`
+	SortOrder       int           `json:"sort_order,omitempty"`
+	Version         int           `json:"version,omitempty"`
+	DurationMinutes int           `json:"duration_minutes,omitempty"`
+	Difficulty      string        `json:"difficulty,omitempty"`
+	IsFeatured      bool          `json:"is_featured,omitempty"`
+	MediaCount      int           `json:"media_count,omitempty"`
+	CoverURL        string        `json:"cover_url,omitempty"`
+	PublishedAt     *time.Time    `json:"published_at,omitempty"`
+	UpdatedAt       time.Time     `json:"updated_at,omitempty"`
+}
+
+type LessonTemplate struct {
+	ID             uuid.UUID       `json:"id,omitempty"`
+	ProgramID      uuid.UUID       `json:"program_id,omitempty"`
+	OrganizationID *uuid.UUID      `json:"organization_id,omitempty"`
+	Name           string          `json:"name,omitempty"`
+	Description    string          `json:"description,omitempty"`
+	Blueprint      json.RawMessage `json:"blueprint,omitempty"`
+	CreatedBy      *uuid.UUID      `json:"created_by,omitempty"`
+	CreatedAt      time.Time       `json:"created_at,omitempty"`
+	UpdatedAt      time.Time       `json:"updated_at,omitempty"`
+	Program        *Program        `json:"program,omitempty"`
+}
+
+type LoginAttempt struct {
+	ID         uuid.UUID  `json:"id,omitempty"`
+	UserID     *uuid.UUID `json:"user_id,omitempty"`
+	Identifier string     `json:"identifier,omitempty"`
+	IP         string     `json:"ip,omitempty"`
+	UserAgent  string     `json:"user_agent,omitempty"`
+	Success    bool       `json:"success,omitempty"`
+	Reason     string     `json:"reason,omitempty"`
+	CreatedAt  time.Time  `json:"created_at,omitempty"`
+}
+
+type LoginHistory struct {
+	Attempts []LoginAttempt `json:"attempts,omitempty"`
+	Lockout  *LoginThrottle `json:"lockout,omitempty"`
+}
+
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type LoginResponse struct {
+	Token              string       `json:"token,omitempty"`
+	User               *UserProfile `json:"user,omitempty"`
+	MFARequired        bool         `json:"mfa_required,omitempty"`
+	EnrollmentRequired bool         `json:"enrollment_required,omitempty"`
+	ChallengeToken     string       `json:"challenge_token,omitempty"`
+	ExpiresIn          int          `json:"expires_in,omitempty"`
+}
+
+type LoginThrottle struct {
+	Key           string     `json:"key,omitempty"`
+	Scope         string     `json:"scope,omitempty"`
+	Failures      int        `json:"failures,omitempty"`
+	LastFailureAt *time.Time `json:"last_failure_at,omitempty"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	LockedUntil   *time.Time `json:"locked_until,omitempty"`
+	UpdatedAt     time.Time  `json:"updated_at,omitempty"`
+}
+
+type Media struct {
+	ID        uuid.UUID       `json:"id,omitempty"`
+	OwnerType MediaOwnerType  `json:"owner_type,omitempty"`
+	OwnerID   uuid.UUID       `json:"owner_id,omitempty"`
+	URL       string          `json:"url,omitempty"`
+	MimeType  string          `json:"mime_type,omitempty"`
+	Purpose   MediaPurpose    `json:"purpose,omitempty"`
+	SortOrder int             `json:"sort_order,omitempty"`
+	Meta      json.RawMessage `json:"meta,omitempty"`
+	CreatedAt time.Time       `json:"created_at,omitempty"`
+	UpdatedAt time.Time       `json:"updated_at,omitempty"`
+}
+
+type MediaOrderInput struct {
+	OwnerType MediaOwnerType `json:"owner_type"`
+	OwnerID   uuid.UUID      `json:"owner_id"`
+	IDs       []uuid.UUID    `json:"ids"`
+}
+
+type MediaOwnerType string
+
+const (
+	MediaOwnerTypeProgram            MediaOwnerType = "program"
+	MediaOwnerTypeSubcourse          MediaOwnerType = "subcourse"
+	MediaOwnerTypeLesson             MediaOwnerType = "lesson"
+	MediaOwnerTypeLessonModel        MediaOwnerType = "lesson_model"
+	MediaOwnerTypeLessonPreparation  MediaOwnerType = "lesson_preparation"
+	MediaOwnerTypeLessonBuild        MediaOwnerType = "lesson_build"
+	MediaOwnerTypeLessonContentBlock MediaOwnerType = "lesson_content_block"
+	MediaOwnerTypeLessonAttachment   MediaOwnerType = "lesson_attachment"
+	MediaOwnerTypeLessonChallenge    MediaOwnerType = "lesson_challenge"
+)
+
+type MediaPurpose string
+
+const (
+	MediaPurposeCover   MediaPurpose = "cover"
+	MediaPurposeIntro   MediaPurpose = "intro"
+	MediaPurposeMain    MediaPurpose = "main"
+	MediaPurposeGallery MediaPurpose = "gallery"
+	MediaPurposeSlide   MediaPurpose = "slide"
+	MediaPurposeOther   MediaPurpose = "other"
+)
+
+type MediaUploadForm struct {
+	OwnerType MediaOwnerType `json:"owner_type"`
+	OwnerID   uuid.UUID      `json:"owner_id"`
+	Purpose   MediaPurpose   `json:"purpose,omitempty"`
+	File      []File         `json:"file"`
+}
+
+type MediaUploadResponse struct {
+	Media []Media `json:"media,omitempty"`
+}
+
+type MessageResponse struct {
+	Message string `json:"message,omitempty"`
+}
+
+type MoveInput struct {
+	ProgramID   uuid.UUID `json:"program_id,omitempty"`
+	SubcourseID uuid.UUID `json:"subcourse_id,omitempty"`
+	Position    *int      `json:"position,omitempty"`
+}
+
+type Operation struct {
+	Op    string          `json:"op,omitempty"`
+	Path  string          `json:"path,omitempty"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+type Organization struct {
+	ID        uuid.UUID          `json:"id,omitempty"`
+	Name      string             `json:"name,omitempty"`
+	Slug      string             `json:"slug,omitempty"`
+	Status    OrganizationStatus `json:"status,omitempty"`
+	CreatedAt time.Time          `json:"created_at,omitempty"`
+	UpdatedAt time.Time          `json:"updated_at,omitempty"`
+}
+
+type OrganizationInput struct {
+	Name   string             `json:"name,omitempty"`
+	Slug   string             `json:"slug,omitempty"`
+	Status OrganizationStatus `json:"status,omitempty"`
+}
+
+type OrganizationStatus string
+
+const (
+	OrganizationStatusActive    OrganizationStatus = "active"
+	OrganizationStatusSuspended OrganizationStatus = "suspended"
+)
+
+type PasswordLoginInput struct {
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+type Permission string
+
+const (
+	PermissionProgramRead        Permission = "program.read"
+	PermissionProgramCreate      Permission = "program.create"
+	PermissionProgramWrite       Permission = "program.write"
+	PermissionProgramDelete      Permission = "program.delete"
+	PermissionSubcourseRead      Permission = "subcourse.read"
+	PermissionSubcourseWrite     Permission = "subcourse.write"
+	PermissionSubcourseDelete    Permission = "subcourse.delete"
+	PermissionLessonRead         Permission = "lesson.read"
+	PermissionLessonWrite        Permission = "lesson.write"
+	PermissionLessonDelete       Permission = "lesson.delete"
+	PermissionLessonPublish      Permission = "lesson.publish"
+	PermissionMediaUpload        Permission = "media.upload"
+	PermissionTeacherRead        Permission = "teacher.read"
+	PermissionTeacherManage      Permission = "teacher.manage"
+	PermissionUserManage         Permission = "user.manage"
+	PermissionPermissionRead     Permission = "permission.read"
+	PermissionSystemSeed         Permission = "system.seed"
+	PermissionSystemHealth       Permission = "system.health"
+	PermissionOrganizationRead   Permission = "organization.read"
+	PermissionOrganizationManage Permission = "organization.manage"
+	PermissionScopeAll           Permission = "scope.all"
+)
+
+type ProbeReport struct {
+	Status string        `json:"status,omitempty"`
+	Checks []CheckResult `json:"checks,omitempty"`
+}
+
+type Program struct {
+	ID                      uuid.UUID       `json:"id,omitempty"`
+	OrganizationID          *uuid.UUID      `json:"organization_id,omitempty"`
+	Name                    string          `json:"name,omitempty"`
+	Slug                    string          `json:"slug,omitempty"`
+	ShortDescription        string          `json:"short_description,omitempty"`
+	Description             string          `json:"description,omitempty"`
+	BlockTypes              json.RawMessage `json:"block_types,omitempty"`
+	Status                  ContentStatus   `json:"status,omitempty"`
+	SortOrder               int             `json:"sort_order,omitempty"`
+	SubcourseCount          int             `json:"subcourse_count,omitempty"`
+	PublishedSubcourseCount int             `json:"published_subcourse_count,omitempty"`
+	LessonCount             int             `json:"lesson_count,omitempty"`
+	PublishedLessonCount    int             `json:"published_lesson_count,omitempty"`
+	TotalDurationMinutes    int             `json:"total_duration_minutes,omitempty"`
+	MediaCount              int             `json:"media_count,omitempty"`
+	Version                 int             `json:"version,omitempty"`
+	CreatedAt               time.Time       `json:"created_at,omitempty"`
+	UpdatedAt               time.Time       `json:"updated_at,omitempty"`
+	DeletedAt               *time.Time      `json:"deleted_at,omitempty"`
+	DeletedBy               *uuid.UUID      `json:"deleted_by,omitempty"`
+	Subcourses              []Subcourse     `json:"subcourses,omitempty"`
+	Media                   []Media         `json:"media,omitempty"`
+}
+
+type ProgramAssignInput struct {
+	ProgramIDs []string   `json:"program_ids,omitempty"`
+	StartAt    *time.Time `json:"start_at,omitempty"`
+	EndAt      *time.Time `json:"end_at,omitempty"`
+}
+
+type ProgramShare struct {
+	ID             uuid.UUID     `json:"id,omitempty"`
+	ProgramID      uuid.UUID     `json:"program_id,omitempty"`
+	OrganizationID uuid.UUID     `json:"organization_id,omitempty"`
+	CreatedAt      time.Time     `json:"created_at,omitempty"`
+	Organization   *Organization `json:"organization,omitempty"`
+}
+
+type ProgramShareInput struct {
+	Organization string `json:"organization"`
+}
+
+type QuizType string
+
+const (
+	QuizTypeSingle   QuizType = "single"
+	QuizTypeMultiple QuizType = "multiple"
+	QuizTypeOpen     QuizType = "open"
+)
+
+type RecoveryCodesRequest struct {
+	Code string `json:"code,omitempty"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+type ReorderInput struct {
+	IDs []uuid.UUID `json:"ids"`
+}
+
+type RolePermissions struct {
+	Role        UserRole     `json:"role,omitempty"`
+	Permissions []Permission `json:"permissions,omitempty"`
+}
+
+type RolesResponse struct {
+	Roles       []RolePermissions `json:"roles,omitempty"`
+	Permissions []Permission      `json:"permissions,omitempty"`
+}
+
+type SetRoleInput struct {
+	Role           UserRole   `json:"role"`
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty"`
+}
+
+type Subcourse struct {
+	ID                   uuid.UUID       `json:"id,omitempty"`
+	ProgramID            uuid.UUID       `json:"program_id,omitempty"`
+	OrganizationID       *uuid.UUID      `json:"organization_id,omitempty"`
+	Name                 string          `json:"name,omitempty"`
+	Slug                 string          `json:"slug,omitempty"`
+	AgeRange             string          `json:"age_range,omitempty"`
+	ShortDescription     string          `json:"short_description,omitempty"`
+	GeneralObjectives    string          `json:"general_objectives,omitempty"`
+	BlockTypes           json.RawMessage `json:"block_types,omitempty"`
+	Status               ContentStatus   `json:"status,omitempty"`
+	SortOrder            int             `json:"sort_order,omitempty"`
+	LessonCount          int             `json:"lesson_count,omitempty"`
+	PublishedLessonCount int             `json:"published_lesson_count,omitempty"`
+	TotalDurationMinutes int             `json:"total_duration_minutes,omitempty"`
+	MediaCount           int             `json:"media_count,omitempty"`
+	Version              int             `json:"version,omitempty"`
+	CreatedAt            time.Time       `json:"created_at,omitempty"`
+	UpdatedAt            time.Time       `json:"updated_at,omitempty"`
+	DeletedAt            *time.Time      `json:"deleted_at,omitempty"`
+	DeletedBy            *uuid.UUID      `json:"deleted_by,omitempty"`
+	Program              *Program        `json:"program,omitempty"`
+	Lessons              []Lesson        `json:"lessons,omitempty"`
+	Media                []Media         `json:"media,omitempty"`
+}
+
+type SubcourseAssignInput struct {
+	SubcourseIDs []string   `json:"subcourse_ids,omitempty"`
+	StartAt      *time.Time `json:"start_at,omitempty"`
+	EndAt        *time.Time `json:"end_at,omitempty"`
+}
+
+type TOTPSetupResponse struct {
+	Secret          string `json:"secret,omitempty"`
+	ProvisioningURI string `json:"provisioning_uri,omitempty"`
+}
+
+type TeacherAssignment struct {
+	ID             uuid.UUID  `json:"id,omitempty"`
+	TeacherID      uuid.UUID  `json:"teacher_id,omitempty"`
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty"`
+	ProgramID      *uuid.UUID `json:"program_id,omitempty"`
+	SubcourseID    *uuid.UUID `json:"subcourse_id,omitempty"`
+	ScopeLevel     string     `json:"scope_level,omitempty"`
+	Status         string     `json:"status,omitempty"`
+	CodeExpiresAt  *time.Time `json:"code_expires_at,omitempty"`
+	StartAt        *time.Time `json:"start_at,omitempty"`
+	EndAt          *time.Time `json:"end_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at,omitempty"`
+	UpdatedAt      time.Time  `json:"updated_at,omitempty"`
+	Teacher        *User      `json:"teacher,omitempty"`
+}
+
+type TeacherLessonHistory struct {
+	TeacherID   uuid.UUID `json:"teacher_id,omitempty"`
+	TeacherName string    `json:"teacher_name,omitempty"`
+	LessonID    uuid.UUID `json:"lesson_id,omitempty"`
+	LessonTitle string    `json:"lesson_title,omitempty"`
+	LessonSlug  string    `json:"lesson_slug,omitempty"`
+	CreatedAt   time.Time `json:"created_at,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at,omitempty"`
+	Status      string    `json:"status,omitempty"`
+}
+
+type TemplateInput struct {
+	Name        string          `json:"name,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Blueprint   json.RawMessage `json:"blueprint,omitempty"`
+}
+
+type TrashItem struct {
+	Type          string     `json:"type,omitempty"`
+	ID            uuid.UUID  `json:"id,omitempty"`
+	Title         string     `json:"title,omitempty"`
+	Slug          string     `json:"slug,omitempty"`
+	ParentID      *uuid.UUID `json:"parent_id,omitempty"`
+	ParentTrashed bool       `json:"parent_trashed,omitempty"`
+	DeletedAt     time.Time  `json:"deleted_at,omitempty"`
+	DeletedBy     *uuid.UUID `json:"deleted_by,omitempty"`
+	DeletedByName string     `json:"deleted_by_name,omitempty"`
+	PurgeAt       time.Time  `json:"purge_at,omitempty"`
+}
+
+type TrashList struct {
+	Items         []TrashItem `json:"items,omitempty"`
+	RetentionDays int         `json:"retention_days,omitempty"`
+}
+
+type TwoFactorDisableRequest struct {
+	Password     string `json:"password,omitempty"`
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+type TwoFactorEnableRequest struct {
+	ChallengeToken string `json:"challenge_token,omitempty"`
+	Code           string `json:"code,omitempty"`
+}
+
+type TwoFactorResponse struct {
+	Enabled       bool     `json:"enabled,omitempty"`
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+	Token         string   `json:"token,omitempty"`
+	User          *User    `json:"user,omitempty"`
+}
+
+type TwoFactorSetupRequest struct {
+	ChallengeToken string `json:"challenge_token,omitempty"`
+}
+
+type TwoFactorStatus struct {
+	Enabled                bool  `json:"enabled,omitempty"`
+	Required               bool  `json:"required,omitempty"`
+	RecoveryCodesRemaining int64 `json:"recovery_codes_remaining,omitempty"`
+}
+
+type TwoFactorVerifyRequest struct {
+	ChallengeToken string `json:"challenge_token,omitempty"`
+	Code           string `json:"code,omitempty"`
+	RecoveryCode   string `json:"recovery_code,omitempty"`
+}
+
+type User struct {
+	ID                    uuid.UUID  `json:"id,omitempty"`
+	Username              string     `json:"username,omitempty"`
+	Email                 string     `json:"email,omitempty"`
+	Role                  UserRole   `json:"role,omitempty"`
+	OrganizationID        *uuid.UUID `json:"organization_id,omitempty"`
+	Status                string     `json:"status,omitempty"`
+	PasswordLoginDisabled bool       `json:"password_login_disabled,omitempty"`
+	TOTPEnabled           bool       `json:"totp_enabled,omitempty"`
+	CreatedAt             time.Time  `json:"created_at,omitempty"`
+	UpdatedAt             time.Time  `json:"updated_at,omitempty"`
+}
+
+type UserIdentity struct {
+	ID          uuid.UUID  `json:"id,omitempty"`
+	UserID      uuid.UUID  `json:"user_id,omitempty"`
+	Issuer      string     `json:"issuer,omitempty"`
+	Subject     string     `json:"subject,omitempty"`
+	Email       string     `json:"email,omitempty"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at,omitempty"`
+	UpdatedAt   time.Time  `json:"updated_at,omitempty"`
+}
+
+type UserProfile struct {
+	ID                    uuid.UUID           `json:"id,omitempty"`
+	Username              string              `json:"username,omitempty"`
+	Email                 string              `json:"email,omitempty"`
+	Role                  UserRole            `json:"role,omitempty"`
+	OrganizationID        *uuid.UUID          `json:"organization_id,omitempty"`
+	Status                string              `json:"status,omitempty"`
+	PasswordLoginDisabled bool                `json:"password_login_disabled,omitempty"`
+	TOTPEnabled           bool                `json:"totp_enabled,omitempty"`
+	CreatedAt             time.Time           `json:"created_at,omitempty"`
+	UpdatedAt             time.Time           `json:"updated_at,omitempty"`
+	Assignments           []TeacherAssignment `json:"assignments,omitempty"`
+}
+
+type UserRole string
+
+const (
+	UserRoleSuperAdmin UserRole = "super_admin"
+	UserRoleAdmin      UserRole = "admin"
+	UserRoleTeacher    UserRole = "teacher"
+	UserRoleReviewer   UserRole = "reviewer"
+	UserRoleViewer     UserRole = "viewer"
+)
+
+// AssignPrograms calls PUT /api/admin/teachers/{id}/program-assignments: replace a teacher's program assignments
+// It requires the teacher.manage permission.
+func (c *Client) AssignPrograms(ctx context.Context, id string, body *ProgramAssignInput, opts ...RequestOption) (*AssignmentsResponse, error) {
+	out := new(AssignmentsResponse)
+	if err := c.do(ctx, http.MethodPut, "/api/admin/teachers/"+url.PathEscape(id)+"/program-assignments", nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AssignSubcourses calls PUT /api/admin/teachers/{id}/subcourse-assignments: replace a teacher's subcourse assignments
+// It requires the teacher.manage permission.
+func (c *Client) AssignSubcourses(ctx context.Context, id string, body *SubcourseAssignInput, opts ...RequestOption) (*AssignmentsResponse, error) {
+	out := new(AssignmentsResponse)
+	if err := c.do(ctx, http.MethodPut, "/api/admin/teachers/"+url.PathEscape(id)+"/subcourse-assignments", nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CloneLesson calls POST /api/admin/lessons/{id}/clone: deep-copy a lesson, optionally into another subcourse
+// It requires the lesson.write permission.
+func (c *Client) CloneLesson(ctx context.Context, id string, body *CloneInput, opts ...RequestOption) (*Lesson, error) {
+	out := new(Lesson)
+	if err := c.do(ctx, http.MethodPost, "/api/admin/lessons/"+url.PathEscape(id)+"/clone", nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CloneProgram calls POST /api/admin/programs/{id}/clone: deep-copy a program into the caller's organization
+// It requires the program.create permission.
+func (c *Client) CloneProgram(ctx context.Context, id string, body *CloneInput, opts ...RequestOption) (*Program, error) {
+	out := new(Program)
+	if err := c.do(ctx, http.MethodPost, "/api/admin/programs/"+url.PathEscape(id)+"/clone", nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CloneSubcourse calls POST /api/admin/subcourses/{id}/clone: deep-copy a subcourse, optionally into another program
+// It requires the subcourse.write permission.
+func (c *Client) CloneSubcourse(ctx context.Context, id string, body *CloneInput, opts ...RequestOption) (*Subcourse, error) {
+	out := new(Subcourse)
+	if err := c.do(ctx, http.MethodPost, "/api/admin/subcourses/"+url.PathEscape(id)+"/clone", nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateLesson calls POST /api/admin/lessons: create a lesson with all of its components
+// It requires the lesson.write permission.
+func (c *Client) CreateLesson(ctx context.Context, body *LessonCreateInput, opts ...RequestOption) (*Lesson, error) {
+	out := new(Lesson)
+	if err := c.do(ctx, http.MethodPost, "/api/admin/lessons", nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateLessonAttachment calls POST /api/admin/lessons/{id}/attachments: add to attachments
+// It requires the lesson.write permission.
+func (c *Client) CreateLessonAttachment(ctx context.Context, id string, body *LessonAttachment, opts ...RequestOption) (*LessonAttachment, error) {
+	out := new(LessonAttachment)
+	if err := c.do(ctx, http.MethodPost, "/api/admin/lessons/"+url.PathEscape(id)+"/attachments", nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateLessonBuild calls POST /api/admin/lessons/{id}/builds: add to builds
+// It requires the lesson.write permission.
+func (c *Client) CreateLessonBuild(ctx context.Context, id string, body *LessonBuild, opts ...RequestOption) (*LessonBuild, error) {
+	out := new(LessonBuild)
+	if err := c.do(ctx, http.MethodPost, "/api/admin/lessons/"+url.PathEscape(id)+"/builds", nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateLessonChallenge calls POST /api/admin/lessons/{id}/challenges: add to challenges
+// It requires the lesson.write permission.
+func (c *Client) CreateLessonChallenge(ctx context.Context, id string, body *LessonChallenge, opts ...RequestOption) (*LessonChallenge, error) {
+	out := new(LessonChallenge)
+	if err := c.do(ctx, http.MethodPost, "/api/admin/lessons/"+url.PathEscape(id)+"/challenges", nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateLessonContentBlock calls POST /api/admin/lessons/{id}/content-blocks: add to content blocks
+// It requires the lesson.write permission.
+func (c *Client) CreateLessonContentBlock(ctx context.Context, id string, body *LessonContentBlock, opts ...RequestOption) (*LessonContentBlock, error) {
+	out := new(LessonContentBlock)
+	if err := c.do(ctx, http.MethodPost, "/api/admin/lessons/"+url.PathEscape(id)+"/content-blocks", nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateLessonModel calls POST /api/admin/lessons/{id}/models: add to models
+// It requires the lesson.write permission.
+func (c *Client) CreateLessonModel(ctx context.Context, id string, body *LessonModel, opts ...RequestOption) (*LessonModel, error) {
+	out := new(LessonModel)
+	if err := c.do(ctx, http.MethodPost, "/api/admin/lessons/"+url.PathEscape(id)+"/models", nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateLessonQuiz calls POST /api/admin/lessons/{id}/quizzes: add to quizzes
+// It requires the lesson.write permission.
+func (c *Client) CreateLessonQuiz(ctx context.Context, id string, body *LessonQuiz, opts ...RequestOption) (*LessonQuiz, error) {
+	out := new(LessonQuiz)
+	if err := c.do(ctx, http.MethodPost, "/api/admin/lessons/"+url.PathEscape(id)+"/quizzes", nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateLessonQuizOption calls POST /api/admin/lessons/{id}/quizzes/{quizId}/options: add to options
+// It requires the lesson.write permission.
+func (c *Client) CreateLessonQuizOption(ctx context.Context, id, quizId string, body *LessonQuizOption, opts ...RequestOption) (*LessonQuizOption, error) {
+	out := new(LessonQuizOption)
+	if err := c.do(ctx, http.MethodPost, "/api/admin/lessons/"+url.PathEscape(id)+"/quizzes/"+url.PathEscape(quizId)+"/options", nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateOrganization calls POST /api/admin/organizations: create an organization
+// It requires the organization.manage permission.
+func (c *Client) CreateOrganization(ctx context.Context, body *OrganizationInput, opts ...RequestOption) (*Organization, error) {
+	out := new(Organization)
+	if err := c.do(ctx, http.MethodPost, "/api/admin/organizations", nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateProgram calls POST /api/admin/programs: create a program
+// It requires the program.create permission.
+func (c *Client) CreateProgram(ctx context.Context, body *Program, opts ...RequestOption) (*Program, error) {
+	out := new(Program)
+	if err := c.do(ctx, http.MethodPost, "/api/admin/programs", nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateSubcourse calls POST /api/admin/subcourses: create a subcourse
+// It requires the subcourse.write permission.
+func (c *Client) CreateSubcourse(ctx context.Context, body *Subcourse, opts ...RequestOption) (*Subcourse, error) {
+	out := new(Subcourse)
+	if err := c.do(ctx, http.MethodPost, "/api/admin/subcourses", nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateTeacher calls POST /api/admin/teachers: create a teacher account
+// It requires the teacher.manage permission.
+func (c *Client) CreateTeacher(ctx context.Context, body *CreateTeacherInput, opts ...RequestOption) (*User, error) {
+	out := new(User)
+	if err := c.do(ctx, http.MethodPost, "/api/admin/teachers", nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateTemplate calls POST /api/admin/programs/{programId}/templates: create a lesson template
+// It requires the program.write permission.
+func (c *Client) CreateTemplate(ctx context.Context, programId string, body *TemplateInput, opts ...RequestOption) (*LessonTemplate, error) {
+	out := new(LessonTemplate)
+	if err := c.do(ctx, http.MethodPost, "/api/admin/programs/"+url.PathEscape(programId)+"/templates", nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeleteLesson calls DELETE /api/admin/lessons/{id}: move a lesson to the trash
+// It requires the lesson.delete permission.
+// Pass IfMatch with the version the change is based on; the server answers
+// 428 without it.
+func (c *Client) DeleteLesson(ctx context.Context, id string, opts ...RequestOption) (*MessageResponse, error) {
+	out := new(MessageResponse)
+	if err := c.do(ctx, http.MethodDelete, "/api/admin/lessons/"+url.PathEscape(id), nil, "", nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeleteLessonAttachment calls DELETE /api/admin/lessons/{id}/attachments/{componentId}: remove one of attachments
+// It requires the lesson.write permission.
+func (c *Client) DeleteLessonAttachment(ctx context.Context, id, componentId string, opts ...RequestOption) error {
+	return c.do(ctx, http.MethodDelete, "/api/admin/lessons/"+url.PathEscape(id)+"/attachments/"+url.PathEscape(componentId), nil, "", nil, nil, opts)
+}
+
+// DeleteLessonBuild calls DELETE /api/admin/lessons/{id}/builds/{componentId}: remove one of builds
+// It requires the lesson.write permission.
+func (c *Client) DeleteLessonBuild(ctx context.Context, id, componentId string, opts ...RequestOption) error {
+	return c.do(ctx, http.MethodDelete, "/api/admin/lessons/"+url.PathEscape(id)+"/builds/"+url.PathEscape(componentId), nil, "", nil, nil, opts)
+}
+
+// DeleteLessonChallenge calls DELETE /api/admin/lessons/{id}/challenges/{componentId}: remove one of challenges
+// It requires the lesson.write permission.
+func (c *Client) DeleteLessonChallenge(ctx context.Context, id, componentId string, opts ...RequestOption) error {
+	return c.do(ctx, http.MethodDelete, "/api/admin/lessons/"+url.PathEscape(id)+"/challenges/"+url.PathEscape(componentId), nil, "", nil, nil, opts)
+}
+
+// DeleteLessonContentBlock calls DELETE /api/admin/lessons/{id}/content-blocks/{componentId}: remove one of content blocks
+// It requires the lesson.write permission.
+func (c *Client) DeleteLessonContentBlock(ctx context.Context, id, componentId string, opts ...RequestOption) error {
+	return c.do(ctx, http.MethodDelete, "/api/admin/lessons/"+url.PathEscape(id)+"/content-blocks/"+url.PathEscape(componentId), nil, "", nil, nil, opts)
+}
+
+// DeleteLessonModel calls DELETE /api/admin/lessons/{id}/models/{componentId}: remove one of models
+// It requires the lesson.write permission.
+func (c *Client) DeleteLessonModel(ctx context.Context, id, componentId string, opts ...RequestOption) error {
+	return c.do(ctx, http.MethodDelete, "/api/admin/lessons/"+url.PathEscape(id)+"/models/"+url.PathEscape(componentId), nil, "", nil, nil, opts)
+}
+
+// DeleteLessonObjective calls DELETE /api/admin/lessons/{id}/objectives: remove the lesson's objectives
+// It requires the lesson.write permission.
+func (c *Client) DeleteLessonObjective(ctx context.Context, id string, opts ...RequestOption) error {
+	return c.do(ctx, http.MethodDelete, "/api/admin/lessons/"+url.PathEscape(id)+"/objectives", nil, "", nil, nil, opts)
+}
+
+// DeleteLessonPreparation calls DELETE /api/admin/lessons/{id}/preparation: remove the lesson's preparation
+// It requires the lesson.write permission.
+func (c *Client) DeleteLessonPreparation(ctx context.Context, id string, opts ...RequestOption) error {
+	return c.do(ctx, http.MethodDelete, "/api/admin/lessons/"+url.PathEscape(id)+"/preparation", nil, "", nil, nil, opts)
+}
+
+// DeleteLessonQuiz calls DELETE /api/admin/lessons/{id}/quizzes/{componentId}: remove one of quizzes
+// It requires the lesson.write permission.
+func (c *Client) DeleteLessonQuiz(ctx context.Context, id, componentId string, opts ...RequestOption) error {
+	return c.do(ctx, http.MethodDelete, "/api/admin/lessons/"+url.PathEscape(id)+"/quizzes/"+url.PathEscape(componentId), nil, "", nil, nil, opts)
+}
+
+// DeleteLessonQuizOption calls DELETE /api/admin/lessons/{id}/quizzes/{quizId}/options/{componentId}: remove one of options
+// It requires the lesson.write permission.
+func (c *Client) DeleteLessonQuizOption(ctx context.Context, id, quizId, componentId string, opts ...RequestOption) error {
+	return c.do(ctx, http.MethodDelete, "/api/admin/lessons/"+url.PathEscape(id)+"/quizzes/"+url.PathEscape(quizId)+"/options/"+url.PathEscape(componentId), nil, "", nil, nil, opts)
+}
+
+// DeleteProgram calls DELETE /api/admin/programs/{id}: move a program and its content to the trash
+// It requires the program.delete permission.
+// Pass IfMatch with the version the change is based on; the server answers
+// 428 without it.
+func (c *Client) DeleteProgram(ctx context.Context, id string, opts ...RequestOption) (*MessageResponse, error) {
+	out := new(MessageResponse)
+	if err := c.do(ctx, http.MethodDelete, "/api/admin/programs/"+url.PathEscape(id), nil, "", nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeleteSubcourse calls DELETE /api/admin/subcourses/{id}: move a subcourse and its lessons to the trash
+// It requires the subcourse.delete permission.
+// Pass IfMatch with the version the change is based on; the server answers
+// 428 without it.
+func (c *Client) DeleteSubcourse(ctx context.Context, id string, opts ...RequestOption) error {
+	return c.do(ctx, http.MethodDelete, "/api/admin/subcourses/"+url.PathEscape(id), nil, "", nil, nil, opts)
+}
+
+// DeleteTemplate calls DELETE /api/admin/templates/{id}: delete a lesson template
+// It requires the program.write permission.
+func (c *Client) DeleteTemplate(ctx context.Context, id string, opts ...RequestOption) error {
+	return c.do(ctx, http.MethodDelete, "/api/admin/templates/"+url.PathEscape(id), nil, "", nil, nil, opts)
+}
+
+// DisableTwoFactor calls POST /api/auth/2fa/disable: turn TOTP off
+func (c *Client) DisableTwoFactor(ctx context.Context, body *TwoFactorDisableRequest, opts ...RequestOption) (*TwoFactorResponse, error) {
+	out := new(TwoFactorResponse)
+	if err := c.do(ctx, http.MethodPost, "/api/auth/2fa/disable", nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// EnableTwoFactor calls POST /api/auth/2fa/enable: confirm TOTP enrollment; returns the recovery codes once
+func (c *Client) EnableTwoFactor(ctx context.Context, body *TwoFactorEnableRequest, opts ...RequestOption) (*TwoFactorResponse, error) {
+	out := new(TwoFactorResponse)
+	if err := c.do(ctx, http.MethodPost, "/api/auth/2fa/enable", nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetCurrentOrganization calls GET /api/admin/organization: the organization the caller works in
+// It requires the organization.read permission.
+func (c *Client) GetCurrentOrganization(ctx context.Context, opts ...RequestOption) (*Organization, error) {
+	out := new(Organization)
+	if err := c.do(ctx, http.MethodGet, "/api/admin/organization", nil, "", nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetLesson calls GET /api/admin/lessons/{id}: a lesson with all of its components
+// It requires the lesson.read permission.
+func (c *Client) GetLesson(ctx context.Context, id string, opts ...RequestOption) (*Lesson, error) {
+	out := new(Lesson)
+	if err := c.do(ctx, http.MethodGet, "/api/admin/lessons/"+url.PathEscape(id), nil, "", nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetLessonDeleteImpact calls GET /api/admin/lessons/{id}/delete-impact: what deleting a lesson takes along
+// It requires the lesson.delete permission.
+func (c *Client) GetLessonDeleteImpact(ctx context.Context, id string, opts ...RequestOption) (*DeleteImpact, error) {
+	out := new(DeleteImpact)
+	if err := c.do(ctx, http.MethodGet, "/api/admin/lessons/"+url.PathEscape(id)+"/delete-impact", nil, "", nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetLessonObjective calls GET /api/admin/lessons/{id}/objectives: the lesson's objectives
+// It requires the lesson.read permission.
+func (c *Client) GetLessonObjective(ctx context.Context, id string, opts ...RequestOption) (*LessonObjective, error) {
+	out := new(LessonObjective)
+	if err := c.do(ctx, http.MethodGet, "/api/admin/lessons/"+url.PathEscape(id)+"/objectives", nil, "", nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetLessonPreparation calls GET /api/admin/lessons/{id}/preparation: the lesson's preparation
+// It requires the lesson.read permission.
+func (c *Client) GetLessonPreparation(ctx context.Context, id string, opts ...RequestOption) (*LessonPreparation, error) {
+	out := new(LessonPreparation)
+	if err := c.do(ctx, http.MethodGet, "/api/admin/lessons/"+url.PathEscape(id)+"/preparation", nil, "", nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetMyIdentities calls GET /api/auth/me/identities: linked SSO identities
+func (c *Client) GetMyIdentities(ctx context.Context, opts ...RequestOption) ([]UserIdentity, error) {
+	var out []UserIdentity
+	if err := c.do(ctx, http.MethodGet, "/api/auth/me/identities", nil, "", nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetMyLoginHistoryParams are the query parameters of GetMyLoginHistory
+type GetMyLoginHistoryParams struct {
+	Limit int
+}
+
+// GetMyLoginHistory calls GET /api/auth/me/login-history: recent logins of the signed-in user
+func (c *Client) GetMyLoginHistory(ctx context.Context, params *GetMyLoginHistoryParams, opts ...RequestOption) (*LoginHistory, error) {
+	query := url.Values{}
+	if params != nil {
+		if params.Limit != 0 {
+			query.Set("limit", strconv.Itoa(params.Limit))
+		}
+	}
+	out := new(LoginHistory)
+	if err := c.do(ctx, http.MethodGet, "/api/auth/me/login-history", query, "", nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetMyPermissions calls GET /api/auth/me/permissions: permissions of the signed-in user
+func (c *Client) GetMyPermissions(ctx context.Context, opts ...RequestOption) (*EffectivePermissions, error) {
+	out := new(EffectivePermissions)
+	if err := c.do(ctx, http.MethodGet, "/api/auth/me/permissions", nil, "", nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetOpenAPI calls GET /api/openapi.json: this document
+func (c *Client) GetOpenAPI(ctx context.Context, opts ...RequestOption) (json.RawMessage, error) {
+	var out json.RawMessage
+	if err := c.do(ctx, http.MethodGet, "/api/openapi.json", nil, "", nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetProgram calls GET /api/admin/programs/{id}: a program with its subcourses
+// It requires the program.read permission.
+func (c *Client) GetProgram(ctx context.Context, id string, opts ...RequestOption) (*Program, error) {
+	out := new(Program)
+	if err := c.do(ctx, http.MethodGet, "/api/admin/programs/"+url.PathEscape(id), nil, "", nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetProgramDeleteImpact calls GET /api/admin/programs/{id}/delete-impact: what deleting a program takes along
+// It requires the program.delete permission.
+func (c *Client) GetProgramDeleteImpact(ctx context.Context, id string, opts ...RequestOption) (*DeleteImpact, error) {
+	out := new(DeleteImpact)
+	if err := c.do(ctx, http.MethodGet, "/api/admin/programs/"+url.PathEscape(id)+"/delete-impact", nil, "", nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetPublicLesson calls GET /api/lessons/{id}: a lesson
+func (c *Client) GetPublicLesson(ctx context.Context, id string, opts ...RequestOption) (*Lesson, error) {
+	out := new(Lesson)
+	if err := c.do(ctx, http.MethodGet, "/api/lessons/"+url.PathEscape(id), nil, "", nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetPublicProgram calls GET /api/programs/{id}: a program
+func (c *Client) GetPublicProgram(ctx context.Context, id string, opts ...RequestOption) (*Program, error) {
+	out := new(Program)
+	if err := c.do(ctx, http.MethodGet, "/api/programs/"+url.PathEscape(id), nil, "", nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetPublicSubcourse calls GET /api/subcourses/{id}: a subcourse
+func (c *Client) GetPublicSubcourse(ctx context.Context, id string, opts ...RequestOption) (*Subcourse, error) {
+	out := new(Subcourse)
+	if err := c.do(ctx, http.MethodGet, "/api/subcourses/"+url.PathEscape(id), nil, "", nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetSubcourse calls GET /api/admin/subcourses/{id}: a subcourse with its lessons
+// It requires the subcourse.read permission.
+func (c *Client) GetSubcourse(ctx context.Context, id string, opts ...RequestOption) (*Subcourse, error) {
+	out := new(Subcourse)
+	if err := c.do(ctx, http.MethodGet, "/api/admin/subcourses/"+url.PathEscape(id), nil, "", nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetSubcourseDeleteImpact calls GET /api/admin/subcourses/{id}/delete-impact: what deleting a subcourse takes along
+// It requires the subcourse.delete permission.
+func (c *Client) GetSubcourseDeleteImpact(ctx context.Context, id string, opts ...RequestOption) (*DeleteImpact, error) {
+	out := new(DeleteImpact)
+	if err := c.do(ctx, http.MethodGet, "/api/admin/subcourses/"+url.PathEscape(id)+"/delete-impact", nil, "", nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetTeacherAssignments calls GET /api/admin/teachers/{teacherId}/assignments: active assignments of a teacher
+// It requires the teacher.read permission.
+func (c *Client) GetTeacherAssignments(ctx context.Context, teacherId string, opts ...RequestOption) (*AssignmentsResponse, error) {
+	out := new(AssignmentsResponse)
+	if err := c.do(ctx, http.MethodGet, "/api/admin/teachers/"+url.PathEscape(teacherId)+"/assignments", nil, "", nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetTeacherHistory calls GET /api/admin/teachers/history: lessons by author
+// It requires the teacher.read permission.
+func (c *Client) GetTeacherHistory(ctx context.Context, opts ...RequestOption) ([]TeacherLessonHistory, error) {
+	var out []TeacherLessonHistory
+	if err := c.do(ctx, http.MethodGet, "/api/admin/teachers/history", nil, "", nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetTeacherLessonHistory calls GET /api/admin/teachers/{teacherId}/lesson-history: lessons a teacher wrote
+// It requires the teacher.read permission.
+func (c *Client) GetTeacherLessonHistory(ctx context.Context, teacherId string, opts ...RequestOption) ([]LessonHistoryItem, error) {
+	var out []LessonHistoryItem
+	if err := c.do(ctx, http.MethodGet, "/api/admin/teachers/"+url.PathEscape(teacherId)+"/lesson-history", nil, "", nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetTemplate calls GET /api/admin/templates/{id}: a lesson template
+// It requires the lesson.read permission.
+func (c *Client) GetTemplate(ctx context.Context, id string, opts ...RequestOption) (*LessonTemplate, error) {
+	out := new(LessonTemplate)
+	if err := c.do(ctx, http.MethodGet, "/api/admin/templates/"+url.PathEscape(id), nil, "", nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetTwoFactorStatus calls GET /api/auth/2fa/status: two-factor status of the signed-in user
+func (c *Client) GetTwoFactorStatus(ctx context.Context, opts ...RequestOption) (*TwoFactorStatus, error) {
+	out := new(TwoFactorStatus)
+	if err := c.do(ctx, http.MethodGet, "/api/auth/2fa/status", nil, "", nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetUserLoginHistoryParams are the query parameters of GetUserLoginHistory
+type GetUserLoginHistoryParams struct {
+	Limit int
+}
+
+// GetUserLoginHistory calls GET /api/admin/users/{id}/login-history: recent logins of a user
+// It requires the user.manage permission.
+func (c *Client) GetUserLoginHistory(ctx context.Context, id string, params *GetUserLoginHistoryParams, opts ...RequestOption) (*LoginHistory, error) {
+	query := url.Values{}
+	if params != nil {
+		if params.Limit != 0 {
+			query.Set("limit", strconv.Itoa(params.Limit))
+		}
+	}
+	out := new(LoginHistory)
+	if err := c.do(ctx, http.MethodGet, "/api/admin/users/"+url.PathEscape(id)+"/login-history", query, "", nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetUserPermissions calls GET /api/admin/users/{id}/permissions: effective permissions of a user
+// It requires the permission.read permission.
+func (c *Client) GetUserPermissions(ctx context.Context, id string, opts ...RequestOption) (*EffectivePermissions, error) {
+	out := new(EffectivePermissions)
+	if err := c.do(ctx, http.MethodGet, "/api/admin/users/"+url.PathEscape(id)+"/permissions", nil, "", nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Health calls GET /health: process is up; use /livez and /readyz for probes
+func (c *Client) Health(ctx context.Context, opts ...RequestOption) (*HealthStatus, error) {
+	out := new(HealthStatus)
+	if err := c.do(ctx, http.MethodGet, "/health", nil, "", nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListLessonAttachments calls GET /api/admin/lessons/{id}/attachments: the lesson's attachments
+// It requires the lesson.read permission.
+func (c *Client) ListLessonAttachments(ctx context.Context, id string, opts ...RequestOption) ([]LessonAttachment, error) {
+	var out []LessonAttachment
+	if err := c.do(ctx, http.MethodGet, "/api/admin/lessons/"+url.PathEscape(id)+"/attachments", nil, "", nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListLessonBuilds calls GET /api/admin/lessons/{id}/builds: the lesson's builds
+// It requires the lesson.read permission.
+func (c *Client) ListLessonBuilds(ctx context.Context, id string, opts ...RequestOption) ([]LessonBuild, error) {
+	var out []LessonBuild
+	if err := c.do(ctx, http.MethodGet, "/api/admin/lessons/"+url.PathEscape(id)+"/builds", nil, "", nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListLessonChallenges calls GET /api/admin/lessons/{id}/challenges: the lesson's challenges
+// It requires the lesson.read permission.
+func (c *Client) ListLessonChallenges(ctx context.Context, id string, opts ...RequestOption) ([]LessonChallenge, error) {
+	var out []LessonChallenge
+	if err := c.do(ctx, http.MethodGet, "/api/admin/lessons/"+url.PathEscape(id)+"/challenges", nil, "", nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListLessonContentBlocks calls GET /api/admin/lessons/{id}/content-blocks: the lesson's content blocks
+// It requires the lesson.read permission.
+func (c *Client) ListLessonContentBlocks(ctx context.Context, id string, opts ...RequestOption) ([]LessonContentBlock, error) {
+	var out []LessonContentBlock
+	if err := c.do(ctx, http.MethodGet, "/api/admin/lessons/"+url.PathEscape(id)+"/content-blocks", nil, "", nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListLessonModels calls GET /api/admin/lessons/{id}/models: the lesson's models
+// It requires the lesson.read permission.
+func (c *Client) ListLessonModels(ctx context.Context, id string, opts ...RequestOption) ([]LessonModel, error) {
+	var out []LessonModel
+	if err := c.do(ctx, http.MethodGet, "/api/admin/lessons/"+url.PathEscape(id)+"/models", nil, "", nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListLessonQuizOptions calls GET /api/admin/lessons/{id}/quizzes/{quizId}/options: the lesson's options
+// It requires the lesson.read permission.
+func (c *Client) ListLessonQuizOptions(ctx context.Context, id, quizId string, opts ...RequestOption) ([]LessonQuizOption, error) {
+	var out []LessonQuizOption
+	if err := c.do(ctx, http.MethodGet, "/api/admin/lessons/"+url.PathEscape(id)+"/quizzes/"+url.PathEscape(quizId)+"/options", nil, "", nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListLessonQuizzes calls GET /api/admin/lessons/{id}/quizzes: the lesson's quizzes
+// It requires the lesson.read permission.
+func (c *Client) ListLessonQuizzes(ctx context.Context, id string, opts ...RequestOption) ([]LessonQuiz, error) {
+	var out []LessonQuiz
+	if err := c.do(ctx, http.MethodGet, "/api/admin/lessons/"+url.PathEscape(id)+"/quizzes", nil, "", nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListLessonSummariesParams are the query parameters of ListLessonSummaries
+type ListLessonSummariesParams struct {
+	// only lessons of this subcourse
+	SubcourseID string
+	// only this content status
+	Status string
+}
+
+// ListLessonSummaries calls GET /api/admin/lessons/summary: summaries of the lessons the caller can access, without components and media
+// It requires the lesson.read permission.
+func (c *Client) ListLessonSummaries(ctx context.Context, params *ListLessonSummariesParams, opts ...RequestOption) ([]LessonSummary, error) {
+	query := url.Values{}
+	if params != nil {
+		if params.SubcourseID != "" {
+			query.Set("subcourse_id", params.SubcourseID)
+		}
+		if params.Status != "" {
+			query.Set("status", params.Status)
+		}
+	}
+	var out []LessonSummary
+	if err := c.do(ctx, http.MethodGet, "/api/admin/lessons/summary", query, "", nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListLessonsParams are the query parameters of ListLessons
+type ListLessonsParams struct {
+	// only lessons of this subcourse
+	SubcourseID string
+	// only this content status
+	Status string
+}
+
+// ListLessons calls GET /api/admin/lessons: lessons the caller can access
+// It requires the lesson.read permission.
+func (c *Client) ListLessons(ctx context.Context, params *ListLessonsParams, opts ...RequestOption) ([]Lesson, error) {
+	query := url.Values{}
+	if params != nil {
+		if params.SubcourseID != "" {
+			query.Set("subcourse_id", params.SubcourseID)
+		}
+		if params.Status != "" {
+			query.Set("status", params.Status)
+		}
+	}
+	var out []Lesson
+	if err := c.do(ctx, http.MethodGet, "/api/admin/lessons", query, "", nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListOrganizations calls GET /api/admin/organizations: all organizations
+// It requires the organization.manage permission.
+func (c *Client) ListOrganizations(ctx context.Context, opts ...RequestOption) ([]Organization, error) {
+	var out []Organization
+	if err := c.do(ctx, http.MethodGet, "/api/admin/organizations", nil, "", nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListProgramShares calls GET /api/admin/programs/{id}/shares: organizations a program is shared with
+// It requires the program.write permission.
+func (c *Client) ListProgramShares(ctx context.Context, id string, opts ...RequestOption) ([]ProgramShare, error) {
+	var out []ProgramShare
+	if err := c.do(ctx, http.MethodGet, "/api/admin/programs/"+url.PathEscape(id)+"/shares", nil, "", nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListProgramSubcourses calls GET /api/admin/programs/{programId}/subcourses: subcourses of a program
+// It requires the subcourse.read permission.
+func (c *Client) ListProgramSubcourses(ctx context.Context, programId string, opts ...RequestOption) ([]Subcourse, error) {
+	var out []Subcourse
+	if err := c.do(ctx, http.MethodGet, "/api/admin/programs/"+url.PathEscape(programId)+"/subcourses", nil, "", nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListProgramsParams are the query parameters of ListPrograms
+type ListProgramsParams struct {
+	// only this content status
+	Status string
+}
+
+// ListPrograms calls GET /api/admin/programs: programs the caller can access
+// It requires the program.read permission.
+func (c *Client) ListPrograms(ctx context.Context, params *ListProgramsParams, opts ...RequestOption) ([]Program, error) {
+	query := url.Values{}
+	if params != nil {
+		if params.Status != "" {
+			query.Set("status", params.Status)
+		}
+	}
+	var out []Program
+	if err := c.do(ctx, http.MethodGet, "/api/admin/programs", query, "", nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListPublicLessonSummariesParams are the query parameters of ListPublicLessonSummaries
+type ListPublicLessonSummariesParams struct {
+	// only lessons of this subcourse
+	SubcourseID string
+	// only this content status
+	Status string
+}
+
+// ListPublicLessonSummaries calls GET /api/lessons/summary: summaries of all lessons, without components and media
+func (c *Client) ListPublicLessonSummaries(ctx context.Context, params *ListPublicLessonSummariesParams, opts ...RequestOption) ([]LessonSummary, error) {
+	query := url.Values{}
+	if params != nil {
+		if params.SubcourseID != "" {
+			query.Set("subcourse_id", params.SubcourseID)
+		}
+		if params.Status != "" {
+			query.Set("status", params.Status)
+		}
+	}
+	var out []LessonSummary
+	if err := c.do(ctx, http.MethodGet, "/api/lessons/summary", query, "", nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListPublicLessonsParams are the query parameters of ListPublicLessons
+type ListPublicLessonsParams struct {
+	// only lessons of this subcourse
+	SubcourseID string
+	// only this content status
+	Status string
+}
+
+// ListPublicLessons calls GET /api/lessons: all lessons
+func (c *Client) ListPublicLessons(ctx context.Context, params *ListPublicLessonsParams, opts ...RequestOption) ([]Lesson, error) {
+	query := url.Values{}
+	if params != nil {
+		if params.SubcourseID != "" {
+			query.Set("subcourse_id", params.SubcourseID)
+		}
+		if params.Status != "" {
+			query.Set("status", params.Status)
+		}
+	}
+	var out []Lesson
+	if err := c.do(ctx, http.MethodGet, "/api/lessons", query, "", nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListPublicProgramSubcourses calls GET /api/programs/{programId}/subcourses: subcourses of a program
+func (c *Client) ListPublicProgramSubcourses(ctx context.Context, programId string, opts ...RequestOption) ([]Subcourse, error) {
+	var out []Subcourse
+	if err := c.do(ctx, http.MethodGet, "/api/programs/"+url.PathEscape(programId)+"/subcourses", nil, "", nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListPublicProgramsParams are the query parameters of ListPublicPrograms
+type ListPublicProgramsParams struct {
+	// only this content status
+	Status string
+}
+
+// ListPublicPrograms calls GET /api/programs: all programs
+func (c *Client) ListPublicPrograms(ctx context.Context, params *ListPublicProgramsParams, opts ...RequestOption) ([]Program, error) {
+	query := url.Values{}
+	if params != nil {
+		if params.Status != "" {
+			query.Set("status", params.Status)
+		}
+	}
+	var out []Program
+	if err := c.do(ctx, http.MethodGet, "/api/programs", query, "", nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListPublicSubcourseLessons calls GET /api/subcourses/{subcourseId}/lessons: lessons of a subcourse
+func (c *Client) ListPublicSubcourseLessons(ctx context.Context, subcourseId string, opts ...RequestOption) ([]Lesson, error) {
+	var out []Lesson
+	if err := c.do(ctx, http.MethodGet, "/api/subcourses/"+url.PathEscape(subcourseId)+"/lessons", nil, "", nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListPublicSubcoursesParams are the query parameters of ListPublicSubcourses
+type ListPublicSubcoursesParams struct {
+	// only subcourses of this program
+	ProgramID string
+	// only this content status
+	Status string
+}
+
+// ListPublicSubcourses calls GET /api/subcourses: subcourses
+func (c *Client) ListPublicSubcourses(ctx context.Context, params *ListPublicSubcoursesParams, opts ...RequestOption) ([]Subcourse, error) {
+	query := url.Values{}
+	if params != nil {
+		if params.ProgramID != "" {
+			query.Set("program_id", params.ProgramID)
+		}
+		if params.Status != "" {
+			query.Set("status", params.Status)
+		}
+	}
+	var out []Subcourse
+	if err := c.do(ctx, http.MethodGet, "/api/subcourses", query, "", nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListRoles calls GET /api/admin/roles: roles and their permissions
+// It requires the permission.read permission.
+func (c *Client) ListRoles(ctx context.Context, opts ...RequestOption) (*RolesResponse, error) {
+	out := new(RolesResponse)
+	if err := c.do(ctx, http.MethodGet, "/api/admin/roles", nil, "", nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListSubcourseLessons calls GET /api/admin/subcourses/{subcourseId}/lessons: lessons of a subcourse
+// It requires the lesson.read permission.
+func (c *Client) ListSubcourseLessons(ctx context.Context, subcourseId string, opts ...RequestOption) ([]Lesson, error) {
+	var out []Lesson
+	if err := c.do(ctx, http.MethodGet, "/api/admin/subcourses/"+url.PathEscape(subcourseId)+"/lessons", nil, "", nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListSubcoursesParams are the query parameters of ListSubcourses
+type ListSubcoursesParams struct {
+	// only subcourses of this program
+	ProgramID string
+	// only this content status
+	Status string
+}
+
+// ListSubcourses calls GET /api/admin/subcourses: subcourses the caller can access
+// It requires the subcourse.read permission.
+func (c *Client) ListSubcourses(ctx context.Context, params *ListSubcoursesParams, opts ...RequestOption) ([]Subcourse, error) {
+	query := url.Values{}
+	if params != nil {
+		if params.ProgramID != "" {
+			query.Set("program_id", params.ProgramID)
+		}
+		if params.Status != "" {
+			query.Set("status", params.Status)
+		}
+	}
+	var out []Subcourse
+	if err := c.do(ctx, http.MethodGet, "/api/admin/subcourses", query, "", nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListTeachers calls GET /api/admin/teachers: teachers of the organization
+// It requires the teacher.read permission.
+func (c *Client) ListTeachers(ctx context.Context, opts ...RequestOption) ([]User, error) {
+	var out []User
+	if err := c.do(ctx, http.MethodGet, "/api/admin/teachers", nil, "", nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListTemplates calls GET /api/admin/programs/{programId}/templates: lesson templates of a program
+// It requires the lesson.read permission.
+func (c *Client) ListTemplates(ctx context.Context, programId string, opts ...RequestOption) ([]LessonTemplate, error) {
+	var out []LessonTemplate
+	if err := c.do(ctx, http.MethodGet, "/api/admin/programs/"+url.PathEscape(programId)+"/templates", nil, "", nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListTrashParams are the query parameters of ListTrash
+type ListTrashParams struct {
+	// program, subcourse or lesson
+	Type string
+}
+
+// ListTrash calls GET /api/admin/trash: trashed programs, subcourses and lessons
+// It requires the program.delete or subcourse.delete or lesson.delete permission.
+func (c *Client) ListTrash(ctx context.Context, params *ListTrashParams, opts ...RequestOption) (*TrashList, error) {
+	query := url.Values{}
+	if params != nil {
+		if params.Type != "" {
+			query.Set("type", params.Type)
+		}
+	}
+	out := new(TrashList)
+	if err := c.do(ctx, http.MethodGet, "/api/admin/trash", query, "", nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LiveParams are the query parameters of Live
+type LiveParams struct {
+	// list the individual checks; needs a bearer token with system.health
+	Verbose bool
+}
+
+// Live calls GET /livez: liveness probe: background workers are running; 503 when failing
+func (c *Client) Live(ctx context.Context, params *LiveParams, opts ...RequestOption) (*ProbeReport, error) {
+	query := url.Values{}
+	if params != nil {
+		if params.Verbose {
+			query.Set("verbose", strconv.FormatBool(params.Verbose))
+		}
+	}
+	out := new(ProbeReport)
+	if err := c.do(ctx, http.MethodGet, "/livez", query, "", nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Login calls POST /api/auth/login: sign in with username and password; may answer with a 2FA challenge
+func (c *Client) Login(ctx context.Context, body *LoginRequest, opts ...RequestOption) (*LoginResponse, error) {
+	out := new(LoginResponse)
+	if err := c.do(ctx, http.MethodPost, "/api/auth/login", nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Me calls GET /api/auth/me: the signed-in user
+func (c *Client) Me(ctx context.Context, opts ...RequestOption) (*UserProfile, error) {
+	out := new(UserProfile)
+	if err := c.do(ctx, http.MethodGet, "/api/auth/me", nil, "", nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MoveLesson calls POST /api/admin/lessons/{id}/move: move a lesson to another subcourse or position
+// It requires the lesson.write permission.
+// Pass IfMatch with the version the change is based on; the server answers
+// 428 without it.
+func (c *Client) MoveLesson(ctx context.Context, id string, body *MoveInput, opts ...RequestOption) (*Lesson, error) {
+	out := new(Lesson)
+	if err := c.do(ctx, http.MethodPost, "/api/admin/lessons/"+url.PathEscape(id)+"/move", nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MoveSubcourse calls POST /api/admin/subcourses/{id}/move: move a subcourse to another program or position
+// It requires the subcourse.write permission.
+// Pass IfMatch with the version the change is based on; the server answers
+// 428 without it.
+func (c *Client) MoveSubcourse(ctx context.Context, id string, body *MoveInput, opts ...RequestOption) (*Subcourse, error) {
+	out := new(Subcourse)
+	if err := c.do(ctx, http.MethodPost, "/api/admin/subcourses/"+url.PathEscape(id)+"/move", nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// OIDCLink calls POST /api/auth/oidc/link: start linking an SSO identity to the signed-in user
+func (c *Client) OIDCLink(ctx context.Context, opts ...RequestOption) (*AuthorizationURL, error) {
+	out := new(AuthorizationURL)
+	if err := c.do(ctx, http.MethodPost, "/api/auth/oidc/link", nil, "", nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// OIDCLoginParams are the query parameters of OIDCLogin
+type OIDCLoginParams struct {
+	// "json" returns the URL instead of redirecting
+	Mode string
+}
+
+// OIDCLogin calls GET /api/auth/oidc/login: redirect to the identity provider, or return its URL with mode=json
+func (c *Client) OIDCLogin(ctx context.Context, params *OIDCLoginParams, opts ...RequestOption) (*AuthorizationURL, error) {
+	query := url.Values{}
+	if params != nil {
+		if params.Mode != "" {
+			query.Set("mode", params.Mode)
+		}
+	}
+	out := new(AuthorizationURL)
+	if err := c.do(ctx, http.MethodGet, "/api/auth/oidc/login", query, "", nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PatchLesson calls PATCH /api/admin/lessons/{id}: apply a JSON Patch (RFC 6902) to the lesson document
+// It requires the lesson.write permission.
+// Pass IfMatch with the version the change is based on; the server answers
+// 428 without it.
+func (c *Client) PatchLesson(ctx context.Context, id string, body []Operation, opts ...RequestOption) (*Lesson, error) {
+	out := new(Lesson)
+	if err := c.do(ctx, http.MethodPatch, "/api/admin/lessons/"+url.PathEscape(id), nil, "application/json-patch+json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PurgeTrashed calls DELETE /api/admin/trash/{type}/{id}: delete a trashed item for good
+// It requires the program.delete or subcourse.delete or lesson.delete permission.
+func (c *Client) PurgeTrashed(ctx context.Context, typeName, id string, opts ...RequestOption) error {
+	return c.do(ctx, http.MethodDelete, "/api/admin/trash/"+url.PathEscape(typeName)+"/"+url.PathEscape(id), nil, "", nil, nil, opts)
+}
+
+// ReadyParams are the query parameters of Ready
+type ReadyParams struct {
+	// list the individual checks; needs a bearer token with system.health
+	Verbose bool
+}
+
+// Ready calls GET /readyz: readiness probe: database, schema version and storage are usable and the server is not shutting down; 503 when failing
+func (c *Client) Ready(ctx context.Context, params *ReadyParams, opts ...RequestOption) (*ProbeReport, error) {
+	query := url.Values{}
+	if params != nil {
+		if params.Verbose {
+			query.Set("verbose", strconv.FormatBool(params.Verbose))
+		}
+	}
+	out := new(ProbeReport)
+	if err := c.do(ctx, http.MethodGet, "/readyz", query, "", nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RegenerateRecoveryCodes calls POST /api/auth/2fa/recovery-codes: replace all recovery codes
+func (c *Client) RegenerateRecoveryCodes(ctx context.Context, body *RecoveryCodesRequest, opts ...RequestOption) (*RecoveryCodesResponse, error) {
+	out := new(RecoveryCodesResponse)
+	if err := c.do(ctx, http.MethodPost, "/api/auth/2fa/recovery-codes", nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReorderLessonAttachments calls PUT /api/admin/lessons/{id}/attachments/order: set the order of attachments
+// It requires the lesson.write permission.
+func (c *Client) ReorderLessonAttachments(ctx context.Context, id string, body *ReorderInput, opts ...RequestOption) ([]LessonAttachment, error) {
+	var out []LessonAttachment
+	if err := c.do(ctx, http.MethodPut, "/api/admin/lessons/"+url.PathEscape(id)+"/attachments/order", nil, "application/json", body, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReorderLessonBuilds calls PUT /api/admin/lessons/{id}/builds/order: set the order of builds
+// It requires the lesson.write permission.
+func (c *Client) ReorderLessonBuilds(ctx context.Context, id string, body *ReorderInput, opts ...RequestOption) ([]LessonBuild, error) {
+	var out []LessonBuild
+	if err := c.do(ctx, http.MethodPut, "/api/admin/lessons/"+url.PathEscape(id)+"/builds/order", nil, "application/json", body, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReorderLessonChallenges calls PUT /api/admin/lessons/{id}/challenges/order: set the order of challenges
+// It requires the lesson.write permission.
+func (c *Client) ReorderLessonChallenges(ctx context.Context, id string, body *ReorderInput, opts ...RequestOption) ([]LessonChallenge, error) {
+	var out []LessonChallenge
+	if err := c.do(ctx, http.MethodPut, "/api/admin/lessons/"+url.PathEscape(id)+"/challenges/order", nil, "application/json", body, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReorderLessonContentBlocks calls PUT /api/admin/lessons/{id}/content-blocks/order: set the order of content blocks
+// It requires the lesson.write permission.
+func (c *Client) ReorderLessonContentBlocks(ctx context.Context, id string, body *ReorderInput, opts ...RequestOption) ([]LessonContentBlock, error) {
+	var out []LessonContentBlock
+	if err := c.do(ctx, http.MethodPut, "/api/admin/lessons/"+url.PathEscape(id)+"/content-blocks/order", nil, "application/json", body, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReorderLessonModels calls PUT /api/admin/lessons/{id}/models/order: set the order of models
+// It requires the lesson.write permission.
+func (c *Client) ReorderLessonModels(ctx context.Context, id string, body *ReorderInput, opts ...RequestOption) ([]LessonModel, error) {
+	var out []LessonModel
+	if err := c.do(ctx, http.MethodPut, "/api/admin/lessons/"+url.PathEscape(id)+"/models/order", nil, "application/json", body, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReorderLessonQuizOptions calls PUT /api/admin/lessons/{id}/quizzes/{quizId}/options/order: set the order of options
+// It requires the lesson.write permission.
+func (c *Client) ReorderLessonQuizOptions(ctx context.Context, id, quizId string, body *ReorderInput, opts ...RequestOption) ([]LessonQuizOption, error) {
+	var out []LessonQuizOption
+	if err := c.do(ctx, http.MethodPut, "/api/admin/lessons/"+url.PathEscape(id)+"/quizzes/"+url.PathEscape(quizId)+"/options/order", nil, "application/json", body, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReorderLessonQuizzes calls PUT /api/admin/lessons/{id}/quizzes/order: set the order of quizzes
+// It requires the lesson.write permission.
+func (c *Client) ReorderLessonQuizzes(ctx context.Context, id string, body *ReorderInput, opts ...RequestOption) ([]LessonQuiz, error) {
+	var out []LessonQuiz
+	if err := c.do(ctx, http.MethodPut, "/api/admin/lessons/"+url.PathEscape(id)+"/quizzes/order", nil, "application/json", body, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReorderLessons calls PUT /api/admin/subcourses/{id}/lessons/order: set the order of a subcourse's lessons
+// It requires the lesson.write permission.
+func (c *Client) ReorderLessons(ctx context.Context, id string, body *ReorderInput, opts ...RequestOption) ([]Lesson, error) {
+	var out []Lesson
+	if err := c.do(ctx, http.MethodPut, "/api/admin/subcourses/"+url.PathEscape(id)+"/lessons/order", nil, "application/json", body, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReorderMedia calls PUT /api/admin/media/order: set the order of an owner's media
+// It requires the lesson.write permission.
+func (c *Client) ReorderMedia(ctx context.Context, body *MediaOrderInput, opts ...RequestOption) ([]Media, error) {
+	var out []Media
+	if err := c.do(ctx, http.MethodPut, "/api/admin/media/order", nil, "application/json", body, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReorderPrograms calls PUT /api/admin/programs/order: set the order of all programs
+// It requires the program.write permission.
+func (c *Client) ReorderPrograms(ctx context.Context, body *ReorderInput, opts ...RequestOption) ([]Program, error) {
+	var out []Program
+	if err := c.do(ctx, http.MethodPut, "/api/admin/programs/order", nil, "application/json", body, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReorderSubcourses calls PUT /api/admin/programs/{id}/subcourses/order: set the order of a program's subcourses
+// It requires the subcourse.write permission.
+func (c *Client) ReorderSubcourses(ctx context.Context, id string, body *ReorderInput, opts ...RequestOption) ([]Subcourse, error) {
+	var out []Subcourse
+	if err := c.do(ctx, http.MethodPut, "/api/admin/programs/"+url.PathEscape(id)+"/subcourses/order", nil, "application/json", body, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ResetTwoFactor calls DELETE /api/admin/users/{id}/2fa: reset a user's 2FA; they enroll again at the next login
+// It requires the user.manage permission.
+func (c *Client) ResetTwoFactor(ctx context.Context, id string, opts ...RequestOption) (*MessageResponse, error) {
+	out := new(MessageResponse)
+	if err := c.do(ctx, http.MethodDelete, "/api/admin/users/"+url.PathEscape(id)+"/2fa", nil, "", nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RestoreTrashed calls POST /api/admin/trash/{type}/{id}/restore: restore a trashed item with what was trashed along with it; returns the program, subcourse or lesson
+// It requires the program.delete or subcourse.delete or lesson.delete permission.
+func (c *Client) RestoreTrashed(ctx context.Context, typeName, id string, opts ...RequestOption) (json.RawMessage, error) {
+	var out json.RawMessage
+	if err := c.do(ctx, http.MethodPost, "/api/admin/trash/"+url.PathEscape(typeName)+"/"+url.PathEscape(id)+"/restore", nil, "", nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Seed calls POST /api/admin/seed: load the sample data
+// It requires the system.seed permission.
+func (c *Client) Seed(ctx context.Context, opts ...RequestOption) (*MessageResponse, error) {
+	out := new(MessageResponse)
+	if err := c.do(ctx, http.MethodPost, "/api/admin/seed", nil, "", nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SetLessonObjective calls PUT /api/admin/lessons/{id}/objectives: create or replace the lesson's objectives
+// It requires the lesson.write permission.
+func (c *Client) SetLessonObjective(ctx context.Context, id string, body *LessonObjective, opts ...RequestOption) (*LessonObjective, error) {
+	out := new(LessonObjective)
+	if err := c.do(ctx, http.MethodPut, "/api/admin/lessons/"+url.PathEscape(id)+"/objectives", nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SetLessonPreparation calls PUT /api/admin/lessons/{id}/preparation: create or replace the lesson's preparation
+// It requires the lesson.write permission.
+func (c *Client) SetLessonPreparation(ctx context.Context, id string, body *LessonPreparation, opts ...RequestOption) (*LessonPreparation, error) {
+	out := new(LessonPreparation)
+	if err := c.do(ctx, http.MethodPut, "/api/admin/lessons/"+url.PathEscape(id)+"/preparation", nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SetLessonStatus calls PUT /api/admin/lessons/{id}/status: change the publication status
+// It requires the lesson.publish permission.
+// Pass IfMatch with the version the change is based on; the server answers
+// 428 without it.
+func (c *Client) SetLessonStatus(ctx context.Context, id string, body *LessonStatusInput, opts ...RequestOption) (*Lesson, error) {
+	out := new(Lesson)
+	if err := c.do(ctx, http.MethodPut, "/api/admin/lessons/"+url.PathEscape(id)+"/status", nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SetPasswordLogin calls PUT /api/admin/users/{id}/password-login: force a user onto single sign-on, or allow passwords again
+// It requires the user.manage permission.
+func (c *Client) SetPasswordLogin(ctx context.Context, id string, body *PasswordLoginInput, opts ...RequestOption) (*User, error) {
+	out := new(User)
+	if err := c.do(ctx, http.MethodPut, "/api/admin/users/"+url.PathEscape(id)+"/password-login", nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SetUserRole calls PUT /api/admin/users/{id}/role: change a user's role
+// It requires the user.manage permission.
+func (c *Client) SetUserRole(ctx context.Context, id string, body *SetRoleInput, opts ...RequestOption) (*EffectivePermissions, error) {
+	out := new(EffectivePermissions)
+	if err := c.do(ctx, http.MethodPut, "/api/admin/users/"+url.PathEscape(id)+"/role", nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SetupTwoFactor calls POST /api/auth/2fa/setup: start TOTP enrollment
+func (c *Client) SetupTwoFactor(ctx context.Context, body *TwoFactorSetupRequest, opts ...RequestOption) (*TOTPSetupResponse, error) {
+	out := new(TOTPSetupResponse)
+	if err := c.do(ctx, http.MethodPost, "/api/auth/2fa/setup", nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ShareProgram calls POST /api/admin/programs/{id}/shares: make a program readable by another organization
+// It requires the program.write permission.
+func (c *Client) ShareProgram(ctx context.Context, id string, body *ProgramShareInput, opts ...RequestOption) (*ProgramShare, error) {
+	out := new(ProgramShare)
+	if err := c.do(ctx, http.MethodPost, "/api/admin/programs/"+url.PathEscape(id)+"/shares", nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UnlinkIdentity calls DELETE /api/auth/me/identities/{id}: unlink an SSO identity
+func (c *Client) UnlinkIdentity(ctx context.Context, id string, opts ...RequestOption) error {
+	return c.do(ctx, http.MethodDelete, "/api/auth/me/identities/"+url.PathEscape(id), nil, "", nil, nil, opts)
+}
+
+// UnlockUserParams are the query parameters of UnlockUser
+type UnlockUserParams struct {
+	// also lift the lockout of this IP
+	IP string
+}
+
+// UnlockUser calls POST /api/admin/users/{id}/unlock: lift a login lockout
+// It requires the user.manage permission.
+func (c *Client) UnlockUser(ctx context.Context, id string, params *UnlockUserParams, opts ...RequestOption) (*MessageResponse, error) {
+	query := url.Values{}
+	if params != nil {
+		if params.IP != "" {
+			query.Set("ip", params.IP)
+		}
+	}
+	out := new(MessageResponse)
+	if err := c.do(ctx, http.MethodPost, "/api/admin/users/"+url.PathEscape(id)+"/unlock", query, "", nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UnshareProgram calls DELETE /api/admin/programs/{id}/shares/{orgId}: stop sharing a program
+// It requires the program.write permission.
+func (c *Client) UnshareProgram(ctx context.Context, id, orgId string, opts ...RequestOption) error {
+	return c.do(ctx, http.MethodDelete, "/api/admin/programs/"+url.PathEscape(id)+"/shares/"+url.PathEscape(orgId), nil, "", nil, nil, opts)
+}
+
+// UpdateLesson calls PUT /api/admin/lessons/{id}: update a lesson; sent components replace the existing ones
+// It requires the lesson.write permission.
+// Pass IfMatch with the version the change is based on; the server answers
+// 428 without it.
+func (c *Client) UpdateLesson(ctx context.Context, id string, body *Lesson, opts ...RequestOption) (*Lesson, error) {
+	out := new(Lesson)
+	if err := c.do(ctx, http.MethodPut, "/api/admin/lessons/"+url.PathEscape(id), nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateLessonAttachment calls PATCH /api/admin/lessons/{id}/attachments/{componentId}: change the sent fields of one of attachments
+// It requires the lesson.write permission.
+func (c *Client) UpdateLessonAttachment(ctx context.Context, id, componentId string, body *LessonAttachment, opts ...RequestOption) (*LessonAttachment, error) {
+	out := new(LessonAttachment)
+	if err := c.do(ctx, http.MethodPatch, "/api/admin/lessons/"+url.PathEscape(id)+"/attachments/"+url.PathEscape(componentId), nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateLessonBuild calls PATCH /api/admin/lessons/{id}/builds/{componentId}: change the sent fields of one of builds
+// It requires the lesson.write permission.
+func (c *Client) UpdateLessonBuild(ctx context.Context, id, componentId string, body *LessonBuild, opts ...RequestOption) (*LessonBuild, error) {
+	out := new(LessonBuild)
+	if err := c.do(ctx, http.MethodPatch, "/api/admin/lessons/"+url.PathEscape(id)+"/builds/"+url.PathEscape(componentId), nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateLessonChallenge calls PATCH /api/admin/lessons/{id}/challenges/{componentId}: change the sent fields of one of challenges
+// It requires the lesson.write permission.
+func (c *Client) UpdateLessonChallenge(ctx context.Context, id, componentId string, body *LessonChallenge, opts ...RequestOption) (*LessonChallenge, error) {
+	out := new(LessonChallenge)
+	if err := c.do(ctx, http.MethodPatch, "/api/admin/lessons/"+url.PathEscape(id)+"/challenges/"+url.PathEscape(componentId), nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateLessonContentBlock calls PATCH /api/admin/lessons/{id}/content-blocks/{componentId}: change the sent fields of one of content blocks
+// It requires the lesson.write permission.
+func (c *Client) UpdateLessonContentBlock(ctx context.Context, id, componentId string, body *LessonContentBlock, opts ...RequestOption) (*LessonContentBlock, error) {
+	out := new(LessonContentBlock)
+	if err := c.do(ctx, http.MethodPatch, "/api/admin/lessons/"+url.PathEscape(id)+"/content-blocks/"+url.PathEscape(componentId), nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateLessonModel calls PATCH /api/admin/lessons/{id}/models/{componentId}: change the sent fields of one of models
+// It requires the lesson.write permission.
+func (c *Client) UpdateLessonModel(ctx context.Context, id, componentId string, body *LessonModel, opts ...RequestOption) (*LessonModel, error) {
+	out := new(LessonModel)
+	if err := c.do(ctx, http.MethodPatch, "/api/admin/lessons/"+url.PathEscape(id)+"/models/"+url.PathEscape(componentId), nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateLessonQuiz calls PATCH /api/admin/lessons/{id}/quizzes/{componentId}: change the sent fields of one of quizzes
+// It requires the lesson.write permission.
+func (c *Client) UpdateLessonQuiz(ctx context.Context, id, componentId string, body *LessonQuiz, opts ...RequestOption) (*LessonQuiz, error) {
+	out := new(LessonQuiz)
+	if err := c.do(ctx, http.MethodPatch, "/api/admin/lessons/"+url.PathEscape(id)+"/quizzes/"+url.PathEscape(componentId), nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateLessonQuizOption calls PATCH /api/admin/lessons/{id}/quizzes/{quizId}/options/{componentId}: change the sent fields of one of options
+// It requires the lesson.write permission.
+func (c *Client) UpdateLessonQuizOption(ctx context.Context, id, quizId, componentId string, body *LessonQuizOption, opts ...RequestOption) (*LessonQuizOption, error) {
+	out := new(LessonQuizOption)
+	if err := c.do(ctx, http.MethodPatch, "/api/admin/lessons/"+url.PathEscape(id)+"/quizzes/"+url.PathEscape(quizId)+"/options/"+url.PathEscape(componentId), nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateOrganization calls PUT /api/admin/organizations/{id}: update an organization
+// It requires the organization.manage permission.
+func (c *Client) UpdateOrganization(ctx context.Context, id string, body *OrganizationInput, opts ...RequestOption) (*Organization, error) {
+	out := new(Organization)
+	if err := c.do(ctx, http.MethodPut, "/api/admin/organizations/"+url.PathEscape(id), nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateProgram calls PUT /api/admin/programs/{id}: update a program; empty fields are left unchanged
+// It requires the program.write permission.
+// Pass IfMatch with the version the change is based on; the server answers
+// 428 without it.
+func (c *Client) UpdateProgram(ctx context.Context, id string, body *Program, opts ...RequestOption) (*Program, error) {
+	out := new(Program)
+	if err := c.do(ctx, http.MethodPut, "/api/admin/programs/"+url.PathEscape(id), nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateSubcourse calls PUT /api/admin/subcourses/{id}: update a subcourse; empty fields are left unchanged
+// It requires the subcourse.write permission.
+// Pass IfMatch with the version the change is based on; the server answers
+// 428 without it.
+func (c *Client) UpdateSubcourse(ctx context.Context, id string, body *Subcourse, opts ...RequestOption) (*Subcourse, error) {
+	out := new(Subcourse)
+	if err := c.do(ctx, http.MethodPut, "/api/admin/subcourses/"+url.PathEscape(id), nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateTemplate calls PUT /api/admin/templates/{id}: update a lesson template
+// It requires the program.write permission.
+func (c *Client) UpdateTemplate(ctx context.Context, id string, body *TemplateInput, opts ...RequestOption) (*LessonTemplate, error) {
+	out := new(LessonTemplate)
+	if err := c.do(ctx, http.MethodPut, "/api/admin/templates/"+url.PathEscape(id), nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UploadMedia calls POST /api/admin/media/upload: upload files as media of a program, subcourse, lesson or component
+// It requires the media.upload permission.
+func (c *Client) UploadMedia(ctx context.Context, body *MediaUploadForm, opts ...RequestOption) (*MediaUploadResponse, error) {
+	out := new(MediaUploadResponse)
+	if err := c.do(ctx, http.MethodPost, "/api/admin/media/upload", nil, "multipart/form-data", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// VerifyTwoFactor calls POST /api/auth/2fa/verify: second login step
+func (c *Client) VerifyTwoFactor(ctx context.Context, body *TwoFactorVerifyRequest, opts ...RequestOption) (*LoginResponse, error) {
+	out := new(LoginResponse)
+	if err := c.do(ctx, http.MethodPost, "/api/auth/2fa/verify", nil, "application/json", body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,39 @@
+// Command apigen writes the typed Go client (client/client_gen.go) from the
+// OpenAPI document the server serves at /api/openapi.json, and optionally the
+// document itself. Run it through go generate ./client after changing routes
+// or the types they read and write.
+package main
+
+import (
+	"courseai/backend/internal/handlers"
+	"courseai/backend/internal/openapi"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+)
+
+func main() {
+	out := flag.String("out", "client/client_gen.go", "file to write the client to")
+	pkg := flag.String("package", "client", "package name of the client")
+	specOut := flag.String("spec", "", "also write the OpenAPI document to this file")
+	flag.Parse()
+
+	doc := handlers.BuildAPISpec().Doc
+	src, err := openapi.GenerateClient(doc, *pkg)
+	if err != nil {
+		log.Fatalf("generate client: %v", err)
+	}
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		log.Fatalf("write client: %v", err)
+	}
+	if *specOut != "" {
+		data, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			log.Fatalf("encode document: %v", err)
+		}
+		if err := os.WriteFile(*specOut, append(data, '\n'), 0o644); err != nil {
+			log.Fatalf("write document: %v", err)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"courseai/backend/internal/database"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TestStatementBudgets runs every bench case once against two catalogs of
+// different sizes. Each case must stay within its budget and run the same
+// number of statements for both, so a query per listed row fails even when
+// the small catalog happens to fit the budget.
+//
+// It needs a Postgres database it may migrate and seed:
+//
+//	TEST_DATABASE_URL=postgres://localhost/courseai_test go test ./cmd/bench
+func TestStatementBudgets(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	if err := database.Connect(dsn, database.PoolOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.AutoMigrate(); err != nil {
+		t.Fatal(err)
+	}
+	db := database.GetDB()
+	var stmts atomic.Int64
+	if err := countStatements(db, &stmts); err != nil {
+		t.Fatal(err)
+	}
+	secret := randomSecret()
+	app := newApp(secret)
+
+	// statements per case for each catalog size
+	counts := map[string][]int{}
+	for _, size := range [][3]int{{2, 2, 2}, {4, 3, 6}} {
+		if err := deleteCatalog(db); err != nil {
+			t.Fatal(err)
+		}
+		cat, err := seedCatalog(db, size[0], size[1], size[2])
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens, err := issueTokens(cat, secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, bc := range benchCases(cat) {
+			before := stmts.Load()
+			status, _, err := get(app, tokens, bc)
+			if err != nil {
+				t.Fatalf("%s: %v", bc.Name, err)
+			}
+			if status != fiber.StatusOK {
+				t.Fatalf("%s: status %d", bc.Name, status)
+			}
+			counts[bc.Name] = append(counts[bc.Name], int(stmts.Load()-before))
+		}
+	}
+	t.Cleanup(func() {
+		if err := deleteCatalog(db); err != nil {
+			t.Errorf("delete catalog: %v", err)
+		}
+	})
+
+	for _, bc := range benchCases(&catalog{}) {
+		small, large := counts[bc.Name][0], counts[bc.Name][1]
+		if large > bc.Budget {
+			t.Errorf("%s: %d statements, budget %d", bc.Name, large, bc.Budget)
+		}
+		if small != large {
+			t.Errorf("%s: %d statements for the small catalog, %d for the large one", bc.Name, small, large)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"courseai/backend/internal/database"
+	"courseai/backend/internal/models"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// catalog is the seeded bench organization: its users, the first program,
+// subcourse and lesson, and how many there are
+type catalog struct {
+	Admin   models.User
+	Teacher models.User
+
+	Program, Subcourse, Lesson    string
+	Programs, Subcourses, Lessons int64
+}
+
+// loadCatalog returns the bench organization's catalog, nil if it was never
+// seeded
+func loadCatalog(db *gorm.DB) (*catalog, error) {
+	var org models.Organization
+	if err := db.Where("slug = ?", benchSlug).First(&org).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	cat := &catalog{}
+	var program models.Program
+	var subcourse models.Subcourse
+	var lesson models.Lesson
+	tenant := database.ForTenant(db, org.ID)
+	// in order: each step needs the one before
+	for _, step := range []func() error{
+		func() error { return db.Where("username = ?", "bench-admin").First(&cat.Admin).Error },
+		func() error { return db.Where("username = ?", "bench-teacher").First(&cat.Teacher).Error },
+		func() error { return tenant.Order("sort_order ASC").First(&program).Error },
+		func() error {
+			return tenant.Where("program_id = ?", program.ID).Order("sort_order ASC").First(&subcourse).Error
+		},
+		func() error {
+			return tenant.Where("subcourse_id = ?", subcourse.ID).Order("sort_order ASC").First(&lesson).Error
+		},
+		func() error { return tenant.Model(&models.Program{}).Count(&cat.Programs).Error },
+		func() error { return tenant.Model(&models.Subcourse{}).Count(&cat.Subcourses).Error },
+		func() error { return tenant.Model(&models.Lesson{}).Count(&cat.Lessons).Error },
+	} {
+		if err := step(); err != nil {
+			return nil, fmt.Errorf("bench organization is incomplete, run with -reset: %w", err)
+		}
+	}
+	cat.Program, cat.Subcourse, cat.Lesson = program.ID.String(), subcourse.ID.String(), lesson.ID.String()
+	return cat, nil
+}
+
+// seedCatalog creates the bench organization: an admin, a teacher assigned
+// to the first program, and the programs, subcourses and lessons, each with
+// media; lessons also get a cover, a content block with media and a quiz
+func seedCatalog(db *gorm.DB, programs, subcourses, lessons int) (*catalog, error) {
+	started := time.Now()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		org := models.Organization{Name: "Bench", Slug: benchSlug, Status: models.OrgStatusActive}
+		if err := tx.Create(&org).Error; err != nil {
+			return err
+		}
+		users := []models.User{
+			{Username: "bench-admin", Email: "bench-admin@bench.invalid", PasswordHash: "!", Role: models.RoleAdmin, OrganizationID: &org.ID},
+			{Username: "bench-teacher", Email: "bench-teacher@bench.invalid", PasswordHash: "!", Role: models.RoleTeacher, OrganizationID: &org.ID},
+		}
+		if err := tx.Create(&users).Error; err != nil {
+			return err
+		}
+
+		var (
+			media   []models.Media
+			progs   []models.Program
+			subs    []models.Subcourse
+			ls      []models.Lesson
+			blocks  []models.LessonContentBlock
+			quizzes []models.LessonQuiz
+			options []models.LessonQuizOption
+		)
+		for i := 0; i < programs; i++ {
+			p := models.Program{
+				ID: uuid.New(), OrganizationID: &org.ID, Status: models.StatusPublished, SortOrder: i,
+				Name: fmt.Sprintf("Bench program %d", i+1), Slug: fmt.Sprintf("bench-p%d", i+1),
+				ShortDescription: "Seeded by cmd/bench", Description: "A program of the bench catalog.",
+			}
+			progs = append(progs, p)
+			media = append(media, benchMedia(models.OwnerProgram, p.ID, 0))
+			for j := 0; j < subcourses; j++ {
+				s := models.Subcourse{
+					ID: uuid.New(), ProgramID: p.ID, OrganizationID: &org.ID, Status: models.StatusPublished, SortOrder: j,
+					Name: fmt.Sprintf("Bench subcourse %d.%d", i+1, j+1), Slug: fmt.Sprintf("%s-s%d", p.Slug, j+1),
+				}
+				subs = append(subs, s)
+				media = append(media, benchMedia(models.OwnerSubcourse, s.ID, 0))
+				for k := 0; k < lessons; k++ {
+					l := models.Lesson{
+						ID: uuid.New(), SubcourseID: s.ID, OrganizationID: &org.ID, Status: models.StatusPublished, SortOrder: k,
+						Title: fmt.Sprintf("Bench lesson %d.%d.%d", i+1, j+1, k+1), Slug: fmt.Sprintf("%s-l%d", s.Slug, k+1),
+						Overview: "A lesson of the bench catalog.", DurationMinutes: 45,
+					}
+					cover := benchMedia(models.OwnerLesson, l.ID, 0)
+					l.CoverMediaID = &cover.ID
+					ls = append(ls, l)
+					media = append(media, cover, benchMedia(models.OwnerLesson, l.ID, 1))
+
+					block := models.LessonContentBlock{ID: uuid.New(), LessonID: l.ID, Title: "Block"}
+					blocks = append(blocks, block)
+					media = append(media, benchMedia(models.OwnerLessonContentBlock, block.ID, 0))
+
+					quiz := models.LessonQuiz{ID: uuid.New(), LessonID: l.ID, Title: "Quiz", QuizType: models.QuizTypeSingle}
+					quizzes = append(quizzes, quiz)
+					for o := 0; o < 3; o++ {
+						options = append(options, models.LessonQuizOption{
+							ID: uuid.New(), QuizID: quiz.ID, Content: fmt.Sprintf("Option %d", o+1), IsCorrect: o == 0, SortOrder: o,
+						})
+					}
+				}
+			}
+		}
+
+		// media first: lessons reference their cover
+		for _, rows := range []interface{}{&media, &progs, &subs, &ls, &blocks, &quizzes, &options} {
+			if err := tx.CreateInBatches(rows, 500).Error; err != nil {
+				return err
+			}
+		}
+		if len(progs) == 0 {
+			return nil
+		}
+		now := time.Now()
+		return tx.Create(&models.TeacherAssignment{
+			ID: uuid.New(), TeacherID: users[1].ID, OrganizationID: &org.ID, ProgramID: &progs[0].ID,
+			ScopeLevel: models.ScopeProgram, Status: models.AssignmentStatusActive, StartAt: &now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := database.RecomputeCounters(db); err != nil {
+		return nil, err
+	}
+	log.Printf("seeded the bench catalog in %s", time.Since(started).Round(time.Millisecond))
+	return loadCatalog(db)
+}
+
+func benchMedia(ownerType models.MediaOwnerType, ownerID uuid.UUID, sortOrder int) models.Media {
+	id := uuid.New()
+	purpose := models.PurposeGallery
+	if sortOrder == 0 {
+		purpose = models.PurposeCover
+	}
+	return models.Media{
+		ID: id, OwnerType: ownerType, OwnerID: ownerID, SortOrder: sortOrder, Purpose: purpose,
+		URL: "/uploads/bench/" + id.String() + ".jpg", MimeType: "image/jpeg",
+	}
+}
+
+// deleteCatalog removes the bench organization with its users and content;
+// the foreign keys and media triggers take the rest along
+func deleteCatalog(db *gorm.DB) error {
+	var org models.Organization
+	if err := db.Where("slug = ?", benchSlug).First(&org).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range []string{
+			"DELETE FROM programs WHERE organization_id = ?",
+			"DELETE FROM users WHERE organization_id = ?",
+			"DELETE FROM organizations WHERE id = ?",
+		} {
+			if err := tx.Exec(stmt, org.ID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,265 @@
+// Command bench seeds a catalog into Postgres and measures the catalog read
+// endpoints against it: SQL statements and latency per request. It exits
+// non-zero when an endpoint runs more statements than its budget. Budgets do
+// not depend on the size of the catalog, so a statement per listed row (an
+// N+1 query) fails the run however small the seed.
+//
+//	go run ./cmd/bench                     # seed once, run every case
+//	go run ./cmd/bench -run lessons -n 500  # cases whose name contains "lessons"
+//	go run ./cmd/bench -reset -lessons 40   # reseed with larger subcourses
+//
+// The catalog lives in its own organization (slug "bench"), so the command
+// can run against a development database.
+package main
+
+import (
+	"courseai/backend/internal/config"
+	"courseai/backend/internal/database"
+	"courseai/backend/internal/handlers"
+	"courseai/backend/internal/logging"
+	"courseai/backend/internal/middleware"
+	"courseai/backend/internal/models"
+	"courseai/backend/internal/repository"
+	"courseai/backend/internal/service"
+	"courseai/backend/internal/utils"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const benchSlug = "bench"
+
+// benchCase is one request and the most SQL statements it may run
+type benchCase struct {
+	Name   string
+	Path   string
+	As     string // "" (anonymous), "admin" or "teacher"
+	Budget int
+}
+
+func main() {
+	programs := flag.Int("programs", 8, "programs to seed")
+	subcourses := flag.Int("subcourses", 6, "subcourses to seed per program")
+	lessons := flag.Int("lessons", 10, "lessons to seed per subcourse")
+	reset := flag.Bool("reset", false, "delete the bench organization and seed it again")
+	n := flag.Int("n", 100, "timed requests per case")
+	run := flag.String("run", "", "only cases whose name contains this")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("config load: %v", err)
+	}
+	logging.Setup(cfg.Log.Options())
+	if err := database.Connect(cfg.Database.DSN(), cfg.Database.Pool()); err != nil {
+		log.Fatalf("connect: %v", err)
+	}
+	db := database.GetDB()
+
+	if *reset {
+		if err := deleteCatalog(db); err != nil {
+			log.Fatalf("reset: %v", err)
+		}
+	}
+	cat, err := loadCatalog(db)
+	if err == nil && cat == nil {
+		cat, err = seedCatalog(db, *programs, *subcourses, *lessons)
+	}
+	if err != nil {
+		log.Fatalf("seed: %v", err)
+	}
+
+	var stmts atomic.Int64
+	if err := countStatements(db, &stmts); err != nil {
+		log.Fatalf("statement counter: %v", err)
+	}
+	secret := randomSecret()
+	app := newApp(secret)
+	tokens, err := issueTokens(cat, secret)
+	if err != nil {
+		log.Fatalf("token: %v", err)
+	}
+
+	fmt.Printf("catalog: %d programs, %d subcourses, %d lessons\n\n", cat.Programs, cat.Subcourses, cat.Lessons)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "case\tstatements\tbudget\tmean\tp95\tbytes\t")
+	failed := false
+	for _, bc := range benchCases(cat) {
+		if !strings.Contains(bc.Name, *run) {
+			continue
+		}
+		before := stmts.Load()
+		status, size, err := get(app, tokens, bc)
+		count := int(stmts.Load() - before)
+		if err != nil || status != fiber.StatusOK {
+			fmt.Fprintf(w, "%s\tstatus %d %v\t\t\t\t\t\n", bc.Name, status, err)
+			failed = true
+			continue
+		}
+
+		durations := make([]time.Duration, 0, *n)
+		for i := 0; i < *n; i++ {
+			started := time.Now()
+			if _, _, err := get(app, tokens, bc); err != nil {
+				log.Fatalf("%s: %v", bc.Name, err)
+			}
+			durations = append(durations, time.Since(started))
+		}
+		mean, p95 := summarize(durations)
+
+		mark := ""
+		if count > bc.Budget {
+			mark = " !"
+			failed = true
+		}
+		fmt.Fprintf(w, "%s\t%d%s\t%d\t%s\t%s\t%d\t\n", bc.Name, count, mark, bc.Budget, mean, p95, size)
+	}
+	w.Flush()
+	if failed {
+		fmt.Println("\nFAIL: cases marked ! ran more statements than their budget, or did not answer 200")
+		os.Exit(1)
+	}
+}
+
+// benchCases returns the measured requests against cat; the teacher is
+// assigned to its first program
+func benchCases(cat *catalog) []benchCase {
+	p, s, l := cat.Program, cat.Subcourse, cat.Lesson
+	return []benchCase{
+		{"public programs", "/api/programs", "", 3},
+		{"public program subcourses", "/api/programs/" + p + "/subcourses", "", 3},
+		{"public subcourses", "/api/subcourses", "", 3},
+		{"public lessons", "/api/lessons", "", 3},
+		{"public lessons summary", "/api/lessons/summary", "", 2},
+		{"public subcourse lessons", "/api/subcourses/" + s + "/lessons", "", 3},
+		{"admin programs", "/api/admin/programs", "admin", 2},
+		{"admin program", "/api/admin/programs/" + p, "admin", 3},
+		{"admin subcourse", "/api/admin/subcourses/" + s, "admin", 4},
+		{"admin lessons", "/api/admin/lessons", "admin", 2},
+		{"admin lessons summary", "/api/admin/lessons/summary", "admin", 1},
+		// one statement per relation of the lesson tree, however many components
+		{"admin lesson", "/api/admin/lessons/" + l, "admin", 20},
+		{"teacher programs", "/api/admin/programs", "teacher", 4},
+		{"teacher lessons", "/api/admin/lessons", "teacher", 3},
+		{"teacher subcourse", "/api/admin/subcourses/" + s, "teacher", 7},
+		{"teacher lesson", "/api/admin/lessons/" + l, "teacher", 23},
+	}
+}
+
+// issueTokens signs a token for each user a case can run as
+func issueTokens(cat *catalog, secret string) (map[string]string, error) {
+	tokens := map[string]string{}
+	for as, user := range map[string]*models.User{"admin": &cat.Admin, "teacher": &cat.Teacher} {
+		token, err := utils.GenerateJWT(user, secret, 1)
+		if err != nil {
+			return nil, err
+		}
+		tokens[as] = token
+	}
+	return tokens, nil
+}
+
+// get runs the request of bc and returns its status and body size
+func get(app *fiber.App, tokens map[string]string, bc benchCase) (int, int, error) {
+	req := httptest.NewRequest(fiber.MethodGet, bc.Path, nil)
+	if bc.As == "" {
+		req.Header.Set(middleware.TenantHeader, benchSlug)
+	} else {
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer "+tokens[bc.As])
+	}
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, len(body), err
+}
+
+// newApp mounts the catalog read routes as cmd/server does, without the
+// response cache, so every request reaches the database
+func newApp(secret string) *fiber.App {
+	uow := repository.NewUnitOfWork(database.GetDB())
+	assignments := service.NewAssignmentService(uow)
+	authz := middleware.NewAuthorizer(assignments)
+	programHandler := handlers.NewProgramHandler(service.NewProgramService(uow, assignments), authz)
+	subcourseHandler := handlers.NewSubcourseHandler(service.NewSubcourseService(uow, assignments), authz)
+	lessonHandler := handlers.NewLessonHandler(service.NewLessonService(uow, assignments), authz)
+	authMiddleware := middleware.NewAuthMiddleware(secret, service.NewUserService(uow), service.NewOrganizationService(uow, assignments), assignments)
+	can := authMiddleware.RequirePermission
+
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	api := app.Group("/api", authMiddleware.TokenOptional(), authMiddleware.ResolveTenant())
+
+	admin := api.Group("/admin", authMiddleware.Protected())
+	admin.Get("/programs", can(models.PermProgramRead), programHandler.GetAll)
+	admin.Get("/programs/:id", can(models.PermProgramRead), programHandler.GetOne)
+	admin.Get("/subcourses/:id", can(models.PermSubcourseRead), subcourseHandler.GetOne)
+	admin.Get("/lessons", can(models.PermLessonRead), lessonHandler.GetAll)
+	admin.Get("/lessons/summary", can(models.PermLessonRead), lessonHandler.GetSummaries)
+	admin.Get("/lessons/:id", can(models.PermLessonRead), lessonHandler.GetOne)
+
+	api.Get("/programs", programHandler.GetAllPublic)
+	api.Get("/programs/:programId/subcourses", subcourseHandler.GetByProgram)
+	api.Get("/subcourses", subcourseHandler.GetAll)
+	api.Get("/lessons", lessonHandler.GetAllPublic)
+	api.Get("/lessons/summary", lessonHandler.GetSummariesPublic)
+	api.Get("/subcourses/:subcourseId/lessons", lessonHandler.GetBySubcourse)
+	return app
+}
+
+// countStatements adds every statement GORM runs on db to n
+func countStatements(db *gorm.DB, n *atomic.Int64) error {
+	count := func(*gorm.DB) { n.Add(1) }
+	cb := db.Callback()
+	for _, err := range []error{
+		cb.Create().After("gorm:create").Register("bench:create", count),
+		cb.Query().After("gorm:query").Register("bench:query", count),
+		cb.Update().After("gorm:update").Register("bench:update", count),
+		cb.Delete().After("gorm:delete").Register("bench:delete", count),
+		cb.Row().After("gorm:row").Register("bench:row", count),
+		cb.Raw().After("gorm:raw").Register("bench:raw", count),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// summarize returns the mean and the 95th percentile of durations
+func summarize(durations []time.Duration) (time.Duration, time.Duration) {
+	if len(durations) == 0 {
+		return 0, 0
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	mean := total / time.Duration(len(durations))
+	p95 := durations[(len(durations)*95-1)/100]
+	return mean.Round(time.Microsecond), p95.Round(time.Microsecond)
+}
+
+// randomSecret signs the bench's own tokens; the app only runs in-process
+func randomSecret() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		log.Fatalf("secret: %v", err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"courseai/backend/internal/config"
+	"courseai/backend/internal/logging"
 	"database/sql"
 	"fmt"
 	"log"
@@ -14,6 +15,7 @@ func main() {
 	if err != nil {
 		log.Fatalf("config load: %v", err)
 	}
+	logging.Setup(cfg.Log.Options())
 	db, err := sql.Open("postgres", cfg.Database.DSN())
 	if err != nil {
 		log.Fatalf("open db: %v", err)
@@ -0,0 +1,69 @@
+// Command checkdb reports orphaned rows in the content tree and, with -repair,
+// removes them, then installs the missing foreign keys and media triggers and
+// validates the constraints the server added while orphans existed.
+package main
+
+import (
+	"courseai/backend/internal/config"
+	"courseai/backend/internal/database"
+	"courseai/backend/internal/logging"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func main() {
+	repair := flag.Bool("repair", false, "fix the issues found and install and validate the constraints")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("config load: %v", err)
+	}
+	logging.Setup(cfg.Log.Options())
+	if err := database.Connect(cfg.Database.DSN(), cfg.Database.Pool()); err != nil {
+		log.Fatalf("connect: %v", err)
+	}
+	db := database.GetDB().Session(&gorm.Session{Logger: logger.Default.LogMode(logger.Warn)})
+
+	issues, err := database.CheckIntegrity(db)
+	if err != nil {
+		log.Fatalf("check: %v", err)
+	}
+	if len(issues) == 0 {
+		fmt.Println("No integrity issues found")
+	} else {
+		fmt.Println("Integrity issues:")
+		for _, issue := range issues {
+			fmt.Println(" -", issue)
+		}
+	}
+
+	if !*repair {
+		if len(issues) > 0 {
+			fmt.Println("Run with -repair to fix them")
+			os.Exit(1)
+		}
+		return
+	}
+
+	fixed, err := database.RepairIntegrity(db)
+	if err != nil {
+		log.Fatalf("repair: %v", err)
+	}
+	for _, issue := range fixed {
+		fmt.Println("Repaired", issue)
+	}
+	if err := database.EnsureConstraints(db); err != nil {
+		log.Fatalf("constraints: %v", err)
+	}
+	fmt.Println("Foreign keys and media triggers installed and validated")
+	if err := database.RecomputeCounters(db); err != nil {
+		log.Fatalf("counters: %v", err)
+	}
+	fmt.Println("Counters recomputed")
+}
@@ -0,0 +1,51 @@
+// Command config loads the configuration the way the server does and either
+// prints it or only validates it:
+//
+//	go run ./cmd/config print [--redacted]
+//	go run ./cmd/config validate
+//
+// The printed YAML can be used as a config file.
+package main
+
+import (
+	"courseai/backend/internal/config"
+	"flag"
+	"fmt"
+	"os"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: config print [--redacted] | config validate")
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "print":
+		flags := flag.NewFlagSet("print", flag.ExitOnError)
+		redacted := flags.Bool("redacted", false, "hide secrets (passwords, tokens, the JWT secret)")
+		_ = flags.Parse(os.Args[2:])
+		cfg := load()
+		if err := config.Print(os.Stdout, cfg, *redacted); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "validate":
+		load()
+		fmt.Println("configuration is valid")
+	default:
+		usage()
+	}
+}
+
+func load() *config.Config {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	return cfg
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"courseai/backend/internal/config"
 	"courseai/backend/internal/database"
+	"courseai/backend/internal/logging"
 	"log"
 )
 
@@ -11,8 +12,9 @@ func main() {
 	if err != nil {
 		log.Fatalf("failed to load config: %v", err)
 	}
+	logging.Setup(cfg.Log.Options())
 
-	if err := database.Connect(cfg.Database.DSN()); err != nil {
+	if err := database.Connect(cfg.Database.DSN(), cfg.Database.Pool()); err != nil {
 		log.Fatalf("failed to connect to database: %v", err)
 	}
 
@@ -0,0 +1,227 @@
+// Command oidcdev is a minimal OpenID Connect provider for local SSO testing.
+// It auto-approves every authorization request for a single configurable user.
+//
+//	go run ./cmd/oidcdev -email teacher@example.com -groups lms-teachers
+//
+// Point the backend at it with OIDC_ENABLED=true OIDC_ISSUER_URL=http://localhost:9998 OIDC_CLIENT_ID=courseai.
+package main
+
+import (
+	"courseai/backend/internal/oidc"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const keyID = "oidcdev-1"
+
+type authRequest struct {
+	ClientID      string
+	RedirectURI   string
+	Nonce         string
+	CodeChallenge string
+	ExpiresAt     time.Time
+}
+
+type server struct {
+	issuer   string
+	clientID string
+	subject  string
+	email    string
+	name     string
+	groups   []string
+	key      *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]authRequest
+}
+
+func main() {
+	addr := flag.String("addr", ":9998", "listen address")
+	issuer := flag.String("issuer", "http://localhost:9998", "issuer URL (must match OIDC_ISSUER_URL)")
+	clientID := flag.String("client", "courseai", "accepted client_id")
+	subject := flag.String("sub", "dev-user-1", "subject of the signed-in user")
+	email := flag.String("email", "teacher@example.com", "email of the signed-in user")
+	name := flag.String("name", "Dev Teacher", "display name of the signed-in user")
+	groups := flag.String("groups", "lms-teachers", "comma-separated groups claim")
+	flag.Parse()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatalf("generate key: %v", err)
+	}
+
+	s := &server{
+		issuer:   strings.TrimSuffix(*issuer, "/"),
+		clientID: *clientID,
+		subject:  *subject,
+		email:    *email,
+		name:     *name,
+		key:      key,
+		codes:    map[string]authRequest{},
+	}
+	for _, g := range strings.Split(*groups, ",") {
+		if g = strings.TrimSpace(g); g != "" {
+			s.groups = append(s.groups, g)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/authorize", s.authorize)
+	mux.HandleFunc("/token", s.token)
+	mux.HandleFunc("/jwks", s.jwks)
+
+	log.Printf("oidcdev issuer %s listening on %s (user %s, groups %v)", s.issuer, *addr, s.email, s.groups)
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
+
+func (s *server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                s.issuer,
+		"authorization_endpoint":                s.issuer + "/authorize",
+		"token_endpoint":                        s.issuer + "/token",
+		"jwks_uri":                              s.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+// authorize approves the request immediately and redirects back with a code
+func (s *server) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("response_type") != "code" || q.Get("client_id") != s.clientID {
+		http.Error(w, "unsupported response_type or unknown client_id", http.StatusBadRequest)
+		return
+	}
+	if q.Get("code_challenge") == "" || q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "PKCE with S256 is required", http.StatusBadRequest)
+		return
+	}
+	redirectURI, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || redirectURI.Scheme == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	code, err := oidc.RandomString(24)
+	if err != nil {
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	s.mu.Lock()
+	s.codes[code] = authRequest{
+		ClientID:      q.Get("client_id"),
+		RedirectURI:   q.Get("redirect_uri"),
+		Nonce:         q.Get("nonce"),
+		CodeChallenge: q.Get("code_challenge"),
+		ExpiresAt:     time.Now().Add(time.Minute),
+	}
+	s.mu.Unlock()
+
+	params := redirectURI.Query()
+	params.Set("code", code)
+	params.Set("state", q.Get("state"))
+	redirectURI.RawQuery = params.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (s *server) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request")
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, "unsupported_grant_type")
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	s.mu.Lock()
+	req, ok := s.codes[code]
+	delete(s.codes, code)
+	s.mu.Unlock()
+
+	switch {
+	case !ok || time.Now().After(req.ExpiresAt):
+		tokenError(w, "invalid_grant")
+		return
+	case r.PostForm.Get("client_id") != req.ClientID || r.PostForm.Get("redirect_uri") != req.RedirectURI:
+		tokenError(w, "invalid_grant")
+		return
+	case oidc.S256Challenge(r.PostForm.Get("code_verifier")) != req.CodeChallenge:
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":                s.issuer,
+		"sub":                s.subject,
+		"aud":                req.ClientID,
+		"iat":                now.Unix(),
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"nonce":              req.Nonce,
+		"email":              s.email,
+		"email_verified":     true,
+		"name":               s.name,
+		"preferred_username": strings.Split(s.email, "@")[0],
+		"groups":             s.groups,
+	}
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	tok.Header["kid"] = keyID
+	idToken, err := tok.SignedString(s.key)
+	if err != nil {
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+
+	accessToken, _ := oidc.RandomString(24)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": accessToken,
+		"id_token":     idToken,
+		"token_type":   "Bearer",
+		"expires_in":   300,
+	})
+}
+
+func (s *server) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := s.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": keyID,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
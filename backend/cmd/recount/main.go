@@ -0,0 +1,26 @@
+// Command recount recomputes the denormalized counters on programs,
+// subcourses and lessons from the rows below them.
+package main
+
+import (
+	"courseai/backend/internal/config"
+	"courseai/backend/internal/database"
+	"courseai/backend/internal/logging"
+	"fmt"
+	"log"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("config load: %v", err)
+	}
+	logging.Setup(cfg.Log.Options())
+	if err := database.Connect(cfg.Database.DSN(), cfg.Database.Pool()); err != nil {
+		log.Fatalf("connect: %v", err)
+	}
+	if err := database.RecomputeCounters(database.GetDB()); err != nil {
+		log.Fatalf("recount: %v", err)
+	}
+	fmt.Println("Counters recomputed")
+}
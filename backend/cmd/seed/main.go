@@ -3,6 +3,7 @@ package main
 import (
 	"courseai/backend/internal/config"
 	"courseai/backend/internal/database"
+	"courseai/backend/internal/logging"
 	"courseai/backend/internal/models"
 	"encoding/json"
 	"fmt"
@@ -17,8 +18,13 @@ func upsertProgram(db *gorm.DB) (models.Program, error) {
 	if err := db.First(&prog, "slug = ?", slug).Error; err == nil {
 		return prog, nil
 	}
+	org, err := database.DefaultOrganization(db)
+	if err != nil {
+		return models.Program{}, err
+	}
 	blockTypes, _ := json.Marshal([]string{"cover", "text", "video"})
 	prog = models.Program{
+		OrganizationID:   &org.ID,
 		Name:             "Chương trình Lập trình Cơ bản (Seed)",
 		Slug:             slug,
 		ShortDescription: "Khóa học mẫu tiếng Việt tạo bởi seed runner",
@@ -41,10 +47,10 @@ func upsertSubcourse(db *gorm.DB, prog models.Program) (models.Subcourse, error)
 	blockTypes, _ := json.Marshal([]string{"text", "video"})
 	sc = models.Subcourse{
 		ProgramID:        prog.ID,
+		OrganizationID:   prog.OrganizationID,
 		Name:             "Lập trình — Mô-đun Seed",
 		Slug:             slug,
 		AgeRange:         "8-12",
-		LessonCount:      4,
 		ShortDescription: "Mô-đun mẫu bằng tiếng Việt để phát triển và kiểm thử",
 		BlockTypes:       blockTypes,
 		Status:           models.StatusPublished,
@@ -64,14 +70,15 @@ func upsertLessons(db *gorm.DB, sc models.Subcourse) error {
 		}
 		blockTypes, _ := json.Marshal([]string{"text", "video", "file"})
 		lesson := models.Lesson{
-			SubcourseID: sc.ID,
-			Title:       fmt.Sprintf("Bài %d: %s", i, sc.Name),
-			Subtitle:    "Bài học mẫu tiếng Việt",
-			Overview:    "Nội dung minh họa: giải thích, ví dụ và bài tập thực hành.",
-			BlockTypes:  blockTypes,
-			Status:      models.StatusPublished,
-			Slug:        slug,
-			SortOrder:   i,
+			SubcourseID:    sc.ID,
+			OrganizationID: sc.OrganizationID,
+			Title:          fmt.Sprintf("Bài %d: %s", i, sc.Name),
+			Subtitle:       "Bài học mẫu tiếng Việt",
+			Overview:       "Nội dung minh họa: giải thích, ví dụ và bài tập thực hành.",
+			BlockTypes:     blockTypes,
+			Status:         models.StatusPublished,
+			Slug:           slug,
+			SortOrder:      i,
 		}
 		if err := db.Create(&lesson).Error; err != nil {
 			return err
@@ -94,7 +101,8 @@ func main() {
 	if err != nil {
 		log.Fatal("failed to load config:", err)
 	}
-	if err := database.Connect(cfg.Database.DSN()); err != nil {
+	logging.Setup(cfg.Log.Options())
+	if err := database.Connect(cfg.Database.DSN(), cfg.Database.Pool()); err != nil {
 		log.Fatal("failed to connect to db:", err)
 	}
 	db := database.GetDB()
@@ -120,4 +128,8 @@ func main() {
 		log.Fatal("failed to create lessons:", err)
 	}
 	fmt.Println("Lessons ensured for subcourse:", sc.Slug)
+
+	if err := database.RecomputeCounters(db); err != nil {
+		log.Fatal("failed to compute counters:", err)
+	}
 }
@@ -2,13 +2,26 @@ package main
 
 import (
 	"context"
+	"courseai/backend/internal/cache"
 	"courseai/backend/internal/config"
 	"courseai/backend/internal/database"
 	"courseai/backend/internal/handlers"
+	"courseai/backend/internal/health"
+	"courseai/backend/internal/logging"
+	"courseai/backend/internal/metrics"
 	"courseai/backend/internal/middleware"
+	"courseai/backend/internal/models"
+	"courseai/backend/internal/openapi"
+	"courseai/backend/internal/ratelimit"
+	"courseai/backend/internal/realtime"
+	"courseai/backend/internal/repository"
+	"courseai/backend/internal/security"
+	"courseai/backend/internal/service"
+	"courseai/backend/internal/telemetry"
 	"fmt"
 	"log"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
@@ -16,35 +29,36 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 )
 
 func main() {
-	// Debug: Log environment variables early
-	log.Println("═══════════════════════════════════════════════════════════════")
-	log.Println("🚀 CourseAI Backend Starting")
-	log.Println("═══════════════════════════════════════════════════════════════")
-	log.Printf("DATABASE_URL set: %v\n", os.Getenv("DATABASE_URL") != "")
-	log.Println("═══════════════════════════════════════════════════════════════")
-
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatal("Failed to load config:", err)
 	}
+	// Structured, redacted logging; log.Printf output goes through it too
+	logging.Setup(cfg.Log.Options())
+
+	// Tracing (OTEL_TRACES_EXPORTER=otlp or stdout); flushed on shutdown
+	shutdownTracing, err := telemetry.Setup(cfg.Tracing.Options())
+	if err != nil {
+		log.Fatal("Failed to set up tracing:", err)
+	}
 
 	// Connect to database
-	if err := database.Connect(cfg.Database.DSN()); err != nil {
+	if err := database.Connect(cfg.Database.DSN(), cfg.Database.Pool()); err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
 	// Run migrations
 	if err := database.AutoMigrate(); err != nil {
 		// In development, do not exit the process on migration error. Log and continue.
-		if os.Getenv("ENV") == "production" {
+		if cfg.IsProduction() {
 			log.Fatal("Failed to run migrations:", err)
 		}
 		log.Println("Warning: migrations failed (non-fatal in dev):", err)
@@ -52,7 +66,7 @@ func main() {
 
 	// Seed data (skip seeding if migrations didn't create tables)
 	if err := database.SeedData(database.GetDB()); err != nil {
-		if os.Getenv("ENV") == "production" {
+		if cfg.IsProduction() {
 			log.Fatal("Failed to seed data:", err)
 		}
 		log.Println("Warning: seeding data failed (non-fatal in dev):", err)
@@ -60,132 +74,372 @@ func main() {
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
-		// allow larger request bodies to support media uploads up to 64MB
-		BodyLimit: 64 << 20, // 64 MB
-		ErrorHandler: func(c *fiber.Ctx, err error) error {
-			code := fiber.StatusInternalServerError
-			if e, ok := err.(*fiber.Error); ok {
-				code = e.Code
-			}
-			return c.Status(code).JSON(fiber.Map{
-				"error": err.Error(),
-			})
-		},
+		// room for media uploads (UPLOAD_MAX_REQUEST_MB)
+		BodyLimit: cfg.Uploads.MaxRequestBytes(),
+		// {"error": ..., "request_id": ...}
+		ErrorHandler: middleware.ErrorHandler,
+		// c.IP() is the client behind a trusted proxy (PROXY_HEADER,
+		// TRUSTED_PROXIES), or the peer when the header is missing, invalid
+		// or comes from anyone else
+		ProxyHeader:             cfg.Server.ProxyHeader,
+		EnableTrustedProxyCheck: cfg.Server.ProxyHeader != "",
+		TrustedProxies:          cfg.Server.TrustedProxies,
+		EnableIPValidation:      true,
 	})
 
 	// Middleware
-	app.Use(recover.New())
-	app.Use(logger.New())
+	app.Use(middleware.RequestID())
+	app.Use(middleware.Tracing())
+	app.Use(middleware.Metrics())
+	app.Use(middleware.AccessLog())
+	app.Use(recover.New(recover.Config{EnableStackTrace: true}))
 
-	// Configure CORS - allow frontend to call this backend
+	// Configure CORS - allow the frontend (CORS_ORIGINS, FRONTEND_URL by default) to call this backend
 	app.Use(cors.New(cors.Config{
-		AllowOrigins:     "https://letscode-tau.vercel.app",
-		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, Cache-Control, Pragma, X-Requested-With",
-		AllowMethods:     "GET, POST, PUT, DELETE, OPTIONS",
+		AllowOrigins:     strings.Join(cfg.Server.CORSOrigins, ", "),
+		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, Cache-Control, Pragma, X-Requested-With, X-Organization, If-Match, If-None-Match, If-Modified-Since, X-Request-ID, traceparent, tracestate",
+		AllowMethods:     "GET, POST, PUT, PATCH, DELETE, OPTIONS",
 		AllowCredentials: true,
-		ExposeHeaders:    "Authorization",
+		ExposeHeaders:    "Authorization, ETag, Last-Modified, X-Version, X-Cache, X-Request-ID, Retry-After, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy",
 	}))
 
+	// Domain services share one unit of work over the database
+	uow := repository.NewUnitOfWork(database.GetDB())
+	assignmentService := service.NewAssignmentService(uow)
+	programService := service.NewProgramService(uow, assignmentService)
+	subcourseService := service.NewSubcourseService(uow, assignmentService)
+	lessonService := service.NewLessonService(uow, assignmentService)
+	uploadsDir := "uploads"
+	mediaService := service.NewMediaService(uow, assignmentService, uploadsDir, cfg.Uploads.MaxFileBytes())
+	templateService := service.NewTemplateService(uow, assignmentService)
+	cloneService := service.NewCloneService(uow, assignmentService, uploadsDir)
+	trashRetention := time.Duration(cfg.Trash.RetentionDays) * 24 * time.Hour
+	trashService := service.NewTrashService(uow, assignmentService, uploadsDir, trashRetention)
+	organizationService := service.NewOrganizationService(uow, assignmentService)
+	userService := service.NewUserService(uow)
+	twoFactorService := service.NewTwoFactorService(uow)
+	signOnService := service.NewSignOnService(uow, cfg.OIDC)
+	guard := security.NewLoginGuard(uow, cfg.Security)
+	authz := middleware.NewAuthorizer(assignmentService)
+
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(cfg)
-	programHandler := handlers.NewProgramHandler()
-	subcourseHandler := handlers.NewSubcourseHandler()
-	lessonHandler := handlers.NewLessonHandler()
-	mediaHandler := handlers.NewMediaHandler(cfg)
-	seedHandler := handlers.NewSeedHandler()
-	teacherHandler := handlers.NewTeacherHandler()
+	authHandler := handlers.NewAuthHandler(cfg, guard, userService, twoFactorService, assignmentService)
+	programHandler := handlers.NewProgramHandler(programService, authz)
+	subcourseHandler := handlers.NewSubcourseHandler(subcourseService, authz)
+	lessonHandler := handlers.NewLessonHandler(lessonService, authz)
+	mediaHandler := handlers.NewMediaHandler(mediaService)
+	seedHandler := handlers.NewSeedHandler(database.GetDB())
+	teacherHandler := handlers.NewTeacherHandler(assignmentService)
+	userHandler := handlers.NewUserHandler(userService)
+	organizationHandler := handlers.NewOrganizationHandler(organizationService)
+	templateHandler := handlers.NewTemplateHandler(templateService)
+	oidcHandler := handlers.NewOIDCHandler(cfg, guard, signOnService, userService)
+	trashHandler := handlers.NewTrashHandler(trashService)
+	cloneHandler := handlers.NewCloneHandler(cloneService)
+
+	// Readiness: dependencies this replica needs to serve traffic. Liveness:
+	// background workers, which only a restart brings back.
+	checker := health.NewChecker()
+	checker.AddReadiness(
+		health.Database(database.GetDB()),
+		health.Check{Name: "schema", Run: database.CheckSchema},
+		health.Writable("storage", uploadsDir),
+	)
+
+	// Lesson editor presence hub; with REALTIME_PG_NOTIFY it is shared across replicas
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	hub := realtime.NewHub(
+		time.Duration(cfg.Realtime.LockSeconds)*time.Second,
+		time.Duration(cfg.Realtime.PresenceSeconds)*time.Second,
+	)
+	realtime.SetDefault(hub)
+	go hub.Run(bgCtx)
+	checker.AddLiveness(health.Worker(realtime.HubWorker, time.Minute))
+	if cfg.Realtime.PGNotify {
+		relay, err := realtime.NewPGRelay(cfg.Database.DSN(), database.GetDB(), hub)
+		if err != nil {
+			log.Println("Warning: realtime relay disabled, presence is per-replica:", err)
+		} else {
+			go relay.Run(bgCtx)
+			checker.AddLiveness(health.Worker(realtime.RelayWorker, 5*time.Minute))
+		}
+	}
+	realtimeHandler := handlers.NewRealtimeHandler(hub, authz)
+
+	// Public catalog responses (CACHE_STORE); database triggers report every
+	// changed row over LISTEN/NOTIFY and the entries built from it are dropped
+	var catalogCache *cache.Cache
+	switch cfg.Cache.Store {
+	case "memory":
+		memory := cache.NewMemory(cfg.Cache.MaxEntries, cfg.Cache.MaxBytes())
+		catalogCache = cache.New(memory, cfg.Cache.TTL())
+		metrics.NewGaugeFunc("courseai_cache_entries", "Entries in the memory response cache.", func() float64 {
+			entries, _ := memory.Len()
+			return float64(entries)
+		})
+		metrics.NewGaugeFunc("courseai_cache_bytes", "Size of the response bodies in the memory response cache.", func() float64 {
+			_, bytes := memory.Len()
+			return float64(bytes)
+		})
+	case "postgres":
+		catalogCache = cache.New(cache.NewPostgres(database.GetDB()), cfg.Cache.TTL())
+	}
+	if catalogCache != nil {
+		listener, err := cache.NewListener(cfg.Database.DSN(), catalogCache)
+		if err != nil {
+			// without invalidations entries would go stale; validators still work
+			log.Println("Warning: response cache disabled, cannot listen for invalidations:", err)
+			catalogCache = nil
+		} else {
+			go listener.Run(bgCtx)
+			checker.AddLiveness(health.Worker(cache.Worker, 5*time.Minute))
+		}
+	}
+	responseCache := middleware.NewResponseCache(catalogCache)
+
+	// Token buckets per caller and route group (RATE_LIMIT_STORE); refilled
+	// buckets are swept every minute
+	var limiter *ratelimit.Limiter
+	switch cfg.RateLimit.Store {
+	case "memory":
+		buckets := ratelimit.NewMemory()
+		limiter = ratelimit.New(buckets)
+		metrics.NewGaugeFunc("courseai_rate_limit_buckets", "Buckets in the memory rate limit store.", func() float64 {
+			return float64(buckets.Len())
+		})
+	case "postgres":
+		limiter = ratelimit.New(ratelimit.NewPostgres(database.GetDB()))
+	}
+	go limiter.Run(bgCtx, time.Minute)
+	limit := middleware.NewRateLimits(limiter, cfg.RateLimit.Limits()).Group
+
+	// Purge content that outlived its time in the trash
+	purgeInterval := time.Duration(cfg.Trash.PurgeIntervalMinutes) * time.Minute
+	go trashService.RunPurge(bgCtx, purgeInterval)
+	checker.AddLiveness(health.Worker(service.TrashPurgeJob, 2*purgeInterval+5*time.Minute))
 
 	// Initialize middleware
-	authMiddleware := middleware.NewAuthMiddleware(cfg.JWT.Secret)
+	authMiddleware := middleware.NewAuthMiddleware(cfg.JWT.Secret, userService, organizationService, assignmentService)
+
+	// OpenAPI document of every route below; it also drives request validation
+	apiSpec := handlers.BuildAPISpec()
 
 	// Routes
 	api := app.Group("/api")
-	// allow optional token parsing on public API so handlers can apply scope-based filtering
+	// WebSocket handshakes carry the token in the query string
+	app.Use("/api/ws", authMiddleware.TokenFromQuery())
+	// allow optional token parsing on public API so handlers can apply scope-based filtering,
+	// then resolve the organization (tenant) every query is filtered by
 	api.Use(authMiddleware.TokenOptional())
+	api.Use(authMiddleware.ResolveTenant())
+	api.Use(apiSpec.Validator(openapi.ValidateOptions{
+		Requests:  cfg.API.ValidateRequests,
+		Responses: cfg.API.ValidateResponses,
+	}))
+	api.Get("/openapi.json", func(c *fiber.Ctx) error {
+		return c.JSON(apiSpec.Doc)
+	})
 
 	// Auth routes (public)
 	auth := api.Group("/auth")
-	auth.Post("/login", authHandler.Login)
+	// Routes that check credentials or codes share the login rate limit
+	loginLimit := limit(ratelimit.Login)
+	auth.Post("/login", loginLimit, authHandler.Login)
+
+	// Single sign-on (OpenID Connect)
+	auth.Get("/oidc/login", loginLimit, oidcHandler.Login)
+	auth.Get("/oidc/callback", loginLimit, oidcHandler.Callback)
+	auth.Post("/oidc/link", authMiddleware.Protected(), oidcHandler.Link)
+
+	// Two-factor authentication. verify completes a login; setup/enable also accept
+	// the enrollment challenge token when 2FA is mandatory but not yet set up.
+	auth.Post("/2fa/verify", loginLimit, authHandler.VerifyTwoFactor)
+	auth.Post("/2fa/setup", loginLimit, authHandler.SetupTwoFactor)
+	auth.Post("/2fa/enable", loginLimit, authHandler.EnableTwoFactor)
+	auth.Post("/2fa/disable", loginLimit, authMiddleware.Protected(), authHandler.DisableTwoFactor)
+	auth.Post("/2fa/recovery-codes", loginLimit, authMiddleware.Protected(), authHandler.RegenerateRecoveryCodes)
+	auth.Get("/2fa/status", authMiddleware.Protected(), authHandler.GetTwoFactorStatus)
 
 	// Protected auth routes
 	auth.Get("/me", authMiddleware.Protected(), authHandler.Me)
+	auth.Get("/me/login-history", authMiddleware.Protected(), authHandler.GetMyLoginHistory)
+	auth.Get("/me/permissions", authMiddleware.Protected(), userHandler.GetMyPermissions)
+	auth.Get("/me/identities", authMiddleware.Protected(), oidcHandler.GetMyIdentities)
+	auth.Delete("/me/identities/:id", authMiddleware.Protected(), oidcHandler.UnlinkIdentity)
 
-	// Admin routes (protected)
-	admin := api.Group("/admin", authMiddleware.Protected())
+	// Admin routes (protected). Every route declares the permission it requires.
+	admin := api.Group("/admin", limit(ratelimit.Admin), authMiddleware.Protected())
+	can := authMiddleware.RequirePermission
 
 	// Programs
-	admin.Get("/programs", programHandler.GetAll)
-	admin.Get("/programs/:id", programHandler.GetOne)
-	admin.Post("/programs", programHandler.Create)
-	admin.Put("/programs/:id", programHandler.Update)
-	admin.Delete("/programs/:id", programHandler.Delete)
+	admin.Get("/programs", can(models.PermProgramRead), programHandler.GetAll)
+	admin.Get("/programs/:id", can(models.PermProgramRead), programHandler.GetOne)
+	admin.Post("/programs", can(models.PermProgramCreate), programHandler.Create)
+	admin.Put("/programs/order", can(models.PermProgramWrite), programHandler.Reorder)
+	admin.Put("/programs/:id", can(models.PermProgramWrite), programHandler.Update)
+	admin.Delete("/programs/:id", can(models.PermProgramDelete), programHandler.Delete)
+	admin.Get("/programs/:id/delete-impact", can(models.PermProgramDelete), trashHandler.Impact(handlers.TrashProgram))
+	admin.Post("/programs/:id/clone", can(models.PermProgramCreate), cloneHandler.CloneProgram)
 
 	// Subcourses
-	admin.Get("/subcourses", subcourseHandler.GetAll)
-	admin.Get("/subcourses/:id", subcourseHandler.GetOne)
-	admin.Get("/programs/:programId/subcourses", subcourseHandler.GetByProgram)
-	admin.Post("/subcourses", subcourseHandler.Create)
-	admin.Put("/subcourses/:id", subcourseHandler.Update)
-	admin.Delete("/subcourses/:id", subcourseHandler.Delete)
+	admin.Get("/subcourses", can(models.PermSubcourseRead), subcourseHandler.GetAll)
+	admin.Get("/subcourses/:id", can(models.PermSubcourseRead), subcourseHandler.GetOne)
+	admin.Get("/programs/:programId/subcourses", can(models.PermSubcourseRead), subcourseHandler.GetByProgram)
+	admin.Post("/subcourses", can(models.PermSubcourseWrite), subcourseHandler.Create)
+	admin.Put("/subcourses/:id", can(models.PermSubcourseWrite), subcourseHandler.Update)
+	admin.Delete("/subcourses/:id", can(models.PermSubcourseDelete), subcourseHandler.Delete)
+	admin.Get("/subcourses/:id/delete-impact", can(models.PermSubcourseDelete), trashHandler.Impact(handlers.TrashSubcourse))
+	admin.Post("/subcourses/:id/clone", can(models.PermSubcourseWrite), cloneHandler.CloneSubcourse)
+	admin.Put("/programs/:id/subcourses/order", can(models.PermSubcourseWrite), subcourseHandler.Reorder)
+	admin.Post("/subcourses/:id/move", can(models.PermSubcourseWrite), subcourseHandler.Move)
 
 	// Lessons
-	admin.Get("/lessons", lessonHandler.GetAll)
-	admin.Get("/lessons/:id", lessonHandler.GetOne)
-	admin.Get("/subcourses/:subcourseId/lessons", lessonHandler.GetBySubcourse)
-	admin.Post("/lessons", lessonHandler.Create)
-	admin.Put("/lessons/:id", lessonHandler.Update)
-	admin.Delete("/lessons/:id", lessonHandler.Delete)
+	admin.Get("/lessons", can(models.PermLessonRead), lessonHandler.GetAll)
+	admin.Get("/lessons/summary", can(models.PermLessonRead), lessonHandler.GetSummaries)
+	admin.Get("/lessons/:id", can(models.PermLessonRead), lessonHandler.GetOne)
+	admin.Get("/subcourses/:subcourseId/lessons", can(models.PermLessonRead), lessonHandler.GetBySubcourse)
+	admin.Post("/lessons", can(models.PermLessonWrite), lessonHandler.Create)
+	admin.Put("/lessons/:id", can(models.PermLessonWrite), lessonHandler.Update)
+	admin.Put("/lessons/:id/status", can(models.PermLessonPublish), lessonHandler.SetStatus)
+	admin.Delete("/lessons/:id", can(models.PermLessonDelete), lessonHandler.Delete)
+	admin.Get("/lessons/:id/delete-impact", can(models.PermLessonDelete), trashHandler.Impact(handlers.TrashLesson))
+	admin.Post("/lessons/:id/clone", can(models.PermLessonWrite), cloneHandler.CloneLesson)
+	admin.Put("/subcourses/:id/lessons/order", can(models.PermLessonWrite), lessonHandler.Reorder)
+	admin.Post("/lessons/:id/move", can(models.PermLessonWrite), lessonHandler.Move)
+	admin.Patch("/lessons/:id", can(models.PermLessonWrite), lessonHandler.PatchTree)
+
+	// Granular lesson component routes
+	registerComponent := func(base string, comp *repository.LessonComponent) {
+		admin.Get(base, can(models.PermLessonRead), lessonHandler.ListComponents(comp))
+		if comp.Single {
+			admin.Put(base, can(models.PermLessonWrite), lessonHandler.PutSingleComponent(comp))
+			admin.Delete(base, can(models.PermLessonWrite), lessonHandler.DeleteComponent(comp))
+			return
+		}
+		admin.Post(base, can(models.PermLessonWrite), lessonHandler.CreateComponent(comp))
+		admin.Put(base+"/order", can(models.PermLessonWrite), lessonHandler.ReorderComponents(comp))
+		admin.Patch(base+"/:componentId", can(models.PermLessonWrite), lessonHandler.PatchComponent(comp))
+		admin.Delete(base+"/:componentId", can(models.PermLessonWrite), lessonHandler.DeleteComponent(comp))
+	}
+	for _, comp := range repository.LessonComponents {
+		registerComponent("/lessons/:id/"+comp.Path, comp)
+	}
+	registerComponent("/lessons/:id/quizzes/:quizId/options", repository.QuizOptionsComponent)
+
+	// Lesson templates (per program); POST /lessons accepts template_id
+	admin.Get("/programs/:programId/templates", can(models.PermLessonRead), templateHandler.GetByProgram)
+	admin.Post("/programs/:programId/templates", can(models.PermProgramWrite), templateHandler.Create)
+	admin.Get("/templates/:id", can(models.PermLessonRead), templateHandler.GetOne)
+	admin.Put("/templates/:id", can(models.PermProgramWrite), templateHandler.Update)
+	admin.Delete("/templates/:id", can(models.PermProgramWrite), templateHandler.Delete)
+
+	// Trash; each type needs its delete permission, checked in the handler
+	canTrash := authMiddleware.RequireAnyPermission(handlers.TrashPermissions()...)
+	admin.Get("/trash", canTrash, trashHandler.List)
+	admin.Post("/trash/:type/:id/restore", canTrash, trashHandler.Restore)
+	admin.Delete("/trash/:type/:id", canTrash, trashHandler.Purge)
 
 	// Teachers
-	admin.Get("/teachers", teacherHandler.GetAll)
-	admin.Post("/teachers", teacherHandler.Create)
-	admin.Get("/teachers/history", teacherHandler.GetTeacherHistory)
-	admin.Put("/teachers/:id/program-assignments", teacherHandler.AssignPrograms)
-	admin.Put("/teachers/:id/subcourse-assignments", teacherHandler.AssignSubcourses)
-	admin.Get("/teachers/:teacherId/assignments", teacherHandler.GetAssignments)
-	admin.Get("/teachers/:teacherId/lesson-history", teacherHandler.GetTeacherLessonHistory)
+	admin.Get("/teachers", can(models.PermTeacherRead), teacherHandler.GetAll)
+	admin.Post("/teachers", can(models.PermTeacherManage), teacherHandler.Create)
+	admin.Get("/teachers/history", can(models.PermTeacherRead), teacherHandler.GetTeacherHistory)
+	admin.Put("/teachers/:id/program-assignments", can(models.PermTeacherManage), teacherHandler.AssignPrograms)
+	admin.Put("/teachers/:id/subcourse-assignments", can(models.PermTeacherManage), teacherHandler.AssignSubcourses)
+	admin.Get("/teachers/:teacherId/assignments", can(models.PermTeacherRead), teacherHandler.GetAssignments)
+	admin.Get("/teachers/:teacherId/lesson-history", can(models.PermTeacherRead), teacherHandler.GetTeacherLessonHistory)
+
+	// Organizations (tenants) and program sharing
+	admin.Get("/organization", can(models.PermOrgRead), organizationHandler.GetCurrent)
+	admin.Get("/organizations", can(models.PermOrgManage), organizationHandler.GetAll)
+	admin.Post("/organizations", can(models.PermOrgManage), organizationHandler.Create)
+	admin.Put("/organizations/:id", can(models.PermOrgManage), organizationHandler.Update)
+	admin.Get("/programs/:id/shares", can(models.PermProgramWrite), organizationHandler.GetProgramShares)
+	admin.Post("/programs/:id/shares", can(models.PermProgramWrite), organizationHandler.ShareProgram)
+	admin.Delete("/programs/:id/shares/:orgId", can(models.PermProgramWrite), organizationHandler.UnshareProgram)
+
+	// Users, roles and permissions
+	admin.Get("/roles", can(models.PermPermissionRead), userHandler.ListRoles)
+	admin.Get("/users/:id/permissions", can(models.PermPermissionRead), userHandler.GetPermissions)
+	admin.Put("/users/:id/role", can(models.PermUserManage), userHandler.SetRole)
+	admin.Put("/users/:id/password-login", can(models.PermUserManage), userHandler.SetPasswordLogin)
+
+	// Login security
+	admin.Get("/users/:id/login-history", can(models.PermUserManage), authHandler.GetLoginHistory)
+	admin.Post("/users/:id/unlock", can(models.PermUserManage), authHandler.UnlockUser)
+	admin.Delete("/users/:id/2fa", can(models.PermUserManage), authHandler.ResetTwoFactor)
 
 	// Media upload
-	admin.Post("/media/upload", mediaHandler.Upload)
+	admin.Post("/media/upload", limit(ratelimit.Upload), can(models.PermMediaUpload), mediaHandler.Upload)
+	admin.Put("/media/order", can(models.PermLessonWrite), mediaHandler.Reorder)
 	// Admin seed trigger (protected)
-	admin.Post("/seed", seedHandler.Run)
+	admin.Post("/seed", can(models.PermSystemSeed), seedHandler.Run)
+
+	// Lesson editor presence, soft locks and save notifications (WebSocket)
+	api.Get("/ws/lessons/:id", authMiddleware.Protected(), can(models.PermLessonRead), realtimeHandler.Upgrade, websocket.New(realtimeHandler.Serve))
 
-	// Health check
+	// Prometheus metrics; on the API port only with METRICS_TOKEN (see the admin listener below)
+	if sqlDB, err := database.GetDB().DB(); err == nil {
+		metrics.RegisterDBStats(sqlDB)
+	}
+	app.Get("/metrics", middleware.MetricsEndpoint(cfg.Metrics.Token))
+
+	// Probes: /livez for restarts, /readyz for routing traffic; ?verbose=true
+	// lists the checks for callers with system.health
+	healthHandler := handlers.NewHealthHandler(checker)
+	app.Get("/livez", authMiddleware.TokenOptional(), healthHandler.Live)
+	app.Get("/readyz", authMiddleware.TokenOptional(), healthHandler.Ready)
+
+	// Health check kept for existing monitors; it only says the process is up
 	app.Get("/health", func(c *fiber.Ctx) error {
-		return c.JSON(fiber.Map{
-			"status":  "ok",
-			"message": "CourseAI Backend is running",
+		return c.JSON(handlers.HealthStatus{
+			Status:  "ok",
+			Message: "CourseAI Backend is running",
 		})
 	})
 
-	// Public read-only routes (no auth) for frontend public pages
+	// Public read-only routes (no auth) for frontend public pages, served
+	// through the response cache. Browsers and CDNs may reuse a response for
+	// max-age; lists go stale with any of their items, so sooner.
+	listCache := responseCache.Route(middleware.CachePolicy{MaxAge: 30 * time.Second, StaleWhileRevalidate: 5 * time.Minute})
+	itemCache := responseCache.Route(middleware.CachePolicy{MaxAge: time.Minute, StaleWhileRevalidate: 10 * time.Minute})
+	// cache hits count against the rate limit too
+	publicLimit := limit(ratelimit.Public)
+
 	// Programs - use public version that shows all programs (no access control)
-	api.Get("/programs", programHandler.GetAllPublic)
-	api.Get("/programs/:id", programHandler.GetOne)
-	api.Get("/programs/:programId/subcourses", subcourseHandler.GetByProgram)
+	api.Get("/programs", publicLimit, listCache, programHandler.GetAllPublic)
+	api.Get("/programs/:id", publicLimit, itemCache, programHandler.GetOne)
+	api.Get("/programs/:programId/subcourses", publicLimit, listCache, subcourseHandler.GetByProgram)
 
 	// Subcourses (ensure optional token parsing is applied at route level)
-	api.Get("/subcourses", authMiddleware.TokenOptional(), subcourseHandler.GetAll)
-	api.Get("/subcourses/:id", authMiddleware.TokenOptional(), subcourseHandler.GetOne)
+	api.Get("/subcourses", publicLimit, authMiddleware.TokenOptional(), listCache, subcourseHandler.GetAll)
+	api.Get("/subcourses/:id", publicLimit, authMiddleware.TokenOptional(), itemCache, subcourseHandler.GetOne)
 
 	// Lessons - use public version that shows all lessons (no access control)
-	api.Get("/lessons", lessonHandler.GetAllPublic)
-	api.Get("/lessons/:id", lessonHandler.GetOne)
-	api.Get("/subcourses/:subcourseId/lessons", lessonHandler.GetBySubcourse)
+	api.Get("/lessons", publicLimit, listCache, lessonHandler.GetAllPublic)
+	api.Get("/lessons/summary", publicLimit, listCache, lessonHandler.GetSummariesPublic)
+	api.Get("/lessons/:id", publicLimit, itemCache, lessonHandler.GetOne)
+	api.Get("/subcourses/:subcourseId/lessons", publicLimit, listCache, lessonHandler.GetBySubcourse)
 
 	// Root handler: provide a friendly message at / to avoid 404s when browsing to http://localhost:PORT/
 	app.Get("/", func(c *fiber.Ctx) error {
-		return c.SendString("CourseAI Backend is running. API root: /api, health: /health")
+		return c.SendString("CourseAI Backend is running. API root: /api, probes: /livez, /readyz")
 	})
 
 	// Serve uploads directory
 	app.Static("/uploads", "./uploads")
 
-	// Start server: bind strictly to configured port. Do NOT auto-jump ports.
-	startPort, _ := strconv.Atoi(cfg.Server.Port)
-	if startPort == 8082 {
-		log.Fatal("Configured server port cannot be Adminer reserved port 8082")
+	// Every route must be in the OpenAPI document; report drift either way
+	undocumented, stale := apiSpec.Diff(app.GetRoutes(true))
+	for _, route := range undocumented {
+		log.Printf("OpenAPI: route %s is not documented", route)
+	}
+	for _, route := range stale {
+		log.Printf("OpenAPI: documented operation %s has no route", route)
 	}
+
+	// Start server: bind strictly to configured port (validated, never Adminer's 8082). Do NOT auto-jump ports.
+	startPort, _ := strconv.Atoi(cfg.Server.Port)
 	addr := fmt.Sprintf(":%d", startPort)
 	listener, err := net.Listen("tcp", addr)
 	if err != nil {
@@ -199,7 +453,7 @@ func main() {
 
 	// write chosen port to repo logs folder for scripts to pick up. Working directory is backend/, so ../logs points to repo logs.
 	// Skip in production to avoid write permission issues
-	if os.Getenv("ENV") != "production" {
+	if !cfg.IsProduction() {
 		portFile := "../logs/backend.port"
 		_ = os.WriteFile(portFile, []byte(strconv.Itoa(startPort)), 0644)
 
@@ -208,8 +462,21 @@ func main() {
 		_ = os.WriteFile(pidFile, []byte(strconv.Itoa(os.Getpid())), 0644)
 	}
 
+	// Admin listener for metrics, kept off the public port
+	var adminServer *http.Server
+	if cfg.Metrics.Addr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler(cfg.Metrics.Token))
+		adminServer = &http.Server{Addr: cfg.Metrics.Addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+		go func() {
+			if err := adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("Warning: metrics listener on %s failed: %v", cfg.Metrics.Addr, err)
+			}
+		}()
+		log.Printf("Metrics on http://%s/metrics", cfg.Metrics.Addr)
+	}
+
 	log.Printf("🚀 Server starting on http://localhost%s", addr)
-	log.Printf("📚 Admin credentials: admin123 / admin123")
 	// start Fiber using the prepared listener in a goroutine so we can handle signals
 	serveErrCh := make(chan error, 1)
 	go func() {
@@ -223,6 +490,14 @@ func main() {
 	select {
 	case sig := <-sigCh:
 		log.Printf("Received signal %s: initiating graceful shutdown...", sig.String())
+		// fail /readyz first, so load balancers stop routing here while requests still complete
+		checker.Drain()
+		if drain := time.Duration(cfg.Server.ShutdownDrainSeconds) * time.Second; drain > 0 {
+			log.Printf("Draining for %s before shutdown", drain)
+			time.Sleep(drain)
+		}
+		// close editor WebSockets so they do not hold up Shutdown
+		stopBackground()
 		// give shutdown a deadline and wait for Shutdown to finish
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
@@ -241,11 +516,17 @@ func main() {
 		case <-ctx.Done():
 			log.Println("Graceful shutdown timed out, forcing close")
 		}
+		if adminServer != nil {
+			_ = adminServer.Shutdown(ctx)
+		}
+		if err := shutdownTracing(ctx); err != nil {
+			log.Println("Error flushing traces:", err)
+		}
 
 		// ensure listener closed
 		_ = listener.Close()
 		// remove pid file if present (only in development)
-		if os.Getenv("ENV") != "production" {
+		if !cfg.IsProduction() {
 			pidFile := ""
 			_ = os.Remove(pidFile)
 		}
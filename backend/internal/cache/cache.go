@@ -0,0 +1,172 @@
+// Package cache keeps serialized responses of the public catalog routes.
+// Each entry is tagged with the programs, subcourses and lessons it was
+// built from. Database triggers publish the tags of every changed row once
+// its transaction commits (see database.EnsureCacheInvalidation), and
+// Listener drops the entries carrying them, on every replica.
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"sync"
+	"time"
+)
+
+// Entry is one serialized response
+type Entry struct {
+	Body        []byte
+	ContentType string
+	// ETag is a strong validator derived from Body, so it is the same on
+	// every replica
+	ETag string
+	// Version is the record version the handler reported, see
+	// middleware.VersionHeader; empty for lists
+	Version      string
+	LastModified time.Time
+	Tags         []string
+	Expires      time.Time
+}
+
+// ETag returns the strong entity tag of body
+func ETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// Store keeps entries. Memory is per process; Postgres is shared by the
+// replicas.
+type Store interface {
+	// Get returns nil when key is missing or expired
+	Get(ctx context.Context, key string) (*Entry, error)
+	Set(ctx context.Context, key string, e *Entry) error
+	// Invalidate removes the entries carrying any of tags
+	Invalidate(ctx context.Context, tags []string) error
+	// Flush removes every entry
+	Flush(ctx context.Context) error
+	// Sweep removes expired entries
+	Sweep(ctx context.Context) error
+}
+
+// guardWindow is how long invalidations are remembered, so a response built
+// from rows read before an invalidation is not stored after it. Responses
+// that took longer to build are not stored at all.
+const guardWindow = time.Minute
+
+// Cache fronts a Store. Store errors are logged and treated as misses: the
+// cache only ever saves work.
+type Cache struct {
+	store Store
+	ttl   time.Duration
+
+	mu          sync.Mutex
+	invalidated map[string]time.Time
+	flushed     time.Time
+	pruned      time.Time
+}
+
+// New caches into store; entries live at most ttl, invalidation usually
+// removes them much sooner
+func New(store Store, ttl time.Duration) *Cache {
+	return &Cache{store: store, ttl: ttl, invalidated: map[string]time.Time{}}
+}
+
+// Ticket marks when a response started being built
+type Ticket time.Time
+
+// Begin returns the ticket Put checks for invalidations that happened while
+// the response was built. It is safe on a nil Cache.
+func (c *Cache) Begin() Ticket {
+	return Ticket(time.Now())
+}
+
+// Get returns the entry stored under key, nil on a miss
+func (c *Cache) Get(ctx context.Context, key string) *Entry {
+	if c == nil {
+		return nil
+	}
+	e, err := c.store.Get(ctx, key)
+	if err != nil {
+		log.Printf("cache: get: %v", err)
+		return nil
+	}
+	return e
+}
+
+// Put stores e under key unless one of its tags was invalidated since the
+// ticket was taken, and reports whether it did
+func (c *Cache) Put(ctx context.Context, key string, e *Entry, since Ticket) bool {
+	if c == nil {
+		return false
+	}
+	started := time.Time(since)
+	if time.Since(started) > guardWindow {
+		return false
+	}
+	c.mu.Lock()
+	stale := c.flushed.After(started)
+	for _, tag := range e.Tags {
+		if c.invalidated[tag].After(started) {
+			stale = true
+			break
+		}
+	}
+	c.mu.Unlock()
+	if stale {
+		return false
+	}
+	e.Expires = time.Now().Add(c.ttl)
+	if err := c.store.Set(ctx, key, e); err != nil {
+		log.Printf("cache: set: %v", err)
+		return false
+	}
+	return true
+}
+
+// Invalidate removes the entries carrying any of tags
+func (c *Cache) Invalidate(ctx context.Context, tags ...string) {
+	if c == nil || len(tags) == 0 {
+		return
+	}
+	now := time.Now()
+	c.mu.Lock()
+	for _, tag := range tags {
+		c.invalidated[tag] = now
+	}
+	if now.Sub(c.pruned) > guardWindow {
+		for tag, at := range c.invalidated {
+			if now.Sub(at) > guardWindow {
+				delete(c.invalidated, tag)
+			}
+		}
+		c.pruned = now
+	}
+	c.mu.Unlock()
+	if err := c.store.Invalidate(ctx, tags); err != nil {
+		log.Printf("cache: invalidate: %v", err)
+	}
+}
+
+// Flush removes every entry, e.g. when invalidations may have been missed
+func (c *Cache) Flush(ctx context.Context) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.flushed = time.Now()
+	c.mu.Unlock()
+	if err := c.store.Flush(ctx); err != nil {
+		log.Printf("cache: flush: %v", err)
+	}
+}
+
+// Sweep removes expired entries
+func (c *Cache) Sweep(ctx context.Context) {
+	if c == nil {
+		return
+	}
+	if err := c.store.Sweep(ctx); err != nil {
+		log.Printf("cache: sweep: %v", err)
+	}
+}
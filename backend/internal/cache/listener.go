@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"context"
+	"courseai/backend/internal/health"
+	"courseai/backend/internal/metrics"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Channel is the Postgres channel the invalidation triggers notify; the
+// payload is the space separated tags of one changed row
+const Channel = "courseai_cache"
+
+// Worker names the listener for the liveness probe
+const Worker = "cache_invalidation"
+
+// Listener applies the invalidations published by the database triggers.
+// Postgres delivers them only once the writing transaction commits, so a
+// reader can never refill the cache from rows about to change.
+type Listener struct {
+	cache    *Cache
+	listener *pq.Listener
+}
+
+// NewListener starts listening on dsn for invalidations of c
+func NewListener(dsn string, c *Cache) (*Listener, error) {
+	listener := pq.NewListener(dsn, 2*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("cache: listener event %d: %v", ev, err)
+		}
+	})
+	if err := listener.Listen(Channel); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return &Listener{cache: c, listener: listener}, nil
+}
+
+// Run applies invalidations until ctx is done
+func (l *Listener) Run(ctx context.Context) {
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			l.listener.Close()
+			return
+		case n := <-l.listener.Notify:
+			if n == nil {
+				// the connection was re-established and invalidations may have been lost
+				l.cache.Flush(ctx)
+				continue
+			}
+			tags := strings.Fields(n.Extra)
+			metrics.CacheInvalidations.Add(float64(len(tags)))
+			l.cache.Invalidate(ctx, tags...)
+		case <-ping.C:
+			health.Beat(Worker)
+			l.cache.Sweep(ctx)
+			go func() {
+				if err := l.listener.Ping(); err != nil {
+					log.Printf("cache: listener ping: %v", err)
+				}
+			}()
+		}
+	}
+}
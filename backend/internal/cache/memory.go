@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory is a per-process Store bounded by entry count and body size; the
+// least recently used entries are evicted first
+type Memory struct {
+	maxEntries int
+	maxBytes   int
+
+	mu    sync.Mutex
+	bytes int
+	lru   *list.List // of *memoryItem, most recently used first
+	items map[string]*list.Element
+	byTag map[string]map[string]struct{}
+}
+
+type memoryItem struct {
+	key   string
+	entry *Entry
+}
+
+// NewMemory returns an empty store; a limit of 0 means no limit
+func NewMemory(maxEntries, maxBytes int) *Memory {
+	return &Memory{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		lru:        list.New(),
+		items:      map[string]*list.Element{},
+		byTag:      map[string]map[string]struct{}{},
+	}
+}
+
+func (m *Memory) Get(_ context.Context, key string) (*Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el, ok := m.items[key]
+	if !ok {
+		return nil, nil
+	}
+	item := el.Value.(*memoryItem)
+	if time.Now().After(item.entry.Expires) {
+		m.remove(el)
+		return nil, nil
+	}
+	m.lru.MoveToFront(el)
+	return item.entry, nil
+}
+
+func (m *Memory) Set(_ context.Context, key string, e *Entry) error {
+	if m.maxBytes > 0 && len(e.Body) > m.maxBytes {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if el, ok := m.items[key]; ok {
+		m.remove(el)
+	}
+	m.items[key] = m.lru.PushFront(&memoryItem{key: key, entry: e})
+	m.bytes += len(e.Body)
+	for _, tag := range e.Tags {
+		keys := m.byTag[tag]
+		if keys == nil {
+			keys = map[string]struct{}{}
+			m.byTag[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+	for (m.maxEntries > 0 && m.lru.Len() > m.maxEntries) || (m.maxBytes > 0 && m.bytes > m.maxBytes) {
+		m.remove(m.lru.Back())
+	}
+	return nil
+}
+
+func (m *Memory) Invalidate(_ context.Context, tags []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, tag := range tags {
+		for key := range m.byTag[tag] {
+			if el, ok := m.items[key]; ok {
+				m.remove(el)
+			}
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Flush(_ context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bytes = 0
+	m.lru.Init()
+	m.items = map[string]*list.Element{}
+	m.byTag = map[string]map[string]struct{}{}
+	return nil
+}
+
+func (m *Memory) Sweep(_ context.Context) error {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for el := m.lru.Back(); el != nil; {
+		prev := el.Prev()
+		if now.After(el.Value.(*memoryItem).entry.Expires) {
+			m.remove(el)
+		}
+		el = prev
+	}
+	return nil
+}
+
+// Len returns the number of entries and the size of their bodies
+func (m *Memory) Len() (entries, bytes int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lru.Len(), m.bytes
+}
+
+// remove drops el and its tag index entries; m.mu must be held
+func (m *Memory) remove(el *list.Element) {
+	item := m.lru.Remove(el).(*memoryItem)
+	delete(m.items, item.key)
+	m.bytes -= len(item.entry.Body)
+	for _, tag := range item.entry.Tags {
+		keys := m.byTag[tag]
+		delete(keys, item.key)
+		if len(keys) == 0 {
+			delete(m.byTag, tag)
+		}
+	}
+}
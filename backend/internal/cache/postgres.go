@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// Postgres is a Store shared by the replicas, in the UNLOGGED response_cache
+// table created by the migrations. A hit costs one primary key lookup
+// instead of the preloads behind the response.
+type Postgres struct {
+	db *gorm.DB
+}
+
+// NewPostgres stores entries through db
+func NewPostgres(db *gorm.DB) *Postgres {
+	return &Postgres{db: db}
+}
+
+type postgresEntry struct {
+	Body         []byte
+	ContentType  string
+	ETag         string `gorm:"column:etag"`
+	Version      string
+	LastModified time.Time
+	Tags         pq.StringArray
+	ExpiresAt    time.Time
+}
+
+func (p *Postgres) Get(ctx context.Context, key string) (*Entry, error) {
+	var rows []postgresEntry
+	err := p.db.WithContext(ctx).Raw(
+		"SELECT body, content_type, etag, version, last_modified, tags, expires_at FROM response_cache WHERE key = ? AND expires_at > now()",
+		key).Scan(&rows).Error
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	row := rows[0]
+	return &Entry{
+		Body:         row.Body,
+		ContentType:  row.ContentType,
+		ETag:         row.ETag,
+		Version:      row.Version,
+		LastModified: row.LastModified,
+		Tags:         row.Tags,
+		Expires:      row.ExpiresAt,
+	}, nil
+}
+
+func (p *Postgres) Set(ctx context.Context, key string, e *Entry) error {
+	return p.db.WithContext(ctx).Exec(`INSERT INTO response_cache (key, body, content_type, etag, version, last_modified, tags, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET body = EXCLUDED.body, content_type = EXCLUDED.content_type, etag = EXCLUDED.etag,
+			version = EXCLUDED.version, last_modified = EXCLUDED.last_modified, tags = EXCLUDED.tags, expires_at = EXCLUDED.expires_at`,
+		key, e.Body, e.ContentType, e.ETag, e.Version, e.LastModified, pq.StringArray(e.Tags), e.Expires).Error
+}
+
+func (p *Postgres) Invalidate(ctx context.Context, tags []string) error {
+	return p.db.WithContext(ctx).Exec("DELETE FROM response_cache WHERE tags && ?", pq.StringArray(tags)).Error
+}
+
+func (p *Postgres) Flush(ctx context.Context) error {
+	return p.db.WithContext(ctx).Exec("DELETE FROM response_cache").Error
+}
+
+func (p *Postgres) Sweep(ctx context.Context) error {
+	return p.db.WithContext(ctx).Exec("DELETE FROM response_cache WHERE expires_at <= now()").Error
+}
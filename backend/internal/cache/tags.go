@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"courseai/backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Tags name what an entry was built from. The database triggers publish the
+// same strings, so the two must change together.
+const (
+	// ProgramsTag is on every list of programs; any program change drops them
+	ProgramsTag = "programs"
+	// SubcoursesTag is on every list of subcourses
+	SubcoursesTag = "subcourses"
+	// LessonsTag is on every list of lessons
+	LessonsTag = "lessons"
+)
+
+// ProgramTag is on every entry showing the program or its media; subcourse
+// changes publish it too, as a program is served with its subcourses
+func ProgramTag(id uuid.UUID) string { return "program:" + id.String() }
+
+// SubcourseTag is on every entry showing the subcourse or its media; lesson
+// changes publish it too, as a subcourse is served with its lessons
+func SubcourseTag(id uuid.UUID) string { return "subcourse:" + id.String() }
+
+// LessonTag is on every entry showing the lesson; changes to its components
+// and to their media publish it too
+func LessonTag(id uuid.UUID) string { return "lesson:" + id.String() }
+
+// TenantTag is on every entry served to an organization. Sharing a program
+// with it, or unsharing one, changes everything it sees.
+func TenantTag(id uuid.UUID) string { return "tenant:" + id.String() }
+
+// ProgramListTags tags a list of programs
+func ProgramListTags(programs []models.Program) []string {
+	tags := []string{ProgramsTag}
+	for i := range programs {
+		tags = append(tags, ProgramTag(programs[i].ID))
+	}
+	return tags
+}
+
+// SubcourseTags tags a subcourse served with its program
+func SubcourseTags(s *models.Subcourse) []string {
+	return []string{SubcourseTag(s.ID), ProgramTag(s.ProgramID)}
+}
+
+// SubcourseListTags tags a list of subcourses served with their programs
+func SubcourseListTags(subcourses []models.Subcourse) []string {
+	tags := []string{SubcoursesTag}
+	for i := range subcourses {
+		tags = append(tags, SubcourseTags(&subcourses[i])...)
+	}
+	return tags
+}
+
+// LessonTags tags a lesson served with its subcourse and program
+func LessonTags(l *models.Lesson) []string {
+	tags := []string{LessonTag(l.ID), SubcourseTag(l.SubcourseID)}
+	if l.Subcourse != nil {
+		tags = append(tags, ProgramTag(l.Subcourse.ProgramID))
+	}
+	return tags
+}
+
+// LessonListTags tags a list of lessons served with their subcourses and
+// programs
+func LessonListTags(lessons []models.Lesson) []string {
+	tags := []string{LessonsTag}
+	for i := range lessons {
+		tags = append(tags, LessonTags(&lessons[i])...)
+	}
+	return tags
+}
@@ -0,0 +1,324 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv names the configuration file; without it the first of
+// DefaultFiles found in the working directory is used, if any
+const FileEnv = "CONFIG_FILE"
+
+// DefaultFiles are looked for in the working directory
+var DefaultFiles = []string{"config.yaml", "config.yml", "config.toml"}
+
+// Load reads the file and the environment over the defaults and validates
+// the result. The error lists every problem found, not just the first.
+func Load() (*Config, error) {
+	// DATABASE_URL (managed database) wins; without it a local .env is read.
+	// It never overrides variables that are already set.
+	if os.Getenv("DATABASE_URL") == "" {
+		if err := godotenv.Load(); err == nil {
+			slog.Info(".env file loaded (development mode)")
+		}
+	}
+
+	path, err := configFile()
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]interface{}
+	if path != "" {
+		if values, err = readFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	// ENV picks the defaults, so it is resolved before everything else
+	env := os.Getenv("ENV")
+	if env == "" {
+		if v, ok := values["env"].(string); ok {
+			env = v
+		}
+	}
+	if env == "" {
+		env = "development"
+	}
+
+	cfg := Defaults(env)
+	var problems []string
+	applyFile(reflect.ValueOf(&cfg).Elem(), values, "", &problems)
+	applyEnv(reflect.ValueOf(&cfg).Elem(), &problems)
+	finish(&cfg)
+
+	if err := cfg.Validate(); err != nil {
+		var invalid *ValidationError
+		if !errors.As(err, &invalid) {
+			return nil, err
+		}
+		problems = append(problems, invalid.Problems...)
+	}
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	slog.Info("configuration loaded", "file", path, "env", cfg.Env)
+	return &cfg, nil
+}
+
+// finish fills in the settings derived from others
+func finish(c *Config) {
+	// Vercel sets VERCEL_URL to the preview or production domain
+	if vercelURL := os.Getenv("VERCEL_URL"); vercelURL != "" {
+		c.Server.FrontendURL = "https://" + vercelURL
+	}
+	if len(c.Server.CORSOrigins) == 0 {
+		c.Server.CORSOrigins = []string{c.Server.FrontendURL}
+	}
+	// browsers send origins without a trailing slash
+	for i, origin := range c.Server.CORSOrigins {
+		c.Server.CORSOrigins[i] = strings.TrimSuffix(origin, "/")
+	}
+	if c.OIDC.PostLoginRedirect == "" {
+		c.OIDC.PostLoginRedirect = strings.TrimSuffix(c.Server.FrontendURL, "/") + "/login/sso"
+	}
+	c.OIDC.IssuerURL = strings.TrimSuffix(c.OIDC.IssuerURL, "/")
+	if c.Metrics.Addr == "off" {
+		c.Metrics.Addr = ""
+	}
+	// outside production a missing secret is replaced, loudly, so the server
+	// still starts; tokens then do not survive a restart
+	if c.JWT.Secret == "" && !c.IsProduction() {
+		buf := make([]byte, 32)
+		_, _ = rand.Read(buf)
+		c.JWT.Secret = hex.EncodeToString(buf)
+		slog.Warn("JWT_SECRET is not set; using a random secret, sessions end when the server restarts")
+	}
+}
+
+// configFile returns the file named by CONFIG_FILE or the first default
+// file found; "" when there is none
+func configFile() (string, error) {
+	if path := os.Getenv(FileEnv); path != "" {
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("%s: %w", FileEnv, err)
+		}
+		return path, nil
+	}
+	for _, name := range DefaultFiles {
+		if _, err := os.Stat(name); err == nil {
+			return name, nil
+		}
+	}
+	return "", nil
+}
+
+// readFile decodes a YAML (.yaml, .yml) or TOML (.toml) file into nested maps
+func readFile(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return nil, fmt.Errorf("%s: unsupported format, use .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return values, nil
+}
+
+// applyFile sets the fields of the struct v from values, keyed by the config
+// tags. Unknown keys are problems, so a typo does not go unnoticed.
+func applyFile(v reflect.Value, values map[string]interface{}, prefix string, problems *[]string) {
+	t := v.Type()
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		raw := values[key]
+		i := fieldByKey(t, key)
+		if i < 0 {
+			*problems = append(*problems, fmt.Sprintf("%s: unknown setting", prefix+key))
+			continue
+		}
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			section, ok := raw.(map[string]interface{})
+			if !ok {
+				*problems = append(*problems, fmt.Sprintf("%s: must be a section", prefix+key))
+				continue
+			}
+			applyFile(field, section, prefix+key+".", problems)
+			continue
+		}
+		if err := setValue(field, raw); err != nil {
+			*problems = append(*problems, fmt.Sprintf("%s: %v", prefix+key, err))
+		}
+	}
+}
+
+// applyEnv sets every field whose env variable is set and not empty
+func applyEnv(v reflect.Value, problems *[]string) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			applyEnv(field, problems)
+			continue
+		}
+		name := t.Field(i).Tag.Get("env")
+		if name == "" {
+			continue
+		}
+		if raw := os.Getenv(name); raw != "" {
+			if err := setValue(field, raw); err != nil {
+				*problems = append(*problems, fmt.Sprintf("%s: %v", name, err))
+			}
+		}
+	}
+}
+
+func fieldByKey(t reflect.Type, key string) int {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("config") == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// setValue converts a decoded file value or an environment string to the
+// field's type. Lists are comma or space separated and mappings are
+// "key=value,key2=value2" when given as a string.
+func setValue(field reflect.Value, raw interface{}) error {
+	s, isString := raw.(string)
+	switch field.Kind() {
+	case reflect.String:
+		switch raw.(type) {
+		case string, int, int64, float64, bool:
+			field.SetString(fmt.Sprint(raw))
+			return nil
+		}
+		return errors.New("must be a single value")
+	case reflect.Int:
+		switch x := raw.(type) {
+		case int:
+			field.SetInt(int64(x))
+			return nil
+		case int64:
+			field.SetInt(x)
+			return nil
+		case string:
+			n, err := strconv.Atoi(strings.TrimSpace(x))
+			if err != nil {
+				return fmt.Errorf("%q is not a whole number", x)
+			}
+			field.SetInt(int64(n))
+			return nil
+		}
+		return errors.New("must be a whole number")
+	case reflect.Float64:
+		switch x := raw.(type) {
+		case int:
+			field.SetFloat(float64(x))
+			return nil
+		case int64:
+			field.SetFloat(float64(x))
+			return nil
+		case float64:
+			field.SetFloat(x)
+			return nil
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(x), 64)
+			if err != nil {
+				return fmt.Errorf("%q is not a number", x)
+			}
+			field.SetFloat(f)
+			return nil
+		}
+		return errors.New("must be a number")
+	case reflect.Bool:
+		switch x := raw.(type) {
+		case bool:
+			field.SetBool(x)
+			return nil
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(x))
+			if err != nil {
+				return fmt.Errorf("%q is not true or false", x)
+			}
+			field.SetBool(b)
+			return nil
+		}
+		return errors.New("must be true or false")
+	case reflect.Slice:
+		if isString {
+			field.Set(reflect.ValueOf(splitList(s)))
+			return nil
+		}
+		if items, ok := raw.([]interface{}); ok {
+			list := make([]string, 0, len(items))
+			for _, item := range items {
+				list = append(list, fmt.Sprint(item))
+			}
+			field.Set(reflect.ValueOf(list))
+			return nil
+		}
+		return errors.New("must be a list")
+	case reflect.Map:
+		if isString {
+			field.Set(reflect.ValueOf(parseMapping(s)))
+			return nil
+		}
+		if entries, ok := raw.(map[string]interface{}); ok {
+			mapping := make(map[string]string, len(entries))
+			for k, v := range entries {
+				mapping[k] = fmt.Sprint(v)
+			}
+			field.Set(reflect.ValueOf(mapping))
+			return nil
+		}
+		return errors.New("must be a mapping")
+	}
+	return fmt.Errorf("unsupported value %v", raw)
+}
+
+// splitList splits on commas and white space
+func splitList(raw string) []string {
+	return strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || unicode.IsSpace(r) })
+}
+
+// parseMapping parses "key=value,key2=value2" into a map
+func parseMapping(raw string) map[string]string {
+	out := map[string]string{}
+	for _, pair := range strings.Split(raw, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || k == "" {
+			continue
+		}
+		out[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return out
+}
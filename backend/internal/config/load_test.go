@@ -0,0 +1,105 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadTOMLFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	src := `env = "production"
+
+[database]
+host = "db.internal"
+max_open_conns = 40
+
+[server]
+cors_origins = [
+  "https://a.example",
+  "https://b.example", # trailing comma and comments are fine
+]
+"proxy_header" = "X-Forwarded-For"
+
+[oidc]
+role_mapping = { lms-admins = "admin", "lms teachers" = "teacher" }
+
+[tracing]
+sample_ratio = 0.25
+rate_limit.login_burst = 3
+`
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	values, err := readFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := Defaults("production")
+	var problems []string
+	applyFile(reflect.ValueOf(&c).Elem(), values, "", &problems)
+
+	// a dotted key inside [tracing] is tracing.rate_limit, not a top-level section
+	if want := []string{"tracing.rate_limit: unknown setting"}; !reflect.DeepEqual(problems, want) {
+		t.Fatalf("problems = %q, want %q", problems, want)
+	}
+	if c.Database.Host != "db.internal" || c.Database.MaxOpenConns != 40 {
+		t.Errorf("database = %+v", c.Database)
+	}
+	if want := []string{"https://a.example", "https://b.example"}; !reflect.DeepEqual(c.Server.CORSOrigins, want) {
+		t.Errorf("cors_origins = %q, want %q", c.Server.CORSOrigins, want)
+	}
+	if c.Server.ProxyHeader != "X-Forwarded-For" {
+		t.Errorf("proxy_header = %q", c.Server.ProxyHeader)
+	}
+	if want := map[string]string{"lms-admins": "admin", "lms teachers": "teacher"}; !reflect.DeepEqual(c.OIDC.RoleMapping, want) {
+		t.Errorf("role_mapping = %v, want %v", c.OIDC.RoleMapping, want)
+	}
+	if c.Tracing.SampleRatio != 0.25 {
+		t.Errorf("sample_ratio = %v", c.Tracing.SampleRatio)
+	}
+}
+
+func TestReadTOMLFileRejects(t *testing.T) {
+	for name, src := range map[string]string{
+		"duplicate key":    "env = \"a\"\nenv = \"b\"\n",
+		"duplicate table":  "[server]\n[server]\n",
+		"missing value":    "env =\n",
+		"unclosed array":   "[server]\ncors_origins = [\"a\"\n",
+		"bare string":      "env = production\n",
+		"unclosed table":   "[server\n",
+		"newline in value": "env = \"a\nb\"\n",
+	} {
+		path := filepath.Join(t.TempDir(), "config.toml")
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := readFile(path); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestApplyFileReportsWrongTypes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	src := "[database]\nmax_open_conns = \"many\"\n[server]\ncors_origins = { a = 1 }\n[[jwt]]\nsecret = \"s\"\n"
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	values, err := readFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := Defaults("development")
+	var problems []string
+	applyFile(reflect.ValueOf(&c).Elem(), values, "", &problems)
+	want := []string{
+		`database.max_open_conns: "many" is not a whole number`,
+		"jwt: must be a section",
+		"server.cors_origins: must be a list",
+	}
+	if !reflect.DeepEqual(problems, want) {
+		t.Errorf("problems = %q, want %q", problems, want)
+	}
+}
@@ -0,0 +1,114 @@
+package config
+
+import (
+	"courseai/backend/internal/logging"
+	"io"
+	"net/url"
+	"reflect"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Print writes c as a YAML configuration file, in the order of the
+// structs, with each setting's environment variable as a comment. With
+// redacted, secret settings are replaced by [REDACTED]; a database URL keeps
+// everything but its password.
+func Print(w io.Writer, c *Config, redacted bool) error {
+	node, err := encodeSection(reflect.ValueOf(c).Elem(), redacted)
+	if err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func encodeSection(v reflect.Value, redacted bool) (*yaml.Node, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key := f.Tag.Get("config")
+		if key == "" {
+			continue
+		}
+		var value *yaml.Node
+		var err error
+		if f.Type.Kind() == reflect.Struct {
+			value, err = encodeSection(v.Field(i), redacted)
+		} else {
+			value, err = encodeValue(v.Field(i).Interface(), redacted && f.Tag.Get("secret") == "true")
+		}
+		if err != nil {
+			return nil, err
+		}
+		keyNode := &yaml.Node{Kind: yaml.ScalarNode, Value: key}
+		if env := f.Tag.Get("env"); env != "" {
+			// a value on the key's line carries the comment; on the key an
+			// empty [] or {} would push it onto the next key
+			if value.Kind == yaml.ScalarNode || len(value.Content) == 0 {
+				value.LineComment = env
+			} else {
+				keyNode.LineComment = env
+			}
+		}
+		node.Content = append(node.Content, keyNode, value)
+	}
+	return node, nil
+}
+
+func encodeValue(value interface{}, secret bool) (*yaml.Node, error) {
+	if secret {
+		value = redact(value)
+	}
+	node := &yaml.Node{}
+	if err := node.Encode(value); err != nil {
+		return nil, err
+	}
+	// mappings in key order, so the output is stable
+	if node.Kind == yaml.MappingNode {
+		sortMapping(node)
+	}
+	return node, nil
+}
+
+// redact hides a secret value; empty values stay empty, so an unset secret
+// remains visible as unset
+func redact(value interface{}) interface{} {
+	switch x := value.(type) {
+	case string:
+		if x == "" {
+			return x
+		}
+		if u, err := url.Parse(x); err == nil && u.User != nil {
+			if _, hasPassword := u.User.Password(); hasPassword {
+				return u.Redacted()
+			}
+		}
+		return logging.Redacted
+	case map[string]string:
+		out := make(map[string]string, len(x))
+		for k := range x {
+			out[k] = logging.Redacted
+		}
+		return out
+	}
+	return logging.Redacted
+}
+
+func sortMapping(node *yaml.Node) {
+	type pair struct{ key, value *yaml.Node }
+	pairs := make([]pair, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		pairs = append(pairs, pair{node.Content[i], node.Content[i+1]})
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].key.Value < pairs[j].key.Value })
+	node.Content = node.Content[:0]
+	for _, p := range pairs {
+		node.Content = append(node.Content, p.key, p.value)
+	}
+}
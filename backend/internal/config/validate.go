@@ -0,0 +1,310 @@
+package config
+
+import (
+	"courseai/backend/internal/models"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ValidationError lists every problem found in the configuration
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// minSecretLength is the shortest JWT secret accepted in production
+const minSecretLength = 32
+
+// exampleSecrets are published in this repository or are common
+// placeholders; they are refused in production whatever their length
+var exampleSecrets = []string{
+	"default-secret-change-me",
+	"dev_secret_change_in_production",
+	"change_me_in_production",
+	"your-secret-key",
+	"changeme",
+	"secret",
+}
+
+// reservedPort is Adminer's in docker-compose.yml
+const reservedPort = 8082
+
+// Validate checks c as a whole. Weak secrets are errors in production and
+// warnings elsewhere.
+func (c *Config) Validate() error {
+	var problems []string
+	fail := func(path, format string, args ...interface{}) {
+		problems = append(problems, describe(path)+": "+fmt.Sprintf(format, args...))
+	}
+	weak := func(path, format string, args ...interface{}) {
+		if c.IsProduction() {
+			fail(path, format, args...)
+			return
+		}
+		slog.Warn("weak setting, refused in production", "setting", describe(path), "problem", fmt.Sprintf(format, args...))
+	}
+
+	switch c.Env {
+	case "development", "test", "staging", "production":
+	default:
+		fail("env", "%q is not development, test, staging or production", c.Env)
+	}
+
+	// server
+	if port, err := strconv.Atoi(c.Server.Port); err != nil || port < 1 || port > 65535 {
+		fail("server.port", "%q is not a port number", c.Server.Port)
+	} else if port == reservedPort {
+		fail("server.port", "%d is reserved for Adminer", reservedPort)
+	}
+	if _, err := parseHTTPURL(c.Server.FrontendURL); err != nil {
+		fail("server.frontend_url", "%v", err)
+	}
+	for _, origin := range c.Server.CORSOrigins {
+		if err := checkOrigin(origin); err != nil {
+			fail("server.cors_origins", "%v", err)
+		}
+	}
+	if c.Server.ShutdownDrainSeconds < 0 {
+		fail("server.shutdown_drain_seconds", "must not be negative")
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		if err := checkProxy(proxy); err != nil {
+			fail("server.trusted_proxies", "%v", err)
+		}
+	}
+	if c.Server.ProxyHeader != "" && len(c.Server.TrustedProxies) == 0 {
+		fail("server.trusted_proxies", "required with server.proxy_header, or any client could choose its IP")
+	}
+
+	// database
+	if c.Database.URL != "" {
+		if u, err := url.Parse(c.Database.URL); err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") {
+			fail("database.url", "must be a postgres:// or postgresql:// URL")
+		}
+	} else {
+		if c.Database.Host == "" {
+			fail("database.host", "is required without database.url")
+		}
+		if c.Database.Name == "" {
+			fail("database.name", "is required without database.url")
+		}
+		// the default is fine for the local Docker database, so this is not warned about
+		if c.IsProduction() && c.Database.Password == "postgres" {
+			fail("database.password", "is the default password")
+		}
+	}
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 || c.Database.ConnMaxLifetimeMinutes < 0 || c.Database.ConnMaxIdleTimeMinutes < 0 {
+		fail("database", "pool settings must not be negative")
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		fail("database.max_idle_conns", "must not exceed database.max_open_conns (%d)", c.Database.MaxOpenConns)
+	}
+
+	// jwt
+	switch secret := c.JWT.Secret; {
+	case secret == "":
+		fail("jwt.secret", "is required")
+	case isExampleSecret(secret):
+		weak("jwt.secret", "is an example value; generate one with: openssl rand -hex 32")
+	case len(secret) < minSecretLength:
+		weak("jwt.secret", "must be at least %d characters", minSecretLength)
+	}
+	if c.JWT.ExpireHours < 1 {
+		fail("jwt.expire_hours", "must be at least 1")
+	}
+
+	// uploads
+	if c.Uploads.MaxFileMB < 1 {
+		fail("uploads.max_file_mb", "must be at least 1")
+	}
+	if c.Uploads.MaxRequestMB < c.Uploads.MaxFileMB {
+		fail("uploads.max_request_mb", "must be at least uploads.max_file_mb (%d)", c.Uploads.MaxFileMB)
+	}
+
+	// cache
+	switch c.Cache.Store {
+	case "memory", "postgres", "off":
+	default:
+		fail("cache.store", "%q is not memory, postgres or off", c.Cache.Store)
+	}
+
+	// rate limits
+	switch c.RateLimit.Store {
+	case "memory", "postgres", "off":
+	default:
+		fail("rate_limit.store", "%q is not memory, postgres or off", c.RateLimit.Store)
+	}
+	for _, group := range []struct {
+		name             string
+		perMinute, burst int
+	}{
+		{"public", c.RateLimit.PublicPerMinute, c.RateLimit.PublicBurst},
+		{"admin", c.RateLimit.AdminPerMinute, c.RateLimit.AdminBurst},
+		{"login", c.RateLimit.LoginPerMinute, c.RateLimit.LoginBurst},
+		{"upload", c.RateLimit.UploadPerMinute, c.RateLimit.UploadBurst},
+	} {
+		if group.perMinute < 0 {
+			fail("rate_limit."+group.name+"_per_minute", "must not be negative")
+		} else if group.perMinute > 0 && group.burst < 1 {
+			fail("rate_limit."+group.name+"_burst", "must be at least 1")
+		}
+	}
+
+	// security, realtime, trash and cache
+	for _, setting := range []struct {
+		path  string
+		value int
+	}{
+		{"security.login_max_failures", c.Security.LoginMaxFailures},
+		{"security.login_ip_max_failures", c.Security.LoginIPMaxFailures},
+		{"security.login_lockout_minutes", c.Security.LoginLockoutMinutes},
+		{"security.login_delay_max_seconds", c.Security.LoginDelayMaxSeconds},
+		{"security.mfa_challenge_minutes", c.Security.MFAChallengeMinutes},
+		{"realtime.lock_seconds", c.Realtime.LockSeconds},
+		{"realtime.presence_seconds", c.Realtime.PresenceSeconds},
+		{"trash.retention_days", c.Trash.RetentionDays},
+		{"trash.purge_interval_minutes", c.Trash.PurgeIntervalMinutes},
+		{"cache.max_entries", c.Cache.MaxEntries},
+		{"cache.max_mb", c.Cache.MaxMB},
+		{"cache.ttl_seconds", c.Cache.TTLSeconds},
+	} {
+		if setting.value < 1 {
+			fail(setting.path, "must be at least 1")
+		}
+	}
+	if c.Security.LoginDelayBaseSeconds < 0 {
+		fail("security.login_delay_base_seconds", "must not be negative")
+	}
+
+	// oidc
+	if c.OIDC.Enabled {
+		if _, err := parseHTTPURL(c.OIDC.IssuerURL); err != nil {
+			fail("oidc.issuer_url", "%v", err)
+		}
+		if c.OIDC.ClientID == "" {
+			fail("oidc.client_id", "is required when oidc.enabled is set")
+		}
+		if _, err := parseHTTPURL(c.OIDC.RedirectURL); err != nil {
+			fail("oidc.redirect_url", "%v", err)
+		}
+	}
+	if !isRole(c.OIDC.DefaultRole) {
+		fail("oidc.default_role", "%q is not a role", c.OIDC.DefaultRole)
+	}
+	for claim, role := range c.OIDC.RoleMapping {
+		if !isRole(role) {
+			fail("oidc.role_mapping", "%q maps to %q, which is not a role", claim, role)
+		}
+	}
+
+	// observability
+	switch strings.ToLower(c.Log.Level) {
+	case "debug", "info", "warn", "warning", "error":
+	default:
+		fail("log.level", "%q is not debug, info, warn or error", c.Log.Level)
+	}
+	if c.Log.Format != "json" && c.Log.Format != "text" {
+		fail("log.format", "%q is not json or text", c.Log.Format)
+	}
+	if c.Log.SlowQueryMS < 0 {
+		fail("log.slow_query_ms", "must not be negative")
+	}
+	switch c.Tracing.Exporter {
+	case "none", "stdout", "otlp":
+	default:
+		fail("tracing.exporter", "%q is not none, stdout or otlp", c.Tracing.Exporter)
+	}
+	switch c.Tracing.Protocol {
+	case "http/protobuf", "grpc":
+	default:
+		fail("tracing.protocol", "%q is not http/protobuf or grpc", c.Tracing.Protocol)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		fail("tracing.sample_ratio", "must be between 0 and 1")
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+func isExampleSecret(secret string) bool {
+	for _, example := range exampleSecrets {
+		if strings.EqualFold(secret, example) {
+			return true
+		}
+	}
+	return false
+}
+
+func isRole(role string) bool {
+	for _, r := range models.UserRoles {
+		if string(r) == role {
+			return true
+		}
+	}
+	return false
+}
+
+func parseHTTPURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%q is not an http(s) URL", raw)
+	}
+	return u, nil
+}
+
+// checkOrigin accepts scheme://host[:port] without a path. "*" is refused:
+// the API allows credentials, which browsers never send to a wildcard origin.
+func checkOrigin(origin string) error {
+	if origin == "*" {
+		return fmt.Errorf(`"*" cannot be used, list the origins`)
+	}
+	u, err := parseHTTPURL(origin)
+	if err != nil {
+		return err
+	}
+	if u.Path != "" || u.RawQuery != "" {
+		return fmt.Errorf("%q is not an origin (scheme://host[:port])", origin)
+	}
+	return nil
+}
+
+// checkProxy accepts an IP or a CIDR range
+func checkProxy(proxy string) error {
+	if net.ParseIP(proxy) != nil {
+		return nil
+	}
+	if _, _, err := net.ParseCIDR(proxy); err != nil {
+		return fmt.Errorf("%q is neither an IP nor a CIDR range", proxy)
+	}
+	return nil
+}
+
+// describe names a setting by its file key and environment variable, e.g.
+// "jwt.secret (JWT_SECRET)"
+func describe(path string) string {
+	t := reflect.TypeOf(Config{})
+	var field reflect.StructField
+	for _, key := range strings.Split(path, ".") {
+		i := fieldByKey(t, key)
+		if i < 0 {
+			return path
+		}
+		field = t.Field(i)
+		t = field.Type
+	}
+	if env := field.Tag.Get("env"); env != "" {
+		return fmt.Sprintf("%s (%s)", path, env)
+	}
+	return path
+}
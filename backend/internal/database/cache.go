@@ -0,0 +1,125 @@
+package database
+
+import (
+	"courseai/backend/internal/cache"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// cacheTables are the tables whose rows public catalog responses are built
+// from, besides the lesson components (see cacheComponentTables)
+var cacheTables = []string{"programs", "subcourses", "lessons", "lesson_quiz_options", "media", "program_shares"}
+
+// cacheComponentTables are the tables of the lesson components
+func cacheComponentTables() []string {
+	var tables []string
+	for _, fk := range ForeignKeys {
+		if fk.Column == "lesson_id" && fk.RefTable == "lessons" {
+			tables = append(tables, fk.Table)
+		}
+	}
+	return tables
+}
+
+// cacheTagsFunction returns the tags of a row (as jsonb) of a table; they
+// must match the tags in the cache package. Components and their media tag
+// their lesson.
+func cacheTagsFunction() string {
+	var componentMedia []string
+	for _, o := range MediaOwnerTables {
+		switch o.Table {
+		case "programs", "subcourses", "lessons":
+			continue
+		}
+		componentMedia = append(componentMedia, fmt.Sprintf(
+			"WHEN '%s' THEN RETURN ARRAY(SELECT 'lesson:' || lesson_id FROM %s WHERE id = (r->>'owner_id')::uuid);",
+			o.OwnerType, o.Table))
+	}
+	return `CREATE OR REPLACE FUNCTION cache_tags(tbl text, r jsonb) RETURNS text[] AS $$
+		BEGIN
+			CASE tbl
+			WHEN 'programs' THEN
+				RETURN ARRAY['` + cache.ProgramsTag + `', 'program:' || (r->>'id')];
+			WHEN 'subcourses' THEN
+				RETURN ARRAY['` + cache.SubcoursesTag + `', 'subcourse:' || (r->>'id'), 'program:' || (r->>'program_id')];
+			WHEN 'lessons' THEN
+				RETURN ARRAY['` + cache.LessonsTag + `', 'lesson:' || (r->>'id'), 'subcourse:' || (r->>'subcourse_id')];
+			WHEN 'lesson_quiz_options' THEN
+				RETURN ARRAY(SELECT 'lesson:' || lesson_id FROM lesson_quizzes WHERE id = (r->>'quiz_id')::uuid);
+			WHEN 'program_shares' THEN
+				RETURN ARRAY['tenant:' || (r->>'organization_id')];
+			WHEN 'media' THEN
+				CASE r->>'owner_type'
+				WHEN 'program', 'subcourse', 'lesson' THEN RETURN ARRAY[(r->>'owner_type') || ':' || (r->>'owner_id')];
+				` + strings.Join(componentMedia, "\n\t\t\t\t") + `
+				ELSE RETURN '{}';
+				END CASE;
+			ELSE
+				RETURN ARRAY['lesson:' || (r->>'lesson_id')];
+			END CASE;
+		END
+		$$ LANGUAGE plpgsql STABLE;`
+}
+
+// notifyCacheFunction publishes the tags of the old and the new row. NOTIFY
+// is delivered on commit and identical payloads of one transaction are sent
+// once, so the tags are sorted.
+const notifyCacheFunction = `CREATE OR REPLACE FUNCTION notify_cache_invalidation() RETURNS trigger AS $$
+	DECLARE
+		tags text[] := '{}';
+	BEGIN
+		IF TG_OP = 'UPDATE' AND to_jsonb(OLD) = to_jsonb(NEW) THEN
+			RETURN NULL;
+		END IF;
+		IF TG_OP <> 'INSERT' THEN
+			tags := tags || cache_tags(TG_TABLE_NAME, to_jsonb(OLD));
+		END IF;
+		IF TG_OP <> 'DELETE' THEN
+			tags := tags || cache_tags(TG_TABLE_NAME, to_jsonb(NEW));
+		END IF;
+		IF cardinality(tags) > 0 THEN
+			PERFORM pg_notify('` + cache.Channel + `', array_to_string(ARRAY(SELECT DISTINCT t FROM unnest(tags) t ORDER BY 1), ' '));
+		END IF;
+		RETURN NULL;
+	END
+	$$ LANGUAGE plpgsql;`
+
+// responseCacheTable holds the entries of the shared cache store
+// (CACHE_STORE=postgres). It is UNLOGGED: a crash empties it, which only
+// costs cache misses.
+var responseCacheTable = []string{
+	`CREATE UNLOGGED TABLE IF NOT EXISTS response_cache (
+		key text PRIMARY KEY,
+		body bytea NOT NULL,
+		content_type text NOT NULL,
+		etag text NOT NULL,
+		version text NOT NULL DEFAULT '',
+		last_modified timestamp with time zone NOT NULL,
+		tags text[] NOT NULL,
+		expires_at timestamp with time zone NOT NULL
+	)`,
+	"ALTER TABLE response_cache ADD COLUMN IF NOT EXISTS version text NOT NULL DEFAULT ''",
+	"CREATE INDEX IF NOT EXISTS idx_response_cache_tags ON response_cache USING gin (tags)",
+	"CREATE INDEX IF NOT EXISTS idx_response_cache_expires_at ON response_cache (expires_at)",
+}
+
+// EnsureCacheInvalidation creates the response cache table and installs the
+// triggers that publish the cache tags of every changed catalog row
+func EnsureCacheInvalidation(db *gorm.DB) error {
+	stmts := append([]string{}, responseCacheTable...)
+	stmts = append(stmts, cacheTagsFunction(), notifyCacheFunction)
+	for _, stmt := range stmts {
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("failed to execute '%s': %w", stmt, err)
+		}
+	}
+	for _, table := range append(cacheComponentTables(), cacheTables...) {
+		def := fmt.Sprintf("AFTER INSERT OR DELETE OR UPDATE ON %s FOR EACH ROW EXECUTE FUNCTION notify_cache_invalidation()", table)
+		if err := ensureTrigger(db, "trg_"+table+"_cache", table, def); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package database
+
+import (
+	"courseai/backend/internal/models"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// The denormalized counters on lessons, subcourses and programs are
+// recomputed from the rows below them rather than incremented, so a write
+// that runs them in its transaction leaves them exact. Trashed rows do not
+// count. Each Refresh* function also refreshes the parents of the rows it
+// touched.
+//
+// Recomputing alone is not enough under READ COMMITTED: an UPDATE that waited
+// for a concurrent writer's row lock re-runs its subqueries on its old
+// snapshot, so the writer's new child rows would be missed. The rows are
+// therefore locked first, with SELECT ... FOR UPDATE in id order; the UPDATE
+// after it takes a fresh snapshot that includes whatever the previous holder
+// committed. Locks are taken lessons, then subcourses, then programs, so two
+// refreshes never wait for each other in a cycle.
+
+// lessonMediaCount counts the media of a lesson and of its components
+func lessonMediaCount() string {
+	var owners []string
+	for _, o := range MediaOwnerTables {
+		switch o.Table {
+		case "programs", "subcourses":
+			continue
+		case "lessons":
+			owners = append(owners, fmt.Sprintf("(m.owner_type = '%s' AND m.owner_id = lessons.id)", o.OwnerType))
+		default:
+			owners = append(owners, fmt.Sprintf("(m.owner_type = '%s' AND m.owner_id IN (SELECT id FROM %s WHERE lesson_id = lessons.id))", o.OwnerType, o.Table))
+		}
+	}
+	return "SELECT COUNT(*) FROM media m WHERE " + strings.Join(owners, " OR ")
+}
+
+var (
+	refreshLessons = "UPDATE lessons SET media_count = (" + lessonMediaCount() + ")"
+
+	refreshSubcourses = `UPDATE subcourses SET
+		lesson_count = (SELECT COUNT(*) FROM lessons l WHERE l.subcourse_id = subcourses.id AND l.deleted_at IS NULL),
+		published_lesson_count = (SELECT COUNT(*) FROM lessons l WHERE l.subcourse_id = subcourses.id AND l.deleted_at IS NULL AND l.status = '` + string(models.StatusPublished) + `'),
+		total_duration_minutes = (SELECT COALESCE(SUM(l.duration_minutes), 0) FROM lessons l WHERE l.subcourse_id = subcourses.id AND l.deleted_at IS NULL),
+		media_count = (SELECT COUNT(*) FROM media m WHERE m.owner_type = '` + string(models.OwnerSubcourse) + `' AND m.owner_id = subcourses.id)`
+
+	refreshPrograms = `UPDATE programs SET
+		subcourse_count = (SELECT COUNT(*) FROM subcourses s WHERE s.program_id = programs.id AND s.deleted_at IS NULL),
+		published_subcourse_count = (SELECT COUNT(*) FROM subcourses s WHERE s.program_id = programs.id AND s.deleted_at IS NULL AND s.status = '` + string(models.StatusPublished) + `'),
+		lesson_count = (SELECT COALESCE(SUM(s.lesson_count), 0) FROM subcourses s WHERE s.program_id = programs.id AND s.deleted_at IS NULL),
+		published_lesson_count = (SELECT COALESCE(SUM(s.published_lesson_count), 0) FROM subcourses s WHERE s.program_id = programs.id AND s.deleted_at IS NULL),
+		total_duration_minutes = (SELECT COALESCE(SUM(s.total_duration_minutes), 0) FROM subcourses s WHERE s.program_id = programs.id AND s.deleted_at IS NULL),
+		media_count = (SELECT COUNT(*) FROM media m WHERE m.owner_type = '` + string(models.OwnerProgram) + `' AND m.owner_id = programs.id)`
+)
+
+// RefreshLessonCounters recomputes the counters of the given lessons and of
+// their subcourses and programs
+func RefreshLessonCounters(tx *gorm.DB, lessonIDs ...uuid.UUID) error {
+	return RefreshMovedLessonCounters(tx, nil, lessonIDs...)
+}
+
+// RefreshMovedLessonCounters is RefreshLessonCounters for lessons that moved
+// out of the subcourses from, which are refreshed along with the new ones so
+// the lock order holds
+func RefreshMovedLessonCounters(tx *gorm.DB, from []uuid.UUID, lessonIDs ...uuid.UUID) error {
+	if len(lessonIDs) == 0 {
+		return RefreshSubcourseCounters(tx, from...)
+	}
+	if err := lockRows(tx, "lessons", lessonIDs); err != nil {
+		return err
+	}
+	if err := tx.Exec(refreshLessons+" WHERE id IN ?", lessonIDs).Error; err != nil {
+		return err
+	}
+	var subcourseIDs []uuid.UUID
+	if err := tx.Raw("SELECT DISTINCT subcourse_id FROM lessons WHERE id IN ?", lessonIDs).Scan(&subcourseIDs).Error; err != nil {
+		return err
+	}
+	return RefreshSubcourseCounters(tx, append(subcourseIDs, from...)...)
+}
+
+// RefreshSubcourseCounters recomputes the counters of the given subcourses and
+// of their programs
+func RefreshSubcourseCounters(tx *gorm.DB, subcourseIDs ...uuid.UUID) error {
+	return RefreshMovedSubcourseCounters(tx, nil, subcourseIDs...)
+}
+
+// RefreshMovedSubcourseCounters is RefreshSubcourseCounters for subcourses
+// that moved out of the programs from
+func RefreshMovedSubcourseCounters(tx *gorm.DB, from []uuid.UUID, subcourseIDs ...uuid.UUID) error {
+	if len(subcourseIDs) == 0 {
+		return RefreshProgramCounters(tx, from...)
+	}
+	if err := lockRows(tx, "subcourses", subcourseIDs); err != nil {
+		return err
+	}
+	if err := tx.Exec(refreshSubcourses+" WHERE id IN ?", subcourseIDs).Error; err != nil {
+		return err
+	}
+	var programIDs []uuid.UUID
+	if err := tx.Raw("SELECT DISTINCT program_id FROM subcourses WHERE id IN ?", subcourseIDs).Scan(&programIDs).Error; err != nil {
+		return err
+	}
+	return RefreshProgramCounters(tx, append(programIDs, from...)...)
+}
+
+// RefreshProgramCounters recomputes the counters of the given programs
+func RefreshProgramCounters(tx *gorm.DB, programIDs ...uuid.UUID) error {
+	if len(programIDs) == 0 {
+		return nil
+	}
+	if err := lockRows(tx, "programs", programIDs); err != nil {
+		return err
+	}
+	return tx.Exec(refreshPrograms+" WHERE id IN ?", programIDs).Error
+}
+
+// lockRows locks the rows of table with the given ids until the transaction
+// ends, in id order
+func lockRows(tx *gorm.DB, table string, ids []uuid.UUID) error {
+	return tx.Exec("SELECT id FROM "+table+" WHERE id IN ? ORDER BY id FOR UPDATE", ids).Error
+}
+
+// RecomputeCounters recomputes every counter, bottom up, in one transaction
+func RecomputeCounters(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range []string{refreshLessons, refreshSubcourses, refreshPrograms} {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
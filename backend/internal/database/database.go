@@ -1,29 +1,43 @@
 package database
 
 import (
+	"courseai/backend/internal/logging"
+	"courseai/backend/internal/metrics"
 	"courseai/backend/internal/models"
+	"courseai/backend/internal/telemetry"
 	"fmt"
 	"log"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 var DB *gorm.DB
 
-func Connect(dsn string) error {
+// PoolOptions sizes the connection pool; zero values keep database/sql's defaults
+type PoolOptions struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+func Connect(dsn string, pool PoolOptions) error {
 	var err error
 
-	// Log connection attempt (mask password in logs if using DSN with individual components)
-	if len(dsn) > 100 || dsn[:10] == "postgres:/" {
-		log.Println("Connecting to PostgreSQL database (DATABASE_URL)...")
-	} else {
-		log.Printf("Connecting to PostgreSQL database: host=%s\n", extractHostFromDSN(dsn))
-	}
+	slog.Info("connecting to PostgreSQL", "host", dsnHost(dsn))
 
 	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		// errors and slow statements only; LOG_SQL adds every statement at debug level
+		Logger: logging.Gorm(),
+		// constraints are managed explicitly, with ON DELETE rules (see integrity.go)
+		DisableForeignKeyConstraintWhenMigrating: true,
+		// unique violations surface as gorm.ErrDuplicatedKey, so handlers can answer 409
+		TranslateError: true,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to connect to database: %w\nEnsure DATABASE_URL is set correctly or local PostgreSQL is running", err)
@@ -31,6 +45,27 @@ func Connect(dsn string) error {
 
 	log.Println("✓ Database connected successfully")
 
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return fmt.Errorf("failed to configure connection pool: %w", err)
+	}
+	sqlDB.SetMaxOpenConns(pool.MaxOpenConns)
+	if pool.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(pool.MaxIdleConns)
+	}
+	sqlDB.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
+
+	if err := RegisterTenantCallbacks(DB); err != nil {
+		return fmt.Errorf("failed to register tenant callbacks: %w", err)
+	}
+	if err := DB.Use(metrics.GormPlugin{}); err != nil {
+		return fmt.Errorf("failed to register metrics plugin: %w", err)
+	}
+	if err := DB.Use(telemetry.GormPlugin{}); err != nil {
+		return fmt.Errorf("failed to register tracing plugin: %w", err)
+	}
+
 	// Attempt to create pgcrypto extension (provides gen_random_uuid()) if available.
 	// Do not fail connect if this is not permitted; migrations will handle errors in dev.
 	if err := DB.Exec("CREATE EXTENSION IF NOT EXISTS pgcrypto;").Error; err != nil {
@@ -41,45 +76,34 @@ func Connect(dsn string) error {
 	return nil
 }
 
-func extractHostFromDSN(dsn string) string {
-	// Simple extraction for logging purposes
-	if len(dsn) > 50 {
-		return dsn[:50] + "..."
+// dsnHost returns the host of a URL or key=value DSN, for logging without credentials
+func dsnHost(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Host != "" {
+		return u.Hostname()
 	}
-	return dsn
+	for _, field := range strings.Fields(dsn) {
+		if host, ok := strings.CutPrefix(field, "host="); ok {
+			return host
+		}
+	}
+	return ""
 }
 
+// AutoMigrate brings the schema up to SchemaVersion and records it; the
+// outcome is reported by the readiness probe (CheckSchema)
 func AutoMigrate() error {
-	log.Println("Running auto migrations...")
-
-	// Clean up old tables with schema issues to ensure fresh migration
-	// This is necessary when model definitions change and old schema conflicts
-	tablesToClean := []interface{}{
-		&models.Program{},
-		&models.Subcourse{},
-		&models.Lesson{},
-		&models.LessonObjective{},
-		&models.LessonModel{},
-		&models.LessonPreparation{},
-		&models.LessonBuild{},
-		&models.LessonContentBlock{},
-		&models.LessonAttachment{},
-		&models.LessonChallenge{},
-		&models.LessonQuiz{},
-		&models.LessonQuizOption{},
-		&models.Media{},
-		&models.TeacherAssignment{},
-		&models.TeacherAssignmentLog{},
-	}
-
-	for _, m := range tablesToClean {
-		if DB.Migrator().HasTable(m) {
-			log.Printf("Dropping table %T to ensure clean migration...\n", m)
-			if err := DB.Migrator().DropTable(m); err != nil {
-				log.Printf("Warning: could not drop table %T: %v\n", m, err)
-			}
+	err := migrate()
+	if err == nil {
+		if err = recordSchemaVersion(); err != nil {
+			err = fmt.Errorf("failed to record schema version: %w", err)
 		}
 	}
+	setMigrationError(err)
+	return err
+}
+
+func migrate() error {
+	log.Println("Running auto migrations...")
 
 	// Ensure users table exists with a schema that is safe and idempotent.
 	if !DB.Migrator().HasTable(&models.User{}) {
@@ -108,6 +132,8 @@ func AutoMigrate() error {
 
 	// Migrate remaining models (exclude User which is handled above)
 	modelsToMigrate := []interface{}{
+		&models.Organization{},
+		&models.ProgramShare{},
 		&models.Program{},
 		&models.Subcourse{},
 		&models.Lesson{},
@@ -123,15 +149,19 @@ func AutoMigrate() error {
 		&models.Media{},
 		&models.TeacherAssignment{},
 		&models.TeacherAssignmentLog{},
+		&models.LessonTemplate{},
+		&models.LoginAttempt{},
+		&models.LoginThrottle{},
+		&models.UserIdentity{},
+		&models.OIDCLoginState{},
+		&models.RecoveryCode{},
 	}
 
+	// Tables and their rows are kept across restarts (trashed content has to
+	// outlive its retention window); AutoMigrate only adds what is missing
 	for _, m := range modelsToMigrate {
-		if err := DB.Migrator().CreateTable(m); err != nil {
-			// Try soft approach first - CreateTable without introspection
-			log.Printf("CreateTable failed for %T: %v, trying AutoMigrate...\n", m, err)
-			if err := DB.AutoMigrate(m); err != nil {
-				return fmt.Errorf("migration failed for model %T: %w", m, err)
-			}
+		if err := DB.AutoMigrate(m); err != nil {
+			return fmt.Errorf("migration failed for model %T: %w", m, err)
 		}
 	}
 
@@ -144,6 +174,17 @@ func AutoMigrate() error {
 		"ALTER TABLE lessons ADD COLUMN IF NOT EXISTS author_id uuid;",
 		"ALTER TABLE lessons ADD COLUMN IF NOT EXISTS is_featured boolean DEFAULT false;",
 		"ALTER TABLE lessons ADD COLUMN IF NOT EXISTS published_at timestamp with time zone;",
+		"ALTER TABLE users ADD COLUMN IF NOT EXISTS organization_id uuid;",
+		"CREATE INDEX IF NOT EXISTS idx_users_organization_id ON users (organization_id);",
+		"ALTER TABLE users ADD COLUMN IF NOT EXISTS password_login_disabled boolean NOT NULL DEFAULT false;",
+		"ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret varchar(64);",
+		"ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled boolean NOT NULL DEFAULT false;",
+		"ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step bigint NOT NULL DEFAULT 0;",
+		// superseded by the composite indexes the list queries use (e.g. idx_lessons_subcourse_sort)
+		"DROP INDEX IF EXISTS idx_lessons_subcourse_id;",
+		"DROP INDEX IF EXISTS idx_subcourses_program_id;",
+		"DROP INDEX IF EXISTS idx_owner;",
+		"DROP INDEX IF EXISTS idx_teacher_assignments_teacher_id;",
 	}
 	for _, stmt := range alterStmts {
 		if err := DB.Exec(stmt).Error; err != nil {
@@ -151,10 +192,72 @@ func AutoMigrate() error {
 		}
 	}
 
+	if err := ensureIntegrity(); err != nil {
+		return fmt.Errorf("failed to ensure referential integrity: %w", err)
+	}
+
+	if err := EnsureCacheInvalidation(DB); err != nil {
+		return fmt.Errorf("failed to install cache invalidation: %w", err)
+	}
+
+	if err := EnsureRateLimits(DB); err != nil {
+		return fmt.Errorf("failed to install rate limits: %w", err)
+	}
+
+	if err := ensureDefaultOrganization(); err != nil {
+		return fmt.Errorf("failed to ensure default organization: %w", err)
+	}
+
 	log.Println("Migrations completed successfully")
 	return nil
 }
 
+// ensureDefaultOrganization creates the default organization and moves users
+// that predate multi-tenancy into it. If no super-admin exists yet, the oldest
+// admin is promoted so the deployment keeps one global administrator.
+func ensureDefaultOrganization() error {
+	org, err := DefaultOrganization(DB)
+	if err != nil {
+		return err
+	}
+
+	var superAdmins int64
+	if err := DB.Model(&models.User{}).Where("role = ?", models.RoleSuperAdmin).Count(&superAdmins).Error; err != nil {
+		return err
+	}
+	if superAdmins == 0 {
+		var oldest models.User
+		if err := DB.Where("role = ?", models.RoleAdmin).Order("created_at ASC").First(&oldest).Error; err == nil {
+			if err := DB.Model(&oldest).Updates(map[string]interface{}{"role": models.RoleSuperAdmin, "organization_id": nil}).Error; err != nil {
+				return err
+			}
+			log.Printf("Promoted %s to super_admin", oldest.Username)
+		}
+	}
+
+	return DB.Model(&models.User{}).
+		Where("organization_id IS NULL AND role <> ?", models.RoleSuperAdmin).
+		Update("organization_id", org.ID).Error
+}
+
+// DefaultOrganization returns the default organization, creating it if needed
+func DefaultOrganization(db *gorm.DB) (*models.Organization, error) {
+	var org models.Organization
+	err := db.Where("slug = ?", models.DefaultOrganizationSlug).First(&org).Error
+	if err == nil {
+		return &org, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	org = models.Organization{Name: "Default", Slug: models.DefaultOrganizationSlug, Status: models.OrgStatusActive}
+	if err := db.Create(&org).Error; err != nil {
+		return nil, err
+	}
+	log.Println("Created default organization")
+	return &org, nil
+}
+
 func GetDB() *gorm.DB {
 	return DB
 }
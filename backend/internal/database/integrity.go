@@ -0,0 +1,303 @@
+package database
+
+import (
+	"courseai/backend/internal/models"
+	"fmt"
+	"log"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ForeignKey is a referential constraint on the content tree. GORM is told not
+// to create constraints itself (see Connect), so these are the only ones.
+type ForeignKey struct {
+	Table    string
+	Column   string
+	RefTable string
+	// OnDelete is "CASCADE" or "SET NULL"
+	OnDelete string
+}
+
+// Name is the constraint name, e.g. fk_lessons_subcourse_id
+func (fk ForeignKey) Name() string {
+	return "fk_" + fk.Table + "_" + fk.Column
+}
+
+// ForeignKeys lists the constraints parents first, so repairing orphans in
+// this order never leaves new orphans behind
+var ForeignKeys = []ForeignKey{
+	{"program_shares", "organization_id", "organizations", "CASCADE"},
+	{"program_shares", "program_id", "programs", "CASCADE"},
+	{"lesson_templates", "program_id", "programs", "CASCADE"},
+	{"subcourses", "program_id", "programs", "CASCADE"},
+	{"lessons", "subcourse_id", "subcourses", "CASCADE"},
+	{"teacher_assignments", "program_id", "programs", "CASCADE"},
+	{"teacher_assignments", "subcourse_id", "subcourses", "CASCADE"},
+	{"teacher_assignments", "teacher_id", "users", "CASCADE"},
+	{"lesson_objectives", "lesson_id", "lessons", "CASCADE"},
+	{"lesson_models", "lesson_id", "lessons", "CASCADE"},
+	{"lesson_preparations", "lesson_id", "lessons", "CASCADE"},
+	{"lesson_builds", "lesson_id", "lessons", "CASCADE"},
+	{"lesson_content_blocks", "lesson_id", "lessons", "CASCADE"},
+	{"lesson_attachments", "lesson_id", "lessons", "CASCADE"},
+	{"lesson_challenges", "lesson_id", "lessons", "CASCADE"},
+	{"lesson_quizzes", "lesson_id", "lessons", "CASCADE"},
+	{"lesson_quiz_options", "quiz_id", "lesson_quizzes", "CASCADE"},
+	{"lessons", "author_id", "users", "SET NULL"},
+	{"lessons", "cover_media_id", "media", "SET NULL"},
+}
+
+// MediaOwnerTables maps each media owner type to the table of its owner.
+// Media is polymorphic, so instead of a foreign key an AFTER DELETE trigger on
+// each owner table removes the owner's media.
+var MediaOwnerTables = []struct {
+	OwnerType models.MediaOwnerType
+	Table     string
+}{
+	{models.OwnerProgram, "programs"},
+	{models.OwnerSubcourse, "subcourses"},
+	{models.OwnerLesson, "lessons"},
+	{models.OwnerLessonModel, "lesson_models"},
+	{models.OwnerLessonPreparation, "lesson_preparations"},
+	{models.OwnerLessonBuild, "lesson_builds"},
+	{models.OwnerLessonContentBlock, "lesson_content_blocks"},
+	{models.OwnerLessonAttachment, "lesson_attachments"},
+	{models.OwnerLessonChallenge, "lesson_challenges"},
+}
+
+// trashedParents lists live rows whose parent is in the trash, which the
+// delete handlers never produce but older data or manual edits may
+var trashedParents = []struct{ Table, Column, RefTable string }{
+	{"subcourses", "program_id", "programs"},
+	{"lessons", "subcourse_id", "subcourses"},
+}
+
+// IntegrityIssue is one kind of inconsistency and how many rows have it
+type IntegrityIssue struct {
+	Check string `json:"check"`
+	Rows  int64  `json:"rows"`
+	// Fix is what RepairIntegrity does about it
+	Fix string `json:"fix"`
+}
+
+func (i IntegrityIssue) String() string {
+	return fmt.Sprintf("%s: %d row(s), fix: %s", i.Check, i.Rows, i.Fix)
+}
+
+// integrityCheck pairs a count query with the statement that repairs it
+type integrityCheck struct {
+	name   string
+	fix    string
+	count  string
+	repair string
+	args   []interface{}
+}
+
+func integrityChecks() []integrityCheck {
+	var checks []integrityCheck
+	for _, fk := range ForeignKeys {
+		orphans := fmt.Sprintf("%[1]s.%[2]s IS NOT NULL AND NOT EXISTS (SELECT 1 FROM %[3]s WHERE %[3]s.id = %[1]s.%[2]s)", fk.Table, fk.Column, fk.RefTable)
+		check := integrityCheck{
+			name:  fmt.Sprintf("%s.%s -> %s", fk.Table, fk.Column, fk.RefTable),
+			count: fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", fk.Table, orphans),
+		}
+		if fk.OnDelete == "SET NULL" {
+			check.fix = "set null"
+			check.repair = fmt.Sprintf("UPDATE %s SET %s = NULL WHERE %s", fk.Table, fk.Column, orphans)
+		} else {
+			check.fix = "delete"
+			check.repair = fmt.Sprintf("DELETE FROM %s WHERE %s", fk.Table, orphans)
+		}
+		checks = append(checks, check)
+	}
+	for _, o := range MediaOwnerTables {
+		orphans := fmt.Sprintf("media.owner_type = ? AND NOT EXISTS (SELECT 1 FROM %[1]s WHERE %[1]s.id = media.owner_id)", o.Table)
+		checks = append(checks, integrityCheck{
+			name:   fmt.Sprintf("media(%s) -> %s", o.OwnerType, o.Table),
+			fix:    "delete",
+			count:  "SELECT COUNT(*) FROM media WHERE " + orphans,
+			repair: "DELETE FROM media WHERE " + orphans,
+			args:   []interface{}{o.OwnerType},
+		})
+	}
+	for _, t := range trashedParents {
+		where := fmt.Sprintf("%[1]s.deleted_at IS NULL AND %[2]s.id = %[1]s.%[3]s AND %[2]s.deleted_at IS NOT NULL", t.Table, t.RefTable, t.Column)
+		checks = append(checks, integrityCheck{
+			name:   fmt.Sprintf("live %s in trashed %s", t.Table, t.RefTable),
+			fix:    "move to trash",
+			count:  fmt.Sprintf("SELECT COUNT(*) FROM %s, %s WHERE %s", t.Table, t.RefTable, where),
+			repair: fmt.Sprintf("UPDATE %[1]s SET deleted_at = %[2]s.deleted_at, deleted_by = %[2]s.deleted_by FROM %[2]s WHERE %[3]s", t.Table, t.RefTable, where),
+		})
+	}
+	return checks
+}
+
+// CheckIntegrity reports orphaned rows and live content under trashed parents
+func CheckIntegrity(db *gorm.DB) ([]IntegrityIssue, error) {
+	var issues []IntegrityIssue
+	for _, check := range integrityChecks() {
+		var n int64
+		if err := db.Raw(check.count, check.args...).Scan(&n).Error; err != nil {
+			return nil, fmt.Errorf("%s: %w", check.name, err)
+		}
+		if n > 0 {
+			issues = append(issues, IntegrityIssue{Check: check.name, Rows: n, Fix: check.fix})
+		}
+	}
+	return issues, nil
+}
+
+// RepairIntegrity fixes what CheckIntegrity reports, in one transaction, and
+// returns what it changed
+func RepairIntegrity(db *gorm.DB) ([]IntegrityIssue, error) {
+	var fixed []IntegrityIssue
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, check := range integrityChecks() {
+			result := tx.Exec(check.repair, check.args...)
+			if result.Error != nil {
+				return fmt.Errorf("%s: %w", check.name, result.Error)
+			}
+			if result.RowsAffected > 0 {
+				fixed = append(fixed, IntegrityIssue{Check: check.name, Rows: result.RowsAffected, Fix: check.fix})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return fixed, nil
+}
+
+// Definition is the constraint as pg_get_constraintdef prints it
+func (fk ForeignKey) Definition() string {
+	return fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s(id) ON DELETE %s", fk.Column, fk.RefTable, fk.OnDelete)
+}
+
+// EnsureConstraints installs the foreign keys and media triggers that are
+// missing or differ, and validates constraints added while orphans existed.
+// Orphans must be repaired first or validating fails.
+func EnsureConstraints(db *gorm.DB) error {
+	return ensureConstraints(db, true)
+}
+
+// ensureConstraints changes only what differs from ForeignKeys and
+// MediaOwnerTables, so a restart takes no table locks. Without validate,
+// missing constraints are added NOT VALID: they hold for new rows while
+// existing orphans wait for cmd/checkdb -repair.
+func ensureConstraints(db *gorm.DB, validate bool) error {
+	var existing []struct {
+		Name     string
+		Relation string
+		Def      string
+		Valid    bool
+	}
+	err := db.Raw(`SELECT c.conname AS name, r.relname AS relation, pg_get_constraintdef(c.oid) AS def, c.convalidated AS valid
+		FROM pg_constraint c JOIN pg_class r ON r.oid = c.conrelid
+		WHERE c.contype = 'f' AND r.relnamespace = to_regnamespace(current_schema())`).Scan(&existing).Error
+	if err != nil {
+		return fmt.Errorf("failed to list foreign keys: %w", err)
+	}
+
+	managed := map[string]ForeignKey{}
+	tables := map[string]bool{}
+	for _, fk := range ForeignKeys {
+		managed[fk.Name()] = fk
+		tables[fk.Table] = true
+	}
+	var stmts []string
+	installed := map[string]bool{}
+	for _, c := range existing {
+		fk, ok := managed[c.Name]
+		switch {
+		// constraints added NOT VALID are printed with that suffix
+		case ok && c.Relation == fk.Table && strings.TrimSuffix(c.Def, " NOT VALID") == fk.Definition():
+			installed[c.Name] = true
+			if !c.Valid && validate {
+				stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s VALIDATE CONSTRAINT %s", c.Relation, c.Name))
+			}
+		case ok, tables[c.Relation] && strings.HasPrefix(c.Name, "fk_"):
+			// a managed constraint that changed, or one GORM created before
+			// constraints were managed here (e.g. fk_subcourses_program)
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s", c.Relation, c.Name))
+		}
+	}
+	for _, fk := range ForeignKeys {
+		if installed[fk.Name()] {
+			continue
+		}
+		stmt := fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s", fk.Table, fk.Name(), fk.Definition())
+		if !validate {
+			stmt += " NOT VALID"
+		}
+		stmts = append(stmts, stmt)
+	}
+	for _, stmt := range stmts {
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("failed to execute '%s': %w", stmt, err)
+		}
+	}
+
+	deleteOwnedMedia := `CREATE OR REPLACE FUNCTION delete_owned_media() RETURNS trigger AS $$
+		BEGIN
+			DELETE FROM media WHERE owner_type = TG_ARGV[0] AND owner_id = OLD.id;
+			RETURN OLD;
+		END
+		$$ LANGUAGE plpgsql;`
+	if err := db.Exec(deleteOwnedMedia).Error; err != nil {
+		return fmt.Errorf("failed to create delete_owned_media(): %w", err)
+	}
+	for _, o := range MediaOwnerTables {
+		def := fmt.Sprintf("AFTER DELETE ON %s FOR EACH ROW EXECUTE FUNCTION delete_owned_media('%s')", o.Table, o.OwnerType)
+		if err := ensureTrigger(db, "trg_"+o.Table+"_media", o.Table, def); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureTrigger (re)creates a trigger unless it exists as def. def is
+// written the way pg_get_triggerdef prints it (events in INSERT, DELETE,
+// UPDATE order), without the schema.
+func ensureTrigger(db *gorm.DB, name, table, def string) error {
+	var current []struct {
+		Def    string
+		Schema string
+	}
+	err := db.Raw(`SELECT pg_get_triggerdef(t.oid) AS def, current_schema() AS schema
+		FROM pg_trigger t JOIN pg_class r ON r.oid = t.tgrelid
+		WHERE t.tgname = ? AND r.relname = ? AND r.relnamespace = to_regnamespace(current_schema())`,
+		name, table).Scan(&current).Error
+	if err != nil {
+		return fmt.Errorf("failed to look up trigger %s: %w", name, err)
+	}
+	want := "CREATE TRIGGER " + name + " " + def
+	if len(current) == 1 && strings.Replace(current[0].Def, " ON "+current[0].Schema+".", " ON ", 1) == want {
+		return nil
+	}
+	for _, stmt := range []string{fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s", name, table), want} {
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("failed to execute '%s': %w", stmt, err)
+		}
+	}
+	return nil
+}
+
+// ensureIntegrity reports orphans left by earlier versions and installs the
+// constraints so no new ones can appear. Repairs delete or change rows, so
+// they only run on request: go run ./cmd/checkdb -repair.
+func ensureIntegrity() error {
+	issues, err := CheckIntegrity(DB)
+	if err != nil {
+		return err
+	}
+	if len(issues) > 0 {
+		for _, issue := range issues {
+			log.Printf("Warning: integrity issue: %s", issue)
+		}
+		// validating a constraint fails while its orphans exist
+		log.Println("Warning: missing foreign keys are installed without validation; run go run ./cmd/checkdb -repair")
+	}
+	return ensureConstraints(DB, len(issues) == 0)
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// rateLimitStmts create the buckets of the shared rate limit store
+// (RATE_LIMIT_STORE=postgres). The table is UNLOGGED: a crash refills every
+// bucket, which only lets callers burst once more.
+var rateLimitStmts = []string{
+	`CREATE UNLOGGED TABLE IF NOT EXISTS rate_limits (
+		key text PRIMARY KEY,
+		level double precision NOT NULL,
+		updated_at timestamp with time zone NOT NULL,
+		full_at timestamp with time zone NOT NULL
+	)`,
+	"CREATE INDEX IF NOT EXISTS idx_rate_limits_full_at ON rate_limits (full_at)",
+	// rate_limit_take refills the bucket of k, takes a token if there is one
+	// and returns whether it did and the tokens left. The row lock makes
+	// concurrent requests of one caller wait for each other.
+	`CREATE OR REPLACE FUNCTION rate_limit_take(k text, rate double precision, burst double precision,
+		OUT allowed boolean, OUT tokens double precision) AS $$
+		DECLARE
+			prev_level double precision;
+			prev_at timestamp with time zone;
+			t timestamp with time zone;
+		BEGIN
+			INSERT INTO rate_limits (key, level, updated_at, full_at)
+				VALUES (k, burst, clock_timestamp(), clock_timestamp())
+				ON CONFLICT (key) DO NOTHING;
+			SELECT level, updated_at INTO prev_level, prev_at FROM rate_limits WHERE key = k FOR UPDATE;
+			t := clock_timestamp();
+			tokens := LEAST(burst, prev_level + GREATEST(0, EXTRACT(EPOCH FROM t - prev_at)) * rate);
+			allowed := tokens >= 1;
+			IF allowed THEN
+				tokens := tokens - 1;
+			END IF;
+			UPDATE rate_limits SET level = tokens, updated_at = t,
+				full_at = t + make_interval(secs => (burst - tokens) / rate)
+				WHERE key = k;
+		END
+		$$ LANGUAGE plpgsql;`,
+}
+
+// EnsureRateLimits (re)creates the rate limit table and function
+func EnsureRateLimits(db *gorm.DB) error {
+	for _, stmt := range rateLimitStmts {
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("failed to execute '%s': %w", stmt, err)
+		}
+	}
+	return nil
+}
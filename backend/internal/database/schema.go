@@ -0,0 +1,84 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// SchemaVersion is the schema this binary migrates to. Bump it with every
+// change to AutoMigrate, so the readiness probe can tell when the database
+// was migrated by another release or migrations did not run.
+const SchemaVersion = 4
+
+// schemaMigration records each schema version AutoMigrate completed
+type schemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (schemaMigration) TableName() string { return "schema_migrations" }
+
+var (
+	migrationMu  sync.Mutex
+	migrationErr error
+)
+
+// setMigrationError remembers the outcome of this process's AutoMigrate;
+// in development the server keeps running after a failed migration
+func setMigrationError(err error) {
+	migrationMu.Lock()
+	migrationErr = err
+	migrationMu.Unlock()
+}
+
+// recordSchemaVersion marks SchemaVersion as applied
+func recordSchemaVersion() error {
+	if err := DB.AutoMigrate(&schemaMigration{}); err != nil {
+		return err
+	}
+	return DB.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&schemaMigration{Version: SchemaVersion, AppliedAt: time.Now().UTC()}).Error
+}
+
+// MigratedVersion returns the newest schema version recorded in the
+// database, 0 when none is
+func MigratedVersion(ctx context.Context) (int, error) {
+	if DB == nil {
+		return 0, errors.New("not connected")
+	}
+	db := DB.WithContext(ctx)
+	if !db.Migrator().HasTable(&schemaMigration{}) {
+		return 0, nil
+	}
+	var version int
+	err := db.Model(&schemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	return version, err
+}
+
+// CheckSchema fails when migrations failed in this process or the database
+// schema is not the version this binary expects
+func CheckSchema(ctx context.Context) error {
+	migrationMu.Lock()
+	err := migrationErr
+	migrationMu.Unlock()
+	if err != nil {
+		return fmt.Errorf("migrations failed: %w", err)
+	}
+
+	version, err := MigratedVersion(ctx)
+	if err != nil {
+		return err
+	}
+	switch {
+	case version == 0:
+		return fmt.Errorf("no migrations applied, expected version %d", SchemaVersion)
+	case version != SchemaVersion:
+		return fmt.Errorf("schema is version %d, expected version %d", version, SchemaVersion)
+	}
+	return nil
+}
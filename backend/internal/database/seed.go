@@ -38,7 +38,7 @@ func SeedData(db *gorm.DB) error {
 		Username:     "admin123",
 		Email:        "admin123@example.com",
 		PasswordHash: string(hashedPassword),
-		Role:         models.RoleAdmin,
+		Role:         models.RoleSuperAdmin,
 		Status:       models.StatusActive,
 	}
 	if err := db.Create(&admin).Error; err != nil {
@@ -52,6 +52,11 @@ func SeedData(db *gorm.DB) error {
 		return nil
 	}
 
+	org, err := DefaultOrganization(db)
+	if err != nil {
+		return err
+	}
+
 	// If any program exists, assume seed already ran
 	var progCount int64
 	db.Model(&models.Program{}).Count(&progCount)
@@ -77,6 +82,7 @@ func SeedData(db *gorm.DB) error {
 		pid := uuid.MustParse(pd.ID)
 		program := models.Program{
 			ID:               pid,
+			OrganizationID:   &org.ID,
 			Name:             pd.Name,
 			Slug:             pd.Slug,
 			ShortDescription: pd.Short,
@@ -112,10 +118,10 @@ func SeedData(db *gorm.DB) error {
 			sub := models.Subcourse{
 				ID:               subID,
 				ProgramID:        program.ID,
+				OrganizationID:   program.OrganizationID,
 				Name:             sd.Name,
 				Slug:             sd.Slug,
 				AgeRange:         "8-12",
-				LessonCount:      4,
 				ShortDescription: fmt.Sprintf("%s — mô-đun hướng dẫn với nội dung thực hành và bài tập.", sd.Name),
 				BlockTypes:       subBlockTypes,
 				Status:           models.StatusPublished,
@@ -137,14 +143,15 @@ func SeedData(db *gorm.DB) error {
 			for li := 1; li <= 4; li++ {
 				lessonBlockTypes, _ := json.Marshal([]string{"text", "video", "file"})
 				lesson := models.Lesson{
-					SubcourseID: sub.ID,
-					Title:       fmt.Sprintf("Bài %d: %s", li, sub.Name),
-					Subtitle:    "Bài học mẫu bằng tiếng Việt",
-					Overview:    "Nội dung bài học minh họa bao gồm giải thích, ví dụ và bài tập thực hành.",
-					BlockTypes:  lessonBlockTypes,
-					Status:      models.StatusPublished,
-					Slug:        fmt.Sprintf("%s-bai-%d", sub.Slug, li),
-					SortOrder:   li,
+					SubcourseID:    sub.ID,
+					OrganizationID: sub.OrganizationID,
+					Title:          fmt.Sprintf("Bài %d: %s", li, sub.Name),
+					Subtitle:       "Bài học mẫu bằng tiếng Việt",
+					Overview:       "Nội dung bài học minh họa bao gồm giải thích, ví dụ và bài tập thực hành.",
+					BlockTypes:     lessonBlockTypes,
+					Status:         models.StatusPublished,
+					Slug:           fmt.Sprintf("%s-bai-%d", sub.Slug, li),
+					SortOrder:      li,
 				}
 				if err := db.Create(&lesson).Error; err != nil {
 					return err
@@ -164,6 +171,10 @@ func SeedData(db *gorm.DB) error {
 		}
 	}
 
+	if err := RecomputeCounters(db); err != nil {
+		return fmt.Errorf("failed to compute counters: %w", err)
+	}
+
 	log.Println("✅ Data seeding completed successfully!")
 	return nil
 }
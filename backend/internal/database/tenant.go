@@ -0,0 +1,145 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type tenantCtxKey struct{}
+
+const skipTenantKey = "tenant:skip"
+
+// WithTenant returns a context whose queries are restricted to organizationID
+func WithTenant(ctx context.Context, organizationID uuid.UUID) context.Context {
+	return context.WithValue(ctx, tenantCtxKey{}, organizationID)
+}
+
+// TenantFromContext returns the organization a context is restricted to, if any
+func TenantFromContext(ctx context.Context) (uuid.UUID, bool) {
+	if ctx == nil {
+		return uuid.Nil, false
+	}
+	id, ok := ctx.Value(tenantCtxKey{}).(uuid.UUID)
+	return id, ok && id != uuid.Nil
+}
+
+// SkipTenant disables tenant filtering for one statement chain, e.g. to check
+// global uniqueness of a slug across all organizations.
+func SkipTenant(db *gorm.DB) *gorm.DB {
+	return db.Set(skipTenantKey, true)
+}
+
+// sharedProgramIDs selects programs other organizations shared with the tenant
+const sharedProgramIDs = "SELECT program_id FROM program_shares WHERE organization_id = ?"
+
+// tenantReadFilters describes, per table, which rows a tenant may read:
+// its own rows plus rows belonging to programs shared with it.
+var tenantReadFilters = map[string]func(table string, org uuid.UUID) clause.Expression{
+	"programs": func(t string, org uuid.UUID) clause.Expression {
+		return clause.Expr{SQL: fmt.Sprintf("(%[1]s.organization_id = ? OR %[1]s.id IN (%[2]s))", t, sharedProgramIDs), Vars: []interface{}{org, org}}
+	},
+	"subcourses": func(t string, org uuid.UUID) clause.Expression {
+		return clause.Expr{SQL: fmt.Sprintf("(%[1]s.organization_id = ? OR %[1]s.program_id IN (%[2]s))", t, sharedProgramIDs), Vars: []interface{}{org, org}}
+	},
+	"lessons": func(t string, org uuid.UUID) clause.Expression {
+		return clause.Expr{SQL: fmt.Sprintf("(%[1]s.organization_id = ? OR %[1]s.subcourse_id IN (SELECT id FROM subcourses WHERE program_id IN (%[2]s)))", t, sharedProgramIDs), Vars: []interface{}{org, org}}
+	},
+	"users":               ownRowsOnly,
+	"teacher_assignments": ownRowsOnly,
+	"lesson_templates":    ownRowsOnly,
+}
+
+func ownRowsOnly(t string, org uuid.UUID) clause.Expression {
+	return clause.Expr{SQL: fmt.Sprintf("%s.organization_id = ?", t), Vars: []interface{}{org}}
+}
+
+// RegisterTenantCallbacks installs GORM callbacks that scope every query,
+// update and delete on tenant-owned tables to the organization stored in the
+// statement context (see WithTenant), and stamp organization_id on create.
+// Shared programs are readable but never writable by other organizations.
+// Child rows (lesson components, media) are only reached through their
+// tenant-filtered parents and are not filtered themselves.
+func RegisterTenantCallbacks(db *gorm.DB) error {
+	if err := db.Callback().Query().Before("gorm:query").Register("tenant:query", tenantQuery); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register("tenant:update", tenantWrite); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().Before("gorm:delete").Register("tenant:delete", tenantWrite); err != nil {
+		return err
+	}
+	return db.Callback().Create().Before("gorm:create").Register("tenant:create", tenantCreate)
+}
+
+func tenantScope(db *gorm.DB) (uuid.UUID, string, bool) {
+	if db.Statement == nil || db.Statement.Schema == nil {
+		return uuid.Nil, "", false
+	}
+	if skip, ok := db.Statement.Settings.Load(skipTenantKey); ok && skip == true {
+		return uuid.Nil, "", false
+	}
+	org, ok := TenantFromContext(db.Statement.Context)
+	if !ok {
+		return uuid.Nil, "", false
+	}
+	table := db.Statement.Table
+	if table == "" {
+		table = db.Statement.Schema.Table
+	}
+	if _, ok := tenantReadFilters[table]; !ok {
+		return uuid.Nil, "", false
+	}
+	return org, table, true
+}
+
+func tenantQuery(db *gorm.DB) {
+	org, table, ok := tenantScope(db)
+	if !ok {
+		return
+	}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{tenantReadFilters[table](db.Statement.Quote(table), org)}})
+}
+
+func tenantWrite(db *gorm.DB) {
+	org, table, ok := tenantScope(db)
+	if !ok {
+		return
+	}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{ownRowsOnly(db.Statement.Quote(table), org)}})
+}
+
+func tenantCreate(db *gorm.DB) {
+	org, _, ok := tenantScope(db)
+	if !ok {
+		return
+	}
+	field := db.Statement.Schema.LookUpField("OrganizationID")
+	if field == nil {
+		return
+	}
+	ctx := db.Statement.Context
+	switch db.Statement.ReflectValue.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < db.Statement.ReflectValue.Len(); i++ {
+			elem := db.Statement.ReflectValue.Index(i)
+			if _, isZero := field.ValueOf(ctx, elem); isZero {
+				_ = field.Set(ctx, elem, &org)
+			}
+		}
+	case reflect.Struct:
+		if _, isZero := field.ValueOf(ctx, db.Statement.ReflectValue); isZero {
+			_ = field.Set(ctx, db.Statement.ReflectValue, &org)
+		}
+	}
+}
+
+// ForTenant returns db restricted to organizationID, independent of the request tenant
+func ForTenant(db *gorm.DB, organizationID uuid.UUID) *gorm.DB {
+	return db.WithContext(WithTenant(db.Statement.Context, organizationID))
+}
@@ -2,31 +2,71 @@ package handlers
 
 import (
 	"courseai/backend/internal/config"
-	"courseai/backend/internal/database"
 	"courseai/backend/internal/middleware"
 	"courseai/backend/internal/models"
+	"courseai/backend/internal/security"
+	"courseai/backend/internal/service"
 	"courseai/backend/internal/utils"
+	"errors"
+	"log"
+	"math"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type AuthHandler struct {
-	Config *config.Config
+	Config      *config.Config
+	Guard       *security.LoginGuard
+	users       *service.UserService
+	twoFactor   *service.TwoFactorService
+	assignments *service.AssignmentService
 }
 
-func NewAuthHandler(cfg *config.Config) *AuthHandler {
-	return &AuthHandler{Config: cfg}
+func NewAuthHandler(cfg *config.Config, guard *security.LoginGuard, users *service.UserService, twoFactor *service.TwoFactorService, assignments *service.AssignmentService) *AuthHandler {
+	return &AuthHandler{Config: cfg, Guard: guard, users: users, twoFactor: twoFactor, assignments: assignments}
 }
 
 type LoginRequest struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Username string `json:"username" openapi:"required"`
+	Password string `json:"password" openapi:"required"`
 }
 
+// LoginResponse completes a login with a session token. When a second factor
+// is needed it carries the challenge instead.
 type LoginResponse struct {
-	Token string       `json:"token"`
-	User  *models.User `json:"user"`
+	Token string       `json:"token,omitempty"`
+	User  *UserProfile `json:"user,omitempty"`
+	*MFAChallenge
+}
+
+// MFAChallenge is the first step of a two-factor login
+type MFAChallenge struct {
+	MFARequired        bool   `json:"mfa_required"`
+	EnrollmentRequired bool   `json:"enrollment_required"`
+	ChallengeToken     string `json:"challenge_token"`
+	ExpiresIn          int    `json:"expires_in"`
+}
+
+// UserProfile is the signed-in user; teachers also get their active assignments
+type UserProfile struct {
+	models.User
+	Assignments []models.TeacherAssignment `json:"assignments,omitempty"`
+}
+
+// userProfile loads what UserProfile adds to user
+func (h *AuthHandler) userProfile(c *fiber.Ctx, user *models.User) (*UserProfile, error) {
+	profile := &UserProfile{User: *user}
+	if user.Role == models.RoleTeacher {
+		assignments, err := h.assignments.Active(c.UserContext(), user.ID)
+		if err != nil {
+			return nil, err
+		}
+		profile.Assignments = assignments
+	}
+	return profile, nil
 }
 
 func (h *AuthHandler) Login(c *fiber.Ctx) error {
@@ -43,32 +83,94 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		})
 	}
 
-	db := database.GetDB()
-	var user models.User
+	ip := c.IP()
+	userAgent := c.Get("User-Agent")
+
+	// Every attempt counts as failed until the password checks out, so
+	// parallel guesses cannot slip past the delay or lockout. A locked out or
+	// throttled IP is rejected before the user is looked up.
+	attempts, ok, err := h.reserve(c, nil, req.Username, security.IPKey(ip))
+	if !ok {
+		return err
+	}
 
 	// Find user by username or email
-	if err := db.Where("username = ? OR email = ?", req.Username, req.Username).First(&user).Error; err != nil {
+	user, err := h.users.FindByLogin(c.UserContext(), req.Username)
+	if err != nil {
+		h.release(attempts...)
+		log.Printf("Login: failed to look up %q: %v", req.Username, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to look up user"})
+	}
+	var userID *uuid.UUID
+	if user != nil {
+		userID = &user.ID
+	}
+	accountKey := security.AccountKey(userID, req.Username)
+
+	account, ok, err := h.reserve(c, userID, req.Username, accountKey)
+	if !ok {
+		h.release(attempts...)
+		return err
+	}
+	attempts = append(attempts, account...)
+
+	if userID == nil {
+		h.recordAttempt(userID, req.Username, ip, userAgent, false, "unknown_user")
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid credentials",
+		})
+	}
+
+	// Verify password before revealing account state
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		h.recordAttempt(userID, req.Username, ip, userAgent, false, "invalid_password")
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Invalid credentials",
 		})
 	}
+	h.release(attempts...)
+
+	// SSO-only accounts cannot use a password
+	if user.PasswordLoginDisabled {
+		h.recordAttempt(userID, req.Username, ip, userAgent, false, "password_login_disabled")
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Password login is disabled for this account; use single sign-on",
+		})
+	}
 
 	// Check if user is active
 	if user.Status != models.StatusActive {
+		h.recordAttempt(userID, req.Username, ip, userAgent, false, "inactive")
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Account is not active",
 		})
 	}
 
-	// Verify password
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+	// Users of a suspended organization cannot sign in
+	if active, err := h.users.OrganizationActive(c.UserContext(), user); err != nil || !active {
+		h.recordAttempt(userID, req.Username, ip, userAgent, false, "organization_suspended")
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid credentials",
+			"error": "Organization is not active",
 		})
 	}
 
+	// Second factor: the account throttle is only reset once the whole login succeeds
+	if purpose := mfaPurpose(h.Config.Security, user); purpose != "" {
+		return h.mfaChallenge(c, user, purpose)
+	}
+
+	if err := h.Guard.Reset(accountKey); err != nil {
+		log.Printf("Login: failed to reset throttle for %s: %v", accountKey, err)
+	}
+	h.recordAttempt(userID, req.Username, ip, userAgent, true, "")
+
+	return h.issueSession(c, user)
+}
+
+// issueSession generates the session JWT and answers with the login response
+func (h *AuthHandler) issueSession(c *fiber.Ctx, user *models.User) error {
 	// Generate JWT token
-	token, err := utils.GenerateJWT(&user, h.Config.JWT.Secret, h.Config.JWT.ExpireHours)
+	token, err := utils.GenerateJWT(user, h.Config.JWT.Secret, h.Config.JWT.ExpireHours)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to generate token",
@@ -76,28 +178,14 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 	}
 
 	// If teacher, include assignments in returned user struct
-	if user.Role == models.RoleTeacher {
-		var assigns []models.TeacherAssignment
-		if err := database.GetDB().Where("teacher_id = ? AND status = ?", user.ID, models.AssignmentStatusActive).Find(&assigns).Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load assignments"})
-		}
-		// prepare a user map including assignments
-		userMap := fiber.Map{
-			"id":          user.ID,
-			"username":    user.Username,
-			"email":       user.Email,
-			"role":        user.Role,
-			"status":      user.Status,
-			"created_at":  user.CreatedAt,
-			"updated_at":  user.UpdatedAt,
-			"assignments": assigns,
-		}
-		return c.JSON(fiber.Map{"token": token, "user": userMap})
+	profile, err := h.userProfile(c, user)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load assignments"})
 	}
 
 	return c.JSON(LoginResponse{
 		Token: token,
-		User:  &user,
+		User:  profile,
 	})
 }
 
@@ -109,33 +197,71 @@ func (h *AuthHandler) Me(c *fiber.Ctx) error {
 		})
 	}
 
-	db := database.GetDB()
-	var user models.User
-	if err := db.First(&user, "id = ?", userID).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "User not found",
-		})
+	user, err := h.users.Get(c.UserContext(), userID)
+	if err != nil {
+		return serviceError(err, "Failed to fetch user")
 	}
 
 	// If the user is a teacher, include active assignments
-	if user.Role == models.RoleTeacher {
-		var assigns []models.TeacherAssignment
-		if err := db.Where("teacher_id = ? AND status = ?", user.ID, models.AssignmentStatusActive).Find(&assigns).Error; err != nil {
-			// log but don't fail completely
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load assignments"})
+	profile, err := h.userProfile(c, user)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load assignments"})
+	}
+	return c.JSON(profile)
+}
+
+// reserve counts the attempt against each key before the credentials are
+// checked (see security.LoginGuard.Reserve). When a key refuses it, the
+// attempts counted so far are given back, the refusal is answered and ok is
+// false.
+func (h *AuthHandler) reserve(c *fiber.Ctx, userID *uuid.UUID, identifier string, keys ...string) ([]*security.Attempt, bool, error) {
+	attempts := make([]*security.Attempt, 0, len(keys))
+	for _, key := range keys {
+		attempt, err := h.Guard.Reserve(key)
+		if err != nil {
+			h.release(attempts...)
+			return nil, false, h.blocked(c, userID, identifier, c.IP(), c.Get("User-Agent"), err)
 		}
-		// Return combined user with assignments
-		return c.JSON(fiber.Map{
-			"id":          user.ID,
-			"username":    user.Username,
-			"email":       user.Email,
-			"role":        user.Role,
-			"status":      user.Status,
-			"created_at":  user.CreatedAt,
-			"updated_at":  user.UpdatedAt,
-			"assignments": assigns,
-		})
+		attempts = append(attempts, attempt)
+	}
+	return attempts, true, nil
+}
+
+// release gives back attempts whose credentials turned out right
+func (h *AuthHandler) release(attempts ...*security.Attempt) {
+	for _, attempt := range attempts {
+		if err := h.Guard.Release(attempt); err != nil {
+			log.Printf("Login: failed to release attempt: %v", err)
+		}
+	}
+}
+
+func (h *AuthHandler) recordAttempt(userID *uuid.UUID, identifier, ip, userAgent string, success bool, reason string) {
+	if err := h.Guard.RecordAttempt(userID, identifier, ip, userAgent, success, reason); err != nil {
+		log.Printf("Login: failed to record login attempt: %v", err)
+	}
+}
+
+// blocked answers a login that was refused by the guard: 423 for lockouts, 429 for progressive delays
+func (h *AuthHandler) blocked(c *fiber.Ctx, userID *uuid.UUID, identifier, ip, userAgent string, err error) error {
+	var be *security.BlockedError
+	if !errors.As(err, &be) {
+		log.Printf("Login: throttle check failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to check login throttle"})
 	}
 
-	return c.JSON(user)
+	reason := "throttled"
+	status := fiber.StatusTooManyRequests
+	if errors.Is(be, security.ErrLocked) {
+		reason = "locked"
+		status = fiber.StatusLocked
+	}
+	h.recordAttempt(userID, identifier, ip, userAgent, false, string(be.Scope)+"_"+reason)
+
+	retryAfter := int(math.Ceil(be.RetryAfter.Seconds()))
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+	return c.Status(status).JSON(fiber.Map{
+		"error":       be.Error(),
+		"retry_after": retryAfter,
+	})
 }
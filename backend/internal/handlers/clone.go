@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"courseai/backend/internal/middleware"
+	"courseai/backend/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type CloneHandler struct {
+	clones *service.CloneService
+}
+
+func NewCloneHandler(clones *service.CloneService) *CloneHandler {
+	return &CloneHandler{clones: clones}
+}
+
+// CloneInput - optional body of the clone endpoints. Title/Name and Slug apply
+// to the top-level copy only; nested copies keep their names and get fresh slugs.
+type CloneInput struct {
+	SubcourseID *uuid.UUID `json:"subcourse_id,omitempty"` // lesson clone target
+	ProgramID   *uuid.UUID `json:"program_id,omitempty"`   // subcourse clone target
+	Title       string     `json:"title,omitempty"`
+	Name        string     `json:"name,omitempty"`
+	Slug        string     `json:"slug,omitempty"`
+}
+
+// parseCloneInput parses the :id to clone and the optional body
+func parseCloneInput(c *fiber.Ctx, what string) (uuid.UUID, *CloneInput, error) {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return uuid.Nil, nil, fiber.NewError(fiber.StatusBadRequest, "Invalid "+what+" ID")
+	}
+	var input CloneInput
+	if len(c.Body()) == 0 {
+		return id, &input, nil
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return uuid.Nil, nil, fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	return id, &input, nil
+}
+
+// POST /api/admin/lessons/:id/clone
+func (h *CloneHandler) CloneLesson(c *fiber.Ctx) error {
+	lessonID, input, err := parseCloneInput(c, "lesson")
+	if err != nil {
+		return err
+	}
+	opts := service.CloneOptions{Name: input.Title, Slug: input.Slug}
+	clone, err := h.clones.CloneLesson(middleware.TenantContext(c), middleware.Actor(c), lessonID, input.SubcourseID, opts)
+	if err != nil {
+		return serviceError(err, "Failed to clone lesson")
+	}
+	return c.Status(fiber.StatusCreated).JSON(clone)
+}
+
+// POST /api/admin/subcourses/:id/clone
+func (h *CloneHandler) CloneSubcourse(c *fiber.Ctx) error {
+	subcourseID, input, err := parseCloneInput(c, "subcourse")
+	if err != nil {
+		return err
+	}
+	opts := service.CloneOptions{Name: input.Name, Slug: input.Slug}
+	clone, err := h.clones.CloneSubcourse(middleware.TenantContext(c), middleware.Actor(c), subcourseID, input.ProgramID, opts)
+	if err != nil {
+		return serviceError(err, "Failed to clone subcourse")
+	}
+	return c.Status(fiber.StatusCreated).JSON(clone)
+}
+
+// POST /api/admin/programs/:id/clone - copies the program into the caller's organization
+func (h *CloneHandler) CloneProgram(c *fiber.Ctx) error {
+	programID, input, err := parseCloneInput(c, "program")
+	if err != nil {
+		return err
+	}
+	opts := service.CloneOptions{Name: input.Name, Slug: input.Slug}
+	clone, err := h.clones.CloneProgram(middleware.TenantContext(c), middleware.Actor(c), programID, opts)
+	if err != nil {
+		return serviceError(err, "Failed to clone program")
+	}
+	return c.Status(fiber.StatusCreated).JSON(clone)
+}
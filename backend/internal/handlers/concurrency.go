@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"courseai/backend/internal/middleware"
+	"courseai/backend/internal/service"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// versionETag formats a content version as an entity tag
+func versionETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// setVersionETag sets the ETag response header for a versioned record, and
+// the version header that keeps it on cached routes
+func setVersionETag(c *fiber.Ctx, version int) {
+	c.Set(fiber.HeaderETag, versionETag(version))
+	c.Set(middleware.VersionHeader, strconv.Itoa(version))
+}
+
+// ifMatch reports whether the request's If-Match header allows a write to a
+// record at version. Versioned writes must send the header: without it the
+// answer is 428, so a client that never read the version cannot overwrite
+// someone else's change. "*" always matches; weak tags are compared by value.
+func ifMatch(c *fiber.Ctx, version int) (bool, error) {
+	header := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if header == "" {
+		return false, fiber.NewError(fiber.StatusPreconditionRequired, "If-Match header is required; send the version the change is based on")
+	}
+	if header == "*" {
+		return true, nil
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		n, err := strconv.Atoi(strings.Trim(tag, `"`))
+		if err != nil {
+			return false, fiber.NewError(fiber.StatusBadRequest, "Invalid If-Match header")
+		}
+		if n == version {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ifMatchPrecondition checks the request's If-Match header for a service call
+func ifMatchPrecondition(c *fiber.Ctx) service.Precondition {
+	return func(version int) (bool, error) {
+		return ifMatch(c, version)
+	}
+}
+
+// VersionConflict is the 412 answer to a stale If-Match
+type VersionConflict struct {
+	Error          string      `json:"error"`
+	CurrentVersion int         `json:"current_version"`
+	Current        interface{} `json:"current"`
+}
+
+// versionConflict answers 412 with the current server copy so the client can merge
+func versionConflict(c *fiber.Ctx, current interface{}, version int) error {
+	setVersionETag(c, version)
+	return c.Status(fiber.StatusPreconditionFailed).JSON(VersionConflict{
+		Error:          "The record was modified by someone else",
+		CurrentVersion: version,
+		Current:        current,
+	})
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"courseai/backend/internal/middleware"
+	"courseai/backend/internal/repository"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// DeleteImpact counts what deleting a program, subcourse or lesson takes
+// along: the whole subtree, including children already in the trash, as that
+// is what a purge finally removes
+type DeleteImpact struct {
+	Type       string           `json:"type"`
+	ID         uuid.UUID        `json:"id"`
+	Subcourses int64            `json:"subcourses"`
+	Lessons    int64            `json:"lessons"`
+	Components map[string]int64 `json:"components"` // by component path, e.g. "content-blocks"
+	Templates  int64            `json:"templates"`
+	Media      int64            `json:"media"`
+	// MediaFiles are stored uploads no other media uses; MediaBytes is their size
+	MediaFiles int64  `json:"media_files"`
+	MediaBytes int64  `json:"media_bytes"`
+	Summary    string `json:"summary"`
+}
+
+// GET /api/admin/{programs,subcourses,lessons}/:id/delete-impact
+func (h *TrashHandler) Impact(kind repository.TrashKind) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid ID")
+		}
+		tree, err := h.trash.Impact(middleware.TenantContext(c), middleware.Actor(c), kind, id)
+		if err != nil {
+			return serviceError(err, "Failed to compute delete impact")
+		}
+		impact := &DeleteImpact{
+			Type: string(kind), ID: id,
+			Subcourses: tree.Subcourses, Lessons: tree.Lessons, Components: tree.Components,
+			Templates: tree.Templates, Media: int64(len(tree.Media)),
+			MediaFiles: tree.MediaFiles, MediaBytes: tree.MediaBytes,
+		}
+		impact.Summary = impactSummary(impact)
+		return c.JSON(impact)
+	}
+}
+
+// impactSummary phrases the counts, e.g. "This will remove 12 lessons, 80 media files"
+func impactSummary(impact *DeleteImpact) string {
+	var parts []string
+	add := func(n int64, one, many string) {
+		switch {
+		case n == 1:
+			parts = append(parts, "1 "+one)
+		case n > 1:
+			parts = append(parts, fmt.Sprintf("%d %s", n, many))
+		}
+	}
+	add(impact.Subcourses, "subcourse", "subcourses")
+	add(impact.Lessons, "lesson", "lessons")
+	add(impact.Templates, "template", "templates")
+	add(impact.MediaFiles, "media file", "media files")
+	if len(parts) == 0 {
+		return "This will remove the " + impact.Type + " only"
+	}
+	return "This will remove " + strings.Join(parts, ", ")
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"courseai/backend/internal/health"
+	"courseai/backend/internal/middleware"
+	"courseai/backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// HealthHandler serves the liveness and readiness probes. Mount it behind
+// TokenOptional: callers with system.health get the individual checks with
+// ?verbose=true, everyone else only the overall status.
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{checker: checker}
+}
+
+// GET /livez
+func (h *HealthHandler) Live(c *fiber.Ctx) error {
+	return h.respond(c, h.checker.Live(c.UserContext()))
+}
+
+// GET /readyz
+func (h *HealthHandler) Ready(c *fiber.Ctx) error {
+	return h.respond(c, h.checker.Ready(c.UserContext()))
+}
+
+// respond answers 200 when every check passed and 503 otherwise
+func (h *HealthHandler) respond(c *fiber.Ctx, report health.ProbeReport) error {
+	if !c.QueryBool("verbose") || !middleware.HasPermission(c, models.PermSystemHealth) {
+		report.Checks = nil
+	}
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	status := fiber.StatusOK
+	if !report.OK() {
+		status = fiber.StatusServiceUnavailable
+	}
+	return c.Status(status).JSON(report)
+}
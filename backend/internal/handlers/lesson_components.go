@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"courseai/backend/internal/jsonpatch"
+	"courseai/backend/internal/middleware"
+	"courseai/backend/internal/repository"
+	"courseai/backend/internal/service"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// componentParams parses :id, the :quizId of quiz options and, for
+// components that are not single, :componentId when withID is set
+func componentParams(c *fiber.Ctx, comp *repository.LessonComponent, withID bool) (lessonID, quizID, id uuid.UUID, err error) {
+	if lessonID, err = uuid.Parse(c.Params("id")); err != nil {
+		return uuid.Nil, uuid.Nil, uuid.Nil, fiber.NewError(fiber.StatusBadRequest, "Invalid lesson ID")
+	}
+	if comp.ParentColumn == "quiz_id" {
+		if quizID, err = uuid.Parse(c.Params("quizId")); err != nil {
+			return uuid.Nil, uuid.Nil, uuid.Nil, fiber.NewError(fiber.StatusBadRequest, "Invalid quiz ID")
+		}
+	}
+	if withID && !comp.Single {
+		if id, err = uuid.Parse(c.Params("componentId")); err != nil {
+			return uuid.Nil, uuid.Nil, uuid.Nil, fiber.NewError(fiber.StatusBadRequest, "Invalid component ID")
+		}
+	}
+	return lessonID, quizID, id, nil
+}
+
+// GET /api/admin/lessons/:id/<component>
+func (h *LessonHandler) ListComponents(comp *repository.LessonComponent) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		lessonID, quizID, _, err := componentParams(c, comp, false)
+		if err != nil {
+			return err
+		}
+		items, err := h.lessons.ListComponents(middleware.TenantContext(c), middleware.Actor(c), comp, lessonID, quizID)
+		if err != nil {
+			return serviceError(err, "Failed to fetch "+comp.JSONKey)
+		}
+		return c.JSON(items)
+	}
+}
+
+// POST /api/admin/lessons/:id/<component>
+func (h *LessonHandler) CreateComponent(comp *repository.LessonComponent) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		lessonID, quizID, _, err := componentParams(c, comp, false)
+		if err != nil {
+			return err
+		}
+		created, err := h.lessons.CreateComponent(middleware.TenantContext(c), middleware.Actor(c), comp, lessonID, quizID, c.Body())
+		if err != nil {
+			return rejectedError(c, err, "Failed to create "+comp.JSONKey)
+		}
+		return c.Status(fiber.StatusCreated).JSON(created)
+	}
+}
+
+// PUT /api/admin/lessons/:id/objectives|preparation - create or partially update a single component
+func (h *LessonHandler) PutSingleComponent(comp *repository.LessonComponent) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		lessonID, _, _, err := componentParams(c, comp, false)
+		if err != nil {
+			return err
+		}
+		saved, err := h.lessons.PutSingleComponent(middleware.TenantContext(c), middleware.Actor(c), comp, lessonID, c.Body())
+		if err != nil {
+			return rejectedError(c, err, "Failed to save "+comp.JSONKey)
+		}
+		return c.JSON(saved)
+	}
+}
+
+// PATCH /api/admin/lessons/:id/<component>/:componentId - update only the fields sent
+func (h *LessonHandler) PatchComponent(comp *repository.LessonComponent) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		lessonID, quizID, id, err := componentParams(c, comp, true)
+		if err != nil {
+			return err
+		}
+		updated, err := h.lessons.PatchComponent(middleware.TenantContext(c), middleware.Actor(c), comp, lessonID, quizID, id, c.Body())
+		if err != nil {
+			return rejectedError(c, err, "Failed to update "+comp.JSONKey)
+		}
+		return c.JSON(updated)
+	}
+}
+
+// DELETE /api/admin/lessons/:id/<component>/:componentId (or /objectives, /preparation)
+func (h *LessonHandler) DeleteComponent(comp *repository.LessonComponent) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		lessonID, quizID, id, err := componentParams(c, comp, true)
+		if err != nil {
+			return err
+		}
+		if err := h.lessons.DeleteComponent(middleware.TenantContext(c), middleware.Actor(c), comp, lessonID, quizID, id); err != nil {
+			return rejectedError(c, err, "Failed to delete "+comp.JSONKey)
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+type ReorderInput struct {
+	IDs []uuid.UUID `json:"ids" openapi:"required"`
+}
+
+// PUT /api/admin/lessons/:id/<component>/order - body {"ids": [...]} lists every component in the new order
+func (h *LessonHandler) ReorderComponents(comp *repository.LessonComponent) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		lessonID, quizID, _, err := componentParams(c, comp, false)
+		if err != nil {
+			return err
+		}
+		var input ReorderInput
+		if err := c.BodyParser(&input); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+		}
+		items, err := h.lessons.ReorderComponents(middleware.TenantContext(c), middleware.Actor(c), comp, lessonID, quizID, input.IDs)
+		if err != nil {
+			return serviceError(err, "Failed to reorder "+comp.JSONKey)
+		}
+		return c.JSON(items)
+	}
+}
+
+// PATCH /api/admin/lessons/:id with Content-Type application/json-patch+json.
+// The patch (RFC 6902) is applied to the lesson document as returned by GetOne;
+// only the rows that actually changed are written, so untouched components keep
+// their IDs. Media arrays are ignored here: media is managed through /media/upload,
+// and cover_media_id may only point at media uploaded for this lesson.
+func (h *LessonHandler) PatchTree(c *fiber.Ctx) error {
+	if !strings.HasPrefix(c.Get(fiber.HeaderContentType), "application/json-patch+json") {
+		return fiber.NewError(fiber.StatusUnsupportedMediaType, "Use Content-Type application/json-patch+json")
+	}
+	lessonID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid lesson ID")
+	}
+	ops, err := jsonpatch.Decode(c.Body())
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	lesson, err := h.lessons.PatchTree(middleware.TenantContext(c), middleware.Actor(c), lessonID, ops, ifMatchPrecondition(c))
+	if errors.Is(err, service.ErrVersionConflict) {
+		return h.conflict(c, lessonID)
+	}
+	if err != nil {
+		return rejectedError(c, err, "Failed to patch lesson")
+	}
+	setVersionETag(c, lesson.Version)
+	return c.JSON(lesson)
+}
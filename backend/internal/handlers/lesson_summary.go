@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"courseai/backend/internal/cache"
+	"courseai/backend/internal/middleware"
+	"courseai/backend/internal/models"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// LessonSummary is a lesson as list pages show it: without components and
+// media, with the names of its subcourse and program and the URL of its
+// cover. A list of them is one query.
+type LessonSummary struct {
+	ID              uuid.UUID            `json:"id"`
+	SubcourseID     uuid.UUID            `json:"subcourse_id"`
+	SubcourseName   string               `json:"subcourse_name"`
+	ProgramID       uuid.UUID            `json:"program_id"`
+	ProgramName     string               `json:"program_name"`
+	Title           string               `json:"title"`
+	Subtitle        string               `json:"subtitle"`
+	Slug            string               `json:"slug"`
+	Status          models.ContentStatus `json:"status"`
+	SortOrder       int                  `json:"sort_order"`
+	Version         int                  `json:"version"`
+	DurationMinutes int                  `json:"duration_minutes"`
+	Difficulty      string               `json:"difficulty"`
+	IsFeatured      bool                 `json:"is_featured"`
+	MediaCount      int                  `json:"media_count"`
+	CoverURL        string               `json:"cover_url,omitempty"`
+	PublishedAt     *time.Time           `json:"published_at,omitempty"`
+	UpdatedAt       time.Time            `json:"updated_at"`
+}
+
+// summarizeLessons selects lesson summaries in list order. The subcourse is
+// joined as "Subcourse", like listLessons, so assignedLessons applies.
+func summarizeLessons(db *gorm.DB) *gorm.DB {
+	return db.Model(&models.Lesson{}).
+		Select(`lessons.id, lessons.subcourse_id, "Subcourse".name AS subcourse_name,
+			"Subcourse".program_id, "Program".name AS program_name,
+			lessons.title, lessons.subtitle, lessons.slug, lessons.status, lessons.sort_order, lessons.version,
+			lessons.duration_minutes, lessons.difficulty, lessons.is_featured, lessons.media_count,
+			COALESCE(cover.url, '') AS cover_url, lessons.published_at, lessons.updated_at`).
+		Joins(`JOIN subcourses "Subcourse" ON "Subcourse".id = lessons.subcourse_id AND "Subcourse".deleted_at IS NULL`).
+		Joins(`JOIN programs "Program" ON "Program".id = "Subcourse".program_id AND "Program".deleted_at IS NULL`).
+		Joins("LEFT JOIN media cover ON cover.id = lessons.cover_media_id").
+		Order("lessons.sort_order ASC, lessons.created_at DESC")
+}
+
+// lessonFilters applies the optional filters of the lesson lists and summaries
+func lessonFilters(c *fiber.Ctx, query *gorm.DB) *gorm.DB {
+	if subcourseID := c.Query("subcourse_id"); subcourseID != "" {
+		query = query.Where("lessons.subcourse_id = ?", subcourseID)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("lessons.status = ?", status)
+	}
+	return query
+}
+
+// GetSummariesPublic - Lesson summaries for public pages (no access control)
+func (h *LessonHandler) GetSummariesPublic(c *fiber.Ctx) error {
+	var summaries []LessonSummary
+	query := lessonFilters(c, summarizeLessons(middleware.TenantDB(c)))
+	if err := query.Find(&summaries).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch lessons"})
+	}
+
+	tags := []string{cache.LessonsTag}
+	for _, s := range summaries {
+		tags = append(tags, cache.LessonTag(s.ID), cache.SubcourseTag(s.SubcourseID), cache.ProgramTag(s.ProgramID))
+	}
+	middleware.CacheTags(c, tags...)
+	if summaries == nil {
+		summaries = []LessonSummary{}
+	}
+	return c.JSON(summaries)
+}
+
+// GetSummaries - Lesson summaries (with access control for teachers)
+func (h *LessonHandler) GetSummaries(c *fiber.Ctx) error {
+	query, ok := assignedLessons(c, summarizeLessons(middleware.TenantDB(c)))
+	if !ok {
+		return c.JSON([]LessonSummary{})
+	}
+	var summaries []LessonSummary
+	if err := lessonFilters(c, query).Find(&summaries).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch lessons"})
+	}
+	if summaries == nil {
+		summaries = []LessonSummary{}
+	}
+	return c.JSON(summaries)
+}
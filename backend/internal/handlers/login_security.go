@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"courseai/backend/internal/middleware"
+	"courseai/backend/internal/models"
+	"courseai/backend/internal/security"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultLoginHistoryLimit = 50
+	maxLoginHistoryLimit     = 500
+)
+
+// LoginHistory is a user's recent login attempts and current lockout
+type LoginHistory struct {
+	Attempts []models.LoginAttempt `json:"attempts"`
+	Lockout  *models.LoginThrottle `json:"lockout"`
+}
+
+// GET /api/admin/users/:id/login-history
+func (h *AuthHandler) GetLoginHistory(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user id")
+	}
+	return h.loginHistory(c, userID)
+}
+
+// GET /api/auth/me/login-history
+func (h *AuthHandler) GetMyLoginHistory(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	if userID == uuid.Nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "Unauthorized")
+	}
+	return h.loginHistory(c, userID)
+}
+
+func (h *AuthHandler) loginHistory(c *fiber.Ctx, userID uuid.UUID) error {
+	limit := c.QueryInt("limit", defaultLoginHistoryLimit)
+	if limit <= 0 || limit > maxLoginHistoryLimit {
+		limit = defaultLoginHistoryLimit
+	}
+
+	db := middleware.TenantDB(c)
+	var attempts []models.LoginAttempt
+	if err := db.Where("user_id = ?", userID).Order("created_at DESC").Limit(limit).Find(&attempts).Error; err != nil {
+		log.Printf("GetLoginHistory error: %v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch login history")
+	}
+
+	lock, err := h.Guard.Status(security.AccountKey(&userID, ""))
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch lockout status")
+	}
+
+	return c.JSON(LoginHistory{Attempts: attempts, Lockout: lock})
+}
+
+// POST /api/admin/users/:id/unlock
+func (h *AuthHandler) UnlockUser(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user id")
+	}
+
+	db := middleware.TenantDB(c)
+	var user models.User
+	if err := db.First(&user, "id = ?", userID).Error; err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "User not found")
+	}
+
+	if err := h.Guard.Reset(security.AccountKey(&userID, "")); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to unlock user")
+	}
+	// Optionally clear an IP lockout at the same time (e.g. a shared school network)
+	if ip := c.Query("ip"); ip != "" {
+		if err := h.Guard.Reset(security.IPKey(ip)); err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to unlock IP")
+		}
+	}
+
+	log.Printf("User %s unlocked by %s", user.Username, middleware.GetUsername(c))
+	return c.JSON(MessageResponse{Message: "User unlocked"})
+}
@@ -1,34 +1,36 @@
 package handlers
 
 import (
-	"courseai/backend/internal/config"
-	"courseai/backend/internal/database"
+	"courseai/backend/internal/middleware"
 	"courseai/backend/internal/models"
-	"encoding/json"
-	"fmt"
-	"io"
-	"net/http"
-	"os"
-	"path/filepath"
-	"strings"
-	"time"
+	"courseai/backend/internal/openapi"
+	"courseai/backend/internal/service"
+	"courseai/backend/internal/validation"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 )
 
 type MediaHandler struct {
-	cfg *config.Config
+	media *service.MediaService
 }
 
-func NewMediaHandler(cfg *config.Config) *MediaHandler {
-	return &MediaHandler{cfg: cfg}
+func NewMediaHandler(media *service.MediaService) *MediaHandler {
+	return &MediaHandler{media: media}
 }
 
-var allowedMimePrefixes = []string{"image/", "video/", "audio/"}
-var allowedMimeExact = []string{"application/pdf", "text/plain"}
+// MediaUploadForm is the multipart form of an upload; every file becomes one
+// media row of the owner
+type MediaUploadForm struct {
+	OwnerType models.MediaOwnerType `json:"owner_type" openapi:"required"`
+	OwnerID   uuid.UUID             `json:"owner_id" openapi:"required"`
+	Purpose   models.MediaPurpose   `json:"purpose,omitempty"`
+	File      []openapi.File        `json:"file" openapi:"required"`
+}
 
-const maxUploadSize = 32 << 20 // 32 MB
+type MediaUploadResponse struct {
+	Media []models.Media `json:"media"`
+}
 
 func (h *MediaHandler) Upload(c *fiber.Ctx) error {
 	// Parse multipart form using Fiber helper
@@ -53,151 +55,32 @@ func (h *MediaHandler) Upload(c *fiber.Ctx) error {
 	}
 
 	purpose := c.FormValue("purpose")
-
-	files := form.File["file"]
-	if len(files) == 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "file is required"})
-	}
-
-	db := database.GetDB()
-
-	// Validate owner_type is one of the allowed enum values and that owner_id exists for that owner_type
-	switch models.MediaOwnerType(ownerType) {
-	case models.OwnerLesson:
-		var l models.Lesson
-		if err := db.First(&l, "id = ?", ownerID).Error; err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "owner_id not found for owner_type lesson"})
-		}
-	case models.OwnerLessonModel:
-		var m models.LessonModel
-		if err := db.First(&m, "id = ?", ownerID).Error; err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "owner_id not found for owner_type lesson_model"})
-		}
-	case models.OwnerLessonPreparation:
-		var p models.LessonPreparation
-		if err := db.First(&p, "id = ?", ownerID).Error; err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "owner_id not found for owner_type lesson_preparation"})
-		}
-	case models.OwnerLessonBuild:
-		var b models.LessonBuild
-		if err := db.First(&b, "id = ?", ownerID).Error; err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "owner_id not found for owner_type lesson_build"})
-		}
-	case models.OwnerLessonContentBlock:
-		var cb models.LessonContentBlock
-		if err := db.First(&cb, "id = ?", ownerID).Error; err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "owner_id not found for owner_type lesson_content_block"})
-		}
-	case models.OwnerLessonAttachment:
-		var a models.LessonAttachment
-		if err := db.First(&a, "id = ?", ownerID).Error; err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "owner_id not found for owner_type lesson_attachment"})
-		}
-	case models.OwnerLessonChallenge:
-		var ch models.LessonChallenge
-		if err := db.First(&ch, "id = ?", ownerID).Error; err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "owner_id not found for owner_type lesson_challenge"})
-		}
-	default:
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid owner_type"})
+	v := validation.New()
+	validation.OneOf(v, "purpose", models.MediaPurpose(purpose), models.MediaPurposes)
+	if err := v.Err(); err != nil {
+		return invalid(c, fiber.StatusBadRequest, err)
 	}
 
-	// create uploads dir if not exists
-	baseDir := "uploads"
-	if _, err := os.Stat(baseDir); os.IsNotExist(err) {
-		_ = os.Mkdir(baseDir, 0755)
+	upload := service.Upload{
+		OwnerType: models.MediaOwnerType(ownerType),
+		OwnerID:   ownerID,
+		Purpose:   models.MediaPurpose(purpose),
 	}
-
-	savedMedias := make([]models.Media, 0)
-
-	// handle multiple files
-	for _, fh := range files {
+	for _, fh := range form.File["file"] {
 		if fh == nil {
 			continue
 		}
-
-		// open file to read header for content-type detection
 		f, err := fh.Open()
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to open file"})
 		}
-		var headerBuf [512]byte
-		n, _ := io.ReadFull(f, headerBuf[:])
-		contentType := http.DetectContentType(headerBuf[:n])
-		// reset reader by closing (we will let SaveFile re-open the file)
-		f.Close()
-
-		// validate mime
-		if !isAllowedMime(contentType) {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("file type not allowed: %s", contentType)})
-		}
-
-		// prepare dest path
-		ownerDir := filepath.Join(baseDir, ownerType)
-		if _, err := os.Stat(ownerDir); os.IsNotExist(err) {
-			_ = os.MkdirAll(ownerDir, 0755)
-		}
-
-		ext := filepath.Ext(fh.Filename)
-		fname := fmt.Sprintf("%s_%d%s", uuid.New().String(), time.Now().UnixNano(), ext)
-		destPath := filepath.Join(ownerDir, fname)
-
-		// save file using Fiber helper (handles underlying copy)
-		if err := c.SaveFile(fh, destPath); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to save file"})
-		}
-
-		// get size and enforce max size
-		info, _ := os.Stat(destPath)
-		var savedSize int64 = 0
-		if info != nil {
-			savedSize = info.Size()
-		}
-		if savedSize > int64(64<<20) { // 64MB limit
-			// remove file and return clear error
-			_ = os.Remove(destPath)
-			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{"error": "file too large"})
-		}
-
-		// build URL relative to server
-		urlPath := fmt.Sprintf("/uploads/%s/%s", ownerType, fname)
-
-		// meta: size, original filename
-		meta := map[string]interface{}{"original_name": fh.Filename, "size": savedSize}
-		metaJson, _ := json.Marshal(meta)
-
-		media := models.Media{
-			OwnerType: models.MediaOwnerType(ownerType),
-			OwnerID:   ownerID,
-			URL:       urlPath,
-			MimeType:  contentType,
-			Purpose:   models.MediaPurpose(purpose),
-			SortOrder: 0,
-			Meta:      metaJson,
-		}
-
-		if err := db.Create(&media).Error; err != nil {
-			// remove saved file on DB failure to avoid orphan files
-			_ = os.Remove(destPath)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to create media record"})
-		}
-
-		savedMedias = append(savedMedias, media)
+		defer f.Close()
+		upload.Files = append(upload.Files, service.UploadFile{Name: fh.Filename, Content: f})
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"media": savedMedias})
-}
-
-func isAllowedMime(ct string) bool {
-	for _, p := range allowedMimePrefixes {
-		if strings.HasPrefix(ct, p) {
-			return true
-		}
-	}
-	for _, e := range allowedMimeExact {
-		if ct == e {
-			return true
-		}
+	media, err := h.media.Upload(middleware.TenantContext(c), middleware.Actor(c), upload)
+	if err != nil {
+		return serviceError(err, "Failed to upload media")
 	}
-	return false
+	return c.Status(fiber.StatusCreated).JSON(MediaUploadResponse{Media: media})
 }
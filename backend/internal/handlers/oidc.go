@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"courseai/backend/internal/config"
+	"courseai/backend/internal/middleware"
+	"courseai/backend/internal/models"
+	"courseai/backend/internal/oidc"
+	"courseai/backend/internal/security"
+	"courseai/backend/internal/service"
+	"courseai/backend/internal/utils"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const oidcStateTTL = 10 * time.Minute
+
+// oidcStateCookie binds a flow to the browser that started it: it holds the
+// hash of the state, and the callback only accepts a state whose hash the
+// browser presents. Without it, a victim could be sent through a callback
+// the attacker started and end up signed in as, or linked to, the attacker.
+const oidcStateCookie = "oidc_state"
+
+type OIDCHandler struct {
+	Config   *config.Config
+	Provider *oidc.Provider
+	Guard    *security.LoginGuard
+	signOn   *service.SignOnService
+	users    *service.UserService
+}
+
+func NewOIDCHandler(cfg *config.Config, guard *security.LoginGuard, signOn *service.SignOnService, users *service.UserService) *OIDCHandler {
+	return &OIDCHandler{Config: cfg, Provider: oidc.NewProvider(cfg.OIDC), Guard: guard, signOn: signOn, users: users}
+}
+
+// AuthorizationURL is where the browser goes to sign in at the identity provider
+type AuthorizationURL struct {
+	URL string `json:"url"`
+}
+
+// GET /api/auth/oidc/login - redirect to the identity provider.
+// With ?mode=json the authorization URL is returned instead of a redirect;
+// the request must then carry credentials, see setStateCookie.
+func (h *OIDCHandler) Login(c *fiber.Ctx) error {
+	if !h.Provider.Enabled() {
+		return fiber.NewError(fiber.StatusNotFound, "Single sign-on is not configured")
+	}
+	viaScript := c.Query("mode") == "json"
+	authURL, err := h.startFlow(c, nil, viaScript)
+	if err != nil {
+		return err
+	}
+	if viaScript {
+		return c.JSON(AuthorizationURL{URL: authURL})
+	}
+	return c.Redirect(authURL, fiber.StatusFound)
+}
+
+// POST /api/auth/oidc/link - start a flow that links an SSO identity to the signed-in user
+func (h *OIDCHandler) Link(c *fiber.Ctx) error {
+	if !h.Provider.Enabled() {
+		return fiber.NewError(fiber.StatusNotFound, "Single sign-on is not configured")
+	}
+	userID := middleware.GetUserID(c)
+	if userID == uuid.Nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "Unauthorized")
+	}
+	authURL, err := h.startFlow(c, &userID, true)
+	if err != nil {
+		return err
+	}
+	return c.JSON(AuthorizationURL{URL: authURL})
+}
+
+// startFlow stores a pending sign-on and returns the provider's authorization
+// URL. viaScript is set when the answer goes to a script rather than being
+// followed as a top-level redirect.
+func (h *OIDCHandler) startFlow(c *fiber.Ctx, linkUserID *uuid.UUID, viaScript bool) (string, error) {
+	state, err := oidc.RandomString(24)
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusInternalServerError, "Failed to start sign-on")
+	}
+	nonce, err := oidc.RandomString(24)
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusInternalServerError, "Failed to start sign-on")
+	}
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusInternalServerError, "Failed to start sign-on")
+	}
+
+	authURL, err := h.Provider.AuthCodeURL(c.UserContext(), state, nonce, challenge)
+	if err != nil {
+		log.Printf("OIDC: %v", err)
+		return "", fiber.NewError(fiber.StatusBadGateway, "Identity provider is unavailable")
+	}
+
+	pending := models.OIDCLoginState{
+		State:        state,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		LinkUserID:   linkUserID,
+		ExpiresAt:    time.Now().Add(oidcStateTTL),
+	}
+	if err := h.signOn.Begin(c.UserContext(), &pending); err != nil {
+		return "", serviceError(err, "Failed to start sign-on")
+	}
+	setStateCookie(c, stateHash(state), oidcStateTTL, viaScript)
+	return authURL, nil
+}
+
+// setStateCookie sets the state cookie for ttl, or removes it with a
+// ttl of 0. It is Lax, so it comes along on the provider's top-level
+// redirect to the callback.
+//
+// A browser only stores a Lax cookie from a response to a script when the
+// page is on the same site as the API. For a frontend on another site,
+// viaScript flows over HTTPS use SameSite=None; Secure instead. The script
+// must still send the request with credentials ("include"), and browsers
+// that block third-party cookies drop the cookie anyway; those need the
+// frontend and the API on one site. The redirect of GET /api/auth/oidc/login
+// works either way.
+func setStateCookie(c *fiber.Ctx, value string, ttl time.Duration, viaScript bool) {
+	secure := c.Protocol() == "https"
+	cookie := &fiber.Cookie{
+		Name:     oidcStateCookie,
+		Value:    value,
+		Path:     "/api/auth/oidc/callback",
+		MaxAge:   int(ttl.Seconds()),
+		Secure:   secure,
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	}
+	// browsers reject SameSite=None without Secure; on plain HTTP (local
+	// development) the frontend is on the API's site, so Lax works
+	if viaScript && secure {
+		cookie.SameSite = fiber.CookieSameSiteNoneMode
+	}
+	if ttl == 0 {
+		cookie.Expires = time.Unix(0, 0)
+	}
+	c.Cookie(cookie)
+}
+
+// stateHash is what the state cookie holds, so the state itself only
+// travels through the provider
+func stateHash(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:])
+}
+
+// GET /api/auth/oidc/callback - the identity provider redirects here with code and state.
+// The browser is sent on to the frontend with the issued token (or an error) in the URL fragment.
+func (h *OIDCHandler) Callback(c *fiber.Ctx) error {
+	if !h.Provider.Enabled() {
+		return fiber.NewError(fiber.StatusNotFound, "Single sign-on is not configured")
+	}
+	if e := c.Query("error"); e != "" {
+		return h.finish(c, url.Values{"error": {"Sign-on was cancelled: " + e}})
+	}
+
+	state, code := c.Query("state"), c.Query("code")
+	if state == "" || code == "" {
+		return h.finish(c, url.Values{"error": {"Invalid sign-on response"}})
+	}
+	cookie := c.Cookies(oidcStateCookie)
+	setStateCookie(c, "", 0, false)
+	if subtle.ConstantTimeCompare([]byte(cookie), []byte(stateHash(state))) != 1 {
+		return h.finish(c, url.Values{"error": {"Sign-on was started in another browser"}})
+	}
+
+	// state is single use: of concurrent callbacks only one gets the flow
+	pending, err := h.signOn.Take(c.UserContext(), state)
+	if err != nil {
+		return h.finish(c, url.Values{"error": {signOnError(err, "Sign-on session not found or already used")}})
+	}
+
+	tokens, err := h.Provider.Exchange(c.UserContext(), code, pending.CodeVerifier)
+	if err != nil {
+		log.Printf("OIDC callback: %v", err)
+		return h.finish(c, url.Values{"error": {"Failed to complete sign-on"}})
+	}
+	idToken, err := h.Provider.VerifyIDToken(c.UserContext(), tokens.IDToken, pending.Nonce)
+	if err != nil {
+		log.Printf("OIDC callback: %v", err)
+		return h.finish(c, url.Values{"error": {"Failed to verify identity"}})
+	}
+
+	if pending.LinkUserID != nil {
+		if err := h.signOn.Link(c.UserContext(), *pending.LinkUserID, idToken); err != nil {
+			return h.finish(c, url.Values{"error": {signOnError(err, "Failed to link identity")}})
+		}
+		return h.finish(c, url.Values{"linked": {"true"}})
+	}
+
+	user, err := h.signOn.Resolve(c.UserContext(), idToken)
+	if err != nil {
+		return h.finish(c, url.Values{"error": {signOnError(err, "Failed to complete sign-on")}})
+	}
+
+	identifier := "oidc:" + idToken.Subject
+	ip, userAgent := c.IP(), c.Get("User-Agent")
+	if user.Status != models.StatusActive {
+		h.recordAttempt(&user.ID, identifier, ip, userAgent, false, "inactive")
+		return h.finish(c, url.Values{"error": {"Account is not active"}})
+	}
+	if active, err := h.users.OrganizationActive(c.UserContext(), user); err != nil || !active {
+		h.recordAttempt(&user.ID, identifier, ip, userAgent, false, "organization_suspended")
+		return h.finish(c, url.Values{"error": {"Organization is not active"}})
+	}
+
+	if err := h.signOn.RecordLogin(c.UserContext(), idToken); err != nil {
+		log.Printf("OIDC: failed to record sign-in of %s: %v", user.Username, err)
+	}
+
+	// 2FA still applies to SSO logins; the frontend continues with the challenge token
+	if purpose := mfaPurpose(h.Config.Security, user); purpose != "" {
+		challenge, err := utils.GenerateChallengeJWT(user, h.Config.JWT.Secret, purpose, mfaChallengeTTL(h.Config.Security))
+		if err != nil {
+			return h.finish(c, url.Values{"error": {"Failed to generate token"}})
+		}
+		values := url.Values{"mfa_challenge": {challenge}}
+		if purpose == utils.PurposeMFAEnroll {
+			values.Set("enrollment_required", "true")
+		}
+		return h.finish(c, values)
+	}
+	h.recordAttempt(&user.ID, identifier, ip, userAgent, true, "sso")
+
+	token, err := utils.GenerateJWT(user, h.Config.JWT.Secret, h.Config.JWT.ExpireHours)
+	if err != nil {
+		return h.finish(c, url.Values{"error": {"Failed to generate token"}})
+	}
+	return h.finish(c, url.Values{"token": {token}})
+}
+
+// signOnError is the message the frontend shows for a failed sign-on step:
+// the service's own for expected failures, otherwise message
+func signOnError(err error, message string) string {
+	var e *service.Error
+	if errors.As(err, &e) {
+		return e.Message
+	}
+	log.Printf("OIDC: %s: %v", message, err)
+	return message
+}
+
+func (h *OIDCHandler) recordAttempt(userID *uuid.UUID, identifier, ip, userAgent string, success bool, reason string) {
+	if err := h.Guard.RecordAttempt(userID, identifier, ip, userAgent, success, reason); err != nil {
+		log.Printf("OIDC: failed to record login attempt: %v", err)
+	}
+}
+
+// finish redirects the browser to the frontend; values travel in the fragment
+// so the token never reaches server logs or Referer headers.
+func (h *OIDCHandler) finish(c *fiber.Ctx, values url.Values) error {
+	return c.Redirect(h.Config.OIDC.PostLoginRedirect+"#"+values.Encode(), fiber.StatusFound)
+}
+
+// GET /api/auth/me/identities
+func (h *OIDCHandler) GetMyIdentities(c *fiber.Ctx) error {
+	identities, err := h.signOn.Identities(c.UserContext(), middleware.GetUserID(c))
+	if err != nil {
+		return serviceError(err, "Failed to fetch identities")
+	}
+	return c.JSON(identities)
+}
+
+// DELETE /api/auth/me/identities/:id
+func (h *OIDCHandler) UnlinkIdentity(c *fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+	identityID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid identity id")
+	}
+
+	if err := h.signOn.Unlink(c.UserContext(), userID, identityID); err != nil {
+		return serviceError(err, "Failed to unlink identity")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"courseai/backend/internal/health"
+	"courseai/backend/internal/jsonpatch"
+	"courseai/backend/internal/models"
+	"courseai/backend/internal/openapi"
+	"courseai/backend/internal/repository"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// MessageResponse acknowledges an action that has nothing else to return
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// HealthStatus is the answer of GET /health
+type HealthStatus struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// APIInfo heads the OpenAPI document
+var APIInfo = openapi.Info{
+	Title:       "CourseAI API",
+	Description: "Programs, subcourses and lessons of the CourseAI learning platform. Admin routes need a bearer token from POST /api/auth/login and the permission listed as x-permission; X-Organization selects the organization a super-admin works in.",
+	Version:     "1.0.0",
+}
+
+// APIEnums are the string types documented with their allowed values
+var APIEnums = []openapi.EnumType{
+	openapi.Enum(models.ContentStatuses),
+	openapi.Enum(models.BuildTypes),
+	openapi.Enum(models.QuizTypes),
+	openapi.Enum(models.MediaPurposes),
+	openapi.Enum(models.MediaOwnerTypes),
+	openapi.Enum(models.UserRoles),
+	openapi.Enum(models.OrganizationStatuses),
+	openapi.Enum(models.AllPermissions),
+}
+
+// BuildAPISpec describes every route main registers. Server start compares it
+// with the router (openapi.Spec.Diff), so a route added without an entry here
+// is reported.
+func BuildAPISpec() *openapi.Spec {
+	return openapi.Build(APIInfo, APIOperations(), APIEnums)
+}
+
+// any JSON value, for responses whose type depends on the request
+var anyJSON = json.RawMessage{}
+
+var (
+	statusQuery = openapi.Param{Name: "status", Description: "only this content status"}
+	programArgs = []openapi.Param{statusQuery}
+	lessonArgs  = []openapi.Param{{Name: "subcourse_id", Description: "only lessons of this subcourse"}, statusQuery}
+	subArgs     = []openapi.Param{{Name: "program_id", Description: "only subcourses of this program"}, statusQuery}
+	verboseArg  = openapi.Param{Name: "verbose", Type: "boolean", Description: "list the individual checks; needs a bearer token with system.health"}
+)
+
+// APIOperations lists every route with the types it reads and writes
+func APIOperations() []openapi.Operation {
+	public := func(method, path, id, tag, summary string, body, response interface{}) openapi.Operation {
+		return openapi.Operation{Method: method, Path: path, ID: id, Tag: tag, Summary: summary, Body: body, Response: response}
+	}
+	signedIn := func(method, path, id, tag, summary string, body, response interface{}) openapi.Operation {
+		op := public(method, path, id, tag, summary, body, response)
+		op.Auth = true
+		return op
+	}
+	admin := func(method, path string, perm models.Permission, id, tag, summary string, body, response interface{}) openapi.Operation {
+		op := signedIn(method, "/api/admin"+path, id, tag, summary, body, response)
+		op.Permission = string(perm)
+		return op
+	}
+	created := func(op openapi.Operation) openapi.Operation {
+		op.Status = http.StatusCreated
+		return op
+	}
+	noContent := func(op openapi.Operation) openapi.Operation {
+		op.Status = http.StatusNoContent
+		return op
+	}
+	versioned := func(op openapi.Operation) openapi.Operation {
+		op.IfMatch = true
+		return op
+	}
+	query := func(op openapi.Operation, params ...openapi.Param) openapi.Operation {
+		op.Query = params
+		return op
+	}
+	cached := func(op openapi.Operation) openapi.Operation {
+		op.Cached = true
+		return op
+	}
+	trash := func(method, path, id, summary string, response interface{}) openapi.Operation {
+		op := admin(method, path, "", id, "trash", summary, nil, response)
+		var perms []string
+		for _, p := range TrashPermissions() {
+			perms = append(perms, string(p))
+		}
+		op.Permission = strings.Join(perms, " or ")
+		return op
+	}
+
+	ops := []openapi.Operation{
+		{Method: http.MethodGet, Path: "/", ID: "Root", Summary: "Plain-text pointer to the API", Response: "", NoClient: true},
+		public(http.MethodGet, "/health", "Health", "system", "Process is up; use /livez and /readyz for probes", nil, HealthStatus{}),
+		query(public(http.MethodGet, "/livez", "Live", "system", "Liveness probe: background workers are running; 503 when failing", nil, health.ProbeReport{}), verboseArg),
+		query(public(http.MethodGet, "/readyz", "Ready", "system", "Readiness probe: database, schema version and storage are usable and the server is not shutting down; 503 when failing", nil, health.ProbeReport{}), verboseArg),
+		public(http.MethodGet, "/api/openapi.json", "GetOpenAPI", "system", "This document", nil, anyJSON),
+		{Method: http.MethodGet, Path: "/metrics", ID: "Metrics", Tag: "system", Summary: "Prometheus metrics; needs METRICS_TOKEN as a bearer token, otherwise only on the admin port", Response: "", NoClient: true},
+
+		// Auth
+		public(http.MethodPost, "/api/auth/login", "Login", "auth", "Sign in with username and password; may answer with a 2FA challenge", LoginRequest{}, LoginResponse{}),
+		query(public(http.MethodGet, "/api/auth/oidc/login", "OIDCLogin", "auth", "Redirect to the identity provider, or return its URL with mode=json", nil, AuthorizationURL{}),
+			openapi.Param{Name: "mode", Description: `"json" returns the URL instead of redirecting`}),
+		func() openapi.Operation {
+			op := query(public(http.MethodGet, "/api/auth/oidc/callback", "OIDCCallback", "auth", "Identity provider callback; redirects to the frontend with the session", nil, nil),
+				openapi.Param{Name: "code"}, openapi.Param{Name: "state"}, openapi.Param{Name: "error"})
+			op.Status = http.StatusFound
+			op.NoClient = true
+			return op
+		}(),
+		signedIn(http.MethodPost, "/api/auth/oidc/link", "OIDCLink", "auth", "Start linking an SSO identity to the signed-in user", nil, AuthorizationURL{}),
+		public(http.MethodPost, "/api/auth/2fa/verify", "VerifyTwoFactor", "auth", "Second login step", TwoFactorVerifyRequest{}, LoginResponse{}),
+		public(http.MethodPost, "/api/auth/2fa/setup", "SetupTwoFactor", "auth", "Start TOTP enrollment", TwoFactorSetupRequest{}, TOTPSetupResponse{}),
+		public(http.MethodPost, "/api/auth/2fa/enable", "EnableTwoFactor", "auth", "Confirm TOTP enrollment; returns the recovery codes once", TwoFactorEnableRequest{}, TwoFactorResponse{}),
+		signedIn(http.MethodPost, "/api/auth/2fa/disable", "DisableTwoFactor", "auth", "Turn TOTP off", TwoFactorDisableRequest{}, TwoFactorResponse{}),
+		signedIn(http.MethodPost, "/api/auth/2fa/recovery-codes", "RegenerateRecoveryCodes", "auth", "Replace all recovery codes", RecoveryCodesRequest{}, RecoveryCodesResponse{}),
+		signedIn(http.MethodGet, "/api/auth/2fa/status", "GetTwoFactorStatus", "auth", "Two-factor status of the signed-in user", nil, TwoFactorStatus{}),
+		signedIn(http.MethodGet, "/api/auth/me", "Me", "auth", "The signed-in user", nil, UserProfile{}),
+		query(signedIn(http.MethodGet, "/api/auth/me/login-history", "GetMyLoginHistory", "auth", "Recent logins of the signed-in user", nil, LoginHistory{}),
+			openapi.Param{Name: "limit", Type: "integer"}),
+		signedIn(http.MethodGet, "/api/auth/me/permissions", "GetMyPermissions", "auth", "Permissions of the signed-in user", nil, EffectivePermissions{}),
+		signedIn(http.MethodGet, "/api/auth/me/identities", "GetMyIdentities", "auth", "Linked SSO identities", nil, []models.UserIdentity{}),
+		noContent(signedIn(http.MethodDelete, "/api/auth/me/identities/:id", "UnlinkIdentity", "auth", "Unlink an SSO identity", nil, nil)),
+
+		// Programs
+		query(admin(http.MethodGet, "/programs", models.PermProgramRead, "ListPrograms", "programs", "Programs the caller can access", nil, []models.Program{}), programArgs...),
+		admin(http.MethodGet, "/programs/:id", models.PermProgramRead, "GetProgram", "programs", "A program with its subcourses", nil, models.Program{}),
+		created(admin(http.MethodPost, "/programs", models.PermProgramCreate, "CreateProgram", "programs", "Create a program", models.Program{}, models.Program{})),
+		admin(http.MethodPut, "/programs/order", models.PermProgramWrite, "ReorderPrograms", "programs", "Set the order of all programs", ReorderInput{}, []models.Program{}),
+		versioned(admin(http.MethodPut, "/programs/:id", models.PermProgramWrite, "UpdateProgram", "programs", "Update a program; empty fields are left unchanged", models.Program{}, models.Program{})),
+		versioned(admin(http.MethodDelete, "/programs/:id", models.PermProgramDelete, "DeleteProgram", "programs", "Move a program and its content to the trash", nil, MessageResponse{})),
+		admin(http.MethodGet, "/programs/:id/delete-impact", models.PermProgramDelete, "GetProgramDeleteImpact", "programs", "What deleting a program takes along", nil, DeleteImpact{}),
+		created(admin(http.MethodPost, "/programs/:id/clone", models.PermProgramCreate, "CloneProgram", "programs", "Deep-copy a program into the caller's organization", CloneInput{}, models.Program{})),
+
+		// Subcourses
+		query(admin(http.MethodGet, "/subcourses", models.PermSubcourseRead, "ListSubcourses", "subcourses", "Subcourses the caller can access", nil, []models.Subcourse{}), subArgs...),
+		admin(http.MethodGet, "/subcourses/:id", models.PermSubcourseRead, "GetSubcourse", "subcourses", "A subcourse with its lessons", nil, models.Subcourse{}),
+		admin(http.MethodGet, "/programs/:programId/subcourses", models.PermSubcourseRead, "ListProgramSubcourses", "subcourses", "Subcourses of a program", nil, []models.Subcourse{}),
+		created(admin(http.MethodPost, "/subcourses", models.PermSubcourseWrite, "CreateSubcourse", "subcourses", "Create a subcourse", models.Subcourse{}, models.Subcourse{})),
+		versioned(admin(http.MethodPut, "/subcourses/:id", models.PermSubcourseWrite, "UpdateSubcourse", "subcourses", "Update a subcourse; empty fields are left unchanged", models.Subcourse{}, models.Subcourse{})),
+		noContent(versioned(admin(http.MethodDelete, "/subcourses/:id", models.PermSubcourseDelete, "DeleteSubcourse", "subcourses", "Move a subcourse and its lessons to the trash", nil, nil))),
+		admin(http.MethodGet, "/subcourses/:id/delete-impact", models.PermSubcourseDelete, "GetSubcourseDeleteImpact", "subcourses", "What deleting a subcourse takes along", nil, DeleteImpact{}),
+		created(admin(http.MethodPost, "/subcourses/:id/clone", models.PermSubcourseWrite, "CloneSubcourse", "subcourses", "Deep-copy a subcourse, optionally into another program", CloneInput{}, models.Subcourse{})),
+		admin(http.MethodPut, "/programs/:id/subcourses/order", models.PermSubcourseWrite, "ReorderSubcourses", "subcourses", "Set the order of a program's subcourses", ReorderInput{}, []models.Subcourse{}),
+		versioned(admin(http.MethodPost, "/subcourses/:id/move", models.PermSubcourseWrite, "MoveSubcourse", "subcourses", "Move a subcourse to another program or position", MoveInput{}, models.Subcourse{})),
+
+		// Lessons
+		query(admin(http.MethodGet, "/lessons", models.PermLessonRead, "ListLessons", "lessons", "Lessons the caller can access", nil, []models.Lesson{}), lessonArgs...),
+		query(admin(http.MethodGet, "/lessons/summary", models.PermLessonRead, "ListLessonSummaries", "lessons", "Summaries of the lessons the caller can access, without components and media", nil, []LessonSummary{}), lessonArgs...),
+		admin(http.MethodGet, "/lessons/:id", models.PermLessonRead, "GetLesson", "lessons", "A lesson with all of its components", nil, models.Lesson{}),
+		admin(http.MethodGet, "/subcourses/:subcourseId/lessons", models.PermLessonRead, "ListSubcourseLessons", "lessons", "Lessons of a subcourse", nil, []models.Lesson{}),
+		created(admin(http.MethodPost, "/lessons", models.PermLessonWrite, "CreateLesson", "lessons", "Create a lesson with all of its components", LessonCreateInput{}, models.Lesson{})),
+		versioned(admin(http.MethodPut, "/lessons/:id", models.PermLessonWrite, "UpdateLesson", "lessons", "Update a lesson; sent components replace the existing ones", models.Lesson{}, models.Lesson{})),
+		versioned(admin(http.MethodPut, "/lessons/:id/status", models.PermLessonPublish, "SetLessonStatus", "lessons", "Change the publication status", LessonStatusInput{}, models.Lesson{})),
+		versioned(admin(http.MethodDelete, "/lessons/:id", models.PermLessonDelete, "DeleteLesson", "lessons", "Move a lesson to the trash", nil, MessageResponse{})),
+		admin(http.MethodGet, "/lessons/:id/delete-impact", models.PermLessonDelete, "GetLessonDeleteImpact", "lessons", "What deleting a lesson takes along", nil, DeleteImpact{}),
+		created(admin(http.MethodPost, "/lessons/:id/clone", models.PermLessonWrite, "CloneLesson", "lessons", "Deep-copy a lesson, optionally into another subcourse", CloneInput{}, models.Lesson{})),
+		admin(http.MethodPut, "/subcourses/:id/lessons/order", models.PermLessonWrite, "ReorderLessons", "lessons", "Set the order of a subcourse's lessons", ReorderInput{}, []models.Lesson{}),
+		versioned(admin(http.MethodPost, "/lessons/:id/move", models.PermLessonWrite, "MoveLesson", "lessons", "Move a lesson to another subcourse or position", MoveInput{}, models.Lesson{})),
+		func() openapi.Operation {
+			op := versioned(admin(http.MethodPatch, "/lessons/:id", models.PermLessonWrite, "PatchLesson", "lessons", "Apply a JSON Patch (RFC 6902) to the lesson document", []jsonpatch.Operation{}, models.Lesson{}))
+			op.BodyType = openapi.ContentJSONPatch
+			return op
+		}(),
+	}
+
+	// Granular lesson component routes, as main registers them
+	for _, comp := range repository.LessonComponents {
+		ops = append(ops, componentOperations(admin, "/lessons/:id/"+comp.Path, comp)...)
+	}
+	ops = append(ops, componentOperations(admin, "/lessons/:id/quizzes/:quizId/options", repository.QuizOptionsComponent)...)
+
+	ops = append(ops,
+		// Lesson templates
+		admin(http.MethodGet, "/programs/:programId/templates", models.PermLessonRead, "ListTemplates", "templates", "Lesson templates of a program", nil, []models.LessonTemplate{}),
+		created(admin(http.MethodPost, "/programs/:programId/templates", models.PermProgramWrite, "CreateTemplate", "templates", "Create a lesson template", TemplateInput{}, models.LessonTemplate{})),
+		admin(http.MethodGet, "/templates/:id", models.PermLessonRead, "GetTemplate", "templates", "A lesson template", nil, models.LessonTemplate{}),
+		admin(http.MethodPut, "/templates/:id", models.PermProgramWrite, "UpdateTemplate", "templates", "Update a lesson template", TemplateInput{}, models.LessonTemplate{}),
+		noContent(admin(http.MethodDelete, "/templates/:id", models.PermProgramWrite, "DeleteTemplate", "templates", "Delete a lesson template", nil, nil)),
+
+		// Trash; each type needs its delete permission
+		query(trash(http.MethodGet, "/trash", "ListTrash", "Trashed programs, subcourses and lessons", TrashList{}),
+			openapi.Param{Name: "type", Description: "program, subcourse or lesson"}),
+		trash(http.MethodPost, "/trash/:type/:id/restore", "RestoreTrashed", "Restore a trashed item with what was trashed along with it; returns the program, subcourse or lesson", anyJSON),
+		noContent(trash(http.MethodDelete, "/trash/:type/:id", "PurgeTrashed", "Delete a trashed item for good", nil)),
+
+		// Teachers
+		admin(http.MethodGet, "/teachers", models.PermTeacherRead, "ListTeachers", "teachers", "Teachers of the organization", nil, []models.User{}),
+		created(admin(http.MethodPost, "/teachers", models.PermTeacherManage, "CreateTeacher", "teachers", "Create a teacher account", CreateTeacherInput{}, models.User{})),
+		admin(http.MethodGet, "/teachers/history", models.PermTeacherRead, "GetTeacherHistory", "teachers", "Lessons by author", nil, []TeacherLessonHistory{}),
+		admin(http.MethodPut, "/teachers/:id/program-assignments", models.PermTeacherManage, "AssignPrograms", "teachers", "Replace a teacher's program assignments", ProgramAssignInput{}, AssignmentsResponse{}),
+		admin(http.MethodPut, "/teachers/:id/subcourse-assignments", models.PermTeacherManage, "AssignSubcourses", "teachers", "Replace a teacher's subcourse assignments", SubcourseAssignInput{}, AssignmentsResponse{}),
+		admin(http.MethodGet, "/teachers/:teacherId/assignments", models.PermTeacherRead, "GetTeacherAssignments", "teachers", "Active assignments of a teacher", nil, AssignmentsResponse{}),
+		admin(http.MethodGet, "/teachers/:teacherId/lesson-history", models.PermTeacherRead, "GetTeacherLessonHistory", "teachers", "Lessons a teacher wrote", nil, []LessonHistoryItem{}),
+
+		// Organizations and program sharing
+		admin(http.MethodGet, "/organization", models.PermOrgRead, "GetCurrentOrganization", "organizations", "The organization the caller works in", nil, models.Organization{}),
+		admin(http.MethodGet, "/organizations", models.PermOrgManage, "ListOrganizations", "organizations", "All organizations", nil, []models.Organization{}),
+		created(admin(http.MethodPost, "/organizations", models.PermOrgManage, "CreateOrganization", "organizations", "Create an organization", OrganizationInput{}, models.Organization{})),
+		admin(http.MethodPut, "/organizations/:id", models.PermOrgManage, "UpdateOrganization", "organizations", "Update an organization", OrganizationInput{}, models.Organization{}),
+		admin(http.MethodGet, "/programs/:id/shares", models.PermProgramWrite, "ListProgramShares", "organizations", "Organizations a program is shared with", nil, []models.ProgramShare{}),
+		created(admin(http.MethodPost, "/programs/:id/shares", models.PermProgramWrite, "ShareProgram", "organizations", "Make a program readable by another organization", ProgramShareInput{}, models.ProgramShare{})),
+		noContent(admin(http.MethodDelete, "/programs/:id/shares/:orgId", models.PermProgramWrite, "UnshareProgram", "organizations", "Stop sharing a program", nil, nil)),
+
+		// Users, roles and permissions
+		admin(http.MethodGet, "/roles", models.PermPermissionRead, "ListRoles", "users", "Roles and their permissions", nil, RolesResponse{}),
+		admin(http.MethodGet, "/users/:id/permissions", models.PermPermissionRead, "GetUserPermissions", "users", "Effective permissions of a user", nil, EffectivePermissions{}),
+		admin(http.MethodPut, "/users/:id/role", models.PermUserManage, "SetUserRole", "users", "Change a user's role", SetRoleInput{}, EffectivePermissions{}),
+		admin(http.MethodPut, "/users/:id/password-login", models.PermUserManage, "SetPasswordLogin", "users", "Force a user onto single sign-on, or allow passwords again", PasswordLoginInput{}, models.User{}),
+		query(admin(http.MethodGet, "/users/:id/login-history", models.PermUserManage, "GetUserLoginHistory", "users", "Recent logins of a user", nil, LoginHistory{}),
+			openapi.Param{Name: "limit", Type: "integer"}),
+		query(admin(http.MethodPost, "/users/:id/unlock", models.PermUserManage, "UnlockUser", "users", "Lift a login lockout", nil, MessageResponse{}),
+			openapi.Param{Name: "ip", Description: "also lift the lockout of this IP"}),
+		admin(http.MethodDelete, "/users/:id/2fa", models.PermUserManage, "ResetTwoFactor", "users", "Reset a user's 2FA; they enroll again at the next login", nil, MessageResponse{}),
+
+		// Media
+		func() openapi.Operation {
+			op := created(admin(http.MethodPost, "/media/upload", models.PermMediaUpload, "UploadMedia", "media", "Upload files as media of a program, subcourse, lesson or component", MediaUploadForm{}, MediaUploadResponse{}))
+			op.BodyType = openapi.ContentMultipart
+			return op
+		}(),
+		admin(http.MethodPut, "/media/order", models.PermLessonWrite, "ReorderMedia", "media", "Set the order of an owner's media", MediaOrderInput{}, []models.Media{}),
+		admin(http.MethodPost, "/seed", models.PermSystemSeed, "Seed", "system", "Load the sample data", nil, MessageResponse{}),
+
+		// Lesson editor presence (WebSocket)
+		func() openapi.Operation {
+			op := signedIn(http.MethodGet, "/api/ws/lessons/:id", "LessonEditorSocket", "lessons", "WebSocket for presence, soft locks and save notifications; the token may be passed as access_token", nil, nil)
+			op.Permission = string(models.PermLessonRead)
+			op.Status = http.StatusSwitchingProtocols
+			op.NoClient = true
+			return op
+		}(),
+
+		// Public read-only routes, served through the response cache
+		cached(query(public(http.MethodGet, "/api/programs", "ListPublicPrograms", "public", "All programs", nil, []models.Program{}), programArgs...)),
+		cached(public(http.MethodGet, "/api/programs/:id", "GetPublicProgram", "public", "A program", nil, models.Program{})),
+		cached(public(http.MethodGet, "/api/programs/:programId/subcourses", "ListPublicProgramSubcourses", "public", "Subcourses of a program", nil, []models.Subcourse{})),
+		cached(query(public(http.MethodGet, "/api/subcourses", "ListPublicSubcourses", "public", "Subcourses", nil, []models.Subcourse{}), subArgs...)),
+		cached(public(http.MethodGet, "/api/subcourses/:id", "GetPublicSubcourse", "public", "A subcourse", nil, models.Subcourse{})),
+		cached(query(public(http.MethodGet, "/api/lessons", "ListPublicLessons", "public", "All lessons", nil, []models.Lesson{}), lessonArgs...)),
+		cached(query(public(http.MethodGet, "/api/lessons/summary", "ListPublicLessonSummaries", "public", "Summaries of all lessons, without components and media", nil, []LessonSummary{}), lessonArgs...)),
+		cached(public(http.MethodGet, "/api/lessons/:id", "GetPublicLesson", "public", "A lesson", nil, models.Lesson{})),
+		cached(public(http.MethodGet, "/api/subcourses/:subcourseId/lessons", "ListPublicSubcourseLessons", "public", "Lessons of a subcourse", nil, []models.Lesson{})),
+	)
+	return ops
+}
+
+// componentOperations mirrors main's registerComponent. Operation IDs are
+// named after the model, e.g. CreateLessonContentBlock.
+func componentOperations(admin func(method, path string, perm models.Permission, id, tag, summary string, body, response interface{}) openapi.Operation, base string, comp *repository.LessonComponent) []openapi.Operation {
+	t := reflect.TypeOf(comp.New()).Elem()
+	one := reflect.New(t).Elem().Interface()
+	list := reflect.MakeSlice(reflect.SliceOf(t), 0, 0).Interface()
+	name, what := t.Name(), strings.ReplaceAll(comp.JSONKey, "_", " ")
+	plural := name + "s"
+	if strings.HasSuffix(name, "z") {
+		plural = name + "zes"
+	}
+	const tag = "lesson components"
+
+	if comp.Single {
+		put := admin(http.MethodPut, base, models.PermLessonWrite, "Set"+name, tag, "Create or replace the lesson's "+what, one, one)
+		del := admin(http.MethodDelete, base, models.PermLessonWrite, "Delete"+name, tag, "Remove the lesson's "+what, nil, nil)
+		del.Status = http.StatusNoContent
+		return []openapi.Operation{
+			admin(http.MethodGet, base, models.PermLessonRead, "Get"+name, tag, "The lesson's "+what, nil, one),
+			put, del,
+		}
+	}
+	create := admin(http.MethodPost, base, models.PermLessonWrite, "Create"+name, tag, "Add to "+what, one, one)
+	create.Status = http.StatusCreated
+	del := admin(http.MethodDelete, base+"/:componentId", models.PermLessonWrite, "Delete"+name, tag, "Remove one of "+what, nil, nil)
+	del.Status = http.StatusNoContent
+	return []openapi.Operation{
+		admin(http.MethodGet, base, models.PermLessonRead, "List"+plural, tag, "The lesson's "+what, nil, list),
+		create,
+		admin(http.MethodPut, base+"/order", models.PermLessonWrite, "Reorder"+plural, tag, "Set the order of "+what, ReorderInput{}, list),
+		admin(http.MethodPatch, base+"/:componentId", models.PermLessonWrite, "Update"+name, tag, "Change the sent fields of one of "+what, one, one),
+		del,
+	}
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"courseai/backend/internal/middleware"
+	"courseai/backend/internal/models"
+	"courseai/backend/internal/service"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type OrganizationHandler struct {
+	organizations *service.OrganizationService
+}
+
+func NewOrganizationHandler(organizations *service.OrganizationService) *OrganizationHandler {
+	return &OrganizationHandler{organizations: organizations}
+}
+
+// GET /api/admin/organizations
+func (h *OrganizationHandler) GetAll(c *fiber.Ctx) error {
+	orgs, err := h.organizations.List(c.UserContext())
+	if err != nil {
+		return serviceError(err, "Failed to fetch organizations")
+	}
+	return c.JSON(orgs)
+}
+
+// GET /api/admin/organization - the organization the caller works in
+func (h *OrganizationHandler) GetCurrent(c *fiber.Ctx) error {
+	tenantID, ok := middleware.GetTenantID(c)
+	if !ok {
+		return fiber.NewError(fiber.StatusNotFound, "No organization selected")
+	}
+	org, err := h.organizations.Get(c.UserContext(), tenantID)
+	if err != nil {
+		return serviceError(err, "Failed to fetch organization")
+	}
+	return c.JSON(org)
+}
+
+type OrganizationInput struct {
+	Name   string                    `json:"name"`
+	Slug   string                    `json:"slug"`
+	Status models.OrganizationStatus `json:"status"`
+}
+
+// POST /api/admin/organizations
+func (h *OrganizationHandler) Create(c *fiber.Ctx) error {
+	var input OrganizationInput
+	if err := c.BodyParser(&input); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid input")
+	}
+	if strings.TrimSpace(input.Name) == "" || strings.TrimSpace(input.Slug) == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "name and slug are required")
+	}
+
+	org, err := h.organizations.Create(c.UserContext(), input.Name, input.Slug)
+	if errors.Is(err, service.ErrSlugInUse) {
+		return fiber.NewError(fiber.StatusConflict, "Organization slug already exists")
+	}
+	if err != nil {
+		return serviceError(err, "Failed to create organization")
+	}
+	return c.Status(fiber.StatusCreated).JSON(org)
+}
+
+// PUT /api/admin/organizations/:id
+func (h *OrganizationHandler) Update(c *fiber.Ctx) error {
+	orgID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid organization id")
+	}
+	var input OrganizationInput
+	if err := c.BodyParser(&input); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid input")
+	}
+	switch input.Status {
+	case "", models.OrgStatusActive, models.OrgStatusSuspended:
+	default:
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid status")
+	}
+
+	org, err := h.organizations.Update(c.UserContext(), orgID, models.Organization{Name: input.Name, Slug: input.Slug, Status: input.Status})
+	if errors.Is(err, service.ErrSlugInUse) {
+		return fiber.NewError(fiber.StatusConflict, "Organization slug already exists")
+	}
+	if err != nil {
+		return serviceError(err, "Failed to update organization")
+	}
+	// a suspension applies to the members' next request
+	middleware.ForgetPrincipals()
+	return c.JSON(org)
+}
+
+// GET /api/admin/programs/:id/shares
+func (h *OrganizationHandler) GetProgramShares(c *fiber.Ctx) error {
+	programID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid program ID")
+	}
+	shares, err := h.organizations.Shares(middleware.TenantContext(c), middleware.Actor(c), programID)
+	if err != nil {
+		return serviceError(err, "Failed to fetch shares")
+	}
+	return c.JSON(shares)
+}
+
+type ProgramShareInput struct {
+	// Organization is the target organization's ID or slug
+	Organization string `json:"organization" openapi:"required"`
+}
+
+// POST /api/admin/programs/:id/shares - make a program readable by another organization
+func (h *OrganizationHandler) ShareProgram(c *fiber.Ctx) error {
+	programID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid program ID")
+	}
+	var input ProgramShareInput
+	if err := c.BodyParser(&input); err != nil || input.Organization == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "organization is required")
+	}
+
+	share, err := h.organizations.Share(middleware.TenantContext(c), middleware.Actor(c), programID, input.Organization)
+	if err != nil {
+		return serviceError(err, "Failed to share program")
+	}
+	return c.Status(fiber.StatusCreated).JSON(share)
+}
+
+// DELETE /api/admin/programs/:id/shares/:orgId
+func (h *OrganizationHandler) UnshareProgram(c *fiber.Ctx) error {
+	programID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid program ID")
+	}
+	orgID, err := uuid.Parse(c.Params("orgId"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid organization id")
+	}
+	if err := h.organizations.Unshare(middleware.TenantContext(c), middleware.Actor(c), programID, orgID); err != nil {
+		return serviceError(err, "Failed to remove share")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
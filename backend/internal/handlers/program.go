@@ -1,116 +1,69 @@
 package handlers
 
 import (
-	"courseai/backend/internal/database"
+	"courseai/backend/internal/cache"
 	"courseai/backend/internal/middleware"
 	"courseai/backend/internal/models"
+	"courseai/backend/internal/service"
+	"courseai/backend/internal/validation"
+	"errors"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 )
 
-type ProgramHandler struct{}
-
-func NewProgramHandler() *ProgramHandler {
-	return &ProgramHandler{}
+type ProgramHandler struct {
+	programs *service.ProgramService
+	authz    *middleware.Authorizer
 }
 
-// GetAllPublic - List all programs for public pages (no access control, shows all programs)
-func (h *ProgramHandler) GetAllPublic(c *fiber.Ctx) error {
-	db := database.GetDB()
-	var programs []models.Program
-
-	query := db.Preload("Media").Order("sort_order ASC, created_at DESC")
-
-	// Optional status filter
-	if status := c.Query("status"); status != "" {
-		query = query.Where("status = ?", status)
-	}
+func NewProgramHandler(programs *service.ProgramService, authz *middleware.Authorizer) *ProgramHandler {
+	return &ProgramHandler{programs: programs, authz: authz}
+}
 
-	if err := query.Find(&programs).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to fetch programs",
-		})
+// catalogActor is the actor for reads of the catalog: teachers see what they
+// are assigned to, everyone else sees all of it
+func catalogActor(c *fiber.Ctx) service.Actor {
+	if middleware.GetUserRole(c) == models.RoleTeacher {
+		return middleware.Actor(c)
 	}
+	return service.System
+}
 
-	// Count subcourses for each program
-	for i := range programs {
-		var count int64
-		db.Model(&models.Subcourse{}).Where("program_id = ?", programs[i].ID).Count(&count)
-		programs[i].SubcourseCount = int(count)
-		// Ensure subcourses is non-nil for JSON consumers expecting an array
-		if programs[i].Subcourses == nil {
-			programs[i].Subcourses = make([]models.Subcourse, 0)
-		}
+// GetAllPublic - List all programs for public pages (no access control, shows all programs)
+func (h *ProgramHandler) GetAllPublic(c *fiber.Ctx) error {
+	programs, err := h.programs.List(middleware.TenantContext(c), service.System, c.Query("status"))
+	if err != nil {
+		return serviceError(err, "Failed to fetch programs")
 	}
-
+	middleware.CacheTags(c, cache.ProgramListTags(programs)...)
 	return c.JSON(programs)
 }
 
 // GetAll - List all programs (with access control)
 func (h *ProgramHandler) GetAll(c *fiber.Ctx) error {
-	db := database.GetDB()
-	var programs []models.Program
-
-	// If teacher, restrict to assigned programs
-	role := middleware.GetUserRole(c)
-	query := db.Preload("Media").Order("sort_order ASC, created_at DESC")
-	if role == models.RoleTeacher {
-		userID := middleware.GetUserID(c)
-		ids, err := middleware.TeacherAssignedProgramIDs(userID)
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to resolve assignments"})
-		}
-		if len(ids) == 0 {
-			return c.JSON([]models.Program{})
-		}
-		query = query.Where("id IN ?", ids)
-	}
-
-	// Optional status filter
-	if status := c.Query("status"); status != "" {
-		query = query.Where("status = ?", status)
-	}
-
-	if err := query.Find(&programs).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to fetch programs",
-		})
-	}
-
-	// Count subcourses for each program
-	for i := range programs {
-		var count int64
-		db.Model(&models.Subcourse{}).Where("program_id = ?", programs[i].ID).Count(&count)
-		programs[i].SubcourseCount = int(count)
-		// Ensure subcourses is non-nil for JSON consumers expecting an array
-		if programs[i].Subcourses == nil {
-			programs[i].Subcourses = make([]models.Subcourse, 0)
-		}
+	programs, err := h.programs.List(middleware.TenantContext(c), catalogActor(c), c.Query("status"))
+	if err != nil {
+		return serviceError(err, "Failed to fetch programs")
 	}
-
 	return c.JSON(programs)
 }
 
 // GetOne - Get single program by ID
 func (h *ProgramHandler) GetOne(c *fiber.Ctx) error {
-	id := c.Params("id")
-	programID, err := uuid.Parse(id)
+	programID, err := uuid.Parse(c.Params("id"))
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Invalid program ID",
 		})
 	}
 
-	db := database.GetDB()
-	// Enforce access
-	if err := middleware.CanAccessProgram(c, programID); err != nil {
-		return err
-	}
-	var program models.Program
-	if err := db.Preload("Media").Preload("Subcourses").First(&program, "id = ?", programID).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Program not found"})
+	program, err := h.programs.Get(middleware.TenantContext(c), middleware.Actor(c), programID)
+	if err != nil {
+		return serviceError(err, "Failed to fetch program")
 	}
+	setVersionETag(c, program.Version)
+	middleware.CacheTags(c, cache.ProgramTag(program.ID))
 	return c.JSON(program)
 }
 
@@ -123,67 +76,36 @@ func (h *ProgramHandler) Create(c *fiber.Ctx) error {
 		})
 	}
 
-	db := database.GetDB()
-
-	// Only admin can create programs
-	if middleware.GetUserRole(c) == models.RoleTeacher {
+	// Only roles with program.create can create programs
+	if !middleware.HasPermission(c, models.PermProgramCreate) {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Only admin can create programs"})
 	}
 
-	// Start transaction
-	tx := db.Begin()
-
-	if err := tx.Create(&program).Error; err != nil {
-		tx.Rollback()
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create program",
-		})
+	v := validation.New()
+	validation.Program(v, &program, false)
+	if err := v.Err(); err != nil {
+		return invalid(c, fiber.StatusBadRequest, err)
 	}
 
-	// Handle media if provided
-	if len(program.Media) > 0 {
-		for i := range program.Media {
-			program.Media[i].OwnerID = program.ID
-			program.Media[i].OwnerType = models.OwnerProgram
-		}
-		if err := tx.Create(&program.Media).Error; err != nil {
-			tx.Rollback()
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to create media",
-			})
-		}
+	created, err := h.programs.Create(middleware.TenantContext(c), &program)
+	if errors.Is(err, service.ErrSlugInUse) {
+		return slugConflict(c, program.Slug)
 	}
-
-	tx.Commit()
-
-	// Reload with media
-	db.Preload("Media").First(&program, "id = ?", program.ID)
-
-	return c.Status(fiber.StatusCreated).JSON(program)
+	if err != nil {
+		return serviceError(err, "Failed to create program")
+	}
+	return c.Status(fiber.StatusCreated).JSON(created)
 }
 
 // Update - Update existing program
 func (h *ProgramHandler) Update(c *fiber.Ctx) error {
-	id := c.Params("id")
-	programID, err := uuid.Parse(id)
+	programID, err := uuid.Parse(c.Params("id"))
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Invalid program ID",
 		})
 	}
 
-	db := database.GetDB()
-	var existing models.Program
-
-	if err := db.First(&existing, "id = ?", programID).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Program not found"})
-	}
-
-	// enforce access
-	if err := middleware.CanAccessProgram(c, programID); err != nil {
-		return err
-	}
-
 	var updates models.Program
 	if err := c.BodyParser(&updates); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -191,81 +113,50 @@ func (h *ProgramHandler) Update(c *fiber.Ctx) error {
 		})
 	}
 
-	// Start transaction
-	tx := db.Begin()
-
-	// Update program fields
-	updates.ID = programID
-	if err := tx.Model(&existing).Updates(updates).Error; err != nil {
-		tx.Rollback()
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update program",
-		})
+	v := validation.New()
+	validation.Program(v, &updates, true)
+	if err := v.Err(); err != nil {
+		return invalid(c, fiber.StatusBadRequest, err)
 	}
 
-	// Handle media updates if provided
-	if len(updates.Media) > 0 {
-		// Delete existing media
-		tx.Where("owner_type = ? AND owner_id = ?", models.OwnerProgram, programID).Delete(&models.Media{})
-
-		// Create new media
-		for i := range updates.Media {
-			updates.Media[i].OwnerID = programID
-			updates.Media[i].OwnerType = models.OwnerProgram
-		}
-		if err := tx.Create(&updates.Media).Error; err != nil {
-			tx.Rollback()
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to update media",
-			})
-		}
+	program, err := h.programs.Update(middleware.TenantContext(c), middleware.Actor(c), programID, &updates, ifMatchPrecondition(c))
+	if errors.Is(err, service.ErrVersionConflict) {
+		return h.conflict(c, programID)
 	}
-
-	tx.Commit()
-
-	// Reload with media
-	db.Preload("Media").First(&existing, "id = ?", programID)
-
-	return c.JSON(existing)
+	if errors.Is(err, service.ErrSlugInUse) {
+		return slugConflict(c, updates.Slug)
+	}
+	if err != nil {
+		return serviceError(err, "Failed to update program")
+	}
+	setVersionETag(c, program.Version)
+	return c.JSON(program)
 }
 
 // Delete - Delete program
 func (h *ProgramHandler) Delete(c *fiber.Ctx) error {
-	id := c.Params("id")
-	programID, err := uuid.Parse(id)
+	programID, err := uuid.Parse(c.Params("id"))
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Invalid program ID",
 		})
 	}
 
-	db := database.GetDB()
-
-	// enforce access
-	if err := middleware.CanAccessProgram(c, programID); err != nil {
-		return err
+	err = h.programs.Delete(middleware.TenantContext(c), middleware.Actor(c), programID, ifMatchPrecondition(c))
+	if errors.Is(err, service.ErrVersionConflict) {
+		return h.conflict(c, programID)
 	}
-
-	// Check if program has subcourses
-	var count int64
-	db.Model(&models.Subcourse{}).Where("program_id = ?", programID).Count(&count)
-	if count > 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Cannot delete program with existing subcourses",
-		})
+	if err != nil {
+		return serviceError(err, "Failed to delete program")
 	}
+	return c.JSON(MessageResponse{Message: "Program moved to trash"})
+}
 
-	// Delete media first
-	db.Where("owner_type = ? AND owner_id = ?", models.OwnerProgram, programID).Delete(&models.Media{})
-
-	// Delete program
-	if err := db.Delete(&models.Program{}, "id = ?", programID).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to delete program",
-		})
+// conflict answers 412 with the program as GetOne returns it
+func (h *ProgramHandler) conflict(c *fiber.Ctx, programID uuid.UUID) error {
+	current, err := h.programs.Get(middleware.TenantContext(c), service.System, programID)
+	if err != nil {
+		return serviceError(err, "Failed to load program")
 	}
-
-	return c.JSON(fiber.Map{
-		"message": "Program deleted successfully",
-	})
+	return versionConflict(c, current, current.Version)
 }
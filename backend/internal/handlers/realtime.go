@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"courseai/backend/internal/middleware"
+	"courseai/backend/internal/models"
+	"courseai/backend/internal/realtime"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	wsWriteWait  = 10 * time.Second
+	wsPongWait   = 60 * time.Second
+	wsPingPeriod = 25 * time.Second
+	wsMaxMessage = 4096
+)
+
+type RealtimeHandler struct {
+	Hub   *realtime.Hub
+	authz *middleware.Authorizer
+}
+
+func NewRealtimeHandler(hub *realtime.Hub, authz *middleware.Authorizer) *RealtimeHandler {
+	return &RealtimeHandler{Hub: hub, authz: authz}
+}
+
+// Upgrade checks access to the lesson before switching to WebSocket
+func (h *RealtimeHandler) Upgrade(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return fiber.ErrUpgradeRequired
+	}
+	lessonID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid lesson ID")
+	}
+	if err := h.authz.CanAccessLesson(c, lessonID); err != nil {
+		return err
+	}
+	var count int64
+	middleware.TenantDB(c).Model(&models.Lesson{}).Where("id = ?", lessonID).Count(&count)
+	if count == 0 {
+		return fiber.NewError(fiber.StatusNotFound, "Lesson not found")
+	}
+	c.Locals("lesson_id", lessonID)
+	return c.Next()
+}
+
+// GET /api/ws/lessons/:id (WebSocket) - join the lesson's editing room.
+// Clients send {"type":"presence","section":...}, {"type":"lock","component":...},
+// {"type":"unlock","component":...} or {"type":"ping"}; locks expire unless renewed
+// by sending lock again. Components are named "<path>/<id>", e.g. "content-blocks/<id>".
+func (h *RealtimeHandler) Serve(conn *websocket.Conn) {
+	lessonID, _ := conn.Locals("lesson_id").(uuid.UUID)
+	userID, _ := conn.Locals("user_id").(uuid.UUID)
+	username, _ := conn.Locals("username").(string)
+
+	client := h.Hub.Join(lessonID, userID, username)
+	defer h.Hub.Leave(client)
+
+	writerDone := make(chan struct{})
+	go func() {
+		defer close(writerDone)
+		ping := time.NewTicker(wsPingPeriod)
+		defer ping.Stop()
+		for {
+			select {
+			case msg, ok := <-client.Send():
+				conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+				if !ok {
+					conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+					conn.SetReadDeadline(time.Now())
+					return
+				}
+				if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+					// unblock the reader so the handler returns
+					conn.SetReadDeadline(time.Now())
+					return
+				}
+			case <-ping.C:
+				conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+				if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+					conn.SetReadDeadline(time.Now())
+					return
+				}
+			}
+		}
+	}()
+
+	conn.SetReadLimit(wsMaxMessage)
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			break
+		}
+		conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		h.Hub.Handle(client, msg)
+	}
+
+	h.Hub.Leave(client)
+	<-writerDone
+}
+
+// announceLessonSaved pushes the lesson's new version to the editors in its room
+func announceLessonSaved(c *fiber.Ctx, lessonID uuid.UUID, component string) {
+	var lesson models.Lesson
+	if err := middleware.TenantDB(c).Select("id", "version").First(&lesson, "id = ?", lessonID).Error; err != nil {
+		return
+	}
+	realtime.NotifySaved(lessonID, middleware.GetUserID(c), middleware.GetUsername(c), component, lesson.Version)
+}
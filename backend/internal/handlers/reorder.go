@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"courseai/backend/internal/middleware"
+	"courseai/backend/internal/models"
+	"courseai/backend/internal/service"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Bulk reordering and moving within the program > subcourse > lesson tree.
+// Every write numbers the live children of a parent 0..n-1, so sort orders
+// stay gap-free; trashed children keep their old number until restored.
+
+// MoveInput - body of the move endpoints. Position is the 0-based index among
+// the target's children; it defaults to the end.
+type MoveInput struct {
+	ProgramID   uuid.UUID `json:"program_id,omitempty"`   // subcourse move target
+	SubcourseID uuid.UUID `json:"subcourse_id,omitempty"` // lesson move target
+	Position    *int      `json:"position,omitempty"`
+}
+
+// MediaOrderInput - body of PUT /api/admin/media/order
+type MediaOrderInput struct {
+	OwnerType models.MediaOwnerType `json:"owner_type" openapi:"required"`
+	OwnerID   uuid.UUID             `json:"owner_id" openapi:"required"`
+	IDs       []uuid.UUID           `json:"ids" openapi:"required"`
+}
+
+func parseOrderInput(c *fiber.Ctx) ([]uuid.UUID, error) {
+	var input ReorderInput
+	if err := c.BodyParser(&input); err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	return input.IDs, nil
+}
+
+// PUT /api/admin/programs/order - body {"ids": [...]} lists every program of the organization in the new order
+func (h *ProgramHandler) Reorder(c *fiber.Ctx) error {
+	ids, err := parseOrderInput(c)
+	if err != nil {
+		return err
+	}
+	programs, err := h.programs.Reorder(middleware.TenantContext(c), middleware.Actor(c), ids)
+	if err != nil {
+		return serviceError(err, "Failed to reorder programs")
+	}
+	return c.JSON(programs)
+}
+
+// PUT /api/admin/programs/:id/subcourses/order - body {"ids": [...]} lists every subcourse of the program in the new order
+func (h *SubcourseHandler) Reorder(c *fiber.Ctx) error {
+	programID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid program ID")
+	}
+	ids, err := parseOrderInput(c)
+	if err != nil {
+		return err
+	}
+	subcourses, err := h.subcourses.Reorder(middleware.TenantContext(c), middleware.Actor(c), programID, ids)
+	if err != nil {
+		return serviceError(err, "Failed to reorder subcourses")
+	}
+	return c.JSON(subcourses)
+}
+
+// PUT /api/admin/subcourses/:id/lessons/order - body {"ids": [...]} lists every lesson of the subcourse in the new order
+func (h *LessonHandler) Reorder(c *fiber.Ctx) error {
+	subcourseID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid subcourse ID")
+	}
+	ids, err := parseOrderInput(c)
+	if err != nil {
+		return err
+	}
+	lessons, err := h.lessons.Reorder(middleware.TenantContext(c), middleware.Actor(c), subcourseID, ids)
+	if err != nil {
+		return serviceError(err, "Failed to reorder lessons")
+	}
+	return c.JSON(lessons)
+}
+
+// POST /api/admin/subcourses/:id/move - body {"program_id": ..., "position": n}
+// moves the subcourse (with its lessons) to another program, or to another
+// position within its program when program_id is omitted
+func (h *SubcourseHandler) Move(c *fiber.Ctx) error {
+	subcourseID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid subcourse ID")
+	}
+	var input MoveInput
+	if err := c.BodyParser(&input); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	subcourse, err := h.subcourses.Move(middleware.TenantContext(c), middleware.Actor(c), subcourseID, input.ProgramID, input.Position, ifMatchPrecondition(c))
+	if errors.Is(err, service.ErrVersionConflict) {
+		return h.conflict(c, subcourseID)
+	}
+	if err != nil {
+		return serviceError(err, "Failed to move subcourse")
+	}
+	setVersionETag(c, subcourse.Version)
+	return c.JSON(subcourse)
+}
+
+// POST /api/admin/lessons/:id/move - body {"subcourse_id": ..., "position": n}
+// moves the lesson to another subcourse, or to another position within its
+// subcourse when subcourse_id is omitted
+func (h *LessonHandler) Move(c *fiber.Ctx) error {
+	lessonID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid lesson ID")
+	}
+	var input MoveInput
+	if err := c.BodyParser(&input); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	lesson, err := h.lessons.Move(middleware.TenantContext(c), middleware.Actor(c), lessonID, input.SubcourseID, input.Position, ifMatchPrecondition(c))
+	if errors.Is(err, service.ErrVersionConflict) {
+		return h.conflict(c, lessonID)
+	}
+	if err != nil {
+		return serviceError(err, "Failed to move lesson")
+	}
+	setVersionETag(c, lesson.Version)
+	return c.JSON(lesson)
+}
+
+// PUT /api/admin/media/order - lists every media item of one owner in the new order
+func (h *MediaHandler) Reorder(c *fiber.Ctx) error {
+	var input MediaOrderInput
+	if err := c.BodyParser(&input); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	lessonID, media, err := h.media.Reorder(middleware.TenantContext(c), middleware.Actor(c), input.OwnerType, input.OwnerID, input.IDs)
+	if err != nil {
+		return serviceError(err, "Failed to reorder media")
+	}
+	announceLessonSaved(c, lessonID, "")
+	return c.JSON(media)
+}
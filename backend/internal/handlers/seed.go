@@ -4,20 +4,19 @@ import (
 	"courseai/backend/internal/database"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
-type SeedHandler struct{}
+type SeedHandler struct {
+	db *gorm.DB
+}
 
-func NewSeedHandler() *SeedHandler { return &SeedHandler{} }
+func NewSeedHandler(db *gorm.DB) *SeedHandler { return &SeedHandler{db: db} }
 
 // Run triggers the database seeding routine. Protected route (admin only).
 func (h *SeedHandler) Run(c *fiber.Ctx) error {
-	db := database.GetDB()
-	if db == nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "database not initialized"})
-	}
-	if err := database.SeedData(db); err != nil {
+	if err := database.SeedData(h.db); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(fiber.Map{"message": "seeding triggered"})
+	return c.JSON(MessageResponse{Message: "seeding triggered"})
 }
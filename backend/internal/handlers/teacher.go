@@ -1,8 +1,9 @@
 package handlers
 
 import (
-	"courseai/backend/internal/database"
+	"courseai/backend/internal/middleware"
 	"courseai/backend/internal/models"
+	"courseai/backend/internal/service"
 	"encoding/json"
 	"log"
 	"time"
@@ -12,17 +13,19 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-type TeacherHandler struct{}
+type TeacherHandler struct {
+	assignments *service.AssignmentService
+}
 
-func NewTeacherHandler() *TeacherHandler {
-	return &TeacherHandler{}
+func NewTeacherHandler(assignments *service.AssignmentService) *TeacherHandler {
+	return &TeacherHandler{assignments: assignments}
 }
 
 // GET /api/admin/teachers
 func (h *TeacherHandler) GetAll(c *fiber.Ctx) error {
 	var teachers []models.User
 
-	if err := database.GetDB().
+	if err := middleware.TenantDB(c).
 		Where("role = ?", models.RoleTeacher).
 		Find(&teachers).Error; err != nil {
 		return err
@@ -36,6 +39,8 @@ type CreateTeacherInput struct {
 	Username string `json:"username"`
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	// OrganizationID is only used by super-admins working outside an organization
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty"`
 }
 
 func (h *TeacherHandler) Create(c *fiber.Ctx) error {
@@ -49,16 +54,26 @@ func (h *TeacherHandler) Create(c *fiber.Ctx) error {
 		return err
 	}
 
+	// Teachers belong to the caller's organization
+	orgID := input.OrganizationID
+	if tenantID, ok := middleware.GetTenantID(c); ok {
+		orgID = &tenantID
+	}
+	if orgID == nil {
+		return fiber.NewError(fiber.StatusBadRequest, "organization_id is required")
+	}
+
 	teacher := models.User{
-		ID:           uuid.New(),
-		Username:     input.Username,
-		Email:        input.Email,
-		PasswordHash: string(hash),
-		Role:         models.RoleTeacher,
-		Status:       models.StatusActive,
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		Username:       input.Username,
+		Email:          input.Email,
+		PasswordHash:   string(hash),
+		Role:           models.RoleTeacher,
+		Status:         models.StatusActive,
 	}
 
-	if err := database.GetDB().Create(&teacher).Error; err != nil {
+	if err := middleware.TenantDB(c).Create(&teacher).Error; err != nil {
 		return err
 	}
 
@@ -66,6 +81,10 @@ func (h *TeacherHandler) Create(c *fiber.Ctx) error {
 }
 
 // PUT /api/admin/teachers/:id/program-assignments
+type AssignmentsResponse struct {
+	Assignments []models.TeacherAssignment `json:"assignments"`
+}
+
 type ProgramAssignInput struct {
 	ProgramIDs []string   `json:"program_ids"`
 	StartAt    *time.Time `json:"start_at,omitempty"`
@@ -73,9 +92,8 @@ type ProgramAssignInput struct {
 }
 
 func (h *TeacherHandler) AssignPrograms(c *fiber.Ctx) error {
-	// Log raw body for debugging and parse flexibly to accept common datetime formats
+	// Parse flexibly to accept common datetime formats
 	body := c.Body()
-	log.Printf("AssignPrograms raw body: %s", string(body))
 
 	var raw struct {
 		ProgramIDs []string `json:"program_ids"`
@@ -83,7 +101,6 @@ func (h *TeacherHandler) AssignPrograms(c *fiber.Ctx) error {
 		EndAt      *string  `json:"end_at,omitempty"`
 	}
 	if err := json.Unmarshal(body, &raw); err != nil {
-		log.Printf("AssignPrograms unmarshal error: %v", err)
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid input: "+err.Error())
 	}
 
@@ -93,84 +110,15 @@ func (h *TeacherHandler) AssignPrograms(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid teacher id")
 	}
 
-	db := database.GetDB()
-
-	// Ensure teacher exists and is a teacher
-	var teacher models.User
-	if err := db.Where("id = ? AND role = ?", teacherID, models.RoleTeacher).First(&teacher).Error; err != nil {
-		return fiber.NewError(fiber.StatusNotFound, "Teacher not found")
-	}
-
-	// Delete existing program-scope assignments for this teacher
-	if err := db.Where("teacher_id = ? AND scope_level = ?", teacherID, models.ScopeProgram).Delete(&models.TeacherAssignment{}).Error; err != nil {
+	input, err := parseAssignInput(raw.ProgramIDs, "program", raw.StartAt, raw.EndAt)
+	if err != nil {
 		return err
 	}
-
-	// Create new assignments (parse string IDs into UUIDs)
-	var created []models.TeacherAssignment
-	for _, pidStr := range raw.ProgramIDs {
-		pid, err := uuid.Parse(pidStr)
-		if err != nil {
-			return fiber.NewError(fiber.StatusBadRequest, "Invalid program id: "+pidStr)
-		}
-		// parse start/end times from strings (if provided), otherwise default to now
-		var start *time.Time
-		if raw.StartAt != nil {
-			// try common layouts
-			layouts := []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02 15:04"}
-			var parsed time.Time
-			var perr error
-			for _, l := range layouts {
-				parsed, perr = time.Parse(l, *raw.StartAt)
-				if perr == nil {
-					break
-				}
-			}
-			if perr != nil {
-				log.Printf("AssignPrograms start_at parse error: %v", perr)
-				return fiber.NewError(fiber.StatusBadRequest, "Invalid start_at format")
-			}
-			start = &parsed
-		} else {
-			now := time.Now()
-			start = &now
-		}
-		var end *time.Time
-		if raw.EndAt != nil {
-			layouts := []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02 15:04"}
-			var parsed time.Time
-			var perr error
-			for _, l := range layouts {
-				parsed, perr = time.Parse(l, *raw.EndAt)
-				if perr == nil {
-					break
-				}
-			}
-			if perr != nil {
-				log.Printf("AssignPrograms end_at parse error: %v", perr)
-				return fiber.NewError(fiber.StatusBadRequest, "Invalid end_at format")
-			}
-			end = &parsed
-		} else {
-			end = nil
-		}
-		ta := models.TeacherAssignment{
-			ID:          uuid.New(),
-			TeacherID:   teacherID,
-			ProgramID:   &pid,
-			SubcourseID: nil,
-			ScopeLevel:  models.ScopeProgram,
-			Status:      models.AssignmentStatusActive,
-			StartAt:     start,
-			EndAt:       end,
-		}
-		if err := db.Create(&ta).Error; err != nil {
-			return err
-		}
-		created = append(created, ta)
+	created, err := h.assignments.AssignPrograms(middleware.TenantContext(c), teacherID, input)
+	if err != nil {
+		return serviceError(err, "Failed to assign programs")
 	}
-
-	return c.JSON(fiber.Map{"assignments": created})
+	return c.JSON(AssignmentsResponse{Assignments: created})
 }
 
 // PUT /api/admin/teachers/:id/subcourse-assignments
@@ -181,9 +129,8 @@ type SubcourseAssignInput struct {
 }
 
 func (h *TeacherHandler) AssignSubcourses(c *fiber.Ctx) error {
-	// Log raw body for debugging and parse flexibly to accept common datetime formats
+	// Parse flexibly to accept common datetime formats
 	body := c.Body()
-	log.Printf("AssignSubcourses raw body: %s", string(body))
 
 	var raw struct {
 		SubcourseIDs []string `json:"subcourse_ids"`
@@ -191,7 +138,6 @@ func (h *TeacherHandler) AssignSubcourses(c *fiber.Ctx) error {
 		EndAt        *string  `json:"end_at,omitempty"`
 	}
 	if err := json.Unmarshal(body, &raw); err != nil {
-		log.Printf("AssignSubcourses unmarshal error: %v", err)
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid input: "+err.Error())
 	}
 
@@ -201,98 +147,68 @@ func (h *TeacherHandler) AssignSubcourses(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid teacher id")
 	}
 
-	db := database.GetDB()
-
-	// Ensure teacher exists and is a teacher
-	var teacher models.User
-	if err := db.Where("id = ? AND role = ?", teacherID, models.RoleTeacher).First(&teacher).Error; err != nil {
-		return fiber.NewError(fiber.StatusNotFound, "Teacher not found")
-	}
-
-	// Delete existing subcourse-scope assignments for this teacher
-	if err := db.Where("teacher_id = ? AND scope_level = ?", teacherID, models.ScopeSubcourse).Delete(&models.TeacherAssignment{}).Error; err != nil {
+	input, err := parseAssignInput(raw.SubcourseIDs, "subcourse", raw.StartAt, raw.EndAt)
+	if err != nil {
 		return err
 	}
+	created, err := h.assignments.AssignSubcourses(middleware.TenantContext(c), teacherID, input)
+	if err != nil {
+		return serviceError(err, "Failed to assign subcourses")
+	}
+	return c.JSON(AssignmentsResponse{Assignments: created})
+}
 
-	// Create new assignments (parse string IDs into UUIDs)
-	var created []models.TeacherAssignment
-	for _, sidStr := range raw.SubcourseIDs {
-		sid, err := uuid.Parse(sidStr)
+// assignTimeLayouts are the datetime formats accepted for start_at and end_at
+var assignTimeLayouts = []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02 15:04"}
+
+// parseAssignInput parses the ids and the optional start and end of an
+// assignment request; what names the kind of id in errors
+func parseAssignInput(ids []string, what string, startAt, endAt *string) (service.AssignInput, error) {
+	var input service.AssignInput
+	for _, idStr := range ids {
+		id, err := uuid.Parse(idStr)
 		if err != nil {
-			return fiber.NewError(fiber.StatusBadRequest, "Invalid subcourse id: "+sidStr)
+			return input, fiber.NewError(fiber.StatusBadRequest, "Invalid "+what+" id: "+idStr)
 		}
-		var start *time.Time
-		if raw.StartAt != nil {
-			layouts := []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02 15:04"}
-			var parsed time.Time
-			var perr error
-			for _, l := range layouts {
-				parsed, perr = time.Parse(l, *raw.StartAt)
-				if perr == nil {
-					break
-				}
-			}
-			if perr != nil {
-				log.Printf("AssignSubcourses start_at parse error: %v", perr)
-				return fiber.NewError(fiber.StatusBadRequest, "Invalid start_at format")
-			}
-			start = &parsed
-		} else {
-			now := time.Now()
-			start = &now
+		input.IDs = append(input.IDs, id)
+	}
+	parse := func(field string, value *string) (*time.Time, error) {
+		if value == nil {
+			return nil, nil
 		}
-		var end *time.Time
-		if raw.EndAt != nil {
-			layouts := []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02 15:04"}
-			var parsed time.Time
-			var perr error
-			for _, l := range layouts {
-				parsed, perr = time.Parse(l, *raw.EndAt)
-				if perr == nil {
-					break
-				}
-			}
-			if perr != nil {
-				log.Printf("AssignSubcourses end_at parse error: %v", perr)
-				return fiber.NewError(fiber.StatusBadRequest, "Invalid end_at format")
+		var parsed time.Time
+		var err error
+		for _, layout := range assignTimeLayouts {
+			if parsed, err = time.Parse(layout, *value); err == nil {
+				return &parsed, nil
 			}
-			end = &parsed
-		} else {
-			end = nil
-		}
-		ta := models.TeacherAssignment{
-			ID:          uuid.New(),
-			TeacherID:   teacherID,
-			ProgramID:   nil,
-			SubcourseID: &sid,
-			ScopeLevel:  models.ScopeSubcourse,
-			Status:      models.AssignmentStatusActive,
-			StartAt:     start,
-			EndAt:       end,
 		}
-		if err := db.Create(&ta).Error; err != nil {
-			return err
-		}
-		created = append(created, ta)
+		log.Printf("Assignment %s parse error: %v", field, err)
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid "+field+" format")
+	}
+	var err error
+	if input.StartAt, err = parse("start_at", startAt); err != nil {
+		return input, err
 	}
+	input.EndAt, err = parse("end_at", endAt)
+	return input, err
+}
 
-	return c.JSON(fiber.Map{"assignments": created})
+// TeacherLessonHistory is a lesson with the teacher who wrote it
+type TeacherLessonHistory struct {
+	TeacherID   uuid.UUID `json:"teacher_id"`
+	TeacherName string    `json:"teacher_name"`
+	LessonID    uuid.UUID `json:"lesson_id"`
+	LessonTitle string    `json:"lesson_title"`
+	LessonSlug  string    `json:"lesson_slug"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Status      string    `json:"status"`
 }
 
 // GET /api/admin/teachers/history
 func (h *TeacherHandler) GetTeacherHistory(c *fiber.Ctx) error {
-	db := database.GetDB()
-
-	type TeacherLessonHistory struct {
-		TeacherID   uuid.UUID `json:"teacher_id"`
-		TeacherName string    `json:"teacher_name"`
-		LessonID    uuid.UUID `json:"lesson_id"`
-		LessonTitle string    `json:"lesson_title"`
-		LessonSlug  string    `json:"lesson_slug"`
-		CreatedAt   time.Time `json:"created_at"`
-		UpdatedAt   time.Time `json:"updated_at"`
-		Status      string    `json:"status"`
-	}
+	db := middleware.TenantDB(c)
 
 	var history []TeacherLessonHistory
 
@@ -309,11 +225,17 @@ func (h *TeacherHandler) GetTeacherHistory(c *fiber.Ctx) error {
 			l.status
 		FROM lessons l
 		LEFT JOIN users u ON l.author_id = u.id
-		WHERE l.author_id IS NOT NULL
-		ORDER BY l.updated_at DESC
+		WHERE l.author_id IS NOT NULL AND l.deleted_at IS NULL
 	`
+	// raw SQL bypasses the tenant callbacks, so filter explicitly
+	var args []interface{}
+	if tenantID, ok := middleware.GetTenantID(c); ok {
+		query += " AND l.organization_id = ?"
+		args = append(args, tenantID)
+	}
+	query += " ORDER BY l.updated_at DESC"
 
-	if err := db.Raw(query).Scan(&history).Error; err != nil {
+	if err := db.Raw(query, args...).Scan(&history).Error; err != nil {
 		log.Printf("GetTeacherHistory error: %v", err)
 		return fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch teacher history")
 	}
@@ -326,6 +248,17 @@ func (h *TeacherHandler) GetTeacherHistory(c *fiber.Ctx) error {
 	return c.JSON(history)
 }
 
+// LessonHistoryItem is a lesson a teacher wrote
+type LessonHistoryItem struct {
+	ID          uuid.UUID `json:"id"`
+	Title       string    `json:"title"`
+	Slug        string    `json:"slug"`
+	SubcourseID uuid.UUID `json:"subcourse_id"`
+	Status      string    `json:"status"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
 // GET /api/admin/teachers/:teacherId/lesson-history
 func (h *TeacherHandler) GetTeacherLessonHistory(c *fiber.Ctx) error {
 	teacherIDStr := c.Params("teacherId")
@@ -334,20 +267,10 @@ func (h *TeacherHandler) GetTeacherLessonHistory(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid teacher id")
 	}
 
-	db := database.GetDB()
-
-	type LessonHistoryItem struct {
-		ID          uuid.UUID `json:"id"`
-		Title       string    `json:"title"`
-		Slug        string    `json:"slug"`
-		SubcourseID uuid.UUID `json:"subcourse_id"`
-		Status      string    `json:"status"`
-		CreatedAt   time.Time `json:"created_at"`
-		UpdatedAt   time.Time `json:"updated_at"`
-	}
+	db := middleware.TenantDB(c)
 
 	var lessons []LessonHistoryItem
-	if err := db.
+	if err := db.Model(&models.Lesson{}).
 		Where("author_id = ?", teacherID).
 		Order("updated_at DESC").
 		Find(&lessons).Error; err != nil {
@@ -365,11 +288,9 @@ func (h *TeacherHandler) GetAssignments(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid teacher id")
 	}
 
-	db := database.GetDB()
-	var assignments []models.TeacherAssignment
-	if err := db.Where("teacher_id = ? AND status = ?", teacherID, models.AssignmentStatusActive).Find(&assignments).Error; err != nil {
+	assignments, err := h.assignments.Active(middleware.TenantContext(c), teacherID)
+	if err != nil {
 		return err
 	}
-
-	return c.JSON(fiber.Map{"assignments": assignments})
+	return c.JSON(AssignmentsResponse{Assignments: assignments})
 }
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"courseai/backend/internal/middleware"
+	"courseai/backend/internal/service"
+	"encoding/json"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type TemplateHandler struct {
+	templates *service.TemplateService
+}
+
+func NewTemplateHandler(templates *service.TemplateService) *TemplateHandler {
+	return &TemplateHandler{templates: templates}
+}
+
+type TemplateInput struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Blueprint   json.RawMessage `json:"blueprint"`
+}
+
+// GET /api/admin/programs/:programId/templates
+func (h *TemplateHandler) GetByProgram(c *fiber.Ctx) error {
+	programID, err := uuid.Parse(c.Params("programId"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid program ID")
+	}
+	templates, err := h.templates.ListByProgram(middleware.TenantContext(c), middleware.Actor(c), programID)
+	if err != nil {
+		return serviceError(err, "Failed to fetch templates")
+	}
+	return c.JSON(templates)
+}
+
+// GET /api/admin/templates/:id
+func (h *TemplateHandler) GetOne(c *fiber.Ctx) error {
+	templateID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid template ID")
+	}
+	template, err := h.templates.Get(middleware.TenantContext(c), middleware.Actor(c), templateID)
+	if err != nil {
+		return serviceError(err, "Failed to fetch template")
+	}
+	return c.JSON(template)
+}
+
+// POST /api/admin/programs/:programId/templates
+func (h *TemplateHandler) Create(c *fiber.Ctx) error {
+	programID, err := uuid.Parse(c.Params("programId"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid program ID")
+	}
+	var input TemplateInput
+	if err := c.BodyParser(&input); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	template, err := h.templates.Create(middleware.TenantContext(c), middleware.Actor(c), programID, service.TemplateFields(input))
+	if err != nil {
+		return serviceError(err, "Failed to create template")
+	}
+	return c.Status(fiber.StatusCreated).JSON(template)
+}
+
+// PUT /api/admin/templates/:id
+func (h *TemplateHandler) Update(c *fiber.Ctx) error {
+	templateID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid template ID")
+	}
+	var input TemplateInput
+	if err := c.BodyParser(&input); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	template, err := h.templates.Update(middleware.TenantContext(c), middleware.Actor(c), templateID, service.TemplateFields(input))
+	if err != nil {
+		return serviceError(err, "Failed to update template")
+	}
+	return c.JSON(template)
+}
+
+// DELETE /api/admin/templates/:id
+func (h *TemplateHandler) Delete(c *fiber.Ctx) error {
+	templateID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid template ID")
+	}
+	if err := h.templates.Delete(middleware.TenantContext(c), middleware.Actor(c), templateID); err != nil {
+		return serviceError(err, "Failed to delete template")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
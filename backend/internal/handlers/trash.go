@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"courseai/backend/internal/middleware"
+	"courseai/backend/internal/models"
+	"courseai/backend/internal/repository"
+	"courseai/backend/internal/service"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Trash item types, as used in the :type route parameter
+const (
+	TrashProgram   = repository.TrashProgram
+	TrashSubcourse = repository.TrashSubcourse
+	TrashLesson    = repository.TrashLesson
+)
+
+// trashPermissions maps each trash item type to the permission that deletes,
+// restores and purges it
+var trashPermissions = map[repository.TrashKind]models.Permission{
+	TrashProgram:   models.PermProgramDelete,
+	TrashSubcourse: models.PermSubcourseDelete,
+	TrashLesson:    models.PermLessonDelete,
+}
+
+// TrashPermissions are the permissions of the trash item types, one of which
+// the trash routes require
+func TrashPermissions() []models.Permission {
+	return []models.Permission{models.PermProgramDelete, models.PermSubcourseDelete, models.PermLessonDelete}
+}
+
+type TrashHandler struct {
+	trash *service.TrashService
+}
+
+func NewTrashHandler(trash *service.TrashService) *TrashHandler {
+	return &TrashHandler{trash: trash}
+}
+
+// TrashItem is one soft-deleted program, subcourse or lesson
+type TrashItem struct {
+	Type          string     `json:"type"`
+	ID            uuid.UUID  `json:"id"`
+	Title         string     `json:"title"`
+	Slug          string     `json:"slug"`
+	ParentID      *uuid.UUID `json:"parent_id,omitempty"`
+	ParentTrashed bool       `json:"parent_trashed"` // the parent must be restored first
+	DeletedAt     time.Time  `json:"deleted_at"`
+	DeletedBy     *uuid.UUID `json:"deleted_by,omitempty"`
+	DeletedByName string     `json:"deleted_by_name,omitempty"`
+	PurgeAt       time.Time  `json:"purge_at"`
+}
+
+// TrashList is the trash and how long items stay restorable
+type TrashList struct {
+	Items         []TrashItem `json:"items"`
+	RetentionDays int         `json:"retention_days"`
+}
+
+// trashTarget parses :type and :id and checks the caller's permission for the type
+func trashTarget(c *fiber.Ctx) (repository.TrashKind, uuid.UUID, error) {
+	kind := repository.TrashKind(c.Params("type"))
+	perm, ok := trashPermissions[kind]
+	if !ok {
+		return "", uuid.Nil, fiber.NewError(fiber.StatusBadRequest, "type must be program, subcourse or lesson")
+	}
+	if !middleware.HasPermission(c, perm) {
+		return "", uuid.Nil, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return "", uuid.Nil, fiber.NewError(fiber.StatusBadRequest, "Invalid ID")
+	}
+	return kind, id, nil
+}
+
+// GET /api/admin/trash?type=program|subcourse|lesson - soft-deleted content the
+// caller may restore, newest first
+func (h *TrashHandler) List(c *fiber.Ctx) error {
+	kinds := repository.TrashKinds
+	if t := repository.TrashKind(c.Query("type")); t != "" {
+		if _, ok := trashPermissions[t]; !ok {
+			return fiber.NewError(fiber.StatusBadRequest, "type must be program, subcourse or lesson")
+		}
+		kinds = []repository.TrashKind{t}
+	}
+	var allowed []repository.TrashKind
+	for _, kind := range kinds {
+		if middleware.HasPermission(c, trashPermissions[kind]) {
+			allowed = append(allowed, kind)
+		}
+	}
+	if len(allowed) == 0 {
+		return fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+
+	trashed, err := h.trash.List(middleware.TenantContext(c), middleware.Actor(c), allowed)
+	if err != nil {
+		return serviceError(err, "Failed to fetch trash")
+	}
+	items := make([]TrashItem, len(trashed))
+	for i, t := range trashed {
+		parentID := t.SubcourseID
+		if t.Kind == TrashSubcourse {
+			parentID = t.ProgramID
+		} else if t.Kind == TrashProgram {
+			parentID = nil
+		}
+		items[i] = TrashItem{
+			Type: string(t.Kind), ID: t.ID, Title: t.Title, Slug: t.Slug, ParentID: parentID,
+			ParentTrashed: t.ParentTrashed, DeletedAt: *t.DeletedAt, DeletedBy: t.DeletedBy,
+			DeletedByName: t.DeletedByName, PurgeAt: t.PurgeAt,
+		}
+	}
+	return c.JSON(TrashList{Items: items, RetentionDays: int(h.trash.Retention() / (24 * time.Hour))})
+}
+
+// POST /api/admin/trash/:type/:id/restore - bring an item back. Restoring a
+// program or subcourse also restores the children that were trashed with it; a
+// lesson or subcourse whose parent is still in the trash cannot be restored.
+func (h *TrashHandler) Restore(c *fiber.Ctx) error {
+	kind, id, err := trashTarget(c)
+	if err != nil {
+		return err
+	}
+	restored, err := h.trash.Restore(middleware.TenantContext(c), middleware.Actor(c), kind, id)
+	if err != nil {
+		return serviceError(err, "Failed to restore "+string(kind))
+	}
+	return c.JSON(restored)
+}
+
+// DELETE /api/admin/trash/:type/:id - purge a trashed item and its subtree now,
+// including stored media files. This cannot be undone.
+func (h *TrashHandler) Purge(c *fiber.Ctx) error {
+	kind, id, err := trashTarget(c)
+	if err != nil {
+		return err
+	}
+	if err := h.trash.Purge(middleware.TenantContext(c), middleware.Actor(c), kind, id); err != nil {
+		return serviceError(err, "Failed to purge "+string(kind))
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
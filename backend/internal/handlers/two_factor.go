@@ -0,0 +1,371 @@
+package handlers
+
+import (
+	"courseai/backend/internal/config"
+	"courseai/backend/internal/middleware"
+	"courseai/backend/internal/models"
+	"courseai/backend/internal/security"
+	"courseai/backend/internal/service"
+	"courseai/backend/internal/utils"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// twoFactorMandatory reports whether policy forces 2FA on the role
+func twoFactorMandatory(cfg config.SecurityConfig, role models.UserRole) bool {
+	return cfg.RequireAdmin2FA && (role == models.RoleAdmin || role == models.RoleSuperAdmin)
+}
+
+// mfaPurpose returns the challenge a user must pass after the first login step,
+// or "" when the password (or SSO) alone is enough.
+func mfaPurpose(cfg config.SecurityConfig, user *models.User) string {
+	if user.TOTPEnabled {
+		return utils.PurposeMFA
+	}
+	if twoFactorMandatory(cfg, user.Role) {
+		return utils.PurposeMFAEnroll
+	}
+	return ""
+}
+
+func mfaChallengeTTL(cfg config.SecurityConfig) time.Duration {
+	return time.Duration(cfg.MFAChallengeMinutes) * time.Minute
+}
+
+// mfaChallenge answers the first login step with a short-lived challenge token
+func (h *AuthHandler) mfaChallenge(c *fiber.Ctx, user *models.User, purpose string) error {
+	ttl := mfaChallengeTTL(h.Config.Security)
+	token, err := utils.GenerateChallengeJWT(user, h.Config.JWT.Secret, purpose, ttl)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate token"})
+	}
+	return c.JSON(LoginResponse{MFAChallenge: &MFAChallenge{
+		MFARequired:        true,
+		EnrollmentRequired: purpose == utils.PurposeMFAEnroll,
+		ChallengeToken:     token,
+		ExpiresIn:          int(ttl.Seconds()),
+	}})
+}
+
+type TwoFactorVerifyRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+// POST /api/auth/2fa/verify - second login step: exchange challenge token + code for a session
+func (h *AuthHandler) VerifyTwoFactor(c *fiber.Ctx) error {
+	var req TwoFactorVerifyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if req.Code == "" && req.RecoveryCode == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "code or recovery_code is required"})
+	}
+	claims, err := utils.ValidateChallengeJWT(req.ChallengeToken, h.Config.JWT.Secret, utils.PurposeMFA)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired challenge"})
+	}
+
+	ip, userAgent := c.IP(), c.Get("User-Agent")
+	userID := claims.UserID
+	accountKey := security.AccountKey(&userID, "")
+	attempts, ok, err := h.reserve(c, &userID, claims.Username, security.IPKey(ip), accountKey)
+	if !ok {
+		return err
+	}
+
+	user, err := h.users.Get(c.UserContext(), userID)
+	if err != nil || !user.TOTPEnabled {
+		h.release(attempts...)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired challenge"})
+	}
+	if user.Status != models.StatusActive {
+		h.release(attempts...)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Account is not active"})
+	}
+
+	method, err := h.twoFactor.Verify(c.UserContext(), user, req.Code, req.RecoveryCode)
+	if err != nil {
+		h.recordAttempt(&userID, user.Username, ip, userAgent, false, "invalid_"+method)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid verification code"})
+	}
+
+	h.release(attempts...)
+	if err := h.Guard.Reset(accountKey); err != nil {
+		log.Printf("2FA: failed to reset throttle for %s: %v", accountKey, err)
+	}
+	h.recordAttempt(&userID, user.Username, ip, userAgent, true, method)
+	return h.issueSession(c, user)
+}
+
+type TwoFactorSetupRequest struct {
+	// ChallengeToken is used instead of a session when enrollment is forced at login
+	ChallengeToken string `json:"challenge_token"`
+}
+
+// POST /api/auth/2fa/setup - start enrollment: generate a secret and its provisioning URI
+func (h *AuthHandler) SetupTwoFactor(c *fiber.Ctx) error {
+	var req TwoFactorSetupRequest
+	_ = c.BodyParser(&req)
+
+	user, _, err := h.enrollingUser(c, req.ChallengeToken)
+	if err != nil {
+		return err
+	}
+	if user.TOTPEnabled {
+		return fiber.NewError(fiber.StatusConflict, "Two-factor authentication is already enabled")
+	}
+
+	secret, err := h.twoFactor.Start(c.UserContext(), user.ID)
+	if err != nil {
+		return serviceError(err, "Failed to start enrollment")
+	}
+
+	return c.JSON(TOTPSetupResponse{
+		Secret:          secret,
+		ProvisioningURI: security.TOTPProvisioningURI(h.Config.Security.TOTPIssuer, user.Username, secret),
+	})
+}
+
+// TOTPSetupResponse is the secret to enter into an authenticator app, also as
+// an otpauth:// URI for a QR code
+type TOTPSetupResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type TwoFactorEnableRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+// TwoFactorResponse reports whether 2FA is now enabled. Enabling returns the
+// recovery codes, and the session when enrollment was forced at login.
+type TwoFactorResponse struct {
+	Enabled       bool         `json:"enabled"`
+	RecoveryCodes []string     `json:"recovery_codes,omitempty"`
+	Token         string       `json:"token,omitempty"`
+	User          *models.User `json:"user,omitempty"`
+}
+
+// POST /api/auth/2fa/enable - confirm enrollment with a first code; returns the recovery codes once.
+// When enrollment was forced at login, the session token is issued as well.
+func (h *AuthHandler) EnableTwoFactor(c *fiber.Ctx) error {
+	var req TwoFactorEnableRequest
+	if err := c.BodyParser(&req); err != nil || req.Code == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "code is required")
+	}
+
+	user, viaChallenge, err := h.enrollingUser(c, req.ChallengeToken)
+	if err != nil {
+		return err
+	}
+	if user.TOTPEnabled {
+		return fiber.NewError(fiber.StatusConflict, "Two-factor authentication is already enabled")
+	}
+	if user.TOTPSecret == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Start enrollment first")
+	}
+
+	ip, userAgent := c.IP(), c.Get("User-Agent")
+	accountKey := security.AccountKey(&user.ID, "")
+	attempts, ok, err := h.reserve(c, &user.ID, user.Username, security.IPKey(ip), accountKey)
+	if !ok {
+		return err
+	}
+	codes, err := h.twoFactor.Enable(c.UserContext(), user, req.Code)
+	if errors.Is(err, service.ErrInvalidSecondFactor) {
+		h.recordAttempt(&user.ID, user.Username, ip, userAgent, false, "invalid_totp")
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid verification code")
+	}
+	h.release(attempts...)
+	if err != nil {
+		return serviceError(err, "Failed to enable two-factor authentication")
+	}
+	user.TOTPEnabled = true
+	log.Printf("2FA enabled for %s", user.Username)
+
+	resp := TwoFactorResponse{Enabled: true, RecoveryCodes: codes}
+	if viaChallenge {
+		// enrollment completed the login
+		if err := h.Guard.Reset(accountKey); err != nil {
+			log.Printf("2FA: failed to reset throttle for %s: %v", accountKey, err)
+		}
+		h.recordAttempt(&user.ID, user.Username, ip, userAgent, true, "totp_enrolled")
+		token, err := utils.GenerateJWT(user, h.Config.JWT.Secret, h.Config.JWT.ExpireHours)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to generate token")
+		}
+		resp.Token = token
+		resp.User = user
+	}
+	return c.JSON(resp)
+}
+
+type TwoFactorDisableRequest struct {
+	Password     string `json:"password"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// POST /api/auth/2fa/disable - requires the password and a current code (or recovery code)
+func (h *AuthHandler) DisableTwoFactor(c *fiber.Ctx) error {
+	var req TwoFactorDisableRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	user, err := h.users.Get(c.UserContext(), middleware.GetUserID(c))
+	if err != nil {
+		return serviceError(err, "Failed to fetch user")
+	}
+	if !user.TOTPEnabled {
+		return fiber.NewError(fiber.StatusBadRequest, "Two-factor authentication is not enabled")
+	}
+	if twoFactorMandatory(h.Config.Security, user.Role) {
+		return fiber.NewError(fiber.StatusForbidden, "Two-factor authentication is mandatory for your role")
+	}
+
+	ip, userAgent := c.IP(), c.Get("User-Agent")
+	accountKey := security.AccountKey(&user.ID, "")
+	attempts, ok, err := h.reserve(c, &user.ID, user.Username, security.IPKey(ip), accountKey)
+	if !ok {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		h.recordAttempt(&user.ID, user.Username, ip, userAgent, false, "invalid_password")
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid credentials")
+	}
+	if method, err := h.twoFactor.Verify(c.UserContext(), user, req.Code, req.RecoveryCode); err != nil {
+		h.recordAttempt(&user.ID, user.Username, ip, userAgent, false, "invalid_"+method)
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid verification code")
+	}
+	h.release(attempts...)
+
+	if err := h.twoFactor.Disable(c.UserContext(), user.ID); err != nil {
+		return serviceError(err, "Failed to disable two-factor authentication")
+	}
+	log.Printf("2FA disabled for %s", user.Username)
+	return c.JSON(TwoFactorResponse{Enabled: false})
+}
+
+type RecoveryCodesRequest struct {
+	Code string `json:"code"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// POST /api/auth/2fa/recovery-codes - replace all recovery codes (requires a current code)
+func (h *AuthHandler) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	var req RecoveryCodesRequest
+	if err := c.BodyParser(&req); err != nil || req.Code == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "code is required")
+	}
+
+	user, err := h.users.Get(c.UserContext(), middleware.GetUserID(c))
+	if err != nil {
+		return serviceError(err, "Failed to fetch user")
+	}
+	if !user.TOTPEnabled {
+		return fiber.NewError(fiber.StatusBadRequest, "Two-factor authentication is not enabled")
+	}
+
+	ip, userAgent := c.IP(), c.Get("User-Agent")
+	accountKey := security.AccountKey(&user.ID, "")
+	attempts, ok, err := h.reserve(c, &user.ID, user.Username, security.IPKey(ip), accountKey)
+	if !ok {
+		return err
+	}
+	if _, err := h.twoFactor.Verify(c.UserContext(), user, req.Code, ""); err != nil {
+		h.recordAttempt(&user.ID, user.Username, ip, userAgent, false, "invalid_totp")
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid verification code")
+	}
+	h.release(attempts...)
+
+	codes, err := h.twoFactor.RegenerateRecoveryCodes(c.UserContext(), user.ID)
+	if err != nil {
+		return serviceError(err, "Failed to generate recovery codes")
+	}
+	return c.JSON(RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+type TwoFactorStatus struct {
+	Enabled bool `json:"enabled"`
+	// Required is set when the user's role must use 2FA
+	Required               bool  `json:"required"`
+	RecoveryCodesRemaining int64 `json:"recovery_codes_remaining"`
+}
+
+// GET /api/auth/2fa/status
+func (h *AuthHandler) GetTwoFactorStatus(c *fiber.Ctx) error {
+	user, err := h.users.Get(c.UserContext(), middleware.GetUserID(c))
+	if err != nil {
+		return serviceError(err, "Failed to fetch user")
+	}
+	remaining, err := h.twoFactor.RecoveryCodesRemaining(c.UserContext(), user.ID)
+	if err != nil {
+		return serviceError(err, "Failed to count recovery codes")
+	}
+	return c.JSON(TwoFactorStatus{
+		Enabled:                user.TOTPEnabled,
+		Required:               twoFactorMandatory(h.Config.Security, user.Role),
+		RecoveryCodesRemaining: remaining,
+	})
+}
+
+// DELETE /api/admin/users/:id/2fa - reset a user's 2FA (lost device); they re-enroll on next login
+func (h *AuthHandler) ResetTwoFactor(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user id")
+	}
+	if userID == middleware.GetUserID(c) {
+		return fiber.NewError(fiber.StatusBadRequest, "Use the disable endpoint for your own account")
+	}
+
+	user, err := h.users.Get(middleware.TenantContext(c), userID)
+	if err != nil {
+		return serviceError(err, "Failed to fetch user")
+	}
+	if user.Role == models.RoleSuperAdmin && middleware.GetUserRole(c) != models.RoleSuperAdmin {
+		return fiber.NewError(fiber.StatusForbidden, "Only super-admins can reset a super-admin")
+	}
+	if err := h.twoFactor.Disable(c.UserContext(), user.ID); err != nil {
+		return serviceError(err, "Failed to reset two-factor authentication")
+	}
+
+	log.Printf("2FA for %s reset by %s", user.Username, middleware.GetUsername(c))
+	return c.JSON(MessageResponse{Message: "Two-factor authentication reset"})
+}
+
+// enrollingUser resolves who is enrolling: the signed-in user, or the holder of
+// an enrollment challenge issued by Login when 2FA is mandatory.
+func (h *AuthHandler) enrollingUser(c *fiber.Ctx, challengeToken string) (*models.User, bool, error) {
+	userID := middleware.GetUserID(c)
+	viaChallenge := false
+	if userID == uuid.Nil {
+		claims, err := utils.ValidateChallengeJWT(challengeToken, h.Config.JWT.Secret, utils.PurposeMFAEnroll)
+		if err != nil {
+			return nil, false, fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired challenge")
+		}
+		userID = claims.UserID
+		viaChallenge = true
+	}
+
+	user, err := h.users.Get(c.UserContext(), userID)
+	if err != nil {
+		return nil, false, serviceError(err, "Failed to fetch user")
+	}
+	if user.Status != models.StatusActive {
+		return nil, false, fiber.NewError(fiber.StatusUnauthorized, "Account is not active")
+	}
+	return user, viaChallenge, nil
+}
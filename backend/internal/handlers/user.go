@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"courseai/backend/internal/middleware"
+	"courseai/backend/internal/models"
+	"courseai/backend/internal/service"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type UserHandler struct {
+	users *service.UserService
+}
+
+func NewUserHandler(users *service.UserService) *UserHandler {
+	return &UserHandler{users: users}
+}
+
+type EffectivePermissions struct {
+	UserID      uuid.UUID           `json:"user_id"`
+	Username    string              `json:"username"`
+	Role        models.UserRole     `json:"role"`
+	Permissions []models.Permission `json:"permissions"`
+}
+
+func effectivePermissions(user *models.User) EffectivePermissions {
+	perms := user.Role.Permissions()
+	if perms == nil {
+		perms = []models.Permission{}
+	}
+	return EffectivePermissions{
+		UserID:      user.ID,
+		Username:    user.Username,
+		Role:        user.Role,
+		Permissions: perms,
+	}
+}
+
+type RolePermissions struct {
+	Role        models.UserRole     `json:"role"`
+	Permissions []models.Permission `json:"permissions"`
+}
+
+// RolesResponse lists every role with its permissions, and all permissions
+type RolesResponse struct {
+	Roles       []RolePermissions   `json:"roles"`
+	Permissions []models.Permission `json:"permissions"`
+}
+
+// GET /api/admin/roles
+func (h *UserHandler) ListRoles(c *fiber.Ctx) error {
+	out := make([]RolePermissions, 0, len(models.UserRoles))
+	for _, r := range models.UserRoles {
+		out = append(out, RolePermissions{Role: r, Permissions: r.Permissions()})
+	}
+	return c.JSON(RolesResponse{Roles: out, Permissions: models.AllPermissions})
+}
+
+// GET /api/admin/users/:id/permissions
+func (h *UserHandler) GetPermissions(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user id")
+	}
+
+	user, err := h.users.Get(middleware.TenantContext(c), userID)
+	if err != nil {
+		return serviceError(err, "Failed to fetch user")
+	}
+	return c.JSON(effectivePermissions(user))
+}
+
+// GET /api/auth/me/permissions
+func (h *UserHandler) GetMyPermissions(c *fiber.Ctx) error {
+	user, err := h.users.Get(c.UserContext(), middleware.GetUserID(c))
+	if err != nil {
+		return serviceError(err, "Failed to fetch user")
+	}
+	return c.JSON(effectivePermissions(user))
+}
+
+type SetRoleInput struct {
+	Role models.UserRole `json:"role" openapi:"required"`
+	// OrganizationID is required when demoting a super-admin
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty"`
+}
+
+// PUT /api/admin/users/:id/role
+func (h *UserHandler) SetRole(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user id")
+	}
+
+	var input SetRoleInput
+	if err := c.BodyParser(&input); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid input")
+	}
+	if !input.Role.IsValid() {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid role")
+	}
+	if userID == middleware.GetUserID(c) {
+		return fiber.NewError(fiber.StatusBadRequest, "Cannot change your own role")
+	}
+	// only super-admins may grant super-admin
+	if input.Role == models.RoleSuperAdmin && middleware.GetUserRole(c) != models.RoleSuperAdmin {
+		return fiber.NewError(fiber.StatusForbidden, "Only super-admins can grant the super_admin role")
+	}
+
+	user, err := h.users.SetRole(middleware.TenantContext(c), userID, input.Role, input.OrganizationID)
+	if err != nil {
+		return serviceError(err, "Failed to update role")
+	}
+	middleware.ForgetPrincipal(user.ID)
+
+	log.Printf("User %s role changed to %s by %s", user.Username, input.Role, middleware.GetUsername(c))
+	return c.JSON(effectivePermissions(user))
+}
+
+type PasswordLoginInput struct {
+	Disabled bool `json:"disabled"`
+}
+
+// PUT /api/admin/users/:id/password-login - force a user onto single sign-on (or allow passwords again)
+func (h *UserHandler) SetPasswordLogin(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user id")
+	}
+	var input PasswordLoginInput
+	if err := c.BodyParser(&input); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid input")
+	}
+
+	user, err := h.users.SetPasswordLogin(middleware.TenantContext(c), userID, input.Disabled)
+	if err != nil {
+		return serviceError(err, "Failed to update user")
+	}
+
+	log.Printf("Password login for %s set to disabled=%v by %s", user.Username, input.Disabled, middleware.GetUsername(c))
+	return c.JSON(user)
+}
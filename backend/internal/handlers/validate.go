@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"courseai/backend/internal/middleware"
+	"courseai/backend/internal/service"
+	"courseai/backend/internal/validation"
+	"errors"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// invalid answers status with every validation error, e.g.
+//
+//	{"error": "content_blocks[2].title is required",
+//	 "errors": [{"field": "content_blocks[2].title", "code": "required", "message": "..."}]}
+//
+// "error" joins all messages so older clients still show something useful.
+func invalid(c *fiber.Ctx, status int, err error) error {
+	var errs validation.Errors
+	if !errors.As(err, &errs) {
+		return fiber.NewError(status, err.Error())
+	}
+	return c.Status(status).JSON(fiber.Map{"error": errs.Error(), "errors": errs})
+}
+
+// serviceError reports an error of a domain service. Expected failures keep
+// their message, as do HTTP errors such as a malformed If-Match; anything else is logged and answered with message.
+func serviceError(err error, message string) error {
+	var e *service.Error
+	var fiberErr *fiber.Error
+	if errors.As(err, &e) {
+		return middleware.ServiceError(err)
+	} else if errors.As(err, &fiberErr) {
+		return fiberErr
+	}
+	log.Printf("%s: %v", message, err)
+	return fiber.NewError(fiber.StatusInternalServerError, message)
+}
+
+// rejectedError reports an error of a domain service like serviceError, but
+// lists every field when the service rejected the input with validation errors
+func rejectedError(c *fiber.Ctx, err error, message string) error {
+	var e *service.Error
+	var errs validation.Errors
+	if errors.As(err, &e) && errors.As(err, &errs) {
+		return invalid(c, middleware.ServiceError(err).(*fiber.Error).Code, errs)
+	}
+	return serviceError(err, message)
+}
+
+// slugConflict answers 409 for a slug that is already in use
+func slugConflict(c *fiber.Ctx, slug string) error {
+	v := validation.New()
+	v.Add("slug", validation.CodeConflict, "%q is already in use", slug)
+	return invalid(c, fiber.StatusConflict, v.Err())
+}
@@ -0,0 +1,80 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Database pings the connection pool of db
+func Database(db *gorm.DB) Check {
+	return Check{
+		Name: "database",
+		Run: func(ctx context.Context) error {
+			if db == nil {
+				return errors.New("not connected")
+			}
+			sqlDB, err := db.DB()
+			if err != nil {
+				return err
+			}
+			return sqlDB.PingContext(ctx)
+		},
+	}
+}
+
+// Writable creates and removes a file in dir, creating dir if needed
+func Writable(name, dir string) Check {
+	return Check{
+		Name: name,
+		Run: func(ctx context.Context) error {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return err
+			}
+			f, err := os.CreateTemp(dir, ".probe-*")
+			if err != nil {
+				return err
+			}
+			_, werr := f.WriteString("ok")
+			cerr := f.Close()
+			rerr := os.Remove(f.Name())
+			return errors.Join(werr, cerr, rerr)
+		},
+	}
+}
+
+// heartbeats holds the last beat of every background worker
+var heartbeats sync.Map
+
+// Beat records that the named background worker is still running; workers
+// call it from their loop
+func Beat(name string) {
+	heartbeats.Store(name, time.Now())
+}
+
+// Worker fails when the named worker has not beaten for maxAge. A worker
+// that has not beaten yet gets maxAge from the moment the check is created.
+func Worker(name string, maxAge time.Duration) Check {
+	registered := time.Now()
+	return Check{
+		Name: "worker:" + name,
+		Run: func(ctx context.Context) error {
+			last := registered
+			if v, ok := heartbeats.Load(name); ok {
+				last = v.(time.Time)
+			}
+			if age := time.Since(last); age > maxAge {
+				if last == registered {
+					return fmt.Errorf("no heartbeat within %s of startup", maxAge)
+				}
+				return fmt.Errorf("last heartbeat %s ago", age.Round(time.Second))
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,164 @@
+// Package health runs the checks behind the liveness and readiness probes.
+// Liveness asks whether the process should be restarted: the checks cover
+// what only a restart fixes, such as a background worker that stopped.
+// Readiness asks whether the process should receive traffic: its checks
+// cover dependencies (database, schema, storage) and it fails while the
+// server drains connections during a graceful shutdown.
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultTimeout bounds a check that does not set its own
+const DefaultTimeout = 2 * time.Second
+
+// Check is one named probe; Run returns nil when the check passes
+type Check struct {
+	Name    string
+	Timeout time.Duration
+	Run     func(ctx context.Context) error
+}
+
+// Status is the outcome of a check or a whole probe
+type Status string
+
+const (
+	StatusOK      Status = "ok"
+	StatusFailing Status = "failing"
+)
+
+// CheckResult is the outcome of one check
+type CheckResult struct {
+	Name       string  `json:"name"`
+	Status     Status  `json:"status"`
+	Error      string  `json:"error,omitempty"`
+	DurationMS float64 `json:"duration_ms"`
+}
+
+// ProbeReport is the answer of a probe; Checks is only filled in for callers
+// allowed to see the details
+type ProbeReport struct {
+	Status Status        `json:"status"`
+	Checks []CheckResult `json:"checks,omitempty"`
+}
+
+// OK reports whether every check passed
+func (r ProbeReport) OK() bool { return r.Status == StatusOK }
+
+// Checker holds the liveness and readiness checks of the process
+type Checker struct {
+	mu       sync.RWMutex
+	live     []Check
+	ready    []Check
+	draining atomic.Bool
+}
+
+func NewChecker() *Checker {
+	return &Checker{}
+}
+
+// AddLiveness registers checks run by Live
+func (c *Checker) AddLiveness(checks ...Check) {
+	c.mu.Lock()
+	c.live = append(c.live, checks...)
+	c.mu.Unlock()
+}
+
+// AddReadiness registers checks run by Ready
+func (c *Checker) AddReadiness(checks ...Check) {
+	c.mu.Lock()
+	c.ready = append(c.ready, checks...)
+	c.mu.Unlock()
+}
+
+// Drain makes Ready fail from now on, so load balancers stop sending
+// traffic before the server shuts down
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Draining reports whether Drain was called
+func (c *Checker) Draining() bool {
+	return c.draining.Load()
+}
+
+// Live runs the liveness checks
+func (c *Checker) Live(ctx context.Context) ProbeReport {
+	c.mu.RLock()
+	checks := c.live
+	c.mu.RUnlock()
+	return run(ctx, checks)
+}
+
+// Ready runs the readiness checks; it fails while draining
+func (c *Checker) Ready(ctx context.Context) ProbeReport {
+	c.mu.RLock()
+	checks := c.ready
+	c.mu.RUnlock()
+	report := run(ctx, checks)
+	if c.Draining() {
+		report.Status = StatusFailing
+		report.Checks = append([]CheckResult{{Name: "shutdown", Status: StatusFailing, Error: "server is shutting down"}}, report.Checks...)
+	}
+	return report
+}
+
+// run runs the checks concurrently, each bounded by its timeout, and keeps
+// their order in the report
+func run(ctx context.Context, checks []Check) ProbeReport {
+	report := ProbeReport{Status: StatusOK, Checks: make([]CheckResult, len(checks))}
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			report.Checks[i] = runCheck(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+	for _, r := range report.Checks {
+		if r.Status != StatusOK {
+			report.Status = StatusFailing
+		}
+	}
+	return report
+}
+
+func runCheck(ctx context.Context, check Check) CheckResult {
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("check panicked: %v", p)
+			}
+		}()
+		done <- check.Run(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		// a check that ignores its context is abandoned, not waited for
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+	result := CheckResult{Name: check.Name, Status: StatusOK, DurationMS: float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		result.Status = StatusFailing
+		result.Error = err.Error()
+	}
+	return result
+}
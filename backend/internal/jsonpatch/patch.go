@@ -0,0 +1,283 @@
+// Package jsonpatch applies RFC 6902 JSON Patch documents to JSON values.
+package jsonpatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Operation is one entry of a JSON Patch document
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Error describes the operation that failed
+type Error struct {
+	Index int
+	Op    Operation
+	Err   error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("patch operation %d (%s %s): %v", e.Index, e.Op.Op, e.Op.Path, e.Err)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// ErrTestFailed is returned when a "test" operation does not match
+var ErrTestFailed = fmt.Errorf("test failed")
+
+// Decode parses a JSON Patch document
+func Decode(patch []byte) ([]Operation, error) {
+	var ops []Operation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("invalid JSON Patch document: %w", err)
+	}
+	return ops, nil
+}
+
+// Apply applies ops to doc and returns the patched document. The patch is
+// atomic: on error the original document is left unchanged.
+func Apply(doc []byte, ops []Operation) ([]byte, error) {
+	var root interface{}
+	if err := json.Unmarshal(doc, &root); err != nil {
+		return nil, err
+	}
+	for i, op := range ops {
+		var err error
+		root, err = applyOp(root, op)
+		if err != nil {
+			return nil, &Error{Index: i, Op: op, Err: err}
+		}
+	}
+	return json.Marshal(root)
+}
+
+func applyOp(root interface{}, op Operation) (interface{}, error) {
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("value is required")
+		}
+		var value interface{}
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return nil, fmt.Errorf("invalid value: %w", err)
+		}
+		switch op.Op {
+		case "add":
+			return add(root, op.Path, value)
+		case "replace":
+			if _, err := get(root, op.Path); err != nil {
+				return nil, err
+			}
+			root, err := remove(root, op.Path)
+			if err != nil {
+				return nil, err
+			}
+			return add(root, op.Path, value)
+		default:
+			current, err := get(root, op.Path)
+			if err != nil {
+				return nil, err
+			}
+			if !reflect.DeepEqual(current, value) {
+				return nil, ErrTestFailed
+			}
+			return root, nil
+		}
+	case "remove":
+		return remove(root, op.Path)
+	case "move", "copy":
+		value, err := get(root, op.From)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "move" {
+			if strings.HasPrefix(op.Path, op.From+"/") {
+				return nil, fmt.Errorf("cannot move a value into itself")
+			}
+			if root, err = remove(root, op.From); err != nil {
+				return nil, err
+			}
+		} else {
+			value = deepCopy(value)
+		}
+		return add(root, op.Path, value)
+	default:
+		return nil, fmt.Errorf("unsupported op %q", op.Op)
+	}
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into unescaped tokens
+func parsePointer(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("invalid pointer %q", path)
+	}
+	tokens := strings.Split(path[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func arrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if allowEnd && token == "-" {
+		return length, nil
+	}
+	idx, err := strconv.Atoi(token)
+	if err != nil || idx < 0 || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	limit := length - 1
+	if allowEnd {
+		limit = length
+	}
+	if idx > limit {
+		return 0, fmt.Errorf("array index %d out of range", idx)
+	}
+	return idx, nil
+}
+
+func get(root interface{}, path string) (interface{}, error) {
+	tokens, err := parsePointer(path)
+	if err != nil {
+		return nil, err
+	}
+	node := root
+	for _, t := range tokens {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			v, ok := n[t]
+			if !ok {
+				return nil, fmt.Errorf("path %q not found", path)
+			}
+			node = v
+		case []interface{}:
+			idx, err := arrayIndex(t, len(n), false)
+			if err != nil {
+				return nil, err
+			}
+			node = n[idx]
+		default:
+			return nil, fmt.Errorf("path %q not found", path)
+		}
+	}
+	return node, nil
+}
+
+// update walks to the parent of path and lets fn replace the child container
+func update(root interface{}, tokens []string, fn func(parent interface{}, key string) (interface{}, error)) (interface{}, error) {
+	if len(tokens) == 1 {
+		return fn(root, tokens[0])
+	}
+	switch n := root.(type) {
+	case map[string]interface{}:
+		child, ok := n[tokens[0]]
+		if !ok {
+			return nil, fmt.Errorf("path segment %q not found", tokens[0])
+		}
+		updated, err := update(child, tokens[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		n[tokens[0]] = updated
+		return n, nil
+	case []interface{}:
+		idx, err := arrayIndex(tokens[0], len(n), false)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := update(n[idx], tokens[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		n[idx] = updated
+		return n, nil
+	default:
+		return nil, fmt.Errorf("path segment %q not found", tokens[0])
+	}
+}
+
+func add(root interface{}, path string, value interface{}) (interface{}, error) {
+	tokens, err := parsePointer(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return update(root, tokens, func(parent interface{}, key string) (interface{}, error) {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			p[key] = value
+			return p, nil
+		case []interface{}:
+			idx, err := arrayIndex(key, len(p), true)
+			if err != nil {
+				return nil, err
+			}
+			p = append(p, nil)
+			copy(p[idx+1:], p[idx:])
+			p[idx] = value
+			return p, nil
+		default:
+			return nil, fmt.Errorf("cannot add to a scalar")
+		}
+	})
+}
+
+func remove(root interface{}, path string) (interface{}, error) {
+	tokens, err := parsePointer(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("cannot remove the document root")
+	}
+	return update(root, tokens, func(parent interface{}, key string) (interface{}, error) {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			if _, ok := p[key]; !ok {
+				return nil, fmt.Errorf("path %q not found", path)
+			}
+			delete(p, key)
+			return p, nil
+		case []interface{}:
+			idx, err := arrayIndex(key, len(p), false)
+			if err != nil {
+				return nil, err
+			}
+			return append(p[:idx], p[idx+1:]...), nil
+		default:
+			return nil, fmt.Errorf("path %q not found", path)
+		}
+	})
+}
+
+func deepCopy(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			out[k] = deepCopy(val)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, val := range t {
+			out[i] = deepCopy(val)
+		}
+		return out
+	default:
+		return v
+	}
+}
@@ -0,0 +1,164 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	const doc = `{"title":"Intro","tags":["a","b","c"],"meta":{"level":1},"a/b":1,"m~n":2}`
+	tests := []struct {
+		name  string
+		patch string
+		want  string
+	}{
+		{"add member", `[{"op":"add","path":"/subtitle","value":"x"}]`,
+			`{"title":"Intro","subtitle":"x","tags":["a","b","c"],"meta":{"level":1},"a/b":1,"m~n":2}`},
+		{"add replaces existing member", `[{"op":"add","path":"/title","value":"New"}]`,
+			`{"title":"New","tags":["a","b","c"],"meta":{"level":1},"a/b":1,"m~n":2}`},
+		{"add inserts into array", `[{"op":"add","path":"/tags/1","value":"x"}]`,
+			`{"title":"Intro","tags":["a","x","b","c"],"meta":{"level":1},"a/b":1,"m~n":2}`},
+		{"add at array length", `[{"op":"add","path":"/tags/3","value":"x"}]`,
+			`{"title":"Intro","tags":["a","b","c","x"],"meta":{"level":1},"a/b":1,"m~n":2}`},
+		{"add appends with -", `[{"op":"add","path":"/tags/-","value":"x"}]`,
+			`{"title":"Intro","tags":["a","b","c","x"],"meta":{"level":1},"a/b":1,"m~n":2}`},
+		{"add replaces the root", `[{"op":"add","path":"","value":{"x":1}}]`, `{"x":1}`},
+		{"remove member", `[{"op":"remove","path":"/meta"}]`,
+			`{"title":"Intro","tags":["a","b","c"],"a/b":1,"m~n":2}`},
+		{"remove array item", `[{"op":"remove","path":"/tags/0"}]`,
+			`{"title":"Intro","tags":["b","c"],"meta":{"level":1},"a/b":1,"m~n":2}`},
+		{"replace", `[{"op":"replace","path":"/meta/level","value":2}]`,
+			`{"title":"Intro","tags":["a","b","c"],"meta":{"level":2},"a/b":1,"m~n":2}`},
+		{"replace with null", `[{"op":"replace","path":"/title","value":null}]`,
+			`{"title":null,"tags":["a","b","c"],"meta":{"level":1},"a/b":1,"m~n":2}`},
+		{"replace array item", `[{"op":"replace","path":"/tags/2","value":"z"}]`,
+			`{"title":"Intro","tags":["a","b","z"],"meta":{"level":1},"a/b":1,"m~n":2}`},
+		{"move member", `[{"op":"move","from":"/title","path":"/meta/title"}]`,
+			`{"tags":["a","b","c"],"meta":{"level":1,"title":"Intro"},"a/b":1,"m~n":2}`},
+		{"move within array", `[{"op":"move","from":"/tags/0","path":"/tags/-"}]`,
+			`{"title":"Intro","tags":["b","c","a"],"meta":{"level":1},"a/b":1,"m~n":2}`},
+		{"move onto itself", `[{"op":"move","from":"/meta","path":"/meta"}]`, doc},
+		{"copy", `[{"op":"copy","from":"/meta","path":"/meta2"},{"op":"replace","path":"/meta2/level","value":5}]`,
+			`{"title":"Intro","tags":["a","b","c"],"meta":{"level":1},"meta2":{"level":5},"a/b":1,"m~n":2}`},
+		{"test passes", `[{"op":"test","path":"/meta","value":{"level":1}},{"op":"test","path":"/tags/1","value":"b"}]`, doc},
+		{"test null", `[{"op":"add","path":"/x","value":null},{"op":"test","path":"/x","value":null}]`,
+			`{"title":"Intro","tags":["a","b","c"],"meta":{"level":1},"a/b":1,"m~n":2,"x":null}`},
+		{"~1 escapes /", `[{"op":"replace","path":"/a~1b","value":3}]`,
+			`{"title":"Intro","tags":["a","b","c"],"meta":{"level":1},"a/b":3,"m~n":2}`},
+		{"~0 escapes ~", `[{"op":"remove","path":"/m~0n"}]`,
+			`{"title":"Intro","tags":["a","b","c"],"meta":{"level":1},"a/b":1}`},
+		{"~01 is ~1, not /", `[{"op":"add","path":"/~01","value":0}]`,
+			`{"title":"Intro","tags":["a","b","c"],"meta":{"level":1},"a/b":1,"m~n":2,"~1":0}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := apply(t, doc, tt.patch)
+			assertJSON(t, got, tt.want)
+		})
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	const doc = `{"title":"Intro","tags":["a","b","c"],"meta":{"level":1}}`
+	tests := []struct {
+		name  string
+		patch string
+		err   string
+	}{
+		{"add past array end", `[{"op":"add","path":"/tags/4","value":"x"}]`, "out of range"},
+		{"add to missing parent", `[{"op":"add","path":"/missing/x","value":1}]`, "not found"},
+		{"add without value", `[{"op":"add","path":"/x"}]`, "value is required"},
+		{"remove missing member", `[{"op":"remove","path":"/missing"}]`, "not found"},
+		{"remove past array end", `[{"op":"remove","path":"/tags/3"}]`, "out of range"},
+		{"remove with -", `[{"op":"remove","path":"/tags/-"}]`, "invalid array index"},
+		{"remove the root", `[{"op":"remove","path":""}]`, "root"},
+		{"replace missing member", `[{"op":"replace","path":"/missing","value":1}]`, "not found"},
+		{"negative index", `[{"op":"replace","path":"/tags/-1","value":1}]`, "invalid array index"},
+		{"leading zero index", `[{"op":"replace","path":"/tags/01","value":1}]`, "invalid array index"},
+		{"move into own child", `[{"op":"move","from":"/meta","path":"/meta/level/x"}]`, "into itself"},
+		{"move root into child", `[{"op":"move","from":"","path":"/meta"}]`, "into itself"},
+		{"move from missing", `[{"op":"move","from":"/missing","path":"/x"}]`, "not found"},
+		{"copy from past array end", `[{"op":"copy","from":"/tags/3","path":"/x"}]`, "out of range"},
+		{"test fails", `[{"op":"test","path":"/title","value":"Other"}]`, "test failed"},
+		{"test compares types", `[{"op":"test","path":"/meta/level","value":"1"}]`, "test failed"},
+		{"pointer without slash", `[{"op":"add","path":"title","value":1}]`, "invalid pointer"},
+		{"unknown op", `[{"op":"merge","path":"/title","value":1}]`, "unsupported op"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ops, err := Decode([]byte(tt.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = Apply([]byte(doc), ops)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("err = %v, want %q", err, tt.err)
+			}
+			var opErr *Error
+			if !errors.As(err, &opErr) || opErr.Index != 0 {
+				t.Fatalf("err = %#v, want *Error for operation 0", err)
+			}
+		})
+	}
+}
+
+func TestApplyIsAllOrNothing(t *testing.T) {
+	doc := []byte(`{"title":"Intro","tags":["a"]}`)
+	ops, err := Decode([]byte(`[
+		{"op":"replace","path":"/title","value":"Changed"},
+		{"op":"add","path":"/tags/-","value":"b"},
+		{"op":"test","path":"/title","value":"Intro"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	patched, err := Apply(doc, ops)
+	if !errors.Is(err, ErrTestFailed) {
+		t.Fatalf("err = %v, want ErrTestFailed", err)
+	}
+	var opErr *Error
+	if !errors.As(err, &opErr) || opErr.Index != 2 {
+		t.Fatalf("err = %v, want the third operation to fail", err)
+	}
+	if patched != nil {
+		t.Fatalf("patched = %s, want nothing", patched)
+	}
+	assertJSON(t, doc, `{"title":"Intro","tags":["a"]}`)
+}
+
+func TestDecodeRejectsNonArrays(t *testing.T) {
+	if _, err := Decode([]byte(`{"op":"add"}`)); err == nil {
+		t.Fatal("an object was accepted as a patch")
+	}
+}
+
+func apply(t *testing.T, doc, patch string) []byte {
+	t.Helper()
+	ops, err := Decode([]byte(patch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := Apply([]byte(doc), ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func assertJSON(t *testing.T, got []byte, want string) {
+	t.Helper()
+	var g, w interface{}
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatal(err)
+	}
+	gotJSON, _ := json.Marshal(g)
+	wantJSON, _ := json.Marshal(w)
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("got %s\nwant %s", gotJSON, wantJSON)
+	}
+}
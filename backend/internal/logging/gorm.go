@@ -0,0 +1,100 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// GormLogger writes GORM's log through slog. Failed statements are logged as
+// errors and statements slower than the threshold as warnings; every other
+// statement only with Options.SQL, at debug level. Statements are logged with
+// placeholders, never with their bound values.
+type GormLogger struct {
+	level         logger.LogLevel
+	slowThreshold time.Duration
+	logQueries    bool
+}
+
+// Gorm returns the GORM logger for the options of the last Setup
+func Gorm() *GormLogger {
+	return &GormLogger{
+		level:         logger.Warn,
+		slowThreshold: current.SlowQuery,
+		logQueries:    current.SQL,
+	}
+}
+
+func (l *GormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	copied := *l
+	copied.level = level
+	return &copied
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Info {
+		slog.InfoContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Warn {
+		slog.WarnContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Error {
+		slog.ErrorContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	slow := l.slowThreshold > 0 && elapsed > l.slowThreshold
+	failed := err != nil && !errors.Is(err, gorm.ErrRecordNotFound)
+
+	var level slog.Level
+	var msg string
+	switch {
+	case failed && l.level >= logger.Error:
+		level, msg = slog.LevelError, "query failed"
+	case slow && l.level >= logger.Warn:
+		level, msg = slog.LevelWarn, "slow query"
+	case l.logQueries:
+		level, msg = slog.LevelDebug, "query"
+	default:
+		return
+	}
+	if !slog.Default().Enabled(ctx, level) {
+		return
+	}
+
+	sql, rows := fc()
+	attrs := []slog.Attr{
+		slog.String("sql", sql),
+		slog.Int64("rows", rows),
+		slog.Float64("duration_ms", float64(elapsed.Microseconds())/1000),
+	}
+	if slow {
+		attrs = append(attrs, slog.Float64("threshold_ms", float64(l.slowThreshold.Microseconds())/1000))
+	}
+	if failed {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	slog.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// ParamsFilter drops the bound values, so passwords, tokens and personal data
+// in statements never reach the log
+func (l *GormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
@@ -0,0 +1,108 @@
+// Package logging sets up the process-wide structured logger. Records are
+// written as JSON (or text) through log/slog, carry the request ID and trace
+// of the request they belong to and have credentials redacted before they
+// are written. The standard library log package is routed through the same
+// handler, so existing log.Printf calls are structured and redacted too.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Options configures the process logger
+type Options struct {
+	// Level is debug, info, warn or error
+	Level string
+	// Format is json or text
+	Format string
+	// SlowQuery is the duration above which a SQL statement is logged as slow; 0 disables it
+	SlowQuery time.Duration
+	// SQL logs every SQL statement at debug level
+	SQL bool
+	// Output defaults to stderr
+	Output io.Writer
+}
+
+// current holds the options of the last Setup, for the GORM logger
+var current = Options{Level: "info", Format: "json", SlowQuery: 200 * time.Millisecond}
+
+// Setup installs the logger described by opts as the slog and log default
+func Setup(opts Options) *slog.Logger {
+	if opts.Output == nil {
+		opts.Output = os.Stderr
+	}
+	current = opts
+
+	handlerOpts := &slog.HandlerOptions{Level: ParseLevel(opts.Level)}
+	var handler slog.Handler
+	if strings.EqualFold(opts.Format, "text") {
+		handler = slog.NewTextHandler(opts.Output, handlerOpts)
+	} else {
+		handler = slog.NewJSONHandler(opts.Output, handlerOpts)
+	}
+	logger := slog.New(&contextHandler{redactingHandler{handler}})
+	slog.SetDefault(logger)
+	return logger
+}
+
+// ParseLevel parses a level name; unknown names mean info
+func ParseLevel(name string) slog.Level {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns ctx carrying the request ID that records logged with
+// it are tagged with
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, if any
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// contextHandler adds the request ID and trace of the record's context
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if ctx != nil {
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// HTTP requests, labelled by route template (e.g. /api/admin/lessons/:id) so
+// IDs in paths do not multiply the series
+var (
+	HTTPRequests = factory.NewCounterVec(prometheus.CounterOpts{Name: "courseai_http_requests_total",
+		Help: "HTTP requests by method, route template and status."}, []string{"method", "route", "status"})
+	HTTPDuration = factory.NewHistogramVec(prometheus.HistogramOpts{Name: "courseai_http_request_duration_seconds",
+		Help: "HTTP request latency by method, route template and status.", Buckets: prometheus.DefBuckets}, []string{"method", "route", "status"})
+	HTTPInFlight = factory.NewGauge(prometheus.GaugeOpts{Name: "courseai_http_requests_in_flight",
+		Help: "HTTP requests being served."})
+)
+
+// Database statements, timed by the GORM plugin
+var DBQueryDuration = factory.NewHistogramVec(prometheus.HistogramOpts{Name: "courseai_db_query_duration_seconds",
+	Help: "Duration of GORM statements by operation and outcome.", Buckets: prometheus.DefBuckets}, []string{"operation", "status"})
+
+// Media uploads
+var (
+	Uploads = factory.NewCounterVec(prometheus.CounterOpts{Name: "courseai_media_uploads_total",
+		Help: "Uploaded media files by MIME type."}, []string{"mime"})
+	UploadBytes = factory.NewCounterVec(prometheus.CounterOpts{Name: "courseai_media_upload_bytes_total",
+		Help: "Bytes of uploaded media files by MIME type."}, []string{"mime"})
+)
+
+// Sign-ins: result is success or failure, reason the login history reason
+// (e.g. invalid_password, account_locked, totp, sso)
+var LoginAttempts = factory.NewCounterVec(prometheus.CounterOpts{Name: "courseai_login_attempts_total",
+	Help: "Sign-in attempts by result and reason."}, []string{"result", "reason"})
+
+// Background jobs such as the trash purge
+var (
+	JobRuns = factory.NewCounterVec(prometheus.CounterOpts{Name: "courseai_job_runs_total",
+		Help: "Background job runs by job and result."}, []string{"job", "result"})
+	JobDuration = factory.NewHistogramVec(prometheus.HistogramOpts{Name: "courseai_job_duration_seconds",
+		Help: "Background job run duration.", Buckets: []float64{.01, .1, .5, 1, 5, 15, 60, 300}}, []string{"job"})
+	JobLastSuccess = factory.NewGaugeVec(prometheus.GaugeOpts{Name: "courseai_job_last_success_timestamp_seconds",
+		Help: "Unix time of the last successful run of a background job."}, []string{"job"})
+	JobItems = factory.NewCounterVec(prometheus.CounterOpts{Name: "courseai_job_items_total",
+		Help: "Items processed by background jobs, e.g. purged lessons."}, []string{"job"})
+)
+
+// Public catalog response cache: result is hit, miss or bypass (callers
+// whose responses are never cached)
+var (
+	CacheRequests = factory.NewCounterVec(prometheus.CounterOpts{Name: "courseai_cache_requests_total",
+		Help: "Cacheable requests by route template and result."}, []string{"route", "result"})
+	CacheInvalidations = factory.NewCounter(prometheus.CounterOpts{Name: "courseai_cache_invalidations_total",
+		Help: "Cache tags invalidated by database changes."})
+)
+
+// Requests refused by the rate limiter, by route group (e.g. login)
+var RateLimited = factory.NewCounterVec(prometheus.CounterOpts{Name: "courseai_rate_limited_total",
+	Help: "Requests refused with 429 by route group."}, []string{"group"})
+
+// ObserveUpload records one stored media file
+func ObserveUpload(mime string, size int64) {
+	Uploads.WithLabelValues(mime).Inc()
+	UploadBytes.WithLabelValues(mime).Add(float64(size))
+}
+
+// ObserveLogin records a sign-in attempt
+func ObserveLogin(success bool, reason string) {
+	result := "failure"
+	if success {
+		result = "success"
+	}
+	if reason == "" {
+		reason = "password"
+	}
+	LoginAttempts.WithLabelValues(result, reason).Inc()
+}
+
+// ObserveJob records one run of a background job that processed items
+func ObserveJob(job string, started time.Time, items int, err error) {
+	JobDuration.WithLabelValues(job).Observe(time.Since(started).Seconds())
+	JobItems.WithLabelValues(job).Add(float64(items))
+	if err != nil {
+		JobRuns.WithLabelValues(job, "error").Inc()
+		return
+	}
+	JobRuns.WithLabelValues(job, "success").Inc()
+	JobLastSuccess.WithLabelValues(job).Set(float64(time.Now().Unix()))
+}
+
+// RegisterDBStats exposes the connection pool statistics of db
+func RegisterDBStats(db *sql.DB) {
+	stat := func(fn func(s sql.DBStats) float64) func() float64 {
+		return func() float64 { return fn(db.Stats()) }
+	}
+	NewGaugeFunc("courseai_db_pool_max_open_connections", "Maximum number of open connections to the database.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }))
+	NewGaugeFunc("courseai_db_pool_open_connections", "Established connections, in use and idle.",
+		stat(func(s sql.DBStats) float64 { return float64(s.OpenConnections) }))
+	NewGaugeFunc("courseai_db_pool_in_use_connections", "Connections currently in use.",
+		stat(func(s sql.DBStats) float64 { return float64(s.InUse) }))
+	NewGaugeFunc("courseai_db_pool_idle_connections", "Idle connections.",
+		stat(func(s sql.DBStats) float64 { return float64(s.Idle) }))
+	NewCounterFunc("courseai_db_pool_wait_count_total", "Connections waited for.",
+		stat(func(s sql.DBStats) float64 { return float64(s.WaitCount) }))
+	NewCounterFunc("courseai_db_pool_wait_duration_seconds_total", "Time spent waiting for a connection.",
+		stat(func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }))
+	NewCounterFunc("courseai_db_pool_max_idle_closed_total", "Connections closed because the idle pool was full.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }))
+	NewCounterFunc("courseai_db_pool_max_lifetime_closed_total", "Connections closed because they reached their maximum lifetime.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }))
+}
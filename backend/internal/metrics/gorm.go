@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GormPlugin times every GORM statement into DBQueryDuration
+type GormPlugin struct{}
+
+func (GormPlugin) Name() string { return "courseai:metrics" }
+
+const startedKey = "courseai:metrics_started"
+
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	before := func(db *gorm.DB) {
+		db.InstanceSet(startedKey, time.Now())
+	}
+	after := func(operation string) func(*gorm.DB) {
+		return func(db *gorm.DB) {
+			v, ok := db.InstanceGet(startedKey)
+			if !ok {
+				return
+			}
+			started, ok := v.(time.Time)
+			if !ok {
+				return
+			}
+			status := "ok"
+			if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+				status = "error"
+			}
+			DBQueryDuration.WithLabelValues(operation, status).Observe(time.Since(started).Seconds())
+		}
+	}
+
+	cb := db.Callback()
+	for _, err := range []error{
+		cb.Create().Before("gorm:create").Register("metrics:before_create", before),
+		cb.Create().After("gorm:create").Register("metrics:after_create", after("create")),
+		cb.Query().Before("gorm:query").Register("metrics:before_query", before),
+		cb.Query().After("gorm:query").Register("metrics:after_query", after("query")),
+		cb.Update().Before("gorm:update").Register("metrics:before_update", before),
+		cb.Update().After("gorm:update").Register("metrics:after_update", after("update")),
+		cb.Delete().Before("gorm:delete").Register("metrics:before_delete", before),
+		cb.Delete().After("gorm:delete").Register("metrics:after_delete", after("delete")),
+		cb.Row().Before("gorm:row").Register("metrics:before_row", before),
+		cb.Row().After("gorm:row").Register("metrics:after_row", after("row")),
+		cb.Raw().Before("gorm:raw").Register("metrics:before_raw", before),
+		cb.Raw().After("gorm:raw").Register("metrics:after_raw", after("raw")),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
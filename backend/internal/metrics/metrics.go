@@ -0,0 +1,62 @@
+// Package metrics defines the backend's Prometheus metrics with the official
+// client library and serves them with promhttp. Everything is registered with
+// Default, next to the Go runtime and process collectors.
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Default is the registry served on /metrics
+var Default = prometheus.NewRegistry()
+
+// factory registers the metrics below with Default
+var factory = promauto.With(Default)
+
+func init() {
+	Default.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// NewGaugeFunc registers a gauge whose value is fn's result at scrape time
+func NewGaugeFunc(name, help string, fn func() float64) {
+	factory.NewGaugeFunc(prometheus.GaugeOpts{Name: name, Help: help}, fn)
+}
+
+// NewCounterFunc registers a counter whose value is fn's result at scrape time
+func NewCounterFunc(name, help string, fn func() float64) {
+	factory.NewCounterFunc(prometheus.CounterOpts{Name: name, Help: help}, fn)
+}
+
+// Handler serves Default for net/http, e.g. on a separate admin port. A
+// non-empty token must be sent as "Authorization: Bearer <token>".
+func Handler(token string) http.Handler {
+	metrics := promhttp.HandlerFor(Default, promhttp.HandlerOpts{})
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !Authorized(req.Header.Get("Authorization"), token) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		metrics.ServeHTTP(w, req)
+	})
+}
+
+// Authorized reports whether an Authorization header carries token; any
+// header is accepted when token is empty
+func Authorized(header, token string) bool {
+	if token == "" {
+		return true
+	}
+	given, ok := strings.CutPrefix(header, "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
@@ -1,23 +1,28 @@
 package middleware
 
 import (
-	"courseai/backend/internal/database"
 	"courseai/backend/internal/models"
+	"courseai/backend/internal/service"
 	"courseai/backend/internal/utils"
 	"strings"
-	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 )
 
 type AuthMiddleware struct {
-	JWTSecret string
+	JWTSecret     string
+	Users         *service.UserService
+	Organizations *service.OrganizationService
+	Assignments   *service.AssignmentService
 }
 
-func NewAuthMiddleware(jwtSecret string) *AuthMiddleware {
+func NewAuthMiddleware(jwtSecret string, users *service.UserService, organizations *service.OrganizationService, assignments *service.AssignmentService) *AuthMiddleware {
 	return &AuthMiddleware{
-		JWTSecret: jwtSecret,
+		JWTSecret:     jwtSecret,
+		Users:         users,
+		Organizations: organizations,
+		Assignments:   assignments,
 	}
 }
 
@@ -46,10 +51,21 @@ func (am *AuthMiddleware) Protected() fiber.Handler {
 			})
 		}
 
+		// Role and organization come from the database, not the token
+		p, err := am.loadPrincipal(c.UserContext(), claims.UserID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to load user",
+			})
+		}
+		if p == nil || !p.active() {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Account or organization is disabled",
+			})
+		}
+
 		// Store user info in context
-		c.Locals("user_id", claims.UserID)
-		c.Locals("username", claims.Username)
-		c.Locals("role", claims.Role)
+		setUserLocals(c, claims, p)
 
 		return c.Next()
 	}
@@ -67,6 +83,51 @@ func (am *AuthMiddleware) AdminOnly() fiber.Handler {
 	}
 }
 
+// RequirePermission rejects the request with 403 unless the caller's role grants
+// every listed permission. Mount it after Protected().
+func (am *AuthMiddleware) RequirePermission(perms ...models.Permission) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		for _, p := range perms {
+			if !HasPermission(c, p) {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"error":      "Access denied",
+					"permission": p,
+				})
+			}
+		}
+		return c.Next()
+	}
+}
+
+// RequireAnyPermission rejects the request with 403 unless the caller's role
+// grants at least one of perms, for routes whose handler checks the exact
+// permission per item. Mount it after Protected().
+func (am *AuthMiddleware) RequireAnyPermission(perms ...models.Permission) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		for _, p := range perms {
+			if HasPermission(c, p) {
+				return c.Next()
+			}
+		}
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":       "Access denied",
+			"permissions": perms,
+		})
+	}
+}
+
+// TokenFromQuery copies ?access_token= into the Authorization header. Browsers
+// cannot set headers on WebSocket handshakes, so mount it only for those routes,
+// ahead of TokenOptional.
+func (am *AuthMiddleware) TokenFromQuery() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if token := c.Query("access_token"); token != "" && c.Get("Authorization") == "" {
+			c.Request().Header.Set("Authorization", "Bearer "+token)
+		}
+		return c.Next()
+	}
+}
+
 // TokenOptional attempts to parse Authorization header and set user locals if present.
 // It does NOT return 401 when header is missing or invalid — it silently continues as unauthenticated.
 func (am *AuthMiddleware) TokenOptional() fiber.Handler {
@@ -86,17 +147,17 @@ func (am *AuthMiddleware) TokenOptional() fiber.Handler {
 			// ignore invalid token for optional middleware
 			return c.Next()
 		}
+		// disabled accounts continue as anonymous too
+		p, err := am.loadPrincipal(c.UserContext(), claims.UserID)
+		if err != nil || p == nil || !p.active() {
+			return c.Next()
+		}
 
-		c.Locals("user_id", claims.UserID)
-		c.Locals("username", claims.Username)
-		c.Locals("role", claims.Role)
+		setUserLocals(c, claims, p)
 
 		// If teacher, load active assignments into locals for handlers to use
-		if claims.Role == models.RoleTeacher {
-			db := database.GetDB()
-			var assigns []models.TeacherAssignment
-			now := time.Now().UTC()
-			if err := db.Where("teacher_id = ? AND status = ? AND (start_at IS NULL OR start_at <= ?) AND (end_at IS NULL OR end_at >= ?)", claims.UserID, models.AssignmentStatusActive, now, now).Find(&assigns).Error; err == nil {
+		if p.Role == models.RoleTeacher {
+			if assigns, err := am.Assignments.Current(c.UserContext(), claims.UserID); err == nil {
 				c.Locals("assignments", assigns)
 			}
 		}
@@ -105,6 +166,17 @@ func (am *AuthMiddleware) TokenOptional() fiber.Handler {
 	}
 }
 
+// setUserLocals stores who the caller is (from the token) and what they may
+// do (from the database)
+func setUserLocals(c *fiber.Ctx, claims *utils.Claims, p *principal) {
+	c.Locals("user_id", claims.UserID)
+	c.Locals("username", claims.Username)
+	c.Locals("role", p.Role)
+	if p.OrganizationID != nil {
+		c.Locals("organization_id", *p.OrganizationID)
+	}
+}
+
 // Helper functions to get user info from context
 func GetUserID(c *fiber.Ctx) uuid.UUID {
 	userID, ok := c.Locals("user_id").(uuid.UUID)
@@ -129,3 +201,8 @@ func GetUserRole(c *fiber.Ctx) models.UserRole {
 	}
 	return role
 }
+
+// HasPermission reports whether the current user's role grants p
+func HasPermission(c *fiber.Ctx, p models.Permission) bool {
+	return GetUserRole(c).HasPermission(p)
+}
@@ -1,149 +1,132 @@
 package middleware
 
 import (
-	"time"
-
+	"context"
 	"courseai/backend/internal/database"
 	"courseai/backend/internal/models"
+	"courseai/backend/internal/service"
+	"courseai/backend/internal/telemetry"
+	"errors"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// TeacherAssignedProgramIDs returns program IDs the teacher is assigned to (active)
-func TeacherAssignedProgramIDs(userID uuid.UUID) ([]uuid.UUID, error) {
-	db := database.GetDB()
-	var assigns []models.TeacherAssignment
-	now := time.Now().UTC()
-	if err := db.Where("teacher_id = ? AND status = ? AND scope_level = ? AND (start_at IS NULL OR start_at <= ?) AND (end_at IS NULL OR end_at >= ?)", userID, models.AssignmentStatusActive, models.ScopeProgram, now, now).Find(&assigns).Error; err != nil {
-		return nil, err
-	}
-	var ids []uuid.UUID
-	for _, a := range assigns {
-		if a.ProgramID != nil {
-			ids = append(ids, *a.ProgramID)
-		}
-	}
-	return ids, nil
+// Authorizer applies the assignment rules of service.AssignmentService to
+// the current request
+type Authorizer struct {
+	Assignments *service.AssignmentService
 }
 
-// TeacherAssignedSubcourseIDs returns subcourse IDs assigned to teacher (active)
-func TeacherAssignedSubcourseIDs(userID uuid.UUID) ([]uuid.UUID, error) {
-	db := database.GetDB()
-	// If the teacher has active program-level assignments, program scope takes precedence
-	progIDs, err := TeacherAssignedProgramIDs(userID)
-	if err != nil {
-		return nil, err
-	}
-	if len(progIDs) > 0 {
-		return []uuid.UUID{}, nil
-	}
+func NewAuthorizer(assignments *service.AssignmentService) *Authorizer {
+	return &Authorizer{Assignments: assignments}
+}
 
-	var assigns []models.TeacherAssignment
-	now := time.Now().UTC()
-	if err := db.Where("teacher_id = ? AND status = ? AND scope_level = ? AND (start_at IS NULL OR start_at <= ?) AND (end_at IS NULL OR end_at >= ?)", userID, models.AssignmentStatusActive, models.ScopeSubcourse, now, now).Find(&assigns).Error; err != nil {
-		return nil, err
-	}
-	var ids []uuid.UUID
-	for _, a := range assigns {
-		if a.SubcourseID != nil {
-			ids = append(ids, *a.SubcourseID)
-		}
+// Actor describes the caller to the domain services
+func Actor(c *fiber.Ctx) service.Actor {
+	return service.Actor{
+		UserID:   GetUserID(c),
+		Username: GetUsername(c),
+		ScopeAll: HasPermission(c, models.PermScopeAll),
+		Publish:  HasPermission(c, models.PermLessonPublish),
 	}
-	return ids, nil
 }
 
-// CanAccessProgram enforces that the current user can access the program
-func CanAccessProgram(c *fiber.Ctx, programID uuid.UUID) error {
-	role := GetUserRole(c)
-	if role == models.RoleAdmin {
-		return nil
-	}
-	// only teachers left
-	userID := GetUserID(c)
-	ids, err := TeacherAssignedProgramIDs(userID)
-	if err != nil {
-		return fiber.ErrInternalServerError
-	}
-	for _, id := range ids {
-		if id == programID {
-			return nil
-		}
-	}
-	return fiber.NewError(fiber.StatusForbidden, "Access to program denied")
+const assignmentMemoKey = "assignment_memo"
+
+// TenantContext returns the request context restricted to the request's
+// organization, for repositories and services. The caller's assignments are
+// looked up once per request.
+func TenantContext(c *fiber.Ctx) context.Context {
+	memo, ok := c.Locals(assignmentMemoKey).(*service.AssignmentMemo)
+	if !ok {
+		memo = &service.AssignmentMemo{}
+		c.Locals(assignmentMemoKey, memo)
+	}
+	ctx := service.WithAssignmentMemo(c.UserContext(), memo)
+	if tenantID, ok := GetTenantID(c); ok {
+		return database.WithTenant(ctx, tenantID)
+	}
+	return ctx
 }
 
-// CanAccessSubcourse enforces access to a subcourse: either assigned subcourse or assigned program
-func CanAccessSubcourse(c *fiber.Ctx, subcourseID uuid.UUID) error {
-	role := GetUserRole(c)
-	if role == models.RoleAdmin {
-		return nil
-	}
-	userID := GetUserID(c)
+// ServiceError turns an error of a domain service into an HTTP error
+func ServiceError(err error) error {
+	var e *service.Error
+	if !errors.As(err, &e) {
+		return err
+	}
+	switch e.Kind {
+	case service.KindInvalid:
+		return fiber.NewError(fiber.StatusBadRequest, e.Message)
+	case service.KindNotFound:
+		return fiber.NewError(fiber.StatusNotFound, e.Message)
+	case service.KindForbidden:
+		return fiber.NewError(fiber.StatusForbidden, e.Message)
+	case service.KindTooLarge:
+		return fiber.NewError(fiber.StatusRequestEntityTooLarge, e.Message)
+	case service.KindConflict:
+		return fiber.NewError(fiber.StatusConflict, e.Message)
+	case service.KindUnprocessable:
+		return fiber.NewError(fiber.StatusUnprocessableEntity, e.Message)
+	}
+	return fiber.NewError(fiber.StatusInternalServerError, e.Message)
+}
 
-	// check direct subcourse assignment
-	subIds, err := TeacherAssignedSubcourseIDs(userID)
-	if err != nil {
-		return fiber.ErrInternalServerError
-	}
-	for _, id := range subIds {
-		if id == subcourseID {
-			return nil
-		}
+// accessError reports a failed access check; lookups that failed are internal
+func accessError(err error) error {
+	var e *service.Error
+	if errors.As(err, &e) {
+		return ServiceError(err)
 	}
+	return fiber.ErrInternalServerError
+}
 
-	// check program assignment matching subcourse's program
-	db := database.GetDB()
-	var sc models.Subcourse
-	if err := db.First(&sc, "id = ?", subcourseID).Error; err != nil {
-		return fiber.NewError(fiber.StatusNotFound, "Subcourse not found")
-	}
-	progIds, err := TeacherAssignedProgramIDs(userID)
-	if err != nil {
-		return fiber.ErrInternalServerError
-	}
-	for _, pid := range progIds {
-		if pid == sc.ProgramID {
-			return nil
-		}
-	}
-	return fiber.NewError(fiber.StatusForbidden, "Access to subcourse denied")
+// AssignedProgramIDs returns the programs the caller is assigned to (active)
+func (a *Authorizer) AssignedProgramIDs(c *fiber.Ctx) ([]uuid.UUID, error) {
+	return a.Assignments.ProgramIDs(TenantContext(c), GetUserID(c))
+}
+
+// AssignedSubcourseIDs returns the subcourses the caller is assigned to
+// (active); empty when program assignments take precedence
+func (a *Authorizer) AssignedSubcourseIDs(c *fiber.Ctx) ([]uuid.UUID, error) {
+	return a.Assignments.SubcourseIDs(TenantContext(c), GetUserID(c))
+}
+
+// CanAccessProgram enforces that the current user can access the program
+func (a *Authorizer) CanAccessProgram(c *fiber.Ctx, programID uuid.UUID) error {
+	return a.check(c, "authz.CanAccessProgram", "program.id", programID, a.Assignments.CanAccessProgram)
+}
+
+// CanAccessSubcourse enforces access to a subcourse: either assigned subcourse or assigned program
+func (a *Authorizer) CanAccessSubcourse(c *fiber.Ctx, subcourseID uuid.UUID) error {
+	return a.check(c, "authz.CanAccessSubcourse", "subcourse.id", subcourseID, a.Assignments.CanAccessSubcourse)
 }
 
 // CanAccessLesson enforces access to a lesson based on its subcourse/program
-func CanAccessLesson(c *fiber.Ctx, lessonID uuid.UUID) error {
-	role := GetUserRole(c)
-	if role == models.RoleAdmin {
-		return nil
-	}
-	userID := GetUserID(c)
+func (a *Authorizer) CanAccessLesson(c *fiber.Ctx, lessonID uuid.UUID) error {
+	return a.check(c, "authz.CanAccessLesson", "lesson.id", lessonID, a.Assignments.CanAccessLesson)
+}
 
-	db := database.GetDB()
-	var lesson models.Lesson
-	if err := db.Preload("Subcourse").First(&lesson, "id = ?", lessonID).Error; err != nil {
-		return fiber.NewError(fiber.StatusNotFound, "Lesson not found")
-	}
-	// check subcourse assignment
-	subIds, err := TeacherAssignedSubcourseIDs(userID)
+// check runs one access check in its own span, so slow assignment lookups
+// show up in traces
+func (a *Authorizer) check(c *fiber.Ctx, name, key string, id uuid.UUID, can func(context.Context, service.Actor, uuid.UUID) error) error {
+	actor := Actor(c)
+	ctx, span := telemetry.Start(TenantContext(c), name, trace.WithAttributes(
+		attribute.String(key, id.String()),
+		attribute.Bool("authz.scope_all", actor.ScopeAll),
+	))
+	defer span.End()
+	err := can(ctx, actor, id)
+	span.SetAttributes(attribute.Bool("authz.allowed", err == nil))
 	if err != nil {
-		return fiber.ErrInternalServerError
-	}
-	for _, id := range subIds {
-		if lesson.SubcourseID == id {
-			return nil
-		}
-	}
-	// check program assignment matching lesson's subcourse.program_id
-	progIds, err := TeacherAssignedProgramIDs(userID)
-	if err != nil {
-		return fiber.ErrInternalServerError
-	}
-	if lesson.Subcourse != nil {
-		for _, pid := range progIds {
-			if pid == lesson.Subcourse.ProgramID {
-				return nil
-			}
+		var e *service.Error
+		if !errors.As(err, &e) {
+			telemetry.RecordError(span, err)
 		}
+		return accessError(err)
 	}
-	return fiber.NewError(fiber.StatusForbidden, "Access to lesson denied")
+	return nil
 }
@@ -0,0 +1,179 @@
+package middleware
+
+import (
+	"courseai/backend/internal/cache"
+	"courseai/backend/internal/metrics"
+	"courseai/backend/internal/models"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// CachePolicy is the Cache-Control of a cached route for anonymous callers.
+// Signed-in callers always revalidate, so editors see their changes at once.
+type CachePolicy struct {
+	// MaxAge is how long browsers and CDNs may reuse a response unchecked
+	MaxAge time.Duration
+	// StaleWhileRevalidate is how long after that they may still serve it
+	// while fetching a fresh copy
+	StaleWhileRevalidate time.Duration
+}
+
+func (p CachePolicy) header() string {
+	h := fmt.Sprintf("public, max-age=%d", int(p.MaxAge.Seconds()))
+	if p.StaleWhileRevalidate > 0 {
+		h += fmt.Sprintf(", stale-while-revalidate=%d", int(p.StaleWhileRevalidate.Seconds()))
+	}
+	return h
+}
+
+const cacheTagsKey = "cache_tags"
+
+// VersionHeader carries the version of a versioned record, the value to send
+// back in If-Match. Cached responses need it besides the ETag: their ETag
+// is a hash of the body, which also changes with the nested records the
+// record's version does not cover.
+const VersionHeader = "X-Version"
+
+// ResponseCache serves read-only catalog routes from serialized responses.
+// Every response gets a strong ETag (a hash of the body) and Last-Modified,
+// and conditional requests are answered 304; the version of a record stays
+// in VersionHeader. With a cache, responses are
+// stored per tenant, path and query and reused until the content they were
+// built from changes; without one (nil) only the validators are added.
+type ResponseCache struct {
+	cache *cache.Cache
+}
+
+func NewResponseCache(c *cache.Cache) *ResponseCache {
+	return &ResponseCache{cache: c}
+}
+
+// CacheTags records what the response being built contains, see the cache
+// package; outside cached routes it does nothing
+func CacheTags(c *fiber.Ctx, tags ...string) {
+	if collected, ok := c.Locals(cacheTagsKey).(*[]string); ok {
+		*collected = append(*collected, tags...)
+	}
+}
+
+// Route caches the responses of one route under policy. Teachers only see
+// their assignments, so their requests bypass the cache.
+func (rc *ResponseCache) Route(policy CachePolicy) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		route := c.Route().Path
+		if GetUserRole(c) == models.RoleTeacher {
+			metrics.CacheRequests.WithLabelValues(route, "bypass").Inc()
+			c.Set(fiber.HeaderCacheControl, "private, no-cache")
+			return c.Next()
+		}
+
+		cacheControl := policy.header()
+		if GetUserID(c) != uuid.Nil {
+			cacheControl = "private, no-cache"
+		}
+		ctx := c.UserContext()
+		key := cacheKey(c)
+		if e := rc.cache.Get(ctx, key); e != nil {
+			metrics.CacheRequests.WithLabelValues(route, "hit").Inc()
+			c.Set("X-Cache", "HIT")
+			return serveEntry(c, e, cacheControl)
+		}
+
+		ticket := rc.cache.Begin()
+		var tags []string
+		c.Locals(cacheTagsKey, &tags)
+		if err := c.Next(); err != nil {
+			return err
+		}
+		if c.Response().StatusCode() != fiber.StatusOK {
+			return nil
+		}
+		if rc.cache != nil {
+			metrics.CacheRequests.WithLabelValues(route, "miss").Inc()
+			c.Set("X-Cache", "MISS")
+		}
+
+		if tenantID, ok := GetTenantID(c); ok {
+			tags = append(tags, cache.TenantTag(tenantID))
+		}
+		body := append([]byte(nil), c.Response().Body()...)
+		e := &cache.Entry{
+			Body:         body,
+			ContentType:  string(c.Response().Header.ContentType()),
+			ETag:         cache.ETag(body),
+			Version:      string(c.Response().Header.Peek(VersionHeader)),
+			LastModified: time.Now().UTC().Truncate(time.Second),
+			Tags:         uniqueStrings(tags),
+		}
+		rc.cache.Put(ctx, key, e, ticket)
+		return serveEntry(c, e, cacheControl)
+	}
+}
+
+// cacheKey identifies a response: the tenant, the path and the query with
+// its parameters sorted
+func cacheKey(c *fiber.Ctx) string {
+	tenant := "-"
+	if tenantID, ok := GetTenantID(c); ok {
+		tenant = tenantID.String()
+	}
+	query, _ := url.ParseQuery(string(c.Request().URI().QueryString()))
+	return tenant + " " + c.Path() + "?" + query.Encode()
+}
+
+// serveEntry answers with e, or 304 when the request's validators match it
+func serveEntry(c *fiber.Ctx, e *cache.Entry, cacheControl string) error {
+	c.Set(fiber.HeaderETag, e.ETag)
+	if e.Version != "" {
+		c.Set(VersionHeader, e.Version)
+	}
+	c.Set(fiber.HeaderLastModified, e.LastModified.Format(http.TimeFormat))
+	c.Set(fiber.HeaderCacheControl, cacheControl)
+	// the tenant and the caller's role select what a response contains
+	c.Vary(fiber.HeaderAuthorization, TenantHeader)
+	if notModified(c, e) {
+		c.Response().ResetBody()
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+	c.Status(fiber.StatusOK)
+	c.Set(fiber.HeaderContentType, e.ContentType)
+	c.Response().SetBody(e.Body)
+	return nil
+}
+
+// notModified evaluates If-None-Match, or If-Modified-Since without it
+// (RFC 9110, section 13.2.2)
+func notModified(c *fiber.Ctx, e *cache.Entry) bool {
+	if header := c.Get(fiber.HeaderIfNoneMatch); header != "" {
+		for _, tag := range strings.Split(header, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == e.ETag {
+				return true
+			}
+		}
+		return false
+	}
+	if header := c.Get(fiber.HeaderIfModifiedSince); header != "" {
+		since, err := http.ParseTime(header)
+		return err == nil && !e.LastModified.After(since)
+	}
+	return false
+}
+
+func uniqueStrings(values []string) []string {
+	sort.Strings(values)
+	out := values[:0]
+	for i, v := range values {
+		if i == 0 || v != values[i-1] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"bytes"
+	"courseai/backend/internal/logging"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the request ID in both directions
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID tags every request with an ID: the caller's X-Request-ID when it
+// is a sensible token, otherwise a new UUID. The ID is echoed in the response
+// header, attached to the user context (so GORM and service logs carry it)
+// and added to JSON error bodies.
+func RequestID() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		c.Locals("request_id", id)
+		c.Set(RequestIDHeader, id)
+		c.SetUserContext(logging.WithRequestID(c.UserContext(), id))
+
+		err := c.Next()
+		if err == nil && c.Response().StatusCode() >= fiber.StatusBadRequest {
+			addRequestID(c, id)
+		}
+		return err
+	}
+}
+
+// GetRequestID returns the ID RequestID assigned to the request
+func GetRequestID(c *fiber.Ctx) string {
+	id, _ := c.Locals("request_id").(string)
+	return id
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// addRequestID adds "request_id" to an error body written by a handler,
+// e.g. {"error": "Lesson not found", "request_id": "..."}
+func addRequestID(c *fiber.Ctx, id string) {
+	if !bytes.HasPrefix(c.Response().Header.ContentType(), []byte(fiber.MIMEApplicationJSON)) {
+		return
+	}
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(c.Response().Body(), &body); err != nil {
+		return
+	}
+	if _, ok := body["error"]; !ok {
+		return
+	}
+	if _, ok := body["request_id"]; ok {
+		return
+	}
+	body["request_id"], _ = json.Marshal(id)
+	if out, err := json.Marshal(body); err == nil {
+		c.Response().SetBodyRaw(out)
+	}
+}
+
+// ErrorHandler answers errors returned by handlers with
+// {"error": message, "request_id": id}; unexpected errors are logged
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	code := fiber.StatusInternalServerError
+	var e *fiber.Error
+	if errors.As(err, &e) {
+		code = e.Code
+	} else {
+		slog.ErrorContext(c.UserContext(), "unhandled error", "method", c.Method(), "path", c.Path(), "error", err)
+	}
+	return c.Status(code).JSON(fiber.Map{
+		"error":      err.Error(),
+		"request_id": GetRequestID(c),
+	})
+}
+
+// probePaths are logged at debug level while they succeed
+var probePaths = map[string]bool{"/health": true, "/livez": true, "/readyz": true}
+
+// AccessLog logs one record per request: server errors at error level,
+// client errors at warn, successful probes at debug and everything else at info
+func AccessLog() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			// the error handler has not written the response yet
+			status = fiber.StatusInternalServerError
+			var e *fiber.Error
+			if errors.As(err, &e) {
+				status = e.Code
+			}
+		}
+		level := slog.LevelInfo
+		switch {
+		case status >= fiber.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= fiber.StatusBadRequest:
+			level = slog.LevelWarn
+		case probePaths[c.Path()]:
+			// load balancers and orchestrators poll these every few seconds
+			level = slog.LevelDebug
+		}
+		attrs := []slog.Attr{
+			slog.String("method", c.Method()),
+			slog.String("path", c.Path()),
+			slog.Int("status", status),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("ip", c.IP()),
+			slog.Int("bytes", len(c.Response().Body())),
+		}
+		if userID := GetUserID(c); userID != uuid.Nil {
+			attrs = append(attrs, slog.String("user_id", userID.String()))
+		}
+		slog.LogAttrs(c.UserContext(), level, "request", attrs...)
+		return err
+	}
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"courseai/backend/internal/metrics"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+)
+
+// Metrics counts and times every request by its route template
+func Metrics() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		metrics.HTTPInFlight.Inc()
+		defer metrics.HTTPInFlight.Dec()
+
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			var e *fiber.Error
+			if errors.As(err, &e) {
+				status = e.Code
+			}
+		}
+		// the matched route, e.g. /api/admin/lessons/:id; requests no route
+		// matched are reported together
+		route := c.Route().Path
+		if status == fiber.StatusNotFound && route == "/" && c.Path() != "/" {
+			route = "unmatched"
+		}
+		labels := []string{c.Method(), route, strconv.Itoa(status)}
+		metrics.HTTPRequests.WithLabelValues(labels...).Inc()
+		metrics.HTTPDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+// MetricsEndpoint serves the metrics on the API port. Without a token the
+// endpoint is not offered there; use the admin port instead.
+func MetricsEndpoint(token string) fiber.Handler {
+	serve := adaptor.HTTPHandler(metrics.Handler(token))
+	return func(c *fiber.Ctx) error {
+		if token == "" {
+			return fiber.ErrNotFound
+		}
+		if !metrics.Authorized(c.Get(fiber.HeaderAuthorization), token) {
+			c.Set(fiber.HeaderWWWAuthenticate, `Bearer realm="metrics"`)
+			return fiber.ErrUnauthorized
+		}
+		return serve(c)
+	}
+}
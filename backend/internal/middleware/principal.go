@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"context"
+	"courseai/backend/internal/models"
+	"courseai/backend/internal/repository"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// principalTTL is how long a caller's role, status and organization are
+// reused; on other replicas a demotion or suspension applies after this long
+const principalTTL = 10 * time.Second
+
+// maxPrincipals bounds the cache; past it the cache starts over
+const maxPrincipals = 10000
+
+// principal is the caller as the database has it now. Tokens are only
+// trusted for who the caller is, so role changes, deactivations and
+// organization moves apply without waiting for the token to expire.
+type principal struct {
+	repository.Principal
+	loaded time.Time
+}
+
+// active reports whether the caller may still use the API
+func (p *principal) active() bool {
+	if p.Status != models.StatusActive {
+		return false
+	}
+	return p.OrganizationID == nil || (p.OrgStatus != nil && *p.OrgStatus == models.OrgStatusActive)
+}
+
+var principals = struct {
+	mu sync.Mutex
+	m  map[uuid.UUID]*principal
+}{m: map[uuid.UUID]*principal{}}
+
+// loadPrincipal returns the caller's current role, status and organization,
+// nil when the user no longer exists
+func (am *AuthMiddleware) loadPrincipal(ctx context.Context, userID uuid.UUID) (*principal, error) {
+	principals.mu.Lock()
+	p, ok := principals.m[userID]
+	principals.mu.Unlock()
+	if ok && time.Since(p.loaded) < principalTTL {
+		return p, nil
+	}
+
+	loaded, err := am.Users.Principal(ctx, userID)
+	if err != nil || loaded == nil {
+		return nil, err
+	}
+	p = &principal{Principal: *loaded, loaded: time.Now()}
+
+	principals.mu.Lock()
+	if len(principals.m) >= maxPrincipals {
+		principals.m = map[uuid.UUID]*principal{}
+	}
+	principals.m[userID] = p
+	principals.mu.Unlock()
+	return p, nil
+}
+
+// ForgetPrincipal drops the cached role and status of a user, so a change
+// applies to their next request on this replica
+func ForgetPrincipal(userID uuid.UUID) {
+	principals.mu.Lock()
+	delete(principals.m, userID)
+	principals.mu.Unlock()
+}
+
+// ForgetPrincipals drops every cached principal, e.g. after an organization
+// was suspended
+func ForgetPrincipals() {
+	principals.mu.Lock()
+	principals.m = map[uuid.UUID]*principal{}
+	principals.mu.Unlock()
+}
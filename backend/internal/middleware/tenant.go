@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"courseai/backend/internal/database"
+	"courseai/backend/internal/models"
+	"courseai/backend/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TenantHeader lets super-admins and anonymous visitors pick an organization
+// by ID or slug. Users bound to an organization cannot override their tenant.
+const TenantHeader = "X-Organization"
+
+// ResolveTenant determines the organization the request operates in and stores
+// it as the "tenant_id" local. Mount it after TokenOptional().
+//   - users bound to an organization (org admins, teachers, ...) always use the org from their JWT
+//   - super-admins and anonymous callers may select one with the X-Organization header;
+//     without it their queries are not tenant-filtered
+func (am *AuthMiddleware) ResolveTenant() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if orgID, ok := c.Locals("organization_id").(uuid.UUID); ok {
+			c.Locals("tenant_id", orgID)
+			return c.Next()
+		}
+
+		ref := c.Get(TenantHeader)
+		if ref == "" {
+			return c.Next()
+		}
+
+		org, err := am.Organizations.Resolve(c.UserContext(), ref)
+		if service.IsKind(err, service.KindNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Organization not found"})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to resolve organization"})
+		}
+		if org.Status != models.OrgStatusActive {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Organization is suspended"})
+		}
+		c.Locals("tenant_id", org.ID)
+		return c.Next()
+	}
+}
+
+// GetTenantID returns the organization the request is scoped to, if any
+func GetTenantID(c *fiber.Ctx) (uuid.UUID, bool) {
+	id, ok := c.Locals("tenant_id").(uuid.UUID)
+	return id, ok && id != uuid.Nil
+}
+
+// GetOrganizationID returns the organization the caller belongs to (nil for super-admins)
+func GetOrganizationID(c *fiber.Ctx) *uuid.UUID {
+	if id, ok := c.Locals("organization_id").(uuid.UUID); ok {
+		return &id
+	}
+	return nil
+}
+
+// TenantDB returns the database handle for this request: queries on
+// tenant-owned tables are automatically restricted to the request's organization.
+func TenantDB(c *fiber.Ctx) *gorm.DB {
+	return database.GetDB().WithContext(TenantContext(c))
+}
+
+// RequireOwnedByTenant rejects writes to content that another organization
+// shared with the tenant; shared programs and their children are read-only.
+func RequireOwnedByTenant(c *fiber.Ctx, organizationID *uuid.UUID) error {
+	tenantID, ok := GetTenantID(c)
+	if !ok {
+		return nil
+	}
+	if organizationID == nil || *organizationID != tenantID {
+		return fiber.NewError(fiber.StatusForbidden, "Shared content is read-only")
+	}
+	return nil
+}
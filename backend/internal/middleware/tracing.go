@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"courseai/backend/internal/telemetry"
+	"errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing starts a server span for every request, continuing the caller's
+// trace through the global propagator (W3C traceparent), as otelhttp does for
+// net/http servers. The span is named after the route template, e.g.
+// "GET /api/admin/lessons/:id", and its context is attached to the user
+// context so GORM statements and service calls become children.
+func Tracing() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !telemetry.Enabled() {
+			return c.Next()
+		}
+		ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), headerCarrier{c})
+		ctx, span := telemetry.Start(ctx, c.Method()+" "+c.Path(), trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+			attribute.String("http.request.method", c.Method()),
+			attribute.String("url.path", c.Path()),
+			attribute.String("client.address", c.IP()),
+			attribute.String("user_agent.original", c.Get(fiber.HeaderUserAgent)),
+			attribute.String("request_id", GetRequestID(c)),
+		))
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			var e *fiber.Error
+			if errors.As(err, &e) {
+				status = e.Code
+			}
+		}
+		route := c.Route().Path
+		span.SetName(c.Method() + " " + route)
+		span.SetAttributes(
+			attribute.String("http.route", route),
+			attribute.Int("http.response.status_code", status),
+		)
+		if userID := GetUserID(c); userID != uuid.Nil {
+			span.SetAttributes(attribute.String("enduser.id", userID.String()))
+		}
+		if status >= fiber.StatusInternalServerError {
+			if err != nil {
+				telemetry.RecordError(span, err)
+			} else {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+		}
+		return err
+	}
+}
+
+// headerCarrier reads the propagation headers of a request
+type headerCarrier struct{ c *fiber.Ctx }
+
+var _ propagation.TextMapCarrier = headerCarrier{}
+
+func (h headerCarrier) Get(key string) string { return h.c.Get(key) }
+
+func (h headerCarrier) Set(key, value string) { h.c.Request().Header.Set(key, value) }
+
+func (h headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(h.c.GetReqHeaders()))
+	for key := range h.c.GetReqHeaders() {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -9,25 +9,31 @@ import (
 )
 
 type Lesson struct {
-	ID          uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
-	SubcourseID uuid.UUID      `gorm:"type:uuid;not null;index" json:"subcourse_id"`
-	Title       string         `gorm:"not null" json:"title"`
-	Subtitle    string         `gorm:"type:text" json:"subtitle"`
-	Overview    string         `gorm:"type:text" json:"overview"`
-	BlockTypes  datatypes.JSON `gorm:"type:jsonb" json:"block_types"`
-	Status      ContentStatus  `gorm:"type:varchar(20);not null;default:'draft'" json:"status"`
-	SortOrder   int            `gorm:"default:0" json:"sort_order"`
+	ID          uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	SubcourseID uuid.UUID `gorm:"type:uuid;not null;index:idx_lessons_subcourse_sort,priority:1" json:"subcourse_id"`
+	// OrganizationID is copied from the subcourse
+	OrganizationID *uuid.UUID     `gorm:"type:uuid;index" json:"organization_id,omitempty"`
+	Title          string         `gorm:"not null" json:"title"`
+	Subtitle       string         `gorm:"type:text" json:"subtitle"`
+	Overview       string         `gorm:"type:text" json:"overview"`
+	BlockTypes     datatypes.JSON `gorm:"type:jsonb" json:"block_types"`
+	Status         ContentStatus  `gorm:"type:varchar(20);not null;default:'draft'" json:"status"`
+	SortOrder      int            `gorm:"default:0;index:idx_lessons_subcourse_sort,priority:2" json:"sort_order"`
+	Version        int            `gorm:"not null;default:1" json:"version"` // bumped on every write; backs ETag / If-Match
 	// Additional metadata
-	DurationMinutes int        `gorm:"default:0" json:"duration_minutes"`
-	Difficulty      string     `gorm:"type:varchar(50)" json:"difficulty"`
-	EstimatedTime   string     `gorm:"type:varchar(50)" json:"estimated_time"`
-	CoverMediaID    *uuid.UUID `gorm:"type:uuid;index" json:"cover_media_id,omitempty"`
-	AuthorID        *uuid.UUID `gorm:"type:uuid;index" json:"author_id,omitempty"`
-	IsFeatured      bool       `gorm:"default:false" json:"is_featured"`
-	PublishedAt     *time.Time `json:"published_at,omitempty"`
-	Slug            string     `gorm:"uniqueIndex;not null" json:"slug"`
-	CreatedAt       time.Time  `json:"created_at"`
-	UpdatedAt       time.Time  `json:"updated_at"`
+	DurationMinutes int            `gorm:"default:0" json:"duration_minutes"`
+	Difficulty      string         `gorm:"type:varchar(50)" json:"difficulty"`
+	EstimatedTime   string         `gorm:"type:varchar(50)" json:"estimated_time"`
+	CoverMediaID    *uuid.UUID     `gorm:"type:uuid;index" json:"cover_media_id,omitempty"`
+	AuthorID        *uuid.UUID     `gorm:"type:uuid;index" json:"author_id,omitempty"`
+	IsFeatured      bool           `gorm:"default:false" json:"is_featured"`
+	PublishedAt     *time.Time     `json:"published_at,omitempty"`
+	MediaCount      int            `gorm:"not null;default:0" json:"media_count"` // media of the lesson and its components, maintained by the server
+	Slug            string         `gorm:"uniqueIndex;not null" json:"slug"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"` // set while the lesson sits in the trash
+	DeletedBy       *uuid.UUID     `gorm:"type:uuid" json:"deleted_by,omitempty"`
 
 	// Relations
 	Subcourse     *Subcourse           `gorm:"foreignKey:SubcourseID;references:ID" json:"subcourse,omitempty"`
@@ -46,5 +52,6 @@ func (l *Lesson) BeforeCreate(tx *gorm.DB) error {
 	if l.ID == uuid.Nil {
 		l.ID = uuid.New()
 	}
+	l.Version = 1
 	return nil
 }
@@ -32,6 +32,7 @@ type LessonModel struct {
 	LessonID    uuid.UUID `gorm:"type:uuid;not null;index" json:"lesson_id"`
 	Title       string    `gorm:"not null" json:"title"`
 	Description string    `gorm:"type:text" json:"description"`
+	SortOrder   int       `gorm:"default:0" json:"sort_order"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 
@@ -73,6 +74,9 @@ const (
 	BuildTypeImages BuildType = "images"
 )
 
+// BuildTypes lists the valid build types
+var BuildTypes = []BuildType{BuildTypePDF, BuildTypeImages}
+
 // LessonBuild - PDF or image slides for lesson
 type LessonBuild struct {
 	ID          uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
@@ -80,6 +84,7 @@ type LessonBuild struct {
 	BuildType   BuildType `gorm:"type:varchar(20);not null" json:"build_type"`
 	Title       string    `gorm:"not null" json:"title"`
 	Description string    `gorm:"type:text" json:"description"`
+	SortOrder   int       `gorm:"default:0" json:"sort_order"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 
@@ -172,6 +177,9 @@ const (
 	QuizTypeOpen     QuizType = "open"
 )
 
+// QuizTypes lists the valid quiz types
+var QuizTypes = []QuizType{QuizTypeSingle, QuizTypeMultiple, QuizTypeOpen}
+
 // LessonQuiz - Quiz questions for lesson
 type LessonQuiz struct {
 	ID          uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
@@ -179,6 +187,7 @@ type LessonQuiz struct {
 	Title       string    `gorm:"not null" json:"title"`
 	Description string    `gorm:"type:text" json:"description"`
 	QuizType    QuizType  `gorm:"type:varchar(20);not null" json:"quiz_type"`
+	SortOrder   int       `gorm:"default:0" json:"sort_order"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 
@@ -200,6 +209,7 @@ type LessonQuizOption struct {
 	Content     string    `gorm:"type:text;not null" json:"content"`
 	IsCorrect   bool      `gorm:"default:false" json:"is_correct"`
 	Explanation string    `gorm:"type:text" json:"explanation"`
+	SortOrder   int       `gorm:"default:0" json:"sort_order"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// LessonTemplate - Reusable lesson skeleton scoped to a program
+type LessonTemplate struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	ProgramID      uuid.UUID  `gorm:"type:uuid;not null;index" json:"program_id"`
+	OrganizationID *uuid.UUID `gorm:"type:uuid;index" json:"organization_id,omitempty"` // copied from the program
+	Name           string     `gorm:"not null" json:"name"`
+	Description    string     `gorm:"type:text" json:"description"`
+	// Blueprint is a LessonBlueprint; its text may contain template placeholders
+	Blueprint datatypes.JSON `gorm:"type:jsonb" json:"blueprint"`
+	CreatedBy *uuid.UUID     `gorm:"type:uuid" json:"created_by,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+
+	// Relations
+	Program *Program `gorm:"foreignKey:ProgramID;references:ID" json:"program,omitempty"`
+}
+
+func (lt *LessonTemplate) BeforeCreate(tx *gorm.DB) error {
+	if lt.ID == uuid.Nil {
+		lt.ID = uuid.New()
+	}
+	return nil
+}
+
+// Placeholders substituted into a blueprint when a lesson is created from it
+const (
+	PlaceholderTitle     = "{{title}}"
+	PlaceholderSubcourse = "{{subcourse}}"
+	PlaceholderProgram   = "{{program}}"
+)
+
+// LessonBlueprint - The partial lesson tree a template pre-populates.
+// IDs and media are not part of a blueprint.
+type LessonBlueprint struct {
+	Subtitle        string               `json:"subtitle,omitempty"`
+	Overview        string               `json:"overview,omitempty"`
+	BlockTypes      datatypes.JSON       `json:"block_types,omitempty"`
+	DurationMinutes int                  `json:"duration_minutes,omitempty"`
+	Difficulty      string               `json:"difficulty,omitempty"`
+	EstimatedTime   string               `json:"estimated_time,omitempty"`
+	Objectives      *LessonObjective     `json:"objectives,omitempty"`
+	Models          []LessonModel        `json:"models,omitempty"`
+	Preparation     *LessonPreparation   `json:"preparation,omitempty"`
+	Builds          []LessonBuild        `json:"builds,omitempty"`
+	ContentBlocks   []LessonContentBlock `json:"content_blocks,omitempty"`
+	Attachments     []LessonAttachment   `json:"attachments,omitempty"`
+	Challenges      []LessonChallenge    `json:"challenges,omitempty"`
+	Quizzes         []LessonQuiz         `json:"quizzes,omitempty"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ThrottleScope string
+
+const (
+	ThrottleScopeAccount ThrottleScope = "account"
+	ThrottleScopeIP      ThrottleScope = "ip"
+)
+
+// LoginAttempt - One row per login attempt (successful or not), kept as login history
+type LoginAttempt struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	UserID     *uuid.UUID `gorm:"type:uuid;index" json:"user_id,omitempty"`
+	Identifier string     `gorm:"type:varchar(255);not null" json:"identifier"`
+	IP         string     `gorm:"type:varchar(64);index" json:"ip"`
+	UserAgent  string     `gorm:"type:text" json:"user_agent"`
+	Success    bool       `gorm:"not null;default:false" json:"success"`
+	Reason     string     `gorm:"type:varchar(50)" json:"reason,omitempty"`
+	CreatedAt  time.Time  `gorm:"index" json:"created_at"`
+}
+
+func (la *LoginAttempt) BeforeCreate(tx *gorm.DB) error {
+	if la.ID == uuid.Nil {
+		la.ID = uuid.New()
+	}
+	return nil
+}
+
+// LoginThrottle - Failure counter for an account or an IP address.
+// Rows are keyed by "<scope>:<value>" so every replica shares the same state.
+type LoginThrottle struct {
+	Key           string        `gorm:"type:varchar(300);primary_key" json:"key"`
+	Scope         ThrottleScope `gorm:"type:varchar(20);not null" json:"scope"`
+	Failures      int           `gorm:"not null;default:0" json:"failures"`
+	LastFailureAt *time.Time    `json:"last_failure_at,omitempty"`
+	NextAttemptAt *time.Time    `json:"next_attempt_at,omitempty"`
+	LockedUntil   *time.Time    `json:"locked_until,omitempty"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+}
+
+// RecoveryCode - Single-use 2FA backup code; only the SHA-256 hash is stored
+type RecoveryCode struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	CodeHash  string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (rc *RecoveryCode) BeforeCreate(tx *gorm.DB) error {
+	if rc.ID == uuid.Nil {
+		rc.ID = uuid.New()
+	}
+	return nil
+}
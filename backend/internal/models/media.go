@@ -30,14 +30,23 @@ const (
 	PurposeOther   MediaPurpose = "other"
 )
 
+// MediaOwnerTypes lists the kinds of rows media can belong to
+var MediaOwnerTypes = []MediaOwnerType{
+	OwnerProgram, OwnerSubcourse, OwnerLesson, OwnerLessonModel, OwnerLessonPreparation,
+	OwnerLessonBuild, OwnerLessonContentBlock, OwnerLessonAttachment, OwnerLessonChallenge,
+}
+
+// MediaPurposes lists the valid media purposes
+var MediaPurposes = []MediaPurpose{PurposeCover, PurposeIntro, PurposeMain, PurposeGallery, PurposeSlide, PurposeOther}
+
 type Media struct {
 	ID        uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
-	OwnerType MediaOwnerType `gorm:"type:varchar(50);not null;index:idx_owner" json:"owner_type"`
-	OwnerID   uuid.UUID      `gorm:"type:uuid;not null;index:idx_owner" json:"owner_id"`
+	OwnerType MediaOwnerType `gorm:"type:varchar(50);not null;index:idx_media_owner_sort,priority:1" json:"owner_type"`
+	OwnerID   uuid.UUID      `gorm:"type:uuid;not null;index:idx_media_owner_sort,priority:2" json:"owner_id"`
 	URL       string         `gorm:"type:text;not null" json:"url"`
 	MimeType  string         `gorm:"type:varchar(100)" json:"mime_type"`
 	Purpose   MediaPurpose   `gorm:"type:varchar(50)" json:"purpose"`
-	SortOrder int            `gorm:"default:0" json:"sort_order"`
+	SortOrder int            `gorm:"default:0;index:idx_media_owner_sort,priority:3" json:"sort_order"`
 	Meta      datatypes.JSON `gorm:"type:jsonb" json:"meta"` // {width, height, duration, size, etc}
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type OrganizationStatus string
+
+const (
+	OrgStatusActive    OrganizationStatus = "active"
+	OrgStatusSuspended OrganizationStatus = "suspended"
+
+	// DefaultOrganizationSlug is the tenant existing data is moved into
+	DefaultOrganizationSlug = "default"
+)
+
+// OrganizationStatuses lists the valid organization statuses
+var OrganizationStatuses = []OrganizationStatus{OrgStatusActive, OrgStatusSuspended}
+
+// Organization - A school or learning center; the tenant boundary for users and content
+type Organization struct {
+	ID        uuid.UUID          `gorm:"type:uuid;primary_key" json:"id"`
+	Name      string             `gorm:"not null" json:"name"`
+	Slug      string             `gorm:"uniqueIndex;not null" json:"slug"`
+	Status    OrganizationStatus `gorm:"type:varchar(20);not null;default:'active'" json:"status"`
+	CreatedAt time.Time          `json:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at"`
+}
+
+func (o *Organization) BeforeCreate(tx *gorm.DB) error {
+	if o.ID == uuid.Nil {
+		o.ID = uuid.New()
+	}
+	return nil
+}
+
+// ProgramShare - Makes a program (and its subcourses/lessons) readable by another organization
+type ProgramShare struct {
+	ID             uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	ProgramID      uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_program_share" json:"program_id"`
+	OrganizationID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_program_share;index" json:"organization_id"`
+	CreatedAt      time.Time `json:"created_at"`
+
+	// Relations
+	Organization *Organization `gorm:"foreignKey:OrganizationID;references:ID" json:"organization,omitempty"`
+}
+
+func (ps *ProgramShare) BeforeCreate(tx *gorm.DB) error {
+	if ps.ID == uuid.Nil {
+		ps.ID = uuid.New()
+	}
+	return nil
+}
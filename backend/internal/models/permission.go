@@ -0,0 +1,112 @@
+package models
+
+// Permission is a single action a role may perform, e.g. "program.write"
+type Permission string
+
+const (
+	PermProgramRead   Permission = "program.read"
+	PermProgramCreate Permission = "program.create"
+	PermProgramWrite  Permission = "program.write"
+	PermProgramDelete Permission = "program.delete"
+
+	PermSubcourseRead   Permission = "subcourse.read"
+	PermSubcourseWrite  Permission = "subcourse.write"
+	PermSubcourseDelete Permission = "subcourse.delete"
+
+	PermLessonRead    Permission = "lesson.read"
+	PermLessonWrite   Permission = "lesson.write"
+	PermLessonDelete  Permission = "lesson.delete"
+	PermLessonPublish Permission = "lesson.publish"
+
+	PermMediaUpload Permission = "media.upload"
+
+	PermTeacherRead   Permission = "teacher.read"
+	PermTeacherManage Permission = "teacher.manage"
+
+	PermUserManage     Permission = "user.manage"
+	PermPermissionRead Permission = "permission.read"
+	PermSystemSeed     Permission = "system.seed"
+	PermSystemHealth   Permission = "system.health"
+	PermOrgRead        Permission = "organization.read"
+	PermOrgManage      Permission = "organization.manage"
+
+	// PermScopeAll lifts assignment-based scoping: the holder sees every
+	// program/subcourse/lesson instead of only the ones assigned to them.
+	PermScopeAll Permission = "scope.all"
+)
+
+// AllPermissions lists every known permission, in display order
+var AllPermissions = []Permission{
+	PermProgramRead, PermProgramCreate, PermProgramWrite, PermProgramDelete,
+	PermSubcourseRead, PermSubcourseWrite, PermSubcourseDelete,
+	PermLessonRead, PermLessonWrite, PermLessonDelete, PermLessonPublish,
+	PermMediaUpload,
+	PermTeacherRead, PermTeacherManage,
+	PermUserManage, PermPermissionRead, PermSystemSeed, PermSystemHealth,
+	PermOrgRead, PermOrgManage,
+	PermScopeAll,
+}
+
+// RolePermissions maps each role to its permission set
+var RolePermissions = map[UserRole][]Permission{
+	RoleSuperAdmin: AllPermissions,
+	// org admins manage everything inside their organization
+	RoleAdmin: without(AllPermissions, PermOrgManage, PermSystemSeed, PermSystemHealth),
+	RoleTeacher: {
+		PermProgramRead, PermProgramWrite,
+		PermSubcourseRead, PermSubcourseWrite, PermSubcourseDelete,
+		PermLessonRead, PermLessonWrite, PermLessonDelete, PermLessonPublish,
+		PermMediaUpload,
+		PermOrgRead,
+	},
+	RoleReviewer: {
+		PermProgramRead, PermSubcourseRead,
+		PermLessonRead, PermLessonPublish,
+		PermTeacherRead,
+		PermOrgRead,
+		PermScopeAll,
+	},
+	RoleViewer: {
+		PermProgramRead, PermSubcourseRead, PermLessonRead,
+		PermOrgRead,
+		PermScopeAll,
+	},
+}
+
+// Permissions returns the permission set of the role (nil for unknown roles)
+func (r UserRole) Permissions() []Permission {
+	return RolePermissions[r]
+}
+
+// HasPermission reports whether the role grants p
+func (r UserRole) HasPermission(p Permission) bool {
+	for _, rp := range RolePermissions[r] {
+		if rp == p {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValid reports whether r is a known role
+func (r UserRole) IsValid() bool {
+	_, ok := RolePermissions[r]
+	return ok
+}
+
+func without(perms []Permission, exclude ...Permission) []Permission {
+	out := make([]Permission, 0, len(perms))
+	for _, p := range perms {
+		skip := false
+		for _, e := range exclude {
+			if p == e {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -16,8 +16,12 @@ const (
 	StatusArchived  ContentStatus = "archived"
 )
 
+// ContentStatuses lists the valid content statuses
+var ContentStatuses = []ContentStatus{StatusDraft, StatusPublished, StatusArchived}
+
 type Program struct {
 	ID               uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
+	OrganizationID   *uuid.UUID     `gorm:"type:uuid;index" json:"organization_id,omitempty"`
 	Name             string         `gorm:"not null" json:"name"`
 	Slug             string         `gorm:"uniqueIndex;not null" json:"slug"`
 	ShortDescription string         `gorm:"type:text" json:"short_description"`
@@ -25,19 +29,28 @@ type Program struct {
 	BlockTypes       datatypes.JSON `gorm:"type:jsonb" json:"block_types"`
 	Status           ContentStatus  `gorm:"type:varchar(20);not null;default:'draft'" json:"status"`
 	SortOrder        int            `gorm:"default:0" json:"sort_order"`
-	CreatedAt        time.Time      `json:"created_at"`
-	UpdatedAt        time.Time      `json:"updated_at"`
+	// Counters maintained by the server (see database.RefreshProgramCounters)
+	SubcourseCount          int            `gorm:"not null;default:0" json:"subcourse_count"`
+	PublishedSubcourseCount int            `gorm:"not null;default:0" json:"published_subcourse_count"`
+	LessonCount             int            `gorm:"not null;default:0" json:"lesson_count"`
+	PublishedLessonCount    int            `gorm:"not null;default:0" json:"published_lesson_count"`
+	TotalDurationMinutes    int            `gorm:"not null;default:0" json:"total_duration_minutes"`
+	MediaCount              int            `gorm:"not null;default:0" json:"media_count"` // media attached to the program itself
+	Version                 int            `gorm:"not null;default:1" json:"version"`     // bumped on every write; backs ETag / If-Match
+	CreatedAt               time.Time      `json:"created_at"`
+	UpdatedAt               time.Time      `json:"updated_at"`
+	DeletedAt               gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"` // set while the program sits in the trash
+	DeletedBy               *uuid.UUID     `gorm:"type:uuid" json:"deleted_by,omitempty"`
 
 	// Relations
 	Subcourses []Subcourse `gorm:"foreignKey:ProgramID" json:"subcourses,omitempty"`
 	Media      []Media     `gorm:"polymorphic:Owner;polymorphicValue:program" json:"media,omitempty"`
-	// Transient field populated by handlers
-	SubcourseCount int `gorm:"-" json:"subcourse_count,omitempty"`
 }
 
 func (p *Program) BeforeCreate(tx *gorm.DB) error {
 	if p.ID == uuid.Nil {
 		p.ID = uuid.New()
 	}
+	p.Version = 1
 	return nil
 }
@@ -10,18 +10,26 @@ import (
 
 type Subcourse struct {
 	ID                uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
-	ProgramID         uuid.UUID      `gorm:"type:uuid;not null;index" json:"program_id"`
+	ProgramID         uuid.UUID      `gorm:"type:uuid;not null;index:idx_subcourses_program_sort,priority:1" json:"program_id"`
+	OrganizationID    *uuid.UUID     `gorm:"type:uuid;index" json:"organization_id,omitempty"` // copied from the program
 	Name              string         `gorm:"not null" json:"name"`
 	Slug              string         `gorm:"uniqueIndex;not null" json:"slug"`
 	AgeRange          string         `gorm:"type:varchar(50)" json:"age_range"`
-	LessonCount       int            `gorm:"default:0" json:"lesson_count"`
 	ShortDescription  string         `gorm:"type:text" json:"short_description"`
 	GeneralObjectives string         `gorm:"type:text" json:"general_objectives"`
 	BlockTypes        datatypes.JSON `gorm:"type:jsonb" json:"block_types"`
 	Status            ContentStatus  `gorm:"type:varchar(20);not null;default:'draft'" json:"status"`
-	SortOrder         int            `gorm:"default:0" json:"sort_order"`
-	CreatedAt         time.Time      `json:"created_at"`
-	UpdatedAt         time.Time      `json:"updated_at"`
+	SortOrder         int            `gorm:"default:0;index:idx_subcourses_program_sort,priority:2" json:"sort_order"`
+	// Counters maintained by the server (see database.RefreshSubcourseCounters)
+	LessonCount          int            `gorm:"not null;default:0" json:"lesson_count"`
+	PublishedLessonCount int            `gorm:"not null;default:0" json:"published_lesson_count"`
+	TotalDurationMinutes int            `gorm:"not null;default:0" json:"total_duration_minutes"`
+	MediaCount           int            `gorm:"not null;default:0" json:"media_count"` // media attached to the subcourse itself
+	Version              int            `gorm:"not null;default:1" json:"version"`     // bumped on every write; backs ETag / If-Match
+	CreatedAt            time.Time      `json:"created_at"`
+	UpdatedAt            time.Time      `json:"updated_at"`
+	DeletedAt            gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"` // set while the subcourse sits in the trash
+	DeletedBy            *uuid.UUID     `gorm:"type:uuid" json:"deleted_by,omitempty"`
 
 	// Relations
 	Program *Program `gorm:"foreignKey:ProgramID;references:ID" json:"program,omitempty"`
@@ -33,5 +41,6 @@ func (s *Subcourse) BeforeCreate(tx *gorm.DB) error {
 	if s.ID == uuid.Nil {
 		s.ID = uuid.New()
 	}
+	s.Version = 1
 	return nil
 }
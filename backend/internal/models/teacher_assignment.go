@@ -28,11 +28,12 @@ const (
 
 type TeacherAssignment struct {
 	ID             uuid.UUID        `gorm:"type:uuid;primary_key" json:"id"`
-	TeacherID      uuid.UUID        `gorm:"type:uuid;not null;index" json:"teacher_id"`
+	TeacherID      uuid.UUID        `gorm:"type:uuid;not null;index:idx_teacher_assignments_active,priority:1" json:"teacher_id"`
+	OrganizationID *uuid.UUID       `gorm:"type:uuid;index" json:"organization_id,omitempty"`
 	ProgramID      *uuid.UUID       `gorm:"type:uuid;index" json:"program_id,omitempty"`
 	SubcourseID    *uuid.UUID       `gorm:"type:uuid;index" json:"subcourse_id,omitempty"`
 	ScopeLevel     AssignmentScope  `gorm:"type:varchar(20);not null" json:"scope_level"`
-	Status         AssignmentStatus `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	Status         AssignmentStatus `gorm:"type:varchar(20);not null;default:'pending';index:idx_teacher_assignments_active,priority:2" json:"status"`
 	AccessCodeHash *string          `gorm:"type:text" json:"-"`
 	CodeExpiresAt  *time.Time       `json:"code_expires_at,omitempty"`
 	StartAt        *time.Time       `json:"start_at,omitempty"`
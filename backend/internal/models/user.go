@@ -11,22 +11,37 @@ type UserRole string
 type UserStatus string
 
 const (
-	RoleAdmin   UserRole = "admin"
-	RoleTeacher UserRole = "teacher"
+	RoleSuperAdmin UserRole = "super_admin"
+	RoleAdmin      UserRole = "admin"
+	RoleTeacher    UserRole = "teacher"
+	RoleReviewer   UserRole = "reviewer"
+	RoleViewer     UserRole = "viewer"
 
 	StatusActive   UserStatus = "active"
 	StatusInactive UserStatus = "inactive"
 )
 
+// UserRoles lists the roles from most to least privileged
+var UserRoles = []UserRole{RoleSuperAdmin, RoleAdmin, RoleTeacher, RoleReviewer, RoleViewer}
+
 type User struct {
-	ID           uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
-	Username     string     `gorm:"uniqueIndex;not null" json:"username"`
-	Email        string     `gorm:"uniqueIndex;not null" json:"email"`
-	PasswordHash string     `gorm:"not null" json:"-"`
-	Role         UserRole   `gorm:"type:varchar(20);not null" json:"role"`
-	Status       UserStatus `gorm:"type:varchar(20);not null;default:'active'" json:"status"`
-	CreatedAt    time.Time  `json:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at"`
+	ID           uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	Username     string    `gorm:"uniqueIndex;not null" json:"username"`
+	Email        string    `gorm:"uniqueIndex;not null" json:"email"`
+	PasswordHash string    `gorm:"not null" json:"-"`
+	Role         UserRole  `gorm:"type:varchar(20);not null" json:"role"`
+	// OrganizationID is nil only for super-admins
+	OrganizationID *uuid.UUID `gorm:"type:uuid;index" json:"organization_id,omitempty"`
+	Status         UserStatus `gorm:"type:varchar(20);not null;default:'active'" json:"status"`
+	// PasswordLoginDisabled forces the user to sign in through SSO
+	PasswordLoginDisabled bool `gorm:"not null;default:false" json:"password_login_disabled"`
+	// TOTPSecret is set once enrollment starts; TOTPEnabled once it is confirmed
+	TOTPSecret  string `gorm:"type:varchar(64)" json:"-"`
+	TOTPEnabled bool   `gorm:"not null;default:false" json:"totp_enabled"`
+	// TOTPLastStep is the last accepted time step, so a code can't be replayed
+	TOTPLastStep int64     `gorm:"not null;default:0" json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) error {
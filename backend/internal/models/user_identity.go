@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UserIdentity - An external (OIDC) identity linked to a local user
+type UserIdentity struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	UserID      uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	Issuer      string     `gorm:"type:varchar(255);not null;uniqueIndex:idx_identity_issuer_subject" json:"issuer"`
+	Subject     string     `gorm:"type:varchar(255);not null;uniqueIndex:idx_identity_issuer_subject" json:"subject"`
+	Email       string     `gorm:"type:varchar(255)" json:"email"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func (ui *UserIdentity) BeforeCreate(tx *gorm.DB) error {
+	if ui.ID == uuid.Nil {
+		ui.ID = uuid.New()
+	}
+	return nil
+}
+
+// OIDCLoginState - Pending authorization request (state, PKCE verifier, nonce).
+// Stored in the database so the callback can land on any replica.
+type OIDCLoginState struct {
+	State        string     `gorm:"type:varchar(100);primary_key" json:"-"`
+	CodeVerifier string     `gorm:"type:varchar(200);not null" json:"-"`
+	Nonce        string     `gorm:"type:varchar(100);not null" json:"-"`
+	LinkUserID   *uuid.UUID `gorm:"type:uuid" json:"-"` // set when linking an identity to a signed-in user
+	ExpiresAt    time.Time  `gorm:"index" json:"-"`
+	CreatedAt    time.Time  `json:"-"`
+}
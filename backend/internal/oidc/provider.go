@@ -0,0 +1,318 @@
+package oidc
+
+import (
+	"context"
+	"courseai/backend/internal/config"
+	"courseai/backend/internal/telemetry"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Discovery is the subset of the provider metadata document we use
+type Discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// TokenResponse is the token endpoint response
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	IDToken     string `json:"id_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// IDToken holds the verified claims of an ID token
+type IDToken struct {
+	Issuer            string
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+	Claims            jwt.MapClaims
+}
+
+// Provider talks to one OpenID Connect issuer. Metadata and signing keys are
+// fetched lazily and cached; keys are refetched when an unknown kid appears.
+type Provider struct {
+	cfg    config.OIDCConfig
+	client *http.Client
+
+	mu          sync.Mutex
+	discovery   *Discovery
+	keys        map[string]*rsa.PublicKey
+	keysFetched time.Time
+}
+
+const keyRefreshInterval = time.Minute
+
+func NewProvider(cfg config.OIDCConfig) *Provider {
+	return &Provider{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second, Transport: telemetry.Transport(nil)}}
+}
+
+// Enabled reports whether SSO is configured
+func (p *Provider) Enabled() bool {
+	return p.cfg.Enabled && p.cfg.IssuerURL != "" && p.cfg.ClientID != ""
+}
+
+func (p *Provider) Issuer() string { return p.cfg.IssuerURL }
+
+// AuthCodeURL builds the authorization request URL with PKCE (S256) and nonce
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", p.cfg.RedirectURL)
+	q.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", codeChallenge)
+	q.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return d.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+// Exchange trades an authorization code (plus PKCE verifier) for tokens
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier string) (*TokenResponse, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("client_id", p.cfg.ClientID)
+	form.Set("code_verifier", codeVerifier)
+	if p.cfg.ClientSecret != "" {
+		form.Set("client_secret", p.cfg.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var tok TokenResponse
+	if err := json.Unmarshal(body, &tok); err != nil {
+		return nil, fmt.Errorf("invalid token response: %w", err)
+	}
+	if tok.IDToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+	return &tok, nil
+}
+
+// VerifyIDToken checks signature, issuer, audience, expiry and nonce
+func (p *Provider) VerifyIDToken(ctx context.Context, raw, nonce string) (*IDToken, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.key(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithIssuer(p.cfg.IssuerURL),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30*time.Second),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_token: %w", err)
+	}
+	if n, _ := claims["nonce"].(string); n != nonce {
+		return nil, errors.New("invalid id_token: nonce mismatch")
+	}
+
+	tok := &IDToken{Issuer: p.cfg.IssuerURL, Claims: claims}
+	tok.Subject, _ = claims["sub"].(string)
+	tok.Email, _ = claims["email"].(string)
+	tok.Name, _ = claims["name"].(string)
+	tok.PreferredUsername, _ = claims["preferred_username"].(string)
+	switch v := claims["email_verified"].(type) {
+	case bool:
+		tok.EmailVerified = v
+	case string:
+		tok.EmailVerified = v == "true"
+	}
+	if tok.Subject == "" {
+		return nil, errors.New("invalid id_token: missing sub")
+	}
+	return tok, nil
+}
+
+// ClaimValues returns the values of a string or string-array claim
+func (t *IDToken) ClaimValues(name string) []string {
+	switch v := t.Claims[name].(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func (p *Provider) discover(ctx context.Context) (*Discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var d Discovery
+	if err := p.getJSON(ctx, p.cfg.IssuerURL+"/.well-known/openid-configuration", &d); err != nil {
+		return nil, fmt.Errorf("oidc discovery failed: %w", err)
+	}
+	if strings.TrimSuffix(d.Issuer, "/") != p.cfg.IssuerURL {
+		return nil, fmt.Errorf("oidc discovery issuer mismatch: got %q, want %q", d.Issuer, p.cfg.IssuerURL)
+	}
+	p.discovery = &d
+	return p.discovery, nil
+}
+
+func (p *Provider) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if k := p.lookupKey(kid); k != nil {
+		return k, nil
+	}
+	if time.Since(p.keysFetched) < keyRefreshInterval && p.keys != nil {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := p.getJSON(ctx, d.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("fetching jwks failed: %w", err)
+	}
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	p.keys = keys
+	p.keysFetched = time.Now()
+
+	if k := p.lookupKey(kid); k != nil {
+		return k, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookupKey finds a key by kid; with no kid it accepts a single published key
+func (p *Provider) lookupKey(kid string) *rsa.PublicKey {
+	if k, ok := p.keys[kid]; ok {
+		return k
+	}
+	if kid == "" && len(p.keys) == 1 {
+		for _, k := range p.keys {
+			return k
+		}
+	}
+	return nil
+}
+
+func (p *Provider) getJSON(ctx context.Context, u string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned %d", u, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out)
+}
+
+// RandomString returns n random bytes, base64url encoded
+func RandomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// NewPKCE returns a code verifier and its S256 challenge
+func NewPKCE() (verifier, challenge string, err error) {
+	verifier, err = RandomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	return verifier, S256Challenge(verifier), nil
+}
+
+// S256Challenge derives the PKCE S256 code challenge from a verifier
+func S256Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
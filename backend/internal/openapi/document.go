@@ -0,0 +1,165 @@
+// Package openapi describes the HTTP API as an OpenAPI 3 document. The
+// document is built from a table of operations whose request and response
+// bodies are the Go types the handlers read and write, so it cannot drift from
+// the JSON the server actually speaks. The same description drives request
+// validation (Validator) and the generated client (cmd/apigen).
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Version of the OpenAPI specification the document follows
+const Version = "3.0.3"
+
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Servers    []Server              `json:"servers,omitempty"`
+	Tags       []Tag                 `json:"tags,omitempty"`
+	Paths      Paths                 `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []map[string][]string `json:"security,omitempty"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Server struct {
+	URL string `json:"url"`
+}
+
+type Tag struct {
+	Name string `json:"name"`
+}
+
+// Paths maps an OpenAPI path (/programs/{id}) to its operations by lowercase method
+type Paths map[string]map[string]*OperationObject
+
+type OperationObject struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security"`
+	// Permission is the permission the route requires, if any
+	Permission string `json:"x-permission,omitempty"`
+	// NoClient leaves the operation out of the generated client
+	NoClient bool `json:"x-no-client,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+// Schema is the subset of the OpenAPI schema object the generator produces
+type Schema struct {
+	Ref                  string     `json:"$ref,omitempty"`
+	Type                 string     `json:"type,omitempty"`
+	Format               string     `json:"format,omitempty"`
+	Description          string     `json:"description,omitempty"`
+	Enum                 []string   `json:"enum,omitempty"`
+	Nullable             bool       `json:"nullable,omitempty"`
+	ReadOnly             bool       `json:"readOnly,omitempty"`
+	Items                *Schema    `json:"items,omitempty"`
+	Properties           Properties `json:"properties,omitempty"`
+	Required             []string   `json:"required,omitempty"`
+	AdditionalProperties *Schema    `json:"additionalProperties,omitempty"`
+	// GoName is the Go field name of a property, used by the client generator
+	GoName string `json:"x-go-name,omitempty"`
+}
+
+// Property is one named property of an object schema
+type Property struct {
+	Name   string
+	Schema *Schema
+}
+
+// Properties keeps object properties in struct field order, which JSON
+// objects (and Go maps) would lose
+type Properties []Property
+
+func (p Properties) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, prop := range p {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(prop.Name)
+		if err != nil {
+			return nil, err
+		}
+		schema, err := json.Marshal(prop.Schema)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(schema)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Get returns the schema of the named property, or nil
+func (p Properties) Get(name string) *Schema {
+	for _, prop := range p {
+		if prop.Name == name {
+			return prop.Schema
+		}
+	}
+	return nil
+}
+
+// RefName is the component name a $ref points to
+func (s *Schema) RefName() string {
+	const prefix = "#/components/schemas/"
+	if len(s.Ref) > len(prefix) && s.Ref[:len(prefix)] == prefix {
+		return s.Ref[len(prefix):]
+	}
+	return ""
+}
+
+// Resolve follows a $ref to the component schema
+func (d *Document) Resolve(s *Schema) *Schema {
+	for s != nil && s.Ref != "" {
+		s = d.Components.Schemas[s.RefName()]
+	}
+	return s
+}
@@ -0,0 +1,351 @@
+package openapi
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// GenerateClient writes the Go source of a typed client for doc: one type per
+// component schema and one method per operation. The methods call
+// (*Client).do, which the package that holds the generated file provides by
+// hand together with Client, RequestOption and File.
+func GenerateClient(doc *Document, pkg string) ([]byte, error) {
+	g := &generator{doc: doc, imports: map[string]bool{"context": true}}
+
+	names := make([]string, 0, len(doc.Components.Schemas))
+	for name := range doc.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		g.component(name, doc.Components.Schemas[name])
+	}
+
+	var ops []*OperationObject
+	paths := map[*OperationObject]string{}
+	methods := map[*OperationObject]string{}
+	for path, byMethod := range doc.Paths {
+		for method, op := range byMethod {
+			if op.NoClient {
+				continue
+			}
+			ops = append(ops, op)
+			paths[op] = path
+			methods[op] = strings.ToUpper(method)
+		}
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i].OperationID < ops[j].OperationID })
+	for _, op := range ops {
+		if err := g.operation(methods[op], paths[op], op); err != nil {
+			return nil, err
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by cmd/apigen from the OpenAPI document; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "package %s\n\nimport (\n", pkg)
+	imports := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		imports = append(imports, path)
+	}
+	sort.Slice(imports, func(i, j int) bool {
+		// the standard library first, then modules (their paths have a dot)
+		iMod, jMod := strings.Contains(imports[i], "."), strings.Contains(imports[j], ".")
+		if iMod != jMod {
+			return jMod
+		}
+		return imports[i] < imports[j]
+	})
+	for i, path := range imports {
+		if i > 0 && strings.Contains(path, ".") && !strings.Contains(imports[i-1], ".") {
+			out.WriteString("\n")
+		}
+		fmt.Fprintf(&out, "\t%q\n", path)
+	}
+	out.WriteString(")\n")
+	out.Write(g.types.Bytes())
+	out.Write(g.methods.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return out.Bytes(), fmt.Errorf("format generated client: %w", err)
+	}
+	return src, nil
+}
+
+type generator struct {
+	doc     *Document
+	imports map[string]bool
+	types   bytes.Buffer
+	methods bytes.Buffer
+}
+
+func (g *generator) component(name string, s *Schema) {
+	w := &g.types
+	switch {
+	case s.Type == "string" && len(s.Enum) > 0:
+		fmt.Fprintf(w, "\ntype %s string\n\nconst (\n", name)
+		seen := map[string]bool{}
+		for _, value := range s.Enum {
+			constant := name + exported(value)
+			if seen[constant] {
+				continue
+			}
+			seen[constant] = true
+			fmt.Fprintf(w, "\t%s %s = %q\n", constant, name, value)
+		}
+		w.WriteString(")\n")
+	case s.Type == "object" && s.AdditionalProperties == nil:
+		fmt.Fprintf(w, "\ntype %s struct {\n", name)
+		required := map[string]bool{}
+		for _, r := range s.Required {
+			required[r] = true
+		}
+		for _, prop := range s.Properties {
+			field := prop.Schema.GoName
+			if field == "" {
+				field = exported(prop.Name)
+			}
+			tag := prop.Name
+			if !required[prop.Name] {
+				tag += ",omitempty"
+			}
+			fmt.Fprintf(w, "\t%s %s `json:%q`\n", field, g.goType(prop.Schema, true), tag)
+		}
+		w.WriteString("}\n")
+	default:
+		fmt.Fprintf(w, "\ntype %s %s\n", name, g.goType(&Schema{Type: s.Type, Format: s.Format, Items: s.Items, AdditionalProperties: s.AdditionalProperties}, false))
+	}
+}
+
+// goType is the Go type of a schema. Object components are referenced by
+// pointer where they are optional, i.e. as fields and top-level bodies.
+func (g *generator) goType(s *Schema, pointer bool) string {
+	if s == nil {
+		g.imports["encoding/json"] = true
+		return "json.RawMessage"
+	}
+	if name := s.RefName(); name != "" {
+		if target := g.doc.Resolve(s); pointer && target != nil && target.Type == "object" && target.AdditionalProperties == nil {
+			return "*" + name
+		}
+		return name
+	}
+	nullable := ""
+	if s.Nullable {
+		nullable = "*"
+	}
+	switch s.Type {
+	case "string":
+		switch s.Format {
+		case "uuid":
+			g.imports["github.com/google/uuid"] = true
+			return nullable + "uuid.UUID"
+		case "date-time":
+			g.imports["time"] = true
+			return nullable + "time.Time"
+		case "binary":
+			return "File"
+		case "byte":
+			return "[]byte"
+		}
+		return nullable + "string"
+	case "integer":
+		if s.Format == "int64" {
+			return nullable + "int64"
+		}
+		return nullable + "int"
+	case "number":
+		return nullable + "float64"
+	case "boolean":
+		return nullable + "bool"
+	case "array":
+		return "[]" + g.goType(s.Items, false)
+	case "object":
+		if s.AdditionalProperties != nil {
+			return "map[string]" + g.goType(s.AdditionalProperties, false)
+		}
+	}
+	g.imports["encoding/json"] = true
+	return "json.RawMessage"
+}
+
+func (g *generator) operation(method, path string, op *OperationObject) error {
+	w := &g.methods
+	var pathArgs, queryFields []string
+	var query []Parameter
+	for _, p := range op.Parameters {
+		switch p.In {
+		case "path":
+			pathArgs = append(pathArgs, argName(p.Name))
+		case "query":
+			query = append(query, p)
+			queryFields = append(queryFields, exported(p.Name))
+		}
+	}
+
+	args := []string{"ctx context.Context"}
+	if len(pathArgs) > 0 {
+		args = append(args, strings.Join(pathArgs, ", ")+" string")
+	}
+	if len(query) > 0 {
+		fmt.Fprintf(w, "\n// %sParams are the query parameters of %s\ntype %sParams struct {\n", op.OperationID, op.OperationID, op.OperationID)
+		for i, p := range query {
+			if p.Description != "" {
+				fmt.Fprintf(w, "\t// %s\n", p.Description)
+			}
+			fmt.Fprintf(w, "\t%s %s\n", queryFields[i], g.goType(p.Schema, false))
+		}
+		w.WriteString("}\n")
+		args = append(args, "params *"+op.OperationID+"Params")
+	}
+
+	bodyType, body := "", "nil"
+	if op.RequestBody != nil {
+		for contentType, media := range op.RequestBody.Content {
+			bodyType = contentType
+			args = append(args, "body "+g.goType(media.Schema, true))
+			body = "body"
+		}
+	}
+	args = append(args, "opts ...RequestOption")
+
+	var result, zero string
+	for code, resp := range op.Responses {
+		if code == "default" {
+			continue
+		}
+		for contentType, media := range resp.Content {
+			if contentType != ContentJSON {
+				return fmt.Errorf("operation %s: the client cannot decode %s responses", op.OperationID, contentType)
+			}
+			result = g.goType(media.Schema, true)
+		}
+	}
+	switch {
+	case result == "":
+	case strings.HasPrefix(result, "*"), strings.HasPrefix(result, "[]"), strings.HasPrefix(result, "map["), result == "json.RawMessage":
+		zero = "nil"
+	default:
+		return fmt.Errorf("operation %s: unsupported response type %s", op.OperationID, result)
+	}
+
+	fmt.Fprintf(w, "\n// %s calls %s %s", op.OperationID, method, path)
+	if op.Summary != "" {
+		fmt.Fprintf(w, ": %s", lowerFirst(op.Summary))
+	}
+	w.WriteString("\n")
+	if op.Permission != "" {
+		fmt.Fprintf(w, "// It requires the %s permission.\n", op.Permission)
+	}
+	for _, p := range op.Parameters {
+		if p.In == "header" && p.Name == "If-Match" && p.Required {
+			w.WriteString("// Pass IfMatch with the version the change is based on; the server answers\n// 428 without it.\n")
+		}
+	}
+	fmt.Fprintf(w, "func (c *Client) %s(%s) ", op.OperationID, strings.Join(args, ", "))
+	if result == "" {
+		w.WriteString("error {\n")
+	} else {
+		fmt.Fprintf(w, "(%s, error) {\n", result)
+	}
+
+	queryArg := "nil"
+	if len(query) > 0 {
+		g.imports["net/url"] = true
+		queryArg = "query"
+		w.WriteString("\tquery := url.Values{}\n\tif params != nil {\n")
+		for i, p := range query {
+			field := "params." + queryFields[i]
+			switch p.Schema.Type {
+			case "integer":
+				g.imports["strconv"] = true
+				fmt.Fprintf(w, "\t\tif %s != 0 {\n\t\t\tquery.Set(%q, strconv.Itoa(%s))\n\t\t}\n", field, p.Name, field)
+			case "boolean":
+				g.imports["strconv"] = true
+				fmt.Fprintf(w, "\t\tif %s {\n\t\t\tquery.Set(%q, strconv.FormatBool(%s))\n\t\t}\n", field, p.Name, field)
+			default:
+				fmt.Fprintf(w, "\t\tif %s != \"\" {\n\t\t\tquery.Set(%q, %s)\n\t\t}\n", field, p.Name, field)
+			}
+		}
+		w.WriteString("\t}\n")
+	}
+
+	call := fmt.Sprintf("c.do(ctx, http.Method%s, %s, %s, %q, %s, %%s, opts)", exported(strings.ToLower(method)), g.pathExpr(path), queryArg, bodyType, body)
+	g.imports["net/http"] = true
+	if result == "" {
+		fmt.Fprintf(w, "\treturn "+call+"\n}\n", "nil")
+		return nil
+	}
+	if strings.HasPrefix(result, "*") {
+		fmt.Fprintf(w, "\tout := new(%s)\n", result[1:])
+		fmt.Fprintf(w, "\tif err := "+call+"; err != nil {\n", "out")
+	} else {
+		fmt.Fprintf(w, "\tvar out %s\n", result)
+		fmt.Fprintf(w, "\tif err := "+call+"; err != nil {\n", "&out")
+	}
+	fmt.Fprintf(w, "\t\treturn %s, err\n\t}\n\treturn out, nil\n}\n", zero)
+	return nil
+}
+
+// pathExpr builds the request path from the path parameters, escaped
+func (g *generator) pathExpr(path string) string {
+	var parts []string
+	literal := ""
+	for _, seg := range strings.Split(strings.TrimPrefix(path, "/"), "/") {
+		literal += "/"
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			g.imports["net/url"] = true
+			parts = append(parts, fmt.Sprintf("%q", literal), "url.PathEscape("+argName(seg[1:len(seg)-1])+")")
+			literal = ""
+			continue
+		}
+		literal += seg
+	}
+	if literal != "" {
+		parts = append(parts, fmt.Sprintf("%q", literal))
+	}
+	return strings.Join(parts, " + ")
+}
+
+// exported turns a JSON name or enum value such as owner_id or program.read
+// into a Go identifier, keeping the usual initialisms upper case
+func exported(name string) string {
+	var b strings.Builder
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		if upper := strings.ToUpper(word); initialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return b.String()
+}
+
+var initialisms = map[string]bool{"ID": true, "URL": true, "URI": true, "UUID": true, "API": true, "JSON": true, "HTML": true, "IP": true, "TOTP": true, "MFA": true, "PDF": true}
+
+// argName turns a path parameter into a Go parameter name
+func argName(name string) string {
+	arg := exported(name)
+	if strings.ToUpper(arg) == arg {
+		arg = strings.ToLower(arg)
+	} else {
+		arg = lowerFirst(arg)
+	}
+	if token.IsKeyword(arg) {
+		arg += "Name"
+	}
+	return arg
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
@@ -0,0 +1,181 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// File is a file part of a multipart request body
+type File []byte
+
+// EnumType lists the values of a string type, e.g. models.ContentStatus
+type EnumType struct {
+	Type   reflect.Type
+	Values []string
+}
+
+// Enum registers the values of a string type; the type becomes a named
+// component schema wherever it is used
+func Enum[T ~string](values []T) EnumType {
+	names := make([]string, len(values))
+	for i, v := range values {
+		names[i] = string(v)
+	}
+	return EnumType{Type: reflect.TypeOf(values).Elem(), Values: names}
+}
+
+// known maps types with their own JSON encoding to their schema
+var known = map[reflect.Type]Schema{
+	reflect.TypeOf(uuid.UUID{}):       {Type: "string", Format: "uuid"},
+	reflect.TypeOf(time.Time{}):       {Type: "string", Format: "date-time"},
+	reflect.TypeOf(gorm.DeletedAt{}):  {Type: "string", Format: "date-time", Nullable: true},
+	reflect.TypeOf(datatypes.JSON{}):  {},
+	reflect.TypeOf(json.RawMessage{}): {},
+	reflect.TypeOf(File{}):            {Type: "string", Format: "binary"},
+}
+
+// schemas turns Go types into schemas, collecting named struct and enum types
+// as components
+type schemas struct {
+	components map[string]*Schema
+	enums      map[reflect.Type][]string
+	// names remembers which type owns a component name
+	names map[string]reflect.Type
+}
+
+func newSchemas(enums []EnumType) *schemas {
+	s := &schemas{
+		components: map[string]*Schema{},
+		enums:      map[reflect.Type][]string{},
+		names:      map[string]reflect.Type{},
+	}
+	for _, e := range enums {
+		s.enums[e.Type] = e.Values
+	}
+	return s
+}
+
+// of returns the schema of the value's type; nil for a nil value
+func (s *schemas) of(v interface{}) *Schema {
+	if v == nil {
+		return nil
+	}
+	return s.schema(reflect.TypeOf(v))
+}
+
+func (s *schemas) schema(t reflect.Type) *Schema {
+	if k, ok := known[t]; ok {
+		return &k
+	}
+	if values, ok := s.enums[t]; ok {
+		return s.component(t, func() *Schema { return &Schema{Type: "string", Enum: values} })
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		elem := s.schema(t.Elem())
+		if elem.Ref == "" {
+			elem.Nullable = true
+		}
+		return elem
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		return s.component(t, func() *Schema { return s.object(t) })
+	}
+	// interface{} and anything else: any JSON value
+	return &Schema{}
+}
+
+// component registers t under its type name and returns a reference to it.
+// The name is reserved before build runs so recursive types terminate.
+func (s *schemas) component(t reflect.Type, build func() *Schema) *Schema {
+	name := t.Name()
+	if owner, ok := s.names[name]; ok && owner != t {
+		// two packages use the same name, e.g. handlers.X and models.X
+		pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+	if _, ok := s.names[name]; !ok {
+		s.names[name] = t
+		s.components[name] = &Schema{}
+		*s.components[name] = *build()
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// object builds the schema of a struct from its exported fields and json tags.
+// The openapi tag marks fields "required" or "readonly", e.g.
+//
+//	Username string `json:"username" openapi:"required"`
+func (s *schemas) object(t reflect.Type) *Schema {
+	obj := &Schema{Type: "object", Properties: Properties{}}
+	s.fields(obj, t)
+	return obj
+}
+
+func (s *schemas) fields(obj *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			// embedded structs are flattened, as encoding/json does
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				s.fields(obj, ft)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		prop := s.schema(f.Type)
+		for _, o := range strings.Split(f.Tag.Get("openapi"), ",") {
+			switch o {
+			case "required":
+				obj.Required = append(obj.Required, name)
+			case "readonly":
+				prop.ReadOnly = true
+			case "":
+			default:
+				panic(fmt.Sprintf("openapi: unknown option %q on %s.%s", o, t.Name(), f.Name))
+			}
+		}
+		prop.GoName = f.Name
+		obj.Properties = append(obj.Properties, Property{Name: name, Schema: prop})
+	}
+}
@@ -0,0 +1,264 @@
+package openapi
+
+import (
+	"courseai/backend/internal/validation"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Operation is one route as the handlers table describes it
+type Operation struct {
+	Method string
+	// Path in router syntax, e.g. /api/admin/programs/:id
+	Path string
+	// ID is the operationId and the generated client method, e.g. UpdateProgram
+	ID      string
+	Summary string
+	Tag     string
+	// Auth requires a bearer token; Permission is what the route checks on top
+	Auth       bool
+	Permission string
+	// IfMatch requires an If-Match version for optimistic concurrency
+	IfMatch bool
+	// Cached responses carry ETag and Last-Modified and answer conditional
+	// requests with 304
+	Cached bool
+	Query  []Param
+	// Body is a value of the request body type, nil for none; BodyType
+	// defaults to application/json
+	Body     interface{}
+	BodyType string
+	// Response is a value of the success response body type, nil for none. A
+	// string response is sent as text/plain.
+	Response interface{}
+	// Status is the success status, 200 unless set
+	Status   int
+	NoClient bool
+}
+
+// Param is a query parameter
+type Param struct {
+	Name        string
+	Description string
+	// Type is a schema type, "string" unless set
+	Type string
+}
+
+const (
+	ContentJSON      = "application/json"
+	ContentJSONPatch = "application/json-patch+json"
+	ContentMultipart = "multipart/form-data"
+	ContentText      = "text/plain"
+)
+
+// Spec is the built document together with what request validation needs
+type Spec struct {
+	Doc    *Document
+	routes []*route
+}
+
+// route is an operation compiled for matching request paths
+type route struct {
+	op       *OperationObject
+	method   string
+	path     string
+	segments []string
+	body     *Schema
+	bodyType string
+	status   int
+	response *Schema
+}
+
+var paramPattern = regexp.MustCompile(`:(\w+)\??`)
+
+// Build describes ops in an OpenAPI document. Routes are written in router
+// syntax, /api/admin/programs/:id becomes /api/admin/programs/{id}.
+func Build(info Info, ops []Operation, enums []EnumType) *Spec {
+	s := newSchemas(enums)
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   Paths{},
+		Components: Components{
+			Schemas: s.components,
+			SecuritySchemes: map[string]*SecurityScheme{
+				"bearer": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+	spec := &Spec{Doc: doc}
+	tags := map[string]bool{}
+	ids := map[string]string{}
+
+	errorRef := s.of(ErrorResponse{})
+	for i := range ops {
+		op := &ops[i]
+		key := op.Method + " " + op.Path
+		if other, ok := ids[op.ID]; ok {
+			panic(fmt.Sprintf("openapi: operation %s is used by %s and %s", op.ID, other, key))
+		}
+		ids[op.ID] = key
+
+		obj := &OperationObject{
+			OperationID: op.ID,
+			Summary:     op.Summary,
+			Responses:   map[string]*Response{},
+			Security:    []map[string][]string{},
+			Permission:  op.Permission,
+			NoClient:    op.NoClient,
+		}
+		if op.Tag != "" {
+			obj.Tags = []string{op.Tag}
+			if !tags[op.Tag] {
+				tags[op.Tag] = true
+				doc.Tags = append(doc.Tags, Tag{Name: op.Tag})
+			}
+		}
+		if op.Auth {
+			obj.Security = []map[string][]string{{"bearer": {}}}
+		}
+		for _, m := range paramPattern.FindAllStringSubmatch(op.Path, -1) {
+			obj.Parameters = append(obj.Parameters, Parameter{Name: m[1], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+		for _, q := range op.Query {
+			typ := q.Type
+			if typ == "" {
+				typ = "string"
+			}
+			obj.Parameters = append(obj.Parameters, Parameter{Name: q.Name, In: "query", Description: q.Description, Schema: &Schema{Type: typ}})
+		}
+		if op.IfMatch {
+			obj.Parameters = append(obj.Parameters, Parameter{
+				Name: "If-Match", In: "header", Description: "version the change is based on; 412 if it is stale, 428 if it is missing",
+				Required: true, Schema: &Schema{Type: "string"},
+			})
+		}
+		if op.Cached {
+			obj.Parameters = append(obj.Parameters,
+				Parameter{Name: "If-None-Match", In: "header", Description: "ETag of a copy the client has; 304 if it is current", Schema: &Schema{Type: "string"}},
+				Parameter{Name: "If-Modified-Since", In: "header", Description: "date of a copy the client has, used without If-None-Match; 304 if it is current", Schema: &Schema{Type: "string"}},
+			)
+		}
+
+		r := &route{op: obj, method: op.Method, path: op.Path, segments: strings.Split(op.Path, "/"), status: op.Status}
+		if r.status == 0 {
+			r.status = http.StatusOK
+		}
+		if op.Body != nil {
+			r.bodyType = op.BodyType
+			if r.bodyType == "" {
+				r.bodyType = ContentJSON
+			}
+			r.body = s.of(op.Body)
+			obj.RequestBody = &RequestBody{Required: true, Content: map[string]*MediaType{r.bodyType: {Schema: r.body}}}
+		}
+		resp := &Response{Description: http.StatusText(r.status)}
+		if op.Response != nil {
+			r.response = s.of(op.Response)
+			contentType := ContentJSON
+			if reflect.TypeOf(op.Response).Kind() == reflect.String {
+				contentType = ContentText
+			}
+			resp.Content = map[string]*MediaType{contentType: {Schema: r.response}}
+		}
+		obj.Responses[strconv.Itoa(r.status)] = resp
+		if op.Cached {
+			obj.Responses[strconv.Itoa(http.StatusNotModified)] = &Response{Description: "The client's copy is current"}
+		}
+		if op.IfMatch {
+			obj.Responses[strconv.Itoa(http.StatusPreconditionFailed)] = &Response{Description: "The record changed since the If-Match version"}
+			obj.Responses[strconv.Itoa(http.StatusPreconditionRequired)] = &Response{Description: "If-Match is missing"}
+		}
+		obj.Responses["default"] = &Response{
+			Description: "Error",
+			Content:     map[string]*MediaType{ContentJSON: {Schema: errorRef}},
+		}
+
+		path := paramPattern.ReplaceAllString(op.Path, "{$1}")
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = map[string]*OperationObject{}
+		}
+		doc.Paths[path][strings.ToLower(op.Method)] = obj
+		spec.routes = append(spec.routes, r)
+	}
+	return spec
+}
+
+// ErrorResponse is the body of every error response; Errors lists the field
+// problems when the request body was invalid, RetryAfter the seconds to wait
+// after a 429 (also in the Retry-After header), RequestID matches the
+// X-Request-ID response header and the server's log records
+type ErrorResponse struct {
+	Error      string            `json:"error" openapi:"required"`
+	Errors     validation.Errors `json:"errors,omitempty"`
+	RetryAfter int               `json:"retry_after,omitempty"`
+	RequestID  string            `json:"request_id,omitempty"`
+}
+
+// match finds the operation for a request. Literal segments beat parameters,
+// so /programs/order wins over /programs/:id as it does in the router.
+func (s *Spec) match(method, path string) *route {
+	segments := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	var best *route
+	bestLiterals := -1
+	for _, r := range s.routes {
+		if r.method != method || len(r.segments) != len(segments) {
+			continue
+		}
+		literals := 0
+		ok := true
+		for i, seg := range r.segments {
+			if strings.HasPrefix(seg, ":") {
+				continue
+			}
+			if seg != segments[i] {
+				ok = false
+				break
+			}
+			literals++
+		}
+		if ok && literals > bestLiterals {
+			best, bestLiterals = r, literals
+		}
+	}
+	return best
+}
+
+// Diff compares the registered routes with the documented operations and
+// returns routes nobody documented and operations no route serves
+func (s *Spec) Diff(routes []fiber.Route) (undocumented, stale []string) {
+	documented := map[string]bool{}
+	for _, r := range s.routes {
+		documented[r.method+" "+r.path] = true
+	}
+	served := map[string]bool{}
+	for _, r := range routes {
+		// the router answers HEAD for every GET itself
+		if r.Method == http.MethodHead {
+			continue
+		}
+		key := r.Method + " " + r.Path
+		if served[key] {
+			continue
+		}
+		served[key] = true
+		if !documented[key] {
+			undocumented = append(undocumented, key)
+		}
+	}
+	for key := range documented {
+		if !served[key] {
+			stale = append(stale, key)
+		}
+	}
+	sort.Strings(undocumented)
+	sort.Strings(stale)
+	return undocumented, stale
+}
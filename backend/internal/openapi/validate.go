@@ -0,0 +1,195 @@
+package openapi
+
+import (
+	"bytes"
+	"courseai/backend/internal/validation"
+	"encoding/json"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// ValidateOptions chooses what Validator checks
+type ValidateOptions struct {
+	// Requests rejects JSON request bodies that do not fit the operation's
+	// schema with 400 and the offending field paths
+	Requests bool
+	// Responses logs success responses that do not fit the schema. It costs a
+	// decode of every response, so it is meant for development and CI.
+	Responses bool
+}
+
+// Validator checks requests (and optionally responses) of documented
+// operations against their schemas. It only checks shape: types, enums, UUIDs
+// and required properties; the handlers still apply the business rules.
+// Unknown properties are allowed, as clients send back what they received.
+func (s *Spec) Validator(opts ValidateOptions) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		r := s.match(c.Method(), c.Path())
+		if r == nil {
+			return c.Next()
+		}
+		if opts.Requests && r.body != nil && isJSON(r.bodyType) && isJSON(c.Get(fiber.HeaderContentType)) && len(c.Body()) > 0 {
+			body, err := decode(c.Body())
+			if err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+			}
+			if err := s.Check(r.body, body); err != nil {
+				var errs validation.Errors
+				if !errors.As(err, &errs) {
+					return fiber.NewError(fiber.StatusBadRequest, err.Error())
+				}
+				return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Error: errs.Error(), Errors: errs})
+			}
+		}
+		if !opts.Responses || r.response == nil {
+			return c.Next()
+		}
+
+		if err := c.Next(); err != nil {
+			return err
+		}
+		resp := c.Response()
+		if resp.StatusCode() != r.status || !isJSON(string(resp.Header.ContentType())) {
+			return nil
+		}
+		body, err := decode(resp.Body())
+		if err == nil {
+			err = s.Check(r.response, body)
+		}
+		if err != nil {
+			log.Printf("openapi: response of %s %s does not match %s: %v", c.Method(), c.Path(), r.op.OperationID, err)
+		}
+		return nil
+	}
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.TrimSpace(mediaType)
+	return mediaType == ContentJSON || strings.HasSuffix(mediaType, "+json")
+}
+
+func decode(data []byte) (interface{}, error) {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	err := dec.Decode(&v)
+	return v, err
+}
+
+// Check reports every place value (as decoded with UseNumber) does not fit
+// schema, as validation.Errors. A value that is not even of the right kind at
+// the top level is a plain error.
+func (s *Spec) Check(schema *Schema, value interface{}) error {
+	schema = s.Doc.Resolve(schema)
+	if want := schema.Type; value != nil && (want == "object" || want == "array") {
+		_, isObject := value.(map[string]interface{})
+		_, isArray := value.([]interface{})
+		if (want == "object" && !isObject) || (want == "array" && !isArray) {
+			return errors.New("body must be " + article(want))
+		}
+	}
+	v := validation.New()
+	s.check(v, "", schema, value)
+	return v.Err()
+}
+
+func (s *Spec) check(v *validation.Validator, field string, schema *Schema, value interface{}) {
+	schema = s.Doc.Resolve(schema)
+	if schema == nil || value == nil {
+		// null decodes to the zero value, like a missing property
+		return
+	}
+	switch schema.Type {
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			v.Add(field, validation.CodeInvalidType, "must be a string")
+			return
+		}
+		if schema.Format == "uuid" {
+			if _, err := uuid.Parse(str); err != nil {
+				v.Add(field, validation.CodeInvalid, "must be a UUID")
+			}
+		}
+		if len(schema.Enum) > 0 && str != "" && !contains(schema.Enum, str) {
+			v.Add(field, validation.CodeInvalidEnum, "must be one of %s", strings.Join(schema.Enum, ", "))
+		}
+	case "integer":
+		n, ok := value.(json.Number)
+		if ok {
+			_, err := n.Int64()
+			ok = err == nil
+		}
+		if !ok {
+			v.Add(field, validation.CodeInvalidType, "must be an integer")
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			v.Add(field, validation.CodeInvalidType, "must be a number")
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			v.Add(field, validation.CodeInvalidType, "must be a boolean")
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			v.Add(field, validation.CodeInvalidType, "must be an array")
+			return
+		}
+		for i, item := range items {
+			s.check(v.Index(field, i), "", schema.Items, item)
+		}
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			v.Add(field, validation.CodeInvalidType, "must be an object")
+			return
+		}
+		nested := v.At(field)
+		for _, name := range schema.Required {
+			if obj[name] == nil {
+				nested.Add(name, validation.CodeRequired, "is required")
+			}
+		}
+		for _, prop := range schema.Properties {
+			if val, ok := obj[prop.Name]; ok {
+				s.check(nested, prop.Name, prop.Schema, val)
+			}
+		}
+		if schema.AdditionalProperties != nil {
+			names := make([]string, 0, len(obj))
+			for name := range obj {
+				if schema.Properties.Get(name) == nil {
+					names = append(names, name)
+				}
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				s.check(nested, name, schema.AdditionalProperties, obj[name])
+			}
+		}
+	}
+}
+
+func article(typ string) string {
+	if typ == "array" || typ == "object" {
+		return "an " + typ
+	}
+	return "a " + typ
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory is a per-process Store; with several replicas each one limits on
+// its own, so a caller gets the limit once per replica
+type Memory struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// full is when the bucket will have refilled
+	full time.Time
+}
+
+// NewMemory returns an empty store
+func NewMemory() *Memory {
+	return &Memory{buckets: map[string]*bucket{}}
+}
+
+func (m *Memory) Take(_ context.Context, key string, limit Limit) (bool, float64, error) {
+	now := time.Now()
+	burst := float64(limit.Burst)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, updated: now}
+		m.buckets[key] = b
+	}
+	b.tokens += now.Sub(b.updated).Seconds() * limit.Rate
+	if b.tokens > burst {
+		b.tokens = burst
+	}
+	b.updated = now
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	b.full = now.Add(refill(burst-b.tokens, limit.Rate))
+	return allowed, b.tokens, nil
+}
+
+func (m *Memory) Sweep(context.Context) error {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, b := range m.buckets {
+		if !now.Before(b.full) {
+			delete(m.buckets, key)
+		}
+	}
+	return nil
+}
+
+// Len is the number of buckets
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.buckets)
+}
@@ -0,0 +1,33 @@
+package ratelimit
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Postgres is a Store shared by the replicas, in the UNLOGGED rate_limits
+// table created by the migrations. A request costs one call of
+// rate_limit_take, which locks the caller's bucket row while it refills it.
+type Postgres struct {
+	db *gorm.DB
+}
+
+// NewPostgres keeps the buckets through db
+func NewPostgres(db *gorm.DB) *Postgres {
+	return &Postgres{db: db}
+}
+
+func (p *Postgres) Take(ctx context.Context, key string, limit Limit) (bool, float64, error) {
+	var row struct {
+		Allowed bool
+		Tokens  float64
+	}
+	err := p.db.WithContext(ctx).Raw("SELECT allowed, tokens FROM rate_limit_take(?, ?, ?)",
+		key, limit.Rate, float64(limit.Burst)).Scan(&row).Error
+	return row.Allowed, row.Tokens, err
+}
+
+func (p *Postgres) Sweep(ctx context.Context) error {
+	return p.db.WithContext(ctx).Exec("DELETE FROM rate_limits WHERE full_at <= now()").Error
+}
@@ -0,0 +1,115 @@
+// Package ratelimit limits how often a caller may use a group of routes.
+// Each caller gets a token bucket per group: it holds up to Burst tokens,
+// refills at Rate and every request takes one. Buckets live in a Store,
+// per process (Memory) or shared by the replicas (Postgres).
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"math"
+	"time"
+)
+
+// Route groups; each has its own limit, see config.RateLimitConfig
+const (
+	// Public is the public catalog reads
+	Public = "public"
+	// Admin is every route under /api/admin
+	Admin = "admin"
+	// Login is the routes that check credentials or codes
+	Login = "login"
+	// Upload is media uploads, on top of Admin
+	Upload = "upload"
+)
+
+// Limit is a token bucket: Burst requests at once, then Rate per second
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// PerMinute allows n requests a minute, burst of them at once
+func PerMinute(n, burst int) Limit {
+	return Limit{Rate: float64(n) / 60, Burst: burst}
+}
+
+// Store keeps the buckets
+type Store interface {
+	// Take refills the bucket of key and takes a token from it if there is
+	// one. It returns whether it did and the tokens left.
+	Take(ctx context.Context, key string, limit Limit) (bool, float64, error)
+	// Sweep removes buckets that have refilled; they start out full anyway
+	Sweep(ctx context.Context) error
+}
+
+// Decision is the outcome of one request
+type Decision struct {
+	Allowed bool
+	Limit   int
+	// Remaining is the requests left in the bucket
+	Remaining int
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is how long until the next request is allowed, when this
+	// one was not
+	RetryAfter time.Duration
+}
+
+// Limiter fronts a Store. Store errors are logged and the request is
+// allowed: an unavailable store must not take the API down with it.
+type Limiter struct {
+	store Store
+}
+
+// New limits with the buckets in store
+func New(store Store) *Limiter {
+	return &Limiter{store: store}
+}
+
+// Take takes a token from the bucket of key. A nil Limiter allows everything.
+func (l *Limiter) Take(ctx context.Context, key string, limit Limit) Decision {
+	d := Decision{Allowed: true, Limit: limit.Burst, Remaining: limit.Burst}
+	if l == nil || limit.Rate <= 0 {
+		return d
+	}
+	ok, tokens, err := l.store.Take(ctx, key, limit)
+	if err != nil {
+		log.Printf("ratelimit: take: %v", err)
+		return d
+	}
+	d.Allowed = ok
+	d.Remaining = int(math.Floor(tokens))
+	d.Reset = refill(float64(limit.Burst)-tokens, limit.Rate)
+	if !ok {
+		d.RetryAfter = refill(1-tokens, limit.Rate)
+	}
+	return d
+}
+
+// Run sweeps refilled buckets every interval until ctx is done
+func (l *Limiter) Run(ctx context.Context, interval time.Duration) {
+	if l == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := l.store.Sweep(ctx); err != nil {
+				log.Printf("ratelimit: sweep: %v", err)
+			}
+		}
+	}
+}
+
+// refill is how long the bucket takes to gain tokens at rate
+func refill(tokens, rate float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	return time.Duration(tokens / rate * float64(time.Second))
+}
@@ -0,0 +1,578 @@
+// Package realtime tracks who is editing which lesson: presence, expiring soft
+// locks on lesson components and save notifications. A Hub serves the
+// WebSocket clients of one process; with a Relay it exchanges events with the
+// hubs of the other replicas.
+package realtime
+
+import (
+	"context"
+	"courseai/backend/internal/health"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event types
+const (
+	EventSnapshot   = "snapshot" // sent to a client after it joins: members and locks
+	EventJoin       = "join"
+	EventLeave      = "leave"
+	EventPresence   = "presence" // a member moved to another section
+	EventLock       = "lock"     // a component was locked or the lock renewed
+	EventUnlock     = "unlock"
+	EventLockDenied = "lock_denied" // sent only to the requester, with the current lock
+	EventSaved      = "saved"
+	EventDeleted    = "deleted"
+	EventPong       = "pong"
+	EventError      = "error"
+)
+
+// maxKeyLength bounds section and component names sent by clients
+const maxKeyLength = 200
+
+// Names of the hub and relay loops in worker heartbeats (health.Beat)
+const (
+	HubWorker   = "realtime_hub"
+	RelayWorker = "realtime_relay"
+)
+
+// sendBuffer is how many events may queue for a client before it is dropped as too slow
+const sendBuffer = 64
+
+// Member is one connected editor
+type Member struct {
+	ClientID string    `json:"client_id"`
+	UserID   uuid.UUID `json:"user_id"`
+	Username string    `json:"username"`
+	// Section is the part of the editor the member is in, e.g. "content_blocks"
+	Section string `json:"section,omitempty"`
+
+	remote   bool
+	lastSeen time.Time
+}
+
+// Lock is an advisory lock on one component, e.g. "content-blocks/<id>"
+type Lock struct {
+	Component  string    `json:"component"`
+	ClientID   string    `json:"client_id"`
+	UserID     uuid.UUID `json:"user_id"`
+	Username   string    `json:"username"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// beats decides between two replicas granting the same component concurrently:
+// the older lock wins, ties go to the lower client ID
+func (l *Lock) beats(o *Lock) bool {
+	if !l.AcquiredAt.Equal(o.AcquiredAt) {
+		return l.AcquiredAt.Before(o.AcquiredAt)
+	}
+	return l.ClientID < o.ClientID
+}
+
+// Event is what clients receive and what replicas exchange
+type Event struct {
+	Type     string    `json:"type"`
+	LessonID uuid.UUID `json:"lesson_id"`
+	// Member is the subject of join/leave/presence, or who saved for saved/deleted
+	Member  *Member  `json:"member,omitempty"`
+	Lock    *Lock    `json:"lock,omitempty"`
+	Members []Member `json:"members,omitempty"`
+	Locks   []Lock   `json:"locks,omitempty"`
+	// Component and Version describe a save
+	Component string    `json:"component,omitempty"`
+	Version   int       `json:"version,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	At        time.Time `json:"at"`
+}
+
+// Relay carries events between the hubs of different replicas
+type Relay interface {
+	Publish(payload []byte) error
+}
+
+type envelope struct {
+	Replica string `json:"replica"`
+	Event   Event  `json:"event"`
+}
+
+// Client is one WebSocket connection in a lesson room
+type Client struct {
+	ID       string
+	LessonID uuid.UUID
+	UserID   uuid.UUID
+	Username string
+
+	send   chan []byte
+	closed bool
+}
+
+// Send delivers the encoded events for the client; it is closed when the client leaves
+func (c *Client) Send() <-chan []byte {
+	return c.send
+}
+
+type room struct {
+	clients map[string]*Client
+	members map[string]*Member
+	locks   map[string]*Lock
+}
+
+// Hub holds the lesson rooms of this process
+type Hub struct {
+	replica     string
+	lockTTL     time.Duration
+	presenceTTL time.Duration
+	relay       Relay
+
+	mu    sync.Mutex
+	rooms map[uuid.UUID]*room
+}
+
+// NewHub creates a hub; locks expire after lockTTL unless renewed and members on
+// other replicas are dropped after presenceTTL without news from them
+func NewHub(lockTTL, presenceTTL time.Duration) *Hub {
+	return &Hub{
+		replica:     uuid.NewString(),
+		lockTTL:     lockTTL,
+		presenceTTL: presenceTTL,
+		rooms:       make(map[uuid.UUID]*room),
+	}
+}
+
+// SetRelay connects the hub to the other replicas
+func (h *Hub) SetRelay(r Relay) {
+	h.mu.Lock()
+	h.relay = r
+	h.mu.Unlock()
+}
+
+// Join adds a client to the lesson room and sends it the current snapshot
+func (h *Hub) Join(lessonID, userID uuid.UUID, username string) *Client {
+	c := &Client{
+		ID:       uuid.NewString(),
+		LessonID: lessonID,
+		UserID:   userID,
+		Username: username,
+		send:     make(chan []byte, sendBuffer),
+	}
+	member := &Member{ClientID: c.ID, UserID: userID, Username: username, lastSeen: time.Now()}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r := h.rooms[lessonID]
+	if r == nil {
+		r = &room{clients: map[string]*Client{}, members: map[string]*Member{}, locks: map[string]*Lock{}}
+		h.rooms[lessonID] = r
+	}
+	r.clients[c.ID] = c
+	r.members[c.ID] = member
+
+	snapshot := Event{Type: EventSnapshot, LessonID: lessonID, Member: copyMember(member), Members: []Member{}, Locks: []Lock{}}
+	for _, m := range r.members {
+		snapshot.Members = append(snapshot.Members, *m)
+	}
+	now := time.Now()
+	for _, l := range r.locks {
+		if l.ExpiresAt.After(now) {
+			snapshot.Locks = append(snapshot.Locks, *l)
+		}
+	}
+	h.sendLocked(r, c, snapshot)
+	h.broadcastLocked(r, Event{Type: EventJoin, LessonID: lessonID, Member: copyMember(member)}, true)
+	return c
+}
+
+// Leave removes a client, releasing its locks
+func (h *Hub) Leave(c *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if r := h.rooms[c.LessonID]; r != nil {
+		h.removeLocked(r, c)
+	}
+}
+
+// Close disconnects every client, e.g. on shutdown
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, r := range h.rooms {
+		for _, c := range r.clients {
+			h.removeLocked(r, c)
+		}
+	}
+}
+
+type clientMessage struct {
+	Type      string `json:"type"` // presence, lock, unlock or ping
+	Section   string `json:"section"`
+	Component string `json:"component"`
+}
+
+// Handle processes one message received from a client
+func (h *Hub) Handle(c *Client, raw []byte) {
+	var msg clientMessage
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		h.reply(c, Event{Type: EventError, LessonID: c.LessonID, Error: "invalid message"})
+		return
+	}
+	if len(msg.Section) > maxKeyLength || len(msg.Component) > maxKeyLength {
+		h.reply(c, Event{Type: EventError, LessonID: c.LessonID, Error: "section or component too long"})
+		return
+	}
+	switch msg.Type {
+	case "presence":
+		h.setSection(c, msg.Section)
+	case "lock":
+		h.lock(c, msg.Component)
+	case "unlock":
+		h.unlock(c, msg.Component)
+	case "ping":
+		h.reply(c, Event{Type: EventPong, LessonID: c.LessonID})
+	default:
+		h.reply(c, Event{Type: EventError, LessonID: c.LessonID, Error: "unknown message type"})
+	}
+}
+
+func (h *Hub) reply(c *Client, ev Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if r := h.rooms[c.LessonID]; r != nil {
+		h.sendLocked(r, c, ev)
+	}
+}
+
+func (h *Hub) setSection(c *Client, section string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r := h.rooms[c.LessonID]
+	if r == nil || r.members[c.ID] == nil {
+		return
+	}
+	m := r.members[c.ID]
+	m.Section = section
+	h.broadcastLocked(r, Event{Type: EventPresence, LessonID: c.LessonID, Member: copyMember(m)}, true)
+}
+
+func (h *Hub) lock(c *Client, component string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r := h.rooms[c.LessonID]
+	if r == nil {
+		return
+	}
+	if component == "" {
+		h.sendLocked(r, c, Event{Type: EventError, LessonID: c.LessonID, Error: "component is required"})
+		return
+	}
+
+	now := time.Now()
+	existing := r.locks[component]
+	if existing != nil && existing.ClientID != c.ID && existing.ExpiresAt.After(now) {
+		held := *existing
+		h.sendLocked(r, c, Event{Type: EventLockDenied, LessonID: c.LessonID, Lock: &held})
+		return
+	}
+	l := &Lock{Component: component, ClientID: c.ID, UserID: c.UserID, Username: c.Username, AcquiredAt: now}
+	if existing != nil && existing.ClientID == c.ID {
+		l.AcquiredAt = existing.AcquiredAt // renewal
+	}
+	l.ExpiresAt = now.Add(h.lockTTL)
+	r.locks[component] = l
+	granted := *l
+	h.broadcastLocked(r, Event{Type: EventLock, LessonID: c.LessonID, Lock: &granted}, true)
+}
+
+func (h *Hub) unlock(c *Client, component string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r := h.rooms[c.LessonID]
+	if r == nil {
+		return
+	}
+	l := r.locks[component]
+	if l == nil || l.ClientID != c.ID {
+		return
+	}
+	delete(r.locks, component)
+	released := *l
+	h.broadcastLocked(r, Event{Type: EventUnlock, LessonID: c.LessonID, Lock: &released}, true)
+}
+
+// NotifySaved tells the editors of a lesson that userID saved a new version;
+// component names the changed component, if the save touched only one
+func (h *Hub) NotifySaved(lessonID, userID uuid.UUID, username, component string, version int) {
+	h.notify(Event{
+		Type:      EventSaved,
+		LessonID:  lessonID,
+		Member:    &Member{UserID: userID, Username: username},
+		Component: component,
+		Version:   version,
+	})
+}
+
+// NotifyDeleted tells the editors of a lesson that it was deleted
+func (h *Hub) NotifyDeleted(lessonID, userID uuid.UUID, username string) {
+	h.notify(Event{Type: EventDeleted, LessonID: lessonID, Member: &Member{UserID: userID, Username: username}})
+}
+
+func (h *Hub) notify(ev Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if r := h.rooms[ev.LessonID]; r != nil {
+		h.broadcastLocked(r, ev, false)
+	}
+	h.publishLocked(ev)
+}
+
+// Receive applies an event published by another replica
+func (h *Hub) Receive(payload []byte) {
+	var env envelope
+	if err := json.Unmarshal(payload, &env); err != nil {
+		log.Printf("realtime: invalid relay payload: %v", err)
+		return
+	}
+	if env.Replica == h.replica {
+		return
+	}
+	ev := env.Event
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r := h.rooms[ev.LessonID]
+	if r == nil {
+		// nobody here edits this lesson; joiners get the state when the others re-announce
+		return
+	}
+
+	switch ev.Type {
+	case EventJoin, EventPresence:
+		if ev.Member == nil {
+			return
+		}
+		m := r.members[ev.Member.ClientID]
+		if m != nil && !m.remote {
+			return
+		}
+		changed := m == nil || m.Section != ev.Member.Section
+		incoming := *ev.Member
+		incoming.remote, incoming.lastSeen = true, time.Now()
+		r.members[incoming.ClientID] = &incoming
+		if changed {
+			h.deliverLocked(r, ev)
+		}
+		if ev.Type == EventJoin {
+			// let the newcomer's replica learn who is here
+			h.announceLocked(ev.LessonID, r)
+		}
+	case EventLeave:
+		if ev.Member == nil {
+			return
+		}
+		if m := r.members[ev.Member.ClientID]; m != nil && m.remote {
+			delete(r.members, m.ClientID)
+			h.deliverLocked(r, ev)
+		}
+	case EventLock:
+		if ev.Lock == nil {
+			return
+		}
+		existing := r.locks[ev.Lock.Component]
+		if existing == nil || existing.ClientID == ev.Lock.ClientID || !existing.ExpiresAt.After(time.Now()) || ev.Lock.beats(existing) {
+			incoming := *ev.Lock
+			r.locks[incoming.Component] = &incoming
+			// re-announcements of a lock clients already know about are not repeated
+			if existing == nil || *existing != incoming {
+				h.deliverLocked(r, ev)
+			}
+		}
+	case EventUnlock:
+		if ev.Lock == nil {
+			return
+		}
+		if existing := r.locks[ev.Lock.Component]; existing != nil && existing.ClientID == ev.Lock.ClientID {
+			delete(r.locks, ev.Lock.Component)
+			h.deliverLocked(r, ev)
+		}
+	case EventSaved, EventDeleted:
+		h.deliverLocked(r, ev)
+	}
+}
+
+// Resync re-announces local members and locks, e.g. after the relay reconnected
+func (h *Hub) Resync() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for lessonID, r := range h.rooms {
+		h.announceLocked(lessonID, r)
+	}
+}
+
+// Run expires locks and stale remote members, and periodically re-announces
+// local presence to the other replicas, until ctx is done
+func (h *Hub) Run(ctx context.Context) {
+	sweep := time.NewTicker(5 * time.Second)
+	defer sweep.Stop()
+	announce := time.NewTicker(h.presenceTTL / 3)
+	defer announce.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			h.Close()
+			return
+		case <-sweep.C:
+			h.sweep()
+			health.Beat(HubWorker)
+		case <-announce.C:
+			h.Resync()
+		}
+	}
+}
+
+func (h *Hub) sweep() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now()
+	for lessonID, r := range h.rooms {
+		for component, l := range r.locks {
+			if !l.ExpiresAt.After(now) {
+				delete(r.locks, component)
+				expired := *l
+				// every replica expires its own copy, so this is not relayed
+				h.broadcastLocked(r, Event{Type: EventUnlock, LessonID: lessonID, Lock: &expired}, false)
+			}
+		}
+		for id, m := range r.members {
+			if m.remote && now.Sub(m.lastSeen) > h.presenceTTL {
+				delete(r.members, id)
+				h.broadcastLocked(r, Event{Type: EventLeave, LessonID: lessonID, Member: copyMember(m)}, false)
+			}
+		}
+	}
+}
+
+// announceLocked publishes the local members and locks of a room
+func (h *Hub) announceLocked(lessonID uuid.UUID, r *room) {
+	if h.relay == nil {
+		return
+	}
+	for _, m := range r.members {
+		if !m.remote {
+			h.publishLocked(Event{Type: EventPresence, LessonID: lessonID, Member: copyMember(m)})
+		}
+	}
+	now := time.Now()
+	for _, l := range r.locks {
+		if _, local := r.clients[l.ClientID]; local && l.ExpiresAt.After(now) {
+			held := *l
+			h.publishLocked(Event{Type: EventLock, LessonID: lessonID, Lock: &held})
+		}
+	}
+}
+
+// removeLocked drops a local client, its locks and its membership
+func (h *Hub) removeLocked(r *room, c *Client) {
+	if c.closed {
+		return
+	}
+	c.closed = true
+	close(c.send)
+	delete(r.clients, c.ID)
+
+	for component, l := range r.locks {
+		if l.ClientID == c.ID {
+			delete(r.locks, component)
+			released := *l
+			h.broadcastLocked(r, Event{Type: EventUnlock, LessonID: c.LessonID, Lock: &released}, true)
+		}
+	}
+	if m := r.members[c.ID]; m != nil {
+		delete(r.members, c.ID)
+		h.broadcastLocked(r, Event{Type: EventLeave, LessonID: c.LessonID, Member: copyMember(m)}, true)
+	}
+	if len(r.clients) == 0 {
+		delete(h.rooms, c.LessonID)
+	}
+}
+
+// broadcastLocked delivers ev to the local clients of the room and, if publish
+// is set, to the other replicas
+func (h *Hub) broadcastLocked(r *room, ev Event, publish bool) {
+	h.deliverLocked(r, ev)
+	if publish {
+		h.publishLocked(ev)
+	}
+}
+
+func (h *Hub) deliverLocked(r *room, ev Event) {
+	for _, c := range r.clients {
+		h.sendLocked(r, c, ev)
+	}
+}
+
+// sendLocked queues ev for c; a client whose queue is full is disconnected
+func (h *Hub) sendLocked(r *room, c *Client, ev Event) {
+	if c.closed {
+		return
+	}
+	if ev.At.IsZero() {
+		ev.At = time.Now().UTC()
+	}
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		log.Printf("realtime: encode %s event: %v", ev.Type, err)
+		return
+	}
+	select {
+	case c.send <- payload:
+	default:
+		log.Printf("realtime: dropping slow client %s (user %s)", c.ID, c.Username)
+		h.removeLocked(r, c)
+	}
+}
+
+func (h *Hub) publishLocked(ev Event) {
+	if h.relay == nil {
+		return
+	}
+	if ev.At.IsZero() {
+		ev.At = time.Now().UTC()
+	}
+	payload, err := json.Marshal(envelope{Replica: h.replica, Event: ev})
+	if err != nil {
+		log.Printf("realtime: encode %s event: %v", ev.Type, err)
+		return
+	}
+	if err := h.relay.Publish(payload); err != nil {
+		log.Printf("realtime: relay %s event: %v", ev.Type, err)
+	}
+}
+
+func copyMember(m *Member) *Member {
+	cp := *m
+	return &cp
+}
+
+var defaultHub *Hub
+
+// SetDefault installs the hub used by the package-level notify functions
+func SetDefault(h *Hub) {
+	defaultHub = h
+}
+
+// NotifySaved notifies through the default hub; it is a no-op without one
+func NotifySaved(lessonID, userID uuid.UUID, username, component string, version int) {
+	if defaultHub != nil {
+		defaultHub.NotifySaved(lessonID, userID, username, component, version)
+	}
+}
+
+// NotifyDeleted notifies through the default hub; it is a no-op without one
+func NotifyDeleted(lessonID, userID uuid.UUID, username string) {
+	if defaultHub != nil {
+		defaultHub.NotifyDeleted(lessonID, userID, username)
+	}
+}
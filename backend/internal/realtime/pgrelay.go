@@ -0,0 +1,98 @@
+package realtime
+
+import (
+	"context"
+	"courseai/backend/internal/health"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// notifyChannel is the Postgres channel the replicas exchange events on
+const notifyChannel = "courseai_realtime"
+
+// maxNotifyPayload is Postgres' NOTIFY payload limit (8000 bytes) minus some slack
+const maxNotifyPayload = 7900
+
+var errRelayBusy = errors.New("relay queue is full")
+
+// PGRelay relays hub events between replicas with Postgres LISTEN/NOTIFY.
+// Publish never blocks the hub: payloads are queued and sent by Run.
+type PGRelay struct {
+	db       *gorm.DB
+	hub      *Hub
+	listener *pq.Listener
+	out      chan []byte
+}
+
+// NewPGRelay starts listening on dsn and attaches the relay to hub
+func NewPGRelay(dsn string, db *gorm.DB, hub *Hub) (*PGRelay, error) {
+	listener := pq.NewListener(dsn, 2*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("realtime: listener event %d: %v", ev, err)
+		}
+	})
+	if err := listener.Listen(notifyChannel); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	r := &PGRelay{db: db, hub: hub, listener: listener, out: make(chan []byte, 256)}
+	hub.SetRelay(r)
+	return r, nil
+}
+
+// Publish queues payload for NOTIFY
+func (r *PGRelay) Publish(payload []byte) error {
+	if len(payload) > maxNotifyPayload {
+		return errors.New("event too large for NOTIFY")
+	}
+	select {
+	case r.out <- payload:
+		return nil
+	default:
+		return errRelayBusy
+	}
+}
+
+// Run sends queued events and hands received ones to the hub until ctx is done
+func (r *PGRelay) Run(ctx context.Context) {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case payload := <-r.out:
+				if err := r.db.Exec("SELECT pg_notify(?, ?)", notifyChannel, string(payload)).Error; err != nil {
+					log.Printf("realtime: NOTIFY failed: %v", err)
+				}
+			}
+		}
+	}()
+
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			r.listener.Close()
+			return
+		case n := <-r.listener.Notify:
+			if n == nil {
+				// the connection was re-established and notifications may have been lost
+				r.hub.Resync()
+				continue
+			}
+			r.hub.Receive([]byte(n.Extra))
+		case <-ping.C:
+			health.Beat(RelayWorker)
+			go func() {
+				if err := r.listener.Ping(); err != nil {
+					log.Printf("realtime: listener ping: %v", err)
+				}
+			}()
+		}
+	}
+}
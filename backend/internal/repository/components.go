@@ -0,0 +1,325 @@
+package repository
+
+import (
+	"context"
+	"courseai/backend/internal/models"
+	"reflect"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LessonComponent describes one kind of lesson child row so the granular
+// endpoints and the JSON Patch sync can treat them uniformly.
+type LessonComponent struct {
+	// Path is the URL segment, e.g. "content-blocks"
+	Path string
+	// JSONKey is the key in the lesson document, e.g. "content_blocks"
+	JSONKey string
+	// ParentField is the Go field referencing the parent ("LessonID", or "QuizID" for quiz options)
+	ParentField  string
+	ParentColumn string
+	// OwnerType is the media owner type of the component; empty if it has no media
+	OwnerType models.MediaOwnerType
+	// Fields are the editable columns; JSON keys equal column names
+	Fields []string
+	// Required fields must be non-empty strings
+	Required []string
+	// Single components exist at most once per lesson (objectives, preparation)
+	Single   bool
+	Preloads []string
+	// Children are nested rows synced with the component; Relation is their field on the parent
+	Children *LessonComponent
+	Relation string
+
+	newOne  func() interface{}
+	newList func() interface{}
+}
+
+// New returns a pointer to a new row of the component
+func (comp *LessonComponent) New() interface{} { return comp.newOne() }
+
+// NewList returns a pointer to a new slice of rows; nil for single components
+func (comp *LessonComponent) NewList() interface{} {
+	if comp.newList == nil {
+		return nil
+	}
+	return comp.newList()
+}
+
+var QuizOptionsComponent = &LessonComponent{
+	Path: "options", JSONKey: "options", ParentField: "QuizID", ParentColumn: "quiz_id", Relation: "Options",
+	Fields:   []string{"content", "is_correct", "explanation", "sort_order"},
+	Required: []string{"content"},
+	newOne:   func() interface{} { return &models.LessonQuizOption{} },
+	newList:  func() interface{} { return &[]models.LessonQuizOption{} },
+}
+
+var QuizzesComponent = &LessonComponent{
+	Path: "quizzes", JSONKey: "quizzes", ParentField: "LessonID", ParentColumn: "lesson_id", Relation: "Quizzes",
+	Fields:   []string{"title", "description", "quiz_type", "sort_order"},
+	Required: []string{"title", "quiz_type"},
+	Children: QuizOptionsComponent,
+	newOne:   func() interface{} { return &models.LessonQuiz{} },
+	newList:  func() interface{} { return &[]models.LessonQuiz{} },
+}
+
+// LessonComponents lists every component kind in the order they are synced
+var LessonComponents = []*LessonComponent{
+	{
+		Path: "objectives", JSONKey: "objectives", ParentField: "LessonID", ParentColumn: "lesson_id", Relation: "Objectives",
+		Fields: []string{"knowledge", "thinking", "skills", "attitude"},
+		Single: true,
+		newOne: func() interface{} { return &models.LessonObjective{} },
+	},
+	{
+		Path: "models", JSONKey: "models", ParentField: "LessonID", ParentColumn: "lesson_id", Relation: "Models",
+		OwnerType: models.OwnerLessonModel,
+		Fields:    []string{"title", "description", "sort_order"},
+		Required:  []string{"title"},
+		Preloads:  []string{"Media"},
+		newOne:    func() interface{} { return &models.LessonModel{} },
+		newList:   func() interface{} { return &[]models.LessonModel{} },
+	},
+	{
+		Path: "preparation", JSONKey: "preparation", ParentField: "LessonID", ParentColumn: "lesson_id", Relation: "Preparation",
+		OwnerType: models.OwnerLessonPreparation,
+		Fields:    []string{"notes"},
+		Single:    true,
+		Preloads:  []string{"Media"},
+		newOne:    func() interface{} { return &models.LessonPreparation{} },
+	},
+	{
+		Path: "builds", JSONKey: "builds", ParentField: "LessonID", ParentColumn: "lesson_id", Relation: "Builds",
+		OwnerType: models.OwnerLessonBuild,
+		Fields:    []string{"build_type", "title", "description", "sort_order"},
+		Required:  []string{"title", "build_type"},
+		Preloads:  []string{"Media"},
+		newOne:    func() interface{} { return &models.LessonBuild{} },
+		newList:   func() interface{} { return &[]models.LessonBuild{} },
+	},
+	{
+		Path: "content-blocks", JSONKey: "content_blocks", ParentField: "LessonID", ParentColumn: "lesson_id", Relation: "ContentBlocks",
+		OwnerType: models.OwnerLessonContentBlock,
+		Fields:    []string{"title", "subtitle", "description", "usage_text", "example_text", "sort_order"},
+		Required:  []string{"title"},
+		Preloads:  []string{"Media"},
+		newOne:    func() interface{} { return &models.LessonContentBlock{} },
+		newList:   func() interface{} { return &[]models.LessonContentBlock{} },
+	},
+	{
+		Path: "attachments", JSONKey: "attachments", ParentField: "LessonID", ParentColumn: "lesson_id", Relation: "Attachments",
+		OwnerType: models.OwnerLessonAttachment,
+		Fields:    []string{"title", "description", "file_type", "sort_order"},
+		Required:  []string{"title"},
+		Preloads:  []string{"Media"},
+		newOne:    func() interface{} { return &models.LessonAttachment{} },
+		newList:   func() interface{} { return &[]models.LessonAttachment{} },
+	},
+	{
+		Path: "challenges", JSONKey: "challenges", ParentField: "LessonID", ParentColumn: "lesson_id", Relation: "Challenges",
+		OwnerType: models.OwnerLessonChallenge,
+		Fields:    []string{"title", "subtitle", "description", "instructions", "sort_order"},
+		Required:  []string{"title"},
+		Preloads:  []string{"Media"},
+		newOne:    func() interface{} { return &models.LessonChallenge{} },
+		newList:   func() interface{} { return &[]models.LessonChallenge{} },
+	},
+	QuizzesComponent,
+}
+
+// ComponentID returns the ID of a component row
+func ComponentID(item interface{}) uuid.UUID {
+	return reflect.ValueOf(item).Elem().FieldByName("ID").Interface().(uuid.UUID)
+}
+
+type gormComponents struct{ db *gorm.DB }
+
+// query preloads the media and the children of comp, in editor order
+func (r *gormComponents) query(ctx context.Context, comp *LessonComponent) *gorm.DB {
+	db := conn(r.db, ctx)
+	for _, p := range comp.Preloads {
+		db = db.Preload(p, OrderedMedia)
+	}
+	if comp.Children != nil {
+		db = db.Preload(comp.Children.Relation, func(db *gorm.DB) *gorm.DB {
+			return db.Order("sort_order ASC, created_at ASC")
+		})
+	}
+	return db
+}
+
+func (r *gormComponents) List(ctx context.Context, comp *LessonComponent, parentID uuid.UUID) (interface{}, error) {
+	list := comp.newList()
+	err := r.query(ctx, comp).Where(comp.ParentColumn+" = ?", parentID).Order("sort_order ASC, created_at ASC").Find(list).Error
+	return list, err
+}
+
+func (r *gormComponents) Get(ctx context.Context, comp *LessonComponent, parentID, id uuid.UUID) (interface{}, error) {
+	item := comp.newOne()
+	query := r.query(ctx, comp).Where(comp.ParentColumn+" = ?", parentID)
+	if id != uuid.Nil {
+		query = query.Where("id = ?", id)
+	}
+	if err := query.First(item).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return item, nil
+}
+
+func (r *gormComponents) IDs(ctx context.Context, comp *LessonComponent, parentID uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := conn(r.db, ctx).Model(comp.newOne()).Where(comp.ParentColumn+" = ?", parentID).Pluck("id", &ids).Error
+	return ids, err
+}
+
+func (r *gormComponents) NextSortOrder(ctx context.Context, comp *LessonComponent, parentID uuid.UUID) (int, error) {
+	return nextSortOrder(conn(r.db, ctx).Model(comp.newOne()).Where(comp.ParentColumn+" = ?", parentID))
+}
+
+func (r *gormComponents) Create(ctx context.Context, comp *LessonComponent, item interface{}) error {
+	return conn(r.db, ctx).Omit(clause.Associations).Create(item).Error
+}
+
+func (r *gormComponents) Update(ctx context.Context, comp *LessonComponent, id uuid.UUID, item interface{}, fields []string) error {
+	return conn(r.db, ctx).Model(comp.newOne()).Where("id = ?", id).Select(fields).Updates(item).Error
+}
+
+func (r *gormComponents) Delete(ctx context.Context, comp *LessonComponent, id uuid.UUID) error {
+	return deleteComponent(conn(r.db, ctx), comp, id)
+}
+
+func (r *gormComponents) SetOrder(ctx context.Context, comp *LessonComponent, ids []uuid.UUID) error {
+	db := conn(r.db, ctx)
+	for i, id := range ids {
+		if err := db.Model(comp.newOne()).Where("id = ?", id).Update("sort_order", i).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteComponent removes a component with its media rows and child rows
+func deleteComponent(db *gorm.DB, comp *LessonComponent, id uuid.UUID) error {
+	if comp.OwnerType != "" {
+		if err := db.Where("owner_type = ? AND owner_id = ?", comp.OwnerType, id).Delete(&models.Media{}).Error; err != nil {
+			return err
+		}
+	}
+	if comp.Children != nil {
+		if err := db.Where(comp.Children.ParentColumn+" = ?", id).Delete(comp.Children.newOne()).Error; err != nil {
+			return err
+		}
+	}
+	return db.Where("id = ?", id).Delete(comp.newOne()).Error
+}
+
+// deleteTree removes every component of a lesson and all of its media
+func deleteTree(db *gorm.DB, lessonID uuid.UUID) error {
+	if err := db.Where("owner_type = ? AND owner_id = ?", models.OwnerLesson, lessonID).Delete(&models.Media{}).Error; err != nil {
+		return err
+	}
+	for _, comp := range LessonComponents {
+		var ids []uuid.UUID
+		if err := db.Model(comp.newOne()).Where(comp.ParentColumn+" = ?", lessonID).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if err := deleteComponent(db, comp, id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// insertTree inserts the components and media of lesson under it. Rows keep
+// the IDs set on them; a nil ID gets a new one.
+func insertTree(db *gorm.DB, lesson *models.Lesson) error {
+	var media []models.Media
+	own := func(ms []models.Media, ownerType models.MediaOwnerType, ownerID uuid.UUID) {
+		for _, m := range ms {
+			m.OwnerType, m.OwnerID = ownerType, ownerID
+			media = append(media, m)
+		}
+	}
+	create := func(row interface{}) error {
+		return db.Omit(clause.Associations).Create(row).Error
+	}
+
+	own(lesson.Media, models.OwnerLesson, lesson.ID)
+	if o := lesson.Objectives; o != nil {
+		o.LessonID = lesson.ID
+		if err := create(o); err != nil {
+			return err
+		}
+	}
+	for i := range lesson.Models {
+		m := &lesson.Models[i]
+		m.LessonID = lesson.ID
+		if err := create(m); err != nil {
+			return err
+		}
+		own(m.Media, models.OwnerLessonModel, m.ID)
+	}
+	if p := lesson.Preparation; p != nil {
+		p.LessonID = lesson.ID
+		if err := create(p); err != nil {
+			return err
+		}
+		own(p.Media, models.OwnerLessonPreparation, p.ID)
+	}
+	for i := range lesson.Builds {
+		b := &lesson.Builds[i]
+		b.LessonID = lesson.ID
+		if err := create(b); err != nil {
+			return err
+		}
+		own(b.Media, models.OwnerLessonBuild, b.ID)
+	}
+	for i := range lesson.ContentBlocks {
+		cb := &lesson.ContentBlocks[i]
+		cb.LessonID = lesson.ID
+		if err := create(cb); err != nil {
+			return err
+		}
+		own(cb.Media, models.OwnerLessonContentBlock, cb.ID)
+	}
+	for i := range lesson.Attachments {
+		a := &lesson.Attachments[i]
+		a.LessonID = lesson.ID
+		if err := create(a); err != nil {
+			return err
+		}
+		own(a.Media, models.OwnerLessonAttachment, a.ID)
+	}
+	for i := range lesson.Challenges {
+		ch := &lesson.Challenges[i]
+		ch.LessonID = lesson.ID
+		if err := create(ch); err != nil {
+			return err
+		}
+		own(ch.Media, models.OwnerLessonChallenge, ch.ID)
+	}
+	for i := range lesson.Quizzes {
+		q := &lesson.Quizzes[i]
+		q.LessonID = lesson.ID
+		if err := create(q); err != nil {
+			return err
+		}
+		for j := range q.Options {
+			q.Options[j].QuizID = q.ID
+		}
+		if len(q.Options) > 0 {
+			if err := db.Omit(clause.Associations).Create(&q.Options).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(media) == 0 {
+		return nil
+	}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&media).Error
+}
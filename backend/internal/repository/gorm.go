@@ -0,0 +1,980 @@
+package repository
+
+import (
+	"context"
+	"courseai/backend/internal/database"
+	"courseai/backend/internal/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// gormUnitOfWork implements UnitOfWork on a GORM handle
+type gormUnitOfWork struct {
+	db *gorm.DB
+}
+
+// NewUnitOfWork returns the GORM-backed unit of work for db
+func NewUnitOfWork(db *gorm.DB) UnitOfWork {
+	return &gormUnitOfWork{db: db}
+}
+
+func (u *gormUnitOfWork) Repositories() Repositories {
+	return newRepositories(u.db)
+}
+
+func (u *gormUnitOfWork) Do(ctx context.Context, fn func(r Repositories) error) error {
+	return u.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(newRepositories(tx))
+	})
+}
+
+func newRepositories(db *gorm.DB) Repositories {
+	return Repositories{
+		Assignments:    &gormAssignments{db},
+		Organizations:  &gormOrganizations{db},
+		Shares:         &gormShares{db},
+		Users:          &gormUsers{db},
+		RecoveryCodes:  &gormRecoveryCodes{db},
+		Identities:     &gormIdentities{db},
+		LoginStates:    &gormLoginStates{db},
+		LoginThrottles: &gormLoginThrottles{db},
+		LoginAttempts:  &gormLoginAttempts{db},
+		Programs:       &gormPrograms{db},
+		Subcourses:     &gormSubcourses{db},
+		Lessons:        &gormLessons{db},
+		Components:     &gormComponents{db},
+		Media:          &gormMedia{db},
+		Templates:      &gormTemplates{db},
+		Trash:          &gormTrash{db},
+	}
+}
+
+// conn binds db to ctx, which carries the organization queries are restricted to
+func conn(db *gorm.DB, ctx context.Context) *gorm.DB {
+	return db.WithContext(ctx)
+}
+
+// notFound maps GORM's missing-row error to ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// duplicate maps a unique constraint violation to ErrDuplicate
+func duplicate(err error) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+// bumpVersion increments the version of the model row id, provided it is
+// still at version. The row stays locked until the transaction ends, so
+// concurrent writers are serialised and the loser gets ErrVersionConflict.
+func bumpVersion(db *gorm.DB, model interface{}, id uuid.UUID, version int) error {
+	result := db.Model(model).Where("id = ? AND version = ?", id, version).
+		Updates(map[string]interface{}{"version": gorm.Expr("version + 1"), "updated_at": time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// slugInUse reports whether a row of model other than exceptID uses slug.
+// Slugs are unique across all organizations, and trashed rows keep theirs.
+func slugInUse(db *gorm.DB, model interface{}, slug string, exceptID uuid.UUID) (bool, error) {
+	var count int64
+	err := database.SkipTenant(db.Unscoped()).Model(model).Where("slug = ? AND id <> ?", slug, exceptID).Count(&count).Error
+	return count > 0, err
+}
+
+// trash moves the live rows of model matching query to the trash and
+// returns how many it moved
+func trash(db *gorm.DB, model interface{}, by uuid.UUID, at time.Time, query string, args ...interface{}) (int64, error) {
+	var deletedBy *uuid.UUID
+	if by != uuid.Nil {
+		deletedBy = &by
+	}
+	result := db.Model(model).Where(query, args...).
+		Updates(map[string]interface{}{"deleted_at": at, "deleted_by": deletedBy})
+	return result.RowsAffected, result.Error
+}
+
+// nextSortOrder returns the sort order that places a row after the rows query
+// selects
+func nextSortOrder(query *gorm.DB) (int, error) {
+	var max *int
+	if err := query.Select("MAX(sort_order)").Scan(&max).Error; err != nil {
+		return 0, err
+	}
+	if max == nil {
+		return 0, nil
+	}
+	return *max + 1, nil
+}
+
+// siblingIDs returns the live children of a parent in the order lists show them
+func siblingIDs(db *gorm.DB, model interface{}, parentColumn string, parentID uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := db.Model(model).Where(parentColumn+" = ?", parentID).
+		Order("sort_order ASC, created_at DESC").Pluck("id", &ids).Error
+	return ids, err
+}
+
+// setOrder numbers ids 0..n-1 and returns the ids whose position changed.
+// They get a new version, so a stale full update cannot undo the move.
+func setOrder(db *gorm.DB, model interface{}, ids []uuid.UUID) ([]uuid.UUID, error) {
+	var changed []uuid.UUID
+	for i, id := range ids {
+		result := db.Model(model).Where("id = ? AND sort_order <> ?", id, i).
+			Updates(map[string]interface{}{"sort_order": i, "version": gorm.Expr("version + 1")})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected > 0 {
+			changed = append(changed, id)
+		}
+	}
+	return changed, nil
+}
+
+type gormAssignments struct{ db *gorm.DB }
+
+func (r *gormAssignments) ActiveIDs(ctx context.Context, teacherID uuid.UUID, at time.Time) ([]uuid.UUID, []uuid.UUID, error) {
+	var rows []models.TeacherAssignment
+	err := conn(r.db, ctx).Select("scope_level", "program_id", "subcourse_id").
+		Where("teacher_id = ? AND status = ? AND (start_at IS NULL OR start_at <= ?) AND (end_at IS NULL OR end_at >= ?)", teacherID, models.AssignmentStatusActive, at, at).
+		Find(&rows).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	var programIDs, subcourseIDs []uuid.UUID
+	for _, a := range rows {
+		switch {
+		case a.ScopeLevel == models.ScopeProgram && a.ProgramID != nil:
+			programIDs = append(programIDs, *a.ProgramID)
+		case a.ScopeLevel == models.ScopeSubcourse && a.SubcourseID != nil:
+			subcourseIDs = append(subcourseIDs, *a.SubcourseID)
+		}
+	}
+	return programIDs, subcourseIDs, nil
+}
+
+func (r *gormAssignments) ListActive(ctx context.Context, teacherID uuid.UUID) ([]models.TeacherAssignment, error) {
+	var assignments []models.TeacherAssignment
+	err := conn(r.db, ctx).Where("teacher_id = ? AND status = ?", teacherID, models.AssignmentStatusActive).Find(&assignments).Error
+	return assignments, err
+}
+
+func (r *gormAssignments) ListCurrent(ctx context.Context, teacherID uuid.UUID, at time.Time) ([]models.TeacherAssignment, error) {
+	var assignments []models.TeacherAssignment
+	err := conn(r.db, ctx).
+		Where("teacher_id = ? AND status = ? AND (start_at IS NULL OR start_at <= ?) AND (end_at IS NULL OR end_at >= ?)", teacherID, models.AssignmentStatusActive, at, at).
+		Find(&assignments).Error
+	return assignments, err
+}
+
+func (r *gormAssignments) DeleteScope(ctx context.Context, teacherID uuid.UUID, scope models.AssignmentScope) error {
+	return conn(r.db, ctx).Where("teacher_id = ? AND scope_level = ?", teacherID, scope).Delete(&models.TeacherAssignment{}).Error
+}
+
+func (r *gormAssignments) Create(ctx context.Context, assignment *models.TeacherAssignment) error {
+	return conn(r.db, ctx).Create(assignment).Error
+}
+
+type gormUsers struct{ db *gorm.DB }
+
+func (r *gormUsers) FindTeacher(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	var user models.User
+	if err := conn(r.db, ctx).Where("id = ? AND role = ?", id, models.RoleTeacher).First(&user).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
+
+func (r *gormUsers) FindByUsername(ctx context.Context, username string) (*models.User, error) {
+	var user models.User
+	if err := conn(r.db, ctx).Where("username = ?", username).First(&user).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
+
+func (r *gormUsers) Get(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	var user models.User
+	if err := conn(r.db, ctx).First(&user, "id = ?", id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
+
+func (r *gormUsers) FindByLogin(ctx context.Context, login string) (*models.User, error) {
+	var user models.User
+	if err := conn(r.db, ctx).Where("username = ? OR email = ?", login, login).First(&user).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
+
+func (r *gormUsers) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	if err := conn(r.db, ctx).Where("LOWER(email) = LOWER(?)", email).First(&user).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
+
+func (r *gormUsers) UsernameInUse(ctx context.Context, username string) (bool, error) {
+	var count int64
+	err := conn(r.db, ctx).Model(&models.User{}).Where("username = ?", username).Count(&count).Error
+	return count > 0, err
+}
+
+// Principal reads the user and their organization in one query
+func (r *gormUsers) Usernames(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]string, error) {
+	names := make(map[uuid.UUID]string, len(ids))
+	if len(ids) == 0 {
+		return names, nil
+	}
+	var users []models.User
+	if err := database.SkipTenant(conn(r.db, ctx)).Select("id", "username").Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		names[u.ID] = u.Username
+	}
+	return names, nil
+}
+
+func (r *gormUsers) Principal(ctx context.Context, id uuid.UUID) (*Principal, error) {
+	var rows []Principal
+	err := conn(r.db, ctx).Table("users").
+		Select("users.role, users.status, users.organization_id, organizations.status AS org_status").
+		Joins("LEFT JOIN organizations ON organizations.id = users.organization_id").
+		Where("users.id = ?", id).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, ErrNotFound
+	}
+	return &rows[0], nil
+}
+
+func (r *gormUsers) Create(ctx context.Context, user *models.User) error {
+	return duplicate(conn(r.db, ctx).Create(user).Error)
+}
+
+func (r *gormUsers) SetRole(ctx context.Context, id uuid.UUID, role models.UserRole, organizationID *uuid.UUID) error {
+	return conn(r.db, ctx).Model(&models.User{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"role": role, "organization_id": organizationID}).Error
+}
+
+func (r *gormUsers) SetPasswordLoginDisabled(ctx context.Context, id uuid.UUID, disabled bool) error {
+	return conn(r.db, ctx).Model(&models.User{}).Where("id = ?", id).Update("password_login_disabled", disabled).Error
+}
+
+func (r *gormUsers) StartTOTP(ctx context.Context, id uuid.UUID, secret string) error {
+	return conn(r.db, ctx).Model(&models.User{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"totp_secret": secret, "totp_last_step": 0}).Error
+}
+
+func (r *gormUsers) EnableTOTP(ctx context.Context, id uuid.UUID, step int64) error {
+	return conn(r.db, ctx).Model(&models.User{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"totp_enabled": true, "totp_last_step": step}).Error
+}
+
+// UseTOTPStep is a conditional update, so it holds under concurrency
+func (r *gormUsers) UseTOTPStep(ctx context.Context, id uuid.UUID, step int64) (bool, error) {
+	result := conn(r.db, ctx).Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", id, step).
+		Update("totp_last_step", step)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *gormUsers) DisableTOTP(ctx context.Context, id uuid.UUID) error {
+	return conn(r.db, ctx).Model(&models.User{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"totp_enabled": false, "totp_secret": "", "totp_last_step": 0}).Error
+}
+
+type gormOrganizations struct{ db *gorm.DB }
+
+func (r *gormOrganizations) List(ctx context.Context) ([]models.Organization, error) {
+	var orgs []models.Organization
+	err := conn(r.db, ctx).Order("name ASC").Find(&orgs).Error
+	return orgs, err
+}
+
+func (r *gormOrganizations) Get(ctx context.Context, id uuid.UUID) (*models.Organization, error) {
+	var org models.Organization
+	if err := conn(r.db, ctx).First(&org, "id = ?", id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &org, nil
+}
+
+func (r *gormOrganizations) Find(ctx context.Context, ref string) (*models.Organization, error) {
+	db := conn(r.db, ctx)
+	query := db.Where("slug = ?", ref)
+	if id, err := uuid.Parse(ref); err == nil {
+		query = db.Where("id = ?", id)
+	}
+	var org models.Organization
+	if err := query.First(&org).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &org, nil
+}
+
+func (r *gormOrganizations) Default(ctx context.Context) (*models.Organization, error) {
+	return database.DefaultOrganization(conn(r.db, ctx))
+}
+
+func (r *gormOrganizations) Create(ctx context.Context, org *models.Organization) error {
+	return duplicate(conn(r.db, ctx).Create(org).Error)
+}
+
+func (r *gormOrganizations) Update(ctx context.Context, id uuid.UUID, updates *models.Organization) error {
+	return duplicate(conn(r.db, ctx).Model(&models.Organization{ID: id}).Updates(updates).Error)
+}
+
+func (r *gormOrganizations) SlugInUse(ctx context.Context, slug string, exceptID uuid.UUID) (bool, error) {
+	var count int64
+	err := conn(r.db, ctx).Model(&models.Organization{}).Where("slug = ? AND id <> ?", slug, exceptID).Count(&count).Error
+	return count > 0, err
+}
+
+type gormShares struct{ db *gorm.DB }
+
+func (r *gormShares) ListByProgram(ctx context.Context, programID uuid.UUID) ([]models.ProgramShare, error) {
+	var shares []models.ProgramShare
+	err := conn(r.db, ctx).Preload("Organization").Where("program_id = ?", programID).Find(&shares).Error
+	return shares, err
+}
+
+func (r *gormShares) Ensure(ctx context.Context, programID, organizationID uuid.UUID) (*models.ProgramShare, error) {
+	share := models.ProgramShare{ProgramID: programID, OrganizationID: organizationID}
+	if err := conn(r.db, ctx).Where(models.ProgramShare{ProgramID: programID, OrganizationID: organizationID}).FirstOrCreate(&share).Error; err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+func (r *gormShares) Delete(ctx context.Context, programID, organizationID uuid.UUID) error {
+	return conn(r.db, ctx).Where("program_id = ? AND organization_id = ?", programID, organizationID).Delete(&models.ProgramShare{}).Error
+}
+
+type gormRecoveryCodes struct{ db *gorm.DB }
+
+func (r *gormRecoveryCodes) Replace(ctx context.Context, userID uuid.UUID, hashes []string) error {
+	db := conn(r.db, ctx)
+	if err := db.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+	rows := make([]models.RecoveryCode, len(hashes))
+	for i, hash := range hashes {
+		rows[i] = models.RecoveryCode{UserID: userID, CodeHash: hash}
+	}
+	return db.Create(&rows).Error
+}
+
+func (r *gormRecoveryCodes) Use(ctx context.Context, userID uuid.UUID, hash string, at time.Time) (bool, error) {
+	result := conn(r.db, ctx).Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", at)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *gormRecoveryCodes) CountUnused(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var count int64
+	err := conn(r.db, ctx).Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+type gormIdentities struct{ db *gorm.DB }
+
+func (r *gormIdentities) Find(ctx context.Context, issuer, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	if err := conn(r.db, ctx).Where("issuer = ? AND subject = ?", issuer, subject).First(&identity).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &identity, nil
+}
+
+func (r *gormIdentities) GetForUser(ctx context.Context, id, userID uuid.UUID) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	if err := conn(r.db, ctx).First(&identity, "id = ? AND user_id = ?", id, userID).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &identity, nil
+}
+
+func (r *gormIdentities) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.UserIdentity, error) {
+	var identities []models.UserIdentity
+	err := conn(r.db, ctx).Where("user_id = ?", userID).Order("created_at ASC").Find(&identities).Error
+	return identities, err
+}
+
+func (r *gormIdentities) CountByUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var count int64
+	err := conn(r.db, ctx).Model(&models.UserIdentity{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+func (r *gormIdentities) Create(ctx context.Context, identity *models.UserIdentity) error {
+	return duplicate(conn(r.db, ctx).Create(identity).Error)
+}
+
+func (r *gormIdentities) RecordLogin(ctx context.Context, issuer, subject, email string, at time.Time) error {
+	return conn(r.db, ctx).Model(&models.UserIdentity{}).
+		Where("issuer = ? AND subject = ?", issuer, subject).
+		Updates(map[string]interface{}{"last_login_at": at, "email": email}).Error
+}
+
+func (r *gormIdentities) Delete(ctx context.Context, id uuid.UUID) error {
+	return conn(r.db, ctx).Where("id = ?", id).Delete(&models.UserIdentity{}).Error
+}
+
+type gormLoginStates struct{ db *gorm.DB }
+
+func (r *gormLoginStates) Create(ctx context.Context, state *models.OIDCLoginState) error {
+	return conn(r.db, ctx).Create(state).Error
+}
+
+func (r *gormLoginStates) Take(ctx context.Context, state string) (*models.OIDCLoginState, error) {
+	db := conn(r.db, ctx)
+	var pending models.OIDCLoginState
+	if err := db.First(&pending, "state = ?", state).Error; err != nil {
+		return nil, notFound(err)
+	}
+	// only the caller whose delete removed the row goes on
+	result := db.Where("state = ?", state).Delete(&models.OIDCLoginState{})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected != 1 {
+		return nil, ErrNotFound
+	}
+	return &pending, nil
+}
+
+func (r *gormLoginStates) DeleteExpired(ctx context.Context, before time.Time) error {
+	return conn(r.db, ctx).Where("expires_at < ?", before).Delete(&models.OIDCLoginState{}).Error
+}
+
+type gormLoginThrottles struct{ db *gorm.DB }
+
+func (r *gormLoginThrottles) Get(ctx context.Context, key string) (*models.LoginThrottle, error) {
+	var t models.LoginThrottle
+	if err := conn(r.db, ctx).First(&t, "key = ?", key).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &t, nil
+}
+
+func (r *gormLoginThrottles) Reserve(ctx context.Context, key string, scope models.ThrottleScope, now, window time.Time, limit ThrottleLimit) (*models.LoginThrottle, bool, error) {
+	var t models.LoginThrottle
+	counted := false
+	err := conn(r.db, ctx).Transaction(func(tx *gorm.DB) error {
+		// the conditional update needs a row to lock
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.LoginThrottle{Key: key, Scope: scope, UpdatedAt: now}).Error; err != nil {
+			return err
+		}
+		// a concurrent attempt holds the row until it commits its delay or
+		// lockout; Postgres then checks the conditions again on the new row
+		result := tx.Model(&t).Clauses(clause.Returning{}).
+			Where("key = ? AND (locked_until IS NULL OR locked_until <= ?) AND (next_attempt_at IS NULL OR next_attempt_at <= ?)", key, now, now).
+			Updates(map[string]interface{}{
+				"failures":        gorm.Expr("CASE WHEN last_failure_at IS NULL OR last_failure_at < ? THEN 1 ELSE failures + 1 END", window),
+				"last_failure_at": now,
+				"updated_at":      now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return tx.First(&t, "key = ?", key).Error
+		}
+		counted = true
+		t.NextAttemptAt, t.LockedUntil = limit(t.Failures)
+		return tx.Model(&models.LoginThrottle{}).Where("key = ?", key).
+			Updates(map[string]interface{}{"next_attempt_at": t.NextAttemptAt, "locked_until": t.LockedUntil}).Error
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return &t, counted, nil
+}
+
+func (r *gormLoginThrottles) Release(ctx context.Context, key string, lockedUntil *time.Time) error {
+	return conn(r.db, ctx).Model(&models.LoginThrottle{}).Where("key = ? AND failures > 0", key).
+		Updates(map[string]interface{}{
+			"failures":        gorm.Expr("failures - 1"),
+			"next_attempt_at": nil,
+			"locked_until":    gorm.Expr("CASE WHEN locked_until = ? THEN NULL ELSE locked_until END", lockedUntil),
+		}).Error
+}
+
+func (r *gormLoginThrottles) Delete(ctx context.Context, key string) error {
+	return conn(r.db, ctx).Where("key = ?", key).Delete(&models.LoginThrottle{}).Error
+}
+
+type gormLoginAttempts struct{ db *gorm.DB }
+
+func (r *gormLoginAttempts) Create(ctx context.Context, attempt *models.LoginAttempt) error {
+	return conn(r.db, ctx).Create(attempt).Error
+}
+
+type gormPrograms struct{ db *gorm.DB }
+
+func (r *gormPrograms) Get(ctx context.Context, id uuid.UUID) (*models.Program, error) {
+	var program models.Program
+	if err := conn(r.db, ctx).First(&program, "id = ?", id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &program, nil
+}
+
+func (r *gormPrograms) GetDetail(ctx context.Context, id uuid.UUID) (*models.Program, error) {
+	var program models.Program
+	if err := conn(r.db, ctx).Preload("Media").Preload("Subcourses").First(&program, "id = ?", id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &program, nil
+}
+
+func (r *gormPrograms) List(ctx context.Context, filter ProgramFilter) ([]models.Program, error) {
+	query := conn(r.db, ctx).Preload("Media", OrderedMedia).Order("sort_order ASC, created_at DESC")
+	if filter.IDs != nil {
+		query = query.Where("id IN ?", filter.IDs)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	var programs []models.Program
+	err := query.Find(&programs).Error
+	return programs, err
+}
+
+func (r *gormPrograms) Create(ctx context.Context, program *models.Program) error {
+	return duplicate(conn(r.db, ctx).Omit(clause.Associations).Create(program).Error)
+}
+
+func (r *gormPrograms) Update(ctx context.Context, id uuid.UUID, updates *models.Program) error {
+	return duplicate(conn(r.db, ctx).Model(&models.Program{ID: id}).Omit(clause.Associations).Updates(updates).Error)
+}
+
+func (r *gormPrograms) BumpVersion(ctx context.Context, id uuid.UUID, version int) error {
+	return bumpVersion(conn(r.db, ctx), &models.Program{}, id, version)
+}
+
+func (r *gormPrograms) SlugInUse(ctx context.Context, slug string, exceptID uuid.UUID) (bool, error) {
+	return slugInUse(conn(r.db, ctx), &models.Program{}, slug, exceptID)
+}
+
+func (r *gormPrograms) Trash(ctx context.Context, id uuid.UUID, version int, by uuid.UUID, at time.Time) ([]uuid.UUID, error) {
+	db := conn(r.db, ctx)
+	var lessonIDs []uuid.UUID
+	if err := db.Model(&models.Lesson{}).
+		Where("subcourse_id IN (?)", db.Model(&models.Subcourse{}).Select("id").Where("program_id = ?", id)).
+		Pluck("id", &lessonIDs).Error; err != nil {
+		return nil, err
+	}
+	n, err := trash(db, &models.Program{}, by, at, "id = ? AND version = ?", id, version)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrVersionConflict
+	}
+	if _, err := trash(db, &models.Lesson{}, by, at, "id IN ?", append(lessonIDs, uuid.Nil)); err != nil {
+		return nil, err
+	}
+	if _, err := trash(db, &models.Subcourse{}, by, at, "program_id = ?", id); err != nil {
+		return nil, err
+	}
+	return lessonIDs, nil
+}
+
+func (r *gormPrograms) RefreshCounters(ctx context.Context, ids ...uuid.UUID) error {
+	return database.RefreshProgramCounters(conn(r.db, ctx), ids...)
+}
+
+func (r *gormPrograms) OrderIDs(ctx context.Context, organizationID uuid.UUID) ([]uuid.UUID, error) {
+	return siblingIDs(conn(r.db, ctx), &models.Program{}, "organization_id", organizationID)
+}
+
+func (r *gormPrograms) SetOrder(ctx context.Context, ids []uuid.UUID) ([]uuid.UUID, error) {
+	return setOrder(conn(r.db, ctx), &models.Program{}, ids)
+}
+
+func (r *gormPrograms) NextSortOrder(ctx context.Context) (int, error) {
+	return nextSortOrder(conn(r.db, ctx).Model(&models.Program{}))
+}
+
+type gormSubcourses struct{ db *gorm.DB }
+
+func (r *gormSubcourses) Get(ctx context.Context, id uuid.UUID) (*models.Subcourse, error) {
+	var subcourse models.Subcourse
+	if err := conn(r.db, ctx).First(&subcourse, "id = ?", id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &subcourse, nil
+}
+
+func (r *gormSubcourses) GetDetail(ctx context.Context, id uuid.UUID) (*models.Subcourse, error) {
+	var subcourse models.Subcourse
+	if err := conn(r.db, ctx).Preload("Media").Preload("Program").Preload("Lessons").First(&subcourse, "id = ?", id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &subcourse, nil
+}
+
+// List joins the program into the same query
+func (r *gormSubcourses) List(ctx context.Context, filter SubcourseFilter) ([]models.Subcourse, error) {
+	query := conn(r.db, ctx).Joins("Program").
+		Preload("Media", OrderedMedia).
+		Order("subcourses.sort_order ASC, subcourses.created_at DESC")
+	if filter.IDs != nil {
+		query = query.Where("subcourses.id IN ?", filter.IDs)
+	}
+	if filter.ProgramID != nil {
+		query = query.Where("subcourses.program_id = ?", *filter.ProgramID)
+	}
+	if filter.Status != "" {
+		query = query.Where("subcourses.status = ?", filter.Status)
+	}
+	var subcourses []models.Subcourse
+	err := query.Find(&subcourses).Error
+	return subcourses, err
+}
+
+func (r *gormSubcourses) Create(ctx context.Context, subcourse *models.Subcourse) error {
+	return duplicate(conn(r.db, ctx).Omit(clause.Associations).Create(subcourse).Error)
+}
+
+func (r *gormSubcourses) Update(ctx context.Context, id uuid.UUID, updates *models.Subcourse) error {
+	return duplicate(conn(r.db, ctx).Model(&models.Subcourse{ID: id}).Omit(clause.Associations).Updates(updates).Error)
+}
+
+func (r *gormSubcourses) BumpVersion(ctx context.Context, id uuid.UUID, version int) error {
+	return bumpVersion(conn(r.db, ctx), &models.Subcourse{}, id, version)
+}
+
+func (r *gormSubcourses) SlugInUse(ctx context.Context, slug string, exceptID uuid.UUID) (bool, error) {
+	return slugInUse(conn(r.db, ctx), &models.Subcourse{}, slug, exceptID)
+}
+
+func (r *gormSubcourses) Trash(ctx context.Context, id uuid.UUID, version int, by uuid.UUID, at time.Time) ([]uuid.UUID, error) {
+	db := conn(r.db, ctx)
+	var lessonIDs []uuid.UUID
+	if err := db.Model(&models.Lesson{}).Where("subcourse_id = ?", id).Pluck("id", &lessonIDs).Error; err != nil {
+		return nil, err
+	}
+	n, err := trash(db, &models.Subcourse{}, by, at, "id = ? AND version = ?", id, version)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrVersionConflict
+	}
+	if _, err := trash(db, &models.Lesson{}, by, at, "subcourse_id = ?", id); err != nil {
+		return nil, err
+	}
+	return lessonIDs, nil
+}
+
+func (r *gormSubcourses) RefreshCounters(ctx context.Context, from []uuid.UUID, ids ...uuid.UUID) error {
+	return database.RefreshMovedSubcourseCounters(conn(r.db, ctx), from, ids...)
+}
+
+func (r *gormSubcourses) OrderIDs(ctx context.Context, programID uuid.UUID) ([]uuid.UUID, error) {
+	return siblingIDs(conn(r.db, ctx), &models.Subcourse{}, "program_id", programID)
+}
+
+func (r *gormSubcourses) SetOrder(ctx context.Context, ids []uuid.UUID) ([]uuid.UUID, error) {
+	return setOrder(conn(r.db, ctx), &models.Subcourse{}, ids)
+}
+
+func (r *gormSubcourses) NextSortOrder(ctx context.Context, programID uuid.UUID) (int, error) {
+	return nextSortOrder(conn(r.db, ctx).Model(&models.Subcourse{}).Where("program_id = ?", programID))
+}
+
+func (r *gormSubcourses) Move(ctx context.Context, id, programID uuid.UUID, organizationID *uuid.UUID) error {
+	db := conn(r.db, ctx)
+	if err := db.Model(&models.Subcourse{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"program_id": programID, "organization_id": organizationID}).Error; err != nil {
+		return err
+	}
+	return db.Unscoped().Model(&models.Lesson{}).Where("subcourse_id = ?", id).Update("organization_id", organizationID).Error
+}
+
+// OrderedMedia sorts preloaded media as the editors arranged them, which
+// the (owner_type, owner_id, sort_order) index serves
+func OrderedMedia(db *gorm.DB) *gorm.DB {
+	return db.Order("sort_order ASC, created_at ASC")
+}
+
+// PreloadLessonTree loads a lesson with every component and its media
+func PreloadLessonTree(db *gorm.DB) *gorm.DB {
+	ordered := func(db *gorm.DB) *gorm.DB {
+		return db.Order("sort_order ASC, created_at ASC")
+	}
+	return db.Preload("Media", OrderedMedia).
+		Preload("Objectives").
+		Preload("Models", ordered).
+		Preload("Models.Media", OrderedMedia).
+		Preload("Preparation").
+		Preload("Preparation.Media", OrderedMedia).
+		Preload("Builds", ordered).
+		Preload("Builds.Media", OrderedMedia).
+		Preload("ContentBlocks", ordered).
+		Preload("ContentBlocks.Media", OrderedMedia).
+		Preload("Attachments", ordered).
+		Preload("Attachments.Media", OrderedMedia).
+		Preload("Challenges", ordered).
+		Preload("Challenges.Media", OrderedMedia).
+		Preload("Quizzes", ordered).
+		Preload("Quizzes.Options", ordered)
+}
+
+type gormLessons struct{ db *gorm.DB }
+
+func (r *gormLessons) Get(ctx context.Context, id uuid.UUID) (*models.Lesson, error) {
+	var lesson models.Lesson
+	if err := conn(r.db, ctx).Joins("Subcourse").First(&lesson, "lessons.id = ?", id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &lesson, nil
+}
+
+func (r *gormLessons) GetTree(ctx context.Context, id uuid.UUID) (*models.Lesson, error) {
+	var lesson models.Lesson
+	if err := PreloadLessonTree(conn(r.db, ctx)).
+		Preload("Subcourse").
+		Preload("Subcourse.Program").
+		First(&lesson, "id = ?", id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &lesson, nil
+}
+
+func (r *gormLessons) BumpVersion(ctx context.Context, id uuid.UUID, version int) error {
+	return bumpVersion(conn(r.db, ctx), &models.Lesson{}, id, version)
+}
+
+func (r *gormLessons) Touch(ctx context.Context, id uuid.UUID) error {
+	return conn(r.db, ctx).Model(&models.Lesson{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"version": gorm.Expr("version + 1"), "updated_at": time.Now()}).Error
+}
+
+func (r *gormLessons) SetStatus(ctx context.Context, id uuid.UUID, version int, status models.ContentStatus, publishedAt *time.Time) error {
+	updates := map[string]interface{}{"status": status, "version": gorm.Expr("version + 1")}
+	if publishedAt != nil {
+		updates["published_at"] = *publishedAt
+	}
+	result := conn(r.db, ctx).Model(&models.Lesson{}).Where("id = ? AND version = ?", id, version).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+func (r *gormLessons) SoftDelete(ctx context.Context, id uuid.UUID, by uuid.UUID, at time.Time) error {
+	_, err := trash(conn(r.db, ctx), &models.Lesson{}, by, at, "id = ?", id)
+	return err
+}
+
+func (r *gormLessons) ListBySubcourse(ctx context.Context, subcourseID uuid.UUID) ([]models.Lesson, error) {
+	var lessons []models.Lesson
+	err := conn(r.db, ctx).Preload("Media", OrderedMedia).Where("subcourse_id = ?", subcourseID).
+		Order("sort_order ASC, created_at DESC").Find(&lessons).Error
+	return lessons, err
+}
+
+func (r *gormLessons) ListTrees(ctx context.Context, subcourseID uuid.UUID) ([]models.Lesson, error) {
+	var lessons []models.Lesson
+	err := PreloadLessonTree(conn(r.db, ctx)).Where("subcourse_id = ?", subcourseID).Order("sort_order ASC").Find(&lessons).Error
+	return lessons, err
+}
+
+func (r *gormLessons) Create(ctx context.Context, lesson *models.Lesson) error {
+	db := conn(r.db, ctx)
+	// the cover may be one of the media inserted below
+	if err := db.Omit(clause.Associations, "CoverMediaID").Create(lesson).Error; err != nil {
+		return duplicate(err)
+	}
+	if err := insertTree(db, lesson); err != nil {
+		return err
+	}
+	if lesson.CoverMediaID == nil {
+		return nil
+	}
+	return db.Model(&models.Lesson{}).Where("id = ?", lesson.ID).Update("cover_media_id", lesson.CoverMediaID).Error
+}
+
+func (r *gormLessons) Update(ctx context.Context, id uuid.UUID, updates *models.Lesson, fields ...string) error {
+	query := conn(r.db, ctx).Model(&models.Lesson{ID: id}).Omit(clause.Associations)
+	if len(fields) > 0 {
+		query = query.Select(fields)
+	}
+	return duplicate(query.Updates(updates).Error)
+}
+
+func (r *gormLessons) ReplaceTree(ctx context.Context, lesson *models.Lesson) error {
+	db := conn(r.db, ctx)
+	if err := deleteTree(db, lesson.ID); err != nil {
+		return err
+	}
+	return insertTree(db, lesson)
+}
+
+func (r *gormLessons) SlugInUse(ctx context.Context, slug string, exceptID uuid.UUID) (bool, error) {
+	return slugInUse(conn(r.db, ctx), &models.Lesson{}, slug, exceptID)
+}
+
+func (r *gormLessons) OrderIDs(ctx context.Context, subcourseID uuid.UUID) ([]uuid.UUID, error) {
+	return siblingIDs(conn(r.db, ctx), &models.Lesson{}, "subcourse_id", subcourseID)
+}
+
+func (r *gormLessons) SetOrder(ctx context.Context, ids []uuid.UUID) ([]uuid.UUID, error) {
+	return setOrder(conn(r.db, ctx), &models.Lesson{}, ids)
+}
+
+func (r *gormLessons) NextSortOrder(ctx context.Context, subcourseID uuid.UUID) (int, error) {
+	return nextSortOrder(conn(r.db, ctx).Model(&models.Lesson{}).Where("subcourse_id = ?", subcourseID))
+}
+
+func (r *gormLessons) Move(ctx context.Context, id, subcourseID uuid.UUID, organizationID *uuid.UUID) error {
+	return conn(r.db, ctx).Model(&models.Lesson{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"subcourse_id": subcourseID, "organization_id": organizationID}).Error
+}
+
+func (r *gormLessons) RefreshCounters(ctx context.Context, from []uuid.UUID, ids ...uuid.UUID) error {
+	return database.RefreshMovedLessonCounters(conn(r.db, ctx), from, ids...)
+}
+
+type gormMedia struct{ db *gorm.DB }
+
+func (r *gormMedia) OwnerLesson(ctx context.Context, ownerType models.MediaOwnerType, ownerID uuid.UUID) (uuid.UUID, error) {
+	if ownerType == models.OwnerLesson {
+		return ownerID, nil
+	}
+	for _, o := range database.MediaOwnerTables {
+		if o.OwnerType != ownerType {
+			continue
+		}
+		if o.Table == "programs" || o.Table == "subcourses" {
+			break
+		}
+		var lessonIDs []uuid.UUID
+		if err := conn(r.db, ctx).Table(o.Table).Where("id = ?", ownerID).Limit(1).Pluck("lesson_id", &lessonIDs).Error; err != nil {
+			return uuid.Nil, err
+		}
+		if len(lessonIDs) == 0 {
+			return uuid.Nil, ErrNotFound
+		}
+		return lessonIDs[0], nil
+	}
+	return uuid.Nil, fmt.Errorf("media of %s do not belong to a lesson", ownerType)
+}
+
+func (r *gormMedia) ListByOwner(ctx context.Context, ownerType models.MediaOwnerType, ownerID uuid.UUID) ([]models.Media, error) {
+	var media []models.Media
+	err := conn(r.db, ctx).Where("owner_type = ? AND owner_id = ?", ownerType, ownerID).Order("sort_order ASC").Find(&media).Error
+	return media, err
+}
+
+func (r *gormMedia) Create(ctx context.Context, media *models.Media) error {
+	return conn(r.db, ctx).Create(media).Error
+}
+
+func (r *gormMedia) Replace(ctx context.Context, ownerType models.MediaOwnerType, ownerID uuid.UUID, media []models.Media) error {
+	db := conn(r.db, ctx)
+	if err := db.Where("owner_type = ? AND owner_id = ?", ownerType, ownerID).Delete(&models.Media{}).Error; err != nil {
+		return err
+	}
+	if len(media) == 0 {
+		return nil
+	}
+	for i := range media {
+		media[i].OwnerID = ownerID
+		media[i].OwnerType = ownerType
+	}
+	return db.Create(&media).Error
+}
+
+func (r *gormMedia) SetOrder(ctx context.Context, ids []uuid.UUID) error {
+	db := conn(r.db, ctx)
+	for i, id := range ids {
+		if err := db.Model(&models.Media{}).Where("id = ? AND sort_order <> ?", id, i).Update("sort_order", i).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *gormMedia) CountByURL(ctx context.Context, urls []string) (map[string]int64, error) {
+	counts := make(map[string]int64, len(urls))
+	if len(urls) == 0 {
+		return counts, nil
+	}
+	var rows []struct {
+		URL   string
+		Count int64
+	}
+	if err := conn(r.db, ctx).Model(&models.Media{}).Select("url, COUNT(*) AS count").
+		Where("url IN ?", urls).Group("url").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.URL] = row.Count
+	}
+	return counts, nil
+}
+
+type gormTemplates struct{ db *gorm.DB }
+
+func (r *gormTemplates) ListByProgram(ctx context.Context, programID uuid.UUID) ([]models.LessonTemplate, error) {
+	var templates []models.LessonTemplate
+	err := conn(r.db, ctx).Where("program_id = ?", programID).Order("name ASC").Find(&templates).Error
+	return templates, err
+}
+
+func (r *gormTemplates) Get(ctx context.Context, id uuid.UUID) (*models.LessonTemplate, error) {
+	var template models.LessonTemplate
+	if err := conn(r.db, ctx).First(&template, "id = ?", id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &template, nil
+}
+
+func (r *gormTemplates) Create(ctx context.Context, template *models.LessonTemplate) error {
+	return conn(r.db, ctx).Omit(clause.Associations).Create(template).Error
+}
+
+func (r *gormTemplates) Update(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error {
+	return conn(r.db, ctx).Model(&models.LessonTemplate{ID: id}).Updates(updates).Error
+}
+
+func (r *gormTemplates) Delete(ctx context.Context, id uuid.UUID) error {
+	return conn(r.db, ctx).Delete(&models.LessonTemplate{}, "id = ?", id).Error
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"courseai/backend/internal/database"
+	"courseai/backend/internal/models"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TestReserveCountsConcurrentAttempts checks that the conditional update
+// lets no more attempts through than the lockout allows. It needs a
+// Postgres database it may migrate:
+//
+//	TEST_DATABASE_URL=postgres://localhost/courseai_test go test ./internal/repository
+func TestReserveCountsConcurrentAttempts(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	if err := database.Connect(dsn, database.PoolOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.AutoMigrate(); err != nil {
+		t.Fatal(err)
+	}
+	throttles := NewUnitOfWork(database.GetDB()).Repositories().LoginThrottles
+	ctx := context.Background()
+	key := "account:" + uuid.NewString()
+	defer throttles.Delete(ctx, key)
+
+	const max = 5
+	var counted atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 40; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			now := time.Now().UTC().Truncate(time.Microsecond)
+			_, ok, err := throttles.Reserve(ctx, key, models.ThrottleScopeAccount, now, now.Add(-time.Hour),
+				func(failures int) (*time.Time, *time.Time) {
+					if failures >= max {
+						until := now.Add(time.Hour)
+						return nil, &until
+					}
+					return nil, nil
+				})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if ok {
+				counted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := counted.Load(); n != max {
+		t.Errorf("%d of 40 concurrent attempts were counted, want %d", n, max)
+	}
+	row, err := throttles.Get(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if row.Failures != max || row.LockedUntil == nil {
+		t.Errorf("row = %d failures, locked until %v; want %d and a lockout", row.Failures, row.LockedUntil, max)
+	}
+
+	// the attempt that locked the key gives it back
+	if err := throttles.Release(ctx, key, row.LockedUntil); err != nil {
+		t.Fatal(err)
+	}
+	if row, err = throttles.Get(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if row.Failures != max-1 || row.LockedUntil != nil {
+		t.Errorf("after Release row = %d failures, locked until %v; want %d and no lockout", row.Failures, row.LockedUntil, max-1)
+	}
+}
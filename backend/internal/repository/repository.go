@@ -0,0 +1,362 @@
+// Package repository is the persistence boundary of the domain services. Each
+// aggregate has a small interface; the GORM implementations below are the only
+// code in the services' call path that builds SQL. The organization a call is
+// restricted to travels in the context (database.WithTenant), exactly as it
+// does for request handlers.
+package repository
+
+import (
+	"context"
+	"courseai/backend/internal/models"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNotFound is returned when the requested row does not exist or is not
+	// visible to the context's organization
+	ErrNotFound = errors.New("record not found")
+	// ErrVersionConflict is returned by versioned writes when the row changed
+	// since it was read
+	ErrVersionConflict = errors.New("version conflict")
+	// ErrDuplicate is returned when a write hits a unique constraint, e.g. a
+	// slug another row took in the meantime
+	ErrDuplicate = errors.New("duplicate key")
+)
+
+// Repositories is the set of repositories of one unit of work; inside
+// UnitOfWork.Do they all share the transaction
+type Repositories struct {
+	Assignments    AssignmentRepository
+	Organizations  OrganizationRepository
+	Shares         ShareRepository
+	Users          UserRepository
+	RecoveryCodes  RecoveryCodeRepository
+	Identities     IdentityRepository
+	LoginStates    LoginStateRepository
+	LoginThrottles LoginThrottleRepository
+	LoginAttempts  LoginAttemptRepository
+	Programs       ProgramRepository
+	Subcourses     SubcourseRepository
+	Lessons        LessonRepository
+	Components     ComponentRepository
+	Media          MediaRepository
+	Templates      TemplateRepository
+	Trash          TrashRepository
+}
+
+// UnitOfWork hands out repositories, either on their own or bound to a
+// transaction that commits when fn returns nil and rolls back otherwise
+type UnitOfWork interface {
+	Repositories() Repositories
+	Do(ctx context.Context, fn func(r Repositories) error) error
+}
+
+type AssignmentRepository interface {
+	// ActiveIDs returns the programs and the subcourses the teacher is
+	// assigned to at the given time, in one query
+	ActiveIDs(ctx context.Context, teacherID uuid.UUID, at time.Time) (programIDs, subcourseIDs []uuid.UUID, err error)
+	// ListActive returns every active assignment of the teacher
+	ListActive(ctx context.Context, teacherID uuid.UUID) ([]models.TeacherAssignment, error)
+	// ListCurrent returns the active assignments of the teacher whose period
+	// includes the given time
+	ListCurrent(ctx context.Context, teacherID uuid.UUID, at time.Time) ([]models.TeacherAssignment, error)
+	// DeleteScope removes every assignment of the teacher at scope
+	DeleteScope(ctx context.Context, teacherID uuid.UUID, scope models.AssignmentScope) error
+	Create(ctx context.Context, assignment *models.TeacherAssignment) error
+}
+
+type OrganizationRepository interface {
+	// List returns every organization by name
+	List(ctx context.Context) ([]models.Organization, error)
+	Get(ctx context.Context, id uuid.UUID) (*models.Organization, error)
+	// Find returns the organization whose ID or slug is ref
+	Find(ctx context.Context, ref string) (*models.Organization, error)
+	// Default returns the default organization, creating it on first use
+	Default(ctx context.Context) (*models.Organization, error)
+	Create(ctx context.Context, org *models.Organization) error
+	// Update writes the non-zero fields of updates
+	Update(ctx context.Context, id uuid.UUID, updates *models.Organization) error
+	// SlugInUse reports whether an organization other than exceptID uses slug
+	SlugInUse(ctx context.Context, slug string, exceptID uuid.UUID) (bool, error)
+}
+
+type ShareRepository interface {
+	// ListByProgram returns the shares of a program with their organization
+	ListByProgram(ctx context.Context, programID uuid.UUID) ([]models.ProgramShare, error)
+	// Ensure shares the program with the organization, unless it already is
+	Ensure(ctx context.Context, programID, organizationID uuid.UUID) (*models.ProgramShare, error)
+	Delete(ctx context.Context, programID, organizationID uuid.UUID) error
+}
+
+// Principal is what authorization needs to know about a user: their role
+// and status, and the status of their organization
+type Principal struct {
+	Role           models.UserRole
+	Status         models.UserStatus
+	OrganizationID *uuid.UUID
+	OrgStatus      *models.OrganizationStatus
+}
+
+type UserRepository interface {
+	// FindTeacher returns the user id if it is a teacher
+	FindTeacher(ctx context.Context, id uuid.UUID) (*models.User, error)
+	FindByUsername(ctx context.Context, username string) (*models.User, error)
+	Get(ctx context.Context, id uuid.UUID) (*models.User, error)
+	// FindByLogin returns the user whose username or email is login
+	FindByLogin(ctx context.Context, login string) (*models.User, error)
+	// FindByEmail matches email regardless of case
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	UsernameInUse(ctx context.Context, username string) (bool, error)
+	// Usernames returns the usernames of the users ids, in any organization
+	Usernames(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]string, error)
+	// Principal returns ErrNotFound when the user no longer exists
+	Principal(ctx context.Context, id uuid.UUID) (*Principal, error)
+	Create(ctx context.Context, user *models.User) error
+	// SetRole changes the role of a user and the organization they belong to
+	SetRole(ctx context.Context, id uuid.UUID, role models.UserRole, organizationID *uuid.UUID) error
+	SetPasswordLoginDisabled(ctx context.Context, id uuid.UUID, disabled bool) error
+	// StartTOTP stores a secret for an enrollment that is not confirmed yet
+	StartTOTP(ctx context.Context, id uuid.UUID, secret string) error
+	// EnableTOTP confirms the enrollment; step is the code that confirmed it
+	EnableTOTP(ctx context.Context, id uuid.UUID, step int64) error
+	// UseTOTPStep records step as the last code used and reports false when
+	// that code or a later one was used already, so each code works once
+	UseTOTPStep(ctx context.Context, id uuid.UUID, step int64) (bool, error)
+	// DisableTOTP turns two-factor authentication off and forgets the secret
+	DisableTOTP(ctx context.Context, id uuid.UUID) error
+}
+
+type RecoveryCodeRepository interface {
+	// Replace removes every recovery code of the user and stores hashes in
+	// their place
+	Replace(ctx context.Context, userID uuid.UUID, hashes []string) error
+	// Use marks an unused code as used and reports false when there is none
+	Use(ctx context.Context, userID uuid.UUID, hash string, at time.Time) (bool, error)
+	CountUnused(ctx context.Context, userID uuid.UUID) (int64, error)
+}
+
+type IdentityRepository interface {
+	// Find returns the identity an ID token of issuer names with subject
+	Find(ctx context.Context, issuer, subject string) (*models.UserIdentity, error)
+	// GetForUser returns the identity id if it belongs to the user
+	GetForUser(ctx context.Context, id, userID uuid.UUID) (*models.UserIdentity, error)
+	// ListByUser returns the user's identities, oldest first
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]models.UserIdentity, error)
+	CountByUser(ctx context.Context, userID uuid.UUID) (int64, error)
+	Create(ctx context.Context, identity *models.UserIdentity) error
+	// RecordLogin stamps a sign-in and the email the provider reported
+	RecordLogin(ctx context.Context, issuer, subject, email string, at time.Time) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+type LoginStateRepository interface {
+	Create(ctx context.Context, state *models.OIDCLoginState) error
+	// Take removes the pending sign-on and returns it. Of concurrent calls
+	// for one state only one gets it; the others get ErrNotFound.
+	Take(ctx context.Context, state string) (*models.OIDCLoginState, error)
+	// DeleteExpired drops the sign-ons abandoned before the given time
+	DeleteExpired(ctx context.Context, before time.Time) error
+}
+
+// ThrottleLimit turns the failure count of a key into the time of its next
+// attempt or the end of its lockout
+type ThrottleLimit func(failures int) (nextAttemptAt, lockedUntil *time.Time)
+
+type LoginThrottleRepository interface {
+	// Get returns the failure counter of key
+	Get(ctx context.Context, key string) (*models.LoginThrottle, error)
+	// Reserve counts a failed attempt for key in one conditional update,
+	// unless key is locked or its delay has not elapsed at now; then it
+	// returns the row unchanged and false. Failures last counted before
+	// window start over at one, and limit sets the delay or lockout of the
+	// new count. Concurrent calls for one key are counted one after another,
+	// so each sees the failures of the others.
+	Reserve(ctx context.Context, key string, scope models.ThrottleScope, now, window time.Time, limit ThrottleLimit) (*models.LoginThrottle, bool, error)
+	// Release takes back one counted attempt and lifts the delay; the
+	// lockout is lifted too when it ends at lockedUntil, i.e. that attempt
+	// set it
+	Release(ctx context.Context, key string, lockedUntil *time.Time) error
+	Delete(ctx context.Context, key string) error
+}
+
+type LoginAttemptRepository interface {
+	Create(ctx context.Context, attempt *models.LoginAttempt) error
+}
+
+// ProgramFilter narrows a program list. A nil IDs lists every program; an
+// empty one lists none.
+type ProgramFilter struct {
+	IDs    []uuid.UUID
+	Status string
+}
+
+type ProgramRepository interface {
+	Get(ctx context.Context, id uuid.UUID) (*models.Program, error)
+	// GetDetail returns the program with its media and subcourses
+	GetDetail(ctx context.Context, id uuid.UUID) (*models.Program, error)
+	// List returns programs in list order with their media
+	List(ctx context.Context, filter ProgramFilter) ([]models.Program, error)
+	// Create inserts the program row; media are stored through MediaRepository
+	Create(ctx context.Context, program *models.Program) error
+	// Update writes the non-zero fields of updates
+	Update(ctx context.Context, id uuid.UUID, updates *models.Program) error
+	// BumpVersion increments the version of a program still at version and
+	// keeps the row locked until the transaction ends
+	BumpVersion(ctx context.Context, id uuid.UUID, version int) error
+	// SlugInUse reports whether a program other than exceptID, in any
+	// organization and including the trash, uses slug
+	SlugInUse(ctx context.Context, slug string, exceptID uuid.UUID) (bool, error)
+	// Trash moves a program still at version, its subcourses and their
+	// lessons to the trash and returns the lessons
+	Trash(ctx context.Context, id uuid.UUID, version int, by uuid.UUID, at time.Time) ([]uuid.UUID, error)
+	// RefreshCounters recomputes the counters of the programs
+	RefreshCounters(ctx context.Context, ids ...uuid.UUID) error
+	// OrderIDs returns the live programs of an organization in list order
+	OrderIDs(ctx context.Context, organizationID uuid.UUID) ([]uuid.UUID, error)
+	// SetOrder numbers ids 0..n-1, bumps the version of every program whose
+	// position changed and returns those
+	SetOrder(ctx context.Context, ids []uuid.UUID) ([]uuid.UUID, error)
+	// NextSortOrder returns the sort order that places a program after all others
+	NextSortOrder(ctx context.Context) (int, error)
+}
+
+// SubcourseFilter narrows a subcourse list; a nil IDs lists every subcourse,
+// an empty one none
+type SubcourseFilter struct {
+	IDs       []uuid.UUID
+	ProgramID *uuid.UUID
+	Status    string
+}
+
+type SubcourseRepository interface {
+	Get(ctx context.Context, id uuid.UUID) (*models.Subcourse, error)
+	// GetDetail returns the subcourse with its media, program and lessons
+	GetDetail(ctx context.Context, id uuid.UUID) (*models.Subcourse, error)
+	// List returns subcourses in list order with their media and program
+	List(ctx context.Context, filter SubcourseFilter) ([]models.Subcourse, error)
+	// Create inserts the subcourse row; media are stored through MediaRepository
+	Create(ctx context.Context, subcourse *models.Subcourse) error
+	// Update writes the non-zero fields of updates
+	Update(ctx context.Context, id uuid.UUID, updates *models.Subcourse) error
+	// BumpVersion increments the version of a subcourse still at version and
+	// keeps the row locked until the transaction ends
+	BumpVersion(ctx context.Context, id uuid.UUID, version int) error
+	// SlugInUse reports whether a subcourse other than exceptID, in any
+	// organization and including the trash, uses slug
+	SlugInUse(ctx context.Context, slug string, exceptID uuid.UUID) (bool, error)
+	// Trash moves a subcourse still at version and its lessons to the trash
+	// and returns the lessons
+	Trash(ctx context.Context, id uuid.UUID, version int, by uuid.UUID, at time.Time) ([]uuid.UUID, error)
+	// RefreshCounters recomputes the counters of the subcourses and their
+	// programs, and of the programs in from they moved out of
+	RefreshCounters(ctx context.Context, from []uuid.UUID, ids ...uuid.UUID) error
+	// OrderIDs returns the live subcourses of a program in list order
+	OrderIDs(ctx context.Context, programID uuid.UUID) ([]uuid.UUID, error)
+	// SetOrder numbers ids 0..n-1, bumps the version of every subcourse whose
+	// position changed and returns those
+	SetOrder(ctx context.Context, ids []uuid.UUID) ([]uuid.UUID, error)
+	// NextSortOrder returns the sort order that places a subcourse after the
+	// others of the program
+	NextSortOrder(ctx context.Context, programID uuid.UUID) (int, error)
+	// Move puts the subcourse into another program; it and its lessons,
+	// trashed ones included, follow the program's organization
+	Move(ctx context.Context, id, programID uuid.UUID, organizationID *uuid.UUID) error
+}
+
+type LessonRepository interface {
+	// Get returns the lesson with its subcourse
+	Get(ctx context.Context, id uuid.UUID) (*models.Lesson, error)
+	// GetTree returns the lesson with every component, its media, and its
+	// subcourse and program, as the editor loads it
+	GetTree(ctx context.Context, id uuid.UUID) (*models.Lesson, error)
+	// BumpVersion increments the version of a lesson still at version and
+	// keeps the row locked until the transaction ends
+	BumpVersion(ctx context.Context, id uuid.UUID, version int) error
+	// Touch increments the version of a lesson whose components changed
+	Touch(ctx context.Context, id uuid.UUID) error
+	// SetStatus changes the status of a lesson still at version
+	SetStatus(ctx context.Context, id uuid.UUID, version int, status models.ContentStatus, publishedAt *time.Time) error
+	// ListBySubcourse returns the lessons of a subcourse in list order with
+	// their media
+	ListBySubcourse(ctx context.Context, subcourseID uuid.UUID) ([]models.Lesson, error)
+	// ListTrees returns the lessons of a subcourse as GetTree loads them,
+	// without the subcourse
+	ListTrees(ctx context.Context, subcourseID uuid.UUID) ([]models.Lesson, error)
+	// Create inserts the lesson with every component and media item set on
+	// it. Rows keep the IDs set on them; a nil ID gets a new one.
+	Create(ctx context.Context, lesson *models.Lesson) error
+	// Update writes the non-zero fields of updates, or only fields when any
+	// are given; components are left alone
+	Update(ctx context.Context, id uuid.UUID, updates *models.Lesson, fields ...string) error
+	// ReplaceTree removes every component and media item of the lesson and
+	// inserts the ones set on lesson in their place
+	ReplaceTree(ctx context.Context, lesson *models.Lesson) error
+	// SlugInUse reports whether a lesson other than exceptID, in any
+	// organization and including the trash, uses slug
+	SlugInUse(ctx context.Context, slug string, exceptID uuid.UUID) (bool, error)
+	// SoftDelete moves the lesson to the trash
+	SoftDelete(ctx context.Context, id uuid.UUID, by uuid.UUID, at time.Time) error
+	// OrderIDs returns the live lessons of a subcourse in list order
+	OrderIDs(ctx context.Context, subcourseID uuid.UUID) ([]uuid.UUID, error)
+	// SetOrder numbers ids 0..n-1, bumps the version of every lesson whose
+	// position changed and returns those
+	SetOrder(ctx context.Context, ids []uuid.UUID) ([]uuid.UUID, error)
+	// NextSortOrder returns the sort order that places a lesson after the
+	// others of the subcourse
+	NextSortOrder(ctx context.Context, subcourseID uuid.UUID) (int, error)
+	// Move puts the lesson into another subcourse and its organization
+	Move(ctx context.Context, id, subcourseID uuid.UUID, organizationID *uuid.UUID) error
+	// RefreshCounters recomputes the counters of the lessons and their
+	// parents, and of the subcourses in from they moved out of
+	RefreshCounters(ctx context.Context, from []uuid.UUID, ids ...uuid.UUID) error
+}
+
+// ComponentRepository reads and writes the child rows of lessons described
+// by a LessonComponent; parentID is the lesson, or the quiz of quiz options
+type ComponentRepository interface {
+	// List returns the components of a parent in editor order with their
+	// media and children
+	List(ctx context.Context, comp *LessonComponent, parentID uuid.UUID) (interface{}, error)
+	// Get returns one component of a parent; a nil id returns the component
+	// of a single kind
+	Get(ctx context.Context, comp *LessonComponent, parentID, id uuid.UUID) (interface{}, error)
+	IDs(ctx context.Context, comp *LessonComponent, parentID uuid.UUID) ([]uuid.UUID, error)
+	// NextSortOrder returns the sort order that places a component after its siblings
+	NextSortOrder(ctx context.Context, comp *LessonComponent, parentID uuid.UUID) (int, error)
+	// Create inserts the row alone; children are created on their own
+	Create(ctx context.Context, comp *LessonComponent, item interface{}) error
+	// Update writes fields of item to the component id
+	Update(ctx context.Context, comp *LessonComponent, id uuid.UUID, item interface{}, fields []string) error
+	// Delete removes the component with its media and children
+	Delete(ctx context.Context, comp *LessonComponent, id uuid.UUID) error
+	// SetOrder stores the position of every id as its sort order
+	SetOrder(ctx context.Context, comp *LessonComponent, ids []uuid.UUID) error
+}
+
+type MediaRepository interface {
+	// OwnerLesson returns the lesson a media owner belongs to; every owner
+	// that accepts uploads resolves to a lesson
+	OwnerLesson(ctx context.Context, ownerType models.MediaOwnerType, ownerID uuid.UUID) (uuid.UUID, error)
+	ListByOwner(ctx context.Context, ownerType models.MediaOwnerType, ownerID uuid.UUID) ([]models.Media, error)
+	Create(ctx context.Context, media *models.Media) error
+	// Replace removes the media of an owner and stores media in their place
+	Replace(ctx context.Context, ownerType models.MediaOwnerType, ownerID uuid.UUID, media []models.Media) error
+	// SetOrder stores the position of every id as its sort order
+	SetOrder(ctx context.Context, ids []uuid.UUID) error
+	// CountByURL returns how many media rows, in any organization, use each
+	// of urls
+	CountByURL(ctx context.Context, urls []string) (map[string]int64, error)
+}
+
+type TemplateRepository interface {
+	// ListByProgram returns the templates of a program by name
+	ListByProgram(ctx context.Context, programID uuid.UUID) ([]models.LessonTemplate, error)
+	Get(ctx context.Context, id uuid.UUID) (*models.LessonTemplate, error)
+	Create(ctx context.Context, template *models.LessonTemplate) error
+	// Update writes the given columns
+	Update(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
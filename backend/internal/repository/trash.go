@@ -0,0 +1,434 @@
+package repository
+
+import (
+	"context"
+	"courseai/backend/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TrashKind is what a trash entry is: a program, a subcourse or a lesson
+type TrashKind string
+
+const (
+	TrashProgram   TrashKind = "program"
+	TrashSubcourse TrashKind = "subcourse"
+	TrashLesson    TrashKind = "lesson"
+)
+
+// TrashKinds in the order a purge takes them: programs take their
+// subcourses and lessons along
+var TrashKinds = []TrashKind{TrashProgram, TrashSubcourse, TrashLesson}
+
+// TrashScope narrows a trash list to the programs and subcourses an
+// assignment-scoped caller manages; All lists everything
+type TrashScope struct {
+	All          bool
+	ProgramIDs   []uuid.UUID
+	SubcourseIDs []uuid.UUID
+}
+
+// TrashEntry is a program, subcourse or lesson, live or trashed, with what
+// access checks need to know about its place in the tree
+type TrashEntry struct {
+	Kind           TrashKind
+	ID             uuid.UUID
+	Title          string
+	Slug           string
+	OrganizationID *uuid.UUID
+	// ProgramID is the program itself or the one the entry belongs to; nil
+	// when it is gone
+	ProgramID *uuid.UUID
+	// SubcourseID is the subcourse itself or the one a lesson belongs to; nil
+	// for programs
+	SubcourseID *uuid.UUID
+	// ParentTrashed is set when the parent is in the trash too
+	ParentTrashed bool
+	DeletedAt     *time.Time
+	DeletedBy     *uuid.UUID
+}
+
+// TrashSubtree is what a program, subcourse or lesson takes along when it is
+// purged, trashed children included
+type TrashSubtree struct {
+	Subcourses int64
+	Lessons    int64
+	// Components counts by component path, e.g. "content-blocks" or "quizzes/options"
+	Components map[string]int64
+	Templates  int64
+	Media      []models.Media
+}
+
+type TrashRepository interface {
+	// List returns the trashed entries of kind within scope
+	List(ctx context.Context, kind TrashKind, scope TrashScope) ([]TrashEntry, error)
+	// Get returns the entry id of kind, live or trashed
+	Get(ctx context.Context, kind TrashKind, id uuid.UUID) (*TrashEntry, error)
+	// Restore brings a trashed entry back, with the children trashed together
+	// with it; children deleted earlier stay in the trash
+	Restore(ctx context.Context, entry *TrashEntry) error
+	// Purge removes the entry and its whole subtree for good and returns the
+	// URLs of the media that went with it
+	Purge(ctx context.Context, kind TrashKind, id uuid.UUID) ([]string, error)
+	// Expired returns the entries of kind trashed before the given time
+	Expired(ctx context.Context, kind TrashKind, before time.Time) ([]uuid.UUID, error)
+	// Subtree counts what purging the entry would remove
+	Subtree(ctx context.Context, kind TrashKind, id uuid.UUID) (*TrashSubtree, error)
+}
+
+// trashModels are the rows behind each kind
+var trashModels = map[TrashKind]func() interface{}{
+	TrashProgram:   func() interface{} { return &models.Program{} },
+	TrashSubcourse: func() interface{} { return &models.Subcourse{} },
+	TrashLesson:    func() interface{} { return &models.Lesson{} },
+}
+
+type gormTrash struct{ db *gorm.DB }
+
+func deletedAt(at gorm.DeletedAt) *time.Time {
+	if !at.Valid {
+		return nil
+	}
+	t := at.Time
+	return &t
+}
+
+func programEntry(p *models.Program) TrashEntry {
+	id := p.ID
+	return TrashEntry{
+		Kind: TrashProgram, ID: p.ID, Title: p.Name, Slug: p.Slug, OrganizationID: p.OrganizationID,
+		ProgramID: &id, DeletedAt: deletedAt(p.DeletedAt), DeletedBy: p.DeletedBy,
+	}
+}
+
+func subcourseEntry(s *models.Subcourse) TrashEntry {
+	programID, id := s.ProgramID, s.ID
+	return TrashEntry{
+		Kind: TrashSubcourse, ID: s.ID, Title: s.Name, Slug: s.Slug, OrganizationID: s.OrganizationID,
+		ProgramID: &programID, SubcourseID: &id,
+		ParentTrashed: s.Program != nil && s.Program.DeletedAt.Valid,
+		DeletedAt:     deletedAt(s.DeletedAt), DeletedBy: s.DeletedBy,
+	}
+}
+
+func lessonEntry(l *models.Lesson) TrashEntry {
+	subcourseID := l.SubcourseID
+	entry := TrashEntry{
+		Kind: TrashLesson, ID: l.ID, Title: l.Title, Slug: l.Slug, OrganizationID: l.OrganizationID,
+		SubcourseID: &subcourseID, DeletedAt: deletedAt(l.DeletedAt), DeletedBy: l.DeletedBy,
+	}
+	if l.Subcourse != nil {
+		programID := l.Subcourse.ProgramID
+		entry.ProgramID = &programID
+		entry.ParentTrashed = l.Subcourse.DeletedAt.Valid
+	}
+	return entry
+}
+
+func unscoped(db *gorm.DB) *gorm.DB { return db.Unscoped() }
+
+func (r *gormTrash) List(ctx context.Context, kind TrashKind, scope TrashScope) ([]TrashEntry, error) {
+	q := conn(r.db, ctx).Unscoped().Where("deleted_at IS NOT NULL")
+	programIDs, subcourseIDs := append(scope.ProgramIDs, uuid.Nil), append(scope.SubcourseIDs, uuid.Nil)
+	var entries []TrashEntry
+	switch kind {
+	case TrashProgram:
+		if !scope.All {
+			q = q.Where("id IN ?", programIDs)
+		}
+		var programs []models.Program
+		if err := q.Find(&programs).Error; err != nil {
+			return nil, err
+		}
+		for i := range programs {
+			entries = append(entries, programEntry(&programs[i]))
+		}
+	case TrashSubcourse:
+		if !scope.All {
+			q = q.Where("program_id IN ? OR id IN ?", programIDs, subcourseIDs)
+		}
+		var subcourses []models.Subcourse
+		if err := q.Preload("Program", unscoped).Find(&subcourses).Error; err != nil {
+			return nil, err
+		}
+		for i := range subcourses {
+			entries = append(entries, subcourseEntry(&subcourses[i]))
+		}
+	case TrashLesson:
+		if !scope.All {
+			q = q.Where("subcourse_id IN ? OR subcourse_id IN (SELECT id FROM subcourses WHERE program_id IN ?)", subcourseIDs, programIDs)
+		}
+		var lessons []models.Lesson
+		if err := q.Preload("Subcourse", unscoped).Find(&lessons).Error; err != nil {
+			return nil, err
+		}
+		for i := range lessons {
+			entries = append(entries, lessonEntry(&lessons[i]))
+		}
+	}
+	return entries, nil
+}
+
+func (r *gormTrash) Get(ctx context.Context, kind TrashKind, id uuid.UUID) (*TrashEntry, error) {
+	db := conn(r.db, ctx).Unscoped()
+	var entry TrashEntry
+	switch kind {
+	case TrashProgram:
+		var program models.Program
+		if err := db.First(&program, "id = ?", id).Error; err != nil {
+			return nil, notFound(err)
+		}
+		entry = programEntry(&program)
+	case TrashSubcourse:
+		var subcourse models.Subcourse
+		if err := db.Preload("Program", unscoped).First(&subcourse, "id = ?", id).Error; err != nil {
+			return nil, notFound(err)
+		}
+		entry = subcourseEntry(&subcourse)
+	case TrashLesson:
+		var lesson models.Lesson
+		if err := db.Preload("Subcourse", unscoped).First(&lesson, "id = ?", id).Error; err != nil {
+			return nil, notFound(err)
+		}
+		entry = lessonEntry(&lesson)
+	default:
+		return nil, ErrNotFound
+	}
+	return &entry, nil
+}
+
+// restoreRows brings the trashed rows of model matching query back
+func restoreRows(db *gorm.DB, model interface{}, query string, args ...interface{}) error {
+	return db.Unscoped().Model(model).Where(query, args...).Where("deleted_at IS NOT NULL").
+		Updates(map[string]interface{}{
+			"deleted_at": nil,
+			"deleted_by": nil,
+			"version":    gorm.Expr("version + 1"),
+			"updated_at": time.Now(),
+		}).Error
+}
+
+func (r *gormTrash) Restore(ctx context.Context, entry *TrashEntry) error {
+	if entry.DeletedAt == nil {
+		return nil
+	}
+	db := conn(r.db, ctx)
+	at := *entry.DeletedAt
+	switch entry.Kind {
+	case TrashProgram:
+		if err := restoreRows(db, &models.Program{}, "id = ?", entry.ID); err != nil {
+			return err
+		}
+		subcourses := db.Session(&gorm.Session{NewDB: true}).Unscoped().Model(&models.Subcourse{}).Select("id").
+			Where("program_id = ? AND deleted_at = ?", entry.ID, at)
+		if err := restoreRows(db, &models.Lesson{}, "subcourse_id IN (?) AND deleted_at = ?", subcourses, at); err != nil {
+			return err
+		}
+		return restoreRows(db, &models.Subcourse{}, "program_id = ? AND deleted_at = ?", entry.ID, at)
+	case TrashSubcourse:
+		if err := restoreRows(db, &models.Subcourse{}, "id = ?", entry.ID); err != nil {
+			return err
+		}
+		return restoreRows(db, &models.Lesson{}, "subcourse_id = ? AND deleted_at = ?", entry.ID, at)
+	default:
+		return restoreRows(db, &models.Lesson{}, "id = ?", entry.ID)
+	}
+}
+
+func (r *gormTrash) Purge(ctx context.Context, kind TrashKind, id uuid.UUID) ([]string, error) {
+	db := conn(r.db, ctx)
+	switch kind {
+	case TrashProgram:
+		return purgeProgram(db, id)
+	case TrashSubcourse:
+		return purgeSubcourse(db, id)
+	default:
+		return purgeLesson(db, id)
+	}
+}
+
+func (r *gormTrash) Expired(ctx context.Context, kind TrashKind, before time.Time) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := conn(r.db, ctx).Unscoped().Model(trashModels[kind]()).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", before).Pluck("id", &ids).Error
+	return ids, err
+}
+
+// purgeMedia deletes the media rows of the given owners and returns their URLs
+func purgeMedia(db *gorm.DB, ownerType models.MediaOwnerType, ownerIDs []uuid.UUID) ([]string, error) {
+	if len(ownerIDs) == 0 {
+		return nil, nil
+	}
+	var urls []string
+	if err := db.Model(&models.Media{}).Where("owner_type = ? AND owner_id IN ?", ownerType, ownerIDs).Pluck("url", &urls).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("owner_type = ? AND owner_id IN ?", ownerType, ownerIDs).Delete(&models.Media{}).Error; err != nil {
+		return nil, err
+	}
+	return urls, nil
+}
+
+// purgeLesson hard-deletes a lesson with its components and media
+func purgeLesson(db *gorm.DB, lessonID uuid.UUID) ([]string, error) {
+	urls, err := purgeMedia(db, models.OwnerLesson, []uuid.UUID{lessonID})
+	if err != nil {
+		return nil, err
+	}
+	for _, comp := range LessonComponents {
+		var ids []uuid.UUID
+		if err := db.Model(comp.newOne()).Where(comp.ParentColumn+" = ?", lessonID).Pluck("id", &ids).Error; err != nil {
+			return nil, err
+		}
+		if comp.OwnerType != "" {
+			more, err := purgeMedia(db, comp.OwnerType, ids)
+			if err != nil {
+				return nil, err
+			}
+			urls = append(urls, more...)
+		}
+		for _, id := range ids {
+			if err := deleteComponent(db, comp, id); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := db.Unscoped().Delete(&models.Lesson{}, "id = ?", lessonID).Error; err != nil {
+		return nil, err
+	}
+	return urls, nil
+}
+
+// purgeSubcourse hard-deletes a subcourse with all its lessons
+func purgeSubcourse(db *gorm.DB, subcourseID uuid.UUID) ([]string, error) {
+	var lessonIDs []uuid.UUID
+	if err := db.Unscoped().Model(&models.Lesson{}).Where("subcourse_id = ?", subcourseID).Pluck("id", &lessonIDs).Error; err != nil {
+		return nil, err
+	}
+	var urls []string
+	for _, id := range lessonIDs {
+		more, err := purgeLesson(db, id)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, more...)
+	}
+	more, err := purgeMedia(db, models.OwnerSubcourse, []uuid.UUID{subcourseID})
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Unscoped().Delete(&models.Subcourse{}, "id = ?", subcourseID).Error; err != nil {
+		return nil, err
+	}
+	return append(urls, more...), nil
+}
+
+// purgeProgram hard-deletes a program with its subcourses, templates and shares
+func purgeProgram(db *gorm.DB, programID uuid.UUID) ([]string, error) {
+	var subcourseIDs []uuid.UUID
+	if err := db.Unscoped().Model(&models.Subcourse{}).Where("program_id = ?", programID).Pluck("id", &subcourseIDs).Error; err != nil {
+		return nil, err
+	}
+	var urls []string
+	for _, id := range subcourseIDs {
+		more, err := purgeSubcourse(db, id)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, more...)
+	}
+	more, err := purgeMedia(db, models.OwnerProgram, []uuid.UUID{programID})
+	if err != nil {
+		return nil, err
+	}
+	urls = append(urls, more...)
+	if err := db.Where("program_id = ?", programID).Delete(&models.LessonTemplate{}).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("program_id = ?", programID).Delete(&models.ProgramShare{}).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Unscoped().Delete(&models.Program{}, "id = ?", programID).Error; err != nil {
+		return nil, err
+	}
+	return urls, nil
+}
+
+// mediaOwners selects the ids of one media owner type within a subtree
+type mediaOwners struct {
+	ownerType models.MediaOwnerType
+	ids       *gorm.DB
+}
+
+func (r *gormTrash) Subtree(ctx context.Context, kind TrashKind, id uuid.UUID) (*TrashSubtree, error) {
+	db := conn(r.db, ctx)
+	tree := &TrashSubtree{Components: map[string]int64{}}
+	// subqueries start from a fresh statement and include trashed rows
+	sub := func(model interface{}) *gorm.DB {
+		return db.Session(&gorm.Session{NewDB: true}).Unscoped().Model(model).Select("id")
+	}
+
+	var subcourses, lessons *gorm.DB
+	var owners []mediaOwners
+	switch kind {
+	case TrashProgram:
+		subcourses = sub(&models.Subcourse{}).Where("program_id = ?", id)
+		lessons = sub(&models.Lesson{}).Where("subcourse_id IN (?)", subcourses)
+		owners = append(owners, mediaOwners{models.OwnerProgram, sub(&models.Program{}).Where("id = ?", id)})
+		if err := db.Model(&models.LessonTemplate{}).Where("program_id = ?", id).Count(&tree.Templates).Error; err != nil {
+			return nil, err
+		}
+		if err := db.Unscoped().Model(&models.Subcourse{}).Where("program_id = ?", id).Count(&tree.Subcourses).Error; err != nil {
+			return nil, err
+		}
+	case TrashSubcourse:
+		subcourses = sub(&models.Subcourse{}).Where("id = ?", id)
+		lessons = sub(&models.Lesson{}).Where("subcourse_id = ?", id)
+	default:
+		lessons = sub(&models.Lesson{}).Where("id = ?", id)
+	}
+	if subcourses != nil {
+		owners = append(owners, mediaOwners{models.OwnerSubcourse, subcourses})
+	}
+	if kind != TrashLesson {
+		if err := db.Unscoped().Model(&models.Lesson{}).Where("id IN (?)", lessons).Count(&tree.Lessons).Error; err != nil {
+			return nil, err
+		}
+	}
+	owners = append(owners, mediaOwners{models.OwnerLesson, lessons})
+
+	for _, comp := range LessonComponents {
+		var n int64
+		if err := db.Model(comp.newOne()).Where("lesson_id IN (?)", lessons).Count(&n).Error; err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			continue
+		}
+		tree.Components[comp.Path] = n
+		if comp.OwnerType != "" {
+			owners = append(owners, mediaOwners{comp.OwnerType, sub(comp.newOne()).Where("lesson_id IN (?)", lessons)})
+		}
+		if comp.Children != nil {
+			parents := sub(comp.newOne()).Where("lesson_id IN (?)", lessons)
+			var children int64
+			if err := db.Model(comp.Children.newOne()).Where(comp.Children.ParentColumn+" IN (?)", parents).Count(&children).Error; err != nil {
+				return nil, err
+			}
+			if children > 0 {
+				tree.Components[comp.Path+"/"+comp.Children.Path] = children
+			}
+		}
+	}
+
+	for _, o := range owners {
+		var batch []models.Media
+		if err := db.Select("id", "url", "meta").Where("owner_type = ? AND owner_id IN (?)", o.ownerType, o.ids).Find(&batch).Error; err != nil {
+			return nil, err
+		}
+		tree.Media = append(tree.Media, batch...)
+	}
+	return tree, nil
+}
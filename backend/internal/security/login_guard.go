@@ -0,0 +1,172 @@
+package security
+
+import (
+	"context"
+	"courseai/backend/internal/config"
+	"courseai/backend/internal/metrics"
+	"courseai/backend/internal/models"
+	"courseai/backend/internal/repository"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrLocked is returned when an account or IP is temporarily locked out.
+// ErrThrottled is returned when the progressive delay has not elapsed yet.
+var (
+	ErrLocked    = errors.New("too many failed login attempts, temporarily locked")
+	ErrThrottled = errors.New("too many failed login attempts, slow down")
+)
+
+// BlockedError carries the reason and how long the caller must wait.
+type BlockedError struct {
+	Err        error
+	Scope      models.ThrottleScope
+	RetryAfter time.Duration
+}
+
+func (e *BlockedError) Error() string { return e.Err.Error() }
+func (e *BlockedError) Unwrap() error { return e.Err }
+
+// LoginGuard tracks failed logins per account and per IP in the database,
+// so state survives restarts and is shared between replicas.
+type LoginGuard struct {
+	uow repository.UnitOfWork
+	cfg config.SecurityConfig
+}
+
+func NewLoginGuard(uow repository.UnitOfWork, cfg config.SecurityConfig) *LoginGuard {
+	return &LoginGuard{uow: uow, cfg: cfg}
+}
+
+// AccountKey returns the throttle key for an account. Unknown identifiers are
+// tracked by the identifier itself so lockout behaves the same for them.
+func AccountKey(userID *uuid.UUID, identifier string) string {
+	if userID != nil {
+		return fmt.Sprintf("%s:%s", models.ThrottleScopeAccount, userID.String())
+	}
+	return fmt.Sprintf("%s:%s", models.ThrottleScopeAccount, strings.ToLower(strings.TrimSpace(identifier)))
+}
+
+// IPKey returns the throttle key for a client IP
+func IPKey(ip string) string {
+	return fmt.Sprintf("%s:%s", models.ThrottleScopeIP, ip)
+}
+
+// Attempt is a login attempt Reserve counted as failed
+type Attempt struct {
+	key string
+	// lockedUntil is the lockout this attempt started, if any
+	lockedUntil *time.Time
+}
+
+// Reserve counts an attempt for key as failed before the credentials are
+// checked and applies the progressive delay or lockout; it returns a
+// *BlockedError, and counts nothing, when key is locked or throttled.
+// Counting first means parallel guesses cannot all get past the guard while
+// the first one is still being checked. An attempt whose credentials turn out
+// right is given back with Release.
+func (g *LoginGuard) Reserve(key string) (*Attempt, error) {
+	// Postgres keeps microseconds; Release compares the lockout it stored
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	scope := scopeOf(key)
+	max := g.cfg.LoginMaxFailures
+	if scope == models.ThrottleScopeIP {
+		max = g.cfg.LoginIPMaxFailures
+	}
+
+	attempt := &Attempt{key: key}
+	t, counted, err := g.uow.Repositories().LoginThrottles.Reserve(context.Background(), key, scope, now, now.Add(-g.lockoutDuration()),
+		func(failures int) (*time.Time, *time.Time) {
+			if max > 0 && failures >= max {
+				until := now.Add(g.lockoutDuration())
+				attempt.lockedUntil = &until
+				return nil, &until
+			}
+			next := now.Add(g.delayFor(failures))
+			return &next, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	if !counted {
+		if t.LockedUntil != nil && t.LockedUntil.After(now) {
+			return nil, &BlockedError{Err: ErrLocked, Scope: t.Scope, RetryAfter: t.LockedUntil.Sub(now)}
+		}
+		var retryAfter time.Duration
+		if t.NextAttemptAt != nil {
+			retryAfter = t.NextAttemptAt.Sub(now)
+		}
+		return nil, &BlockedError{Err: ErrThrottled, Scope: t.Scope, RetryAfter: retryAfter}
+	}
+	return attempt, nil
+}
+
+// Release takes back an attempt whose credentials were right: its failure is
+// uncounted and the delay it started is lifted, as is its lockout. A nil
+// attempt is ignored.
+func (g *LoginGuard) Release(a *Attempt) error {
+	if a == nil {
+		return nil
+	}
+	return g.uow.Repositories().LoginThrottles.Release(context.Background(), a.key, a.lockedUntil)
+}
+
+// Reset clears the counter for key (after a successful login or an admin unlock)
+func (g *LoginGuard) Reset(key string) error {
+	return g.uow.Repositories().LoginThrottles.Delete(context.Background(), key)
+}
+
+// Status returns the current throttle row for key, or nil if there is none
+func (g *LoginGuard) Status(key string) (*models.LoginThrottle, error) {
+	t, err := g.uow.Repositories().LoginThrottles.Get(context.Background(), key)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	return t, err
+}
+
+// RecordAttempt appends an entry to the login history
+func (g *LoginGuard) RecordAttempt(userID *uuid.UUID, identifier, ip, userAgent string, success bool, reason string) error {
+	metrics.ObserveLogin(success, reason)
+	attempt := models.LoginAttempt{
+		UserID:     userID,
+		Identifier: identifier,
+		IP:         ip,
+		UserAgent:  userAgent,
+		Success:    success,
+		Reason:     reason,
+	}
+	return g.uow.Repositories().LoginAttempts.Create(context.Background(), &attempt)
+}
+
+// scopeOf returns the scope a key was built for by AccountKey or IPKey
+func scopeOf(key string) models.ThrottleScope {
+	scope, _, _ := strings.Cut(key, ":")
+	return models.ThrottleScope(scope)
+}
+
+func (g *LoginGuard) lockoutDuration() time.Duration {
+	return time.Duration(g.cfg.LoginLockoutMinutes) * time.Minute
+}
+
+func (g *LoginGuard) delayFor(failures int) time.Duration {
+	if failures <= 0 || g.cfg.LoginDelayBaseSeconds <= 0 {
+		return 0
+	}
+	delay := time.Duration(g.cfg.LoginDelayBaseSeconds) * time.Second
+	maxDelay := time.Duration(g.cfg.LoginDelayMaxSeconds) * time.Second
+	for i := 1; i < failures; i++ {
+		delay *= 2
+		if maxDelay > 0 && delay >= maxDelay {
+			return maxDelay
+		}
+	}
+	if maxDelay > 0 && delay > maxDelay {
+		return maxDelay
+	}
+	return delay
+}
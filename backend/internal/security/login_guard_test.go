@@ -0,0 +1,183 @@
+package security
+
+import (
+	"context"
+	"courseai/backend/internal/config"
+	"courseai/backend/internal/models"
+	"courseai/backend/internal/repository"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// fakeThrottles keeps the counters in memory; the mutex stands in for the
+// row lock of the conditional update
+type fakeThrottles struct {
+	repository.LoginThrottleRepository
+	mu   sync.Mutex
+	rows map[string]models.LoginThrottle
+}
+
+func (f *fakeThrottles) Get(ctx context.Context, key string) (*models.LoginThrottle, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t, ok := f.rows[key]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &t, nil
+}
+
+func (f *fakeThrottles) Reserve(ctx context.Context, key string, scope models.ThrottleScope, now, window time.Time, limit repository.ThrottleLimit) (*models.LoginThrottle, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t, ok := f.rows[key]
+	if !ok {
+		t = models.LoginThrottle{Key: key, Scope: scope}
+	}
+	if (t.LockedUntil != nil && t.LockedUntil.After(now)) || (t.NextAttemptAt != nil && t.NextAttemptAt.After(now)) {
+		return &t, false, nil
+	}
+	if t.LastFailureAt == nil || t.LastFailureAt.Before(window) {
+		t.Failures = 1
+	} else {
+		t.Failures++
+	}
+	t.LastFailureAt = &now
+	t.UpdatedAt = now
+	t.NextAttemptAt, t.LockedUntil = limit(t.Failures)
+	f.rows[key] = t
+	return &t, true, nil
+}
+
+func (f *fakeThrottles) Release(ctx context.Context, key string, lockedUntil *time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t, ok := f.rows[key]
+	if !ok || t.Failures == 0 {
+		return nil
+	}
+	t.Failures--
+	t.NextAttemptAt = nil
+	if lockedUntil != nil && t.LockedUntil != nil && t.LockedUntil.Equal(*lockedUntil) {
+		t.LockedUntil = nil
+	}
+	f.rows[key] = t
+	return nil
+}
+
+func (f *fakeThrottles) Delete(ctx context.Context, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.rows, key)
+	return nil
+}
+
+type fakeUnitOfWork struct{ throttles *fakeThrottles }
+
+func (u *fakeUnitOfWork) Repositories() repository.Repositories {
+	return repository.Repositories{LoginThrottles: u.throttles}
+}
+
+func (u *fakeUnitOfWork) Do(ctx context.Context, fn func(r repository.Repositories) error) error {
+	return fn(u.Repositories())
+}
+
+func newTestGuard(cfg config.SecurityConfig) *LoginGuard {
+	return NewLoginGuard(&fakeUnitOfWork{&fakeThrottles{rows: map[string]models.LoginThrottle{}}}, cfg)
+}
+
+// guessInParallel runs n wrong-password logins at once, each holding its
+// reservation for as long as a password check takes, and returns how many
+// got past the guard
+func guessInParallel(t *testing.T, g *LoginGuard, key string, n int) int {
+	t.Helper()
+	var mu sync.Mutex
+	passed := 0
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := g.Reserve(key)
+			if err != nil {
+				var blocked *BlockedError
+				if !errors.As(err, &blocked) {
+					t.Errorf("Reserve: %v", err)
+				}
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+			mu.Lock()
+			passed++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return passed
+}
+
+func TestReserveHoldsLockoutUnderConcurrentLogins(t *testing.T) {
+	g := newTestGuard(config.SecurityConfig{LoginMaxFailures: 5, LoginLockoutMinutes: 15})
+	key := AccountKey(nil, "alice")
+
+	if passed := guessInParallel(t, g, key, 50); passed != 5 {
+		t.Fatalf("%d of 50 parallel guesses were checked, want 5", passed)
+	}
+	_, err := g.Reserve(key)
+	var blocked *BlockedError
+	if !errors.As(err, &blocked) || !errors.Is(err, ErrLocked) {
+		t.Fatalf("after the parallel guesses Reserve = %v, want a lockout", err)
+	}
+	if blocked.RetryAfter <= 14*time.Minute {
+		t.Errorf("RetryAfter = %v, want about 15m", blocked.RetryAfter)
+	}
+}
+
+func TestReserveHoldsDelayUnderConcurrentLogins(t *testing.T) {
+	g := newTestGuard(config.SecurityConfig{LoginMaxFailures: 5, LoginLockoutMinutes: 15, LoginDelayBaseSeconds: 1, LoginDelayMaxSeconds: 30})
+
+	if passed := guessInParallel(t, g, IPKey("203.0.113.7"), 20); passed != 1 {
+		t.Fatalf("%d of 20 parallel guesses were checked, want 1 before the delay", passed)
+	}
+}
+
+func TestReleaseLiftsOnlyItsOwnLockout(t *testing.T) {
+	g := newTestGuard(config.SecurityConfig{LoginMaxFailures: 2, LoginLockoutMinutes: 15})
+	id := uuid.New()
+	key := AccountKey(&id, "")
+
+	wrong, err := g.Reserve(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	right, err := g.Reserve(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Reserve(key); !errors.Is(err, ErrLocked) {
+		t.Fatalf("Reserve after 2 failures = %v, want ErrLocked", err)
+	}
+
+	// giving back an attempt that did not start the lockout keeps it
+	if err := g.Release(wrong); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Reserve(key); !errors.Is(err, ErrLocked) {
+		t.Fatalf("Reserve = %v, want the lockout to stay", err)
+	}
+
+	// the attempt that started it had the right password
+	if err := g.Release(right); err != nil {
+		t.Fatal(err)
+	}
+	if status, err := g.Status(key); err != nil || status.Failures != 0 || status.LockedUntil != nil {
+		t.Fatalf("Status = %+v, %v; want no failures and no lockout", status, err)
+	}
+	if _, err := g.Reserve(key); err != nil {
+		t.Fatalf("Reserve after release = %v", err)
+	}
+}
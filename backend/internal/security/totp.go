@@ -0,0 +1,114 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters (RFC 6238). These are the defaults every authenticator app supports.
+const (
+	totpPeriod = 30
+	totpDigits = 6
+	// accept one step of clock drift in either direction
+	totpSkew = 1
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewTOTPSecret returns a random 160-bit base32 secret
+func NewTOTPSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(b), nil
+}
+
+// TOTPProvisioningURI returns the otpauth:// URI encoded in enrollment QR codes
+func TOTPProvisioningURI(issuer, account, secret string) string {
+	label := url.PathEscape(issuer + ":" + account)
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(totpDigits))
+	q.Set("period", fmt.Sprint(totpPeriod))
+	return "otpauth://totp/" + label + "?" + q.Encode()
+}
+
+// VerifyTOTP checks code against secret at time t. It returns the matched time
+// step; callers must reject steps <= the last accepted one to prevent replay.
+func VerifyTOTP(secret, code string, t time.Time) (int64, bool) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return 0, false
+	}
+	step := t.Unix() / totpPeriod
+	for i := int64(-totpSkew); i <= totpSkew; i++ {
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step+i)), []byte(code)) == 1 {
+			return step + i, true
+		}
+	}
+	return 0, false
+}
+
+// TOTPCode returns the code an authenticator app shows for secret at time t
+func TOTPCode(secret string, t time.Time) (string, error) {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return totpCode(key, t.Unix()/totpPeriod), nil
+}
+
+func decodeTOTPSecret(secret string) ([]byte, error) {
+	return totpEncoding.DecodeString(strings.ToUpper(secret))
+}
+
+func totpCode(key []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// NewRecoveryCodes returns n human-friendly single-use codes (xxxxx-xxxxx)
+func NewRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		h := hex.EncodeToString(b)
+		codes[i] = h[:5] + "-" + h[5:]
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode normalizes and hashes a recovery code for storage and lookup.
+// The codes carry 40 bits of randomness and are single use, so a plain SHA-256 suffices.
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
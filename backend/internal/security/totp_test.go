@@ -0,0 +1,72 @@
+package security
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed of RFC 6238 Appendix B, "12345678901234567890"
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+// The appendix lists 8-digit codes; a 6-digit code is their last six digits.
+func TestTOTPCodeMatchesRFC6238(t *testing.T) {
+	for _, tt := range []struct {
+		unix int64
+		code string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	} {
+		got, err := TOTPCode(rfcSecret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := tt.code[2:]; got != want {
+			t.Errorf("T=%d: code %s, want %s", tt.unix, got, want)
+		}
+	}
+}
+
+func TestVerifyTOTPSkewWindow(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	step := now.Unix() / totpPeriod
+	for _, tt := range []struct {
+		offset int64
+		ok     bool
+	}{
+		{-2, false}, {-1, true}, {0, true}, {1, true}, {2, false},
+	} {
+		code, err := TOTPCode(rfcSecret, now.Add(time.Duration(tt.offset*totpPeriod)*time.Second))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, ok := VerifyTOTP(rfcSecret, code, now)
+		if ok != tt.ok {
+			t.Errorf("code %+d steps away: ok = %v, want %v", tt.offset, ok, tt.ok)
+		}
+		if ok && got != step+tt.offset {
+			t.Errorf("code %+d steps away: matched step %d, want %d", tt.offset, got, step+tt.offset)
+		}
+	}
+}
+
+func TestVerifyTOTPNormalizesInput(t *testing.T) {
+	now := time.Unix(59, 0)
+	// lower-case secrets and codes typed with spaces are accepted
+	if _, ok := VerifyTOTP("gezdgnbvgy3tqojqgezdgnbvgy3tqojq", " 287 082 ", now); !ok {
+		t.Error("spaced code with a lower-case secret was rejected")
+	}
+	for _, code := range []string{"", "28708", "2870820", "28708x", "94287082"} {
+		if _, ok := VerifyTOTP(rfcSecret, code, now); ok {
+			t.Errorf("code %q was accepted", code)
+		}
+	}
+	if _, ok := VerifyTOTP("not base32!", "287082", now); ok {
+		t.Error("an invalid secret accepted a code")
+	}
+}
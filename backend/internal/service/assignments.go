@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"courseai/backend/internal/database"
+	"courseai/backend/internal/models"
+	"courseai/backend/internal/repository"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AssignmentService manages which programs and subcourses teachers are
+// assigned to, and enforces those assignments on assignment-scoped actors
+type AssignmentService struct {
+	uow repository.UnitOfWork
+}
+
+func NewAssignmentService(uow repository.UnitOfWork) *AssignmentService {
+	return &AssignmentService{uow: uow}
+}
+
+// Assigned is what a teacher is actively assigned to. Program assignments
+// take precedence: a teacher who has any gets no subcourses.
+type Assigned struct {
+	ProgramIDs   []uuid.UUID
+	SubcourseIDs []uuid.UUID
+}
+
+// AssignmentMemo remembers the assignments looked up while serving one
+// request, so its access checks query them once
+type AssignmentMemo struct {
+	mu       sync.Mutex
+	assigned map[uuid.UUID]*Assigned
+}
+
+type assignmentMemoKey struct{}
+
+// WithAssignmentMemo returns a context whose assignment lookups are
+// remembered in memo
+func WithAssignmentMemo(ctx context.Context, memo *AssignmentMemo) context.Context {
+	return context.WithValue(ctx, assignmentMemoKey{}, memo)
+}
+
+func assignmentMemo(ctx context.Context) *AssignmentMemo {
+	memo, _ := ctx.Value(assignmentMemoKey{}).(*AssignmentMemo)
+	return memo
+}
+
+func (m *AssignmentMemo) get(teacherID uuid.UUID) *Assigned {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.assigned[teacherID]
+}
+
+func (m *AssignmentMemo) set(teacherID uuid.UUID, assigned *Assigned) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.assigned == nil {
+		m.assigned = map[uuid.UUID]*Assigned{}
+	}
+	m.assigned[teacherID] = assigned
+}
+
+func (m *AssignmentMemo) forget(teacherID uuid.UUID) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.assigned, teacherID)
+}
+
+// Assigned returns what the teacher is actively assigned to
+func (s *AssignmentService) Assigned(ctx context.Context, teacherID uuid.UUID) (*Assigned, error) {
+	memo := assignmentMemo(ctx)
+	if assigned := memo.get(teacherID); assigned != nil {
+		return assigned, nil
+	}
+	programIDs, subcourseIDs, err := s.uow.Repositories().Assignments.ActiveIDs(ctx, teacherID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	assigned := &Assigned{ProgramIDs: programIDs, SubcourseIDs: subcourseIDs}
+	if len(programIDs) > 0 {
+		assigned.SubcourseIDs = []uuid.UUID{}
+	}
+	memo.set(teacherID, assigned)
+	return assigned, nil
+}
+
+// ProgramIDs returns the programs the teacher is actively assigned to
+func (s *AssignmentService) ProgramIDs(ctx context.Context, teacherID uuid.UUID) ([]uuid.UUID, error) {
+	assigned, err := s.Assigned(ctx, teacherID)
+	if err != nil {
+		return nil, err
+	}
+	return assigned.ProgramIDs, nil
+}
+
+// SubcourseIDs returns the subcourses the teacher is actively assigned to;
+// empty when program assignments take precedence
+func (s *AssignmentService) SubcourseIDs(ctx context.Context, teacherID uuid.UUID) ([]uuid.UUID, error) {
+	assigned, err := s.Assigned(ctx, teacherID)
+	if err != nil {
+		return nil, err
+	}
+	return assigned.SubcourseIDs, nil
+}
+
+// Active returns the teacher's active assignments
+func (s *AssignmentService) Active(ctx context.Context, teacherID uuid.UUID) ([]models.TeacherAssignment, error) {
+	return s.uow.Repositories().Assignments.ListActive(ctx, teacherID)
+}
+
+// Current returns the teacher's active assignments whose period includes now
+func (s *AssignmentService) Current(ctx context.Context, teacherID uuid.UUID) ([]models.TeacherAssignment, error) {
+	return s.uow.Repositories().Assignments.ListCurrent(ctx, teacherID, time.Now().UTC())
+}
+
+// CanAccessProgram fails unless the actor may work on the program
+func (s *AssignmentService) CanAccessProgram(ctx context.Context, actor Actor, programID uuid.UUID) error {
+	if actor.ScopeAll {
+		return nil
+	}
+	programIDs, err := s.ProgramIDs(ctx, actor.UserID)
+	if err != nil {
+		return err
+	}
+	if containsID(programIDs, programID) {
+		return nil
+	}
+	return forbidden("Access to program denied")
+}
+
+// CanAccessSubcourse fails unless the actor is assigned to the subcourse or
+// to its program
+func (s *AssignmentService) CanAccessSubcourse(ctx context.Context, actor Actor, subcourseID uuid.UUID) error {
+	if actor.ScopeAll {
+		return nil
+	}
+	assigned, err := s.Assigned(ctx, actor.UserID)
+	if err != nil {
+		return err
+	}
+	if containsID(assigned.SubcourseIDs, subcourseID) {
+		return nil
+	}
+
+	subcourse, err := s.uow.Repositories().Subcourses.Get(ctx, subcourseID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return notFound("Subcourse not found")
+		}
+		return err
+	}
+	if containsID(assigned.ProgramIDs, subcourse.ProgramID) {
+		return nil
+	}
+	return forbidden("Access to subcourse denied")
+}
+
+// CanAccessLesson fails unless the actor is assigned to the lesson's
+// subcourse or program
+func (s *AssignmentService) CanAccessLesson(ctx context.Context, actor Actor, lessonID uuid.UUID) error {
+	if actor.ScopeAll {
+		return nil
+	}
+	lesson, err := s.uow.Repositories().Lessons.Get(ctx, lessonID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return notFound("Lesson not found")
+		}
+		return err
+	}
+	assigned, err := s.Assigned(ctx, actor.UserID)
+	if err != nil {
+		return err
+	}
+	if containsID(assigned.SubcourseIDs, lesson.SubcourseID) {
+		return nil
+	}
+	if lesson.Subcourse != nil && containsID(assigned.ProgramIDs, lesson.Subcourse.ProgramID) {
+		return nil
+	}
+	return forbidden("Access to lesson denied")
+}
+
+// AssignInput replaces a teacher's assignments at one scope. StartAt defaults
+// to now; EndAt nil means open-ended.
+type AssignInput struct {
+	IDs     []uuid.UUID
+	StartAt *time.Time
+	EndAt   *time.Time
+}
+
+// AssignPrograms replaces the teacher's program assignments
+func (s *AssignmentService) AssignPrograms(ctx context.Context, teacherID uuid.UUID, input AssignInput) ([]models.TeacherAssignment, error) {
+	return s.assign(ctx, teacherID, models.ScopeProgram, input)
+}
+
+// AssignSubcourses replaces the teacher's subcourse assignments
+func (s *AssignmentService) AssignSubcourses(ctx context.Context, teacherID uuid.UUID, input AssignInput) ([]models.TeacherAssignment, error) {
+	return s.assign(ctx, teacherID, models.ScopeSubcourse, input)
+}
+
+func (s *AssignmentService) assign(ctx context.Context, teacherID uuid.UUID, scope models.AssignmentScope, input AssignInput) ([]models.TeacherAssignment, error) {
+	start := input.StartAt
+	if start == nil {
+		now := time.Now()
+		start = &now
+	}
+
+	var created []models.TeacherAssignment
+	err := s.uow.Do(ctx, func(r repository.Repositories) error {
+		teacher, err := r.Users.FindTeacher(ctx, teacherID)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return notFound("Teacher not found")
+			}
+			return err
+		}
+		if err := r.Assignments.DeleteScope(ctx, teacherID, scope); err != nil {
+			return err
+		}
+
+		// the content must be visible to the teacher's organization (owned or shared)
+		teacherCtx := ctx
+		if teacher.OrganizationID != nil {
+			teacherCtx = database.WithTenant(ctx, *teacher.OrganizationID)
+		}
+		for _, id := range input.IDs {
+			id := id
+			assignment := models.TeacherAssignment{
+				ID:             uuid.New(),
+				TeacherID:      teacherID,
+				OrganizationID: teacher.OrganizationID,
+				ScopeLevel:     scope,
+				Status:         models.AssignmentStatusActive,
+				StartAt:        start,
+				EndAt:          input.EndAt,
+			}
+			if scope == models.ScopeProgram {
+				if _, err := r.Programs.Get(teacherCtx, id); err != nil {
+					if errors.Is(err, repository.ErrNotFound) {
+						return invalidf("Program not found: %s", id)
+					}
+					return err
+				}
+				assignment.ProgramID = &id
+			} else {
+				if _, err := r.Subcourses.Get(teacherCtx, id); err != nil {
+					if errors.Is(err, repository.ErrNotFound) {
+						return invalidf("Subcourse not found: %s", id)
+					}
+					return err
+				}
+				assignment.SubcourseID = &id
+			}
+			if err := r.Assignments.Create(ctx, &assignment); err != nil {
+				return err
+			}
+			created = append(created, assignment)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// the assignments changed
+	assignmentMemo(ctx).forget(teacherID)
+	return created, nil
+}
@@ -0,0 +1,510 @@
+package service
+
+import (
+	"context"
+	"courseai/backend/internal/database"
+	"courseai/backend/internal/models"
+	"courseai/backend/internal/repository"
+	"courseai/backend/internal/telemetry"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// CloneService deep-copies programs, subcourses and lessons. Uploaded files
+// are copied too, so a copy stays intact when the original's media is deleted.
+type CloneService struct {
+	uow         repository.UnitOfWork
+	assignments *AssignmentService
+	// dir is where uploaded files live; they are served under /uploads
+	dir string
+}
+
+func NewCloneService(uow repository.UnitOfWork, assignments *AssignmentService, dir string) *CloneService {
+	return &CloneService{uow: uow, assignments: assignments, dir: dir}
+}
+
+// CloneOptions override the name and slug of the top-level copy; nested
+// copies keep their names and get fresh slugs
+type CloneOptions struct {
+	Name string
+	Slug string
+}
+
+// CloneLesson copies a lesson into subcourseID, or next to the original when
+// it is nil, and returns the copy as the editor loads it
+func (s *CloneService) CloneLesson(ctx context.Context, actor Actor, id uuid.UUID, subcourseID *uuid.UUID, opts CloneOptions) (*models.Lesson, error) {
+	if err := s.assignments.CanAccessLesson(ctx, actor, id); err != nil {
+		return nil, err
+	}
+	repos := s.uow.Repositories()
+	src, err := repos.Lessons.GetTree(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, notFound("Lesson not found")
+		}
+		return nil, err
+	}
+
+	targetID := src.SubcourseID
+	if subcourseID != nil {
+		targetID = *subcourseID
+		if err := s.assignments.CanAccessSubcourse(ctx, actor, targetID); err != nil {
+			return nil, err
+		}
+	}
+	subcourse, err := repos.Subcourses.Get(ctx, targetID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, invalidf("Subcourse not found")
+		}
+		return nil, err
+	}
+	if err := requireOwned(ctx, subcourse.OrganizationID); err != nil {
+		return nil, err
+	}
+
+	cl := &cloner{ctx: ctx, dir: s.dir, authorID: &actor.UserID}
+	var clone *models.Lesson
+	err = s.uow.Do(ctx, func(r repository.Repositories) error {
+		cl.r = r
+		sortOrder, err := r.Lessons.NextSortOrder(ctx, subcourse.ID)
+		if err != nil {
+			return err
+		}
+		clone, err = cl.lesson(src, subcourse, &cloneTop{CloneOptions: opts, sortOrder: sortOrder})
+		if err != nil {
+			return err
+		}
+		return r.Lessons.RefreshCounters(ctx, nil, clone.ID)
+	})
+	if err != nil {
+		cl.discardFiles()
+		return nil, fmt.Errorf("clone lesson %s: %w", id, err)
+	}
+	return repos.Lessons.GetTree(ctx, clone.ID)
+}
+
+// CloneSubcourse copies a subcourse with its lessons into programID, or
+// next to the original when it is nil
+func (s *CloneService) CloneSubcourse(ctx context.Context, actor Actor, id uuid.UUID, programID *uuid.UUID, opts CloneOptions) (*models.Subcourse, error) {
+	if err := s.assignments.CanAccessSubcourse(ctx, actor, id); err != nil {
+		return nil, err
+	}
+	repos := s.uow.Repositories()
+	src, err := repos.Subcourses.GetDetail(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, notFound("Subcourse not found")
+		}
+		return nil, err
+	}
+
+	targetID := src.ProgramID
+	if programID != nil {
+		targetID = *programID
+		if err := s.assignments.CanAccessProgram(ctx, actor, targetID); err != nil {
+			return nil, err
+		}
+	}
+	program, err := repos.Programs.Get(ctx, targetID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, invalidf("Program not found")
+		}
+		return nil, err
+	}
+	if err := requireOwned(ctx, program.OrganizationID); err != nil {
+		return nil, err
+	}
+
+	cl := &cloner{ctx: ctx, dir: s.dir, authorID: &actor.UserID}
+	var clone *models.Subcourse
+	err = s.uow.Do(ctx, func(r repository.Repositories) error {
+		cl.r = r
+		sortOrder, err := r.Subcourses.NextSortOrder(ctx, program.ID)
+		if err != nil {
+			return err
+		}
+		clone, err = cl.subcourse(src, program, &cloneTop{CloneOptions: opts, sortOrder: sortOrder})
+		if err != nil {
+			return err
+		}
+		return r.Subcourses.RefreshCounters(ctx, nil, clone.ID)
+	})
+	if err != nil {
+		cl.discardFiles()
+		return nil, fmt.Errorf("clone subcourse %s: %w", id, err)
+	}
+	return repos.Subcourses.GetDetail(ctx, clone.ID)
+}
+
+// CloneProgram copies a program with its subcourses and lessons. A shared
+// program is copied into the context's organization, which owns the copy.
+func (s *CloneService) CloneProgram(ctx context.Context, actor Actor, id uuid.UUID, opts CloneOptions) (*models.Program, error) {
+	if err := s.assignments.CanAccessProgram(ctx, actor, id); err != nil {
+		return nil, err
+	}
+	repos := s.uow.Repositories()
+	src, err := repos.Programs.GetDetail(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, notFound("Program not found")
+		}
+		return nil, err
+	}
+	orgID := src.OrganizationID
+	if tenantID, ok := database.TenantFromContext(ctx); ok {
+		orgID = &tenantID
+	}
+
+	cl := &cloner{ctx: ctx, dir: s.dir, authorID: &actor.UserID}
+	var clone *models.Program
+	var subcourseIDs []uuid.UUID
+	err = s.uow.Do(ctx, func(r repository.Repositories) error {
+		cl.r = r
+		sortOrder, err := r.Programs.NextSortOrder(ctx)
+		if err != nil {
+			return err
+		}
+		clone, subcourseIDs, err = cl.program(src, orgID, &cloneTop{CloneOptions: opts, sortOrder: sortOrder})
+		if err != nil {
+			return err
+		}
+		if err := r.Subcourses.RefreshCounters(ctx, nil, subcourseIDs...); err != nil {
+			return err
+		}
+		return r.Programs.RefreshCounters(ctx, clone.ID)
+	})
+	if err != nil {
+		cl.discardFiles()
+		return nil, fmt.Errorf("clone program %s: %w", id, err)
+	}
+	return repos.Programs.GetDetail(ctx, clone.ID)
+}
+
+// cloneTop carries the overrides for the top-level copy; nil for nested copies
+type cloneTop struct {
+	CloneOptions
+	sortOrder int
+}
+
+// cloner writes the copies of one clone through the repositories of its
+// transaction; discardFiles removes the copied files again if it fails
+type cloner struct {
+	ctx         context.Context
+	r           repository.Repositories
+	dir         string
+	authorID    *uuid.UUID
+	copiedFiles []string
+}
+
+// program copies src and returns the copy and its subcourses
+func (cl *cloner) program(src *models.Program, orgID *uuid.UUID, top *cloneTop) (*models.Program, []uuid.UUID, error) {
+	p := *src
+	p.ID = uuid.Nil
+	p.OrganizationID = orgID
+	p.Name = copyName(src.Name, top.Name)
+	p.Status = models.StatusDraft
+	p.SortOrder = top.sortOrder
+	p.CreatedAt, p.UpdatedAt = time.Time{}, time.Time{}
+	p.Subcourses, p.Media = nil, nil
+
+	slug, err := cl.uniqueSlug(cl.r.Programs.SlugInUse, copySlug(src.Slug, top.Slug))
+	if err != nil {
+		return nil, nil, err
+	}
+	p.Slug = slug
+	if err := cl.r.Programs.Create(cl.ctx, &p); err != nil {
+		return nil, nil, fmt.Errorf("create program: %w", err)
+	}
+	if _, err := cl.media(src.Media, models.OwnerProgram, p.ID); err != nil {
+		return nil, nil, err
+	}
+
+	subcourses, err := cl.r.Subcourses.List(cl.ctx, repository.SubcourseFilter{ProgramID: &src.ID})
+	if err != nil {
+		return nil, nil, fmt.Errorf("load subcourses: %w", err)
+	}
+	ids := make([]uuid.UUID, 0, len(subcourses))
+	for i := range subcourses {
+		s, err := cl.subcourse(&subcourses[i], &p, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		ids = append(ids, s.ID)
+	}
+	return &p, ids, nil
+}
+
+func (cl *cloner) subcourse(src *models.Subcourse, program *models.Program, top *cloneTop) (*models.Subcourse, error) {
+	s := *src
+	s.ID = uuid.Nil
+	s.ProgramID = program.ID
+	s.OrganizationID = program.OrganizationID
+	s.CreatedAt, s.UpdatedAt = time.Time{}, time.Time{}
+	s.Program, s.Lessons, s.Media = nil, nil, nil
+	base := src.Slug
+	if top != nil {
+		s.Name = copyName(src.Name, top.Name)
+		s.Status = models.StatusDraft
+		s.SortOrder = top.sortOrder
+		base = copySlug(src.Slug, top.Slug)
+	}
+
+	slug, err := cl.uniqueSlug(cl.r.Subcourses.SlugInUse, base)
+	if err != nil {
+		return nil, err
+	}
+	s.Slug = slug
+	if err := cl.r.Subcourses.Create(cl.ctx, &s); err != nil {
+		return nil, fmt.Errorf("create subcourse: %w", err)
+	}
+	if _, err := cl.media(src.Media, models.OwnerSubcourse, s.ID); err != nil {
+		return nil, err
+	}
+
+	lessons, err := cl.r.Lessons.ListTrees(cl.ctx, src.ID)
+	if err != nil {
+		return nil, fmt.Errorf("load lessons: %w", err)
+	}
+	for i := range lessons {
+		if _, err := cl.lesson(&lessons[i], &s, nil); err != nil {
+			return nil, err
+		}
+	}
+	return &s, nil
+}
+
+// lesson copies a lesson loaded as GetTree loads it into subcourse
+func (cl *cloner) lesson(src *models.Lesson, subcourse *models.Subcourse, top *cloneTop) (*models.Lesson, error) {
+	l := *src
+	l.ID = uuid.Nil
+	l.SubcourseID = subcourse.ID
+	l.OrganizationID = subcourse.OrganizationID
+	l.AuthorID = cl.authorID
+	// copies start unpublished
+	l.Status = models.StatusDraft
+	l.PublishedAt = nil
+	l.IsFeatured = false
+	l.CreatedAt, l.UpdatedAt = time.Time{}, time.Time{}
+	l.Subcourse = nil
+	base := src.Slug
+	if top != nil {
+		l.Title = copyName(src.Title, top.Name)
+		l.SortOrder = top.sortOrder
+		base = copySlug(src.Slug, top.Slug)
+	}
+	slug, err := cl.uniqueSlug(cl.r.Lessons.SlugInUse, base)
+	if err != nil {
+		return nil, err
+	}
+	l.Slug = slug
+
+	var mediaIDs map[uuid.UUID]uuid.UUID
+	if l.Media, mediaIDs, err = cl.copyMedia(src.Media); err != nil {
+		return nil, err
+	}
+	l.CoverMediaID = nil
+	if src.CoverMediaID != nil {
+		if newID, ok := mediaIDs[*src.CoverMediaID]; ok {
+			l.CoverMediaID = &newID
+		}
+	}
+
+	if src.Objectives != nil {
+		o := *src.Objectives
+		o.ID = uuid.Nil
+		o.CreatedAt, o.UpdatedAt = time.Time{}, time.Time{}
+		l.Objectives = &o
+	}
+	if src.Preparation != nil {
+		p := *src.Preparation
+		p.ID = uuid.Nil
+		p.CreatedAt, p.UpdatedAt = time.Time{}, time.Time{}
+		if p.Media, _, err = cl.copyMedia(p.Media); err != nil {
+			return nil, err
+		}
+		l.Preparation = &p
+	}
+	l.Models = append([]models.LessonModel(nil), src.Models...)
+	for i := range l.Models {
+		m := &l.Models[i]
+		m.ID = uuid.Nil
+		m.CreatedAt, m.UpdatedAt = time.Time{}, time.Time{}
+		if m.Media, _, err = cl.copyMedia(m.Media); err != nil {
+			return nil, err
+		}
+	}
+	l.Builds = append([]models.LessonBuild(nil), src.Builds...)
+	for i := range l.Builds {
+		b := &l.Builds[i]
+		b.ID = uuid.Nil
+		b.CreatedAt, b.UpdatedAt = time.Time{}, time.Time{}
+		if b.Media, _, err = cl.copyMedia(b.Media); err != nil {
+			return nil, err
+		}
+	}
+	l.ContentBlocks = append([]models.LessonContentBlock(nil), src.ContentBlocks...)
+	for i := range l.ContentBlocks {
+		cb := &l.ContentBlocks[i]
+		cb.ID = uuid.Nil
+		cb.CreatedAt, cb.UpdatedAt = time.Time{}, time.Time{}
+		if cb.Media, _, err = cl.copyMedia(cb.Media); err != nil {
+			return nil, err
+		}
+	}
+	l.Attachments = append([]models.LessonAttachment(nil), src.Attachments...)
+	for i := range l.Attachments {
+		a := &l.Attachments[i]
+		a.ID = uuid.Nil
+		a.CreatedAt, a.UpdatedAt = time.Time{}, time.Time{}
+		if a.Media, _, err = cl.copyMedia(a.Media); err != nil {
+			return nil, err
+		}
+	}
+	l.Challenges = append([]models.LessonChallenge(nil), src.Challenges...)
+	for i := range l.Challenges {
+		ch := &l.Challenges[i]
+		ch.ID = uuid.Nil
+		ch.CreatedAt, ch.UpdatedAt = time.Time{}, time.Time{}
+		if ch.Media, _, err = cl.copyMedia(ch.Media); err != nil {
+			return nil, err
+		}
+	}
+	l.Quizzes = append([]models.LessonQuiz(nil), src.Quizzes...)
+	for i := range l.Quizzes {
+		q := &l.Quizzes[i]
+		q.ID = uuid.Nil
+		q.CreatedAt, q.UpdatedAt = time.Time{}, time.Time{}
+		q.Options = append([]models.LessonQuizOption(nil), q.Options...)
+		for j := range q.Options {
+			q.Options[j].ID = uuid.Nil
+			q.Options[j].CreatedAt, q.Options[j].UpdatedAt = time.Time{}, time.Time{}
+		}
+	}
+
+	if err := cl.r.Lessons.Create(cl.ctx, &l); err != nil {
+		return nil, fmt.Errorf("create lesson: %w", err)
+	}
+	return &l, nil
+}
+
+// media copies the media of a program or subcourse to its copy
+func (cl *cloner) media(src []models.Media, ownerType models.MediaOwnerType, ownerID uuid.UUID) (map[uuid.UUID]uuid.UUID, error) {
+	media, ids, err := cl.copyMedia(src)
+	if err != nil || len(media) == 0 {
+		return ids, err
+	}
+	if err := cl.r.Media.Replace(cl.ctx, ownerType, ownerID, media); err != nil {
+		return nil, fmt.Errorf("create media: %w", err)
+	}
+	return ids, nil
+}
+
+// copyMedia returns copies of src with new IDs and copied files, and a map
+// of old to new media IDs; the owner is set when the copies are stored
+func (cl *cloner) copyMedia(src []models.Media) ([]models.Media, map[uuid.UUID]uuid.UUID, error) {
+	ids := make(map[uuid.UUID]uuid.UUID, len(src))
+	if len(src) == 0 {
+		return nil, ids, nil
+	}
+	out := make([]models.Media, len(src))
+	for i, m := range src {
+		url, err := cl.copyUpload(m.URL)
+		if err != nil {
+			return nil, nil, err
+		}
+		m.ID = uuid.New()
+		m.URL = url
+		m.CreatedAt, m.UpdatedAt = time.Time{}, time.Time{}
+		ids[src[i].ID] = m.ID
+		out[i] = m
+	}
+	return out, ids, nil
+}
+
+// copyUpload duplicates an uploaded file and returns its URL. External URLs,
+// and files missing on disk, are kept as they are.
+func (cl *cloner) copyUpload(url string) (string, error) {
+	srcPath, ok := uploadFile(cl.dir, url)
+	if !ok {
+		return url, nil
+	}
+
+	_, span := telemetry.Start(cl.ctx, "storage.copy", trace.WithAttributes(attribute.String("storage.path", srcPath)))
+	defer span.End()
+
+	in, err := os.Open(srcPath)
+	if err != nil {
+		log.Printf("Clone: media file %s not found, keeping original URL", srcPath)
+		return url, nil
+	}
+	defer in.Close()
+
+	name := fmt.Sprintf("%s_%d%s", uuid.New().String(), time.Now().UnixNano(), filepath.Ext(srcPath))
+	destPath := filepath.Join(filepath.Dir(srcPath), name)
+	out, err := os.Create(destPath)
+	if err != nil {
+		return "", fmt.Errorf("copy media file: %w", err)
+	}
+	cl.copiedFiles = append(cl.copiedFiles, destPath)
+	n, err := io.Copy(out, in)
+	span.SetAttributes(attribute.Int64("storage.bytes", n))
+	if err != nil {
+		out.Close()
+		telemetry.RecordError(span, err)
+		return "", fmt.Errorf("copy media file: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return "", fmt.Errorf("copy media file: %w", err)
+	}
+	return uploadURL(cl.dir, destPath), nil
+}
+
+// discardFiles removes files copied by a clone whose transaction failed
+func (cl *cloner) discardFiles() {
+	for _, p := range cl.copiedFiles {
+		_ = os.Remove(p)
+	}
+	cl.copiedFiles = nil
+}
+
+// uniqueSlug returns base, or base-N, that inUse reports free. Slugs are
+// unique across all organizations.
+func (cl *cloner) uniqueSlug(inUse func(ctx context.Context, slug string, exceptID uuid.UUID) (bool, error), base string) (string, error) {
+	slug := base
+	for suffix := 1; ; suffix++ {
+		taken, err := inUse(cl.ctx, slug, uuid.Nil)
+		if err != nil {
+			return "", fmt.Errorf("check slug: %w", err)
+		}
+		if !taken {
+			return slug, nil
+		}
+		slug = fmt.Sprintf("%s-%d", base, suffix)
+	}
+}
+
+func copyName(original, override string) string {
+	if strings.TrimSpace(override) != "" {
+		return override
+	}
+	return original + " (copy)"
+}
+
+func copySlug(original, override string) string {
+	if strings.TrimSpace(override) != "" {
+		return override
+	}
+	return original + "-copy"
+}
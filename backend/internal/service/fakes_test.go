@@ -0,0 +1,714 @@
+package service
+
+import (
+	"context"
+	"courseai/backend/internal/models"
+	"courseai/backend/internal/repository"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// fakeStore is an in-memory stand-in for the database behind the
+// repositories. The fakes implement what the services under test call; the
+// embedded interfaces panic on anything else, which flags a test that
+// reaches further than it set up.
+type fakeStore struct {
+	mu            sync.Mutex
+	assignments   []models.TeacherAssignment
+	organizations map[uuid.UUID]*models.Organization
+	shares        []models.ProgramShare
+	users         map[uuid.UUID]*models.User
+	recoveryCodes []models.RecoveryCode
+	identities    []models.UserIdentity
+	loginStates   map[string]*models.OIDCLoginState
+	programs      map[uuid.UUID]*models.Program
+	subcourses    map[uuid.UUID]*models.Subcourse
+	lessons       map[uuid.UUID]*models.Lesson
+	templates     map[uuid.UUID]*models.LessonTemplate
+	media         map[uuid.UUID][]models.Media
+	trash         map[uuid.UUID]*repository.TrashEntry
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		organizations: map[uuid.UUID]*models.Organization{},
+		users:         map[uuid.UUID]*models.User{},
+		loginStates:   map[string]*models.OIDCLoginState{},
+		programs:      map[uuid.UUID]*models.Program{},
+		subcourses:    map[uuid.UUID]*models.Subcourse{},
+		lessons:       map[uuid.UUID]*models.Lesson{},
+		templates:     map[uuid.UUID]*models.LessonTemplate{},
+		media:         map[uuid.UUID][]models.Media{},
+		trash:         map[uuid.UUID]*repository.TrashEntry{},
+	}
+}
+
+func (s *fakeStore) Repositories() repository.Repositories {
+	return repository.Repositories{
+		Assignments:   fakeAssignments{s: s},
+		Organizations: fakeOrganizations{s: s},
+		Shares:        fakeShares{s: s},
+		Users:         fakeUsers{s: s},
+		RecoveryCodes: fakeRecoveryCodes{s: s},
+		Identities:    fakeIdentities{s: s},
+		LoginStates:   fakeLoginStates{s: s},
+		Programs:      fakePrograms{s: s},
+		Subcourses:    fakeSubcourses{s: s},
+		Lessons:       fakeLessons{s: s},
+		Media:         fakeMedia{s: s},
+		Templates:     fakeTemplates{s: s},
+		Trash:         fakeTrash{s: s},
+	}
+}
+
+// Do runs fn against the store itself; the fakes do not roll back
+func (s *fakeStore) Do(ctx context.Context, fn func(r repository.Repositories) error) error {
+	return fn(s.Repositories())
+}
+
+func (s *fakeStore) addOrganization(slug string) *models.Organization {
+	org := &models.Organization{ID: uuid.New(), Name: slug, Slug: slug, Status: models.OrgStatusActive}
+	s.organizations[org.ID] = org
+	return org
+}
+
+func (s *fakeStore) addUser(username string, role models.UserRole, org *models.Organization) *models.User {
+	user := &models.User{ID: uuid.New(), Username: username, Email: username + "@example.com", Role: role, Status: models.StatusActive}
+	if org != nil {
+		user.OrganizationID = &org.ID
+	}
+	s.users[user.ID] = user
+	return user
+}
+
+func (s *fakeStore) addProgram(slug string, org *models.Organization) *models.Program {
+	program := &models.Program{ID: uuid.New(), Name: slug, Slug: slug, Version: 1}
+	if org != nil {
+		program.OrganizationID = &org.ID
+	}
+	s.programs[program.ID] = program
+	return program
+}
+
+func (s *fakeStore) addSubcourse(slug string, program *models.Program) *models.Subcourse {
+	subcourse := &models.Subcourse{ID: uuid.New(), ProgramID: program.ID, OrganizationID: program.OrganizationID, Name: slug, Slug: slug, Version: 1}
+	s.subcourses[subcourse.ID] = subcourse
+	return subcourse
+}
+
+func (s *fakeStore) addTemplate(program *models.Program, blueprint string) *models.LessonTemplate {
+	template := &models.LessonTemplate{ID: uuid.New(), ProgramID: program.ID, OrganizationID: program.OrganizationID, Name: "template", Blueprint: datatypes.JSON(blueprint)}
+	s.templates[template.ID] = template
+	return template
+}
+
+// addTrashed puts a lesson trashed at deletedAt into the trash, with media
+// rows for urls
+func (s *fakeStore) addTrashed(kind repository.TrashKind, subcourse *models.Subcourse, deletedAt time.Time, urls ...string) *repository.TrashEntry {
+	entry := &repository.TrashEntry{
+		Kind: kind, ID: uuid.New(), Slug: string(kind), OrganizationID: subcourse.OrganizationID,
+		ProgramID: &subcourse.ProgramID, SubcourseID: &subcourse.ID, DeletedAt: &deletedAt,
+	}
+	if kind == repository.TrashSubcourse {
+		entry.ID = subcourse.ID
+	}
+	s.trash[entry.ID] = entry
+	for _, url := range urls {
+		s.media[entry.ID] = append(s.media[entry.ID], models.Media{ID: uuid.New(), OwnerID: entry.ID, URL: url})
+	}
+	return entry
+}
+
+func (s *fakeStore) assign(teacher *models.User, scope models.AssignmentScope, id uuid.UUID) {
+	assignment := models.TeacherAssignment{ID: uuid.New(), TeacherID: teacher.ID, ScopeLevel: scope, Status: models.AssignmentStatusActive}
+	if scope == models.ScopeProgram {
+		assignment.ProgramID = &id
+	} else {
+		assignment.SubcourseID = &id
+	}
+	s.assignments = append(s.assignments, assignment)
+}
+
+type fakeAssignments struct {
+	repository.AssignmentRepository
+	s *fakeStore
+}
+
+func (f fakeAssignments) ActiveIDs(ctx context.Context, teacherID uuid.UUID, at time.Time) ([]uuid.UUID, []uuid.UUID, error) {
+	programIDs, subcourseIDs := []uuid.UUID{}, []uuid.UUID{}
+	for _, a := range f.s.assignments {
+		if a.TeacherID != teacherID || a.Status != models.AssignmentStatusActive {
+			continue
+		}
+		if a.ProgramID != nil {
+			programIDs = append(programIDs, *a.ProgramID)
+		}
+		if a.SubcourseID != nil {
+			subcourseIDs = append(subcourseIDs, *a.SubcourseID)
+		}
+	}
+	return programIDs, subcourseIDs, nil
+}
+
+type fakeOrganizations struct {
+	repository.OrganizationRepository
+	s *fakeStore
+}
+
+func (f fakeOrganizations) Get(ctx context.Context, id uuid.UUID) (*models.Organization, error) {
+	org, ok := f.s.organizations[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	copied := *org
+	return &copied, nil
+}
+
+func (f fakeOrganizations) Find(ctx context.Context, ref string) (*models.Organization, error) {
+	for _, org := range f.s.organizations {
+		if org.Slug == ref || org.ID.String() == ref {
+			copied := *org
+			return &copied, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (f fakeOrganizations) Default(ctx context.Context) (*models.Organization, error) {
+	if org, err := f.Find(ctx, models.DefaultOrganizationSlug); err == nil {
+		return org, nil
+	}
+	return f.s.addOrganization(models.DefaultOrganizationSlug), nil
+}
+
+func (f fakeOrganizations) Create(ctx context.Context, org *models.Organization) error {
+	if org.ID == uuid.Nil {
+		org.ID = uuid.New()
+	}
+	copied := *org
+	f.s.organizations[org.ID] = &copied
+	return nil
+}
+
+func (f fakeOrganizations) SlugInUse(ctx context.Context, slug string, exceptID uuid.UUID) (bool, error) {
+	for _, org := range f.s.organizations {
+		if org.Slug == slug && org.ID != exceptID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type fakeShares struct {
+	repository.ShareRepository
+	s *fakeStore
+}
+
+func (f fakeShares) Ensure(ctx context.Context, programID, organizationID uuid.UUID) (*models.ProgramShare, error) {
+	for i := range f.s.shares {
+		if f.s.shares[i].ProgramID == programID && f.s.shares[i].OrganizationID == organizationID {
+			return &f.s.shares[i], nil
+		}
+	}
+	share := models.ProgramShare{ID: uuid.New(), ProgramID: programID, OrganizationID: organizationID}
+	f.s.shares = append(f.s.shares, share)
+	return &share, nil
+}
+
+type fakeUsers struct {
+	repository.UserRepository
+	s *fakeStore
+}
+
+func (f fakeUsers) Get(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	user, ok := f.s.users[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (f fakeUsers) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	for _, user := range f.s.users {
+		if strings.EqualFold(user.Email, email) {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (f fakeUsers) UsernameInUse(ctx context.Context, username string) (bool, error) {
+	for _, user := range f.s.users {
+		if user.Username == username {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f fakeUsers) Create(ctx context.Context, user *models.User) error {
+	if user.ID == uuid.Nil {
+		user.ID = uuid.New()
+	}
+	copied := *user
+	f.s.users[user.ID] = &copied
+	return nil
+}
+
+func (f fakeUsers) SetRole(ctx context.Context, id uuid.UUID, role models.UserRole, organizationID *uuid.UUID) error {
+	user, ok := f.s.users[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	user.Role = role
+	user.OrganizationID = organizationID
+	return nil
+}
+
+func (f fakeUsers) SetPasswordLoginDisabled(ctx context.Context, id uuid.UUID, disabled bool) error {
+	user, ok := f.s.users[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	user.PasswordLoginDisabled = disabled
+	return nil
+}
+
+func (f fakeUsers) DisableTOTP(ctx context.Context, id uuid.UUID) error {
+	user, ok := f.s.users[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	user.TOTPEnabled = false
+	user.TOTPSecret = ""
+	user.TOTPLastStep = 0
+	return nil
+}
+
+func (f fakeUsers) UseTOTPStep(ctx context.Context, id uuid.UUID, step int64) (bool, error) {
+	user, ok := f.s.users[id]
+	if !ok || user.TOTPLastStep >= step {
+		return false, nil
+	}
+	user.TOTPLastStep = step
+	return true, nil
+}
+
+type fakeRecoveryCodes struct {
+	repository.RecoveryCodeRepository
+	s *fakeStore
+}
+
+func (f fakeRecoveryCodes) Replace(ctx context.Context, userID uuid.UUID, hashes []string) error {
+	kept := f.s.recoveryCodes[:0]
+	for _, code := range f.s.recoveryCodes {
+		if code.UserID != userID {
+			kept = append(kept, code)
+		}
+	}
+	for _, hash := range hashes {
+		kept = append(kept, models.RecoveryCode{ID: uuid.New(), UserID: userID, CodeHash: hash})
+	}
+	f.s.recoveryCodes = kept
+	return nil
+}
+
+func (f fakeRecoveryCodes) Use(ctx context.Context, userID uuid.UUID, hash string, at time.Time) (bool, error) {
+	for i := range f.s.recoveryCodes {
+		code := &f.s.recoveryCodes[i]
+		if code.UserID == userID && code.CodeHash == hash && code.UsedAt == nil {
+			code.UsedAt = &at
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f fakeRecoveryCodes) CountUnused(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var n int64
+	for _, code := range f.s.recoveryCodes {
+		if code.UserID == userID && code.UsedAt == nil {
+			n++
+		}
+	}
+	return n, nil
+}
+
+type fakeIdentities struct {
+	repository.IdentityRepository
+	s *fakeStore
+}
+
+func (f fakeIdentities) Find(ctx context.Context, issuer, subject string) (*models.UserIdentity, error) {
+	for _, identity := range f.s.identities {
+		if identity.Issuer == issuer && identity.Subject == subject {
+			copied := identity
+			return &copied, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (f fakeIdentities) GetForUser(ctx context.Context, id, userID uuid.UUID) (*models.UserIdentity, error) {
+	for _, identity := range f.s.identities {
+		if identity.ID == id && identity.UserID == userID {
+			copied := identity
+			return &copied, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (f fakeIdentities) CountByUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var n int64
+	for _, identity := range f.s.identities {
+		if identity.UserID == userID {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (f fakeIdentities) Create(ctx context.Context, identity *models.UserIdentity) error {
+	if _, err := f.Find(ctx, identity.Issuer, identity.Subject); err == nil {
+		return repository.ErrDuplicate
+	}
+	if identity.ID == uuid.Nil {
+		identity.ID = uuid.New()
+	}
+	f.s.identities = append(f.s.identities, *identity)
+	return nil
+}
+
+func (f fakeIdentities) Delete(ctx context.Context, id uuid.UUID) error {
+	for i, identity := range f.s.identities {
+		if identity.ID == id {
+			f.s.identities = append(f.s.identities[:i], f.s.identities[i+1:]...)
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
+type fakeLoginStates struct {
+	repository.LoginStateRepository
+	s *fakeStore
+}
+
+func (f fakeLoginStates) Create(ctx context.Context, state *models.OIDCLoginState) error {
+	copied := *state
+	f.s.loginStates[state.State] = &copied
+	return nil
+}
+
+func (f fakeLoginStates) Take(ctx context.Context, state string) (*models.OIDCLoginState, error) {
+	f.s.mu.Lock()
+	defer f.s.mu.Unlock()
+	pending, ok := f.s.loginStates[state]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	delete(f.s.loginStates, state)
+	return pending, nil
+}
+
+func (f fakeLoginStates) DeleteExpired(ctx context.Context, before time.Time) error {
+	for state, pending := range f.s.loginStates {
+		if pending.ExpiresAt.Before(before) {
+			delete(f.s.loginStates, state)
+		}
+	}
+	return nil
+}
+
+type fakePrograms struct {
+	repository.ProgramRepository
+	s *fakeStore
+}
+
+func (f fakePrograms) Get(ctx context.Context, id uuid.UUID) (*models.Program, error) {
+	program, ok := f.s.programs[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	copied := *program
+	return &copied, nil
+}
+
+func (f fakePrograms) GetDetail(ctx context.Context, id uuid.UUID) (*models.Program, error) {
+	program, err := f.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	program.Media = f.s.media[id]
+	return program, nil
+}
+
+func (f fakePrograms) List(ctx context.Context, filter repository.ProgramFilter) ([]models.Program, error) {
+	programs := []models.Program{}
+	for _, program := range f.s.programs {
+		if filter.IDs != nil && !containsID(filter.IDs, program.ID) {
+			continue
+		}
+		if filter.Status != "" && string(program.Status) != filter.Status {
+			continue
+		}
+		programs = append(programs, *program)
+	}
+	sort.Slice(programs, func(i, j int) bool { return programs[i].Slug < programs[j].Slug })
+	return programs, nil
+}
+
+func (f fakePrograms) Create(ctx context.Context, program *models.Program) error {
+	if program.ID == uuid.Nil {
+		program.ID = uuid.New()
+	}
+	program.Version = 1
+	copied := *program
+	copied.Media = nil
+	f.s.programs[program.ID] = &copied
+	return nil
+}
+
+func (f fakePrograms) Update(ctx context.Context, id uuid.UUID, updates *models.Program) error {
+	program, ok := f.s.programs[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if updates.Name != "" {
+		program.Name = updates.Name
+	}
+	if updates.Slug != "" {
+		program.Slug = updates.Slug
+	}
+	return nil
+}
+
+func (f fakePrograms) BumpVersion(ctx context.Context, id uuid.UUID, version int) error {
+	program, ok := f.s.programs[id]
+	if !ok || program.Version != version {
+		return repository.ErrVersionConflict
+	}
+	program.Version++
+	return nil
+}
+
+func (f fakePrograms) SlugInUse(ctx context.Context, slug string, exceptID uuid.UUID) (bool, error) {
+	for _, program := range f.s.programs {
+		if program.Slug == slug && program.ID != exceptID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f fakePrograms) RefreshCounters(ctx context.Context, ids ...uuid.UUID) error {
+	return nil
+}
+
+type fakeSubcourses struct {
+	repository.SubcourseRepository
+	s *fakeStore
+}
+
+func (f fakeSubcourses) Get(ctx context.Context, id uuid.UUID) (*models.Subcourse, error) {
+	subcourse, ok := f.s.subcourses[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	copied := *subcourse
+	return &copied, nil
+}
+
+func (f fakeSubcourses) GetDetail(ctx context.Context, id uuid.UUID) (*models.Subcourse, error) {
+	return f.Get(ctx, id)
+}
+
+func (f fakeSubcourses) List(ctx context.Context, filter repository.SubcourseFilter) ([]models.Subcourse, error) {
+	subcourses := []models.Subcourse{}
+	for _, subcourse := range f.s.subcourses {
+		if filter.IDs != nil && !containsID(filter.IDs, subcourse.ID) {
+			continue
+		}
+		if filter.ProgramID != nil && subcourse.ProgramID != *filter.ProgramID {
+			continue
+		}
+		subcourses = append(subcourses, *subcourse)
+	}
+	sort.Slice(subcourses, func(i, j int) bool { return subcourses[i].Slug < subcourses[j].Slug })
+	return subcourses, nil
+}
+
+func (f fakeSubcourses) Update(ctx context.Context, id uuid.UUID, updates *models.Subcourse) error {
+	subcourse, ok := f.s.subcourses[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if updates.Name != "" {
+		subcourse.Name = updates.Name
+	}
+	if updates.ProgramID != uuid.Nil {
+		subcourse.ProgramID = updates.ProgramID
+	}
+	if updates.OrganizationID != nil {
+		subcourse.OrganizationID = updates.OrganizationID
+	}
+	return nil
+}
+
+func (f fakeSubcourses) BumpVersion(ctx context.Context, id uuid.UUID, version int) error {
+	subcourse, ok := f.s.subcourses[id]
+	if !ok || subcourse.Version != version {
+		return repository.ErrVersionConflict
+	}
+	subcourse.Version++
+	return nil
+}
+
+func (f fakeSubcourses) SlugInUse(ctx context.Context, slug string, exceptID uuid.UUID) (bool, error) {
+	for _, subcourse := range f.s.subcourses {
+		if subcourse.Slug == slug && subcourse.ID != exceptID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f fakeSubcourses) RefreshCounters(ctx context.Context, from []uuid.UUID, ids ...uuid.UUID) error {
+	return nil
+}
+
+type fakeMedia struct {
+	repository.MediaRepository
+	s *fakeStore
+}
+
+func (f fakeMedia) Replace(ctx context.Context, ownerType models.MediaOwnerType, ownerID uuid.UUID, media []models.Media) error {
+	f.s.media[ownerID] = append([]models.Media(nil), media...)
+	return nil
+}
+
+func (f fakeMedia) CountByURL(ctx context.Context, urls []string) (map[string]int64, error) {
+	counts := map[string]int64{}
+	for _, media := range f.s.media {
+		for _, m := range media {
+			if containsURL(urls, m.URL) {
+				counts[m.URL]++
+			}
+		}
+	}
+	return counts, nil
+}
+
+func containsURL(urls []string, url string) bool {
+	for _, u := range urls {
+		if u == url {
+			return true
+		}
+	}
+	return false
+}
+
+type fakeLessons struct {
+	repository.LessonRepository
+	s *fakeStore
+}
+
+func (f fakeLessons) Get(ctx context.Context, id uuid.UUID) (*models.Lesson, error) {
+	lesson, ok := f.s.lessons[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	copied := *lesson
+	return &copied, nil
+}
+
+func (f fakeLessons) GetTree(ctx context.Context, id uuid.UUID) (*models.Lesson, error) {
+	return f.Get(ctx, id)
+}
+
+func (f fakeLessons) Create(ctx context.Context, lesson *models.Lesson) error {
+	if lesson.ID == uuid.Nil {
+		lesson.ID = uuid.New()
+	}
+	lesson.Version = 1
+	copied := *lesson
+	f.s.lessons[lesson.ID] = &copied
+	return nil
+}
+
+func (f fakeLessons) SlugInUse(ctx context.Context, slug string, exceptID uuid.UUID) (bool, error) {
+	for _, lesson := range f.s.lessons {
+		if lesson.Slug == slug && lesson.ID != exceptID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f fakeLessons) RefreshCounters(ctx context.Context, from []uuid.UUID, ids ...uuid.UUID) error {
+	return nil
+}
+
+type fakeTemplates struct {
+	repository.TemplateRepository
+	s *fakeStore
+}
+
+func (f fakeTemplates) Get(ctx context.Context, id uuid.UUID) (*models.LessonTemplate, error) {
+	template, ok := f.s.templates[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	copied := *template
+	return &copied, nil
+}
+
+type fakeTrash struct {
+	repository.TrashRepository
+	s *fakeStore
+}
+
+func (f fakeTrash) Get(ctx context.Context, kind repository.TrashKind, id uuid.UUID) (*repository.TrashEntry, error) {
+	entry, ok := f.s.trash[id]
+	if !ok || entry.Kind != kind {
+		return nil, repository.ErrNotFound
+	}
+	copied := *entry
+	return &copied, nil
+}
+
+func (f fakeTrash) Restore(ctx context.Context, entry *repository.TrashEntry) error {
+	delete(f.s.trash, entry.ID)
+	if entry.Kind == repository.TrashLesson {
+		f.s.lessons[entry.ID] = &models.Lesson{ID: entry.ID, SubcourseID: *entry.SubcourseID, OrganizationID: entry.OrganizationID, Slug: entry.Slug, Version: 1}
+	}
+	return nil
+}
+
+func (f fakeTrash) Purge(ctx context.Context, kind repository.TrashKind, id uuid.UUID) ([]string, error) {
+	var urls []string
+	for _, m := range f.s.media[id] {
+		urls = append(urls, m.URL)
+	}
+	delete(f.s.media, id)
+	delete(f.s.trash, id)
+	return urls, nil
+}
+
+func (f fakeTrash) Expired(ctx context.Context, kind repository.TrashKind, before time.Time) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	for _, entry := range f.s.trash {
+		if entry.Kind == kind && entry.DeletedAt.Before(before) {
+			ids = append(ids, entry.ID)
+		}
+	}
+	return ids, nil
+}
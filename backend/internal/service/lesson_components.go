@@ -0,0 +1,764 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"courseai/backend/internal/jsonpatch"
+	"courseai/backend/internal/models"
+	"courseai/backend/internal/repository"
+	"courseai/backend/internal/validation"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Lesson components are edited one at a time through the granular endpoints
+// or all at once through JSON Patch. Quiz options are components of a quiz:
+// their methods take the quiz, other components ignore quizID.
+
+// lessonPatchFields are the lesson columns a JSON Patch may change
+var lessonPatchFields = []string{
+	"title", "subtitle", "overview", "block_types", "status", "sort_order", "slug",
+	"duration_minutes", "difficulty", "estimated_time", "cover_media_id", "is_featured",
+}
+
+// lessonImmutableFields may not be changed through JSON Patch
+var lessonImmutableFields = []string{"id", "subcourse_id", "organization_id", "author_id", "created_at", "published_at"}
+
+// componentName names a component the way the editor locks it, e.g.
+// "content-blocks/<id>" or "quizzes/<quizId>/options/<id>"; singles and whole
+// lists are named by their path alone
+func componentName(comp *repository.LessonComponent, quizID, id uuid.UUID) string {
+	name := comp.Path
+	if comp.ParentColumn == "quiz_id" {
+		name = "quizzes/" + quizID.String() + "/" + comp.Path
+	}
+	if !comp.Single && id != uuid.Nil {
+		name += "/" + id.String()
+	}
+	return name
+}
+
+// ListComponents returns the components of a lesson, or the single component
+func (s *LessonService) ListComponents(ctx context.Context, actor Actor, comp *repository.LessonComponent, lessonID, quizID uuid.UUID) (interface{}, error) {
+	if err := s.assignments.CanAccessLesson(ctx, actor, lessonID); err != nil {
+		return nil, err
+	}
+	if _, err := s.uow.Repositories().Lessons.Get(ctx, lessonID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, notFound("Lesson not found")
+		}
+		return nil, err
+	}
+	parentID, err := s.componentParent(ctx, comp, lessonID, quizID)
+	if err != nil {
+		return nil, err
+	}
+	if comp.Single {
+		item, err := s.uow.Repositories().Components.Get(ctx, comp, parentID, uuid.Nil)
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, notFound("Not found")
+		}
+		return item, err
+	}
+	return s.uow.Repositories().Components.List(ctx, comp, parentID)
+}
+
+// CreateComponent adds the component encoded in raw; a quiz may bring its
+// options. Without a sort_order it goes after its siblings.
+func (s *LessonService) CreateComponent(ctx context.Context, actor Actor, comp *repository.LessonComponent, lessonID, quizID uuid.UUID, raw []byte) (interface{}, error) {
+	if _, err := s.writable(ctx, actor, lessonID, nil); err != nil {
+		return nil, err
+	}
+	item, present, err := decodeComponent(comp, raw)
+	if err != nil {
+		return nil, err
+	}
+	v := validation.New()
+	validation.Component(v, item)
+	if err := v.Err(); err != nil {
+		return nil, rejected(KindInvalid, err)
+	}
+	parentID, err := s.componentParent(ctx, comp, lessonID, quizID)
+	if err != nil {
+		return nil, err
+	}
+	err = s.uow.Do(ctx, func(r repository.Repositories) error {
+		if err := createComponent(ctx, r, comp, item, parentID, present); err != nil {
+			return err
+		}
+		if err := touchLesson(ctx, r, lessonID); err != nil {
+			return err
+		}
+		return checkQuiz(ctx, r, comp, lessonID, parentID)
+	})
+	if err != nil {
+		var e *Error
+		if errors.As(err, &e) {
+			return nil, err
+		}
+		return nil, invalidf("Failed to create %s: %v", comp.JSONKey, err)
+	}
+	id := repository.ComponentID(item)
+	s.announceSaved(ctx, actor, componentName(comp, quizID, id), lessonID)
+	return s.uow.Repositories().Components.Get(ctx, comp, parentID, id)
+}
+
+// PutSingleComponent creates the single component of a lesson, or updates
+// the fields present in raw if it exists
+func (s *LessonService) PutSingleComponent(ctx context.Context, actor Actor, comp *repository.LessonComponent, lessonID uuid.UUID, raw []byte) (interface{}, error) {
+	if _, err := s.writable(ctx, actor, lessonID, nil); err != nil {
+		return nil, err
+	}
+	item, present, err := decodeComponent(comp, raw)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := editableKeys(comp, present)
+	if err != nil {
+		return nil, err
+	}
+	v := validation.New()
+	validation.Component(v, item)
+	if err := v.Err(); err != nil {
+		return nil, rejected(KindInvalid, err)
+	}
+
+	var id uuid.UUID
+	err = s.uow.Do(ctx, func(r repository.Repositories) error {
+		existing, err := r.Components.Get(ctx, comp, lessonID, uuid.Nil)
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			setComponentField(item, "ID", uuid.Nil)
+			if err := createComponent(ctx, r, comp, item, lessonID, present); err != nil {
+				return err
+			}
+			id = repository.ComponentID(item)
+		case err != nil:
+			return err
+		default:
+			id = repository.ComponentID(existing)
+			if len(keys) > 0 {
+				if err := r.Components.Update(ctx, comp, id, item, keys); err != nil {
+					return err
+				}
+			}
+		}
+		return touchLesson(ctx, r, lessonID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.announceSaved(ctx, actor, componentName(comp, uuid.Nil, uuid.Nil), lessonID)
+	return s.uow.Repositories().Components.Get(ctx, comp, lessonID, id)
+}
+
+// PatchComponent updates only the fields present in raw. The component is
+// validated as it will be, e.g. a quiz type change against the stored options.
+func (s *LessonService) PatchComponent(ctx context.Context, actor Actor, comp *repository.LessonComponent, lessonID, quizID, id uuid.UUID, raw []byte) (interface{}, error) {
+	if _, err := s.writable(ctx, actor, lessonID, nil); err != nil {
+		return nil, err
+	}
+	item, present, err := decodeComponent(comp, raw)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := editableKeys(comp, present)
+	if err != nil {
+		return nil, err
+	}
+	parentID, err := s.componentParent(ctx, comp, lessonID, quizID)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.uow.Repositories().Components.Get(ctx, comp, parentID, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, notFound("Not found")
+		}
+		return nil, err
+	}
+	if err := validatePatched(comp, existing, present, keys); err != nil {
+		return nil, err
+	}
+	if len(keys) > 0 {
+		err = s.uow.Do(ctx, func(r repository.Repositories) error {
+			if err := r.Components.Update(ctx, comp, id, item, keys); err != nil {
+				return err
+			}
+			if err := touchLesson(ctx, r, lessonID); err != nil {
+				return err
+			}
+			return checkQuiz(ctx, r, comp, lessonID, parentID)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	s.announceSaved(ctx, actor, componentName(comp, quizID, id), lessonID)
+	return s.uow.Repositories().Components.Get(ctx, comp, parentID, id)
+}
+
+// DeleteComponent removes a component with its media and children; id is
+// ignored for single components
+func (s *LessonService) DeleteComponent(ctx context.Context, actor Actor, comp *repository.LessonComponent, lessonID, quizID, id uuid.UUID) error {
+	if _, err := s.writable(ctx, actor, lessonID, nil); err != nil {
+		return err
+	}
+	parentID, err := s.componentParent(ctx, comp, lessonID, quizID)
+	if err != nil {
+		return err
+	}
+	if comp.Single {
+		id = uuid.Nil
+	}
+	existing, err := s.uow.Repositories().Components.Get(ctx, comp, parentID, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return notFound("Not found")
+		}
+		return err
+	}
+	id = repository.ComponentID(existing)
+	err = s.uow.Do(ctx, func(r repository.Repositories) error {
+		if err := r.Components.Delete(ctx, comp, id); err != nil {
+			return err
+		}
+		if err := touchLesson(ctx, r, lessonID); err != nil {
+			return err
+		}
+		return checkQuiz(ctx, r, comp, lessonID, parentID)
+	})
+	if err != nil {
+		return err
+	}
+	s.announceSaved(ctx, actor, componentName(comp, quizID, id), lessonID)
+	return nil
+}
+
+// ReorderComponents puts the components of a parent in the order of ids,
+// which must list every one of them exactly once
+func (s *LessonService) ReorderComponents(ctx context.Context, actor Actor, comp *repository.LessonComponent, lessonID, quizID uuid.UUID, ids []uuid.UUID) (interface{}, error) {
+	if _, err := s.writable(ctx, actor, lessonID, nil); err != nil {
+		return nil, err
+	}
+	parentID, err := s.componentParent(ctx, comp, lessonID, quizID)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.uow.Repositories().Components.IDs(ctx, comp, parentID)
+	if err != nil {
+		return nil, err
+	}
+	if !sameIDSet(existing, ids) {
+		return nil, invalidf("ids must list every %s item exactly once", comp.JSONKey)
+	}
+	err = s.uow.Do(ctx, func(r repository.Repositories) error {
+		if err := r.Components.SetOrder(ctx, comp, ids); err != nil {
+			return err
+		}
+		return touchLesson(ctx, r, lessonID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.announceSaved(ctx, actor, componentName(comp, quizID, uuid.Nil), lessonID)
+	return s.uow.Repositories().Components.List(ctx, comp, parentID)
+}
+
+// PatchTree applies a JSON Patch (RFC 6902) to the lesson document as Get
+// returns it. Only the rows that actually changed are written, so untouched
+// components keep their IDs. Media arrays are ignored: media is managed
+// through uploads, and cover_media_id may only point at media of the lesson.
+// A failed test operation is a KindConflict, a patch that cannot be applied
+// or yields an invalid lesson a KindUnprocessable.
+func (s *LessonService) PatchTree(ctx context.Context, actor Actor, id uuid.UUID, ops []jsonpatch.Operation, check Precondition) (*models.Lesson, error) {
+	if _, err := s.writable(ctx, actor, id, nil); err != nil {
+		return nil, err
+	}
+	current, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPrecondition(check, current.Version); err != nil {
+		return nil, err
+	}
+
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+	patched, err := jsonpatch.Apply(doc, ops)
+	if err != nil {
+		if errors.Is(err, jsonpatch.ErrTestFailed) {
+			return nil, rejected(KindConflict, err)
+		}
+		return nil, rejected(KindUnprocessable, err)
+	}
+	var before, after map[string]json.RawMessage
+	if err := json.Unmarshal(doc, &before); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patched, &after); err != nil {
+		return nil, &Error{Kind: KindUnprocessable, Message: "Patched document is not an object"}
+	}
+	var next models.Lesson
+	if err := json.Unmarshal(patched, &next); err != nil {
+		return nil, &Error{Kind: KindUnprocessable, Message: "Patched document is not a valid lesson: " + err.Error()}
+	}
+
+	for _, f := range lessonImmutableFields {
+		if !jsonEqual(before[f], after[f]) {
+			return nil, &Error{Kind: KindUnprocessable, Message: f + " cannot be changed with JSON Patch"}
+		}
+	}
+	var changed []string
+	for _, f := range lessonPatchFields {
+		if !jsonEqual(before[f], after[f]) {
+			changed = append(changed, f)
+		}
+	}
+	// the patched document must be a valid lesson as a whole
+	v := validation.New()
+	validation.Lesson(v, &next, false)
+	if err := v.Err(); err != nil {
+		return nil, rejected(KindUnprocessable, err)
+	}
+	if containsString(changed, "slug") {
+		if err := s.checkSlug(ctx, next.Slug, id); err != nil {
+			return nil, slugTaken(err, next.Slug)
+		}
+	}
+	if containsString(changed, "cover_media_id") {
+		if err := s.checkCover(ctx, id, next.CoverMediaID); err != nil {
+			return nil, err
+		}
+	}
+	if containsString(changed, "status") {
+		if err := requirePublish(actor, next.Status); err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.uow.Do(ctx, func(r repository.Repositories) error {
+		if err := r.Lessons.BumpVersion(ctx, id, current.Version); err != nil {
+			return err
+		}
+		if len(changed) > 0 {
+			if next.Status == models.StatusPublished && current.PublishedAt == nil {
+				now := time.Now().UTC()
+				next.PublishedAt = &now
+				changed = append(changed, "published_at")
+			}
+			if err := r.Lessons.Update(ctx, id, &next, changed...); err != nil {
+				return err
+			}
+		}
+		for _, comp := range repository.LessonComponents {
+			if err := syncComponent(ctx, r, comp, id, before[comp.JSONKey], after[comp.JSONKey]); err != nil {
+				return err
+			}
+		}
+		return r.Lessons.RefreshCounters(ctx, nil, id)
+	})
+	if err != nil {
+		if errors.Is(err, ErrVersionConflict) {
+			return nil, err
+		}
+		if err := slugError(err); errors.Is(err, ErrSlugInUse) {
+			return nil, slugTaken(err, next.Slug)
+		}
+		// anything wrong with the patched components is unprocessable
+		var e *Error
+		if errors.As(err, &e) && e.Kind != KindInvalid {
+			return nil, err
+		}
+		return nil, rejected(KindUnprocessable, err)
+	}
+	s.announceSaved(ctx, actor, "", id)
+	return s.Get(ctx, id)
+}
+
+// slugTaken reports ErrSlugInUse as a conflict of the slug field, the way
+// validation reports fields, so a patch learns which slug is taken
+func slugTaken(err error, slug string) error {
+	if !errors.Is(err, ErrSlugInUse) {
+		return err
+	}
+	v := validation.New()
+	v.Add("slug", validation.CodeConflict, "%q is already in use", slug)
+	return rejected(KindConflict, v.Err())
+}
+
+// checkCover rejects a cover that is not media uploaded for the lesson
+// itself, e.g. of another lesson or organization; a nil mediaID clears the
+// cover and is always allowed
+func (s *LessonService) checkCover(ctx context.Context, lessonID uuid.UUID, mediaID *uuid.UUID) error {
+	if mediaID == nil {
+		return nil
+	}
+	media, err := s.uow.Repositories().Media.ListByOwner(ctx, models.OwnerLesson, lessonID)
+	if err != nil {
+		return err
+	}
+	for _, m := range media {
+		if m.ID == *mediaID {
+			return nil
+		}
+	}
+	v := validation.New()
+	v.Add("cover_media_id", validation.CodeInvalid, "must be media of this lesson")
+	return rejected(KindUnprocessable, v.Err())
+}
+
+// componentParent resolves the row components hang off: the lesson, or for
+// quiz options the quiz quizID of the lesson
+func (s *LessonService) componentParent(ctx context.Context, comp *repository.LessonComponent, lessonID, quizID uuid.UUID) (uuid.UUID, error) {
+	if comp.ParentColumn == "lesson_id" {
+		return lessonID, nil
+	}
+	if _, err := s.uow.Repositories().Components.Get(ctx, repository.QuizzesComponent, lessonID, quizID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return uuid.Nil, notFound("Quiz not found")
+		}
+		return uuid.Nil, err
+	}
+	return quizID, nil
+}
+
+// touchLesson bumps the lesson's version and updated_at after a component
+// change and refreshes its media counters
+func touchLesson(ctx context.Context, r repository.Repositories, lessonID uuid.UUID) error {
+	if err := r.Lessons.Touch(ctx, lessonID); err != nil {
+		return err
+	}
+	return r.Lessons.RefreshCounters(ctx, nil, lessonID)
+}
+
+// checkQuiz validates the quiz parentID as a whole after one of its options
+// was written, e.g. that a single-choice quiz still has exactly one correct
+// option. It runs after touchLesson, whose lesson row lock orders concurrent
+// writes to the lesson, so the options read here include every committed one.
+func checkQuiz(ctx context.Context, r repository.Repositories, comp *repository.LessonComponent, lessonID, parentID uuid.UUID) error {
+	if comp != repository.QuizOptionsComponent {
+		return nil
+	}
+	quiz, err := r.Components.Get(ctx, repository.QuizzesComponent, lessonID, parentID)
+	if err != nil {
+		return err
+	}
+	v := validation.New()
+	validation.LessonQuiz(v.At("quiz"), quiz.(*models.LessonQuiz))
+	if err := v.Err(); err != nil {
+		return rejected(KindInvalid, err)
+	}
+	return nil
+}
+
+// decodeComponent decodes raw into a new component and reports which keys were present
+func decodeComponent(comp *repository.LessonComponent, raw []byte) (interface{}, map[string]json.RawMessage, error) {
+	var present map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &present); err != nil {
+		return nil, nil, invalidf("invalid %s: %v", comp.JSONKey, err)
+	}
+	item := comp.New()
+	if err := json.Unmarshal(raw, item); err != nil {
+		return nil, nil, invalidf("invalid %s: %v", comp.JSONKey, err)
+	}
+	return item, present, nil
+}
+
+// setComponentField sets a field of the component row item
+func setComponentField(item interface{}, field string, value interface{}) {
+	reflect.ValueOf(item).Elem().FieldByName(field).Set(reflect.ValueOf(value))
+}
+
+// checkRequired rejects empty required fields among keys (nil keys = all required fields)
+func checkRequired(comp *repository.LessonComponent, present map[string]json.RawMessage, keys []string) error {
+	for _, f := range comp.Required {
+		if keys != nil && !containsString(keys, f) {
+			continue
+		}
+		var s string
+		if raw, ok := present[f]; !ok || json.Unmarshal(raw, &s) != nil || strings.TrimSpace(s) == "" {
+			return invalidf("%s.%s is required", comp.JSONKey, f)
+		}
+	}
+	return nil
+}
+
+// validatePatched validates existing with the keys of present applied to a copy
+func validatePatched(comp *repository.LessonComponent, existing interface{}, present map[string]json.RawMessage, keys []string) error {
+	raw, err := json.Marshal(existing)
+	if err != nil {
+		return err
+	}
+	merged := comp.New()
+	if err := json.Unmarshal(raw, merged); err != nil {
+		return err
+	}
+	changes := make(map[string]json.RawMessage, len(keys))
+	for _, k := range keys {
+		changes[k] = present[k]
+	}
+	raw, _ = json.Marshal(changes)
+	if err := json.Unmarshal(raw, merged); err != nil {
+		return invalidf("invalid %s: %v", comp.JSONKey, err)
+	}
+	v := validation.New()
+	validation.Component(v, merged)
+	if err := v.Err(); err != nil {
+		return rejected(KindInvalid, err)
+	}
+	return nil
+}
+
+// editableKeys returns the editable fields present in a request, rejecting unknown ones
+func editableKeys(comp *repository.LessonComponent, present map[string]json.RawMessage) ([]string, error) {
+	var keys []string
+	for k := range present {
+		switch {
+		case containsString(comp.Fields, k):
+			keys = append(keys, k)
+		case k == "id" || k == comp.ParentColumn || k == "media" || k == "created_at" || k == "updated_at" || (comp.Children != nil && k == comp.Children.JSONKey):
+			// read-only keys echoed back by clients are ignored
+		default:
+			return nil, invalidf("%s.%s is not an editable field", comp.JSONKey, k)
+		}
+	}
+	return keys, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// createComponent inserts item under parentID; quizzes also get their options
+func createComponent(ctx context.Context, r repository.Repositories, comp *repository.LessonComponent, item interface{}, parentID uuid.UUID, present map[string]json.RawMessage) error {
+	setComponentField(item, comp.ParentField, parentID)
+	if !comp.Single {
+		if _, ok := present["sort_order"]; !ok {
+			next, err := r.Components.NextSortOrder(ctx, comp, parentID)
+			if err != nil {
+				return err
+			}
+			reflect.ValueOf(item).Elem().FieldByName("SortOrder").SetInt(int64(next))
+		}
+	}
+	if err := r.Components.Create(ctx, comp, item); err != nil {
+		return err
+	}
+	if comp.Children == nil {
+		return nil
+	}
+	raw, ok := present[comp.Children.JSONKey]
+	if !ok {
+		return nil
+	}
+	var children []json.RawMessage
+	if err := json.Unmarshal(raw, &children); err != nil {
+		return invalidf("invalid %s: %v", comp.Children.JSONKey, err)
+	}
+	for i, childRaw := range children {
+		child, childPresent, err := decodeComponent(comp.Children, childRaw)
+		if err != nil {
+			return err
+		}
+		if err := checkRequired(comp.Children, childPresent, nil); err != nil {
+			return err
+		}
+		if _, ok := childPresent["sort_order"]; !ok {
+			reflect.ValueOf(child).Elem().FieldByName("SortOrder").SetInt(int64(i))
+			childPresent["sort_order"] = json.RawMessage("0")
+		}
+		setComponentField(child, comp.Children.ParentField, repository.ComponentID(item))
+		if err := r.Components.Create(ctx, comp.Children, child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func jsonEqual(a, b json.RawMessage) bool {
+	if len(a) == 0 {
+		a = json.RawMessage("null")
+	}
+	if len(b) == 0 {
+		b = json.RawMessage("null")
+	}
+	var ca, cb bytes.Buffer
+	if json.Compact(&ca, a) != nil || json.Compact(&cb, b) != nil {
+		return bytes.Equal(a, b)
+	}
+	return bytes.Equal(ca.Bytes(), cb.Bytes())
+}
+
+// syncComponent writes the difference between two versions of one component
+// key of the lesson document: new items are created, missing ones deleted and
+// changed fields updated. When the item order changed, sort_order follows it.
+func syncComponent(ctx context.Context, r repository.Repositories, comp *repository.LessonComponent, parentID uuid.UUID, before, after json.RawMessage) error {
+	if jsonEqual(before, after) {
+		return nil
+	}
+	if comp.Single {
+		return syncSingle(ctx, r, comp, parentID, before, after)
+	}
+
+	var oldItems, newItems []json.RawMessage
+	if !jsonEqual(before, nil) {
+		if err := json.Unmarshal(before, &oldItems); err != nil {
+			return err
+		}
+	}
+	if !jsonEqual(after, nil) {
+		if err := json.Unmarshal(after, &newItems); err != nil {
+			return invalidf("%s must be an array", comp.JSONKey)
+		}
+	}
+
+	oldByID := map[uuid.UUID]map[string]json.RawMessage{}
+	var oldOrder []uuid.UUID
+	for _, raw := range oldItems {
+		var m map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return err
+		}
+		var id uuid.UUID
+		_ = json.Unmarshal(m["id"], &id)
+		oldByID[id] = m
+		oldOrder = append(oldOrder, id)
+	}
+
+	var newOrder []uuid.UUID
+	kept := map[uuid.UUID]bool{}
+	for i, raw := range newItems {
+		item, present, err := decodeComponent(comp, raw)
+		if err != nil {
+			return err
+		}
+		id := repository.ComponentID(item)
+		old, exists := oldByID[id]
+		if !exists || kept[id] {
+			// new item (or a copy of an existing one): insert with a fresh ID
+			setComponentField(item, "ID", uuid.Nil)
+			if err := checkRequired(comp, present, nil); err != nil {
+				return err
+			}
+			if _, ok := present["sort_order"]; !ok {
+				reflect.ValueOf(item).Elem().FieldByName("SortOrder").SetInt(int64(i))
+				present["sort_order"] = json.RawMessage("0")
+			}
+			if err := createComponent(ctx, r, comp, item, parentID, present); err != nil {
+				return err
+			}
+			newOrder = append(newOrder, repository.ComponentID(item))
+			continue
+		}
+
+		kept[id] = true
+		newOrder = append(newOrder, id)
+		var changed []string
+		for _, f := range comp.Fields {
+			if !jsonEqual(old[f], present[f]) {
+				changed = append(changed, f)
+			}
+		}
+		if err := checkRequired(comp, present, changed); err != nil {
+			return err
+		}
+		if len(changed) > 0 {
+			if err := r.Components.Update(ctx, comp, id, item, changed); err != nil {
+				return err
+			}
+		}
+		if comp.Children != nil {
+			if err := syncComponent(ctx, r, comp.Children, id, old[comp.Children.JSONKey], present[comp.Children.JSONKey]); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, id := range oldOrder {
+		if !kept[id] {
+			if err := r.Components.Delete(ctx, comp, id); err != nil {
+				return err
+			}
+		}
+	}
+
+	// items were moved or inserted: the array order becomes the sort order
+	if !sameOrder(oldOrder, newOrder, kept) {
+		return r.Components.SetOrder(ctx, comp, newOrder)
+	}
+	return nil
+}
+
+// sameOrder reports whether the kept items appear in the same relative order and nothing was added
+func sameOrder(oldOrder, newOrder []uuid.UUID, kept map[uuid.UUID]bool) bool {
+	var survivors []uuid.UUID
+	for _, id := range oldOrder {
+		if kept[id] {
+			survivors = append(survivors, id)
+		}
+	}
+	if len(survivors) != len(newOrder) {
+		return false
+	}
+	for i := range survivors {
+		if survivors[i] != newOrder[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func syncSingle(ctx context.Context, r repository.Repositories, comp *repository.LessonComponent, lessonID uuid.UUID, before, after json.RawMessage) error {
+	hadBefore, hasAfter := !jsonEqual(before, nil), !jsonEqual(after, nil)
+	switch {
+	case hadBefore && !hasAfter:
+		existing, err := r.Components.Get(ctx, comp, lessonID, uuid.Nil)
+		if err != nil {
+			return err
+		}
+		return r.Components.Delete(ctx, comp, repository.ComponentID(existing))
+	case !hadBefore && hasAfter:
+		item, present, err := decodeComponent(comp, after)
+		if err != nil {
+			return err
+		}
+		setComponentField(item, "ID", uuid.Nil)
+		return createComponent(ctx, r, comp, item, lessonID, present)
+	default:
+		var old map[string]json.RawMessage
+		if err := json.Unmarshal(before, &old); err != nil {
+			return err
+		}
+		item, present, err := decodeComponent(comp, after)
+		if err != nil {
+			return err
+		}
+		var changed []string
+		for _, f := range comp.Fields {
+			if !jsonEqual(old[f], present[f]) {
+				changed = append(changed, f)
+			}
+		}
+		if len(changed) == 0 {
+			return nil
+		}
+		existing, err := r.Components.Get(ctx, comp, lessonID, uuid.Nil)
+		if err != nil {
+			return err
+		}
+		return r.Components.Update(ctx, comp, repository.ComponentID(existing), item, changed)
+	}
+}
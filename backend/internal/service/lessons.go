@@ -0,0 +1,489 @@
+package service
+
+import (
+	"context"
+	"courseai/backend/internal/models"
+	"courseai/backend/internal/realtime"
+	"courseai/backend/internal/repository"
+	"courseai/backend/internal/telemetry"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// LessonService runs the lesson operations: loading, writing a lesson with
+// all of its components, editing single components, JSON Patch, ordering and
+// moving lessons, the review workflow and moving lessons to the trash. Every
+// write is announced to the lesson's editors.
+type LessonService struct {
+	uow         repository.UnitOfWork
+	assignments *AssignmentService
+}
+
+func NewLessonService(uow repository.UnitOfWork, assignments *AssignmentService) *LessonService {
+	return &LessonService{uow: uow, assignments: assignments}
+}
+
+// Get returns the lesson with every component, as the editor loads it
+func (s *LessonService) Get(ctx context.Context, id uuid.UUID) (*models.Lesson, error) {
+	// one child span per preloaded relation comes from the GORM plugin
+	ctx, span := telemetry.Start(ctx, "lessons.Get", trace.WithAttributes(attribute.String("lesson.id", id.String())))
+	defer span.End()
+	lesson, err := s.uow.Repositories().Lessons.GetTree(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, notFound("Lesson not found")
+	}
+	return lesson, err
+}
+
+// Create adds a lesson with all of its components to a subcourse. An actor
+// limited to one assigned subcourse always creates lessons there. With a
+// template of the subcourse's program, the parts of lesson left empty are
+// filled from its blueprint. A taken slug gets a numeric suffix.
+func (s *LessonService) Create(ctx context.Context, actor Actor, lesson *models.Lesson, templateID *uuid.UUID) (*models.Lesson, error) {
+	if err := requirePublish(actor, lesson.Status); err != nil {
+		return nil, err
+	}
+	repos := s.uow.Repositories()
+	subcourse, err := repos.Subcourses.Get(ctx, lesson.SubcourseID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, invalidf("Subcourse not found")
+		}
+		return nil, err
+	}
+	if !actor.ScopeAll {
+		if subcourse, err = s.assignedSubcourse(ctx, actor, subcourse); err != nil {
+			return nil, err
+		}
+		lesson.SubcourseID = subcourse.ID
+	}
+	if err := requireOwned(ctx, subcourse.OrganizationID); err != nil {
+		return nil, err
+	}
+	lesson.OrganizationID = subcourse.OrganizationID
+
+	if templateID != nil {
+		if err := s.applyTemplate(ctx, lesson, subcourse, *templateID); err != nil {
+			return nil, err
+		}
+	}
+
+	base := lesson.Slug
+	if base == "" {
+		base = "lesson"
+	}
+	for suffix := 1; suffix <= 100; suffix++ {
+		taken, err := repos.Lessons.SlugInUse(ctx, lesson.Slug, uuid.Nil)
+		if err != nil {
+			return nil, err
+		}
+		if !taken {
+			break
+		}
+		lesson.Slug = fmt.Sprintf("%s-%d", base, suffix)
+	}
+
+	lesson.AuthorID = &actor.UserID
+	// clients may pre-assign component IDs, e.g. to upload media before saving
+	resetTreeIDs(lesson, true)
+	err = s.uow.Do(ctx, func(r repository.Repositories) error {
+		if err := r.Lessons.Create(ctx, lesson); err != nil {
+			return err
+		}
+		return r.Lessons.RefreshCounters(ctx, nil, lesson.ID)
+	})
+	if err != nil {
+		return nil, slugError(err)
+	}
+	return repos.Lessons.GetTree(ctx, lesson.ID)
+}
+
+// assignedSubcourse returns the subcourse an assignment-scoped actor creates
+// a lesson in: the requested one if its program or the subcourse itself is
+// assigned, or the only assigned subcourse whatever was requested
+func (s *LessonService) assignedSubcourse(ctx context.Context, actor Actor, requested *models.Subcourse) (*models.Subcourse, error) {
+	assigned, err := s.assignments.Assigned(ctx, actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(assigned.ProgramIDs) > 0:
+		if !containsID(assigned.ProgramIDs, requested.ProgramID) {
+			return nil, forbidden("Access to subcourse denied")
+		}
+		return requested, nil
+	case len(assigned.SubcourseIDs) == 1:
+		subcourse, err := s.uow.Repositories().Subcourses.Get(ctx, assigned.SubcourseIDs[0])
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return nil, invalidf("Assigned subcourse not found")
+			}
+			return nil, err
+		}
+		return subcourse, nil
+	case len(assigned.SubcourseIDs) > 1:
+		if !containsID(assigned.SubcourseIDs, requested.ID) {
+			return nil, forbidden("Access to subcourse denied")
+		}
+		return requested, nil
+	}
+	return nil, forbidden("No active assignments")
+}
+
+// applyTemplate fills the parts of lesson left empty from the blueprint of a
+// template of the subcourse's program
+func (s *LessonService) applyTemplate(ctx context.Context, lesson *models.Lesson, subcourse *models.Subcourse, templateID uuid.UUID) error {
+	repos := s.uow.Repositories()
+	template, err := repos.Templates.Get(ctx, templateID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return invalidf("Template not found")
+		}
+		return err
+	}
+	if template.ProgramID != subcourse.ProgramID {
+		return invalidf("Template belongs to another program")
+	}
+	program, err := repos.Programs.Get(ctx, subcourse.ProgramID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return invalidf("Template not found")
+		}
+		return err
+	}
+	bp, err := renderBlueprint(template.Blueprint, lesson.Title, subcourse.Name, program.Name)
+	if err != nil {
+		return fmt.Errorf("render template %s: %w", template.ID, err)
+	}
+	applyBlueprint(lesson, bp)
+	return nil
+}
+
+// Update writes the fields set in updates and replaces every component and
+// media item of the lesson with the ones in updates. Moving to another
+// subcourse also moves the lesson into that subcourse's organization;
+// assignment-scoped actors cannot move lessons.
+func (s *LessonService) Update(ctx context.Context, actor Actor, id uuid.UUID, updates *models.Lesson, check Precondition) (*models.Lesson, error) {
+	existing, err := s.writable(ctx, actor, id, check)
+	if err != nil {
+		return nil, err
+	}
+	if updates.Slug != "" && updates.Slug != existing.Slug {
+		if err := s.checkSlug(ctx, updates.Slug, id); err != nil {
+			return nil, err
+		}
+	}
+	if updates.Status != existing.Status {
+		if err := requirePublish(actor, updates.Status); err != nil {
+			return nil, err
+		}
+	}
+	updates.OrganizationID = nil
+	if updates.SubcourseID != uuid.Nil && updates.SubcourseID != existing.SubcourseID {
+		target, err := s.uow.Repositories().Subcourses.Get(ctx, updates.SubcourseID)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return nil, invalidf("Subcourse not found")
+			}
+			return nil, err
+		}
+		if err := requireOwned(ctx, target.OrganizationID); err != nil {
+			return nil, err
+		}
+		if !actor.ScopeAll {
+			return nil, forbidden("Teachers cannot change lesson subcourse")
+		}
+		updates.OrganizationID = target.OrganizationID
+	}
+
+	updates.ID = id
+	updates.Version = 0
+	// keep the original author, but set one if there was none
+	if existing.AuthorID == nil {
+		updates.AuthorID = &actor.UserID
+	}
+	resetTreeIDs(updates, false)
+	err = s.uow.Do(ctx, func(r repository.Repositories) error {
+		if err := r.Lessons.BumpVersion(ctx, id, existing.Version); err != nil {
+			return err
+		}
+		if err := r.Lessons.Update(ctx, id, updates); err != nil {
+			return err
+		}
+		if err := r.Lessons.ReplaceTree(ctx, updates); err != nil {
+			return err
+		}
+		// a move changes the counters of both subcourses
+		return r.Lessons.RefreshCounters(ctx, []uuid.UUID{existing.SubcourseID}, id)
+	})
+	if err != nil {
+		return nil, slugError(err)
+	}
+	s.announceSaved(ctx, actor, "", id)
+	return s.Get(ctx, id)
+}
+
+// resetTreeIDs clears the IDs of the media and quizzes of lesson so they are
+// inserted as new rows. Objectives always keep theirs; the other components
+// keep theirs only with keepComponents.
+func resetTreeIDs(lesson *models.Lesson, keepComponents bool) {
+	resetMedia := func(media []models.Media) {
+		for i := range media {
+			media[i].ID = uuid.Nil
+		}
+	}
+	resetMedia(lesson.Media)
+	for i := range lesson.Models {
+		if !keepComponents {
+			lesson.Models[i].ID = uuid.Nil
+		}
+		resetMedia(lesson.Models[i].Media)
+	}
+	if p := lesson.Preparation; p != nil {
+		if !keepComponents {
+			p.ID = uuid.Nil
+		}
+		resetMedia(p.Media)
+	}
+	for i := range lesson.Builds {
+		if !keepComponents {
+			lesson.Builds[i].ID = uuid.Nil
+		}
+		resetMedia(lesson.Builds[i].Media)
+	}
+	for i := range lesson.ContentBlocks {
+		if !keepComponents {
+			lesson.ContentBlocks[i].ID = uuid.Nil
+		}
+		resetMedia(lesson.ContentBlocks[i].Media)
+	}
+	for i := range lesson.Attachments {
+		if !keepComponents {
+			lesson.Attachments[i].ID = uuid.Nil
+		}
+		resetMedia(lesson.Attachments[i].Media)
+	}
+	for i := range lesson.Challenges {
+		if !keepComponents {
+			lesson.Challenges[i].ID = uuid.Nil
+		}
+		resetMedia(lesson.Challenges[i].Media)
+	}
+	for i := range lesson.Quizzes {
+		lesson.Quizzes[i].ID = uuid.Nil
+		for j := range lesson.Quizzes[i].Options {
+			lesson.Quizzes[i].Options[j].ID = uuid.Nil
+		}
+	}
+}
+
+// Reorder puts the lessons of a subcourse in the order of ids, which must
+// list every live lesson of the subcourse exactly once
+func (s *LessonService) Reorder(ctx context.Context, actor Actor, subcourseID uuid.UUID, ids []uuid.UUID) ([]models.Lesson, error) {
+	if err := s.assignments.CanAccessSubcourse(ctx, actor, subcourseID); err != nil {
+		return nil, err
+	}
+	repos := s.uow.Repositories()
+	subcourse, err := repos.Subcourses.Get(ctx, subcourseID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, notFound("Subcourse not found")
+		}
+		return nil, err
+	}
+	if err := requireOwned(ctx, subcourse.OrganizationID); err != nil {
+		return nil, err
+	}
+	existing, err := repos.Lessons.OrderIDs(ctx, subcourseID)
+	if err != nil {
+		return nil, err
+	}
+	if !sameIDSet(existing, ids) {
+		return nil, invalidf("ids must list every lesson of the subcourse exactly once")
+	}
+
+	var changed []uuid.UUID
+	err = s.uow.Do(ctx, func(r repository.Repositories) error {
+		var err error
+		changed, err = r.Lessons.SetOrder(ctx, ids)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.announceSaved(ctx, actor, "", changed...)
+	return repos.Lessons.ListBySubcourse(ctx, subcourseID)
+}
+
+// Move puts the lesson at position among the lessons of another subcourse,
+// or of its own when subcourseID is nil; position defaults to the end. The
+// lessons left behind are renumbered.
+func (s *LessonService) Move(ctx context.Context, actor Actor, id, subcourseID uuid.UUID, position *int, check Precondition) (*models.Lesson, error) {
+	lesson, err := s.writable(ctx, actor, id, check)
+	if err != nil {
+		return nil, err
+	}
+	from := lesson.SubcourseID
+	to := from
+	if subcourseID != uuid.Nil {
+		to = subcourseID
+	}
+	target, err := s.uow.Repositories().Subcourses.Get(ctx, to)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, invalidf("Subcourse not found")
+		}
+		return nil, err
+	}
+	if to != from {
+		if err := s.assignments.CanAccessSubcourse(ctx, actor, to); err != nil {
+			return nil, err
+		}
+	}
+	if err := requireOwned(ctx, target.OrganizationID); err != nil {
+		return nil, err
+	}
+
+	var changed []uuid.UUID
+	err = s.uow.Do(ctx, func(r repository.Repositories) error {
+		if err := r.Lessons.BumpVersion(ctx, id, lesson.Version); err != nil {
+			return err
+		}
+		if to != from {
+			if err := r.Lessons.Move(ctx, id, to, target.OrganizationID); err != nil {
+				return err
+			}
+			siblings, err := r.Lessons.OrderIDs(ctx, from)
+			if err != nil {
+				return err
+			}
+			if changed, err = r.Lessons.SetOrder(ctx, siblings); err != nil {
+				return err
+			}
+		}
+		siblings, err := r.Lessons.OrderIDs(ctx, to)
+		if err != nil {
+			return err
+		}
+		moved, err := r.Lessons.SetOrder(ctx, insertAt(siblings, id, position))
+		if err != nil {
+			return err
+		}
+		changed = append(changed, moved...)
+		return r.Lessons.RefreshCounters(ctx, []uuid.UUID{from}, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.announceSaved(ctx, actor, "", append(changed, id)...)
+	return s.Get(ctx, id)
+}
+
+// announceSaved pushes the new version of each lesson to the editors in its
+// room; component names the part that was saved, empty for the whole lesson
+func (s *LessonService) announceSaved(ctx context.Context, actor Actor, component string, ids ...uuid.UUID) {
+	for _, id := range ids {
+		lesson, err := s.uow.Repositories().Lessons.Get(ctx, id)
+		if err != nil {
+			continue
+		}
+		realtime.NotifySaved(id, actor.UserID, actor.Username, component, lesson.Version)
+	}
+}
+
+// requirePublish rejects publishing a lesson by an actor without the permission
+func requirePublish(actor Actor, status models.ContentStatus) error {
+	if status == models.StatusPublished && !actor.Publish {
+		return forbidden("Publishing lessons requires lesson.publish permission")
+	}
+	return nil
+}
+
+func (s *LessonService) checkSlug(ctx context.Context, slug string, exceptID uuid.UUID) error {
+	taken, err := s.uow.Repositories().Lessons.SlugInUse(ctx, slug, exceptID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrSlugInUse
+	}
+	return nil
+}
+
+// writable loads a lesson the actor is about to change and checks that they
+// may, and that it is still at the version check expects
+func (s *LessonService) writable(ctx context.Context, actor Actor, id uuid.UUID, check Precondition) (*models.Lesson, error) {
+	if err := s.assignments.CanAccessLesson(ctx, actor, id); err != nil {
+		return nil, err
+	}
+	lesson, err := s.uow.Repositories().Lessons.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, notFound("Lesson not found")
+		}
+		return nil, err
+	}
+	if err := requireOwned(ctx, lesson.OrganizationID); err != nil {
+		return nil, err
+	}
+	if err := checkPrecondition(check, lesson.Version); err != nil {
+		return nil, err
+	}
+	return lesson, nil
+}
+
+// Delete moves the lesson to the trash. Its components and media stay in
+// place so a restore brings everything back; the purge job removes them.
+func (s *LessonService) Delete(ctx context.Context, actor Actor, id uuid.UUID, check Precondition) error {
+	lesson, err := s.writable(ctx, actor, id, check)
+	if err != nil {
+		return err
+	}
+	// truncated to what Postgres stores, so rows trashed together match on restore
+	at := time.Now().UTC().Truncate(time.Microsecond)
+	err = s.uow.Do(ctx, func(r repository.Repositories) error {
+		// lock the lesson at the version that was checked
+		if err := r.Lessons.BumpVersion(ctx, id, lesson.Version); err != nil {
+			return err
+		}
+		if err := r.Lessons.SoftDelete(ctx, id, actor.UserID, at); err != nil {
+			return err
+		}
+		return r.Lessons.RefreshCounters(ctx, nil, id)
+	})
+	if err != nil {
+		return err
+	}
+	realtime.NotifyDeleted(id, actor.UserID, actor.Username)
+	return nil
+}
+
+// SetStatus changes only the publication status of a lesson (review
+// workflow). The first publication stamps published_at.
+func (s *LessonService) SetStatus(ctx context.Context, actor Actor, id uuid.UUID, status models.ContentStatus, check Precondition) (*models.Lesson, error) {
+	lesson, err := s.writable(ctx, actor, id, check)
+	if err != nil {
+		return nil, err
+	}
+	var publishedAt *time.Time
+	if status == models.StatusPublished && lesson.PublishedAt == nil {
+		now := time.Now().UTC()
+		publishedAt = &now
+	}
+	err = s.uow.Do(ctx, func(r repository.Repositories) error {
+		if err := r.Lessons.SetStatus(ctx, id, lesson.Version, status, publishedAt); err != nil {
+			return err
+		}
+		return r.Lessons.RefreshCounters(ctx, nil, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.announceSaved(ctx, actor, "", id)
+	return s.uow.Repositories().Lessons.Get(ctx, id)
+}
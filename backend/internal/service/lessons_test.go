@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"courseai/backend/internal/database"
+	"courseai/backend/internal/models"
+	"testing"
+)
+
+func newLessonService(store *fakeStore) *LessonService {
+	return NewLessonService(store, NewAssignmentService(store))
+}
+
+func TestLessonCreatePutsTeachersIntoTheirOnlySubcourse(t *testing.T) {
+	store := newFakeStore()
+	org := store.addOrganization("acme")
+	program := store.addProgram("go", org)
+	assigned := store.addSubcourse("basics", program)
+	other := store.addSubcourse("advanced", program)
+	teacher := store.addUser("teacher", models.RoleTeacher, org)
+	store.assign(teacher, models.ScopeSubcourse, assigned.ID)
+	lessons := newLessonService(store)
+	ctx := database.WithTenant(context.Background(), org.ID)
+
+	lesson, err := lessons.Create(ctx, Actor{UserID: teacher.ID}, &models.Lesson{SubcourseID: other.ID, Title: "Loops", Slug: "loops"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lesson.SubcourseID != assigned.ID {
+		t.Fatalf("lesson created in %s, want the assigned subcourse %s", lesson.SubcourseID, assigned.ID)
+	}
+	if lesson.AuthorID == nil || *lesson.AuthorID != teacher.ID {
+		t.Fatalf("author = %v, want the teacher", lesson.AuthorID)
+	}
+
+	// with a second assignment the requested subcourse must be one of them
+	store.assign(teacher, models.ScopeSubcourse, store.addSubcourse("testing", program).ID)
+	if _, err := lessons.Create(ctx, Actor{UserID: teacher.ID}, &models.Lesson{SubcourseID: other.ID, Title: "Maps", Slug: "maps"}, nil); !IsKind(err, KindForbidden) {
+		t.Fatalf("create in an unassigned subcourse: err = %v, want KindForbidden", err)
+	}
+}
+
+func TestLessonCreateAppliesTheTemplate(t *testing.T) {
+	store := newFakeStore()
+	org := store.addOrganization("acme")
+	program := store.addProgram("go", org)
+	subcourse := store.addSubcourse("basics", program)
+	template := store.addTemplate(program, `{"subtitle": "{{title}} in {{subcourse}}", "overview": "Part of {{program}}"}`)
+	lessons := newLessonService(store)
+	ctx := database.WithTenant(context.Background(), org.ID)
+
+	lesson, err := lessons.Create(ctx, System, &models.Lesson{SubcourseID: subcourse.ID, Title: "Loops", Slug: "loops", Overview: "Kept"}, &template.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lesson.Subtitle != "Loops in basics" || lesson.Overview != "Kept" {
+		t.Fatalf("subtitle %q, overview %q; want the template's subtitle and the client's overview", lesson.Subtitle, lesson.Overview)
+	}
+
+	elsewhere := store.addTemplate(store.addProgram("rust", org), `{}`)
+	if _, err := lessons.Create(ctx, System, &models.Lesson{SubcourseID: subcourse.ID, Title: "Maps", Slug: "maps"}, &elsewhere.ID); !IsKind(err, KindInvalid) {
+		t.Fatalf("template of another program: err = %v, want KindInvalid", err)
+	}
+}
+
+func TestLessonCreatePublishRequiresPermission(t *testing.T) {
+	store := newFakeStore()
+	org := store.addOrganization("acme")
+	program := store.addProgram("go", org)
+	subcourse := store.addSubcourse("basics", program)
+	lessons := newLessonService(store)
+	ctx := database.WithTenant(context.Background(), org.ID)
+
+	editor := Actor{UserID: store.addUser("editor", models.RoleAdmin, org).ID, ScopeAll: true}
+	if _, err := lessons.Create(ctx, editor, &models.Lesson{SubcourseID: subcourse.ID, Slug: "loops", Status: models.StatusPublished}, nil); !IsKind(err, KindForbidden) {
+		t.Fatalf("publish without permission: err = %v, want KindForbidden", err)
+	}
+	editor.Publish = true
+	if _, err := lessons.Create(ctx, editor, &models.Lesson{SubcourseID: subcourse.ID, Slug: "loops", Status: models.StatusPublished}, nil); err != nil {
+		t.Fatalf("publish with permission: %v", err)
+	}
+}
@@ -0,0 +1,289 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"courseai/backend/internal/metrics"
+	"courseai/backend/internal/models"
+	"courseai/backend/internal/repository"
+	"courseai/backend/internal/telemetry"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var allowedMimePrefixes = []string{"image/", "video/", "audio/"}
+var allowedMimeExact = []string{"application/pdf", "text/plain"}
+
+// MediaService stores uploaded files and keeps the media rows of lessons and
+// their components in order
+type MediaService struct {
+	uow         repository.UnitOfWork
+	assignments *AssignmentService
+	// dir is where files are written; they are served under /uploads
+	dir string
+	// maxFileSize is the largest single file an upload may contain
+	maxFileSize int64
+}
+
+func NewMediaService(uow repository.UnitOfWork, assignments *AssignmentService, dir string, maxFileSize int64) *MediaService {
+	return &MediaService{uow: uow, assignments: assignments, dir: dir, maxFileSize: maxFileSize}
+}
+
+// UploadFile is one file of an upload
+type UploadFile struct {
+	Name    string
+	Content io.Reader
+}
+
+// Upload adds files as media of one owner
+type Upload struct {
+	OwnerType models.MediaOwnerType
+	OwnerID   uuid.UUID
+	Purpose   models.MediaPurpose
+	Files     []UploadFile
+}
+
+// ownerLesson resolves a media owner to its lesson and checks that the actor
+// may change it
+func (s *MediaService) ownerLesson(ctx context.Context, actor Actor, r repository.Repositories, ownerType models.MediaOwnerType, ownerID uuid.UUID) (*models.Lesson, error) {
+	if ownerType == models.OwnerProgram || ownerType == models.OwnerSubcourse || !containsOwnerType(ownerType) {
+		return nil, invalidf("invalid owner_type")
+	}
+	lessonID, err := r.Media.OwnerLesson(ctx, ownerType, ownerID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, invalidf("owner_id not found for owner_type %s", ownerType)
+		}
+		return nil, err
+	}
+	if err := s.assignments.CanAccessLesson(ctx, actor, lessonID); err != nil {
+		return nil, err
+	}
+	lesson, err := r.Lessons.Get(ctx, lessonID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, invalidf("owner_id not found for owner_type %s", ownerType)
+		}
+		return nil, err
+	}
+	if err := requireOwned(ctx, lesson.OrganizationID); err != nil {
+		return nil, err
+	}
+	return lesson, nil
+}
+
+// Upload saves the files and creates one media row for each. Nothing is kept
+// unless every file is accepted.
+func (s *MediaService) Upload(ctx context.Context, actor Actor, upload Upload) ([]models.Media, error) {
+	ctx, span := telemetry.Start(ctx, "media.Upload", trace.WithAttributes(
+		attribute.String("media.owner_type", string(upload.OwnerType)),
+		attribute.Int("media.files", len(upload.Files)),
+	))
+	defer span.End()
+
+	if len(upload.Files) == 0 {
+		return nil, invalidf("file is required")
+	}
+	lesson, err := s.ownerLesson(ctx, actor, s.uow.Repositories(), upload.OwnerType, upload.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+
+	ownerDir := filepath.Join(s.dir, string(upload.OwnerType))
+	if err := os.MkdirAll(ownerDir, 0755); err != nil {
+		return nil, err
+	}
+	var saved []string
+	discard := func() {
+		for _, path := range saved {
+			_ = os.Remove(path)
+		}
+	}
+
+	media := make([]models.Media, 0, len(upload.Files))
+	sizes := make([]int64, 0, len(upload.Files))
+	for _, file := range upload.Files {
+		// detect the type from the content, not the name
+		content := bufio.NewReaderSize(file.Content, 512)
+		head, _ := content.Peek(512)
+		contentType := http.DetectContentType(head)
+		if !isAllowedMime(contentType) {
+			discard()
+			return nil, invalidf("file type not allowed: %s", contentType)
+		}
+
+		name := fmt.Sprintf("%s_%d%s", uuid.New().String(), time.Now().UnixNano(), filepath.Ext(file.Name))
+		path := filepath.Join(ownerDir, name)
+		saved = append(saved, path)
+		_, write := telemetry.Start(ctx, "storage.write", trace.WithAttributes(
+			attribute.String("storage.path", path),
+			attribute.String("media.mime", contentType),
+		))
+		size, err := writeFile(path, content, s.maxFileSize)
+		write.SetAttributes(attribute.Int64("storage.bytes", size))
+		telemetry.RecordError(write, err)
+		write.End()
+		if err != nil {
+			discard()
+			if errors.Is(err, errFileTooLarge) {
+				return nil, &Error{Kind: KindTooLarge, Message: "file too large"}
+			}
+			return nil, fmt.Errorf("failed to save file: %w", err)
+		}
+
+		sizes = append(sizes, size)
+		meta, _ := json.Marshal(map[string]interface{}{"original_name": file.Name, "size": size})
+		media = append(media, models.Media{
+			OwnerType: upload.OwnerType,
+			OwnerID:   upload.OwnerID,
+			URL:       fmt.Sprintf("/uploads/%s/%s", upload.OwnerType, name),
+			MimeType:  contentType,
+			Purpose:   upload.Purpose,
+			Meta:      meta,
+		})
+	}
+
+	err = s.uow.Do(ctx, func(r repository.Repositories) error {
+		for i := range media {
+			if err := r.Media.Create(ctx, &media[i]); err != nil {
+				return fmt.Errorf("failed to create media record: %w", err)
+			}
+		}
+		return r.Lessons.RefreshCounters(ctx, nil, lesson.ID)
+	})
+	if err != nil {
+		// no orphan files when the rows could not be written
+		discard()
+		return nil, err
+	}
+	for i := range media {
+		metrics.ObserveUpload(media[i].MimeType, sizes[i])
+	}
+	return media, nil
+}
+
+var errFileTooLarge = errors.New("file too large")
+
+// writeFile copies content to path, stopping once it exceeds maxSize
+func writeFile(path string, content io.Reader, maxSize int64) (int64, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	size, err := io.Copy(f, io.LimitReader(content, maxSize+1))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && size > maxSize {
+		err = errFileTooLarge
+	}
+	return size, err
+}
+
+// Reorder sets the order of every media item of one owner and returns the
+// lesson it belongs to with the reordered media
+func (s *MediaService) Reorder(ctx context.Context, actor Actor, ownerType models.MediaOwnerType, ownerID uuid.UUID, ids []uuid.UUID) (uuid.UUID, []models.Media, error) {
+	repos := s.uow.Repositories()
+	lesson, err := s.ownerLesson(ctx, actor, repos, ownerType, ownerID)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	existing, err := repos.Media.ListByOwner(ctx, ownerType, ownerID)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+	if !sameMedia(existing, ids) {
+		return uuid.Nil, nil, invalidf("ids must list every media item of the owner exactly once")
+	}
+
+	err = s.uow.Do(ctx, func(r repository.Repositories) error {
+		if err := r.Media.SetOrder(ctx, ids); err != nil {
+			return err
+		}
+		if err := r.Lessons.Touch(ctx, lesson.ID); err != nil {
+			return err
+		}
+		return r.Lessons.RefreshCounters(ctx, nil, lesson.ID)
+	})
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+	media, err := repos.Media.ListByOwner(ctx, ownerType, ownerID)
+	return lesson.ID, media, err
+}
+
+// sameMedia reports whether ids lists every item of media exactly once
+func sameMedia(media []models.Media, ids []uuid.UUID) bool {
+	if len(media) != len(ids) {
+		return false
+	}
+	seen := make(map[uuid.UUID]bool, len(media))
+	for _, m := range media {
+		seen[m.ID] = true
+	}
+	for _, id := range ids {
+		if !seen[id] {
+			return false
+		}
+		delete(seen, id)
+	}
+	return len(seen) == 0
+}
+
+func containsOwnerType(ownerType models.MediaOwnerType) bool {
+	for _, t := range models.MediaOwnerTypes {
+		if t == ownerType {
+			return true
+		}
+	}
+	return false
+}
+
+func isAllowedMime(ct string) bool {
+	for _, p := range allowedMimePrefixes {
+		if strings.HasPrefix(ct, p) {
+			return true
+		}
+	}
+	for _, e := range allowedMimeExact {
+		if ct == e {
+			return true
+		}
+	}
+	return false
+}
+
+// uploadFile maps a media URL to its file under dir; ok is false for
+// external URLs and for paths that would leave dir
+func uploadFile(dir, url string) (string, bool) {
+	const prefix = "/uploads/"
+	if !strings.HasPrefix(url, prefix) {
+		return "", false
+	}
+	rel := filepath.Clean(strings.TrimPrefix(url, prefix))
+	if rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(rel) {
+		return "", false
+	}
+	return filepath.Join(dir, rel), true
+}
+
+// uploadURL is the URL the file path under dir is served at
+func uploadURL(dir, path string) string {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		rel = filepath.Base(path)
+	}
+	return "/uploads/" + filepath.ToSlash(rel)
+}
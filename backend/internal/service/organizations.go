@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"courseai/backend/internal/models"
+	"courseai/backend/internal/repository"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// OrganizationService manages organizations and the programs they share with
+// each other
+type OrganizationService struct {
+	uow         repository.UnitOfWork
+	assignments *AssignmentService
+}
+
+func NewOrganizationService(uow repository.UnitOfWork, assignments *AssignmentService) *OrganizationService {
+	return &OrganizationService{uow: uow, assignments: assignments}
+}
+
+// List returns every organization by name
+func (s *OrganizationService) List(ctx context.Context) ([]models.Organization, error) {
+	return s.uow.Repositories().Organizations.List(ctx)
+}
+
+func (s *OrganizationService) Get(ctx context.Context, id uuid.UUID) (*models.Organization, error) {
+	org, err := s.uow.Repositories().Organizations.Get(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, notFound("Organization not found")
+	}
+	return org, err
+}
+
+// Resolve returns the organization whose ID or slug is ref
+func (s *OrganizationService) Resolve(ctx context.Context, ref string) (*models.Organization, error) {
+	org, err := s.uow.Repositories().Organizations.Find(ctx, ref)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, notFound("Organization not found")
+	}
+	return org, err
+}
+
+// Create adds an active organization
+func (s *OrganizationService) Create(ctx context.Context, name, slug string) (*models.Organization, error) {
+	if err := s.checkSlug(ctx, slug, uuid.Nil); err != nil {
+		return nil, err
+	}
+	org := models.Organization{Name: name, Slug: slug, Status: models.OrgStatusActive}
+	if err := s.uow.Repositories().Organizations.Create(ctx, &org); err != nil {
+		return nil, slugError(err)
+	}
+	return &org, nil
+}
+
+// Update writes the fields set in updates
+func (s *OrganizationService) Update(ctx context.Context, id uuid.UUID, updates models.Organization) (*models.Organization, error) {
+	org, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if updates.Slug != "" && updates.Slug != org.Slug {
+		if err := s.checkSlug(ctx, updates.Slug, id); err != nil {
+			return nil, err
+		}
+	}
+	updates.ID = uuid.Nil
+	if err := s.uow.Repositories().Organizations.Update(ctx, id, &updates); err != nil {
+		return nil, slugError(err)
+	}
+	return s.Get(ctx, id)
+}
+
+// Shares returns the organizations a program is shared with
+func (s *OrganizationService) Shares(ctx context.Context, actor Actor, programID uuid.UUID) ([]models.ProgramShare, error) {
+	if _, err := s.ownedProgram(ctx, actor, programID); err != nil {
+		return nil, err
+	}
+	return s.uow.Repositories().Shares.ListByProgram(ctx, programID)
+}
+
+// Share makes a program readable by the organization whose ID or slug is ref
+func (s *OrganizationService) Share(ctx context.Context, actor Actor, programID uuid.UUID, ref string) (*models.ProgramShare, error) {
+	program, err := s.ownedProgram(ctx, actor, programID)
+	if err != nil {
+		return nil, err
+	}
+	org, err := s.Resolve(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if program.OrganizationID != nil && *program.OrganizationID == org.ID {
+		return nil, invalidf("Program already belongs to this organization")
+	}
+	return s.uow.Repositories().Shares.Ensure(ctx, program.ID, org.ID)
+}
+
+// Unshare stops sharing a program with an organization
+func (s *OrganizationService) Unshare(ctx context.Context, actor Actor, programID, organizationID uuid.UUID) error {
+	if _, err := s.ownedProgram(ctx, actor, programID); err != nil {
+		return err
+	}
+	return s.uow.Repositories().Shares.Delete(ctx, programID, organizationID)
+}
+
+// ownedProgram loads a program the actor may work on and the context's
+// organization owns; only owners decide who it is shared with
+func (s *OrganizationService) ownedProgram(ctx context.Context, actor Actor, id uuid.UUID) (*models.Program, error) {
+	if err := s.assignments.CanAccessProgram(ctx, actor, id); err != nil {
+		return nil, err
+	}
+	program, err := s.uow.Repositories().Programs.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, notFound("Program not found")
+		}
+		return nil, err
+	}
+	if err := requireOwned(ctx, program.OrganizationID); err != nil {
+		return nil, err
+	}
+	return program, nil
+}
+
+func (s *OrganizationService) checkSlug(ctx context.Context, slug string, exceptID uuid.UUID) error {
+	taken, err := s.uow.Repositories().Organizations.SlugInUse(ctx, slug, exceptID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrSlugInUse
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"courseai/backend/internal/database"
+	"errors"
+	"testing"
+)
+
+func TestOrganizationCreateRejectsTakenSlug(t *testing.T) {
+	store := newFakeStore()
+	store.addOrganization("acme")
+	organizations := NewOrganizationService(store, NewAssignmentService(store))
+
+	if _, err := organizations.Create(context.Background(), "Acme", "acme"); !errors.Is(err, ErrSlugInUse) {
+		t.Fatalf("err = %v, want ErrSlugInUse", err)
+	}
+	org, err := organizations.Create(context.Background(), "Globex", "globex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := organizations.Resolve(context.Background(), org.ID.String()); err != nil {
+		t.Fatalf("resolve by id: %v", err)
+	}
+	if _, err := organizations.Resolve(context.Background(), "initech"); !IsKind(err, KindNotFound) {
+		t.Fatalf("resolve unknown slug: err = %v, want KindNotFound", err)
+	}
+}
+
+func TestOrganizationShareOnlyOwnPrograms(t *testing.T) {
+	store := newFakeStore()
+	org := store.addOrganization("acme")
+	partner := store.addOrganization("partner")
+	program := store.addProgram("go", org)
+	foreign := store.addProgram("foreign", partner)
+	organizations := NewOrganizationService(store, NewAssignmentService(store))
+	ctx := database.WithTenant(context.Background(), org.ID)
+
+	if _, err := organizations.Share(ctx, System, program.ID, "acme"); !IsKind(err, KindInvalid) {
+		t.Fatalf("share with the owner: err = %v, want KindInvalid", err)
+	}
+	if _, err := organizations.Share(ctx, System, foreign.ID, "acme"); !IsKind(err, KindForbidden) {
+		t.Fatalf("share a program of another organization: err = %v, want KindForbidden", err)
+	}
+
+	first, err := organizations.Share(ctx, System, program.ID, "partner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := organizations.Share(ctx, System, program.ID, partner.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ID != again.ID || len(store.shares) != 1 {
+		t.Fatalf("sharing twice stored %d shares, want 1", len(store.shares))
+	}
+}
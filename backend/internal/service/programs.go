@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"courseai/backend/internal/database"
+	"courseai/backend/internal/models"
+	"courseai/backend/internal/realtime"
+	"courseai/backend/internal/repository"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrSlugInUse is returned when a slug is taken by another record; slugs are
+// unique across all organizations
+var ErrSlugInUse = errors.New("slug in use")
+
+// ProgramService runs the program operations: listing, loading, writing and
+// moving programs to the trash together with their subcourses and lessons
+type ProgramService struct {
+	uow         repository.UnitOfWork
+	assignments *AssignmentService
+}
+
+func NewProgramService(uow repository.UnitOfWork, assignments *AssignmentService) *ProgramService {
+	return &ProgramService{uow: uow, assignments: assignments}
+}
+
+// List returns the programs the actor may see, optionally with one status
+func (s *ProgramService) List(ctx context.Context, actor Actor, status string) ([]models.Program, error) {
+	filter := repository.ProgramFilter{Status: status}
+	if !actor.ScopeAll {
+		ids, err := s.assignments.ProgramIDs(ctx, actor.UserID)
+		if err != nil {
+			return nil, err
+		}
+		filter.IDs = ids
+	}
+	if filter.IDs != nil && len(filter.IDs) == 0 {
+		return []models.Program{}, nil
+	}
+	programs, err := s.uow.Repositories().Programs.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	// JSON consumers expect an array
+	for i := range programs {
+		if programs[i].Subcourses == nil {
+			programs[i].Subcourses = make([]models.Subcourse, 0)
+		}
+	}
+	return programs, nil
+}
+
+// Get returns the program with its media and subcourses
+func (s *ProgramService) Get(ctx context.Context, actor Actor, id uuid.UUID) (*models.Program, error) {
+	if err := s.assignments.CanAccessProgram(ctx, actor, id); err != nil {
+		return nil, err
+	}
+	program, err := s.uow.Repositories().Programs.GetDetail(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, notFound("Program not found")
+	}
+	return program, err
+}
+
+// Create adds a program with its media. It belongs to the context's
+// organization; callers outside an organization must name one.
+func (s *ProgramService) Create(ctx context.Context, program *models.Program) (*models.Program, error) {
+	if tenantID, ok := database.TenantFromContext(ctx); ok {
+		program.OrganizationID = &tenantID
+	} else if program.OrganizationID == nil {
+		return nil, invalidf("organization_id is required")
+	}
+	if err := s.checkSlug(ctx, program.Slug, uuid.Nil); err != nil {
+		return nil, err
+	}
+	media := program.Media
+	err := s.uow.Do(ctx, func(r repository.Repositories) error {
+		if err := r.Programs.Create(ctx, program); err != nil {
+			return err
+		}
+		if len(media) > 0 {
+			if err := r.Media.Replace(ctx, models.OwnerProgram, program.ID, media); err != nil {
+				return err
+			}
+		}
+		return r.Programs.RefreshCounters(ctx, program.ID)
+	})
+	if err != nil {
+		return nil, slugError(err)
+	}
+	return s.uow.Repositories().Programs.GetDetail(ctx, program.ID)
+}
+
+// Update writes the fields set in updates; media are replaced when any are
+// given. Ownership and version cannot be changed here.
+func (s *ProgramService) Update(ctx context.Context, actor Actor, id uuid.UUID, updates *models.Program, check Precondition) (*models.Program, error) {
+	existing, err := s.writable(ctx, actor, id, check)
+	if err != nil {
+		return nil, err
+	}
+	if updates.Slug != "" && updates.Slug != existing.Slug {
+		if err := s.checkSlug(ctx, updates.Slug, id); err != nil {
+			return nil, err
+		}
+	}
+	updates.ID = id
+	updates.OrganizationID = nil
+	updates.Version = 0
+	err = s.uow.Do(ctx, func(r repository.Repositories) error {
+		if err := r.Programs.BumpVersion(ctx, id, existing.Version); err != nil {
+			return err
+		}
+		if err := r.Programs.Update(ctx, id, updates); err != nil {
+			return err
+		}
+		if len(updates.Media) > 0 {
+			if err := r.Media.Replace(ctx, models.OwnerProgram, id, updates.Media); err != nil {
+				return err
+			}
+		}
+		return r.Programs.RefreshCounters(ctx, id)
+	})
+	if err != nil {
+		return nil, slugError(err)
+	}
+	return s.uow.Repositories().Programs.GetDetail(ctx, id)
+}
+
+// Delete moves the program and its live subtree to the trash with one
+// timestamp, so restoring the program brings exactly that subtree back
+func (s *ProgramService) Delete(ctx context.Context, actor Actor, id uuid.UUID, check Precondition) error {
+	program, err := s.writable(ctx, actor, id, check)
+	if err != nil {
+		return err
+	}
+	// truncated to what Postgres stores, so rows trashed together match on restore
+	at := time.Now().UTC().Truncate(time.Microsecond)
+	var lessonIDs []uuid.UUID
+	err = s.uow.Do(ctx, func(r repository.Repositories) error {
+		var err error
+		if lessonIDs, err = r.Programs.Trash(ctx, id, program.Version, actor.UserID, at); err != nil {
+			return err
+		}
+		return r.Programs.RefreshCounters(ctx, id)
+	})
+	if err != nil {
+		return err
+	}
+	for _, lessonID := range lessonIDs {
+		realtime.NotifyDeleted(lessonID, actor.UserID, actor.Username)
+	}
+	return nil
+}
+
+// Reorder puts the programs of the context's organization in the order of
+// ids, which must list every live one exactly once. The list covers every
+// program, so only actors not limited to assignments may reorder it.
+func (s *ProgramService) Reorder(ctx context.Context, actor Actor, ids []uuid.UUID) ([]models.Program, error) {
+	tenantID, ok := database.TenantFromContext(ctx)
+	if !ok {
+		return nil, invalidf("Select an organization to reorder its programs")
+	}
+	if !actor.ScopeAll {
+		return nil, forbidden("Reordering programs requires access to all programs")
+	}
+	existing, err := s.uow.Repositories().Programs.OrderIDs(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if !sameIDSet(existing, ids) {
+		return nil, invalidf("ids must list every program exactly once")
+	}
+	err = s.uow.Do(ctx, func(r repository.Repositories) error {
+		_, err := r.Programs.SetOrder(ctx, ids)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.uow.Repositories().Programs.List(ctx, repository.ProgramFilter{IDs: ids})
+}
+
+// writable loads a program the actor is about to change and checks that they
+// may, and that it is still at the version check expects
+func (s *ProgramService) writable(ctx context.Context, actor Actor, id uuid.UUID, check Precondition) (*models.Program, error) {
+	if err := s.assignments.CanAccessProgram(ctx, actor, id); err != nil {
+		return nil, err
+	}
+	program, err := s.uow.Repositories().Programs.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, notFound("Program not found")
+		}
+		return nil, err
+	}
+	if err := requireOwned(ctx, program.OrganizationID); err != nil {
+		return nil, err
+	}
+	if err := checkPrecondition(check, program.Version); err != nil {
+		return nil, err
+	}
+	return program, nil
+}
+
+func (s *ProgramService) checkSlug(ctx context.Context, slug string, exceptID uuid.UUID) error {
+	taken, err := s.uow.Repositories().Programs.SlugInUse(ctx, slug, exceptID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrSlugInUse
+	}
+	return nil
+}
+
+// slugError reports a unique violation that slipped past the slug check as
+// ErrSlugInUse
+func slugError(err error) error {
+	if errors.Is(err, repository.ErrDuplicate) {
+		return ErrSlugInUse
+	}
+	return err
+}
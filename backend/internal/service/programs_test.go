@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"courseai/backend/internal/database"
+	"courseai/backend/internal/models"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func newProgramService(store *fakeStore) *ProgramService {
+	return NewProgramService(store, NewAssignmentService(store))
+}
+
+func TestProgramListScopesTeachersToTheirAssignments(t *testing.T) {
+	store := newFakeStore()
+	org := store.addOrganization("acme")
+	assigned := store.addProgram("assigned", org)
+	store.addProgram("other", org)
+	teacher := store.addUser("teacher", models.RoleTeacher, org)
+	store.assign(teacher, models.ScopeProgram, assigned.ID)
+	programs := newProgramService(store)
+
+	got, err := programs.List(context.Background(), Actor{UserID: teacher.ID}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].ID != assigned.ID {
+		t.Fatalf("teacher sees %v, want only the assigned program", got)
+	}
+
+	unassigned := store.addUser("unassigned", models.RoleTeacher, org)
+	got, err = programs.List(context.Background(), Actor{UserID: unassigned.ID}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || len(got) != 0 {
+		t.Fatalf("unassigned teacher sees %v, want an empty list", got)
+	}
+
+	got, err = programs.List(context.Background(), System, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("system sees %d programs, want 2", len(got))
+	}
+}
+
+func TestProgramCreateTakesTheContextOrganization(t *testing.T) {
+	store := newFakeStore()
+	org := store.addOrganization("acme")
+	other := uuid.New()
+	programs := newProgramService(store)
+
+	ctx := database.WithTenant(context.Background(), org.ID)
+	program, err := programs.Create(ctx, &models.Program{Name: "Go", Slug: "go", OrganizationID: &other})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if program.OrganizationID == nil || *program.OrganizationID != org.ID {
+		t.Fatalf("program belongs to %v, want %s", program.OrganizationID, org.ID)
+	}
+
+	_, err = programs.Create(context.Background(), &models.Program{Name: "Rust", Slug: "rust"})
+	if !IsKind(err, KindInvalid) {
+		t.Fatalf("create without organization: err = %v, want KindInvalid", err)
+	}
+}
+
+func TestProgramSlugsAreUnique(t *testing.T) {
+	store := newFakeStore()
+	org := store.addOrganization("acme")
+	store.addProgram("go", org)
+	rust := store.addProgram("rust", org)
+	programs := newProgramService(store)
+	ctx := database.WithTenant(context.Background(), org.ID)
+
+	if _, err := programs.Create(ctx, &models.Program{Name: "Go", Slug: "go"}); !errors.Is(err, ErrSlugInUse) {
+		t.Fatalf("create: err = %v, want ErrSlugInUse", err)
+	}
+	if _, err := programs.Update(ctx, System, rust.ID, &models.Program{Slug: "go"}, nil); !errors.Is(err, ErrSlugInUse) {
+		t.Fatalf("update: err = %v, want ErrSlugInUse", err)
+	}
+}
+
+func TestProgramUpdateChecksVersionAndOwnership(t *testing.T) {
+	store := newFakeStore()
+	org := store.addOrganization("acme")
+	sharer := store.addOrganization("sharer")
+	program := store.addProgram("go", org)
+	shared := store.addProgram("shared", sharer)
+	programs := newProgramService(store)
+	ctx := database.WithTenant(context.Background(), org.ID)
+
+	stale := func(version int) (bool, error) { return version == 0, nil }
+	if _, err := programs.Update(ctx, System, program.ID, &models.Program{Name: "Go 2"}, stale); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("stale update: err = %v, want ErrVersionConflict", err)
+	}
+
+	updated, err := programs.Update(ctx, System, program.ID, &models.Program{Name: "Go 2"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Name != "Go 2" || updated.Version != 2 {
+		t.Fatalf("updated = %q at version %d, want %q at version 2", updated.Name, updated.Version, "Go 2")
+	}
+
+	if _, err := programs.Update(ctx, System, shared.ID, &models.Program{Name: "Mine"}, nil); !IsKind(err, KindForbidden) {
+		t.Fatalf("update shared program: err = %v, want KindForbidden", err)
+	}
+
+	teacher := store.addUser("teacher", models.RoleTeacher, org)
+	if _, err := programs.Update(ctx, Actor{UserID: teacher.ID}, program.ID, &models.Program{Name: "Go 3"}, nil); !IsKind(err, KindForbidden) {
+		t.Fatalf("unassigned teacher update: err = %v, want KindForbidden", err)
+	}
+}
@@ -0,0 +1,162 @@
+// Package service holds the domain operations on content, assignments and
+// media. Services get their repositories from a repository.UnitOfWork, take
+// the caller as an Actor and a context carrying the organization, and know
+// nothing about HTTP, so the same operations run from handlers, CLI commands
+// and background jobs.
+package service
+
+import (
+	"context"
+	"courseai/backend/internal/database"
+	"courseai/backend/internal/repository"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Actor is who performs an operation
+type Actor struct {
+	UserID   uuid.UUID
+	Username string
+	// ScopeAll actors are not limited to the content they are assigned to
+	ScopeAll bool
+	// Publish actors may set a lesson's status to published
+	Publish bool
+}
+
+// System is the actor of CLI commands and background jobs
+var System = Actor{Username: "system", ScopeAll: true, Publish: true}
+
+// Precondition is checked against the current version of a record before it
+// is changed, e.g. an If-Match header; nil always passes
+type Precondition func(version int) (bool, error)
+
+// ErrVersionConflict is returned when a Precondition fails or the record
+// changed while it was being written
+var ErrVersionConflict = repository.ErrVersionConflict
+
+// Kind classifies an Error so callers can report it their own way
+type Kind int
+
+const (
+	KindInvalid Kind = iota + 1
+	KindNotFound
+	KindForbidden
+	KindTooLarge
+	// KindConflict is a write that clashes with the current state, e.g. a
+	// restore whose parent is still in the trash
+	KindConflict
+	// KindUnprocessable is a well-formed request whose result would be
+	// invalid, e.g. a JSON Patch producing a lesson that fails validation
+	KindUnprocessable
+)
+
+// Error is an expected failure with a message meant for the caller; any other
+// error a service returns is internal. Err, when set, holds the details, e.g.
+// the validation.Errors of every invalid field.
+type Error struct {
+	Kind    Kind
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+func invalidf(format string, args ...interface{}) error {
+	return &Error{Kind: KindInvalid, Message: fmt.Sprintf(format, args...)}
+}
+
+// rejected reports err, e.g. validation.Errors, as an Error of kind
+func rejected(kind Kind, err error) error {
+	return &Error{Kind: kind, Message: err.Error(), Err: err}
+}
+
+func notFound(message string) error {
+	return &Error{Kind: KindNotFound, Message: message}
+}
+
+func forbidden(message string) error {
+	return &Error{Kind: KindForbidden, Message: message}
+}
+
+// IsKind reports whether err is an Error of kind
+func IsKind(err error, kind Kind) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Kind == kind
+}
+
+// checkPrecondition runs check, if any, against version
+func checkPrecondition(check Precondition, version int) error {
+	if check == nil {
+		return nil
+	}
+	ok, err := check(version)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// requireOwned rejects writes to content another organization shared with the
+// context's organization; shared programs and their children are read-only
+func requireOwned(ctx context.Context, organizationID *uuid.UUID) error {
+	tenantID, ok := database.TenantFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	if organizationID == nil || *organizationID != tenantID {
+		return forbidden("Shared content is read-only")
+	}
+	return nil
+}
+
+func containsID(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
+
+// sameIDSet reports whether a and b hold the same IDs, each exactly once
+func sameIDSet(a, b []uuid.UUID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := make(map[uuid.UUID]bool, len(a))
+	for _, id := range a {
+		seen[id] = true
+	}
+	for _, id := range b {
+		if !seen[id] {
+			return false
+		}
+		delete(seen, id)
+	}
+	return len(seen) == 0
+}
+
+// insertAt places id at position in ids (without id), clamping position to the list
+func insertAt(ids []uuid.UUID, id uuid.UUID, position *int) []uuid.UUID {
+	out := make([]uuid.UUID, 0, len(ids)+1)
+	for _, other := range ids {
+		if other != id {
+			out = append(out, other)
+		}
+	}
+	at := len(out)
+	if position != nil && *position >= 0 && *position < at {
+		at = *position
+	}
+	out = append(out, uuid.Nil)
+	copy(out[at+1:], out[at:])
+	out[at] = id
+	return out
+}
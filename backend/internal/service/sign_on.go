@@ -0,0 +1,276 @@
+package service
+
+import (
+	"context"
+	"courseai/backend/internal/config"
+	"courseai/backend/internal/models"
+	"courseai/backend/internal/oidc"
+	"courseai/backend/internal/repository"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ssoRoleRank orders the roles SSO may grant; the highest mapped role wins.
+// super_admin is never granted through SSO.
+var ssoRoleRank = []models.UserRole{models.RoleAdmin, models.RoleTeacher, models.RoleReviewer, models.RoleViewer}
+
+var usernameSanitizer = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// SignOnService keeps the pending OpenID Connect flows and maps verified ID
+// tokens to local users: linked identities, accounts with the same verified
+// email, or users provisioned just in time
+type SignOnService struct {
+	uow repository.UnitOfWork
+	cfg config.OIDCConfig
+}
+
+func NewSignOnService(uow repository.UnitOfWork, cfg config.OIDCConfig) *SignOnService {
+	return &SignOnService{uow: uow, cfg: cfg}
+}
+
+// Begin stores a pending flow, and drops the ones that were abandoned
+func (s *SignOnService) Begin(ctx context.Context, pending *models.OIDCLoginState) error {
+	r := s.uow.Repositories()
+	if err := r.LoginStates.DeleteExpired(ctx, time.Now()); err != nil {
+		log.Printf("OIDC: failed to drop expired sign-ons: %v", err)
+	}
+	return r.LoginStates.Create(ctx, pending)
+}
+
+// Take consumes the pending flow of state; a state is single use
+func (s *SignOnService) Take(ctx context.Context, state string) (*models.OIDCLoginState, error) {
+	pending, err := s.uow.Repositories().LoginStates.Take(ctx, state)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, notFound("Sign-on session not found or already used")
+		}
+		return nil, err
+	}
+	if time.Now().After(pending.ExpiresAt) {
+		return nil, invalidf("Sign-on session expired")
+	}
+	return pending, nil
+}
+
+// Resolve finds the local user for an ID token: by linked identity, then by
+// verified email, otherwise provisions a new user just in time
+func (s *SignOnService) Resolve(ctx context.Context, tok *oidc.IDToken) (*models.User, error) {
+	r := s.uow.Repositories()
+	identity, err := r.Identities.Find(ctx, tok.Issuer, tok.Subject)
+	if err == nil {
+		user, err := r.Users.Get(ctx, identity.UserID)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return nil, invalidf("Linked account no longer exists")
+			}
+			return nil, err
+		}
+		s.syncRole(ctx, user, tok)
+		return user, nil
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("look up identity: %w", err)
+	}
+
+	// Link to an existing account only when the provider vouches for the email
+	if tok.Email != "" && tok.EmailVerified {
+		user, err := r.Users.FindByEmail(ctx, tok.Email)
+		if err == nil {
+			if err := r.Identities.Create(ctx, &models.UserIdentity{UserID: user.ID, Issuer: tok.Issuer, Subject: tok.Subject, Email: tok.Email}); err != nil {
+				return nil, fmt.Errorf("link identity: %w", err)
+			}
+			log.Printf("OIDC: linked %s to existing user %s by verified email", tok.Subject, user.Username)
+			s.syncRole(ctx, user, tok)
+			return user, nil
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return nil, err
+		}
+	}
+
+	return s.provision(ctx, tok)
+}
+
+// RecordLogin stamps the identity's last sign-in
+func (s *SignOnService) RecordLogin(ctx context.Context, tok *oidc.IDToken) error {
+	return s.uow.Repositories().Identities.RecordLogin(ctx, tok.Issuer, tok.Subject, tok.Email, time.Now())
+}
+
+// Link adds the identity of an ID token to a signed-in user
+func (s *SignOnService) Link(ctx context.Context, userID uuid.UUID, tok *oidc.IDToken) error {
+	r := s.uow.Repositories()
+	existing, err := r.Identities.Find(ctx, tok.Issuer, tok.Subject)
+	if err == nil {
+		if existing.UserID == userID {
+			return nil
+		}
+		return invalidf("This identity is already linked to another account")
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+	if err := r.Identities.Create(ctx, &models.UserIdentity{UserID: userID, Issuer: tok.Issuer, Subject: tok.Subject, Email: tok.Email}); err != nil {
+		return fmt.Errorf("link identity: %w", err)
+	}
+	return nil
+}
+
+// Identities returns the identities linked to the user
+func (s *SignOnService) Identities(ctx context.Context, userID uuid.UUID) ([]models.UserIdentity, error) {
+	return s.uow.Repositories().Identities.ListByUser(ctx, userID)
+}
+
+// Unlink removes one of the user's identities. Users who may only use SSO
+// keep at least one, so they cannot lock themselves out.
+func (s *SignOnService) Unlink(ctx context.Context, userID, identityID uuid.UUID) error {
+	r := s.uow.Repositories()
+	user, err := r.Users.Get(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return notFound("User not found")
+		}
+		return err
+	}
+	identity, err := r.Identities.GetForUser(ctx, identityID, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return notFound("Identity not found")
+		}
+		return err
+	}
+	if user.PasswordLoginDisabled {
+		count, err := r.Identities.CountByUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if count <= 1 {
+			return invalidf("Cannot unlink the only sign-in method for this account")
+		}
+	}
+	return r.Identities.Delete(ctx, identity.ID)
+}
+
+// provision creates a user for a first-time SSO login. Such users have no
+// usable password and can only sign in through SSO.
+func (s *SignOnService) provision(ctx context.Context, tok *oidc.IDToken) (*models.User, error) {
+	if tok.Email == "" {
+		return nil, invalidf("Identity provider did not supply an email address")
+	}
+
+	r := s.uow.Repositories()
+	var org *models.Organization
+	var err error
+	if s.cfg.Organization == models.DefaultOrganizationSlug {
+		org, err = r.Organizations.Default(ctx)
+	} else {
+		org, err = r.Organizations.Find(ctx, s.cfg.Organization)
+	}
+	if err != nil {
+		log.Printf("OIDC: provisioning organization %q: %v", s.cfg.Organization, err)
+		return nil, invalidf("Sign-on organization is not configured")
+	}
+
+	role, _ := s.mapRole(tok)
+	secret, err := oidc.RandomString(32)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	username, err := s.uniqueUsername(ctx, tok)
+	if err != nil {
+		return nil, err
+	}
+
+	user := models.User{
+		Username:              username,
+		Email:                 tok.Email,
+		PasswordHash:          string(hash),
+		Role:                  role,
+		OrganizationID:        &org.ID,
+		Status:                models.StatusActive,
+		PasswordLoginDisabled: true,
+	}
+	err = s.uow.Do(ctx, func(r repository.Repositories) error {
+		if err := r.Users.Create(ctx, &user); err != nil {
+			return err
+		}
+		return r.Identities.Create(ctx, &models.UserIdentity{UserID: user.ID, Issuer: tok.Issuer, Subject: tok.Subject, Email: tok.Email})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("provision %s: %w", tok.Email, err)
+	}
+	log.Printf("OIDC: provisioned user %s (%s) in organization %s", user.Username, role, org.Slug)
+	return &user, nil
+}
+
+// mapRole maps the configured role claim to a role; ok is false when no value matched
+func (s *SignOnService) mapRole(tok *oidc.IDToken) (models.UserRole, bool) {
+	mapped := map[models.UserRole]bool{}
+	for _, v := range tok.ClaimValues(s.cfg.RoleClaim) {
+		if r, ok := s.cfg.RoleMapping[v]; ok {
+			mapped[models.UserRole(r)] = true
+		}
+	}
+	for _, r := range ssoRoleRank {
+		if mapped[r] {
+			return r, true
+		}
+	}
+
+	def := models.UserRole(s.cfg.DefaultRole)
+	if !def.IsValid() || def == models.RoleSuperAdmin {
+		def = models.RoleViewer
+	}
+	return def, false
+}
+
+// syncRole re-applies the role mapping when enabled. Super-admins and users
+// without a matching claim value keep their role.
+func (s *SignOnService) syncRole(ctx context.Context, user *models.User, tok *oidc.IDToken) {
+	if !s.cfg.SyncRole || user.Role == models.RoleSuperAdmin {
+		return
+	}
+	role, ok := s.mapRole(tok)
+	if !ok || role == user.Role {
+		return
+	}
+	if err := s.uow.Repositories().Users.SetRole(ctx, user.ID, role, user.OrganizationID); err != nil {
+		log.Printf("OIDC: failed to sync role for %s: %v", user.Username, err)
+		return
+	}
+	log.Printf("OIDC: role of %s synced from %s to %s", user.Username, user.Role, role)
+	user.Role = role
+}
+
+func (s *SignOnService) uniqueUsername(ctx context.Context, tok *oidc.IDToken) (string, error) {
+	base := tok.PreferredUsername
+	if base == "" {
+		base, _, _ = strings.Cut(tok.Email, "@")
+	}
+	base = usernameSanitizer.ReplaceAllString(base, "")
+	if base == "" {
+		base = "user"
+	}
+
+	name := base
+	for i := 2; ; i++ {
+		taken, err := s.uow.Repositories().Users.UsernameInUse(ctx, name)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return name, nil
+		}
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+}
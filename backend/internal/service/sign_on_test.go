@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"courseai/backend/internal/config"
+	"courseai/backend/internal/models"
+	"courseai/backend/internal/oidc"
+	"testing"
+	"time"
+)
+
+func newSignOnService(store *fakeStore) *SignOnService {
+	return NewSignOnService(store, config.OIDCConfig{
+		Organization: "acme",
+		RoleClaim:    "groups",
+		RoleMapping:  map[string]string{"lms-admins": "admin", "lms-teachers": "teacher"},
+		DefaultRole:  "viewer",
+		SyncRole:     true,
+	})
+}
+
+func TestSignOnStateIsSingleUse(t *testing.T) {
+	store := newFakeStore()
+	signOn := newSignOnService(store)
+	ctx := context.Background()
+
+	if err := signOn.Begin(ctx, &models.OIDCLoginState{State: "s1", ExpiresAt: time.Now().Add(time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := signOn.Take(ctx, "s1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := signOn.Take(ctx, "s1"); !IsKind(err, KindNotFound) {
+		t.Fatalf("second take: err = %v, want KindNotFound", err)
+	}
+
+	store.loginStates["old"] = &models.OIDCLoginState{State: "old", ExpiresAt: time.Now().Add(-time.Second)}
+	if _, err := signOn.Take(ctx, "old"); !IsKind(err, KindInvalid) {
+		t.Fatalf("expired take: err = %v, want KindInvalid", err)
+	}
+}
+
+func TestSignOnLinksOnlyVerifiedEmails(t *testing.T) {
+	store := newFakeStore()
+	org := store.addOrganization("acme")
+	existing := store.addUser("ada", models.RoleTeacher, org)
+	signOn := newSignOnService(store)
+	ctx := context.Background()
+
+	unverified := &oidc.IDToken{Issuer: "https://idp", Subject: "u1", Email: existing.Email}
+	user, err := signOn.Resolve(ctx, unverified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID == existing.ID {
+		t.Fatal("unverified email was linked to the existing account")
+	}
+	if user.Username != "ada2" || !user.PasswordLoginDisabled || user.OrganizationID == nil || *user.OrganizationID != org.ID {
+		t.Fatalf("provisioned %+v, want ada2 in acme with password login disabled", user)
+	}
+
+	// emails are unique, so link against a store without the provisioned ada2
+	store = newFakeStore()
+	existing = store.addUser("ada", models.RoleTeacher, store.addOrganization("acme"))
+	signOn = newSignOnService(store)
+
+	verified := &oidc.IDToken{Issuer: "https://idp", Subject: "v1", Email: existing.Email, EmailVerified: true}
+	user, err = signOn.Resolve(ctx, verified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != existing.ID {
+		t.Fatalf("verified email resolved to %s, want %s", user.ID, existing.ID)
+	}
+	if _, err := store.Repositories().Identities.Find(ctx, "https://idp", "v1"); err != nil {
+		t.Fatalf("identity not linked: %v", err)
+	}
+}
+
+func TestSignOnSyncsMappedRole(t *testing.T) {
+	store := newFakeStore()
+	store.addOrganization("acme")
+	signOn := newSignOnService(store)
+	ctx := context.Background()
+
+	tok := &oidc.IDToken{Issuer: "https://idp", Subject: "s1", Email: "grace@example.com", Claims: map[string]interface{}{"groups": []interface{}{"lms-teachers"}}}
+	user, err := signOn.Resolve(ctx, tok)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Role != models.RoleTeacher {
+		t.Fatalf("provisioned as %s, want teacher", user.Role)
+	}
+
+	// the highest mapped role wins
+	tok.Claims = map[string]interface{}{"groups": []interface{}{"lms-teachers", "lms-admins"}}
+	user, err = signOn.Resolve(ctx, tok)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Role != models.RoleAdmin || store.users[user.ID].Role != models.RoleAdmin {
+		t.Fatalf("synced to %s, want admin", user.Role)
+	}
+
+	// no matching claim value keeps the role
+	tok.Claims = nil
+	user, err = signOn.Resolve(ctx, tok)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Role != models.RoleAdmin {
+		t.Fatalf("role without mapped claim = %s, want admin", user.Role)
+	}
+}
+
+func TestSignOnUnlinkKeepsTheOnlySignInMethod(t *testing.T) {
+	store := newFakeStore()
+	org := store.addOrganization("acme")
+	signOn := newSignOnService(store)
+	ctx := context.Background()
+
+	user, err := signOn.Resolve(ctx, &oidc.IDToken{Issuer: "https://idp", Subject: "s1", Email: "grace@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity := store.identities[0]
+	if err := signOn.Unlink(ctx, user.ID, identity.ID); !IsKind(err, KindInvalid) {
+		t.Fatalf("unlink only identity: err = %v, want KindInvalid", err)
+	}
+
+	other := store.addUser("ada", models.RoleTeacher, org)
+	if err := signOn.Unlink(ctx, other.ID, identity.ID); !IsKind(err, KindNotFound) {
+		t.Fatalf("unlink another user's identity: err = %v, want KindNotFound", err)
+	}
+
+	if err := signOn.Link(ctx, user.ID, &oidc.IDToken{Issuer: "https://other-idp", Subject: "s2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := signOn.Unlink(ctx, user.ID, identity.ID); err != nil {
+		t.Fatalf("unlink with a second identity: %v", err)
+	}
+	if err := signOn.Link(ctx, other.ID, &oidc.IDToken{Issuer: "https://other-idp", Subject: "s2"}); !IsKind(err, KindInvalid) {
+		t.Fatalf("link an identity of another account: err = %v, want KindInvalid", err)
+	}
+}
@@ -0,0 +1,305 @@
+package service
+
+import (
+	"context"
+	"courseai/backend/internal/models"
+	"courseai/backend/internal/realtime"
+	"courseai/backend/internal/repository"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SubcourseService runs the subcourse operations: listing, loading, writing,
+// moving between programs and moving subcourses to the trash with their
+// lessons
+type SubcourseService struct {
+	uow         repository.UnitOfWork
+	assignments *AssignmentService
+}
+
+func NewSubcourseService(uow repository.UnitOfWork, assignments *AssignmentService) *SubcourseService {
+	return &SubcourseService{uow: uow, assignments: assignments}
+}
+
+// List returns the subcourses the actor may see. Assignment-scoped actors see
+// only the subcourses they are explicitly assigned to.
+func (s *SubcourseService) List(ctx context.Context, actor Actor, filter repository.SubcourseFilter) ([]models.Subcourse, error) {
+	if !actor.ScopeAll {
+		ids, err := s.assignments.SubcourseIDs(ctx, actor.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return []models.Subcourse{}, nil
+		}
+		filter.IDs = ids
+	}
+	return s.uow.Repositories().Subcourses.List(ctx, filter)
+}
+
+// ListByProgram returns the subcourses of a program the actor may access
+func (s *SubcourseService) ListByProgram(ctx context.Context, actor Actor, programID uuid.UUID) ([]models.Subcourse, error) {
+	if err := s.assignments.CanAccessProgram(ctx, actor, programID); err != nil {
+		return nil, err
+	}
+	return s.uow.Repositories().Subcourses.List(ctx, repository.SubcourseFilter{ProgramID: &programID})
+}
+
+// Get returns the subcourse with its media, program and lessons
+func (s *SubcourseService) Get(ctx context.Context, actor Actor, id uuid.UUID) (*models.Subcourse, error) {
+	if err := s.assignments.CanAccessSubcourse(ctx, actor, id); err != nil {
+		return nil, err
+	}
+	subcourse, err := s.uow.Repositories().Subcourses.GetDetail(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, notFound("Subcourse not found")
+	}
+	return subcourse, err
+}
+
+// Create adds a subcourse with its media to a program the actor may access;
+// it belongs to the program's organization
+func (s *SubcourseService) Create(ctx context.Context, actor Actor, subcourse *models.Subcourse) (*models.Subcourse, error) {
+	program, err := s.uow.Repositories().Programs.Get(ctx, subcourse.ProgramID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, invalidf("Program not found")
+		}
+		return nil, err
+	}
+	if err := s.assignments.CanAccessProgram(ctx, actor, program.ID); err != nil {
+		return nil, err
+	}
+	if err := requireOwned(ctx, program.OrganizationID); err != nil {
+		return nil, err
+	}
+	subcourse.OrganizationID = program.OrganizationID
+	if err := s.checkSlug(ctx, subcourse.Slug, uuid.Nil); err != nil {
+		return nil, err
+	}
+	media := subcourse.Media
+	err = s.uow.Do(ctx, func(r repository.Repositories) error {
+		if err := r.Subcourses.Create(ctx, subcourse); err != nil {
+			return err
+		}
+		if len(media) > 0 {
+			if err := r.Media.Replace(ctx, models.OwnerSubcourse, subcourse.ID, media); err != nil {
+				return err
+			}
+		}
+		return r.Subcourses.RefreshCounters(ctx, nil, subcourse.ID)
+	})
+	if err != nil {
+		return nil, slugError(err)
+	}
+	return s.uow.Repositories().Subcourses.GetDetail(ctx, subcourse.ID)
+}
+
+// Update writes the fields set in updates; media are replaced when any are
+// given. Moving to another program also moves the subcourse into that
+// program's organization.
+func (s *SubcourseService) Update(ctx context.Context, actor Actor, id uuid.UUID, updates *models.Subcourse, check Precondition) (*models.Subcourse, error) {
+	existing, err := s.writable(ctx, actor, id, check)
+	if err != nil {
+		return nil, err
+	}
+	if updates.Slug != "" && updates.Slug != existing.Slug {
+		if err := s.checkSlug(ctx, updates.Slug, id); err != nil {
+			return nil, err
+		}
+	}
+	updates.OrganizationID = nil
+	if updates.ProgramID != uuid.Nil && updates.ProgramID != existing.ProgramID {
+		program, err := s.uow.Repositories().Programs.Get(ctx, updates.ProgramID)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return nil, invalidf("Program not found")
+			}
+			return nil, err
+		}
+		if err := s.assignments.CanAccessProgram(ctx, actor, program.ID); err != nil {
+			return nil, err
+		}
+		if err := requireOwned(ctx, program.OrganizationID); err != nil {
+			return nil, err
+		}
+		updates.OrganizationID = program.OrganizationID
+	}
+	updates.ID = id
+	updates.Version = 0
+	err = s.uow.Do(ctx, func(r repository.Repositories) error {
+		if err := r.Subcourses.BumpVersion(ctx, id, existing.Version); err != nil {
+			return err
+		}
+		if err := r.Subcourses.Update(ctx, id, updates); err != nil {
+			return err
+		}
+		if len(updates.Media) > 0 {
+			if err := r.Media.Replace(ctx, models.OwnerSubcourse, id, updates.Media); err != nil {
+				return err
+			}
+		}
+		// a move changes the counters of both programs
+		return r.Subcourses.RefreshCounters(ctx, []uuid.UUID{existing.ProgramID}, id)
+	})
+	if err != nil {
+		return nil, slugError(err)
+	}
+	return s.uow.Repositories().Subcourses.GetDetail(ctx, id)
+}
+
+// Delete moves the subcourse and its live lessons to the trash with the same
+// timestamp, so restoring the subcourse brings exactly those lessons back
+func (s *SubcourseService) Delete(ctx context.Context, actor Actor, id uuid.UUID, check Precondition) error {
+	subcourse, err := s.writable(ctx, actor, id, check)
+	if err != nil {
+		return err
+	}
+	// truncated to what Postgres stores, so rows trashed together match on restore
+	at := time.Now().UTC().Truncate(time.Microsecond)
+	var lessonIDs []uuid.UUID
+	err = s.uow.Do(ctx, func(r repository.Repositories) error {
+		var err error
+		if lessonIDs, err = r.Subcourses.Trash(ctx, id, subcourse.Version, actor.UserID, at); err != nil {
+			return err
+		}
+		return r.Subcourses.RefreshCounters(ctx, nil, id)
+	})
+	if err != nil {
+		return err
+	}
+	for _, lessonID := range lessonIDs {
+		realtime.NotifyDeleted(lessonID, actor.UserID, actor.Username)
+	}
+	return nil
+}
+
+// Reorder puts the subcourses of a program in the order of ids, which must
+// list every live subcourse of the program exactly once
+func (s *SubcourseService) Reorder(ctx context.Context, actor Actor, programID uuid.UUID, ids []uuid.UUID) ([]models.Subcourse, error) {
+	if err := s.assignments.CanAccessProgram(ctx, actor, programID); err != nil {
+		return nil, err
+	}
+	repos := s.uow.Repositories()
+	program, err := repos.Programs.Get(ctx, programID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, notFound("Program not found")
+		}
+		return nil, err
+	}
+	if err := requireOwned(ctx, program.OrganizationID); err != nil {
+		return nil, err
+	}
+	existing, err := repos.Subcourses.OrderIDs(ctx, programID)
+	if err != nil {
+		return nil, err
+	}
+	if !sameIDSet(existing, ids) {
+		return nil, invalidf("ids must list every subcourse of the program exactly once")
+	}
+	err = s.uow.Do(ctx, func(r repository.Repositories) error {
+		_, err := r.Subcourses.SetOrder(ctx, ids)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return repos.Subcourses.List(ctx, repository.SubcourseFilter{ProgramID: &programID})
+}
+
+// Move puts the subcourse at position among the subcourses of another
+// program, or of its own when programID is nil; position defaults to the
+// end. The subcourse and its lessons, trashed ones included, follow the
+// program's organization, and the subcourses left behind are renumbered.
+func (s *SubcourseService) Move(ctx context.Context, actor Actor, id, programID uuid.UUID, position *int, check Precondition) (*models.Subcourse, error) {
+	subcourse, err := s.writable(ctx, actor, id, check)
+	if err != nil {
+		return nil, err
+	}
+	from := subcourse.ProgramID
+	to := from
+	if programID != uuid.Nil {
+		to = programID
+	}
+	program, err := s.uow.Repositories().Programs.Get(ctx, to)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, invalidf("Program not found")
+		}
+		return nil, err
+	}
+	if to != from {
+		if err := s.assignments.CanAccessProgram(ctx, actor, to); err != nil {
+			return nil, err
+		}
+	}
+	if err := requireOwned(ctx, program.OrganizationID); err != nil {
+		return nil, err
+	}
+
+	err = s.uow.Do(ctx, func(r repository.Repositories) error {
+		if err := r.Subcourses.BumpVersion(ctx, id, subcourse.Version); err != nil {
+			return err
+		}
+		if to != from {
+			if err := r.Subcourses.Move(ctx, id, to, program.OrganizationID); err != nil {
+				return err
+			}
+			siblings, err := r.Subcourses.OrderIDs(ctx, from)
+			if err != nil {
+				return err
+			}
+			if _, err := r.Subcourses.SetOrder(ctx, siblings); err != nil {
+				return err
+			}
+		}
+		siblings, err := r.Subcourses.OrderIDs(ctx, to)
+		if err != nil {
+			return err
+		}
+		if _, err := r.Subcourses.SetOrder(ctx, insertAt(siblings, id, position)); err != nil {
+			return err
+		}
+		return r.Subcourses.RefreshCounters(ctx, []uuid.UUID{from}, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.uow.Repositories().Subcourses.GetDetail(ctx, id)
+}
+
+// writable loads a subcourse the actor is about to change and checks that
+// they may, and that it is still at the version check expects
+func (s *SubcourseService) writable(ctx context.Context, actor Actor, id uuid.UUID, check Precondition) (*models.Subcourse, error) {
+	if err := s.assignments.CanAccessSubcourse(ctx, actor, id); err != nil {
+		return nil, err
+	}
+	subcourse, err := s.uow.Repositories().Subcourses.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, notFound("Subcourse not found")
+		}
+		return nil, err
+	}
+	if err := requireOwned(ctx, subcourse.OrganizationID); err != nil {
+		return nil, err
+	}
+	if err := checkPrecondition(check, subcourse.Version); err != nil {
+		return nil, err
+	}
+	return subcourse, nil
+}
+
+func (s *SubcourseService) checkSlug(ctx context.Context, slug string, exceptID uuid.UUID) error {
+	taken, err := s.uow.Repositories().Subcourses.SlugInUse(ctx, slug, exceptID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrSlugInUse
+	}
+	return nil
+}
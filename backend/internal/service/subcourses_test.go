@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"courseai/backend/internal/database"
+	"courseai/backend/internal/models"
+	"courseai/backend/internal/repository"
+	"testing"
+)
+
+func newSubcourseService(store *fakeStore) *SubcourseService {
+	return NewSubcourseService(store, NewAssignmentService(store))
+}
+
+func TestSubcourseListScopesTeachersToAssignedSubcourses(t *testing.T) {
+	store := newFakeStore()
+	org := store.addOrganization("acme")
+	program := store.addProgram("go", org)
+	assigned := store.addSubcourse("basics", program)
+	store.addSubcourse("advanced", program)
+	teacher := store.addUser("teacher", models.RoleTeacher, org)
+	store.assign(teacher, models.ScopeSubcourse, assigned.ID)
+	subcourses := newSubcourseService(store)
+
+	got, err := subcourses.List(context.Background(), Actor{UserID: teacher.ID}, repository.SubcourseFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].ID != assigned.ID {
+		t.Fatalf("teacher sees %v, want only the assigned subcourse", got)
+	}
+
+	// a program assignment takes precedence and grants no subcourse list
+	store.assign(teacher, models.ScopeProgram, program.ID)
+	got, err = subcourses.List(context.Background(), Actor{UserID: teacher.ID}, repository.SubcourseFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("teacher with a program assignment sees %v, want none", got)
+	}
+}
+
+func TestSubcourseMoveFollowsTheTargetProgram(t *testing.T) {
+	store := newFakeStore()
+	org := store.addOrganization("acme")
+	sharer := store.addOrganization("sharer")
+	from := store.addProgram("go", org)
+	to := store.addProgram("rust", org)
+	shared := store.addProgram("shared", sharer)
+	subcourse := store.addSubcourse("basics", from)
+	subcourses := newSubcourseService(store)
+	ctx := database.WithTenant(context.Background(), org.ID)
+
+	if _, err := subcourses.Update(ctx, System, subcourse.ID, &models.Subcourse{ProgramID: shared.ID}, nil); !IsKind(err, KindForbidden) {
+		t.Fatalf("move into a shared program: err = %v, want KindForbidden", err)
+	}
+
+	teacher := store.addUser("teacher", models.RoleTeacher, org)
+	store.assign(teacher, models.ScopeProgram, from.ID)
+	if _, err := subcourses.Update(ctx, Actor{UserID: teacher.ID}, subcourse.ID, &models.Subcourse{ProgramID: to.ID}, nil); !IsKind(err, KindForbidden) {
+		t.Fatalf("move into an unassigned program: err = %v, want KindForbidden", err)
+	}
+
+	moved, err := subcourses.Update(ctx, System, subcourse.ID, &models.Subcourse{ProgramID: to.ID}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.ProgramID != to.ID || moved.Version != 2 {
+		t.Fatalf("moved to %s at version %d, want %s at version 2", moved.ProgramID, moved.Version, to.ID)
+	}
+}
+
+func TestSubcourseUpdateRequiresAccess(t *testing.T) {
+	store := newFakeStore()
+	org := store.addOrganization("acme")
+	program := store.addProgram("go", org)
+	subcourse := store.addSubcourse("basics", program)
+	teacher := store.addUser("teacher", models.RoleTeacher, org)
+	subcourses := newSubcourseService(store)
+	ctx := database.WithTenant(context.Background(), org.ID)
+
+	if _, err := subcourses.Update(ctx, Actor{UserID: teacher.ID}, subcourse.ID, &models.Subcourse{Name: "Mine"}, nil); !IsKind(err, KindForbidden) {
+		t.Fatalf("unassigned teacher update: err = %v, want KindForbidden", err)
+	}
+	store.assign(teacher, models.ScopeSubcourse, subcourse.ID)
+	if _, err := subcourses.Update(ctx, Actor{UserID: teacher.ID}, subcourse.ID, &models.Subcourse{Name: "Mine"}, nil); err != nil {
+		t.Fatalf("assigned teacher update: %v", err)
+	}
+}
@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+	"courseai/backend/internal/models"
+	"courseai/backend/internal/repository"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// TemplateService manages the lesson templates of programs. A template's
+// blueprint pre-populates new lessons; see LessonService.Create.
+type TemplateService struct {
+	uow         repository.UnitOfWork
+	assignments *AssignmentService
+}
+
+func NewTemplateService(uow repository.UnitOfWork, assignments *AssignmentService) *TemplateService {
+	return &TemplateService{uow: uow, assignments: assignments}
+}
+
+// TemplateFields are the writable fields of a template; the blueprint is a
+// lesson document that is checked and normalized before it is stored
+type TemplateFields struct {
+	Name        string
+	Description string
+	Blueprint   json.RawMessage
+}
+
+// ListByProgram returns the templates of a program the actor may access, by name
+func (s *TemplateService) ListByProgram(ctx context.Context, actor Actor, programID uuid.UUID) ([]models.LessonTemplate, error) {
+	if err := s.assignments.CanAccessProgram(ctx, actor, programID); err != nil {
+		return nil, err
+	}
+	return s.uow.Repositories().Templates.ListByProgram(ctx, programID)
+}
+
+// Get returns a template of a program the actor may access
+func (s *TemplateService) Get(ctx context.Context, actor Actor, id uuid.UUID) (*models.LessonTemplate, error) {
+	template, err := s.uow.Repositories().Templates.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, notFound("Template not found")
+		}
+		return nil, err
+	}
+	if err := s.assignments.CanAccessProgram(ctx, actor, template.ProgramID); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// Create adds a template to a program the actor may access; it belongs to
+// the program's organization
+func (s *TemplateService) Create(ctx context.Context, actor Actor, programID uuid.UUID, fields TemplateFields) (*models.LessonTemplate, error) {
+	if strings.TrimSpace(fields.Name) == "" {
+		return nil, invalidf("name is required")
+	}
+	blueprint, err := normalizeBlueprint(fields.Blueprint)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.assignments.CanAccessProgram(ctx, actor, programID); err != nil {
+		return nil, err
+	}
+	program, err := s.uow.Repositories().Programs.Get(ctx, programID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, notFound("Program not found")
+		}
+		return nil, err
+	}
+	if err := requireOwned(ctx, program.OrganizationID); err != nil {
+		return nil, err
+	}
+
+	template := &models.LessonTemplate{
+		ProgramID:      program.ID,
+		OrganizationID: program.OrganizationID,
+		Name:           fields.Name,
+		Description:    fields.Description,
+		Blueprint:      blueprint,
+		CreatedBy:      &actor.UserID,
+	}
+	if err := s.uow.Repositories().Templates.Create(ctx, template); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// Update writes the name, description and blueprint of fields that are set
+func (s *TemplateService) Update(ctx context.Context, actor Actor, id uuid.UUID, fields TemplateFields) (*models.LessonTemplate, error) {
+	if _, err := s.Get(ctx, actor, id); err != nil {
+		return nil, err
+	}
+	updates := map[string]interface{}{}
+	if strings.TrimSpace(fields.Name) != "" {
+		updates["name"] = fields.Name
+	}
+	if fields.Description != "" {
+		updates["description"] = fields.Description
+	}
+	if len(fields.Blueprint) > 0 {
+		blueprint, err := normalizeBlueprint(fields.Blueprint)
+		if err != nil {
+			return nil, err
+		}
+		updates["blueprint"] = blueprint
+	}
+	if err := s.uow.Repositories().Templates.Update(ctx, id, updates); err != nil {
+		return nil, err
+	}
+	return s.uow.Repositories().Templates.Get(ctx, id)
+}
+
+// Delete removes a template; lessons created from it keep their content
+func (s *TemplateService) Delete(ctx context.Context, actor Actor, id uuid.UUID) error {
+	if _, err := s.Get(ctx, actor, id); err != nil {
+		return err
+	}
+	return s.uow.Repositories().Templates.Delete(ctx, id)
+}
+
+// normalizeBlueprint validates a blueprint and strips IDs and media, which
+// belong to concrete lessons rather than to a template
+func normalizeBlueprint(raw json.RawMessage) (datatypes.JSON, error) {
+	var bp models.LessonBlueprint
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &bp); err != nil {
+			return nil, invalidf("invalid blueprint: %v", err)
+		}
+	}
+
+	if bp.Objectives != nil {
+		bp.Objectives.ID, bp.Objectives.LessonID = uuid.Nil, uuid.Nil
+	}
+	if bp.Preparation != nil {
+		bp.Preparation.ID, bp.Preparation.LessonID, bp.Preparation.Media = uuid.Nil, uuid.Nil, nil
+	}
+	for i := range bp.Models {
+		if strings.TrimSpace(bp.Models[i].Title) == "" {
+			return nil, invalidf("blueprint.models[%d].title is required", i)
+		}
+		bp.Models[i].ID, bp.Models[i].LessonID, bp.Models[i].Media = uuid.Nil, uuid.Nil, nil
+	}
+	for i := range bp.Builds {
+		if strings.TrimSpace(bp.Builds[i].Title) == "" {
+			return nil, invalidf("blueprint.builds[%d].title is required", i)
+		}
+		bp.Builds[i].ID, bp.Builds[i].LessonID, bp.Builds[i].Media = uuid.Nil, uuid.Nil, nil
+	}
+	for i := range bp.ContentBlocks {
+		if strings.TrimSpace(bp.ContentBlocks[i].Title) == "" {
+			return nil, invalidf("blueprint.content_blocks[%d].title is required", i)
+		}
+		bp.ContentBlocks[i].ID, bp.ContentBlocks[i].LessonID, bp.ContentBlocks[i].Media = uuid.Nil, uuid.Nil, nil
+	}
+	for i := range bp.Attachments {
+		if strings.TrimSpace(bp.Attachments[i].Title) == "" {
+			return nil, invalidf("blueprint.attachments[%d].title is required", i)
+		}
+		bp.Attachments[i].ID, bp.Attachments[i].LessonID, bp.Attachments[i].Media = uuid.Nil, uuid.Nil, nil
+	}
+	for i := range bp.Challenges {
+		if strings.TrimSpace(bp.Challenges[i].Title) == "" {
+			return nil, invalidf("blueprint.challenges[%d].title is required", i)
+		}
+		bp.Challenges[i].ID, bp.Challenges[i].LessonID, bp.Challenges[i].Media = uuid.Nil, uuid.Nil, nil
+	}
+	for i := range bp.Quizzes {
+		if strings.TrimSpace(bp.Quizzes[i].Title) == "" {
+			return nil, invalidf("blueprint.quizzes[%d].title is required", i)
+		}
+		bp.Quizzes[i].ID, bp.Quizzes[i].LessonID = uuid.Nil, uuid.Nil
+		for j := range bp.Quizzes[i].Options {
+			bp.Quizzes[i].Options[j].ID, bp.Quizzes[i].Options[j].QuizID = uuid.Nil, uuid.Nil
+		}
+	}
+
+	out, err := json.Marshal(bp)
+	if err != nil {
+		return nil, err
+	}
+	return datatypes.JSON(out), nil
+}
+
+// renderBlueprint substitutes the placeholders and decodes the blueprint.
+// Values are JSON-escaped so any title is safe to splice into the document.
+func renderBlueprint(raw datatypes.JSON, title, subcourse, program string) (*models.LessonBlueprint, error) {
+	escape := func(s string) string {
+		b, _ := json.Marshal(s)
+		return string(b[1 : len(b)-1])
+	}
+	rendered := strings.NewReplacer(
+		models.PlaceholderTitle, escape(title),
+		models.PlaceholderSubcourse, escape(subcourse),
+		models.PlaceholderProgram, escape(program),
+	).Replace(string(raw))
+
+	var bp models.LessonBlueprint
+	if err := json.Unmarshal([]byte(rendered), &bp); err != nil {
+		return nil, err
+	}
+	return &bp, nil
+}
+
+// applyBlueprint fills the parts of lesson the client left empty from bp
+func applyBlueprint(lesson *models.Lesson, bp *models.LessonBlueprint) {
+	if lesson.Subtitle == "" {
+		lesson.Subtitle = bp.Subtitle
+	}
+	if lesson.Overview == "" {
+		lesson.Overview = bp.Overview
+	}
+	if len(lesson.BlockTypes) == 0 {
+		lesson.BlockTypes = bp.BlockTypes
+	}
+	if lesson.DurationMinutes == 0 {
+		lesson.DurationMinutes = bp.DurationMinutes
+	}
+	if lesson.Difficulty == "" {
+		lesson.Difficulty = bp.Difficulty
+	}
+	if lesson.EstimatedTime == "" {
+		lesson.EstimatedTime = bp.EstimatedTime
+	}
+	if lesson.Objectives == nil {
+		lesson.Objectives = bp.Objectives
+	}
+	if len(lesson.Models) == 0 {
+		lesson.Models = bp.Models
+	}
+	if lesson.Preparation == nil {
+		lesson.Preparation = bp.Preparation
+	}
+	if len(lesson.Builds) == 0 {
+		lesson.Builds = bp.Builds
+	}
+	if len(lesson.ContentBlocks) == 0 {
+		lesson.ContentBlocks = bp.ContentBlocks
+	}
+	if len(lesson.Attachments) == 0 {
+		lesson.Attachments = bp.Attachments
+	}
+	if len(lesson.Challenges) == 0 {
+		lesson.Challenges = bp.Challenges
+	}
+	if len(lesson.Quizzes) == 0 {
+		lesson.Quizzes = bp.Quizzes
+	}
+}
@@ -0,0 +1,360 @@
+package service
+
+import (
+	"context"
+	"courseai/backend/internal/health"
+	"courseai/backend/internal/metrics"
+	"courseai/backend/internal/repository"
+	"courseai/backend/internal/telemetry"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TrashPurgeJob names the purge in metrics and worker heartbeats
+const TrashPurgeJob = "trash_purge"
+
+// TrashService lists, restores and purges trashed programs, subcourses and
+// lessons, and purges what has been in the trash longer than the retention
+type TrashService struct {
+	uow         repository.UnitOfWork
+	assignments *AssignmentService
+	// dir is where uploaded files live; purges remove the ones nothing uses
+	dir string
+	// retention is how long trashed content stays restorable
+	retention time.Duration
+}
+
+func NewTrashService(uow repository.UnitOfWork, assignments *AssignmentService, dir string, retention time.Duration) *TrashService {
+	return &TrashService{uow: uow, assignments: assignments, dir: dir, retention: retention}
+}
+
+// Retention is how long trashed content stays restorable
+func (s *TrashService) Retention() time.Duration { return s.retention }
+
+// TrashItem is one trashed program, subcourse or lesson
+type TrashItem struct {
+	repository.TrashEntry
+	DeletedByName string
+	PurgeAt       time.Time
+}
+
+// TrashImpact is what deleting a program, subcourse or lesson takes along
+type TrashImpact struct {
+	repository.TrashSubtree
+	// MediaFiles are stored uploads no other media uses; MediaBytes is their size
+	MediaFiles int64
+	MediaBytes int64
+}
+
+// trashNames name the kinds in messages
+var trashNames = map[repository.TrashKind]string{
+	repository.TrashProgram:   "Program",
+	repository.TrashSubcourse: "Subcourse",
+	repository.TrashLesson:    "Lesson",
+}
+
+// scope returns the programs and subcourses the actor manages
+func (s *TrashService) scope(ctx context.Context, actor Actor) (repository.TrashScope, error) {
+	if actor.ScopeAll {
+		return repository.TrashScope{All: true}, nil
+	}
+	assigned, err := s.assignments.Assigned(ctx, actor.UserID)
+	if err != nil {
+		return repository.TrashScope{}, err
+	}
+	return repository.TrashScope{ProgramIDs: assigned.ProgramIDs, SubcourseIDs: assigned.SubcourseIDs}, nil
+}
+
+// manage checks that the actor may manage the entry. Access is checked by
+// IDs, as trashed rows are invisible to the CanAccess* checks.
+func (s *TrashService) manage(ctx context.Context, actor Actor, entry *repository.TrashEntry) error {
+	if err := requireOwned(ctx, entry.OrganizationID); err != nil {
+		return err
+	}
+	scope, err := s.scope(ctx, actor)
+	if err != nil {
+		return err
+	}
+	if scope.All {
+		return nil
+	}
+	if entry.ProgramID != nil && containsID(scope.ProgramIDs, *entry.ProgramID) {
+		return nil
+	}
+	if entry.SubcourseID != nil && containsID(scope.SubcourseIDs, *entry.SubcourseID) {
+		return nil
+	}
+	return forbidden("Access denied")
+}
+
+// trashed returns the entry of kind if it is in the trash
+func (s *TrashService) trashed(ctx context.Context, r repository.Repositories, kind repository.TrashKind, id uuid.UUID) (*repository.TrashEntry, error) {
+	entry, err := r.Trash.Get(ctx, kind, id)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+	if err != nil || entry.DeletedAt == nil {
+		return nil, notFound(trashNames[kind] + " is not in the trash")
+	}
+	return entry, nil
+}
+
+// List returns the trashed entries of kinds the actor manages, newest first
+func (s *TrashService) List(ctx context.Context, actor Actor, kinds []repository.TrashKind) ([]TrashItem, error) {
+	scope, err := s.scope(ctx, actor)
+	if err != nil {
+		return nil, err
+	}
+	repos := s.uow.Repositories()
+	items := []TrashItem{}
+	var userIDs []uuid.UUID
+	for _, kind := range kinds {
+		entries, err := repos.Trash.List(ctx, kind, scope)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			items = append(items, TrashItem{TrashEntry: entry, PurgeAt: entry.DeletedAt.Add(s.retention)})
+			if entry.DeletedBy != nil {
+				userIDs = append(userIDs, *entry.DeletedBy)
+			}
+		}
+	}
+
+	// resolve who deleted what
+	if len(userIDs) > 0 {
+		names, err := repos.Users.Usernames(ctx, userIDs)
+		if err != nil {
+			return nil, err
+		}
+		for i := range items {
+			if items[i].DeletedBy != nil {
+				items[i].DeletedByName = names[*items[i].DeletedBy]
+			}
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].DeletedAt.After(*items[j].DeletedAt) })
+	return items, nil
+}
+
+// Restore brings a trashed entry back and returns it. A program or subcourse
+// brings back the children trashed with it; a subcourse or lesson whose
+// parent is still in the trash cannot be restored.
+func (s *TrashService) Restore(ctx context.Context, actor Actor, kind repository.TrashKind, id uuid.UUID) (interface{}, error) {
+	err := s.uow.Do(ctx, func(r repository.Repositories) error {
+		entry, err := s.trashed(ctx, r, kind, id)
+		if err != nil {
+			return err
+		}
+		if err := s.manage(ctx, actor, entry); err != nil {
+			return err
+		}
+		switch {
+		case kind == repository.TrashSubcourse && entry.ParentTrashed:
+			return &Error{Kind: KindConflict, Message: "Restore the program first"}
+		case kind == repository.TrashLesson && entry.ParentTrashed:
+			return &Error{Kind: KindConflict, Message: "Restore the subcourse first"}
+		}
+		if err := r.Trash.Restore(ctx, entry); err != nil {
+			return err
+		}
+
+		switch kind {
+		case repository.TrashProgram:
+			subcourseIDs, err := r.Subcourses.OrderIDs(ctx, id)
+			if err != nil {
+				return err
+			}
+			if err := r.Subcourses.RefreshCounters(ctx, nil, subcourseIDs...); err != nil {
+				return err
+			}
+			return r.Programs.RefreshCounters(ctx, id)
+		case repository.TrashSubcourse:
+			return r.Subcourses.RefreshCounters(ctx, nil, id)
+		default:
+			return r.Lessons.RefreshCounters(ctx, nil, id)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	repos := s.uow.Repositories()
+	switch kind {
+	case repository.TrashProgram:
+		return repos.Programs.Get(ctx, id)
+	case repository.TrashSubcourse:
+		return repos.Subcourses.Get(ctx, id)
+	default:
+		return repos.Lessons.Get(ctx, id)
+	}
+}
+
+// Purge removes a trashed entry and its subtree for good, with the uploaded
+// files nothing else uses. This cannot be undone.
+func (s *TrashService) Purge(ctx context.Context, actor Actor, kind repository.TrashKind, id uuid.UUID) error {
+	entry, err := s.trashed(ctx, s.uow.Repositories(), kind, id)
+	if err != nil {
+		return err
+	}
+	if err := s.manage(ctx, actor, entry); err != nil {
+		return err
+	}
+	return s.purge(ctx, kind, id)
+}
+
+// purge removes the entry in one transaction, then deletes the upload files
+// nothing references any more
+func (s *TrashService) purge(ctx context.Context, kind repository.TrashKind, id uuid.UUID) error {
+	var urls []string
+	err := s.uow.Do(ctx, func(r repository.Repositories) error {
+		var err error
+		urls, err = r.Trash.Purge(ctx, kind, id)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("purge %s %s: %w", kind, id, err)
+	}
+	s.removeUnusedUploads(ctx, urls)
+	return nil
+}
+
+// removeUnusedUploads deletes the files behind urls unless another media row
+// still points at them
+func (s *TrashService) removeUnusedUploads(ctx context.Context, urls []string) {
+	if len(urls) == 0 {
+		return
+	}
+	counts, err := s.uow.Repositories().Media.CountByURL(ctx, urls)
+	if err != nil {
+		log.Printf("Trash: failed to count media uses: %v", err)
+		return
+	}
+	for _, url := range urls {
+		path, ok := uploadFile(s.dir, url)
+		if !ok || counts[url] > 0 {
+			continue
+		}
+		_, span := telemetry.Start(ctx, "storage.remove", trace.WithAttributes(attribute.String("storage.path", path)))
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			log.Printf("Trash: failed to remove %s: %v", path, err)
+			telemetry.RecordError(span, err)
+		}
+		span.End()
+	}
+}
+
+// Impact counts what deleting a program, subcourse or lesson, live or
+// trashed, takes along: the whole subtree including children already in the
+// trash, as that is what a purge finally removes
+func (s *TrashService) Impact(ctx context.Context, actor Actor, kind repository.TrashKind, id uuid.UUID) (*TrashImpact, error) {
+	repos := s.uow.Repositories()
+	entry, err := repos.Trash.Get(ctx, kind, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, notFound(trashNames[kind] + " not found")
+		}
+		return nil, err
+	}
+	if err := s.manage(ctx, actor, entry); err != nil {
+		return nil, err
+	}
+	tree, err := repos.Trash.Subtree(ctx, kind, id)
+	if err != nil {
+		return nil, err
+	}
+	impact := &TrashImpact{TrashSubtree: *tree}
+
+	// a file goes away only when every media row using it does
+	uses := map[string]int64{}
+	sizes := map[string]int64{}
+	var urls []string
+	for _, m := range tree.Media {
+		if _, ok := uploadFile(s.dir, m.URL); !ok {
+			continue
+		}
+		if uses[m.URL] == 0 {
+			urls = append(urls, m.URL)
+		}
+		uses[m.URL]++
+		var meta struct {
+			Size int64 `json:"size"`
+		}
+		if json.Unmarshal(m.Meta, &meta) == nil && meta.Size > 0 {
+			sizes[m.URL] = meta.Size
+		}
+	}
+	counts, err := repos.Media.CountByURL(ctx, urls)
+	if err != nil {
+		return nil, err
+	}
+	for _, url := range urls {
+		if counts[url] <= uses[url] {
+			impact.MediaFiles++
+			impact.MediaBytes += sizes[url]
+		}
+	}
+	return impact, nil
+}
+
+// RunPurge purges content that has been in the trash longer than the
+// retention, every interval, until ctx is done
+func (s *TrashService) RunPurge(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.purgeExpired(ctx)
+		health.Beat(TrashPurgeJob)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purgeExpired runs outside any request, so no organization applies. It
+// purges programs first, as they take their subcourses and lessons along,
+// then subcourses, then the remaining lessons.
+func (s *TrashService) purgeExpired(ctx context.Context) int {
+	// one trace per run, with the statements and file removals below it
+	ctx, span := telemetry.Start(ctx, "job trash_purge")
+	defer span.End()
+
+	started := time.Now()
+	cutoff := started.Add(-s.retention)
+	purged := 0
+	var failed error
+	for _, kind := range repository.TrashKinds {
+		ids, err := s.uow.Repositories().Trash.Expired(ctx, kind, cutoff)
+		if err != nil {
+			log.Printf("Trash: failed to find expired %ss: %v", kind, err)
+			failed = err
+			continue
+		}
+		for _, id := range ids {
+			if err := s.purge(ctx, kind, id); err != nil {
+				log.Printf("Trash: %v", err)
+				failed = err
+				continue
+			}
+			purged++
+		}
+	}
+	if purged > 0 {
+		log.Printf("Trash: purged %d expired item(s)", purged)
+	}
+	span.SetAttributes(attribute.Int("job.items", purged))
+	telemetry.RecordError(span, failed)
+	metrics.ObserveJob(TrashPurgeJob, started, purged, failed)
+	return purged
+}
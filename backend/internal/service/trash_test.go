@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"courseai/backend/internal/database"
+	"courseai/backend/internal/models"
+	"courseai/backend/internal/repository"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTrashRestoreNeedsTheParentBack(t *testing.T) {
+	store := newFakeStore()
+	org := store.addOrganization("acme")
+	program := store.addProgram("go", org)
+	subcourse := store.addSubcourse("basics", program)
+	trashed := store.addTrashed(repository.TrashSubcourse, subcourse, time.Now())
+	trashed.ParentTrashed = true
+	lesson := store.addTrashed(repository.TrashLesson, subcourse, time.Now())
+	trash := NewTrashService(store, NewAssignmentService(store), t.TempDir(), time.Hour)
+	ctx := database.WithTenant(context.Background(), org.ID)
+
+	if _, err := trash.Restore(ctx, System, repository.TrashSubcourse, subcourse.ID); !IsKind(err, KindConflict) {
+		t.Fatalf("restore with the program trashed: err = %v, want KindConflict", err)
+	}
+
+	teacher := store.addUser("teacher", models.RoleTeacher, org)
+	if _, err := trash.Restore(ctx, Actor{UserID: teacher.ID}, repository.TrashLesson, lesson.ID); !IsKind(err, KindForbidden) {
+		t.Fatalf("restore by an unassigned teacher: err = %v, want KindForbidden", err)
+	}
+	store.assign(teacher, models.ScopeSubcourse, subcourse.ID)
+	if _, err := trash.Restore(ctx, Actor{UserID: teacher.ID}, repository.TrashLesson, lesson.ID); err != nil {
+		t.Fatalf("restore by an assigned teacher: %v", err)
+	}
+	if _, err := trash.Restore(ctx, System, repository.TrashLesson, lesson.ID); !IsKind(err, KindNotFound) {
+		t.Fatalf("restore twice: err = %v, want KindNotFound", err)
+	}
+}
+
+func TestTrashPurgeExpiredRemovesUnusedUploads(t *testing.T) {
+	store := newFakeStore()
+	org := store.addOrganization("acme")
+	program := store.addProgram("go", org)
+	subcourse := store.addSubcourse("basics", program)
+	dir := t.TempDir()
+	for _, name := range []string{"only.png", "shared.png", "fresh.png"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("png"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expired := store.addTrashed(repository.TrashLesson, subcourse, time.Now().Add(-48*time.Hour), "/uploads/only.png", "/uploads/shared.png")
+	fresh := store.addTrashed(repository.TrashLesson, subcourse, time.Now(), "/uploads/fresh.png", "/uploads/shared.png")
+	trash := NewTrashService(store, NewAssignmentService(store), dir, 24*time.Hour)
+
+	if purged := trash.purgeExpired(context.Background()); purged != 1 {
+		t.Fatalf("purged %d item(s), want 1", purged)
+	}
+	if _, ok := store.trash[expired.ID]; ok {
+		t.Fatal("expired lesson is still in the trash")
+	}
+	if _, ok := store.trash[fresh.ID]; !ok {
+		t.Fatal("lesson within the retention was purged")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "only.png")); !os.IsNotExist(err) {
+		t.Fatalf("unused upload: stat err = %v, want it removed", err)
+	}
+	for _, name := range []string{"shared.png", "fresh.png"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Fatalf("%s still in use: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"courseai/backend/internal/models"
+	"courseai/backend/internal/repository"
+	"courseai/backend/internal/security"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const recoveryCodeCount = 10
+
+// ErrInvalidSecondFactor is returned when a TOTP or recovery code is wrong
+// or was used already
+var ErrInvalidSecondFactor = errors.New("invalid second factor")
+
+// TwoFactorService runs TOTP enrollment and checks second factors. Login
+// throttling and the policy of who must use 2FA stay with the callers.
+type TwoFactorService struct {
+	uow repository.UnitOfWork
+}
+
+func NewTwoFactorService(uow repository.UnitOfWork) *TwoFactorService {
+	return &TwoFactorService{uow: uow}
+}
+
+// Verify accepts a TOTP code (not replayable) or an unused recovery code,
+// consuming it. It returns the method used, for login history.
+func (s *TwoFactorService) Verify(ctx context.Context, user *models.User, code, recoveryCode string) (string, error) {
+	r := s.uow.Repositories()
+	if recoveryCode != "" {
+		ok, err := r.RecoveryCodes.Use(ctx, user.ID, security.HashRecoveryCode(recoveryCode), time.Now())
+		if err != nil || !ok {
+			return "recovery_code", ErrInvalidSecondFactor
+		}
+		return "recovery_code", nil
+	}
+
+	step, ok := security.VerifyTOTP(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return "totp", ErrInvalidSecondFactor
+	}
+	if ok, err := r.Users.UseTOTPStep(ctx, user.ID, step); err != nil || !ok {
+		return "totp", ErrInvalidSecondFactor
+	}
+	return "totp", nil
+}
+
+// Start generates a new secret for the user to enter into an authenticator
+// app; it takes effect once Enable confirms it
+func (s *TwoFactorService) Start(ctx context.Context, userID uuid.UUID) (string, error) {
+	secret, err := security.NewTOTPSecret()
+	if err != nil {
+		return "", err
+	}
+	if err := s.uow.Repositories().Users.StartTOTP(ctx, userID, secret); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// Enable confirms enrollment with a first code from the app and returns the
+// recovery codes, which are never shown again
+func (s *TwoFactorService) Enable(ctx context.Context, user *models.User, code string) ([]string, error) {
+	step, ok := security.VerifyTOTP(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidSecondFactor
+	}
+	var codes []string
+	err := s.uow.Do(ctx, func(r repository.Repositories) error {
+		if err := r.Users.EnableTOTP(ctx, user.ID, step); err != nil {
+			return err
+		}
+		var err error
+		codes, err = replaceRecoveryCodes(ctx, r, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable turns 2FA off and drops the recovery codes; the user re-enrolls
+// when policy requires it
+func (s *TwoFactorService) Disable(ctx context.Context, userID uuid.UUID) error {
+	return s.uow.Do(ctx, func(r repository.Repositories) error {
+		if err := r.Users.DisableTOTP(ctx, userID); err != nil {
+			return err
+		}
+		return r.RecoveryCodes.Replace(ctx, userID, nil)
+	})
+}
+
+// RegenerateRecoveryCodes replaces every recovery code of the user
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	var codes []string
+	err := s.uow.Do(ctx, func(r repository.Repositories) error {
+		var err error
+		codes, err = replaceRecoveryCodes(ctx, r, userID)
+		return err
+	})
+	return codes, err
+}
+
+// RecoveryCodesRemaining counts the user's unused recovery codes
+func (s *TwoFactorService) RecoveryCodesRemaining(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return s.uow.Repositories().RecoveryCodes.CountUnused(ctx, userID)
+}
+
+func replaceRecoveryCodes(ctx context.Context, r repository.Repositories, userID uuid.UUID) ([]string, error) {
+	codes, err := security.NewRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = security.HashRecoveryCode(code)
+	}
+	if err := r.RecoveryCodes.Replace(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
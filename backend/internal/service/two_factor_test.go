@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"courseai/backend/internal/models"
+	"courseai/backend/internal/security"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTwoFactorRecoveryCodesWorkOnce(t *testing.T) {
+	store := newFakeStore()
+	user := store.addUser("ada", models.RoleTeacher, store.addOrganization("acme"))
+	twoFactor := NewTwoFactorService(store)
+	ctx := context.Background()
+
+	codes, err := twoFactor.RegenerateRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("got %d recovery codes, want %d", len(codes), recoveryCodeCount)
+	}
+
+	method, err := twoFactor.Verify(ctx, user, "", codes[0])
+	if err != nil || method != "recovery_code" {
+		t.Fatalf("first use: method = %q, err = %v", method, err)
+	}
+	if _, err := twoFactor.Verify(ctx, user, "", codes[0]); !errors.Is(err, ErrInvalidSecondFactor) {
+		t.Fatalf("second use: err = %v, want ErrInvalidSecondFactor", err)
+	}
+	if n, _ := twoFactor.RecoveryCodesRemaining(ctx, user.ID); n != recoveryCodeCount-1 {
+		t.Fatalf("%d codes remaining, want %d", n, recoveryCodeCount-1)
+	}
+
+	// regenerating invalidates the old codes
+	if _, err := twoFactor.RegenerateRecoveryCodes(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := twoFactor.Verify(ctx, user, "", codes[1]); !errors.Is(err, ErrInvalidSecondFactor) {
+		t.Fatalf("old code after regenerating: err = %v, want ErrInvalidSecondFactor", err)
+	}
+}
+
+func TestTwoFactorRejectsReplayedCodes(t *testing.T) {
+	store := newFakeStore()
+	user := store.addUser("ada", models.RoleTeacher, store.addOrganization("acme"))
+	user.TOTPSecret = "JBSWY3DPEHPK3PXP"
+	user.TOTPEnabled = true
+	twoFactor := NewTwoFactorService(store)
+	ctx := context.Background()
+
+	now := time.Now()
+	code, err := security.TOTPCode(user.TOTPSecret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if method, err := twoFactor.Verify(ctx, user, code, ""); err != nil || method != "totp" {
+		t.Fatalf("first use: method = %q, err = %v", method, err)
+	}
+	if _, err := twoFactor.Verify(ctx, user, code, ""); !errors.Is(err, ErrInvalidSecondFactor) {
+		t.Fatalf("replay: err = %v, want ErrInvalidSecondFactor", err)
+	}
+	// the previous code is still inside the skew window, but older than the one used
+	previous, err := security.TOTPCode(user.TOTPSecret, now.Add(-30*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := twoFactor.Verify(ctx, user, previous, ""); !errors.Is(err, ErrInvalidSecondFactor) {
+		t.Fatalf("earlier code: err = %v, want ErrInvalidSecondFactor", err)
+	}
+}
+
+func TestTwoFactorRejectsWrongCodes(t *testing.T) {
+	store := newFakeStore()
+	user := store.addUser("ada", models.RoleTeacher, store.addOrganization("acme"))
+	user.TOTPSecret = "JBSWY3DPEHPK3PXP"
+	twoFactor := NewTwoFactorService(store)
+
+	if _, err := twoFactor.Verify(context.Background(), user, "000000x", ""); !errors.Is(err, ErrInvalidSecondFactor) {
+		t.Fatalf("verify: err = %v, want ErrInvalidSecondFactor", err)
+	}
+	if _, err := twoFactor.Enable(context.Background(), user, "000000x"); !errors.Is(err, ErrInvalidSecondFactor) {
+		t.Fatalf("enable: err = %v, want ErrInvalidSecondFactor", err)
+	}
+}
+
+func TestTwoFactorDisableDropsRecoveryCodes(t *testing.T) {
+	store := newFakeStore()
+	user := store.addUser("ada", models.RoleTeacher, store.addOrganization("acme"))
+	user.TOTPEnabled = true
+	twoFactor := NewTwoFactorService(store)
+	ctx := context.Background()
+
+	if _, err := twoFactor.RegenerateRecoveryCodes(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := twoFactor.Disable(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if store.users[user.ID].TOTPEnabled {
+		t.Fatal("2FA still enabled")
+	}
+	if n, _ := twoFactor.RecoveryCodesRemaining(ctx, user.ID); n != 0 {
+		t.Fatalf("%d recovery codes remaining, want 0", n)
+	}
+}
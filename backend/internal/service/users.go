@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"courseai/backend/internal/models"
+	"courseai/backend/internal/repository"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// UserService manages accounts: roles, how users may sign in, and what
+// authorization needs to know about them
+type UserService struct {
+	uow repository.UnitOfWork
+}
+
+func NewUserService(uow repository.UnitOfWork) *UserService {
+	return &UserService{uow: uow}
+}
+
+func (s *UserService) Get(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	user, err := s.uow.Repositories().Users.Get(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, notFound("User not found")
+	}
+	return user, err
+}
+
+// FindByLogin returns the user whose username or email is login, nil when
+// there is none
+func (s *UserService) FindByLogin(ctx context.Context, login string) (*models.User, error) {
+	user, err := s.uow.Repositories().Users.FindByLogin(ctx, login)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	return user, err
+}
+
+// Principal returns the user's current role, status and organization, nil
+// when the user no longer exists
+func (s *UserService) Principal(ctx context.Context, id uuid.UUID) (*repository.Principal, error) {
+	p, err := s.uow.Repositories().Users.Principal(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	return p, err
+}
+
+// OrganizationActive reports whether the user's organization, if any, is
+// active; members of a suspended organization cannot sign in
+func (s *UserService) OrganizationActive(ctx context.Context, user *models.User) (bool, error) {
+	if user.OrganizationID == nil {
+		return true, nil
+	}
+	org, err := s.uow.Repositories().Organizations.Get(ctx, *user.OrganizationID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return org.Status == models.OrgStatusActive, nil
+}
+
+// SetRole changes a user's role. Super-admins are not bound to an
+// organization, so a demoted super-admin must be given one.
+func (s *UserService) SetRole(ctx context.Context, id uuid.UUID, role models.UserRole, organizationID *uuid.UUID) (*models.User, error) {
+	user, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	orgID := user.OrganizationID
+	if role == models.RoleSuperAdmin {
+		orgID = nil
+	} else if user.OrganizationID == nil {
+		if organizationID == nil {
+			return nil, invalidf("organization_id is required when demoting a super-admin")
+		}
+		org, err := s.uow.Repositories().Organizations.Get(ctx, *organizationID)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return nil, invalidf("Organization not found")
+			}
+			return nil, err
+		}
+		orgID = &org.ID
+	}
+	if err := s.uow.Repositories().Users.SetRole(ctx, id, role, orgID); err != nil {
+		return nil, err
+	}
+	user.Role = role
+	user.OrganizationID = orgID
+	return user, nil
+}
+
+// SetPasswordLogin forces a user onto single sign-on, or allows passwords
+// again. Only users with a linked identity can be forced, or they could not
+// sign in at all.
+func (s *UserService) SetPasswordLogin(ctx context.Context, id uuid.UUID, disabled bool) (*models.User, error) {
+	user, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if disabled {
+		count, err := s.uow.Repositories().Identities.CountByUser(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return nil, invalidf("User has no linked single sign-on identity")
+		}
+	}
+	if err := s.uow.Repositories().Users.SetPasswordLoginDisabled(ctx, id, disabled); err != nil {
+		return nil, err
+	}
+	user.PasswordLoginDisabled = disabled
+	return user, nil
+}
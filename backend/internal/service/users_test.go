@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"courseai/backend/internal/models"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestUserSetRoleDemotingSuperAdminNeedsOrganization(t *testing.T) {
+	store := newFakeStore()
+	org := store.addOrganization("acme")
+	admin := store.addUser("root", models.RoleSuperAdmin, nil)
+	users := NewUserService(store)
+	ctx := context.Background()
+
+	if _, err := users.SetRole(ctx, admin.ID, models.RoleAdmin, nil); !IsKind(err, KindInvalid) {
+		t.Fatalf("demote without organization: err = %v, want KindInvalid", err)
+	}
+	unknown := uuid.New()
+	if _, err := users.SetRole(ctx, admin.ID, models.RoleAdmin, &unknown); !IsKind(err, KindInvalid) {
+		t.Fatalf("demote into unknown organization: err = %v, want KindInvalid", err)
+	}
+
+	user, err := users.SetRole(ctx, admin.ID, models.RoleAdmin, &org.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.OrganizationID == nil || *user.OrganizationID != org.ID {
+		t.Fatalf("demoted into %v, want %s", user.OrganizationID, org.ID)
+	}
+
+	// promoting releases the organization again
+	user, err = users.SetRole(ctx, admin.ID, models.RoleSuperAdmin, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.OrganizationID != nil || store.users[admin.ID].OrganizationID != nil {
+		t.Fatalf("super-admin still belongs to %v", user.OrganizationID)
+	}
+}
+
+func TestUserSetPasswordLoginNeedsLinkedIdentity(t *testing.T) {
+	store := newFakeStore()
+	org := store.addOrganization("acme")
+	user := store.addUser("ada", models.RoleTeacher, org)
+	users := NewUserService(store)
+	ctx := context.Background()
+
+	if _, err := users.SetPasswordLogin(ctx, user.ID, true); !IsKind(err, KindInvalid) {
+		t.Fatalf("force SSO without identity: err = %v, want KindInvalid", err)
+	}
+	store.identities = append(store.identities, models.UserIdentity{ID: uuid.New(), UserID: user.ID, Issuer: "https://idp", Subject: "ada"})
+	if _, err := users.SetPasswordLogin(ctx, user.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	if !store.users[user.ID].PasswordLoginDisabled {
+		t.Fatal("password login still enabled")
+	}
+}
+
+func TestUserOrganizationActive(t *testing.T) {
+	store := newFakeStore()
+	org := store.addOrganization("acme")
+	user := store.addUser("ada", models.RoleTeacher, org)
+	users := NewUserService(store)
+
+	if ok, err := users.OrganizationActive(context.Background(), user); err != nil || !ok {
+		t.Fatalf("active organization: ok = %v, err = %v", ok, err)
+	}
+	org.Status = models.OrgStatusSuspended
+	if ok, err := users.OrganizationActive(context.Background(), user); err != nil || ok {
+		t.Fatalf("suspended organization: ok = %v, err = %v", ok, err)
+	}
+}
@@ -0,0 +1,85 @@
+package telemetry
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// GormPlugin records a client span for every GORM statement, as a child of
+// the span in the statement's context (db.WithContext). The SQL is recorded
+// with placeholders, never with bound values.
+type GormPlugin struct{}
+
+func (GormPlugin) Name() string { return "courseai:tracing" }
+
+const spanKeyGorm = "courseai:tracing_span"
+
+// statementSpan is the span of a running statement and its initial name,
+// which the table is appended to once GORM resolved it
+type statementSpan struct {
+	span trace.Span
+	name string
+}
+
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	before := func(operation string) func(*gorm.DB) {
+		return func(db *gorm.DB) {
+			if !Enabled() || db.Statement.Context == nil {
+				return
+			}
+			name := "db." + operation
+			_, span := Start(db.Statement.Context, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+				attribute.String("db.system", "postgresql"),
+				attribute.String("db.operation", operation),
+			))
+			db.InstanceSet(spanKeyGorm, statementSpan{span: span, name: name})
+		}
+	}
+	after := func(db *gorm.DB) {
+		v, ok := db.InstanceGet(spanKeyGorm)
+		if !ok {
+			return
+		}
+		s, ok := v.(statementSpan)
+		if !ok {
+			return
+		}
+		span := s.span
+		if table := db.Statement.Table; table != "" {
+			span.SetName(s.name + " " + table)
+			span.SetAttributes(attribute.String("db.sql.table", table))
+		}
+		span.SetAttributes(
+			attribute.String("db.statement", db.Statement.SQL.String()),
+			attribute.Int64("db.rows_affected", db.RowsAffected),
+		)
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			RecordError(span, db.Error)
+		}
+		span.End()
+	}
+
+	cb := db.Callback()
+	for _, err := range []error{
+		cb.Create().Before("gorm:create").Register("tracing:before_create", before("insert")),
+		cb.Create().After("gorm:create").Register("tracing:after_create", after),
+		cb.Query().Before("gorm:query").Register("tracing:before_query", before("select")),
+		cb.Query().After("gorm:query").Register("tracing:after_query", after),
+		cb.Update().Before("gorm:update").Register("tracing:before_update", before("update")),
+		cb.Update().After("gorm:update").Register("tracing:after_update", after),
+		cb.Delete().Before("gorm:delete").Register("tracing:before_delete", before("delete")),
+		cb.Delete().After("gorm:delete").Register("tracing:after_delete", after),
+		cb.Row().Before("gorm:row").Register("tracing:before_row", before("row")),
+		cb.Row().After("gorm:row").Register("tracing:after_row", after),
+		cb.Raw().Before("gorm:raw").Register("tracing:before_raw", before("raw")),
+		cb.Raw().After("gorm:raw").Register("tracing:after_raw", after),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+// Package telemetry sets up OpenTelemetry tracing: the SDK's tracer provider
+// with an OTLP (HTTP or gRPC) or stdout exporter, ratio sampling and W3C
+// trace-context propagation. Spans for requests, SQL statements, storage and
+// authorization are recorded through the OpenTelemetry API; Start is a
+// shorthand for the backend's tracer.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Options configures tracing
+type Options struct {
+	// Exporter is none, stdout or otlp
+	Exporter string
+	// Protocol is the OTLP transport, http/protobuf or grpc
+	Protocol string
+	// Endpoint is the OTLP base URL, e.g. http://localhost:4318 (HTTP) or
+	// http://localhost:4317 (gRPC)
+	Endpoint string
+	// Headers are sent with every export, e.g. an API key of a hosted collector
+	Headers map[string]string
+	// ServiceName is reported as the service.name resource attribute
+	ServiceName string
+	// SampleRatio is the share of new traces that are recorded, 0 to 1;
+	// traces started by a caller follow the caller's decision
+	SampleRatio float64
+}
+
+// scopeName identifies the instrumentation in exported data
+const scopeName = "courseai/backend"
+
+var (
+	tracer  = otel.Tracer(scopeName)
+	enabled atomic.Bool
+)
+
+// Enabled reports whether spans are being recorded, so instrumentation can
+// skip its own work while tracing is off
+func Enabled() bool { return enabled.Load() }
+
+// Start begins a span as a child of the span in ctx and returns ctx carrying
+// it. While tracing is off the span records nothing.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return tracer.Start(ctx, name, opts...)
+}
+
+// Setup installs the global tracer provider and propagator as configured. The
+// returned function flushes pending spans and stops; it must be called
+// before the process exits.
+func Setup(opts Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	ctx := context.Background()
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch strings.ToLower(opts.Exporter) {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "stdout":
+		exporter, err = stdouttrace.New()
+	case "otlp":
+		if opts.Endpoint == "" {
+			return nil, errors.New("telemetry: the otlp exporter needs an endpoint")
+		}
+		exporter, err = otlpExporter(ctx, opts)
+	default:
+		return nil, errors.New("telemetry: unknown exporter " + opts.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if opts.ServiceName == "" {
+		opts.ServiceName = "courseai-backend"
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(opts.ServiceName)))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	enabled.Store(true)
+	return func(ctx context.Context) error {
+		enabled.Store(false)
+		return provider.Shutdown(ctx)
+	}, nil
+}
+
+// otlpExporter connects to the collector over OTLP/HTTP (protobuf) or gRPC
+func otlpExporter(ctx context.Context, opts Options) (sdktrace.SpanExporter, error) {
+	switch strings.ToLower(opts.Protocol) {
+	case "", "http/protobuf":
+		// the endpoint is the collector's base URL, as OTEL_EXPORTER_OTLP_ENDPOINT is
+		url := strings.TrimSuffix(opts.Endpoint, "/")
+		if !strings.HasSuffix(url, "/v1/traces") {
+			url += "/v1/traces"
+		}
+		return otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(url), otlptracehttp.WithHeaders(opts.Headers))
+	case "grpc":
+		return otlptracegrpc.New(ctx, otlptracegrpc.WithEndpointURL(opts.Endpoint), otlptracegrpc.WithHeaders(opts.Headers))
+	default:
+		return nil, errors.New("telemetry: unknown OTLP protocol " + opts.Protocol)
+	}
+}
+
+// Transport traces outgoing requests and propagates the trace context to the
+// called service; base nil means http.DefaultTransport
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return otelhttp.NewTransport(base)
+}
+
+// RecordError adds err to span as an exception event and marks the span
+// failed; a nil err leaves the span as it is
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -13,16 +13,45 @@ type Claims struct {
 	UserID   uuid.UUID       `json:"user_id"`
 	Username string          `json:"username"`
 	Role     models.UserRole `json:"role"`
+	// OrganizationID is the tenant the token is bound to (nil for super-admins)
+	OrganizationID *uuid.UUID `json:"org_id,omitempty"`
+	// Purpose marks restricted tokens (e.g. a pending 2FA challenge); empty for session tokens
+	Purpose string `json:"purpose,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// Challenge token purposes
+const (
+	PurposeMFA       = "mfa"        // password verified, TOTP code pending
+	PurposeMFAEnroll = "mfa_enroll" // password verified, 2FA enrollment required first
+)
+
 func GenerateJWT(user *models.User, secret string, expireHours int) (string, error) {
+	claims := &Claims{
+		UserID:         user.ID,
+		Username:       user.Username,
+		Role:           user.Role,
+		OrganizationID: user.OrganizationID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * time.Duration(expireHours))),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(secret))
+}
+
+// GenerateChallengeJWT issues a short-lived token that only proves the first
+// login step; it is rejected by ValidateJWT and so cannot be used as a session.
+func GenerateChallengeJWT(user *models.User, secret, purpose string, ttl time.Duration) (string, error) {
 	claims := &Claims{
 		UserID:   user.ID,
 		Username: user.Username,
 		Role:     user.Role,
+		Purpose:  purpose,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * time.Duration(expireHours))),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
@@ -31,7 +60,30 @@ func GenerateJWT(user *models.User, secret string, expireHours int) (string, err
 	return token.SignedString([]byte(secret))
 }
 
+// ValidateChallengeJWT validates a challenge token issued for purpose
+func ValidateChallengeJWT(tokenString, secret, purpose string) (*Claims, error) {
+	claims, err := parseJWT(tokenString, secret)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != purpose {
+		return nil, fmt.Errorf("invalid token purpose")
+	}
+	return claims, nil
+}
+
 func ValidateJWT(tokenString string, secret string) (*Claims, error) {
+	claims, err := parseJWT(tokenString, secret)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != "" {
+		return nil, fmt.Errorf("invalid token")
+	}
+	return claims, nil
+}
+
+func parseJWT(tokenString string, secret string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
@@ -0,0 +1,179 @@
+package validation
+
+import (
+	"courseai/backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Rules for the content tree. With partial set, as for updates where empty
+// fields are left unchanged, top-level required fields are only checked when
+// sent; nested components are always replaced whole and checked in full.
+
+// shortText is the size of the varchar(50) columns
+const shortText = 50
+
+func required(v *Validator, partial bool, field, value string) {
+	if !partial || value != "" {
+		v.Required(field, value)
+	}
+}
+
+func slug(v *Validator, partial bool, value string) {
+	if !partial || value != "" {
+		v.Slug("slug", value)
+	}
+}
+
+func Program(v *Validator, p *models.Program, partial bool) {
+	required(v, partial, "name", p.Name)
+	slug(v, partial, p.Slug)
+	OneOf(v, "status", p.Status, models.ContentStatuses)
+	mediaList(v, p.Media)
+}
+
+func Subcourse(v *Validator, s *models.Subcourse, partial bool) {
+	if !partial && s.ProgramID == uuid.Nil {
+		v.Add("program_id", CodeRequired, "is required")
+	}
+	required(v, partial, "name", s.Name)
+	slug(v, partial, s.Slug)
+	v.MaxLen("age_range", s.AgeRange, shortText)
+	OneOf(v, "status", s.Status, models.ContentStatuses)
+	mediaList(v, s.Media)
+}
+
+// Lesson checks a lesson with all of its components
+func Lesson(v *Validator, l *models.Lesson, partial bool) {
+	if !partial && l.SubcourseID == uuid.Nil {
+		v.Add("subcourse_id", CodeRequired, "is required")
+	}
+	required(v, partial, "title", l.Title)
+	slug(v, partial, l.Slug)
+	OneOf(v, "status", l.Status, models.ContentStatuses)
+	v.Min("duration_minutes", l.DurationMinutes, 0)
+	v.MaxLen("difficulty", l.Difficulty, shortText)
+	v.MaxLen("estimated_time", l.EstimatedTime, shortText)
+	mediaList(v, l.Media)
+
+	for i := range l.Models {
+		LessonModel(v.Index("models", i), &l.Models[i])
+	}
+	if l.Preparation != nil {
+		LessonPreparation(v.At("preparation"), l.Preparation)
+	}
+	for i := range l.Builds {
+		LessonBuild(v.Index("builds", i), &l.Builds[i])
+	}
+	for i := range l.ContentBlocks {
+		LessonContentBlock(v.Index("content_blocks", i), &l.ContentBlocks[i])
+	}
+	for i := range l.Attachments {
+		LessonAttachment(v.Index("attachments", i), &l.Attachments[i])
+	}
+	for i := range l.Challenges {
+		LessonChallenge(v.Index("challenges", i), &l.Challenges[i])
+	}
+	for i := range l.Quizzes {
+		LessonQuiz(v.Index("quizzes", i), &l.Quizzes[i])
+	}
+}
+
+// Component checks any lesson component, e.g. the body of a component endpoint
+func Component(v *Validator, item interface{}) {
+	switch c := item.(type) {
+	case *models.LessonModel:
+		LessonModel(v, c)
+	case *models.LessonPreparation:
+		LessonPreparation(v, c)
+	case *models.LessonBuild:
+		LessonBuild(v, c)
+	case *models.LessonContentBlock:
+		LessonContentBlock(v, c)
+	case *models.LessonAttachment:
+		LessonAttachment(v, c)
+	case *models.LessonChallenge:
+		LessonChallenge(v, c)
+	case *models.LessonQuiz:
+		LessonQuiz(v, c)
+	case *models.LessonQuizOption:
+		LessonQuizOption(v, c)
+	}
+}
+
+func LessonModel(v *Validator, m *models.LessonModel) {
+	v.Required("title", m.Title)
+	mediaList(v, m.Media)
+}
+
+func LessonPreparation(v *Validator, p *models.LessonPreparation) {
+	mediaList(v, p.Media)
+}
+
+func LessonBuild(v *Validator, b *models.LessonBuild) {
+	v.Required("title", b.Title)
+	v.Required("build_type", string(b.BuildType))
+	OneOf(v, "build_type", b.BuildType, models.BuildTypes)
+	mediaList(v, b.Media)
+}
+
+func LessonContentBlock(v *Validator, cb *models.LessonContentBlock) {
+	v.Required("title", cb.Title)
+	mediaList(v, cb.Media)
+}
+
+func LessonAttachment(v *Validator, a *models.LessonAttachment) {
+	v.Required("title", a.Title)
+	v.MaxLen("file_type", a.FileType, shortText)
+	mediaList(v, a.Media)
+}
+
+func LessonChallenge(v *Validator, ch *models.LessonChallenge) {
+	v.Required("title", ch.Title)
+	mediaList(v, ch.Media)
+}
+
+// LessonQuiz also checks that the options fit the quiz type. A choice quiz
+// still being written may have no options yet.
+func LessonQuiz(v *Validator, q *models.LessonQuiz) {
+	v.Required("title", q.Title)
+	v.Required("quiz_type", string(q.QuizType))
+	OneOf(v, "quiz_type", q.QuizType, models.QuizTypes)
+	correct := 0
+	for i := range q.Options {
+		LessonQuizOption(v.Index("options", i), &q.Options[i])
+		if q.Options[i].IsCorrect {
+			correct++
+		}
+	}
+	if len(q.Options) == 0 {
+		return
+	}
+	switch q.QuizType {
+	case models.QuizTypeSingle:
+		if correct != 1 {
+			v.Add("options", CodeCorrectCount, "must have exactly one correct option in a single-choice quiz, found %d", correct)
+		}
+	case models.QuizTypeMultiple:
+		if correct == 0 {
+			v.Add("options", CodeCorrectCount, "must have at least one correct option in a multiple-choice quiz")
+		}
+	}
+}
+
+func LessonQuizOption(v *Validator, o *models.LessonQuizOption) {
+	v.Required("content", o.Content)
+}
+
+// Media checks one media row
+func Media(v *Validator, m *models.Media) {
+	v.Required("url", m.URL)
+	v.MaxLen("mime_type", m.MimeType, 100)
+	OneOf(v, "purpose", m.Purpose, models.MediaPurposes)
+}
+
+func mediaList(v *Validator, media []models.Media) {
+	for i := range media {
+		Media(v.Index("media", i), &media[i])
+	}
+}
@@ -0,0 +1,135 @@
+// Package validation checks request payloads and reports every problem at
+// once, each with the JSON path of the offending field and a machine-readable
+// code, e.g. {"field": "content_blocks[2].title", "code": "required"}.
+package validation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Error codes
+const (
+	CodeRequired     = "required"
+	CodeInvalid      = "invalid"       // malformed value, e.g. a slug with spaces
+	CodeInvalidType  = "invalid_type"  // wrong JSON type, e.g. a number for a string
+	CodeInvalidEnum  = "invalid_enum"  // value outside the allowed set
+	CodeTooLong      = "too_long"      // longer than the column allows
+	CodeOutOfRange   = "out_of_range"  // number outside its bounds
+	CodeCorrectCount = "correct_count" // wrong number of correct quiz options
+	CodeConflict     = "conflict"      // unique value already in use
+)
+
+// FieldError is one problem with one field
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Errors is every problem found in a payload
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validator collects errors; At and Index return validators for nested paths
+// that share the same error list
+type Validator struct {
+	path string
+	errs *Errors
+}
+
+func New() *Validator {
+	return &Validator{errs: &Errors{}}
+}
+
+// At descends into an object field
+func (v *Validator) At(field string) *Validator {
+	return &Validator{path: v.join(field), errs: v.errs}
+}
+
+// Index descends into element i of an array field
+func (v *Validator) Index(field string, i int) *Validator {
+	return &Validator{path: fmt.Sprintf("%s[%d]", v.join(field), i), errs: v.errs}
+}
+
+func (v *Validator) join(field string) string {
+	if v.path == "" {
+		return field
+	}
+	if field == "" {
+		return v.path
+	}
+	return v.path + "." + field
+}
+
+// Add records an error on field; the message is prefixed with the field's path
+func (v *Validator) Add(field, code, format string, args ...interface{}) {
+	path := v.join(field)
+	*v.errs = append(*v.errs, FieldError{Field: path, Code: code, Message: path + " " + fmt.Sprintf(format, args...)})
+}
+
+// Err returns the collected errors as Errors, or nil if there are none
+func (v *Validator) Err() error {
+	if len(*v.errs) == 0 {
+		return nil
+	}
+	return *v.errs
+}
+
+// Required rejects an empty or blank string
+func (v *Validator) Required(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.Add(field, CodeRequired, "is required")
+	}
+}
+
+// MaxLen rejects strings longer than max characters
+func (v *Validator) MaxLen(field, value string, max int) {
+	if utf8.RuneCountInString(value) > max {
+		v.Add(field, CodeTooLong, "must be at most %d characters", max)
+	}
+}
+
+// Min rejects numbers below min
+func (v *Validator) Min(field string, value, min int) {
+	if value < min {
+		v.Add(field, CodeOutOfRange, "must be at least %d", min)
+	}
+}
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+// Slug requires lowercase letters, digits and hyphens, as the editor does
+func (v *Validator) Slug(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.Add(field, CodeRequired, "is required")
+		return
+	}
+	if !slugPattern.MatchString(value) {
+		v.Add(field, CodeInvalid, "may only contain lowercase letters, digits and hyphens")
+	}
+}
+
+// OneOf rejects a non-empty value outside allowed; empty values are left to Required
+func OneOf[T ~string](v *Validator, field string, value T, allowed []T) {
+	if value == "" {
+		return
+	}
+	names := make([]string, len(allowed))
+	for i, a := range allowed {
+		if a == value {
+			return
+		}
+		names[i] = string(a)
+	}
+	v.Add(field, CodeInvalidEnum, "must be one of %s", strings.Join(names, ", "))
+}
@@ -1,6 +1,7 @@
 import { ReactNode } from 'react';
 import { Navigate } from 'react-router-dom';
 import { useAuth } from '../contexts/AuthContext';
+import { isAdminRole } from '../types';
 import { routes } from '../routes';
 
 export default function AdminGuard({ children }: { children: ReactNode }) {
@@ -8,7 +9,7 @@ export default function AdminGuard({ children }: { children: ReactNode }) {
 
   if (isLoading) return <div />;
 
-  if (!user || user.role !== 'admin') {
+  if (!user || !isAdminRole(user.role)) {
     return <Navigate to={routes.home()} replace />;
   }
 
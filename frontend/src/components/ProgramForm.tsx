@@ -46,6 +46,7 @@ export default function ProgramForm({ initial, onCancel, onSuccess }: Props) {
         short_description: shortDescription,
         status,
         sort_order: sortOrder,
+        version: initial?.version,
       } as any;
 
       let res: Program;
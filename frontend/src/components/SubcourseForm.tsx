@@ -54,6 +54,7 @@ export default function SubcourseForm({ initial, onCancel, onSuccess }: Props) {
         slug,
         age_range: ageRange,
         status,
+        version: initial?.version,
       } as any;
 
       let res: Subcourse;
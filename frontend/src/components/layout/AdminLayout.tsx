@@ -1,6 +1,7 @@
 /* eslint-disable @typescript-eslint/no-explicit-any */
 // import { Outlet, Link, useNavigate } from 'react-router-dom';
 // import { useAuth } from '../../contexts/AuthContext';
+import { isAdminRole } from '../../types';
 // import { routes } from '../../routes';
 
 // export default function AdminLayout() {
@@ -75,7 +76,7 @@ export default function AdminLayout() {
 
         {/* NAV */}
         <nav className="flex-1 px-4 py-6 space-y-2">
-          {user?.role === 'admin' && (
+          {isAdminRole(user?.role) && (
             <Link
               to={routes.admin.programs()}
               className="flex items-center gap-3 px-4 py-3 rounded-xl hover:bg-white/10 transition"
@@ -85,7 +86,7 @@ export default function AdminLayout() {
             </Link>
           )}
 
-          {(user?.role === 'admin' || (user?.assignments || []).some((a: any) => !!a.program_id)) && (
+          {(isAdminRole(user?.role) || (user?.assignments || []).some((a: any) => !!a.program_id)) && (
             <Link
               to={routes.admin.subcourses()}
               className="flex items-center gap-3 px-4 py-3 rounded-xl hover:bg-white/10 transition"
@@ -95,7 +96,7 @@ export default function AdminLayout() {
             </Link>
           )}
 
-          {(user?.role === 'admin' || (user?.assignments || []).some((a: any) => !!a.subcourse_id || !!a.program_id)) && (
+          {(isAdminRole(user?.role) || (user?.assignments || []).some((a: any) => !!a.subcourse_id || !!a.program_id)) && (
             <Link
               to={routes.admin.lessons()}
               className="flex items-center gap-3 px-4 py-3 rounded-xl hover:bg-white/10 transition"
@@ -105,7 +106,7 @@ export default function AdminLayout() {
             </Link>
           )}
 
-          {user?.role === 'admin' && (
+          {isAdminRole(user?.role) && (
             <Link
               to={routes.admin.teachers()}
               className="flex items-center gap-3 px-4 py-3 rounded-xl hover:bg-white/10 transition"
@@ -115,7 +116,7 @@ export default function AdminLayout() {
             </Link>
           )}
 
-          {user?.role === 'admin' && (
+          {isAdminRole(user?.role) && (
             <Link
               to={routes.admin.teacherHistory()}
               className="flex items-center gap-3 px-4 py-3 rounded-xl hover:bg-white/10 transition"
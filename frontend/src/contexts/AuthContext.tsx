@@ -1,12 +1,14 @@
 /* eslint-disable @typescript-eslint/no-explicit-any */
 import { createContext, useContext, useState, useEffect, ReactNode } from 'react';
-import type { User } from '../types';
+import type { MfaChallenge, User } from '../types';
 import { authAPI } from '../services/api';
 
 interface AuthContextType {
   user: User | null;
   token: string | null;
-  login: (username: string, password: string) => Promise<void>;
+  // resolves with a challenge when a second factor is still required
+  login: (username: string, password: string) => Promise<MfaChallenge | null>;
+  completeLogin: (token: string, user: unknown) => void;
   logout: () => void;
   isLoading: boolean;
 }
@@ -53,11 +55,17 @@ export function AuthProvider({ children }: { children: ReactNode }) {
     initAuth();
   }, []);
 
+  const completeLogin = (newToken: string, newUser: unknown) => {
+    localStorage.setItem('token', newToken);
+    setToken(newToken);
+    setUser(normalizeUser(newUser));
+  };
+
   const login = async (username: string, password: string) => {
     const response = await authAPI.login(username, password);
-    localStorage.setItem('token', response.token);
-    setToken(response.token);
-    setUser(normalizeUser(response.user));
+    if (response.mfa_required) return response as MfaChallenge;
+    completeLogin(response.token, response.user);
+    return null;
   };
 
   const logout = () => {
@@ -67,7 +75,7 @@ export function AuthProvider({ children }: { children: ReactNode }) {
   };
 
   return (
-    <AuthContext.Provider value={{ user, token, login, logout, isLoading }}>
+    <AuthContext.Provider value={{ user, token, login, completeLogin, logout, isLoading }}>
       {children}
     </AuthContext.Provider>
   );
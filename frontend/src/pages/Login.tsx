@@ -3,13 +3,37 @@ import { useNavigate, Link } from 'react-router-dom';
 import { routes } from '../routes';
 import type { AxiosError } from 'axios';
 import { useAuth } from '../contexts/AuthContext';
+import { authAPI } from '../services/api';
+import type { MfaChallenge } from '../types';
+
+const errorMessage = (err: unknown, fallback: string) => {
+  let message = fallback;
+  if (typeof err === 'object' && err !== null && 'response' in err) {
+    const axiosErr = err as AxiosError;
+    // axiosErr.response?.data may be unknown; guard accordingly
+    const respData = axiosErr.response?.data as unknown;
+    if (respData && typeof respData === 'object' && 'error' in respData) {
+      const maybeError = (respData as Record<string, unknown>).error;
+      if (typeof maybeError === 'string') message = maybeError;
+    }
+  } else if (err instanceof Error) {
+    message = err.message;
+  }
+  return message;
+};
 
 export default function Login() {
   const [username, setUsername] = useState('');
   const [password, setPassword] = useState('');
   const [error, setError] = useState('');
   const [loading, setLoading] = useState(false);
-  const { login } = useAuth();
+  // Second step (2FA): code entry, or enrollment when it is mandatory
+  const [challenge, setChallenge] = useState<MfaChallenge | null>(null);
+  const [code, setCode] = useState('');
+  const [useRecovery, setUseRecovery] = useState(false);
+  const [enrollment, setEnrollment] = useState<{ secret: string; provisioning_uri: string } | null>(null);
+  const [recoveryCodes, setRecoveryCodes] = useState<string[]>([]);
+  const { login, completeLogin } = useAuth();
   const navigate = useNavigate();
 
   const handleSubmit = async (e: FormEvent) => {
@@ -18,22 +42,41 @@ export default function Login() {
     setLoading(true);
 
     try {
-      await login(username, password);
-      navigate(routes.home());
+      const pending = await login(username, password);
+      if (!pending) {
+        navigate(routes.home());
+        return;
+      }
+      setChallenge(pending);
+      if (pending.enrollment_required) {
+        setEnrollment(await authAPI.setupTwoFactor(pending.challenge_token));
+      }
     } catch (err: unknown) {
-      let message = 'Login failed';
-      if (typeof err === 'object' && err !== null && 'response' in err) {
-        const axiosErr = err as AxiosError;
-        // axiosErr.response?.data may be unknown; guard accordingly
-        const respData = axiosErr.response?.data as unknown;
-        if (respData && typeof respData === 'object' && 'error' in respData) {
-          const maybeError = (respData as Record<string, unknown>).error;
-          if (typeof maybeError === 'string') message = maybeError;
-        }
-      } else if (err instanceof Error) {
-        message = err.message;
+      setError(errorMessage(err, 'Login failed'));
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const handleCodeSubmit = async (e: FormEvent) => {
+    e.preventDefault();
+    if (!challenge) return;
+    setError('');
+    setLoading(true);
+
+    try {
+      if (challenge.enrollment_required) {
+        const result = await authAPI.enableTwoFactor(code, challenge.challenge_token);
+        if (result.token) completeLogin(result.token, result.user);
+        // show the recovery codes once before continuing
+        setRecoveryCodes(result.recovery_codes);
+      } else {
+        const result = await authAPI.verifyTwoFactor(challenge.challenge_token, code, useRecovery);
+        completeLogin(result.token, result.user);
+        navigate(routes.home());
       }
-      setError(typeof message === 'string' ? message : 'Login failed');
+    } catch (err: unknown) {
+      setError(errorMessage(err, 'Verification failed'));
     } finally {
       setLoading(false);
     }
@@ -58,51 +101,120 @@ export default function Login() {
             <p className="text-gray-600 text-sm">Đăng nhập để bắt đầu</p>
           </div>
 
-          <form onSubmit={handleSubmit} className="space-y-5">
-            {/* Username Input */}
-            <div>
-              <label className="block text-gray-700 text-sm font-semibold mb-2">
-                Tên đăng nhập
-              </label>
-              <input
-                type="text"
-                value={username}
-                onChange={(e) => setUsername(e.target.value)}
-                className="w-full px-4 py-3 border border-gray-300 rounded-lg focus:outline-none focus:ring-2 focus:ring-purple-500 focus:border-transparent transition-all bg-gray-50 hover:bg-white text-black"
-                required
-              />
+          {recoveryCodes.length > 0 ? (
+            <div className="space-y-5">
+              <p className="text-gray-700 text-sm">
+                Lưu các mã khôi phục này ở nơi an toàn. Mỗi mã chỉ dùng được một lần.
+              </p>
+              <pre className="p-4 bg-gray-50 border border-gray-200 rounded-lg text-sm text-black">{recoveryCodes.join('\n')}</pre>
+              <button
+                type="button"
+                onClick={() => navigate(routes.home())}
+                className="w-full bg-gradient-to-r from-purple-600 to-pink-600 text-white font-bold py-3 px-4 rounded-lg hover:from-purple-700 hover:to-pink-700 transition-all shadow-lg hover:shadow-xl"
+              >
+                Tiếp tục
+              </button>
             </div>
+          ) : challenge ? (
+            <form onSubmit={handleCodeSubmit} className="space-y-5">
+              {enrollment && (
+                <div className="space-y-2 text-sm text-gray-700">
+                  <p>Tài khoản quản trị bắt buộc xác thực hai lớp. Thêm khóa sau vào ứng dụng xác thực:</p>
+                  <code className="block p-3 bg-gray-50 border border-gray-200 rounded-lg break-all text-black">{enrollment.secret}</code>
+                  <a href={enrollment.provisioning_uri} className="text-purple-600 hover:text-purple-700 font-semibold">
+                    Mở trong ứng dụng xác thực
+                  </a>
+                </div>
+              )}
+              <div>
+                <label className="block text-gray-700 text-sm font-semibold mb-2">
+                  {useRecovery ? 'Mã khôi phục' : 'Mã xác thực (6 chữ số)'}
+                </label>
+                <input
+                  type="text"
+                  inputMode={useRecovery ? 'text' : 'numeric'}
+                  autoComplete="one-time-code"
+                  value={code}
+                  onChange={(e) => setCode(e.target.value)}
+                  className="w-full px-4 py-3 border border-gray-300 rounded-lg focus:outline-none focus:ring-2 focus:ring-purple-500 focus:border-transparent transition-all bg-gray-50 hover:bg-white text-black"
+                  required
+                />
+              </div>
 
-            {/* Password Input */}
-            <div>
-              <label className="block text-gray-700 text-sm font-semibold mb-2">
-                Mật khẩu
-              </label>
-              <input
-                type="password"
-                value={password}
-                onChange={(e) => setPassword(e.target.value)}
-                className="w-full px-4 py-3 border border-gray-300 rounded-lg focus:outline-none focus:ring-2 focus:ring-purple-500 focus:border-transparent transition-all bg-gray-50 hover:bg-white text-black"
-                required
-              />
-            </div>
+              {error && (
+                <div className="p-4 bg-red-50 border border-red-200 text-red-700 rounded-lg text-sm">
+                  {error}
+                </div>
+              )}
 
-            {/* Error Message */}
-            {error && (
-              <div className="p-4 bg-red-50 border border-red-200 text-red-700 rounded-lg text-sm">
-                {error}
+              <button
+                type="submit"
+                disabled={loading}
+                className="w-full bg-gradient-to-r from-purple-600 to-pink-600 text-white font-bold py-3 px-4 rounded-lg hover:from-purple-700 hover:to-pink-700 focus:outline-none focus:ring-2 focus:ring-purple-500 focus:ring-offset-2 disabled:opacity-50 disabled:cursor-not-allowed transition-all shadow-lg hover:shadow-xl"
+              >
+                {loading ? 'Đang xác thực...' : 'Xác thực'}
+              </button>
+
+              {!challenge.enrollment_required && (
+                <button
+                  type="button"
+                  onClick={() => {
+                    setUseRecovery(!useRecovery);
+                    setCode('');
+                  }}
+                  className="w-full text-sm text-purple-600 hover:text-purple-700 font-semibold"
+                >
+                  {useRecovery ? 'Dùng mã xác thực' : 'Dùng mã khôi phục'}
+                </button>
+              )}
+            </form>
+          ) : (
+            <form onSubmit={handleSubmit} className="space-y-5">
+              {/* Username Input */}
+              <div>
+                <label className="block text-gray-700 text-sm font-semibold mb-2">
+                  Tên đăng nhập
+                </label>
+                <input
+                  type="text"
+                  value={username}
+                  onChange={(e) => setUsername(e.target.value)}
+                  className="w-full px-4 py-3 border border-gray-300 rounded-lg focus:outline-none focus:ring-2 focus:ring-purple-500 focus:border-transparent transition-all bg-gray-50 hover:bg-white text-black"
+                  required
+                />
               </div>
-            )}
 
-            {/* Submit Button */}
-            <button
-              type="submit"
-              disabled={loading}
-              className="w-full bg-gradient-to-r from-purple-600 to-pink-600 text-white font-bold py-3 px-4 rounded-lg hover:from-purple-700 hover:to-pink-700 focus:outline-none focus:ring-2 focus:ring-purple-500 focus:ring-offset-2 disabled:opacity-50 disabled:cursor-not-allowed transition-all shadow-lg hover:shadow-xl"
-            >
-              {loading ? 'Đang đăng nhập...' : 'Đăng nhập'}
-            </button>
-          </form>
+              {/* Password Input */}
+              <div>
+                <label className="block text-gray-700 text-sm font-semibold mb-2">
+                  Mật khẩu
+                </label>
+                <input
+                  type="password"
+                  value={password}
+                  onChange={(e) => setPassword(e.target.value)}
+                  className="w-full px-4 py-3 border border-gray-300 rounded-lg focus:outline-none focus:ring-2 focus:ring-purple-500 focus:border-transparent transition-all bg-gray-50 hover:bg-white text-black"
+                  required
+                />
+              </div>
+
+              {/* Error Message */}
+              {error && (
+                <div className="p-4 bg-red-50 border border-red-200 text-red-700 rounded-lg text-sm">
+                  {error}
+                </div>
+              )}
+
+              {/* Submit Button */}
+              <button
+                type="submit"
+                disabled={loading}
+                className="w-full bg-gradient-to-r from-purple-600 to-pink-600 text-white font-bold py-3 px-4 rounded-lg hover:from-purple-700 hover:to-pink-700 focus:outline-none focus:ring-2 focus:ring-purple-500 focus:ring-offset-2 disabled:opacity-50 disabled:cursor-not-allowed transition-all shadow-lg hover:shadow-xl"
+              >
+                {loading ? 'Đang đăng nhập...' : 'Đăng nhập'}
+              </button>
+            </form>
+          )}
 
           {/* Footer Link */}
           <div className="mt-8 text-center">
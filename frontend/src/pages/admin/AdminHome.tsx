@@ -1,6 +1,7 @@
 /* eslint-disable @typescript-eslint/no-explicit-any */
 import { Link } from 'react-router-dom';
 import { useAuth } from '../../contexts/AuthContext';
+import { isAdminRole } from '../../types';
 import { routes } from '../../routes';
 
 export default function AdminHome() {
@@ -8,7 +9,7 @@ export default function AdminHome() {
 
   const widgets = [
     {
-      show: user?.role === 'admin',
+      show: isAdminRole(user?.role),
       to: routes.admin.programs(),
       title: 'Programs',
       description: 'Manage programs',
@@ -16,7 +17,7 @@ export default function AdminHome() {
       color: 'from-blue-500 to-blue-600'
     },
     {
-      show: (user?.role === 'admin' || (user?.assignments || []).some((a: any) => !!a.program_id)),
+      show: (isAdminRole(user?.role) || (user?.assignments || []).some((a: any) => !!a.program_id)),
       to: routes.admin.subcourses(),
       title: 'Subcourses',
       description: 'Manage subcourses',
@@ -24,7 +25,7 @@ export default function AdminHome() {
       color: 'from-purple-500 to-purple-600'
     },
     {
-      show: (user?.role === 'admin' || (user?.role === "teacher") && (user?.assignments || []).some((a: any) => !!a.subcourse_id || !!a.program_id)),
+      show: (isAdminRole(user?.role) || (user?.role === "teacher") && (user?.assignments || []).some((a: any) => !!a.subcourse_id || !!a.program_id)),
       to: routes.admin.lessons(),
       title: 'Lessons',
       description: 'Manage lessons',
@@ -32,7 +33,7 @@ export default function AdminHome() {
       color: 'from-pink-500 to-pink-600'
     },
     {
-      show: user?.role === 'admin',
+      show: isAdminRole(user?.role),
       to: routes.admin.teachers(),
       title: 'Teachers',
       description: 'Manage teacher accounts & assignments',
@@ -4,6 +4,7 @@
 import { useEffect, useState } from 'react';
 import { teachersAPI, programsAPI, subcoursesAPI, teacherAssignmentsAPI } from '../../services/api';
 import { useAuth } from '../../contexts/AuthContext';
+import { isAdminRole } from '../../types';
 import { useNavigate } from 'react-router-dom';
 import { useToast } from '../../components/Toast';
 
@@ -24,7 +25,7 @@ export default function Teachers() {
 
     useEffect(() => {
       if (!user) return; // wait for auth to initialize
-      if (user.role !== 'admin') {
+      if (!isAdminRole(user.role)) {
         navigate('/', { replace: true });
       }
     }, [user, navigate]);
@@ -2,6 +2,7 @@
 
 import { useEffect, useState } from 'react';
 import { lessonsAPI, subcoursesAPI } from '../../services/api';
+import { describeImpact } from '../../utils/deleteImpact';
 import type { Lesson, Subcourse } from '../../types';
 import { useParams, useNavigate } from 'react-router-dom';
 import AdminLessonFormV2 from '../../components/AdminLessonFormV2';
@@ -49,7 +50,7 @@ export default function Lessons() {
   }, [subcourseId]);
 
   async function handleSubmit(id: string | undefined, data: Lesson) {
-    if (id) return lessonsAPI.update(id, data);
+    if (id) return lessonsAPI.update(id, { ...data, version: data.version ?? lessons.find((x) => x.id === id)?.version });
     return lessonsAPI.create(data);
   }
 
@@ -66,9 +67,10 @@ export default function Lessons() {
 
   async function handleDelete(id?: string) {
     if (!id) return;
-    if (!confirm('Xác nhận xóa lesson này?')) return;
+    const impact = await lessonsAPI.deleteImpact(id).then(describeImpact).catch(() => '');
+    if (!confirm(['Xác nhận xóa lesson này?', impact].filter(Boolean).join('\n'))) return;
     try {
-      await lessonsAPI.delete(id);
+      await lessonsAPI.delete(id, lessons.find((x) => x.id === id)?.version);
       setLessons((prev) => prev.filter((x) => x.id !== id));
       success('Bài học đã được xóa thành công');
     } catch (err: any) {
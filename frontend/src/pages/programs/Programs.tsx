@@ -1,9 +1,11 @@
 /* eslint-disable @typescript-eslint/no-explicit-any */
 import { useEffect, useState } from 'react';
 import { programsAPI } from '../../services/api';
+import { describeImpact } from '../../utils/deleteImpact';
 import type { Program } from '../../types';
 import { useNavigate } from 'react-router-dom';
 import { useAuth } from '../../contexts/AuthContext';
+import { isAdminRole } from '../../types';
 import ProgramForm from '../../components/ProgramForm';
 import { getErrorMessage } from '../../utils/error';
 import { routes } from '../../routes';
@@ -23,7 +25,7 @@ export default function Programs() {
   // Only admin may access Programs page
   useEffect(() => {
     if (!user) return;
-    if (user.role !== 'admin') {
+    if (!isAdminRole(user.role)) {
       navigate('/', { replace: true });
     }
   }, [user, navigate]);
@@ -56,9 +58,10 @@ export default function Programs() {
 
   async function handleDelete(id?: string) {
     if (!id) return;
-    if (!confirm('Xác nhận xóa program này?')) return;
+    const impact = await programsAPI.deleteImpact(id).then(describeImpact).catch(() => '');
+    if (!confirm(['Xác nhận xóa program này?', impact].filter(Boolean).join('\n'))) return;
     try {
-      await programsAPI.delete(id);
+      await programsAPI.delete(id, programs.find((x) => x.id === id)?.version);
       setPrograms((prev) => prev.filter((x) => x.id !== id));
       success('Chương trình đã được xóa thành công');
     } catch (err: any) {
@@ -152,7 +155,7 @@ export default function Programs() {
         </p>
       </div>
 
-      {user?.role === 'admin' && (
+      {isAdminRole(user?.role) && (
         <button
           className="w-full sm:w-auto px-5 py-2.5 rounded-xl bg-indigo-600 text-white text-sm font-medium hover:bg-indigo-700 shadow-md transition"
           onClick={handleCreate}
@@ -1,11 +1,13 @@
 import { useEffect, useState } from 'react';
 import { subcoursesAPI, programsAPI } from '../../services/api';
+import { describeImpact } from '../../utils/deleteImpact';
 import type { Subcourse, Program } from '../../types';
 import { getErrorMessage } from '../../utils/error';
 import { useParams, useNavigate } from 'react-router-dom';
 import { routes } from '../../routes';
 import SubcourseForm from '../../components/SubcourseForm';
 import { useAuth } from '../../contexts/AuthContext';
+import { isAdminRole } from '../../types';
 import { resolveMediaUrl } from '../../utils/media';
 import { useToast } from '../../components/Toast';
 
@@ -27,7 +29,7 @@ export default function Subcourses() {
   // If not admin, teachers must have a program assignment to access subcourses admin page
   useEffect(() => {
     if (!user) return;
-    if (user.role !== 'admin') {
+    if (!isAdminRole(user.role)) {
       const hasProgramAssign = (user.assignments || []).some((a: any) => !!a.program_id);
       if (!hasProgramAssign) {
         navigate('/', { replace: true });
@@ -72,9 +74,10 @@ export default function Subcourses() {
 
   async function handleDelete(id?: string) {
     if (!id) return;
-    if (!confirm('Xác nhận xóa subcourse này?')) return;
+    const impact = await subcoursesAPI.deleteImpact(id).then(describeImpact).catch(() => '');
+    if (!confirm(['Xác nhận xóa subcourse này?', impact].filter(Boolean).join('\n'))) return;
     try {
-      await subcoursesAPI.delete(id);
+      await subcoursesAPI.delete(id, subcourses.find((x) => x.id === id)?.version);
       setSubcourses((prev) => prev.filter((x) => x.id !== id));
       success('Khóa học đã được xóa thành công');
     // eslint-disable-next-line @typescript-eslint/no-explicit-any
@@ -190,7 +193,7 @@ return (
         </p>
       </div>
 
-      {(user?.role === 'admin' || (user?.assignments || []).some((a: any) => !!a.program_id)) && (
+      {(isAdminRole(user?.role) || (user?.assignments || []).some((a: any) => !!a.program_id)) && (
         <button
           className="px-5 py-2.5 rounded-xl bg-indigo-600 text-white text-sm font-medium hover:bg-indigo-700 shadow-md"
           onClick={handleCreate}
@@ -1,6 +1,6 @@
 /* eslint-disable @typescript-eslint/no-explicit-any */
 import axios from 'axios';
-import type { User, Program, Subcourse, Lesson } from '../types';
+import type { User, Program, Subcourse, Lesson, DeleteImpact } from '../types';
 import { routes } from '../routes';
 
 const API_BASE_URL = import.meta.env.VITE_API_BASE_URL || '/api';
@@ -136,8 +136,32 @@ export const authAPI = {
     const response = await api.get('/auth/me');
     return response.data;
   },
+
+  verifyTwoFactor: async (challengeToken: string, code: string, recovery = false) => {
+    const response = await api.post('/auth/2fa/verify', {
+      challenge_token: challengeToken,
+      ...(recovery ? { recovery_code: code } : { code }),
+    });
+    return response.data;
+  },
+
+  setupTwoFactor: async (challengeToken?: string): Promise<{ secret: string; provisioning_uri: string }> => {
+    const response = await api.post('/auth/2fa/setup', { challenge_token: challengeToken });
+    return response.data;
+  },
+
+  enableTwoFactor: async (code: string, challengeToken?: string) => {
+    const response = await api.post('/auth/2fa/enable', { code, challenge_token: challengeToken });
+    return response.data as { recovery_codes: string[]; token?: string; user?: User };
+  },
 };
 
+// If-Match header for versioned records. The server requires it (428 without
+// a version) and answers 412 with the current copy when someone else saved in
+// the meantime
+const ifMatch = (version?: number) =>
+  version ? { headers: { 'If-Match': `"${version}"` } } : {};
+
 // Programs API
 export const programsAPI = {
   getAll: async (): Promise<Program[]> => {
@@ -156,12 +180,23 @@ export const programsAPI = {
   },
   
   update: async (id: string, data: Program): Promise<Program> => {
-    const response = await api.put(`/admin/programs/${id}`, data);
+    const response = await api.put(`/admin/programs/${id}`, data, ifMatch(data.version));
     return response.data;
   },
   
-  delete: async (id: string): Promise<void> => {
-    await api.delete(`/admin/programs/${id}`);
+  delete: async (id: string, version?: number): Promise<void> => {
+    await api.delete(`/admin/programs/${id}`, ifMatch(version));
+  },
+
+  deleteImpact: async (id: string): Promise<DeleteImpact> => {
+    const response = await api.get(`/admin/programs/${id}/delete-impact`);
+    return response.data;
+  },
+
+  // ids lists every program of the organization in the new order
+  reorder: async (ids: string[]): Promise<Program[]> => {
+    const response = await api.put('/admin/programs/order', { ids });
+    return response.data;
   },
 };
 
@@ -202,12 +237,28 @@ export const subcoursesAPI = {
   },
   
   update: async (id: string, data: Subcourse): Promise<Subcourse> => {
-    const response = await api.put(`/admin/subcourses/${id}`, data);
+    const response = await api.put(`/admin/subcourses/${id}`, data, ifMatch(data.version));
     return response.data;
   },
   
-  delete: async (id: string): Promise<void> => {
-    await api.delete(`/admin/subcourses/${id}`);
+  delete: async (id: string, version?: number): Promise<void> => {
+    await api.delete(`/admin/subcourses/${id}`, ifMatch(version));
+  },
+
+  deleteImpact: async (id: string): Promise<DeleteImpact> => {
+    const response = await api.get(`/admin/subcourses/${id}/delete-impact`);
+    return response.data;
+  },
+
+  // ids lists every subcourse of the program in the new order
+  reorder: async (programId: string, ids: string[]): Promise<Subcourse[]> => {
+    const response = await api.put(`/admin/programs/${programId}/subcourses/order`, { ids });
+    return response.data;
+  },
+
+  move: async (id: string, programId: string, position?: number, version?: number): Promise<Subcourse> => {
+    const response = await api.post(`/admin/subcourses/${id}/move`, { program_id: programId, position }, ifMatch(version));
+    return response.data;
   },
 };
 
@@ -254,12 +305,28 @@ export const lessonsAPI = {
   },
   
   update: async (id: string, data: Lesson): Promise<Lesson> => {
-    const response = await api.put(`/admin/lessons/${id}`, data);
+    const response = await api.put(`/admin/lessons/${id}`, data, ifMatch(data.version));
     return response.data;
   },
   
-  delete: async (id: string): Promise<void> => {
-    await api.delete(`/admin/lessons/${id}`);
+  delete: async (id: string, version?: number): Promise<void> => {
+    await api.delete(`/admin/lessons/${id}`, ifMatch(version));
+  },
+
+  deleteImpact: async (id: string): Promise<DeleteImpact> => {
+    const response = await api.get(`/admin/lessons/${id}/delete-impact`);
+    return response.data;
+  },
+
+  // ids lists every lesson of the subcourse in the new order
+  reorder: async (subcourseId: string, ids: string[]): Promise<Lesson[]> => {
+    const response = await api.put(`/admin/subcourses/${subcourseId}/lessons/order`, { ids });
+    return response.data;
+  },
+
+  move: async (id: string, subcourseId: string, position?: number, version?: number): Promise<Lesson> => {
+    const response = await api.post(`/admin/lessons/${id}/move`, { subcourse_id: subcourseId, position }, ifMatch(version));
+    return response.data;
   },
 };
 
@@ -362,6 +429,12 @@ export const mediaAPI = {
     });
     return response.data;
   },
+
+  // ids lists every media item of the owner in the new order
+  reorder: async (owner_type: string, owner_id: string, ids: string[]) => {
+    const response = await api.put('/admin/media/order', { owner_type, owner_id, ids });
+    return response.data;
+  },
 };
 
 export default api;
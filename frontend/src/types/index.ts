@@ -3,12 +3,25 @@ export interface User {
   id: string;
   username: string;
   email: string;
-  role: 'admin' | 'teacher';
+  role: 'super_admin' | 'admin' | 'teacher' | 'reviewer' | 'viewer';
+  organization_id?: string | null;
   status: 'active' | 'inactive';
+  totp_enabled?: boolean;
   created_at: string;
   updated_at: string;
 }
 
+// Returned by login instead of a token when a second factor is pending
+export interface MfaChallenge {
+  mfa_required: true;
+  enrollment_required: boolean;
+  challenge_token: string;
+  expires_in: number;
+}
+
+// Org admins and super-admins share the admin UI
+export const isAdminRole = (role?: string) => role === 'admin' || role === 'super_admin';
+
 export interface Media {
   filename?: string;
   id?: string;
@@ -28,12 +41,33 @@ export interface Program {
   block_types?: string[];
   status: 'draft' | 'published' | 'archived';
   sort_order?: number;
+  version?: number;
+  // counters maintained by the server
   subcourse_count?: number;
+  published_subcourse_count?: number;
+  lesson_count?: number;
+  published_lesson_count?: number;
+  total_duration_minutes?: number;
+  media_count?: number;
   media?: Media[];
   created_at?: string;
   updated_at?: string;
 }
 
+// What deleting a program, subcourse or lesson removes (GET .../delete-impact)
+export interface DeleteImpact {
+  type: 'program' | 'subcourse' | 'lesson';
+  id: string;
+  subcourses: number;
+  lessons: number;
+  components: Record<string, number>;
+  templates: number;
+  media: number;
+  media_files: number;
+  media_bytes: number;
+  summary: string;
+}
+
 export interface Subcourse {
   id?: string;
   program_id: string;
@@ -41,11 +75,15 @@ export interface Subcourse {
   slug: string;
   age_range?: string;
   lesson_count?: number;
+  published_lesson_count?: number;
+  total_duration_minutes?: number;
+  media_count?: number;
   short_description?: string;
   general_objectives?: string;
   block_types?: string[];
   status: 'draft' | 'published' | 'archived';
   sort_order?: number;
+  version?: number;
   media?: Media[];
   program?: Program;
   created_at?: string;
@@ -147,10 +185,12 @@ export interface Lesson {
   block_types?: string[];
   status: 'draft' | 'published' | 'archived';
   sort_order?: number;
+  version?: number;
   slug: string;
   duration_minutes?: number;
   difficulty?: string;
   estimated_time?: string;
+  media_count?: number;
   media?: Media[];
   subcourse?: Subcourse;
   objectives?: LessonObjective;
@@ -0,0 +1,14 @@
+import type { DeleteImpact } from '../types';
+
+// describeImpact phrases a delete-impact preview for the confirm dialog
+export function describeImpact(impact: DeleteImpact): string {
+  const parts: string[] = [];
+  if (impact.subcourses) parts.push(`${impact.subcourses} khóa học`);
+  if (impact.lessons) parts.push(`${impact.lessons} bài học`);
+  if (impact.templates) parts.push(`${impact.templates} mẫu bài học`);
+  if (impact.media_files) parts.push(`${impact.media_files} tệp media`);
+  if (!parts.length) return '';
+  return `Thao tác này sẽ chuyển vào thùng rác: ${parts.join(', ')}.`;
+}
+
+export default describeImpact;
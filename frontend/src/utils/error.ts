@@ -15,4 +15,18 @@ export function getErrorMessage(err: unknown): string {
   }
 }
 
+// One entry of the "errors" list the API returns with 400/409/422 responses,
+// e.g. { field: 'content_blocks[2].title', code: 'required', message: '...' }
+export interface FieldError {
+  field: string;
+  code: string;
+  message: string;
+}
+
+export function getFieldErrors(err: unknown): FieldError[] {
+  // eslint-disable-next-line @typescript-eslint/no-explicit-any
+  const errors = (err as any)?.response?.data?.errors;
+  return Array.isArray(errors) ? errors : [];
+}
+
 export default getErrorMessage;
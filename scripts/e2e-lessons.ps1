@@ -57,7 +57,7 @@ Write-Output "GET returned title: $($got.title) and objectives count: $($got.obj
 # Update title
 $updateBody = @{ title = "E2E Lesson Updated $ts" } | ConvertTo-Json
 try {
-    $upd = Invoke-RestMethod -Method Put -Uri "$base/api/admin/lessons/$lessonId" -Headers @{ Authorization = "Bearer $token" } -ContentType 'application/json' -Body $updateBody -ErrorAction Stop
+    $upd = Invoke-RestMethod -Method Put -Uri "$base/api/admin/lessons/$lessonId" -Headers @{ Authorization = "Bearer $token"; 'If-Match' = "`"$($got.version)`"" } -ContentType 'application/json' -Body $updateBody -ErrorAction Stop
 } catch {
     ExitWith 7 "Update failed: $($_.Exception.Message)"
 }
@@ -74,7 +74,7 @@ Write-Output "Update confirmed"
 
 # Delete
 try {
-    Invoke-RestMethod -Method Delete -Uri "$base/api/admin/lessons/$lessonId" -Headers @{ Authorization = "Bearer $token" } -ErrorAction Stop
+    Invoke-RestMethod -Method Delete -Uri "$base/api/admin/lessons/$lessonId" -Headers @{ Authorization = "Bearer $token"; 'If-Match' = "`"$($got2.version)`"" } -ErrorAction Stop
 } catch {
     ExitWith 10 "Delete failed: $($_.Exception.Message)"
 }
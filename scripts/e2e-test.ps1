@@ -19,8 +19,13 @@ if (-not $token) { Write-Error "Login failed or token missing"; exit 1 }
 $headers = @{ Authorization = "Bearer $token" }
 
 function PostJson($url, $obj){ Invoke-RestMethod -Uri $url -Method Post -Body ($obj | ConvertTo-Json -Depth 10) -ContentType 'application/json' -Headers $headers -ErrorAction Stop }
-function PutJson($url, $obj){ Invoke-RestMethod -Uri $url -Method Put -Body ($obj | ConvertTo-Json -Depth 10) -ContentType 'application/json' -Headers $headers -ErrorAction Stop }
-function Delete($url){ Invoke-RestMethod -Uri $url -Method Delete -Headers $headers -ErrorAction Stop }
+# Versioned writes must send If-Match with the version they are based on (428 without it)
+function IfMatch($version){ $headers + @{ 'If-Match' = "`"$version`"" } }
+function PutJson($url, $obj, $version){ Invoke-RestMethod -Uri $url -Method Put -Body ($obj | ConvertTo-Json -Depth 10) -ContentType 'application/json' -Headers (IfMatch $version) -ErrorAction Stop }
+function Delete($url){
+    $current = Invoke-RestMethod -Uri $url -Method Get -Headers $headers -ErrorAction Stop
+    Invoke-RestMethod -Uri $url -Method Delete -Headers (IfMatch $current.version) -ErrorAction Stop
+}
 
 try {
     Write-Host "Creating program..."
@@ -52,7 +57,7 @@ try {
 
     Write-Host "Updating lesson title..."
     $updatePayload = @{ title = "E2E Lesson Updated" }
-    $updated = PutJson "$Base/admin/lessons/$($lesson.id)" $updatePayload
+    $updated = PutJson "$Base/admin/lessons/$($lesson.id)" $updatePayload $fetched.version
     if ($updated.title -ne "E2E Lesson Updated") { throw "Lesson update failed" }
     Write-Host "Lesson update OK"
 